@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/samber/lo"
+)
+
+const (
+	completeTemplates = "templates" // Value of the completion tag for template name arguments
+	templatesDirFlag  = "templates-dir"
+)
+
+// Completion scripts call back the binary with the words of the command line, and fall back
+// to file completion if it returns no candidates.
+var completionScripts = map[string]string{
+	"bash": `_{{name}}_completion() {
+    local IFS=$'\n'
+    local candidates
+    candidates=($("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+    if [ ${#candidates[@]} -eq 0 ]; then
+        COMPREPLY=($(compgen -f -- "${COMP_WORDS[COMP_CWORD]}"))
+    else
+        COMPREPLY=("${candidates[@]}")
+    fi
+}
+complete -o filenames -F _{{name}}_completion {{name}}
+`,
+	"zsh": `#compdef {{name}}
+_{{name}}() {
+    local -a candidates
+    candidates=("${(@f)$(${words[1]} __complete "${(@)words[2,$CURRENT]}" 2>/dev/null)}")
+    if [[ -z "${candidates[1]}" ]]; then
+        _files
+    else
+        compadd -a candidates
+    fi
+}
+compdef _{{name}} {{name}}
+`,
+	"fish": `function __{{name}}_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    set -l candidates ($tokens[1] __complete $tokens[2..-1] 2>/dev/null)
+    if test (count $candidates) -eq 0
+        __fish_complete_path (commandline -ct)
+    else
+        printf '%s\n' $candidates
+    end
+end
+complete -c {{name}} -f -a '(__{{name}}_complete)'
+`,
+}
+
+type completionCmd struct {
+	Shell string `arg:"" enum:"bash,zsh,fish" help:"shell to generate the completion script for: bash, zsh or fish"`
+}
+
+func (c *completionCmd) Help() string {
+	return `Load the script in your shell profile, for example:
+
+    # bash, in ~/.bashrc
+    source <(render completion bash)
+    # zsh, in ~/.zshrc
+    source <(render completion zsh)
+    # fish
+    render completion fish > ~/.config/fish/completions/render.fish`
+}
+
+func (c *completionCmd) Run(ctx *kong.Context) error {
+	_, err := fmt.Fprint(stdout, strings.ReplaceAll(completionScripts[c.Shell], "{{name}}", ctx.Model.Name))
+	return err
+}
+
+// completeCmd is called by the completion scripts, and prints one candidate per line
+type completeCmd struct {
+	Words []string `arg:"" optional:""`
+}
+
+func (c *completeCmd) Run(ctx *kong.Context) error {
+	candidates := complete(ctx.Model.Node, c.Words, func(templatesDir string) []string {
+		repo, err := (&globals{TemplatesDir: templatesDir}).loadRepository()
+		if err != nil {
+			return nil
+		}
+		names := make([]string, 0, len(repo.GetAll()))
+		for _, tpl := range repo.GetAll() {
+			names = append(names, tpl.Name)
+		}
+		sort.Strings(names)
+		return names
+	})
+	for _, candidate := range candidates {
+		if _, err := fmt.Fprintln(stdout, candidate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// complete returns the candidates for the last word, given the previous words of the command line.
+// No candidates are returned when file completion should be used instead.
+func complete(app *kong.Node, words []string, templateNames func(templatesDir string) []string) []string {
+	current := ""
+	if len(words) > 0 {
+		current, words = words[len(words)-1], words[:len(words)-1]
+	}
+
+	node, position, templatesDir := app, 0, ""
+	var pending *kong.Flag // Flag expecting a value as next word
+	for _, word := range words {
+		if pending != nil {
+			if pending.Name == templatesDirFlag {
+				templatesDir = word
+			}
+			pending = nil
+			continue
+		}
+		if strings.HasPrefix(word, "-") && len(word) > 1 {
+			name, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+			flag := findFlag(node, name, !strings.HasPrefix(word, "--"))
+			switch {
+			case flag == nil || flag.IsBool():
+			case hasValue && flag.Name == templatesDirFlag:
+				templatesDir = value
+			case !hasValue:
+				pending = flag
+			}
+			continue
+		}
+		if child := findCommand(node, word); child != nil && position == 0 {
+			node = child
+			continue
+		}
+		position++
+	}
+
+	var candidates []string
+	switch {
+	case pending != nil && pending.Enum != "":
+		candidates = strings.Split(pending.Enum, ",")
+	case pending != nil && pending.Tag.Get("completion") == completeTemplates:
+		candidates = templateNames(templatesDir)
+	case pending != nil:
+		return nil
+	case strings.HasPrefix(current, "-"):
+		for _, flag := range allFlags(node) {
+			if !flag.Hidden {
+				candidates = append(candidates, "--"+flag.Name)
+			}
+		}
+	default:
+		if position == 0 {
+			for _, child := range node.Children {
+				if !child.Hidden {
+					candidates = append(candidates, child.Name)
+				}
+			}
+		}
+		if arg := positionalAt(node, position); arg != nil {
+			if arg.Enum != "" {
+				candidates = append(candidates, strings.Split(arg.Enum, ",")...)
+			} else if arg.Tag.Get("completion") == completeTemplates {
+				candidates = append(candidates, templateNames(templatesDir)...)
+			}
+		}
+	}
+
+	matching := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			matching = append(matching, candidate)
+		}
+	}
+	return matching
+}
+
+// allFlags returns the flags of the node and its parents, and the flags of its default command
+func allFlags(node *kong.Node) []*kong.Flag {
+	var flags []*kong.Flag
+	for _, group := range node.AllFlags(false) {
+		flags = append(flags, group...)
+	}
+	if node.DefaultCmd != nil {
+		flags = append(flags, node.DefaultCmd.Flags...)
+	}
+	return flags
+}
+
+func findFlag(node *kong.Node, name string, short bool) *kong.Flag {
+	for _, flag := range allFlags(node) {
+		if short && len(name) == 1 && flag.Short == rune(name[0]) || !short && flag.Name == name {
+			return flag
+		}
+	}
+	return nil
+}
+
+func findCommand(node *kong.Node, name string) *kong.Node {
+	for _, child := range node.Children {
+		if child.Name == name || lo.Contains(child.Aliases, name) {
+			return child
+		}
+	}
+	return nil
+}
+
+func positionalAt(node *kong.Node, position int) *kong.Value {
+	switch {
+	case len(node.Positional) == 0:
+		return nil
+	case position < len(node.Positional):
+		return node.Positional[position]
+	case node.Positional[len(node.Positional)-1].IsCumulative():
+		return node.Positional[len(node.Positional)-1]
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplete(t *testing.T) {
+	parser, err := kong.New(&commands{})
+	require.NoError(t, err)
+
+	var templatesDir string
+	templateNames := func(dir string) []string {
+		templatesDir = dir
+		return []string{"custom-rules", "youtube-cleanup", "youtube-shorts"}
+	}
+
+	tests := map[string]struct {
+		words    []string
+		expected []string
+	}{
+		"commands":          {words: []string{"te"}, expected: []string{"templates", "test"}},
+		"hidden command":    {words: []string{"__"}, expected: []string{}},
+		"subcommands":       {words: []string{"templates", ""}, expected: []string{"list", "show"}},
+		"template argument": {words: []string{"templates", "show", "you"}, expected: []string{"youtube-cleanup", "youtube-shorts"}},
+		"second argument":   {words: []string{"templates", "show", "custom-rules", ""}, expected: []string{}},
+		"default flags":     {words: []string{"--ex"}, expected: []string{"--exclude"}},
+		"global flags":      {words: []string{"diff", "--ver"}, expected: []string{"--verbose"}},
+		"template flag":     {words: []string{"--only", "cu"}, expected: []string{"custom-rules"}},
+		"enum flag":         {words: []string{"validate", "--format", ""}, expected: []string{"text", "json"}},
+		"enum argument":     {words: []string{"completion", "z"}, expected: []string{"zsh"}},
+		"file argument":     {words: []string{"-v", "-o", "out.txt", "my-list"}, expected: []string{}},
+		"file flag value":   {words: []string{"serve", "--templates-dir", ""}, expected: nil},
+		"no words":          {words: nil, expected: []string{"render", "diff", "serve", "validate", "templates", "test", "version", "completion"}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, complete(parser.Model.Node, tc.words, templateNames))
+		})
+	}
+
+	complete(parser.Model.Node, []string{"--templates-dir", "my/templates", "test", ""}, templateNames)
+	assert.Equal(t, "my/templates", templatesDir)
+	complete(parser.Model.Node, []string{"test", "--templates-dir=other", ""}, templateNames)
+	assert.Equal(t, "other", templatesDir)
+}
+
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		out, _, err := runCLI(t, "", "completion", shell)
+		assert.NoError(t, err)
+		assert.Contains(t, out, "__complete")
+		assert.NotContains(t, out, "{{name}}")
+	}
+
+	out, _, err := runCLI(t, "", "__complete", "templates", "show", "custom-")
+	assert.NoError(t, err)
+	assert.Equal(t, "custom-rules\n", out)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+var errOutputsDiffer = errors.New("rendered outputs differ")
+
+type diffCmd struct {
+	IncludeComments bool     `help:"include comments and section headers in the comparison"`
+	AgainstGit      string   `placeholder:"REV" help:"compare a single file against its version at the given git revision"`
+	Strict          bool     `help:"validate the input data before rendering the outputs"`
+	Files           []string `arg:"" type:"existingfile" help:"list files to compare, or a single file when using --against-git"`
+}
+
+// Validate is called by kong after parsing the command line
+func (c *diffCmd) Validate() error {
+	switch {
+	case c.AgainstGit != "" && len(c.Files) != 1:
+		return errors.New("--against-git requires exactly one list file")
+	case c.AgainstGit == "" && len(c.Files) != 2:
+		return errors.New("exactly two list files are required")
+	}
+	return nil
+}
+
+func (c *diffCmd) Help() string {
+	return `Examples:
+
+    # Compare two files
+    render diff before.yaml after.yaml
+    # Compare your uncommitted changes with the last commit
+    render diff --against-git HEAD my-list.yaml`
+}
+
+func (c *diffCmd) Run(g *globals) error {
+	repo, err := g.loadRepository()
+	if err != nil {
+		return err
+	}
+
+	var before, after []string
+	var beforeName, afterName string
+	if c.AgainstGit != "" {
+		contents, err := readGitRevision(c.Files[0], c.AgainstGit)
+		if err != nil {
+			return err
+		}
+		beforeName, afterName = c.Files[0]+"@"+c.AgainstGit, c.Files[0]
+		if before, err = c.renderLines(bytes.NewReader(contents), beforeName, repo); err != nil {
+			return fmt.Errorf("cannot render %s: %w", beforeName, err)
+		}
+	} else {
+		beforeName, afterName = c.Files[0], c.Files[1]
+		if before, err = c.renderFileLines(beforeName, repo); err != nil {
+			return err
+		}
+	}
+	if after, err = c.renderFileLines(afterName, repo); err != nil {
+		return err
+	}
+
+	added, removed := countChanges(before, after)
+	if added == 0 && removed == 0 {
+		_, err = fmt.Fprintln(stderr, "No changes in the rendered output")
+		return err
+	}
+	if err = difflib.WriteUnifiedDiff(stdout, difflib.UnifiedDiff{
+		A:        before,
+		B:        after,
+		FromFile: beforeName,
+		ToFile:   afterName,
+		Context:  3,
+	}); err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(stderr, "%d rules added, %d rules removed\n", added, removed); err != nil {
+		return err
+	}
+	return errOutputsDiffer
+}
+
+func (c *diffCmd) renderFileLines(name string, repo *filters.Repository) ([]string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open input file: %w", err)
+	}
+	defer file.Close()
+	lines, err := c.renderLines(file, name, repo)
+	if err != nil {
+		return nil, fmt.Errorf("cannot render %s: %w", name, err)
+	}
+	return lines, nil
+}
+
+// renderLines renders a list and splits its output in newline-terminated lines,
+// removing comments and blank lines unless IncludeComments is set.
+func (c *diffCmd) renderLines(input io.Reader, name string, repo *filters.Repository) ([]string, error) {
+	var buf bytes.Buffer
+	if err := renderList(input, name, &buf, repo, c.Strict); err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !c.IncludeComments && (strings.TrimSpace(line) == "" || strings.HasPrefix(line, "!")) {
+			continue
+		}
+		lines = append(lines, line+"\n")
+	}
+	return lines, scanner.Err()
+}
+
+func countChanges(before, after []string) (added, removed int) {
+	for _, op := range difflib.NewMatcher(before, after).GetOpCodes() {
+		switch op.Tag {
+		case 'r':
+			removed += op.I2 - op.I1
+			added += op.J2 - op.J1
+		case 'd':
+			removed += op.I2 - op.I1
+		case 'i':
+			added += op.J2 - op.J1
+		}
+	}
+	return
+}
+
+// readGitRevision retrieves the contents of a file at a given git revision
+func readGitRevision(name, revision string) ([]byte, error) {
+	cmd := exec.Command("git", "show", revision+":./"+filepath.Base(name))
+	cmd.Dir = filepath.Dir(name)
+	var errOut bytes.Buffer
+	cmd.Stderr = &errOut
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s at revision %s: %w: %s", name, revision, err, strings.TrimSpace(errOut.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff_NoChanges(t *testing.T) {
+	out, err := strings.Builder{}, strings.Builder{}
+	stdout = &out
+	stderr = &err
+
+	cmd := &diffCmd{Files: []string{"testdata/input.yaml", "testdata/input.yaml"}}
+	assert.NoError(t, cmd.Run(&globals{}))
+	assert.Empty(t, out.String())
+	assert.Contains(t, err.String(), "No changes in the rendered output\n")
+}
+
+func TestDiff_Changes(t *testing.T) {
+	out, err := strings.Builder{}, strings.Builder{}
+	stdout = &out
+	stderr = &err
+
+	cmd := &diffCmd{Files: []string{"testdata/input.yaml", "testdata/modified.yaml"}}
+	assert.ErrorIs(t, cmd.Run(&globals{}), errOutputsDiffer)
+	assert.Equal(t, `--- testdata/input.yaml
++++ testdata/modified.yaml
+@@ -1,2 +1,2 @@
+ line1##ruleA
+-line2###ruleB
++line3###ruleC
+`, out.String())
+	assert.Contains(t, err.String(), "1 rules added, 1 rules removed\n")
+}
+
+func TestDiff_IncludeComments(t *testing.T) {
+	out := strings.Builder{}
+	stdout = &out
+	stderr = &strings.Builder{}
+
+	cmd := &diffCmd{
+		Files:           []string{"testdata/input.yaml", "testdata/modified.yaml"},
+		IncludeComments: true,
+	}
+	assert.ErrorIs(t, cmd.Run(&globals{}), errOutputsDiffer)
+	assert.Contains(t, out.String(), "-! Title: letsblock.it - locally rendered list\n+! Title: letsblock.it - modified list\n")
+	assert.Contains(t, out.String(), "-! unknown\n")
+}
+
+func TestDiff_Validate(t *testing.T) {
+	assert.NoError(t, (&diffCmd{Files: []string{"a", "b"}}).Validate())
+	assert.NoError(t, (&diffCmd{Files: []string{"a"}, AgainstGit: "HEAD"}).Validate())
+	assert.Error(t, (&diffCmd{Files: []string{"a"}}).Validate())
+	assert.Error(t, (&diffCmd{Files: []string{"a", "b"}, AgainstGit: "HEAD"}).Validate())
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/letsblockit/letsblockit/src/filters"
+)
+
+// Only the braced form is supported, as $ is common in filter rules
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// substituteEnv expands ${VAR} references in the string parameter values of the list.
+// Unset variables are expanded to an empty string if allowMissing is set, else an error is returned.
+func substituteEnv(list *filters.List, name string, allowMissing bool) error {
+	var details []errorDetail
+	for _, instance := range list.Instances {
+		if instance == nil {
+			continue // Reported by list.Validate
+		}
+		for param, value := range instance.Params {
+			var missing []string
+			instance.Params[param] = expandValue(value, func(key string) string {
+				v, found := os.LookupEnv(key)
+				if !found && !allowMissing {
+					missing = append(missing, key)
+				}
+				return v
+			})
+			for _, key := range missing {
+				details = append(details, errorDetail{
+					Template:  instance.Template,
+					Parameter: param,
+					Message:   fmt.Sprintf("environment variable %s is not set", key),
+				})
+			}
+		}
+	}
+	if len(details) == 0 {
+		return nil
+	}
+
+	sort.Slice(details, func(i, j int) bool {
+		return details[i].String() < details[j].String()
+	})
+	messages := make([]string, 0, len(details))
+	for _, d := range details {
+		messages = append(messages, d.String())
+	}
+	return newValidationError(fmt.Errorf("cannot substitute variables in %s: %s", name, strings.Join(messages, "; ")), details)
+}
+
+// expandValue expands references in strings, including inside lists, other types are returned as-is
+func expandValue(value interface{}, lookup func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return envReference.ReplaceAllStringFunc(v, func(ref string) string {
+			return lookup(envReference.FindStringSubmatch(ref)[1])
+		})
+	case []interface{}:
+		expanded := make([]interface{}, len(v))
+		for i, item := range v {
+			expanded[i] = expandValue(item, lookup)
+		}
+		return expanded
+	case []string:
+		expanded := make([]string, len(v))
+		for i, item := range v {
+			expanded[i] = expandValue(item, lookup).(string)
+		}
+		return expanded
+	default:
+		return value
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const envInput = `title: env
+instances:
+  - template: custom-rules
+    params:
+      rules: |
+        ${LBI_TEST_HOST}##.banner
+        ||ads.example.com^$all
+  - template: ${LBI_TEST_HOST}
+`
+
+func TestEnvSubst(t *testing.T) {
+	t.Setenv("LBI_TEST_HOST", "intranet.example.com")
+
+	out, _, err := runCLI(t, envInput, "--env-subst")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "\nintranet.example.com##.banner\n||ads.example.com^$all\n")
+	assert.Contains(t, out, "\n! ${LBI_TEST_HOST}\n", "template names must not be expanded")
+}
+
+func TestEnvSubst_NotFlagged(t *testing.T) {
+	t.Setenv("LBI_TEST_HOST", "intranet.example.com")
+
+	out, _, err := runCLI(t, envInput)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "\n${LBI_TEST_HOST}##.banner\n")
+	assert.NotContains(t, out, "intranet.example.com")
+}
+
+func TestEnvSubst_Missing(t *testing.T) {
+	_, _, err := runCLI(t, envInput, "--env-subst")
+	assert.EqualError(t, err, "cannot substitute variables in stdin: custom-rules: parameter rules: environment variable LBI_TEST_HOST is not set")
+
+	out, _, err := runCLI(t, envInput, "--env-subst", "--allow-missing-env")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "\n##.banner\n")
+}
+
+func TestExpandValue(t *testing.T) {
+	lookup := func(key string) string { return "<" + key + ">" }
+	for input, expected := range map[interface{}]interface{}{
+		"${A} and ${B_2}": "<A> and <B_2>",
+		"$A ${} $${C":     "$A ${} $${C",
+		true:              true,
+	} {
+		assert.Equal(t, expected, expandValue(input, lookup))
+	}
+	assert.Equal(t, []interface{}{"<A>", 42}, expandValue([]interface{}{"${A}", 42}, lookup))
+	assert.Equal(t, []string{"<A>", "b"}, expandValue([]string{"${A}", "b"}, lookup))
+}
@@ -1,69 +1,388 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/letsblockit/letsblockit/data"
 	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/samber/lo"
 	"gopkg.in/yaml.v3"
 )
 
-// Alias outputs to allow capturing them in tests
+// Alias inputs, outputs and clock to allow capturing them in tests
 var (
+	stdin  io.Reader = os.Stdin
 	stdout io.Writer = os.Stdout
 	stderr io.Writer = os.Stderr
+	now              = time.Now
 )
 
+const stdinName = "stdin"
+
+var (
+	errNoInput     = errors.New("no input file given and stdin is a terminal, pass - to read from it anyway")
+	errStdinReused = errors.New("stdin can only be used as one of the input files")
+)
+
+const defaultOutputMode os.FileMode = 0644
+
 type renderCmd struct {
-	Strict bool   `help:"validate the input data before rendering the output"`
-	Input  string `default:"-" help:"input file to use, defaults to stdin" arg:"" type:"existingfile"`
+	Strict          bool          `help:"validate the input data before rendering the output"`
+	Watch           bool          `help:"re-render the output file every time the input file changes, requires --output"`
+	Interval        time.Duration `placeholder:"24h" help:"re-render the output file periodically, only rewriting it if its contents change, requires --output"`
+	OnceIfChanged   bool          `help:"render the output file once, only rewriting it if its contents change, requires --output"`
+	Output          string        `short:"o" xor:"output" help:"output file to write to, only replaced if the rendering succeeds" type:"path"`
+	Stdout          bool          `xor:"output" help:"write the output to stdout, this is the default"`
+	Only            []string      `placeholder:"TEMPLATE,..." completion:"templates" help:"only render instances of the given templates"`
+	Exclude         []string      `placeholder:"TEMPLATE,..." completion:"templates" help:"do not render instances of the given templates"`
+	From            string        `placeholder:"URL" help:"fetch the list definition from a server, for example https://letsblock.it/api/list/<token>"`
+	EnvSubst        bool          `help:"expand $${VAR} references in parameter values with environment variables"`
+	AllowMissingEnv bool          `help:"expand unset environment variables to an empty string instead of failing"`
+	Format          string        `enum:"text,json" default:"text" help:"report format on stderr: text, or a json result or error object"`
+	StrictMerge     bool          `help:"fail if a template other than custom-rules is defined in several input files"`
+	EmitMerged      string        `placeholder:"FILE" help:"write the merged list definition to a YAML file" type:"path"`
+	SafeMode        bool          `help:"comment out the rendered lines with an invalid rule syntax, prefixing them with \"! INVALID: \""`
+	Inputs          []string      `optional:"" help:"input files to merge and render, pass - or pipe the list to read from stdin" arg:"" name:"input" type:"existingfile"`
 }
 
-type logger struct{}
+// logger prints warnings to stderr, or stores them if capture is set
+type logger struct {
+	capture  bool
+	warnings []string
+}
 
 func (l *logger) Warnf(format string, args ...interface{}) {
+	if l.capture {
+		l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+		return
+	}
 	if _, err := fmt.Fprintf(stderr, "WARNING: "+format+"\n", args...); err != nil {
 		panic(err)
 	}
 }
 
-func (c *renderCmd) Run() error {
-	var err error
-	var input io.Reader
-	if c.Input == "-" {
-		input = os.Stdin
+type globals struct {
+	TemplatesDir string `type:"existingdir" placeholder:"DIR" help:"load templates from a local folder, replacing the embedded templates with the same name. Presets are read from its sibling presets folder."`
+	Verbose      bool   `short:"v" help:"print the source of every loaded template"`
+}
+
+type commands struct {
+	globals
+	Render    renderCmd    `cmd:"" default:"withargs" help:"Render a list file into a filter list, this is the default command."`
+	Diff      diffCmd      `cmd:"" help:"Compare the rendered output of two list files."`
+	Serve     serveCmd     `cmd:"" help:"Serve the rendered list over HTTP, re-rendering it when the list files change."`
+	Validate  validateCmd  `cmd:"" help:"Check a list file against the filter template definitions."`
+	Templates templatesCmd `cmd:"" help:"Show the available filter templates and their parameters."`
+	Test      testCmd      `cmd:"" help:"Run the test cases declared by the filter templates."`
+	Version   versionCmd   `cmd:"" help:"Show the version of the binary and of its embedded templates."`
+
+	Completion completionCmd `cmd:"" help:"Print the shell completion script for bash, zsh or fish."`
+	Complete   completeCmd   `cmd:"" name:"__complete" hidden:"" passthrough:""`
+}
+
+// loadRepository loads the embedded templates, then the ones in TemplatesDir if set
+func (g *globals) loadRepository() (*filters.Repository, error) {
+	sources := []filters.Source{{Name: "embedded", Templates: data.Templates, Presets: data.Presets}}
+	if g.TemplatesDir != "" {
+		sources = append(sources, filters.DirSource(g.TemplatesDir))
+	}
+	repo, err := filters.LoadSources(sources...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load filter templates: %w", err)
+	}
+	if g.Verbose {
+		for _, tpl := range repo.GetAll() {
+			if _, err = fmt.Fprintf(stderr, "Loaded template %s from %s\n", tpl.Name, repo.Source(tpl.Name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return repo, nil
+}
+
+// Validate is called by kong after parsing the command line
+func (c *renderCmd) Validate() error {
+	switch {
+	case (c.Interval > 0 || c.OnceIfChanged) && c.Output == "":
+		return errors.New("--interval and --once-if-changed require --output")
+	case c.OnceIfChanged && (c.Watch || c.Interval > 0):
+		return errors.New("--once-if-changed cannot be used with --watch or --interval")
+	case c.Interval < 0:
+		return errors.New("--interval must be positive")
+	}
+	return nil
+}
+
+// onlyIfChanged returns whether the output file should be kept as-is if its contents would not change
+func (c *renderCmd) onlyIfChanged() bool {
+	return c.Interval > 0 || c.OnceIfChanged
+}
+
+func (c *renderCmd) Help() string {
+	return `Examples:
+
+    # Render a list file to stdout
+    render my-list.yaml > output.txt
+    # Merge two list files into an output file, re-rendering it on changes
+    render --watch -o output.txt alice.yaml bob.yaml
+    # Render a list stored on a server
+    render --from https://letsblock.it/api/list/<token>`
+}
+
+func (c *renderCmd) Run(g *globals) error {
+	repo, err := g.loadRepository()
+	if err != nil {
+		return err
+	}
+
+	if c.Watch || c.Interval > 0 {
+		return c.watch(g, repo)
+	}
+
+	var result *renderResult
+	if c.Output != "" && !c.Stdout {
+		result, err = c.renderToFile(repo)
 	} else {
-		input, err = os.Open(c.Input)
+		result, err = c.render(stdout, repo)
+	}
+	if c.Format == "json" {
+		return reportJSON(result, err)
+	}
+	return err
+}
+
+// render reads the list definition and renders it into out
+func (c *renderCmd) render(out io.Writer, repo *filters.Repository) (*renderResult, error) {
+	list, name, err := c.readList(repo)
+	if err != nil {
+		return nil, err
+	}
+	if c.EnvSubst {
+		if err = substituteEnv(list, name, c.AllowMissingEnv); err != nil {
+			return nil, err
+		}
+	}
+	if c.Strict {
+		if err = checkList(list, repo, name); err != nil {
+			return nil, err
+		}
+	}
+	if err = list.Subset(c.Only, c.Exclude); err != nil {
+		return nil, newValidationError(err, nil)
+	}
+
+	list.SafeMode = c.SafeMode
+	log := &logger{capture: c.Format == "json"}
+	counter := &ruleCounter{out: out}
+	if err = list.Render(counter, log, repo); err != nil {
+		return nil, newRenderError(fmt.Errorf("cannot render %s: %w", name, err))
+	}
+	return &renderResult{
+		Instances:   len(list.Instances),
+		Rules:       counter.rules,
+		Neutralized: list.Neutralized,
+		Output:      "stdout",
+		Warnings:    log.warnings,
+	}, nil
+}
+
+// readList reads the list definition from the server, stdin or the input files.
+// It returns the name of the input, to use in error messages.
+func (c *renderCmd) readList(repo *filters.Repository) (*filters.List, string, error) {
+	if c.From != "" {
+		list, err := c.fetchList(repo)
+		return list, c.From, err
+	}
+
+	paths := c.Inputs
+	if len(paths) == 0 {
+		paths = []string{""}
+	} else if lo.Count(paths, "-") > 1 {
+		return nil, "", errStdinReused
+	}
+	lists, names := make([]*filters.List, 0, len(paths)), make([]string, 0, len(paths))
+	for _, path := range paths {
+		list, name, err := readListFile(path)
 		if err != nil {
-			return fmt.Errorf("cannot open input file: %w", err)
+			return nil, "", err
 		}
+		lists, names = append(lists, list), append(names, name)
 	}
 
-	repo, err := filters.Load(data.Templates, data.Presets)
+	list, name := lists[0], names[0]
+	if len(lists) > 1 {
+		var err error
+		if list, err = mergeLists(lists, names, c.StrictMerge); err != nil {
+			return nil, "", err
+		}
+		name = strings.Join(names, ", ")
+	}
+	if c.EmitMerged != "" {
+		if err := writeList(list, c.EmitMerged); err != nil {
+			return nil, "", err
+		}
+	}
+	return list, name, nil
+}
+
+func readListFile(path string) (*filters.List, string, error) {
+	input, name, err := openInput(path)
 	if err != nil {
-		return fmt.Errorf("cannot load filter templates: %w", err)
+		return nil, "", err
 	}
-	var list filters.List
-	err = yaml.NewDecoder(input).Decode(&list)
+	defer input.Close()
+	list, err := decodeList(input, name)
+	return list, name, err
+}
+
+// openInput opens the given input file, or stdin if path is "-".
+// If path is empty, stdin is used if it is not a terminal.
+func openInput(path string) (io.ReadCloser, string, error) {
+	switch path {
+	case "":
+		if isTerminal(stdin) {
+			return nil, "", errNoInput
+		}
+		return io.NopCloser(stdin), stdinName, nil
+	case "-":
+		return io.NopCloser(stdin), stdinName, nil
+	}
+	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("cannot decode input file: %w", err)
+		return nil, "", fmt.Errorf("cannot open input file: %w", err)
 	}
+	return file, path, nil
+}
 
-	if c.Strict {
-		err = list.Validate()
-		if err != nil {
-			return fmt.Errorf("invalid input data: %w", err)
+func isTerminal(r io.Reader) bool {
+	file, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// renderList decodes a list definition from input and renders it into out
+func renderList(input io.Reader, name string, out io.Writer, repo *filters.Repository, strict bool) error {
+	list, err := decodeList(input, name)
+	if err != nil {
+		return err
+	}
+	if strict {
+		if err = checkList(list, repo, name); err != nil {
+			return err
+		}
+	}
+	return list.Render(out, &logger{}, repo)
+}
+
+// decodeList decodes a list definition, the input name is used in error messages
+func decodeList(input io.Reader, name string) (*filters.List, error) {
+	var list filters.List
+	if err := yaml.NewDecoder(input).Decode(&list); err != nil {
+		return nil, newParseError(fmt.Errorf("cannot decode %s: %w", name, err))
+	}
+	return &list, nil
+}
+
+// checkList validates the list structure and the parameters of the instances,
+// unknown templates are only reported as warnings
+func checkList(list *filters.List, repo *filters.Repository, name string) error {
+	details := structureErrors(list)
+	for _, instance := range list.Instances {
+		if instance == nil {
+			continue // Reported by structureErrors
 		}
+		if tpl, err := repo.Get(instance.Template); err == nil {
+			for _, e := range tpl.ValidateParams(instance.Params) {
+				details = append(details, paramErrorDetail(instance.Template, e))
+			}
+		}
+	}
+	if len(details) == 0 {
+		return nil
 	}
+	messages := make([]string, 0, len(details))
+	for _, d := range details {
+		messages = append(messages, d.String())
+	}
+	return newValidationError(fmt.Errorf("invalid input data in %s: %s", name, strings.Join(messages, "; ")), details)
+}
 
-	return list.Render(stdout, &logger{}, repo)
+// renderToFile renders into a temporary file, then moves it to the output path.
+// Readers of the output path never see a partially written file, and the
+// previous file is kept in place if the rendering fails, or if it is unchanged
+// and onlyIfChanged is set.
+func (c *renderCmd) renderToFile(repo *filters.Repository) (*renderResult, error) {
+	mode := defaultOutputMode
+	if info, err := os.Stat(c.Output); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.Output), "."+filepath.Base(c.Output)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	result, err := c.render(tmp, repo)
+	if err != nil {
+		_ = tmp.Close()
+		return nil, err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		return nil, fmt.Errorf("cannot set output file permissions: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return nil, fmt.Errorf("cannot write temporary file: %w", err)
+	}
+	if c.onlyIfChanged() && sameContents(tmp.Name(), c.Output) {
+		result.Output, result.Unchanged = c.Output, true
+		return result, nil
+	}
+
+	// Explicitly bump the mtime for downstream "newer than" checks
+	ts := now()
+	if err = os.Chtimes(tmp.Name(), ts, ts); err != nil {
+		return nil, fmt.Errorf("cannot set output file mtime: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), c.Output); err != nil {
+		return nil, fmt.Errorf("cannot move output file in place: %w", err)
+	}
+	result.Output = c.Output
+	return result, nil
+}
+
+// sameContents returns whether both files exist and have the same contents
+func sameContents(a, b string) bool {
+	contentsA, err := os.ReadFile(a)
+	if err != nil {
+		return false
+	}
+	contentsB, err := os.ReadFile(b)
+	return err == nil && bytes.Equal(contentsA, contentsB)
 }
 
 func main() {
-	cmd := &renderCmd{}
-	k := kong.Parse(cmd)
-	k.FatalIfErrorf(cmd.Run())
+	cli := &commands{}
+	k := kong.Parse(cli)
+	err := k.Run(&cli.globals)
+	var cmdErr *commandError
+	if errors.As(err, &cmdErr) {
+		if !cmdErr.reported {
+			k.Errorf("%s", err)
+		}
+		k.Exit(cmdErr.code)
+	}
+	k.FatalIfErrorf(err)
 }
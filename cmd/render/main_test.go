@@ -2,24 +2,207 @@ package main
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/alecthomas/kong"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// runCLI parses the command line arguments and runs the selected command with the given stdin contents
+func runCLI(t *testing.T, input string, args ...string) (string, string, error) {
+	t.Helper()
+	out, errOut := strings.Builder{}, strings.Builder{}
+	stdin = strings.NewReader(input)
+	stdout = &out
+	stderr = &errOut
+	defer func() { stdin = os.Stdin }()
+
+	cli := &commands{}
+	parser, err := kong.New(cli)
+	require.NoError(t, err)
+	ctx, err := parser.Parse(args)
+	require.NoError(t, err)
+	err = ctx.Run(&cli.globals)
+	return out.String(), errOut.String(), err
+}
+
+func TestRenderFromStdin(t *testing.T) {
+	input, err := os.ReadFile("testdata/input.yaml")
+	require.NoError(t, err)
+	expected, err := os.ReadFile("testdata/expected.txt")
+	require.NoError(t, err)
+
+	for name, args := range map[string][]string{
+		"no args":      {},
+		"dash":         {"-"},
+		"render":       {"render"},
+		"render dash":  {"render", "-"},
+		"strict flags": {"--strict", "--stdout"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			out, errOut, err := runCLI(t, string(input), args...)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), out)
+			assert.Equal(t, "WARNING: skipping unknown: template 'unknown' not found\n", errOut)
+		})
+	}
+}
+
+func TestRenderFromStdin_Errors(t *testing.T) {
+	_, _, err := runCLI(t, "title: [invalid")
+	assert.ErrorContains(t, err, "cannot decode stdin: ")
+
+	_, _, err = runCLI(t, "instances: []", "--strict")
+	assert.ErrorContains(t, err, "invalid input data in stdin: ")
+
+	out, _, err := runCLI(t, "instances: []", "validate")
+	assert.ErrorIs(t, err, errValidationFailed)
+	assert.Equal(t, "stdin: List.Title: failed on the 'required' rule\n", out)
+}
+
 func TestRenderFromFile(t *testing.T) {
 	out, err := strings.Builder{}, strings.Builder{}
 	stdout = &out
 	stderr = &err
 
 	cmd := &renderCmd{
-		Input: "testdata/input.yaml",
+		Inputs: []string{"testdata/input.yaml"},
 	}
-	assert.NoError(t, cmd.Run())
+	assert.NoError(t, cmd.Run(&globals{}))
 
 	expected, e := os.ReadFile("testdata/expected.txt")
 	assert.NoError(t, e)
 	assert.Equal(t, string(expected), out.String())
 	assert.Equal(t, "WARNING: skipping unknown: template 'unknown' not found\n", err.String())
 }
+
+func TestRenderToFile(t *testing.T) {
+	stderr = &strings.Builder{}
+	fixedNow := time.Date(2020, 06, 02, 17, 44, 22, 0, time.UTC)
+	now = func() time.Time { return fixedNow }
+	defer func() { now = time.Now }()
+
+	cmd := &renderCmd{
+		Inputs: []string{"testdata/input.yaml"},
+		Output: filepath.Join(t.TempDir(), "output.txt"),
+	}
+	assert.NoError(t, cmd.Run(&globals{}))
+
+	expected, e := os.ReadFile("testdata/expected.txt")
+	assert.NoError(t, e)
+	out, e := os.ReadFile(cmd.Output)
+	assert.NoError(t, e)
+	assert.Equal(t, string(expected), string(out))
+
+	info, e := os.Stat(cmd.Output)
+	assert.NoError(t, e)
+	assert.Equal(t, defaultOutputMode, info.Mode().Perm())
+	assert.Equal(t, fixedNow, info.ModTime().UTC())
+}
+
+func TestRenderToFile_KeepPreviousOnError(t *testing.T) {
+	dir := t.TempDir()
+	cmd := &renderCmd{
+		Inputs: []string{filepath.Join(dir, "input.yaml")},
+		Output: filepath.Join(dir, "output.txt"),
+	}
+	require.NoError(t, os.WriteFile(cmd.Inputs[0], []byte("title: [invalid"), 0600))
+	require.NoError(t, os.WriteFile(cmd.Output, []byte("previous"), 0640))
+
+	assert.ErrorContains(t, cmd.Run(&globals{}), "cannot decode "+cmd.Inputs[0])
+	out, e := os.ReadFile(cmd.Output)
+	assert.NoError(t, e)
+	assert.Equal(t, "previous", string(out))
+
+	entries, e := os.ReadDir(dir)
+	assert.NoError(t, e)
+	assert.Len(t, entries, 2, "temporary file was not cleaned up")
+}
+
+func TestRenderSubset(t *testing.T) {
+	out, err := strings.Builder{}, strings.Builder{}
+	stdout = &out
+	stderr = &err
+
+	cmd := &renderCmd{
+		Inputs:  []string{"testdata/input.yaml"},
+		Exclude: []string{"unknown"},
+	}
+	assert.NoError(t, cmd.Run(&globals{}))
+	assert.Equal(t, `! Title: letsblock.it - locally rendered list
+! Expires: 12 hours
+! Homepage: https://letsblock.it
+! License: https://github.com/letsblockit/letsblockit/blob/main/LICENSE.txt
+
+! custom-rules
+line1##ruleA
+line2###ruleB
+`, out.String())
+	assert.Empty(t, err.String())
+
+	cmd = &renderCmd{
+		Inputs: []string{"testdata/input.yaml"},
+		Only:   []string{"custom-rules", "other"},
+	}
+	assert.EqualError(t, cmd.Run(&globals{}), "no instance found for templates: other")
+}
+
+func TestRenderSafeMode(t *testing.T) {
+	input := `title: safe
+instances:
+  - template: custom-rules
+    params:
+      rules: |
+        example.com##.ad
+        example.com##div:has(.ad
+`
+	out, errOut, err := runCLI(t, input, "--safe-mode", "--format", "json")
+	require.NoError(t, err)
+	assert.Contains(t, out, "\nexample.com##.ad\n! INVALID: example.com##div:has(.ad\n")
+	assert.JSONEq(t, `{"instances": 1, "rules": 1, "output": "stdout", "neutralized": 1}`, errOut)
+
+	out, _, err = runCLI(t, input)
+	require.NoError(t, err)
+	assert.Contains(t, out, "\nexample.com##div:has(.ad\n")
+}
+
+func TestRenderToFile_OnceIfChanged(t *testing.T) {
+	stderr = &strings.Builder{}
+	dir := t.TempDir()
+	cmd := &renderCmd{
+		Inputs:        []string{filepath.Join(dir, "input.yaml")},
+		Output:        filepath.Join(dir, "output.txt"),
+		OnceIfChanged: true,
+	}
+	writeInput := func(title string) {
+		require.NoError(t, os.WriteFile(cmd.Inputs[0], []byte("title: "+title+"\n"), 0600))
+	}
+	renderAt := func(ts time.Time) time.Time {
+		now = func() time.Time { return ts }
+		defer func() { now = time.Now }()
+		require.NoError(t, cmd.Run(&globals{}))
+		info, err := os.Stat(cmd.Output)
+		require.NoError(t, err)
+		return info.ModTime().UTC()
+	}
+	first := time.Date(2020, 06, 02, 17, 44, 22, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	writeInput("first")
+	assert.Equal(t, first, renderAt(first), "missing output file is written")
+	assert.Equal(t, first, renderAt(second), "unchanged output file is kept")
+	writeInput("second")
+	assert.Equal(t, second, renderAt(second), "changed output file is written")
+}
+
+func TestRenderCmd_Validate(t *testing.T) {
+	assert.NoError(t, (&renderCmd{Output: "out.txt", Interval: time.Hour, Watch: true}).Validate())
+	assert.NoError(t, (&renderCmd{Output: "out.txt", OnceIfChanged: true}).Validate())
+	assert.Error(t, (&renderCmd{Interval: time.Hour}).Validate())
+	assert.Error(t, (&renderCmd{OnceIfChanged: true}).Validate())
+	assert.Error(t, (&renderCmd{Output: "out.txt", OnceIfChanged: true, Watch: true}).Validate())
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/letsblockit/letsblockit/src/filters"
+	"gopkg.in/yaml.v3"
+)
+
+const customRulesParam = "rules"
+
+// mergeSlot holds the merged instances of a template, at the position of its first definition
+type mergeSlot struct {
+	file      int // Index of the file the instances come from
+	instances []*filters.Instance
+}
+
+// mergeLists merges the instances of several lists, in order. The rules of custom-rules
+// instances are concatenated. For other templates, the instances of the last file win,
+// unless strict is set, where defining a template in several files is an error.
+// Several instances of a template in the same file are all kept.
+func mergeLists(lists []*filters.List, names []string, strict bool) (*filters.List, error) {
+	merged := &filters.List{}
+	var slots []*mergeSlot
+	byTemplate := make(map[string]*mergeSlot)
+	for i, list := range lists {
+		if list.Title != "" {
+			merged.Title = list.Title
+		}
+		merged.TestMode = merged.TestMode || list.TestMode
+		for _, instance := range list.Instances {
+			if instance == nil {
+				// Reported by list.Validate
+				slots = append(slots, &mergeSlot{file: i, instances: []*filters.Instance{nil}})
+				continue
+			}
+			slot, found := byTemplate[instance.Template]
+			switch {
+			case !found:
+				slot = &mergeSlot{file: i, instances: []*filters.Instance{copyInstance(instance)}}
+				byTemplate[instance.Template] = slot
+				slots = append(slots, slot)
+			case instance.Template == filters.CustomRulesFilterName:
+				if !appendRules(slot.instances[0], instance) {
+					slots = append(slots, &mergeSlot{file: i, instances: []*filters.Instance{copyInstance(instance)}})
+				}
+			case slot.file == i:
+				slot.instances = append(slot.instances, copyInstance(instance))
+			case strict:
+				return nil, newValidationError(fmt.Errorf("template %s is defined in several files, including %s", instance.Template, names[i]), nil)
+			default:
+				slot.file, slot.instances = i, []*filters.Instance{copyInstance(instance)}
+			}
+		}
+	}
+	for _, slot := range slots {
+		merged.Instances = append(merged.Instances, slot.instances...)
+	}
+	return merged, nil
+}
+
+// appendRules appends the custom rules of src into dst, returning false if any of them is not a string
+func appendRules(dst, src *filters.Instance) bool {
+	dstRules, ok := dst.Params[customRulesParam].(string)
+	if !ok && dst.Params[customRulesParam] != nil {
+		return false
+	}
+	srcRules, ok := src.Params[customRulesParam].(string)
+	if !ok && src.Params[customRulesParam] != nil {
+		return false
+	}
+	if dstRules != "" && !strings.HasSuffix(dstRules, "\n") {
+		dstRules += "\n"
+	}
+	if dst.Params == nil {
+		dst.Params = make(map[string]interface{})
+	}
+	dst.Params[customRulesParam] = dstRules + srcRules
+	return true
+}
+
+func copyInstance(instance *filters.Instance) *filters.Instance {
+	params := make(map[string]interface{}, len(instance.Params))
+	for k, v := range instance.Params {
+		params[k] = v
+	}
+	return &filters.Instance{
+		Template: instance.Template,
+		Params:   params,
+		TestMode: instance.TestMode,
+	}
+}
+
+// writeList writes the list definition as a YAML file
+func writeList(list *filters.List, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cannot create merged list file: %w", err)
+	}
+	encoder := yaml.NewEncoder(file)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(list); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("cannot write merged list file: %w", err)
+	}
+	if err = encoder.Close(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("cannot write merged list file: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeFiles(t *testing.T) {
+	merged := filepath.Join(t.TempDir(), "merged.yaml")
+	out, _, err := runCLI(t, "", "testdata/input.yaml", "testdata/fragment.yaml", "--emit-merged", merged)
+	require.NoError(t, err)
+	assert.Equal(t, `! Title: letsblock.it - merged list
+! Expires: 12 hours
+! Homepage: https://letsblock.it
+! License: https://github.com/letsblockit/letsblockit/blob/main/LICENSE.txt
+
+! custom-rules
+line1##ruleA
+line2###ruleB
+line3##ruleC
+
+! unknown
+`, out)
+
+	contents, err := os.ReadFile(merged)
+	require.NoError(t, err)
+	var list filters.List
+	require.NoError(t, yaml.Unmarshal(contents, &list))
+	assert.Equal(t, filters.List{
+		Title: "merged list",
+		Instances: []*filters.Instance{{
+			Template: "custom-rules",
+			Params:   map[string]interface{}{"rules": "line1##ruleA\nline2###ruleB\nline3##ruleC\n"},
+		}, {
+			Template: "unknown",
+		}},
+	}, list)
+}
+
+func TestMergeFiles_StdinOnce(t *testing.T) {
+	_, _, err := runCLI(t, "title: test", "-", "testdata/input.yaml", "-")
+	assert.ErrorIs(t, err, errStdinReused)
+}
+
+func TestMergeLists(t *testing.T) {
+	first := &filters.List{
+		Title: "first",
+		Instances: []*filters.Instance{
+			{Template: "one", Params: map[string]interface{}{"a": "first"}},
+			{Template: "custom-rules", Params: map[string]interface{}{"rules": "ruleA"}},
+		},
+	}
+	second := &filters.List{
+		Instances: []*filters.Instance{
+			{Template: "custom-rules"},
+			{Template: "two"},
+			{Template: "one", Params: map[string]interface{}{"a": "second"}},
+			{Template: "custom-rules", Params: map[string]interface{}{"rules": "ruleB\n"}},
+		},
+	}
+
+	merged, err := mergeLists([]*filters.List{first, second}, []string{"first.yaml", "second.yaml"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, &filters.List{
+		Title: "first",
+		Instances: []*filters.Instance{
+			{Template: "one", Params: map[string]interface{}{"a": "second"}},
+			{Template: "custom-rules", Params: map[string]interface{}{"rules": "ruleA\nruleB\n"}},
+			{Template: "two", Params: map[string]interface{}{}},
+		},
+	}, merged)
+	assert.Equal(t, "ruleA", first.Instances[1].Params["rules"], "input lists must not be modified")
+
+	_, err = mergeLists([]*filters.List{first, second}, []string{"first.yaml", "second.yaml"}, true)
+	assert.EqualError(t, err, "template one is defined in several files, including second.yaml")
+}
+
+func TestMergeLists_RepeatedInOneFile(t *testing.T) {
+	first := &filters.List{
+		Title: "first",
+		Instances: []*filters.Instance{
+			{Template: "one", Params: map[string]interface{}{"a": "first"}},
+			{Template: "two"},
+			{Template: "one", Params: map[string]interface{}{"a": "first again"}},
+		},
+	}
+	second := &filters.List{
+		Instances: []*filters.Instance{
+			{Template: "one", Params: map[string]interface{}{"a": "second"}},
+			{Template: "one", Params: map[string]interface{}{"a": "second again"}},
+		},
+	}
+
+	merged, err := mergeLists([]*filters.List{first}, []string{"first.yaml"}, true)
+	require.NoError(t, err, "repeating a template in one file is not a conflict")
+	assert.Equal(t, []*filters.Instance{
+		{Template: "one", Params: map[string]interface{}{"a": "first"}},
+		{Template: "one", Params: map[string]interface{}{"a": "first again"}},
+		{Template: "two", Params: map[string]interface{}{}},
+	}, merged.Instances)
+
+	merged, err = mergeLists([]*filters.List{first, second}, []string{"first.yaml", "second.yaml"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []*filters.Instance{
+		{Template: "one", Params: map[string]interface{}{"a": "second"}},
+		{Template: "one", Params: map[string]interface{}{"a": "second again"}},
+		{Template: "two", Params: map[string]interface{}{}},
+	}, merged.Instances, "the instances of the last file replace all the previous ones")
+
+	_, err = mergeLists([]*filters.List{first, second}, []string{"first.yaml", "second.yaml"}, true)
+	assert.EqualError(t, err, "template one is defined in several files, including second.yaml")
+}
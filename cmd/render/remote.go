@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/letsblockit/letsblockit/src/filters"
+)
+
+const remoteFetchTimeout = 30 * time.Second
+
+// userAgent identifies the CLI, for the server to trust the trace context it sends
+const userAgent = "letsblockit-render"
+
+// fetchList fetches the list definition from a server, to render it with the local templates
+func (c *renderCmd) fetchList(repo *filters.Repository) (*filters.List, error) {
+	client := retryablehttp.NewClient()
+	client.Logger = nil
+	client.HTTPClient.Timeout = remoteFetchTimeout
+
+	req, err := retryablehttp.NewRequest(http.MethodGet, c.From, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch list definition: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	// Continue the trace of the calling script, following the environment variable convention of OpenTelemetry
+	if parent := os.Getenv("TRACEPARENT"); parent != "" {
+		req.Header.Set("traceparent", parent)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch list definition: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch list definition: server returned %s", resp.Status)
+	}
+
+	list, err := decodeList(resp.Body, c.From)
+	if err != nil {
+		return nil, err
+	}
+	warnUnknownTemplates(list, repo)
+	return list, nil
+}
+
+// warnUnknownTemplates reports templates used by the server that are not known locally,
+// usually because the local templates are older than the server's.
+func warnUnknownTemplates(list *filters.List, repo *filters.Repository) {
+	var unknown []string
+	for _, instance := range list.Instances {
+		if !repo.Has(instance.Template) {
+			unknown = append(unknown, instance.Template)
+		}
+	}
+	if len(unknown) > 0 {
+		(&logger{}).Warnf("the list uses templates unknown locally, check that your templates are up to date: %s",
+			strings.Join(unknown, ", "))
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderRemote(t *testing.T) {
+	out, errOut := strings.Builder{}, strings.Builder{}
+	stdout = &out
+	stderr = &errOut
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/list/token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeFile(w, r, "testdata/input.yaml")
+	}))
+	defer server.Close()
+
+	cmd := &renderCmd{From: server.URL + "/api/list/token"}
+	assert.NoError(t, cmd.Run(&globals{}))
+
+	expected, e := os.ReadFile("testdata/expected.txt")
+	assert.NoError(t, e)
+	assert.Equal(t, string(expected), out.String())
+	assert.Equal(t, "WARNING: the list uses templates unknown locally, check that your templates are up to date: unknown\n"+
+		"WARNING: skipping unknown: template 'unknown' not found\n", errOut.String())
+
+	cmd = &renderCmd{From: server.URL + "/api/list/other"}
+	assert.ErrorContains(t, cmd.Run(&globals{}), "server returned 404 Not Found")
+}
+
+func TestRenderRemote_TraceParent(t *testing.T) {
+	stdout, stderr = &strings.Builder{}, &strings.Builder{}
+	const parent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	t.Setenv("TRACEPARENT", parent)
+
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		http.ServeFile(w, r, "testdata/input.yaml")
+	}))
+	defer server.Close()
+
+	cmd := &renderCmd{From: server.URL + "/api/list/token"}
+	assert.NoError(t, cmd.Run(&globals{}))
+	assert.Equal(t, userAgent, headers.Get("User-Agent"))
+	assert.Equal(t, parent, headers.Get("traceparent"))
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// Exit codes, allowing wrapper scripts to tell failure causes apart
+const (
+	exitGenericError    = 1
+	exitParseError      = 2
+	exitValidationError = 3
+	exitRenderError     = 4
+)
+
+var errorKinds = map[int]string{
+	exitGenericError:    "error",
+	exitParseError:      "parse",
+	exitValidationError: "validation",
+	exitRenderError:     "render",
+}
+
+// commandError wraps an error with the exit code to use, and details for the json output
+type commandError struct {
+	code     int
+	err      error
+	details  []errorDetail
+	reported bool // Already printed, only use the exit code
+}
+
+func (e *commandError) Error() string {
+	return e.err.Error()
+}
+
+func (e *commandError) Unwrap() error {
+	return e.err
+}
+
+func newParseError(err error) error {
+	return &commandError{code: exitParseError, err: err}
+}
+
+func newRenderError(err error) error {
+	return &commandError{code: exitRenderError, err: err}
+}
+
+func newValidationError(err error, details []errorDetail) error {
+	return &commandError{code: exitValidationError, err: err, details: details}
+}
+
+type errorDetail struct {
+	Template  string `json:"template,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (d errorDetail) String() string {
+	switch {
+	case d.Parameter != "":
+		return fmt.Sprintf("%s: parameter %s: %s", d.Template, d.Parameter, d.Message)
+	case d.Template != "":
+		return fmt.Sprintf("%s: %s", d.Template, d.Message)
+	default:
+		return d.Message
+	}
+}
+
+// renderResult is reported on success in json mode
+type renderResult struct {
+	Instances   int      `json:"instances"`
+	Rules       int      `json:"rules"`
+	Output      string   `json:"output"`
+	Unchanged   bool     `json:"unchanged,omitempty"`   // The output file was kept as its contents did not change
+	Neutralized int      `json:"neutralized,omitempty"` // Lines commented out by --safe-mode
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// errorReport is reported on failure in json mode
+type errorReport struct {
+	Kind    string        `json:"kind"`
+	Error   string        `json:"error"`
+	Details []errorDetail `json:"details,omitempty"`
+}
+
+// reportJSON prints the result or error to stderr, and marks the error as reported
+func reportJSON(result *renderResult, err error) error {
+	if err == nil {
+		return encode(stderr, "json", result)
+	}
+
+	cmdErr, ok := err.(*commandError)
+	if !ok {
+		cmdErr = &commandError{code: exitGenericError, err: err}
+	}
+	if e := encode(stderr, "json", errorReport{
+		Kind:    errorKinds[cmdErr.code],
+		Error:   cmdErr.Error(),
+		Details: cmdErr.details,
+	}); e != nil {
+		return e
+	}
+	cmdErr.reported = true
+	return cmdErr
+}
+
+// ruleCounter counts the rules written through it, ignoring comments and empty lines
+type ruleCounter struct {
+	out   io.Writer
+	rules int
+	seen  bool // The first non-blank character of the current line was seen
+}
+
+func (r *ruleCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		switch {
+		case b == '\n':
+			r.seen = false
+		case r.seen || b == ' ' || b == '\t' || b == '\r':
+		default:
+			r.seen = true
+			if b != '!' {
+				r.rules++
+			}
+		}
+	}
+	return r.out.Write(p)
+}
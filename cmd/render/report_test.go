@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderJSON(t *testing.T) {
+	input, err := os.ReadFile("testdata/input.yaml")
+	require.NoError(t, err)
+	expected, err := os.ReadFile("testdata/expected.txt")
+	require.NoError(t, err)
+
+	out, errOut, err := runCLI(t, string(input), "--format", "json")
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), out)
+	assert.JSONEq(t, `{
+		"instances": 2,
+		"rules": 2,
+		"output": "stdout",
+		"warnings": ["skipping unknown: template 'unknown' not found"]
+	}`, errOut)
+}
+
+func TestRenderJSON_Errors(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		args     []string
+		code     int
+		expected string
+	}{
+		"parse": {
+			input: "title: [invalid",
+			args:  []string{"--format", "json"},
+			code:  exitParseError,
+			expected: `{
+				"kind": "parse",
+				"error": "cannot decode stdin: yaml: line 1: did not find expected ',' or ']'"
+			}`,
+		},
+		"validation": {
+			input: "instances: []",
+			args:  []string{"--format", "json", "--strict"},
+			code:  exitValidationError,
+			expected: `{
+				"kind": "validation",
+				"error": "invalid input data in stdin: List.Title: failed on the 'required' rule",
+				"details": [{"message": "List.Title: failed on the 'required' rule"}]
+			}`,
+		},
+		"invalid parameter": {
+			input: "title: test\ninstances:\n  - template: custom-rules\n    params:\n      rules: 12\n",
+			args:  []string{"--format", "json", "--strict"},
+			code:  exitValidationError,
+			expected: `{
+				"kind": "validation",
+				"error": "invalid input data in stdin: custom-rules: parameter rules: expected a multiline value, got int",
+				"details": [{"template": "custom-rules", "parameter": "rules", "message": "expected a multiline value, got int"}]
+			}`,
+		},
+		"subset": {
+			input: "title: test\ninstances: []",
+			args:  []string{"--format", "json", "--only", "missing"},
+			code:  exitValidationError,
+			expected: `{
+				"kind": "validation",
+				"error": "no instance found for templates: missing"
+			}`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			out, errOut, err := runCLI(t, tc.input, tc.args...)
+			var cmdErr *commandError
+			require.True(t, errors.As(err, &cmdErr))
+			assert.Equal(t, tc.code, cmdErr.code)
+			assert.True(t, cmdErr.reported)
+			assert.Empty(t, out)
+			assert.JSONEq(t, tc.expected, errOut)
+		})
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		args  []string
+		code  int
+	}{
+		"parse":       {input: "title: [invalid", code: exitParseError},
+		"strict":      {input: "instances: []", args: []string{"--strict"}, code: exitValidationError},
+		"validate":    {input: "instances: []", args: []string{"validate"}, code: exitValidationError},
+		"diff strict": {args: []string{"diff", "--strict", "testdata/input.yaml", "testdata/invalid.yaml"}, code: exitValidationError},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := runCLI(t, tc.input, tc.args...)
+			var cmdErr *commandError
+			require.True(t, errors.As(err, &cmdErr))
+			assert.Equal(t, tc.code, cmdErr.code)
+			assert.False(t, cmdErr.reported)
+		})
+	}
+}
+
+func TestRuleCounter(t *testing.T) {
+	var out strings.Builder
+	counter := &ruleCounter{out: &out}
+	for _, chunk := range []string{"! comment\n", "rule", "A\n\n  ! indented comment\n", "  ruleB\nrule!C"} {
+		_, err := counter.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, counter.rules)
+	assert.Equal(t, "! comment\nruleA\n\n  ! indented comment\n  ruleB\nrule!C", out.String())
+}
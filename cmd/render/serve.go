@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/samber/lo"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+var errServeNeedsFiles = errors.New("serve mode requires input files")
+
+type serveCmd struct {
+	Listen          string   `default:":8080" help:"address to listen on"`
+	Path            string   `default:"/list.txt" help:"URL path to serve the rendered list on"`
+	Strict          bool     `help:"validate the input data before rendering the output"`
+	Only            []string `placeholder:"TEMPLATE,..." completion:"templates" help:"only render instances of the given templates"`
+	Exclude         []string `placeholder:"TEMPLATE,..." completion:"templates" help:"do not render instances of the given templates"`
+	EnvSubst        bool     `help:"expand $${VAR} references in parameter values with environment variables"`
+	AllowMissingEnv bool     `help:"expand unset environment variables to an empty string instead of failing"`
+	Inputs          []string `arg:"" name:"input" help:"input files to merge and render" type:"existingfile"`
+}
+
+// renderedList caches the latest successful rendering, and serves it over HTTP
+type renderedList struct {
+	sync.RWMutex
+	contents []byte
+	etag     string // Quoted strong validator, the contents are byte-for-byte identical while it does not change
+}
+
+func (l *renderedList) update(contents []byte) {
+	hasher := fnv.New64()
+	_, _ = hasher.Write(contents)
+	l.Lock()
+	defer l.Unlock()
+	l.contents = contents
+	l.etag = `"` + strconv.FormatUint(hasher.Sum64(), 36) + `"`
+}
+
+// matchETag tells whether an If-None-Match header holds the etag, or is *. Values are compared without their
+// weak prefix, as the If-None-Match comparison is weak, and without their quotes, for clients that strip them.
+func matchETag(header, etag string) bool {
+	etag = strings.Trim(etag, `"`)
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if value == "*" || strings.Trim(strings.TrimPrefix(value, "W/"), `"`) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *renderedList) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	l.RLock()
+	contents, etag := l.contents, l.etag
+	l.RUnlock()
+
+	w.Header().Set("Etag", etag)
+	if matchETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(contents)))
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(contents)
+	}
+}
+
+// Validate is called by kong after parsing the command line
+func (c *serveCmd) Validate() error {
+	if lo.Contains(c.Inputs, "-") {
+		return errServeNeedsFiles
+	}
+	if !strings.HasPrefix(c.Path, "/") {
+		return errors.New("--path must start with a /")
+	}
+	return nil
+}
+
+func (c *serveCmd) Help() string {
+	return `Examples:
+
+    render serve --listen :8080 --path /list.txt my-list.yaml`
+}
+
+func (c *serveCmd) Run(g *globals) error {
+	repo, err := g.loadRepository()
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", c.Listen)
+	if err != nil {
+		return fmt.Errorf("cannot listen on %s: %w", c.Listen, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return c.serveUntil(ctx, g, repo, listener, nil)
+}
+
+// serveUntil renders the list, then serves it on the listener until ctx is cancelled.
+// The list is re-rendered on every change to the input files. If not nil,
+// the ready channel is closed once the list is served.
+func (c *serveCmd) serveUntil(ctx context.Context, g *globals, repo *filters.Repository, listener net.Listener, ready chan<- struct{}) error {
+	renderer := &renderCmd{
+		Strict:          c.Strict,
+		Only:            c.Only,
+		Exclude:         c.Exclude,
+		EnvSubst:        c.EnvSubst,
+		AllowMissingEnv: c.AllowMissingEnv,
+		Inputs:          c.Inputs,
+	}
+	list := &renderedList{}
+	render := func(repo *filters.Repository) error {
+		var buf bytes.Buffer
+		if _, err := renderer.render(&buf, repo); err != nil {
+			return err
+		}
+		list.update(buf.Bytes())
+		return nil
+	}
+
+	// Fail early on invalid input, later errors are only reported
+	if err := render(repo); err != nil {
+		_ = listener.Close()
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(c.Path, list)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+	fmt.Fprintf(stderr, "Serving %s on http://%s%s\n", strings.Join(c.Inputs, ", "), listener.Addr(), c.Path)
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	watched := make(chan error, 1)
+	go func() {
+		watched <- watchFiles(watchCtx, g, c.Inputs, 0, repo, ready, func(repo *filters.Repository) {
+			if err := render(repo); err != nil {
+				fmt.Fprintln(stderr, "ERROR:", err)
+				return
+			}
+			fmt.Fprintln(stderr, "Rendered", strings.Join(c.Inputs, ", "))
+		})
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-served:
+		err = fmt.Errorf("cannot serve: %w", err)
+	case err = <-watched:
+	}
+
+	stopWatch()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if e := server.Shutdown(shutdownCtx); e != nil && err == nil {
+		err = fmt.Errorf("cannot shut down the server: %w", e)
+	}
+	return err
+}
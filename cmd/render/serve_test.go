@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderedList(t *testing.T) {
+	list := &renderedList{}
+	list.update([]byte("contents"))
+
+	rec := httptest.NewRecorder()
+	list.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list.txt", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "contents", rec.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	etag := rec.Header().Get("Etag")
+	assert.Regexp(t, `^"[^"]+"$`, etag)
+
+	req := httptest.NewRequest(http.MethodGet, "/list.txt", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	list.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, etag, rec.Header().Get("Etag"))
+	assert.Empty(t, rec.Body.String())
+
+	list.update([]byte("updated"))
+	rec = httptest.NewRecorder()
+	list.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "updated", rec.Body.String())
+	assert.NotEqual(t, etag, rec.Header().Get("Etag"))
+
+	rec = httptest.NewRecorder()
+	list.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/list.txt", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "7", rec.Header().Get("Content-Length"))
+	assert.Empty(t, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	list.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/list.txt", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestMatchETag(t *testing.T) {
+	for header, expected := range map[string]bool{
+		"":                      false,
+		`"abc"`:                 true,
+		`W/"abc"`:               true,
+		"abc":                   true,
+		`"other", W/"abc"`:      true,
+		"*":                     true,
+		`"other"`:               false,
+		`"abcd"`:                false,
+		`W/"other" , "another"`: false,
+	} {
+		assert.Equal(t, expected, matchETag(header, `"abc"`), header)
+	}
+}
+
+func TestServe(t *testing.T) {
+	stderr = &strings.Builder{}
+	dir := t.TempDir()
+	cmd := &serveCmd{
+		Path:   "/list.txt",
+		Inputs: []string{filepath.Join(dir, "input.yaml")},
+	}
+	require.NoError(t, os.WriteFile(cmd.Inputs[0], []byte("title: first\n"), 0600))
+	g := &globals{}
+	repo, err := g.loadRepository()
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready, done := make(chan struct{}), make(chan error)
+	go func() { done <- cmd.serveUntil(ctx, g, repo, listener, ready) }()
+	<-ready
+
+	url := "http://" + listener.Addr().String() + "/list.txt"
+	get := func() string {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+	assert.True(t, strings.HasPrefix(get(), "! Title: letsblock.it - first\n"))
+
+	// Invalid input is reported, the previous output is kept
+	require.NoError(t, os.WriteFile(cmd.Inputs[0], []byte("title: [invalid"), 0600))
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, strings.HasPrefix(get(), "! Title: letsblock.it - first\n"))
+
+	require.NoError(t, os.WriteFile(cmd.Inputs[0], []byte("title: second\n"), 0600))
+	assert.Eventually(t, func() bool {
+		return strings.HasPrefix(get(), "! Title: letsblock.it - second\n")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+	_, err = http.Get(url)
+	assert.Error(t, err, "server should be shut down")
+}
+
+func TestServe_FailsOnInvalidInput(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	cmd := &serveCmd{Path: "/list.txt", Inputs: []string{"testdata/invalid.yaml"}, Strict: true}
+	g := &globals{}
+	repo, err := g.loadRepository()
+	require.NoError(t, err)
+	assert.ErrorContains(t, cmd.serveUntil(context.Background(), g, repo, listener, nil), "invalid input data in testdata/invalid.yaml")
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/letsblockit/letsblockit/src/filters"
+	"gopkg.in/yaml.v3"
+)
+
+type templatesCmd struct {
+	List templatesListCmd `cmd:"" default:"1" help:"List the available templates, this is the default command."`
+	Show templatesShowCmd `cmd:"" help:"Show the parameters of a template, with an example instance."`
+}
+
+func (c *templatesCmd) Help() string {
+	return `Examples:
+
+    render templates --format yaml
+    render templates show google-search-cleanup`
+}
+
+type templatesListCmd struct {
+	Format string `enum:"table,yaml,json" default:"table" help:"output format: table, yaml or json"`
+}
+
+type templatesShowCmd struct {
+	Format string `enum:"table,yaml,json" default:"table" help:"output format: table, yaml or json"`
+	Name   string `arg:"" completion:"templates" help:"name of the template to show"`
+}
+
+type templateSummary struct {
+	Name  string   `json:"name" yaml:"name"`
+	Title string   `json:"title" yaml:"title"`
+	Tags  []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+type templateDetails struct {
+	templateSummary `yaml:",inline"`
+	Params          []paramDetails    `json:"params,omitempty" yaml:"params,omitempty"`
+	Example         *filters.Instance `json:"example" yaml:"example"`
+}
+
+type paramDetails struct {
+	Name        string      `json:"name" yaml:"name"`
+	Type        string      `json:"type" yaml:"type"`
+	Default     interface{} `json:"default" yaml:"default"`
+	Description string      `json:"description" yaml:"description"`
+	Presets     []string    `json:"presets,omitempty" yaml:"presets,omitempty"`
+}
+
+func (c *templatesListCmd) Run(g *globals) error {
+	repo, err := g.loadRepository()
+	if err != nil {
+		return err
+	}
+
+	templates := repo.GetAll()
+	summaries := make([]templateSummary, 0, len(templates))
+	for _, t := range templates {
+		summaries = append(summaries, templateSummary{Name: t.Name, Title: t.Title, Tags: t.Tags})
+	}
+
+	if c.Format != "table" {
+		return encode(stdout, c.Format, summaries)
+	}
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTITLE\tTAGS")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, s.Title, strings.Join(s.Tags, ", "))
+	}
+	return w.Flush()
+}
+
+func (c *templatesShowCmd) Run(g *globals) error {
+	repo, err := g.loadRepository()
+	if err != nil {
+		return err
+	}
+	tpl, err := repo.Get(c.Name)
+	if err != nil {
+		return err
+	}
+
+	details := templateDetails{
+		templateSummary: templateSummary{Name: tpl.Name, Title: tpl.Title, Tags: tpl.Tags},
+		Example:         &filters.Instance{Template: tpl.Name},
+	}
+	if len(tpl.Params) > 0 {
+		details.Example.Params = tpl.DefaultParams()
+	}
+	for _, p := range tpl.Params {
+		param := paramDetails{
+			Name:        p.Name,
+			Type:        string(p.Type),
+			Default:     p.Default,
+			Description: p.Description,
+		}
+		for _, preset := range p.Presets {
+			param.Presets = append(param.Presets, p.BuildPresetParamName(preset.Name))
+		}
+		details.Params = append(details.Params, param)
+	}
+
+	if c.Format != "table" {
+		return encode(stdout, c.Format, details)
+	}
+	return details.print(stdout)
+}
+
+func (d *templateDetails) print(out io.Writer) error {
+	fmt.Fprintf(out, "%s: %s\n", d.Name, d.Title)
+	if len(d.Params) > 0 {
+		fmt.Fprintln(out)
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PARAM\tTYPE\tDEFAULT\tDESCRIPTION")
+		for _, p := range d.Params {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, p.Type, formatDefault(p.Default), p.Description)
+			for _, preset := range p.Presets {
+				fmt.Fprintf(w, "%s\t%s\t\t%s\n", preset, filters.BooleanParam, "Enable the preset values")
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(out, "\nExample instance:")
+	return encode(out, "yaml", []*filters.Instance{d.Example})
+}
+
+// formatDefault summarizes a default value on a single line
+func formatDefault(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if i := strings.IndexByte(v, '\n'); i >= 0 {
+			v = v[:i] + "..."
+		}
+		return fmt.Sprintf("%q", v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			values = append(values, fmt.Sprintf("%q", e))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func encode(out io.Writer, format string, value interface{}) error {
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	return encoder.Encode(value)
+}
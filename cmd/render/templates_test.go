@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplatesList(t *testing.T) {
+	out := strings.Builder{}
+	stdout = &out
+
+	cmd := &templatesListCmd{Format: "table"}
+	assert.NoError(t, cmd.Run(&globals{}))
+	assert.True(t, strings.HasPrefix(out.String(), "NAME "))
+	assert.Regexp(t, `\ncustom-rules +Add custom blocking rules +custom\n`, out.String())
+
+	out.Reset()
+	cmd = &templatesListCmd{Format: "json"}
+	assert.NoError(t, cmd.Run(&globals{}))
+	var summaries []templateSummary
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &summaries))
+	assert.Contains(t, summaries, templateSummary{
+		Name:  "custom-rules",
+		Title: "Add custom blocking rules",
+		Tags:  []string{"custom"},
+	})
+}
+
+func TestTemplatesShow(t *testing.T) {
+	out := strings.Builder{}
+	stdout = &out
+
+	cmd := &templatesShowCmd{Name: "amazon-products", Format: "json"}
+	assert.NoError(t, cmd.Run(&globals{}))
+	var details map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &details))
+	assert.Equal(t, "amazon-products", details["name"])
+	assert.Equal(t, map[string]interface{}{
+		"template": "amazon-products",
+		"params": map[string]interface{}{
+			"rules":                           []interface{}{},
+			"rules---preset---amazon-basics":  false,
+			"rules---preset---amazon-devices": false,
+		},
+	}, details["example"])
+
+	out.Reset()
+	cmd = &templatesShowCmd{Name: "amazon-products", Format: "table"}
+	assert.NoError(t, cmd.Run(&globals{}))
+	assert.Contains(t, out.String(), "\nExample instance:\n- template: amazon-products\n  params:\n    rules: []\n")
+
+	cmd = &templatesShowCmd{Name: "unknown", Format: "table"}
+	assert.ErrorContains(t, cmd.Run(&globals{}), "unknown template 'unknown'")
+}
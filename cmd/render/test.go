@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+var errTestsFailed = errors.New("some template tests failed")
+
+type testCmd struct {
+	Name      string `arg:"" optional:"" completion:"templates" help:"only run the tests of this template"`
+	Durations bool   `help:"print the render duration of the test cases of each template, slowest first"`
+}
+
+func (c *testCmd) Help() string {
+	return `Examples:
+
+    # Run the tests of all templates
+    render test --templates-dir data/filters/templates
+    # Only run the tests of a template
+    render test --templates-dir data/filters/templates youtube-cleanup
+    # Find the slowest templates
+    render test --durations`
+}
+
+func (c *testCmd) Run(g *globals) error {
+	repo, err := g.loadRepository()
+	if err != nil {
+		return err
+	}
+
+	templates := repo.GetAll()
+	if c.Name != "" {
+		tpl, err := repo.Get(c.Name)
+		if err != nil {
+			return err
+		}
+		templates = []*filters.Template{tpl}
+	}
+
+	var passed, failed int
+	var durations []templateDuration
+	for _, tpl := range templates {
+		duration := templateDuration{name: tpl.Name}
+		for _, result := range repo.RunTests(tpl) {
+			duration.cases++
+			duration.total += result.Duration
+			if result.Passed() {
+				passed++
+				_, err = fmt.Fprintf(stdout, "PASS %s/%d\n", result.Template, result.Index)
+			} else {
+				failed++
+				err = printFailure(result)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if duration.cases > 0 {
+			durations = append(durations, duration)
+		}
+	}
+
+	if _, err = fmt.Fprintf(stdout, "\n%d passed, %d failed\n", passed, failed); err != nil {
+		return err
+	}
+	if c.Durations {
+		if err = printDurations(durations); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return errTestsFailed
+	}
+	return nil
+}
+
+// templateDuration sums the render durations of the test cases of a template
+type templateDuration struct {
+	name  string
+	cases int
+	total time.Duration
+}
+
+func printDurations(durations []templateDuration) error {
+	sort.SliceStable(durations, func(i, j int) bool {
+		return durations[i].total > durations[j].total
+	})
+	if _, err := fmt.Fprintln(stdout, "\nRender durations, slowest first:"); err != nil {
+		return err
+	}
+	for _, d := range durations {
+		if _, err := fmt.Fprintf(stdout, "%10s %s (%d cases)\n", d.total.Round(time.Microsecond), d.name, d.cases); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printFailure(result *filters.TestResult) error {
+	if _, err := fmt.Fprintf(stdout, "FAIL %s/%d\n", result.Template, result.Index); err != nil {
+		return err
+	}
+	if result.Err != nil {
+		_, err := fmt.Fprintf(stdout, "    %s\n", result.Err)
+		return err
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(result.Expected),
+		B:        splitLines(result.Output),
+		FromFile: "expected",
+		ToFile:   "actual",
+		Context:  3,
+	})
+	if err != nil {
+		return err
+	}
+	for _, line := range splitLines(diff) {
+		if _, err = fmt.Fprint(stdout, "    ", line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitLines splits s in newline-terminated lines, without an empty trailing line
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateTests(t *testing.T) {
+	out, _, err := runCLI(t, "", "--templates-dir", "testdata/templates", "test", "greeting")
+	assert.ErrorIs(t, err, errTestsFailed)
+	assert.Equal(t, `PASS greeting/0
+FAIL greeting/1
+    --- expected
+    +++ actual
+    @@ -1 +1 @@
+    -hello##four
+    +hello##three
+
+1 passed, 1 failed
+`, out)
+}
+
+func TestTemplateTests_Single(t *testing.T) {
+	out, _, err := runCLI(t, "", "test", "custom-rules")
+	assert.NoError(t, err)
+	assert.Equal(t, "PASS custom-rules/0\nPASS custom-rules/1\n\n2 passed, 0 failed\n", out)
+
+	_, _, err = runCLI(t, "", "test", "greeting")
+	assert.EqualError(t, err, "unknown template 'greeting'")
+}
+
+func TestTemplateTests_Durations(t *testing.T) {
+	out, _, err := runCLI(t, "", "test", "--durations", "custom-rules")
+	assert.NoError(t, err)
+	assert.Regexp(t, `\n2 passed, 0 failed\n\nRender durations, slowest first:\n +[0-9.]+[µm]?s custom-rules \(2 cases\)\n$`, out)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"gopkg.in/yaml.v3"
+)
+
+var errValidationFailed = errors.New("validation failed")
+
+type validateCmd struct {
+	Format string `enum:"text,json" default:"text" help:"output format for the validation report: text or json"`
+	Input  string `optional:"" help:"input file to validate, pass - or pipe the list to read from stdin" arg:"" type:"existingfile"`
+}
+
+type validationReport struct {
+	Input     string           `json:"input"`
+	Valid     bool             `json:"valid"`
+	Errors    []string         `json:"errors,omitempty"`
+	Instances []instanceReport `json:"instances,omitempty"`
+}
+
+type instanceReport struct {
+	Index    int      `json:"index"`
+	Template string   `json:"template"`
+	Errors   []string `json:"errors"`
+}
+
+func (c *validateCmd) Help() string {
+	return `Examples:
+
+    render validate my-list.yaml
+    render validate --format json my-list.yaml`
+}
+
+func (c *validateCmd) Run(g *globals) error {
+	repo, err := g.loadRepository()
+	if err != nil {
+		return err
+	}
+
+	input, name, err := openInput(c.Input)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	report := validateList(input, repo)
+	report.Input = name
+	if c.Format == "json" {
+		err = encode(stdout, c.Format, report)
+	} else {
+		err = report.print(stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if !report.Valid {
+		return &commandError{code: exitValidationError, err: errValidationFailed}
+	}
+	return nil
+}
+
+// validateList checks the list structure, then that every instance
+// references a known template with valid parameters.
+func validateList(input io.Reader, repo *filters.Repository) *validationReport {
+	var list filters.List
+	if err := yaml.NewDecoder(input).Decode(&list); err != nil {
+		return &validationReport{Errors: []string{fmt.Sprintf("cannot decode list: %s", err)}}
+	}
+	return validateInstances(&list, repo)
+}
+
+// validateInstances checks an already decoded list
+func validateInstances(list *filters.List, repo *filters.Repository) *validationReport {
+	report := &validationReport{}
+	for _, detail := range structureErrors(list) {
+		report.Errors = append(report.Errors, detail.Message)
+	}
+
+	for i, instance := range list.Instances {
+		if instance == nil || instance.Template == "" {
+			continue // Reported by list.Validate
+		}
+		entry := instanceReport{Index: i, Template: instance.Template}
+		if tpl, err := repo.Get(instance.Template); err != nil {
+			entry.Errors = append(entry.Errors, err.Error())
+		} else {
+			for _, e := range tpl.ValidateParams(instance.Params) {
+				entry.Errors = append(entry.Errors, e.Error())
+			}
+		}
+		if len(entry.Errors) > 0 {
+			report.Instances = append(report.Instances, entry)
+		}
+	}
+
+	report.Valid = len(report.Errors) == 0 && len(report.Instances) == 0
+	return report
+}
+
+// structureErrors checks the list structure, without looking up templates
+func structureErrors(list *filters.List) []errorDetail {
+	err := list.Validate()
+	if err == nil {
+		return nil
+	}
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return []errorDetail{{Message: err.Error()}}
+	}
+	details := make([]errorDetail, 0, len(errs))
+	for _, e := range errs {
+		details = append(details, errorDetail{Message: fmt.Sprintf("%s: failed on the '%s' rule", e.Namespace(), e.Tag())})
+	}
+	return details
+}
+
+// paramErrorDetail converts an error returned by ValidateParams for the json output
+func paramErrorDetail(template string, err error) errorDetail {
+	detail := errorDetail{Template: template, Message: err.Error()}
+	var paramErr *filters.ParamError
+	if errors.As(err, &paramErr) {
+		detail.Parameter, detail.Message = paramErr.Param, paramErr.Message
+	}
+	return detail
+}
+
+func (r *validationReport) print(out io.Writer) error {
+	if r.Valid {
+		_, err := fmt.Fprintf(out, "%s: OK\n", r.Input)
+		return err
+	}
+	for _, e := range r.Errors {
+		if _, err := fmt.Fprintf(out, "%s: %s\n", r.Input, e); err != nil {
+			return err
+		}
+	}
+	for _, i := range r.Instances {
+		for _, e := range i.Errors {
+			if _, err := fmt.Fprintf(out, "%s: instance %d (%s): %s\n", r.Input, i.Index, i.Template, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
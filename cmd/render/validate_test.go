@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate_OK(t *testing.T) {
+	out := strings.Builder{}
+	stdout = &out
+
+	cmd := &validateCmd{Input: "testdata/modified.yaml", Format: "text"}
+	assert.NoError(t, cmd.Run(&globals{}))
+	assert.Equal(t, "testdata/modified.yaml: OK\n", out.String())
+}
+
+func TestValidate_Text(t *testing.T) {
+	out := strings.Builder{}
+	stdout = &out
+
+	cmd := &validateCmd{Input: "testdata/invalid.yaml", Format: "text"}
+	assert.ErrorIs(t, cmd.Run(&globals{}), errValidationFailed)
+	assert.Equal(t, `testdata/invalid.yaml: List.Instances[2].Template: failed on the 'required' rule
+testdata/invalid.yaml: instance 0 (custom-rules): parameter rules: expected a multiline value, got int
+testdata/invalid.yaml: instance 0 (custom-rules): parameter typo: unknown parameter
+testdata/invalid.yaml: instance 1 (unknown): unknown template 'unknown'
+`, out.String())
+}
+
+func TestValidate_JSON(t *testing.T) {
+	out := strings.Builder{}
+	stdout = &out
+
+	cmd := &validateCmd{Input: "testdata/invalid.yaml", Format: "json"}
+	assert.ErrorIs(t, cmd.Run(&globals{}), errValidationFailed)
+
+	var report validationReport
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &report))
+	assert.Equal(t, validationReport{
+		Input:  "testdata/invalid.yaml",
+		Valid:  false,
+		Errors: []string{"List.Instances[2].Template: failed on the 'required' rule"},
+		Instances: []instanceReport{{
+			Index:    0,
+			Template: "custom-rules",
+			Errors: []string{
+				"parameter rules: expected a multiline value, got int",
+				"parameter typo: unknown parameter",
+			},
+		}, {
+			Index:    1,
+			Template: "unknown",
+			Errors:   []string{"unknown template 'unknown'"},
+		}},
+	}, report)
+}
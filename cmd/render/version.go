@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+
+	"github.com/letsblockit/letsblockit/data"
+)
+
+type versionCmd struct{}
+
+// Run prints the module version, and the hash of the embedded templates, matching the server's filter hash
+func (c *versionCmd) Run(g *globals) error {
+	version := "(devel)"
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		version = info.Main.Version
+	}
+	hash, err := data.HashFiles(data.Templates, data.Presets)
+	if err != nil {
+		return fmt.Errorf("cannot hash the embedded templates: %w", err)
+	}
+	if _, err = fmt.Fprintf(stdout, "render %s\nembedded templates: %s\n", version, hash); err != nil {
+		return err
+	}
+
+	if g.TemplatesDir != "" {
+		if hash, err = data.HashFiles(os.DirFS(g.TemplatesDir)); err != nil {
+			return fmt.Errorf("cannot hash the templates in %s: %w", g.TemplatesDir, err)
+		}
+		_, err = fmt.Fprintf(stdout, "templates in %s: %s\n", g.TemplatesDir, hash)
+	}
+	return err
+}
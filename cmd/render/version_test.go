@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/letsblockit/letsblockit/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion(t *testing.T) {
+	hash, err := data.HashFiles(data.Templates, data.Presets)
+	require.NoError(t, err)
+
+	out, _, err := runCLI(t, "", "version")
+	assert.NoError(t, err)
+	assert.Equal(t, "render (devel)\nembedded templates: "+hash+"\n", out)
+
+	out, _, err = runCLI(t, "", "--templates-dir", "testdata/templates", "version")
+	assert.NoError(t, err)
+	dir, err := filepath.Abs("testdata/templates")
+	require.NoError(t, err)
+	assert.Contains(t, out, "\ntemplates in "+dir+": ")
+}
+
+func TestTemplatesDir_Verbose(t *testing.T) {
+	_, errOut, err := runCLI(t, "", "--templates-dir", "testdata/templates", "-v", "test", "greeting")
+	assert.ErrorIs(t, err, errTestsFailed)
+	dir, err := filepath.Abs("testdata/templates")
+	require.NoError(t, err)
+	assert.Contains(t, errOut, "Loaded template greeting from "+dir+"\n")
+	assert.Contains(t, errOut, "Loaded template custom-rules from embedded\n")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/samber/lo"
+)
+
+var errWatchNeedsFiles = errors.New("watch mode requires input files and an --output file")
+
+// watch renders the output file, then re-renders it on every change to the input files,
+// and every interval if set, until the process is interrupted.
+func (c *renderCmd) watch(g *globals, repo *filters.Repository) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return c.watchUntil(ctx, g, repo, nil)
+}
+
+// watchUntil runs the watch loop until ctx is cancelled. If not nil,
+// the ready channel is closed once the watcher is set up.
+func (c *renderCmd) watchUntil(ctx context.Context, g *globals, repo *filters.Repository, ready chan<- struct{}) error {
+	if c.Output == "" {
+		return errWatchNeedsFiles
+	}
+	var paths []string // Not watching files in interval-only mode
+	if c.Watch || c.Interval == 0 {
+		if len(c.Inputs) == 0 || lo.Contains(c.Inputs, "-") {
+			return errWatchNeedsFiles
+		}
+		paths = c.Inputs
+	}
+	return watchFiles(ctx, g, paths, c.Interval, repo, ready, c.renderAndReport)
+}
+
+// watchFiles calls onChange once the watcher is set up, then on every change to the input files,
+// and every interval if not zero, until ctx is cancelled. If input files are watched, templates
+// are reloaded when a file changes in the templates folder.
+func watchFiles(ctx context.Context, g *globals, paths []string, interval time.Duration, repo *filters.Repository, ready chan<- struct{}, onChange func(*filters.Repository)) error {
+	inputs := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		input, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("cannot resolve input file path: %w", err)
+		}
+		inputs[input] = struct{}{}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("cannot create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Watch the parent folders, as most editors save by replacing the file
+	for input := range inputs {
+		if err = watcher.Add(filepath.Dir(input)); err != nil {
+			return fmt.Errorf("cannot watch input file: %w", err)
+		}
+	}
+	var templatesDir string
+	if g.TemplatesDir != "" && len(paths) > 0 {
+		if templatesDir, err = filepath.Abs(g.TemplatesDir); err != nil {
+			return fmt.Errorf("cannot resolve templates folder path: %w", err)
+		}
+		if err = watcher.Add(templatesDir); err != nil {
+			return fmt.Errorf("cannot watch templates folder: %w", err)
+		}
+	}
+	if ready != nil {
+		close(ready)
+	}
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	onChange(repo)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick:
+			onChange(repo)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintln(stderr, "ERROR: file watcher:", err)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			path := filepath.Clean(event.Name)
+			if templatesDir != "" && filepath.Dir(path) == templatesDir && strings.HasSuffix(path, ".yaml") {
+				reloaded, err := g.loadRepository()
+				if err != nil {
+					fmt.Fprintln(stderr, "ERROR:", err)
+					continue
+				}
+				repo = reloaded
+			} else if _, found := inputs[path]; !found {
+				continue
+			}
+			onChange(repo)
+		}
+	}
+}
+
+// renderAndReport renders the output file and prints errors instead of returning them
+func (c *renderCmd) renderAndReport(repo *filters.Repository) {
+	result, err := c.renderToFile(repo)
+	switch {
+	case err != nil:
+		fmt.Fprintln(stderr, "ERROR:", err)
+	case result.Unchanged:
+		fmt.Fprintln(stderr, "No changes in", c.Output)
+	default:
+		fmt.Fprintln(stderr, "Rendered", strings.Join(c.Inputs, ", "), "into", c.Output)
+	}
+}
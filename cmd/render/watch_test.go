@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/letsblockit/letsblockit/data"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	stderr = &strings.Builder{}
+	repo, err := filters.Load(data.Templates, data.Presets)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	cmd := &renderCmd{
+		Inputs: []string{filepath.Join(dir, "input.yaml")},
+		Output: filepath.Join(dir, "output.txt"),
+	}
+	require.NoError(t, os.WriteFile(cmd.Inputs[0], []byte("title: first\n"), 0600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready, done := make(chan struct{}), make(chan error)
+	go func() { done <- cmd.watchUntil(ctx, &globals{}, repo, ready) }()
+	<-ready
+
+	readOutput := func() string {
+		out, _ := os.ReadFile(cmd.Output)
+		return string(out)
+	}
+	assert.Eventually(t, func() bool {
+		return strings.HasPrefix(readOutput(), "! Title: letsblock.it - first\n")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Invalid input is reported, the previous output is kept
+	require.NoError(t, os.WriteFile(cmd.Inputs[0], []byte("title: [invalid"), 0600))
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, strings.HasPrefix(readOutput(), "! Title: letsblock.it - first\n"))
+
+	require.NoError(t, os.WriteFile(cmd.Inputs[0], []byte("title: second\n"), 0600))
+	assert.Eventually(t, func() bool {
+		return strings.HasPrefix(readOutput(), "! Title: letsblock.it - second\n")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestWatch_RequiresFiles(t *testing.T) {
+	cmd := &renderCmd{Inputs: []string{"-"}, Output: "out.txt"}
+	assert.ErrorIs(t, cmd.watchUntil(context.Background(), &globals{}, nil, nil), errWatchNeedsFiles)
+	cmd = &renderCmd{Inputs: []string{"testdata/input.yaml"}}
+	assert.ErrorIs(t, cmd.watchUntil(context.Background(), &globals{}, nil, nil), errWatchNeedsFiles)
+}
+
+func TestWatch_ReloadTemplates(t *testing.T) {
+	stderr = &strings.Builder{}
+	dir := t.TempDir()
+	g := &globals{TemplatesDir: filepath.Join(dir, "templates")}
+	require.NoError(t, os.Mkdir(g.TemplatesDir, 0700))
+	writeTemplate := func(rule string) {
+		require.NoError(t, os.WriteFile(filepath.Join(g.TemplatesDir, "local.yaml"),
+			[]byte("title: Local\ntemplate: "+rule+"\n---\nLocal template\n"), 0600))
+	}
+	writeTemplate("first##rule")
+	repo, err := g.loadRepository()
+	require.NoError(t, err)
+
+	cmd := &renderCmd{
+		Inputs: []string{filepath.Join(dir, "input.yaml")},
+		Output: filepath.Join(dir, "output.txt"),
+	}
+	require.NoError(t, os.WriteFile(cmd.Inputs[0], []byte("title: test\ninstances:\n  - template: local\n"), 0600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready, done := make(chan struct{}), make(chan error)
+	go func() { done <- cmd.watchUntil(ctx, g, repo, ready) }()
+	<-ready
+
+	readOutput := func() string {
+		out, _ := os.ReadFile(cmd.Output)
+		return string(out)
+	}
+	assert.Eventually(t, func() bool {
+		return strings.Contains(readOutput(), "\nfirst##rule")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	writeTemplate("second##rule")
+	assert.Eventually(t, func() bool {
+		return strings.Contains(readOutput(), "\nsecond##rule")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestWatch_Interval(t *testing.T) {
+	var errOut syncBuilder
+	stderr = &errOut
+	repo, err := filters.Load(data.Templates, data.Presets)
+	require.NoError(t, err)
+
+	cmd := &renderCmd{
+		Inputs:   []string{"testdata/input.yaml"},
+		Output:   filepath.Join(t.TempDir(), "output.txt"),
+		Interval: 10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- cmd.watchUntil(ctx, &globals{}, repo, nil) }()
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(errOut.String(), "No changes in "+cmd.Output)
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Contains(t, errOut.String(), "Rendered testdata/input.yaml into "+cmd.Output+"\n")
+}
+
+// syncBuilder is a strings.Builder safe for concurrent use
+type syncBuilder struct {
+	sync.Mutex
+	b strings.Builder
+}
+
+func (s *syncBuilder) Write(p []byte) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuilder) String() string {
+	s.Lock()
+	defer s.Unlock()
+	return s.b.String()
+}
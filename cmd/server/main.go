@@ -2,24 +2,61 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/letsblockit/letsblockit/src/server"
 )
 
-func main() {
+type commands struct {
+	Serve       serveCmd       `cmd:"" default:"withargs" help:"Start the server, this is the default command."`
+	Migrate     migrateCmd     `cmd:"" help:"Manage the database schema migrations."`
+	Maintenance maintenanceCmd `cmd:"" aliases:"cleanup" help:"Run a database maintenance task, for cron jobs and runbooks."`
+}
+
+type serveCmd struct {
+	Config kong.ConfigFlag `type:"existingfile" placeholder:"FILE" help:"JSON file holding option values, re-read on SIGHUP"`
+	server.Options
+}
+
+func (c *serveCmd) Run() error {
 	start := time.Now()
-	options := &server.Options{}
-	k := kong.Parse(options,
-		kong.Description("Read https://github.com/letsblockit/letsblockit/blob/main/cmd/server/README.md for setup instructions."),
-		kong.DefaultEnvars("LETSBLOCKIT"),
-	)
-	err := server.NewServer(options).Start()
-
-	if err == server.ErrDryRunFinished {
+	s := server.NewServer(&c.Options)
+	s.SetOptionsLoader(loadOptions)
+	err := s.Start()
+	switch err {
+	case server.ErrDryRunFinished:
 		fmt.Printf("Dry-run checks finished in %s\n", time.Since(start))
-	} else {
-		k.FatalIfErrorf(err)
+	case server.ErrTemplateCheckFinished:
+		fmt.Printf("Template check passed in %s\n", time.Since(start))
+	default:
+		return err
+	}
+	return nil
+}
+
+// loadOptions parses the command line, environment and config file again, for the server to reload its options
+func loadOptions() (*server.Options, error) {
+	cli := &commands{}
+	parser, err := kong.New(cli, kongOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = parser.Parse(os.Args[1:]); err != nil {
+		return nil, err
 	}
+	return &cli.Serve.Options, nil
+}
+
+var kongOptions = []kong.Option{
+	kong.Description("Read https://github.com/letsblockit/letsblockit/blob/main/cmd/server/README.md for setup instructions."),
+	kong.DefaultEnvars("LETSBLOCKIT"),
+	kong.Configuration(kong.JSON),
+}
+
+func main() {
+	cli := &commands{}
+	k := kong.Parse(cli, kongOptions...)
+	k.FatalIfErrorf(k.Run(&cli.Migrate, &cli.Maintenance))
 }
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/server"
+)
+
+type maintenanceCmd struct {
+	DatabaseUrl string `default:"postgresql:///letsblockit" help:"psql database to run the task on"`
+
+	Orphans         maintenanceOrphansCmd         `cmd:"" help:"Reparent, or delete, the filter instances whose list is missing or belongs to another user."`
+	PurgeBanned     maintenancePurgeBannedCmd     `cmd:"" name:"purge-banned" help:"Delete the data of the users with an active ban, their bans are kept."`
+	ArchiveInactive maintenanceArchiveInactiveCmd `cmd:"" name:"archive-inactive" help:"Move the filters of the lists nobody downloaded or edited for long to a snapshot."`
+	NormalizeParams maintenanceNormalizeParamsCmd `cmd:"" name:"normalize-params" help:"Drop the filter parameters left invalid or unknown by template changes."`
+}
+
+// maintenanceFlags are shared by all the maintenance tasks
+type maintenanceFlags struct {
+	DryRun    bool  `help:"only report what the task would do, without changing anything"`
+	BatchSize int32 `default:"1000" help:"rows to fix per statement or transaction, to not lock the tables for long"`
+}
+
+// maintenanceSummary is printed as JSON on stdout when a task finishes, even if it partially failed
+type maintenanceSummary struct {
+	Task   string      `json:"task"`
+	DryRun bool        `json:"dry_run"`
+	Report interface{} `json:"report,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// runMaintenance connects to the database, runs the task and prints its summary.
+// The task error is returned for kong to exit with a non-zero status.
+func runMaintenance(m *maintenanceCmd, name string, dryRun bool, task func(ctx context.Context, store db.Store) (interface{}, error)) error {
+	store, err := db.Connect(m.DatabaseUrl, "", &statsd.NoOpClient{}, db.SlowQueryLog{})
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return printMaintenanceSummary(os.Stdout, name, dryRun, func() (interface{}, error) {
+		return task(context.Background(), store)
+	})
+}
+
+func printMaintenanceSummary(out io.Writer, name string, dryRun bool, task func() (interface{}, error)) error {
+	report, err := task()
+	summary := maintenanceSummary{Task: name, DryRun: dryRun, Report: report}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if encErr := encoder.Encode(summary); encErr != nil && err == nil {
+		return encErr
+	}
+	return err
+}
+
+type maintenanceOrphansCmd struct {
+	maintenanceFlags
+	Delete  bool `help:"delete the orphan instances instead of moving them to the list of their user"`
+	Verbose bool `short:"v" help:"list every orphan instance found in the summary"`
+}
+
+type orphansSummary struct {
+	Found        int      `json:"found"`
+	MissingList  int      `json:"missing_list"`
+	UserMismatch int      `json:"user_mismatch"`
+	Deleted      int64    `json:"deleted"`
+	Reparented   int64    `json:"reparented"`
+	Kept         int      `json:"kept"`
+	Instances    []string `json:"instances,omitempty"`
+}
+
+func (c *maintenanceOrphansCmd) Run(m *maintenanceCmd) error {
+	return runMaintenance(m, "orphans", c.DryRun, c.run)
+}
+
+func (c *maintenanceOrphansCmd) run(ctx context.Context, store db.Store) (interface{}, error) {
+	action := db.OrphanReparent
+	switch {
+	case c.DryRun:
+		action = db.OrphanReport
+	case c.Delete:
+		action = db.OrphanDelete
+	}
+	report, err := db.CleanupOrphanInstances(ctx, store, action, c.BatchSize)
+	if report == nil {
+		return nil, err
+	}
+	summary := &orphansSummary{
+		Found:        len(report.Instances),
+		MissingList:  report.MissingList,
+		UserMismatch: report.UserMismatch,
+		Deleted:      report.Deleted,
+		Reparented:   report.Reparented,
+		Kept:         report.Kept,
+	}
+	if c.Verbose {
+		for _, instance := range report.Instances {
+			summary.Instances = append(summary.Instances, instance.String())
+		}
+	}
+	return summary, err
+}
+
+type maintenancePurgeBannedCmd struct {
+	maintenanceFlags
+}
+
+type purgeBannedSummary struct {
+	Banned int      `json:"banned"`
+	Purged int      `json:"purged"`
+	Failed []string `json:"failed,omitempty"`
+}
+
+func (c *maintenancePurgeBannedCmd) Run(m *maintenanceCmd) error {
+	return runMaintenance(m, "purge-banned", c.DryRun, c.run)
+}
+
+func (c *maintenancePurgeBannedCmd) run(ctx context.Context, store db.Store) (interface{}, error) {
+	report, err := db.PurgeBannedUsers(ctx, store, c.DryRun, c.BatchSize)
+	if report == nil {
+		return nil, err
+	}
+	return &purgeBannedSummary{
+		Banned: len(report.Users),
+		Purged: report.Purged,
+		Failed: report.Failed,
+	}, err
+}
+
+type maintenanceArchiveInactiveCmd struct {
+	maintenanceFlags
+	InactiveFor time.Duration `default:"8760h" help:"archive the lists not downloaded, and whose filters were not changed, for this long"`
+}
+
+type archiveInactiveSummary struct {
+	Inactive int      `json:"inactive"`
+	Archived int      `json:"archived"`
+	Failed   []string `json:"failed,omitempty"`
+}
+
+func (c *maintenanceArchiveInactiveCmd) Run(m *maintenanceCmd) error {
+	return runMaintenance(m, "archive-inactive", c.DryRun, c.run)
+}
+
+func (c *maintenanceArchiveInactiveCmd) run(ctx context.Context, store db.Store) (interface{}, error) {
+	report, err := server.ArchiveInactiveLists(ctx, store, time.Now().Add(-c.InactiveFor), c.DryRun, c.BatchSize)
+	if report == nil {
+		return nil, err
+	}
+	return &archiveInactiveSummary{
+		Inactive: len(report.Users),
+		Archived: report.Archived,
+		Failed:   report.Failed,
+	}, err
+}
+
+type maintenanceNormalizeParamsCmd struct {
+	maintenanceFlags
+	TemplatesDir string `type:"existingdir" placeholder:"DIR" help:"load templates from a local folder, like the server option with the same name"`
+}
+
+type normalizeParamsSummary struct {
+	Scanned         int `json:"scanned"`
+	Invalid         int `json:"invalid"`
+	Normalized      int `json:"normalized"`
+	UnknownTemplate int `json:"unknown_template"`
+	Failed          int `json:"failed"`
+}
+
+func (c *maintenanceNormalizeParamsCmd) Run(m *maintenanceCmd) error {
+	return runMaintenance(m, "normalize-params", c.DryRun, c.run)
+}
+
+func (c *maintenanceNormalizeParamsCmd) run(ctx context.Context, store db.Store) (interface{}, error) {
+	report, err := server.NormalizeInstanceParams(ctx, store, c.TemplatesDir, c.DryRun, c.BatchSize)
+	if report == nil {
+		return nil, err
+	}
+	return &normalizeParamsSummary{
+		Scanned:         report.Scanned,
+		Invalid:         report.Invalid,
+		Normalized:      report.Normalized,
+		UnknownTemplate: report.UnknownTemplate,
+		Failed:          report.Failed,
+	}, err
+}
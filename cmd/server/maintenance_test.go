@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runTestTask runs a maintenance task on the store, and decodes its summary
+func runTestTask(t *testing.T, store db.Store, name string, dryRun bool,
+	task func(ctx context.Context, store db.Store) (interface{}, error)) (map[string]interface{}, error) {
+	var out strings.Builder
+	err := printMaintenanceSummary(&out, name, dryRun, func() (interface{}, error) {
+		return task(context.Background(), store)
+	})
+	var summary map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &summary))
+	assert.Equal(t, name, summary["task"])
+	assert.Equal(t, dryRun, summary["dry_run"])
+	return summary, err
+}
+
+func TestMaintenanceOrphans(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewTestStore(t)
+	for _, user := range []string{"valid", "missing"} {
+		_, err := store.CreateListForUser(ctx, user)
+		require.NoError(t, err)
+		require.NoError(t, store.CreateInstance(ctx, db.CreateInstanceParams{UserID: user, TemplateName: "filter1"}))
+	}
+	// The test schemas are cloned without their foreign keys: the instance is kept, pointing to the deleted list
+	require.NoError(t, store.DeleteListForUser(ctx, "missing"))
+	_, err := store.CreateListForUser(ctx, "missing")
+	require.NoError(t, err)
+
+	cmd := &maintenanceOrphansCmd{maintenanceFlags: maintenanceFlags{DryRun: true, BatchSize: 1}}
+	summary, err := runTestTask(t, store, "orphans", true, cmd.run)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"found": 1.0, "missing_list": 1.0, "user_mismatch": 0.0, "deleted": 0.0, "reparented": 0.0, "kept": 0.0,
+	}, summary["report"])
+
+	cmd.Verbose = true
+	summary, err = runTestTask(t, store, "orphans", true, cmd.run)
+	require.NoError(t, err)
+	assert.Len(t, summary["report"].(map[string]interface{})["instances"], 1)
+
+	cmd.Verbose, cmd.DryRun = false, false
+	summary, err = runTestTask(t, store, "orphans", false, cmd.run)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, summary["report"].(map[string]interface{})["reparented"])
+
+	summary, err = runTestTask(t, store, "orphans", false, cmd.run)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, summary["report"].(map[string]interface{})["found"])
+}
+
+// failingStore fails the transactions after the first ones, as if the database went down during the task
+type failingStore struct {
+	db.Store
+	allowed int
+}
+
+func (f *failingStore) RunTxContext(ctx context.Context, fn db.TxFunc) error {
+	if f.allowed == 0 {
+		return errors.New("connection refused")
+	}
+	f.allowed--
+	return f.Store.RunTxContext(ctx, fn)
+}
+
+func TestMaintenancePurgeBanned(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewTestStore(t)
+	for _, user := range []string{"banned1", "banned2", "banned3", "kept"} {
+		_, err := store.CreateListForUser(ctx, user)
+		require.NoError(t, err)
+		require.NoError(t, store.CreateInstance(ctx, db.CreateInstanceParams{UserID: user, TemplateName: "filter1"}))
+		if user != "kept" {
+			require.NoError(t, store.AddUserBan(ctx, db.AddUserBanParams{UserID: user}))
+		}
+	}
+	countLists := func(user string) int64 {
+		count, err := store.CountListsForUser(ctx, user)
+		require.NoError(t, err)
+		return count
+	}
+
+	cmd := &maintenancePurgeBannedCmd{maintenanceFlags{DryRun: true, BatchSize: 2}}
+	summary, err := runTestTask(t, store, "purge-banned", true, cmd.run)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"banned": 3.0, "purged": 0.0}, summary["report"])
+	assert.EqualValues(t, 1, countLists("banned1"))
+
+	// The second batch fails, the task goes on and exits with an error
+	cmd.DryRun = false
+	summary, err = runTestTask(t, &failingStore{Store: store, allowed: 1}, "purge-banned", false, cmd.run)
+	require.ErrorContains(t, err, "1 of 3 users could not be purged")
+	assert.Contains(t, summary["error"], "connection refused")
+	report := summary["report"].(map[string]interface{})
+	assert.Equal(t, 2.0, report["purged"])
+	require.Len(t, report["failed"], 1)
+	assert.EqualValues(t, 1, countLists(report["failed"].([]interface{})[0].(string)))
+
+	summary, err = runTestTask(t, store, "purge-banned", false, cmd.run)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"banned": 3.0, "purged": 3.0}, summary["report"])
+	for _, user := range []string{"banned1", "banned2", "banned3"} {
+		assert.Zero(t, countLists(user), user)
+		bans, err := store.GetBannedUsers(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, bans, user, "bans are kept")
+	}
+	assert.EqualValues(t, 1, countLists("kept"))
+	instances, err := store.GetInstancesForUser(ctx, "kept")
+	require.NoError(t, err)
+	assert.Len(t, instances, 1)
+}
+
+func TestMaintenanceArchiveInactive(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewTestStore(t)
+	_, err := store.CreateListForUser(ctx, "user")
+	require.NoError(t, err)
+	require.NoError(t, store.CreateInstance(ctx, db.CreateInstanceParams{UserID: "user", TemplateName: "filter1"}))
+
+	cmd := &maintenanceArchiveInactiveCmd{maintenanceFlags: maintenanceFlags{BatchSize: 10}, InactiveFor: time.Hour}
+	summary, err := runTestTask(t, store, "archive-inactive", false, cmd.run)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"inactive": 0.0, "archived": 0.0}, summary["report"])
+
+	// Every list created before the run is inactive
+	cmd.InactiveFor, cmd.DryRun = time.Nanosecond, true
+	summary, err = runTestTask(t, store, "archive-inactive", true, cmd.run)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"inactive": 1.0, "archived": 0.0}, summary["report"])
+
+	cmd.DryRun = false
+	summary, err = runTestTask(t, store, "archive-inactive", false, cmd.run)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"inactive": 1.0, "archived": 1.0}, summary["report"])
+	instances, err := store.GetInstancesForUser(ctx, "user")
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+	snapshots, err := store.GetSnapshotsForUser(ctx, "user")
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.EqualValues(t, 1, snapshots[0].InstanceCount)
+
+	summary, err = runTestTask(t, store, "archive-inactive", false, cmd.run)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"inactive": 0.0, "archived": 0.0}, summary["report"], "empty lists are skipped")
+}
+
+func TestMaintenanceNormalizeParams(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewTestStore(t)
+	createInstance := func(user, template string, params map[string]interface{}) {
+		_, err := store.CreateListForUser(ctx, user)
+		require.NoError(t, err)
+		var jsonParams pgtype.JSONB
+		require.NoError(t, jsonParams.Set(params))
+		require.NoError(t, store.CreateInstance(ctx, db.CreateInstanceParams{UserID: user, TemplateName: template, Params: jsonParams}))
+	}
+	createInstance("valid", "custom-rules", map[string]interface{}{"rules": "example.com##.ad"})
+	createInstance("invalid", "custom-rules", map[string]interface{}{"rules": "example.com##.ad", "removed": true})
+	createInstance("unknown", "removed-template", map[string]interface{}{"removed": true})
+
+	cmd := &maintenanceNormalizeParamsCmd{maintenanceFlags: maintenanceFlags{DryRun: true, BatchSize: 2}}
+	summary, err := runTestTask(t, store, "normalize-params", true, cmd.run)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"scanned": 3.0, "invalid": 1.0, "normalized": 0.0, "unknown_template": 1.0, "failed": 0.0,
+	}, summary["report"])
+
+	cmd.DryRun = false
+	summary, err = runTestTask(t, store, "normalize-params", false, cmd.run)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, summary["report"].(map[string]interface{})["normalized"])
+	instance, err := store.GetInstance(ctx, db.GetInstanceParams{UserID: "invalid", TemplateName: "custom-rules"})
+	require.NoError(t, err)
+	var params map[string]interface{}
+	require.NoError(t, instance.Params.AssignTo(&params))
+	assert.Equal(t, map[string]interface{}{"rules": "example.com##.ad"}, params)
+
+	summary, err = runTestTask(t, store, "normalize-params", false, cmd.run)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, summary["report"].(map[string]interface{})["invalid"])
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/letsblockit/letsblockit/src/db"
+)
+
+type migrateCmd struct {
+	DatabaseUrl string `default:"postgresql:///letsblockit" help:"psql database to migrate"`
+
+	Up     migrateUpCmd     `cmd:"" help:"Apply the pending migrations."`
+	Status migrateStatusCmd `cmd:"" help:"Show the schema version and the pending migrations."`
+	DryRun migrateDryRunCmd `cmd:"" name:"dry-run" help:"Print the statements of the pending migrations, without applying them."`
+}
+
+type migrateUpCmd struct{}
+
+func (c *migrateUpCmd) Run(m *migrateCmd) error {
+	return db.Migrate(m.DatabaseUrl)
+}
+
+type migrateStatusCmd struct{}
+
+func (c *migrateStatusCmd) Run(m *migrateCmd) error {
+	status, err := db.GetMigrationStatus(m.DatabaseUrl, false)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Schema version: %d, latest migration: %d\n", status.Version, status.Latest)
+	if status.Dirty {
+		fmt.Printf("Migration %d failed, fix the database schema before running the next migrations\n", status.Version)
+	}
+	if len(status.Pending) == 0 {
+		fmt.Println("No pending migration")
+		return nil
+	}
+	fmt.Printf("%d pending migrations:\n", len(status.Pending))
+	for _, migration := range status.Pending {
+		fmt.Printf("  %04d %s\n", migration.Version, migration.Name)
+	}
+	return nil
+}
+
+type migrateDryRunCmd struct{}
+
+func (c *migrateDryRunCmd) Run(m *migrateCmd) error {
+	status, err := db.GetMigrationStatus(m.DatabaseUrl, true)
+	if err != nil {
+		return err
+	}
+	if len(status.Pending) == 0 {
+		fmt.Println("-- No pending migration")
+	}
+	for _, migration := range status.Pending {
+		fmt.Printf("-- Migration %04d %s\n%s\n", migration.Version, migration.Name, migration.Statements)
+	}
+	return nil
+}
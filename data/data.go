@@ -22,6 +22,9 @@ var Presets embed.FS
 //go:embed pages/*
 var Pages embed.FS
 
+//go:embed news
+var News embed.FS
+
 //go:embed tabler-icons.yaml
 var Icons []byte
 
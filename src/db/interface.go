@@ -6,33 +6,139 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
+	AckTemplate(ctx context.Context, arg AckTemplateParams) error
+	AddFavorite(ctx context.Context, arg AddFavoriteParams) error
+	AddTemplateDefinitions(ctx context.Context, arg AddTemplateDefinitionsParams) error
+	AddTemplateFeedback(ctx context.Context, arg AddTemplateFeedbackParams) error
+	AddTemplateVersions(ctx context.Context, arg AddTemplateVersionsParams) error
 	AddUserBan(ctx context.Context, arg AddUserBanParams) error
+	AdoptEphemeralInstances(ctx context.Context, arg AdoptEphemeralInstancesParams) error
+	AdoptEphemeralList(ctx context.Context, arg AdoptEphemeralListParams) error
+	ClaimCloneCode(ctx context.Context, codeHash []byte) (string, error)
+	CopyInstances(ctx context.Context, arg CopyInstancesParams) (int64, error)
 	CountInstances(ctx context.Context, arg CountInstancesParams) (int64, error)
+	CountListTokensForUser(ctx context.Context, userID string) (int64, error)
 	CountListsForUser(ctx context.Context, userID string) (int64, error)
+	CountProposalUpdates(ctx context.Context, arg CountProposalUpdatesParams) (int64, error)
+	CountRecentFeedback(ctx context.Context, arg CountRecentFeedbackParams) (int64, error)
+	CountRecentProposals(ctx context.Context, arg CountRecentProposalsParams) (int64, error)
+	CountShareTokensForUser(ctx context.Context, userID string) (int64, error)
+	CountSnapshotsForUser(ctx context.Context, userID string) (int64, error)
+	CreateApiToken(ctx context.Context, arg CreateApiTokenParams) error
+	CreateCloneCode(ctx context.Context, arg CreateCloneCodeParams) error
+	CreateEphemeralList(ctx context.Context, arg CreateEphemeralListParams) (uuid.UUID, error)
 	CreateInstance(ctx context.Context, arg CreateInstanceParams) error
 	CreateListForUser(ctx context.Context, userID string) (uuid.UUID, error)
+	CreateListToken(ctx context.Context, arg CreateListTokenParams) (uuid.UUID, error)
+	CreateMergeCode(ctx context.Context, arg CreateMergeCodeParams) error
+	CreateProposal(ctx context.Context, arg CreateProposalParams) error
+	CreateShareToken(ctx context.Context, arg CreateShareTokenParams) (uuid.UUID, error)
+	CreateSnapshot(ctx context.Context, arg CreateSnapshotParams) error
+	DeleteApiTokensForUser(ctx context.Context, userID string) error
+	DeleteAutomaticSnapshots(ctx context.Context, userID string) error
+	DeleteCloneCodesForUser(ctx context.Context, userID string) error
+	DeleteExpiredLists(ctx context.Context) (int64, error)
+	DeleteFavorite(ctx context.Context, arg DeleteFavoriteParams) error
+	DeleteFavoritesForUser(ctx context.Context, userID string) error
+	DeleteFeedbackForUser(ctx context.Context, userID string) error
 	DeleteInstance(ctx context.Context, arg DeleteInstanceParams) error
+	DeleteInstanceForUndo(ctx context.Context, arg DeleteInstanceForUndoParams) (DeleteInstanceForUndoRow, error)
+	DeleteInstancesByID(ctx context.Context, ids []int32) (int64, error)
+	DeleteInstancesForUser(ctx context.Context, userID string) error
+	DeleteListForUser(ctx context.Context, userID string) error
+	DeleteListToken(ctx context.Context, arg DeleteListTokenParams) (int64, error)
+	DeleteMergeCodesForUser(ctx context.Context, userID string) error
+	DeleteParamStatsBefore(ctx context.Context, computedAt time.Time) error
+	DeleteProposalsForUser(ctx context.Context, userID string) error
+	DeleteSessionsForUser(ctx context.Context, userID string) error
+	DeleteShareToken(ctx context.Context, arg DeleteShareTokenParams) error
+	DeleteSnapshot(ctx context.Context, arg DeleteSnapshotParams) (int64, error)
+	DeleteSnapshotsForUser(ctx context.Context, userID string) error
+	DeleteTemplateAcksForUser(ctx context.Context, userID string) error
+	DeleteTombstonesForUser(ctx context.Context, userID string) error
+	DeleteUserPreferences(ctx context.Context, userID string) error
+	DeleteWebhookDeliveriesForUser(ctx context.Context, userID string) error
+	DeleteWebhookForUser(ctx context.Context, userID string) error
+	GetActiveBans(ctx context.Context) ([]GetActiveBansRow, error)
+	GetAdminActions(ctx context.Context, limit int32) ([]GetAdminActionsRow, error)
+	GetAllApiTokensForUser(ctx context.Context, userID string) ([]GetAllApiTokensForUserRow, error)
+	GetApiTokenForHash(ctx context.Context, tokenHash []byte) (GetApiTokenForHashRow, error)
+	GetApiTokensForUser(ctx context.Context, userID string) ([]GetApiTokensForUserRow, error)
 	GetBannedUsers(ctx context.Context) ([]string, error)
+	GetBooleanParamCounts(ctx context.Context) ([]GetBooleanParamCountsRow, error)
+	GetChangeCursor(ctx context.Context, userID string) (int64, error)
+	GetFavoritesForUser(ctx context.Context, userID string) ([]GetFavoritesForUserRow, error)
+	GetFeedbackCounts(ctx context.Context, createdAt time.Time) ([]GetFeedbackCountsRow, error)
+	GetFeedbackForUser(ctx context.Context, userID string) ([]GetFeedbackForUserRow, error)
+	GetInactiveListUsers(ctx context.Context, inactiveSince time.Time) ([]string, error)
 	GetInstance(ctx context.Context, arg GetInstanceParams) (GetInstanceRow, error)
+	GetInstanceChanges(ctx context.Context, arg GetInstanceChangesParams) ([]GetInstanceChangesRow, error)
+	GetInstanceDetails(ctx context.Context, arg GetInstanceDetailsParams) (GetInstanceDetailsRow, error)
+	GetInstanceHistoryForUser(ctx context.Context, userID string) ([]GetInstanceHistoryForUserRow, error)
+	GetInstanceRenderStatus(ctx context.Context, userID string) ([]GetInstanceRenderStatusRow, error)
 	GetInstanceStats(ctx context.Context) ([]GetInstanceStatsRow, error)
 	GetInstancesForList(ctx context.Context, listID int32) ([]GetInstancesForListRow, error)
+	GetInstancesForTemplates(ctx context.Context, templateNames []string) ([]GetInstancesForTemplatesRow, error)
 	GetInstancesForUser(ctx context.Context, userID string) ([]GetInstancesForUserRow, error)
+	GetLatestTemplateVersions(ctx context.Context) ([]GetLatestTemplateVersionsRow, error)
+	GetListForShareToken(ctx context.Context, token uuid.UUID) (GetListForShareTokenRow, error)
 	GetListForToken(ctx context.Context, token uuid.UUID) (GetListForTokenRow, error)
 	GetListForUser(ctx context.Context, userID string) (GetListForUserRow, error)
+	GetListTokensForUser(ctx context.Context, userID string) ([]GetListTokensForUserRow, error)
+	GetMergeCodeUser(ctx context.Context, codeHash []byte) (string, error)
+	GetOrphanInstances(ctx context.Context, arg GetOrphanInstancesParams) ([]GetOrphanInstancesRow, error)
+	GetParamStats(ctx context.Context) ([]TemplateParamStat, error)
+	GetProposalStatus(ctx context.Context, id int32) (ProposalStatus, error)
+	GetProposals(ctx context.Context, limit int32) ([]FilterProposal, error)
+	GetProposalsForUser(ctx context.Context, userID string) ([]FilterProposal, error)
+	GetRecentFeedbackComments(ctx context.Context, limit int32) ([]TemplateFeedback, error)
+	GetSessionsForUser(ctx context.Context, userID string) ([]GetSessionsForUserRow, error)
+	GetShareTokensForUser(ctx context.Context, userID string) ([]GetShareTokensForUserRow, error)
+	GetSnapshot(ctx context.Context, arg GetSnapshotParams) (GetSnapshotRow, error)
+	GetSnapshotsForUser(ctx context.Context, userID string) ([]GetSnapshotsForUserRow, error)
 	GetStats(ctx context.Context) (GetStatsRow, error)
+	GetSyncInstances(ctx context.Context, userID string) ([]GetSyncInstancesRow, error)
+	GetTemplateAcksForUser(ctx context.Context, userID string) ([]GetTemplateAcksForUserRow, error)
+	GetTemplateDefinition(ctx context.Context, arg GetTemplateDefinitionParams) (string, error)
+	GetTemplatePairs(ctx context.Context, minCount int64) ([]GetTemplatePairsRow, error)
+	GetTemplateVersions(ctx context.Context, limit int32) ([]TemplateVersion, error)
+	GetUnpinnedInstances(ctx context.Context, arg GetUnpinnedInstancesParams) ([]GetUnpinnedInstancesRow, error)
 	GetUserPreferences(ctx context.Context, userID string) (UserPreference, error)
+	GetWebhookDeliveries(ctx context.Context, arg GetWebhookDeliveriesParams) ([]GetWebhookDeliveriesRow, error)
+	GetWebhookForUser(ctx context.Context, userID string) (GetWebhookForUserRow, error)
 	InitUserPreferences(ctx context.Context, userID string) (UserPreference, error)
 	LiftUserBan(ctx context.Context, arg LiftUserBanParams) error
+	LogAdminAction(ctx context.Context, arg LogAdminActionParams) error
+	LogWebhookDelivery(ctx context.Context, arg LogWebhookDeliveryParams) error
+	MarkApiTokenUsed(ctx context.Context, id int32) error
 	MarkListDownloaded(ctx context.Context, token uuid.UUID) error
+	MoveInstance(ctx context.Context, arg MoveInstanceParams) error
+	NormalizeInstance(ctx context.Context, arg NormalizeInstanceParams) error
+	PinInstance(ctx context.Context, arg PinInstanceParams) (int64, error)
+	PruneTemplateDefinitions(ctx context.Context, keep int32) error
+	PruneWebhookDeliveries(ctx context.Context, arg PruneWebhookDeliveriesParams) error
+	RecordInstanceRenders(ctx context.Context, arg RecordInstanceRendersParams) error
+	ReparentInstances(ctx context.Context, ids []int32) (int64, error)
+	RestoreInstance(ctx context.Context, arg RestoreInstanceParams) error
+	RevokeApiToken(ctx context.Context, arg RevokeApiTokenParams) error
+	RevokeOtherSessions(ctx context.Context, arg RevokeOtherSessionsParams) ([]string, error)
 	RotateListToken(ctx context.Context, arg RotateListTokenParams) error
+	SetInstanceTestMode(ctx context.Context, arg SetInstanceTestModeParams) (int64, error)
+	SetWebhookForUser(ctx context.Context, arg SetWebhookForUserParams) error
+	StripFeedbackComment(ctx context.Context, id int32) error
+	TrackSession(ctx context.Context, arg TrackSessionParams) (sql.NullTime, error)
 	UpdateInstance(ctx context.Context, arg UpdateInstanceParams) error
 	UpdateNewsCursor(ctx context.Context, arg UpdateNewsCursorParams) error
+	UpdateProposalStatus(ctx context.Context, arg UpdateProposalStatusParams) error
 	UpdateUserPreferences(ctx context.Context, arg UpdateUserPreferencesParams) error
+	UpsertParamStats(ctx context.Context, arg UpsertParamStatsParams) error
 }
 
 var _ Querier = (*Queries)(nil)
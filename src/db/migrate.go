@@ -0,0 +1,176 @@
+package db
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+	mpgx "github.com/golang-migrate/migrate/v4/database/pgx"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v4"
+)
+
+//go:embed migrations/*.up.sql
+var migrations embed.FS
+
+// ErrSchemaOutdated is returned by CheckSchema when migrations are pending
+var ErrSchemaOutdated = errors.New("database schema is outdated")
+
+type migrateLogger struct {
+	db string
+}
+
+func (m migrateLogger) Printf(format string, v ...interface{}) {
+	format = fmt.Sprintf("migrate[%s]: %s", m.db, format)
+	fmt.Printf(format, v...)
+}
+
+func (m migrateLogger) Verbose() bool {
+	return true
+}
+
+// Migration is one of the embedded schema migrations
+type Migration struct {
+	Version    uint
+	Name       string
+	Statements string
+}
+
+// MigrationStatus compares the schema version of the database with the embedded migrations
+type MigrationStatus struct {
+	Version uint // 0 if no migration was applied
+	Dirty   bool // A migration failed, the schema must be fixed manually
+	Latest  uint
+	Pending []*Migration
+}
+
+// migrator holds a migration instance for the embedded migrations, it must be closed after use
+type migrator struct {
+	*migrate.Migrate
+	source source.Driver
+}
+
+func newMigrator(databaseUrl string) (*migrator, error) {
+	db, err := (&mpgx.Postgres{}).Open(databaseUrl)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open db for migration: %w", err)
+	}
+	src, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("cannot open migration source: %w", err)
+	}
+	instance, err := migrate.NewWithInstance("embedded", src, "pgx", db)
+	if err != nil {
+		_, _ = src.Close(), db.Close()
+		return nil, fmt.Errorf("cannot init migrator: %w", err)
+	}
+	if c, err := pgx.ParseConfig(databaseUrl); err == nil {
+		instance.Log = &migrateLogger{db: c.Database}
+	}
+	return &migrator{Migrate: instance, source: src}, nil
+}
+
+func (m *migrator) close() error {
+	sourceErr, dbErr := m.Close()
+	if sourceErr != nil {
+		return fmt.Errorf("cannot close migration source: %w", sourceErr)
+	}
+	return dbErr
+}
+
+// status lists the embedded migrations newer than the schema version, reading their statements if withSQL is set
+func (m *migrator) status(withSQL bool) (*MigrationStatus, error) {
+	status := &MigrationStatus{}
+	var err error
+	status.Version, status.Dirty, err = m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return nil, fmt.Errorf("cannot read schema version: %w", err)
+	}
+
+	version, err := m.source.First()
+	for err == nil {
+		status.Latest = version
+		if version > status.Version {
+			migration, e := m.readMigration(version, withSQL)
+			if e != nil {
+				return nil, e
+			}
+			status.Pending = append(status.Pending, migration)
+		}
+		version, err = m.source.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("cannot list migrations: %w", err)
+	}
+	return status, nil
+}
+
+func (m *migrator) readMigration(version uint, withSQL bool) (*Migration, error) {
+	reader, name, err := m.source.ReadUp(version)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read migration %d: %w", version, err)
+	}
+	defer reader.Close()
+	migration := &Migration{Version: version, Name: name}
+	if withSQL {
+		statements, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read migration %d: %w", version, err)
+		}
+		migration.Statements = string(statements)
+	}
+	return migration, nil
+}
+
+// Migrate applies the pending migrations. The migrator holds a postgres advisory lock, for
+// replicas starting at the same time to wait for the first one to finish the migration.
+func Migrate(databaseUrl string) error {
+	instance, err := newMigrator(databaseUrl)
+	if err != nil {
+		return err
+	}
+	if err = instance.Up(); err != nil {
+		if err == migrate.ErrNoChange {
+			instance.Log.Printf("No database migration to run\n")
+		} else {
+			_ = instance.close()
+			return fmt.Errorf("migration error: %w", err)
+		}
+	}
+	return instance.close()
+}
+
+// GetMigrationStatus returns the schema version and the pending migrations, with their statements if withSQL is set
+func GetMigrationStatus(databaseUrl string, withSQL bool) (*MigrationStatus, error) {
+	instance, err := newMigrator(databaseUrl)
+	if err != nil {
+		return nil, err
+	}
+	status, err := instance.status(withSQL)
+	if e := instance.close(); err == nil {
+		err = e
+	}
+	return status, err
+}
+
+// CheckSchema fails if migrations are pending, or if a previous migration failed. Schemas
+// newer than the embedded migrations are accepted, for older replicas to keep running
+// during upgrades.
+func CheckSchema(databaseUrl string) error {
+	status, err := GetMigrationStatus(databaseUrl, false)
+	switch {
+	case err != nil:
+		return err
+	case status.Dirty:
+		return fmt.Errorf("migration %d failed, fix the database schema then run `server migrate up`", status.Version)
+	case len(status.Pending) > 0:
+		return fmt.Errorf("%w: version %d, %d migrations pending, run `server migrate up` or enable auto-migrate",
+			ErrSchemaOutdated, status.Version, len(status.Pending))
+	}
+	return nil
+}
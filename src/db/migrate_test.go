@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/gommon/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEmptySchema creates an unmigrated schema for the test duration, and returns its connection string
+func newEmptySchema(t *testing.T) string {
+	conn, err := pgx.Connect(context.Background(), GetTestDatabaseURL())
+	require.NoError(t, err)
+	name := schemaNamePrefix + random.String(16, random.Alphabetic)
+	mustExec(t, conn, createSchemaPattern, name)
+	t.Cleanup(func() {
+		mustExec(t, conn, dropSchemaPattern, name)
+		_ = conn.Close(context.Background())
+	})
+	return buildConnString(name)
+}
+
+func countMigrations(t *testing.T) int {
+	files, err := fs.Glob(migrations, "migrations/*.up.sql")
+	require.NoError(t, err)
+	return len(files)
+}
+
+func TestMigrationStatus_Fresh(t *testing.T) {
+	url := newEmptySchema(t)
+	status, err := GetMigrationStatus(url, false)
+	require.NoError(t, err)
+	assert.Zero(t, status.Version)
+	assert.False(t, status.Dirty)
+	assert.EqualValues(t, countMigrations(t), status.Latest)
+	require.Len(t, status.Pending, countMigrations(t))
+	assert.Equal(t, &Migration{Version: 1, Name: "initial"}, status.Pending[0])
+	assert.ErrorIs(t, CheckSchema(url), ErrSchemaOutdated)
+
+	require.NoError(t, Migrate(url))
+	status, err = GetMigrationStatus(url, false)
+	require.NoError(t, err)
+	assert.Equal(t, status.Latest, status.Version)
+	assert.Empty(t, status.Pending)
+	assert.NoError(t, CheckSchema(url))
+}
+
+func TestMigrationStatus_Partial(t *testing.T) {
+	url := newEmptySchema(t)
+	instance, err := newMigrator(url)
+	require.NoError(t, err)
+	require.NoError(t, instance.Migrate.Migrate(5))
+	require.NoError(t, instance.close())
+
+	status, err := GetMigrationStatus(url, true)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, status.Version)
+	require.Len(t, status.Pending, countMigrations(t)-5)
+	assert.EqualValues(t, 6, status.Pending[0].Version)
+	assert.NotEmpty(t, status.Pending[0].Statements, "dry-runs read the statements")
+	assert.ErrorIs(t, CheckSchema(url), ErrSchemaOutdated)
+
+	status, err = GetMigrationStatus(url, true)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, status.Version, "dry-runs do not apply the migrations")
+
+	require.NoError(t, Migrate(url))
+	assert.NoError(t, CheckSchema(url))
+}
+
+func TestMigrate_Concurrent(t *testing.T) {
+	url := newEmptySchema(t)
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = Migrate(url)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, CheckSchema(url))
+}
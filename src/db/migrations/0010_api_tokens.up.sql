@@ -0,0 +1,14 @@
+CREATE TABLE api_tokens
+(
+    id           SERIAL PRIMARY KEY,
+    user_id      text        NOT NULL,
+    label        text        NOT NULL,
+    token_hash   bytea       NOT NULL,
+    scopes       text[]      NOT NULL CHECK (scopes <@ ARRAY ['read', 'write']),
+    created_at   timestamptz NOT NULL DEFAULT NOW(),
+    last_used_at timestamptz,
+    revoked_at   timestamptz
+);
+
+CREATE UNIQUE INDEX idx_api_tokens_by_hash ON api_tokens USING btree (token_hash);
+CREATE INDEX idx_api_tokens_by_user ON api_tokens USING btree (user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE banned_users ADD COLUMN expires_at timestamptz;
+CREATE INDEX idx_bans_by_user ON banned_users USING btree (user_id);
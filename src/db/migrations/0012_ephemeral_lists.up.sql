@@ -0,0 +1,2 @@
+ALTER TABLE filter_lists ADD COLUMN expires_at timestamptz;
+CREATE INDEX idx_lists_by_expiry ON filter_lists USING btree (expires_at) WHERE expires_at IS NOT NULL;
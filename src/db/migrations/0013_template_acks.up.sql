@@ -0,0 +1,8 @@
+CREATE TABLE template_acks
+(
+    user_id       text        NOT NULL,
+    template_name text        NOT NULL,
+    template_hash text        NOT NULL,
+    acked_at      timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, template_name)
+);
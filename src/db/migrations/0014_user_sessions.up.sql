@@ -0,0 +1,11 @@
+CREATE TABLE user_sessions
+(
+    session_id   text PRIMARY KEY,
+    user_id      text        NOT NULL,
+    user_agent   text        NOT NULL DEFAULT '',
+    created_at   timestamptz NOT NULL DEFAULT NOW(),
+    last_seen_at timestamptz NOT NULL DEFAULT NOW(),
+    revoked_at   timestamptz
+);
+
+CREATE INDEX idx_user_sessions_by_user ON user_sessions USING btree (user_id);
@@ -0,0 +1,10 @@
+CREATE TABLE admin_audit_log
+(
+    id         SERIAL PRIMARY KEY,
+    admin_id   text        NOT NULL,
+    action     text        NOT NULL,
+    target_id  text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_admin_audit_log_by_date ON admin_audit_log USING btree (created_at);
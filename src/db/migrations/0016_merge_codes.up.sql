@@ -0,0 +1,9 @@
+CREATE TABLE merge_codes
+(
+    code_hash  bytea PRIMARY KEY,
+    user_id    text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX idx_merge_codes_by_user ON merge_codes USING btree (user_id);
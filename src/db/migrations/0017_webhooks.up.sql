@@ -0,0 +1,20 @@
+CREATE TABLE user_webhooks
+(
+    user_id    text PRIMARY KEY,
+    url        text        NOT NULL,
+    secret     text        NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE webhook_deliveries
+(
+    id          serial PRIMARY KEY,
+    user_id     text        NOT NULL,
+    event       text        NOT NULL,
+    status_code integer     NOT NULL DEFAULT 0,
+    error       text        NOT NULL DEFAULT '',
+    attempts    integer     NOT NULL,
+    created_at  timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_deliveries_by_user ON webhook_deliveries USING btree (user_id, created_at);
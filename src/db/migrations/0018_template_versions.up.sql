@@ -0,0 +1,10 @@
+CREATE TABLE template_versions
+(
+    id            serial PRIMARY KEY,
+    template_name text        NOT NULL,
+    template_hash text        NOT NULL,
+    title         text        NOT NULL,
+    change        text        NOT NULL,
+    created_at    timestamptz NOT NULL DEFAULT NOW(),
+    UNIQUE (template_name, template_hash)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE template_feedback
+(
+    id            serial PRIMARY KEY,
+    user_id       text        NOT NULL,
+    template_name text        NOT NULL,
+    working       boolean     NOT NULL,
+    browser       text        NOT NULL,
+    adblocker     text        NOT NULL,
+    comment       text        NOT NULL DEFAULT '',
+    created_at    timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_template_feedback_by_user ON template_feedback USING btree (user_id, template_name, created_at);
+CREATE INDEX idx_template_feedback_by_date ON template_feedback USING btree (created_at);
@@ -0,0 +1,18 @@
+CREATE TYPE proposal_status AS ENUM ('new', 'triaged', 'rejected', 'adopted');
+
+CREATE TABLE filter_proposals
+(
+    id                serial PRIMARY KEY,
+    user_id           text            NOT NULL,
+    template_name     text            NOT NULL,
+    target_site       text            NOT NULL,
+    description       text            NOT NULL,
+    example_rules     text            NOT NULL,
+    status            proposal_status NOT NULL DEFAULT 'new',
+    created_at        timestamptz     NOT NULL DEFAULT NOW(),
+    status_changed_at timestamptz     NULL
+);
+
+CREATE INDEX idx_filter_proposals_by_user ON filter_proposals USING btree (user_id, created_at);
+CREATE INDEX idx_filter_proposals_by_status_change ON filter_proposals USING btree (user_id, status_changed_at)
+    WHERE status_changed_at IS NOT NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE list_share_tokens
+(
+    id         SERIAL PRIMARY KEY,
+    list_id    integer     NOT NULL REFERENCES filter_lists (id) ON DELETE CASCADE,
+    token      uuid        NOT NULL DEFAULT gen_random_uuid(),
+    label      text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_share_tokens_by_token ON list_share_tokens USING btree (token);
+CREATE INDEX idx_share_tokens_by_list ON list_share_tokens USING btree (list_id);
@@ -0,0 +1,17 @@
+CREATE TABLE list_tokens
+(
+    id           SERIAL PRIMARY KEY,
+    list_id      integer     NOT NULL REFERENCES filter_lists (id) ON DELETE CASCADE,
+    token        uuid        NOT NULL DEFAULT gen_random_uuid(),
+    label        text        NOT NULL,
+    created_at   timestamptz NOT NULL DEFAULT NOW(),
+    last_used_at timestamptz
+);
+
+CREATE UNIQUE INDEX idx_list_tokens_by_token ON list_tokens USING btree (token);
+CREATE INDEX idx_list_tokens_by_list ON list_tokens USING btree (list_id);
+
+-- The list token becomes the default device token, it is kept in filter_lists to be rotated
+INSERT INTO list_tokens (list_id, token, label, created_at, last_used_at)
+SELECT id, token, 'default', created_at, downloaded_at
+FROM filter_lists;
@@ -0,0 +1,12 @@
+CREATE TABLE list_snapshots
+(
+    id             SERIAL PRIMARY KEY,
+    user_id        text        NOT NULL,
+    name           text        NOT NULL,
+    contents       text        NOT NULL,
+    instance_count integer     NOT NULL,
+    automatic      boolean     NOT NULL DEFAULT false,
+    created_at     timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_snapshots_by_user ON list_snapshots USING btree (user_id);
@@ -0,0 +1,12 @@
+CREATE TABLE template_definitions
+(
+    template_name text        NOT NULL,
+    template_hash text        NOT NULL,
+    definition    text        NOT NULL,
+    seen_at       timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (template_name, template_hash)
+);
+
+-- The template hash is recorded when saving an instance, existing instances are left unknown
+ALTER TABLE filter_instances ADD COLUMN template_hash text NOT NULL DEFAULT '';
+ALTER TABLE filter_instances ADD COLUMN pinned bool NOT NULL DEFAULT false;
@@ -0,0 +1,9 @@
+CREATE TABLE list_clone_codes
+(
+    code_hash  bytea PRIMARY KEY,
+    user_id    text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX idx_clone_codes_by_user ON list_clone_codes USING btree (user_id);
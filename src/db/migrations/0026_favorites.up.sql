@@ -0,0 +1,7 @@
+CREATE TABLE favorites
+(
+    user_id       text        NOT NULL,
+    template_name text        NOT NULL,
+    created_at    timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, template_name)
+);
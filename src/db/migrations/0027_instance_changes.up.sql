@@ -0,0 +1,42 @@
+-- Changes to filter instances are numbered for the sync API: inserts and updates take the next change id,
+-- removals are recorded as tombstones, for clients to poll the changes after the last id they saw.
+CREATE SEQUENCE instance_change_ids;
+
+ALTER TABLE filter_instances ADD COLUMN change_id bigint NOT NULL DEFAULT nextval('instance_change_ids');
+CREATE INDEX idx_instances_by_user_and_change ON filter_instances USING btree (user_id, change_id);
+
+CREATE TABLE instance_tombstones
+(
+    user_id       text        NOT NULL,
+    template_name text        NOT NULL,
+    change_id     bigint      NOT NULL DEFAULT nextval('instance_change_ids'),
+    removed_at    timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, template_name)
+);
+
+CREATE FUNCTION record_instance_change() RETURNS trigger AS
+$$
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        NEW.change_id := nextval('instance_change_ids');
+        IF OLD.user_id = NEW.user_id THEN
+            RETURN NEW;
+        END IF;
+    END IF;
+    -- Deleted, or moved to another account by a merge
+    INSERT INTO instance_tombstones (user_id, template_name)
+    VALUES (OLD.user_id, OLD.template_name)
+    ON CONFLICT (user_id, template_name) DO UPDATE SET change_id  = nextval('instance_change_ids'),
+                                                       removed_at = NOW();
+    IF TG_OP = 'UPDATE' THEN
+        RETURN NEW;
+    END IF;
+    RETURN OLD;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_instance_changes
+    BEFORE UPDATE OR DELETE
+    ON filter_instances
+    FOR EACH ROW
+EXECUTE FUNCTION record_instance_change();
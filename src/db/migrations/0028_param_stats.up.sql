@@ -0,0 +1,9 @@
+CREATE TABLE template_param_stats
+(
+    template_name text        NOT NULL,
+    param_name    text        NOT NULL,
+    enabled       bigint      NOT NULL,
+    disabled      bigint      NOT NULL,
+    computed_at   timestamptz NOT NULL,
+    PRIMARY KEY (template_name, param_name)
+);
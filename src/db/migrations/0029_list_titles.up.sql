@@ -0,0 +1,2 @@
+-- Lists without a title are rendered with the default one
+ALTER TABLE filter_lists ADD COLUMN title text;
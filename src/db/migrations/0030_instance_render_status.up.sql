@@ -0,0 +1,10 @@
+-- Outcome of the last render of each instance, kept apart from filter_instances: writing it must not count as an
+-- instance change for the sync API. An outcome is only shown if the instance was not updated since its render.
+CREATE TABLE instance_render_status
+(
+    list_id       integer     NOT NULL REFERENCES filter_lists (id) ON DELETE CASCADE,
+    template_name text        NOT NULL,
+    rendered_at   timestamptz NOT NULL,
+    render_error  text,
+    PRIMARY KEY (list_id, template_name)
+);
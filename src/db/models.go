@@ -57,6 +57,69 @@ func (ns NullColorMode) Value() (driver.Value, error) {
 	return string(ns.ColorMode), nil
 }
 
+type ProposalStatus string
+
+const (
+	ProposalStatusNew      ProposalStatus = "new"
+	ProposalStatusTriaged  ProposalStatus = "triaged"
+	ProposalStatusRejected ProposalStatus = "rejected"
+	ProposalStatusAdopted  ProposalStatus = "adopted"
+)
+
+func (e *ProposalStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ProposalStatus(s)
+	case string:
+		*e = ProposalStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ProposalStatus: %T", src)
+	}
+	return nil
+}
+
+type NullProposalStatus struct {
+	ProposalStatus ProposalStatus
+	Valid          bool // Valid is true if ProposalStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullProposalStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ProposalStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ProposalStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullProposalStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ProposalStatus), nil
+}
+
+type AdminAuditLog struct {
+	ID        int32
+	AdminID   string
+	Action    string
+	TargetID  string
+	CreatedAt time.Time
+}
+
+type ApiToken struct {
+	ID         int32
+	UserID     string
+	Label      string
+	TokenHash  []byte
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
 type BannedUser struct {
 	ID         int32
 	UserID     string
@@ -64,6 +127,13 @@ type BannedUser struct {
 	Reason     string
 	LiftedAt   sql.NullTime
 	LiftReason sql.NullString
+	ExpiresAt  sql.NullTime
+}
+
+type Favorite struct {
+	UserID       string
+	TemplateName string
+	CreatedAt    time.Time
 }
 
 type FilterInstance struct {
@@ -75,6 +145,9 @@ type FilterInstance struct {
 	CreatedAt    time.Time
 	UpdatedAt    sql.NullTime
 	TestMode     bool
+	TemplateHash string
+	Pinned       bool
+	ChangeID     int64
 }
 
 type FilterList struct {
@@ -83,6 +156,117 @@ type FilterList struct {
 	Token        uuid.UUID
 	CreatedAt    time.Time
 	DownloadedAt sql.NullTime
+	ExpiresAt    sql.NullTime
+	Title        sql.NullString
+}
+
+type FilterProposal struct {
+	ID              int32
+	UserID          string
+	TemplateName    string
+	TargetSite      string
+	Description     string
+	ExampleRules    string
+	Status          ProposalStatus
+	CreatedAt       time.Time
+	StatusChangedAt sql.NullTime
+}
+
+type InstanceRenderStatus struct {
+	ListID       int32
+	TemplateName string
+	RenderedAt   time.Time
+	RenderError  sql.NullString
+}
+
+type InstanceTombstone struct {
+	UserID       string
+	TemplateName string
+	ChangeID     int64
+	RemovedAt    time.Time
+}
+
+type ListCloneCode struct {
+	CodeHash  []byte
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type ListShareToken struct {
+	ID        int32
+	ListID    int32
+	Token     uuid.UUID
+	Label     string
+	CreatedAt time.Time
+}
+
+type ListSnapshot struct {
+	ID            int32
+	UserID        string
+	Name          string
+	Contents      string
+	InstanceCount int32
+	Automatic     bool
+	CreatedAt     time.Time
+}
+
+type ListToken struct {
+	ID         int32
+	ListID     int32
+	Token      uuid.UUID
+	Label      string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+}
+
+type MergeCode struct {
+	CodeHash  []byte
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type TemplateAck struct {
+	UserID       string
+	TemplateName string
+	TemplateHash string
+	AckedAt      time.Time
+}
+
+type TemplateDefinition struct {
+	TemplateName string
+	TemplateHash string
+	Definition   string
+	SeenAt       time.Time
+}
+
+type TemplateFeedback struct {
+	ID           int32
+	UserID       string
+	TemplateName string
+	Working      bool
+	Browser      string
+	Adblocker    string
+	Comment      string
+	CreatedAt    time.Time
+}
+
+type TemplateParamStat struct {
+	TemplateName string
+	ParamName    string
+	Enabled      int64
+	Disabled     int64
+	ComputedAt   time.Time
+}
+
+type TemplateVersion struct {
+	ID           int32
+	TemplateName string
+	TemplateHash string
+	Title        string
+	Change       string
+	CreatedAt    time.Time
 }
 
 type UserPreference struct {
@@ -91,3 +275,29 @@ type UserPreference struct {
 	BetaFeatures bool
 	ColorMode    ColorMode
 }
+
+type UserSession struct {
+	SessionID  string
+	UserID     string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  sql.NullTime
+}
+
+type UserWebhook struct {
+	UserID    string
+	Url       string
+	Secret    string
+	CreatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	ID         int32
+	UserID     string
+	Event      string
+	StatusCode int32
+	Error      string
+	Attempts   int32
+	CreatedAt  time.Time
+}
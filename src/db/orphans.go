@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// OrphanAction is what CleanupOrphanInstances does with the orphan instances it finds
+type OrphanAction string
+
+const (
+	OrphanReport   OrphanAction = "report"   // Only list them, as a dry run
+	OrphanDelete   OrphanAction = "delete"   // Delete them
+	OrphanReparent OrphanAction = "reparent" // Move them to the list of their user, if they have one
+)
+
+// OrphanInstance is a filter instance whose list is missing, or belongs to another user
+type OrphanInstance struct {
+	ID           int32
+	UserID       string
+	TemplateName string
+	ListID       int32
+	MissingList  bool
+	UserListID   int32 // List of the instance's user, zero if they have none
+}
+
+func (o OrphanInstance) String() string {
+	reason := "list belongs to another user"
+	if o.MissingList {
+		reason = "list is missing"
+	}
+	return fmt.Sprintf("instance %d of %s for user %s: list %d %s", o.ID, o.TemplateName, o.UserID, o.ListID, reason)
+}
+
+// OrphanCleanupReport summarizes a run of CleanupOrphanInstances
+type OrphanCleanupReport struct {
+	Instances    []OrphanInstance
+	MissingList  int
+	UserMismatch int
+	Deleted      int64
+	Reparented   int64
+	Kept         int // Orphans left in place, as their user has no list to move them to
+}
+
+// CleanupOrphanInstances scans the filter instances by batches of batchSize, to find the ones whose list is
+// missing or belongs to another user. Each batch is deleted or reparented in its own statement, to not lock
+// the table for long. Running it again only finds the instances that could not be reparented.
+func CleanupOrphanInstances(ctx context.Context, q Querier, action OrphanAction, batchSize int32) (*OrphanCleanupReport, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	report := &OrphanCleanupReport{}
+	var afterID int32
+	for {
+		rows, err := q.GetOrphanInstances(ctx, GetOrphanInstancesParams{AfterID: afterID, BatchSize: batchSize})
+		if err != nil {
+			return report, err
+		}
+		var ids, reparented []int32
+		for _, row := range rows {
+			report.Instances = append(report.Instances, OrphanInstance(row))
+			if row.MissingList {
+				report.MissingList++
+			} else {
+				report.UserMismatch++
+			}
+			ids = append(ids, row.ID)
+			if row.UserListID != 0 {
+				reparented = append(reparented, row.ID)
+			}
+		}
+
+		switch action {
+		case OrphanDelete:
+			if len(ids) > 0 {
+				count, err := q.DeleteInstancesByID(ctx, ids)
+				if err != nil {
+					return report, err
+				}
+				report.Deleted += count
+			}
+		case OrphanReparent:
+			if len(reparented) > 0 {
+				count, err := q.ReparentInstances(ctx, reparented)
+				if err != nil {
+					return report, err
+				}
+				report.Reparented += count
+			}
+			report.Kept += len(ids) - len(reparented)
+		}
+
+		if len(rows) < int(batchSize) {
+			return report, nil
+		}
+		afterID = rows[len(rows)-1].ID
+	}
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanupOrphanInstances(t *testing.T) {
+	ctx := context.Background()
+	store := NewTestStore(t)
+	pool := store.(*pgxStore).pool
+	for _, user := range []string{"valid", "mismatch", "deleted", "missing"} {
+		_, err := store.CreateListForUser(ctx, user)
+		require.NoError(t, err)
+		require.NoError(t, store.CreateInstance(ctx, CreateInstanceParams{UserID: user, TemplateName: "filter1"}))
+	}
+	// The test schemas are cloned without their foreign keys, allowing to create orphans
+	_, err := pool.Exec(ctx, `UPDATE filter_instances SET list_id = (SELECT id FROM filter_lists WHERE user_id = 'valid') WHERE user_id = 'mismatch'`)
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, `DELETE FROM filter_lists WHERE user_id = 'deleted'`)
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, `UPDATE filter_instances SET list_id = 9999 WHERE user_id = 'missing'`)
+	require.NoError(t, err)
+
+	report, err := CleanupOrphanInstances(ctx, store, OrphanReport, 1)
+	require.NoError(t, err)
+	require.Len(t, report.Instances, 3)
+	assert.Equal(t, "mismatch", report.Instances[0].UserID)
+	assert.Equal(t, 2, report.MissingList)
+	assert.Equal(t, 1, report.UserMismatch)
+	assert.Zero(t, report.Deleted+report.Reparented)
+
+	report, err = CleanupOrphanInstances(ctx, store, OrphanReparent, 1)
+	require.NoError(t, err)
+	assert.Len(t, report.Instances, 3)
+	assert.EqualValues(t, 2, report.Reparented)
+	assert.Equal(t, 1, report.Kept)
+
+	// Only the instance without a list to move to is left
+	report, err = CleanupOrphanInstances(ctx, store, OrphanDelete, 10)
+	require.NoError(t, err)
+	require.Len(t, report.Instances, 1)
+	assert.Equal(t, "deleted", report.Instances[0].UserID)
+	assert.EqualValues(t, 1, report.Deleted)
+
+	report, err = CleanupOrphanInstances(ctx, store, OrphanDelete, 10)
+	require.NoError(t, err)
+	assert.Empty(t, report.Instances)
+	var attached int
+	require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM filter_instances i
+    JOIN filter_lists l ON l.id = i.list_id AND l.user_id = i.user_id`).Scan(&attached))
+	assert.Equal(t, 3, attached)
+}
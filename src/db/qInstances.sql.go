@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/jackc/pgtype"
 )
@@ -30,8 +32,8 @@ func (q *Queries) CountInstances(ctx context.Context, arg CountInstancesParams)
 }
 
 const createInstance = `-- name: CreateInstance :exec
-INSERT INTO filter_instances (list_id, user_id, template_name, params, test_mode)
-VALUES ((SELECT id FROM filter_lists WHERE user_id = $1), $1, $2, $3, $4)
+INSERT INTO filter_instances (list_id, user_id, template_name, params, test_mode, template_hash)
+VALUES ((SELECT id FROM filter_lists WHERE user_id = $1), $1, $2, $3, $4, $5)
 `
 
 type CreateInstanceParams struct {
@@ -39,6 +41,7 @@ type CreateInstanceParams struct {
 	TemplateName string
 	Params       pgtype.JSONB
 	TestMode     bool
+	TemplateHash string
 }
 
 func (q *Queries) CreateInstance(ctx context.Context, arg CreateInstanceParams) error {
@@ -47,6 +50,7 @@ func (q *Queries) CreateInstance(ctx context.Context, arg CreateInstanceParams)
 		arg.TemplateName,
 		arg.Params,
 		arg.TestMode,
+		arg.TemplateHash,
 	)
 	return err
 }
@@ -67,10 +71,58 @@ func (q *Queries) DeleteInstance(ctx context.Context, arg DeleteInstanceParams)
 	return err
 }
 
-const getInstance = `-- name: GetInstance :one
-SELECT params, test_mode
+const deleteInstanceForUndo = `-- name: DeleteInstanceForUndo :one
+DELETE
 FROM filter_instances
 WHERE (user_id = $1 AND template_name = $2)
+RETURNING params, test_mode, pinned, template_hash, created_at, updated_at
+`
+
+type DeleteInstanceForUndoParams struct {
+	UserID       string
+	TemplateName string
+}
+
+type DeleteInstanceForUndoRow struct {
+	Params       pgtype.JSONB
+	TestMode     bool
+	Pinned       bool
+	TemplateHash string
+	CreatedAt    time.Time
+	UpdatedAt    sql.NullTime
+}
+
+func (q *Queries) DeleteInstanceForUndo(ctx context.Context, arg DeleteInstanceForUndoParams) (DeleteInstanceForUndoRow, error) {
+	row := q.db.QueryRow(ctx, deleteInstanceForUndo, arg.UserID, arg.TemplateName)
+	var i DeleteInstanceForUndoRow
+	err := row.Scan(
+		&i.Params,
+		&i.TestMode,
+		&i.Pinned,
+		&i.TemplateHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteInstancesForUser = `-- name: DeleteInstancesForUser :exec
+DELETE
+FROM filter_instances
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteInstancesForUser(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, deleteInstancesForUser, userID)
+	return err
+}
+
+const getInstance = `-- name: GetInstance :one
+SELECT i.params, i.test_mode, i.pinned, i.template_hash, s.rendered_at, s.render_error
+FROM filter_instances i
+         LEFT JOIN instance_render_status s ON s.list_id = i.list_id AND s.template_name = i.template_name AND
+                                               s.rendered_at >= coalesce(i.updated_at, i.created_at)
+WHERE (i.user_id = $1 AND i.template_name = $2)
 `
 
 type GetInstanceParams struct {
@@ -79,19 +131,139 @@ type GetInstanceParams struct {
 }
 
 type GetInstanceRow struct {
-	Params   pgtype.JSONB
-	TestMode bool
+	Params       pgtype.JSONB
+	TestMode     bool
+	Pinned       bool
+	TemplateHash string
+	RenderedAt   sql.NullTime
+	RenderError  sql.NullString
 }
 
 func (q *Queries) GetInstance(ctx context.Context, arg GetInstanceParams) (GetInstanceRow, error) {
 	row := q.db.QueryRow(ctx, getInstance, arg.UserID, arg.TemplateName)
 	var i GetInstanceRow
-	err := row.Scan(&i.Params, &i.TestMode)
+	err := row.Scan(
+		&i.Params,
+		&i.TestMode,
+		&i.Pinned,
+		&i.TemplateHash,
+		&i.RenderedAt,
+		&i.RenderError,
+	)
 	return i, err
 }
 
+const getInstanceDetails = `-- name: GetInstanceDetails :one
+SELECT params, test_mode, created_at, updated_at
+FROM filter_instances
+WHERE (user_id = $1 AND template_name = $2)
+`
+
+type GetInstanceDetailsParams struct {
+	UserID       string
+	TemplateName string
+}
+
+type GetInstanceDetailsRow struct {
+	Params    pgtype.JSONB
+	TestMode  bool
+	CreatedAt time.Time
+	UpdatedAt sql.NullTime
+}
+
+func (q *Queries) GetInstanceDetails(ctx context.Context, arg GetInstanceDetailsParams) (GetInstanceDetailsRow, error) {
+	row := q.db.QueryRow(ctx, getInstanceDetails, arg.UserID, arg.TemplateName)
+	var i GetInstanceDetailsRow
+	err := row.Scan(
+		&i.Params,
+		&i.TestMode,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getInstanceHistoryForUser = `-- name: GetInstanceHistoryForUser :many
+SELECT template_name, params, test_mode, created_at, updated_at
+FROM filter_instances
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+type GetInstanceHistoryForUserRow struct {
+	TemplateName string
+	Params       pgtype.JSONB
+	TestMode     bool
+	CreatedAt    time.Time
+	UpdatedAt    sql.NullTime
+}
+
+func (q *Queries) GetInstanceHistoryForUser(ctx context.Context, userID string) ([]GetInstanceHistoryForUserRow, error) {
+	rows, err := q.db.Query(ctx, getInstanceHistoryForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetInstanceHistoryForUserRow
+	for rows.Next() {
+		var i GetInstanceHistoryForUserRow
+		if err := rows.Scan(
+			&i.TemplateName,
+			&i.Params,
+			&i.TestMode,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getInstanceRenderStatus = `-- name: GetInstanceRenderStatus :many
+SELECT s.template_name, s.rendered_at, s.render_error
+FROM instance_render_status s
+         JOIN filter_instances i ON i.list_id = s.list_id AND i.template_name = s.template_name
+WHERE i.user_id = $1
+  AND s.rendered_at >= coalesce(i.updated_at, i.created_at)
+`
+
+type GetInstanceRenderStatusRow struct {
+	TemplateName string
+	RenderedAt   time.Time
+	RenderError  sql.NullString
+}
+
+func (q *Queries) GetInstanceRenderStatus(ctx context.Context, userID string) ([]GetInstanceRenderStatusRow, error) {
+	rows, err := q.db.Query(ctx, getInstanceRenderStatus, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetInstanceRenderStatusRow
+	for rows.Next() {
+		var i GetInstanceRenderStatusRow
+		if err := rows.Scan(
+			&i.TemplateName,
+			&i.RenderedAt,
+			&i.RenderError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getInstancesForList = `-- name: GetInstancesForList :many
-SELECT template_name, params, test_mode
+SELECT template_name, params, test_mode, pinned, template_hash
 FROM filter_instances
 WHERE list_id = $1
 ORDER BY template_name ASC
@@ -101,6 +273,8 @@ type GetInstancesForListRow struct {
 	TemplateName string
 	Params       pgtype.JSONB
 	TestMode     bool
+	Pinned       bool
+	TemplateHash string
 }
 
 func (q *Queries) GetInstancesForList(ctx context.Context, listID int32) ([]GetInstancesForListRow, error) {
@@ -112,7 +286,45 @@ func (q *Queries) GetInstancesForList(ctx context.Context, listID int32) ([]GetI
 	var items []GetInstancesForListRow
 	for rows.Next() {
 		var i GetInstancesForListRow
-		if err := rows.Scan(&i.TemplateName, &i.Params, &i.TestMode); err != nil {
+		if err := rows.Scan(
+			&i.TemplateName,
+			&i.Params,
+			&i.TestMode,
+			&i.Pinned,
+			&i.TemplateHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getInstancesForTemplates = `-- name: GetInstancesForTemplates :many
+SELECT user_id, template_name
+FROM filter_instances
+WHERE template_name = ANY ($1::text[])
+ORDER BY user_id, template_name
+`
+
+type GetInstancesForTemplatesRow struct {
+	UserID       string
+	TemplateName string
+}
+
+func (q *Queries) GetInstancesForTemplates(ctx context.Context, templateNames []string) ([]GetInstancesForTemplatesRow, error) {
+	rows, err := q.db.Query(ctx, getInstancesForTemplates, templateNames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetInstancesForTemplatesRow
+	for rows.Next() {
+		var i GetInstancesForTemplatesRow
+		if err := rows.Scan(&i.UserID, &i.TemplateName); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -124,7 +336,7 @@ func (q *Queries) GetInstancesForList(ctx context.Context, listID int32) ([]GetI
 }
 
 const getInstancesForUser = `-- name: GetInstancesForUser :many
-SELECT template_name, params, test_mode
+SELECT template_name, params, test_mode, pinned, template_hash
 FROM filter_instances
 WHERE user_id = $1
 `
@@ -133,6 +345,8 @@ type GetInstancesForUserRow struct {
 	TemplateName string
 	Params       pgtype.JSONB
 	TestMode     bool
+	Pinned       bool
+	TemplateHash string
 }
 
 func (q *Queries) GetInstancesForUser(ctx context.Context, userID string) ([]GetInstancesForUserRow, error) {
@@ -144,7 +358,59 @@ func (q *Queries) GetInstancesForUser(ctx context.Context, userID string) ([]Get
 	var items []GetInstancesForUserRow
 	for rows.Next() {
 		var i GetInstancesForUserRow
-		if err := rows.Scan(&i.TemplateName, &i.Params, &i.TestMode); err != nil {
+		if err := rows.Scan(
+			&i.TemplateName,
+			&i.Params,
+			&i.TestMode,
+			&i.Pinned,
+			&i.TemplateHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnpinnedInstances = `-- name: GetUnpinnedInstances :many
+SELECT id, template_name, params, change_id
+FROM filter_instances
+WHERE id > $1
+  AND pinned = false
+ORDER BY id
+LIMIT $2
+`
+
+type GetUnpinnedInstancesParams struct {
+	AfterID   int32
+	BatchSize int32
+}
+
+type GetUnpinnedInstancesRow struct {
+	ID           int32
+	TemplateName string
+	Params       pgtype.JSONB
+	ChangeID     int64
+}
+
+func (q *Queries) GetUnpinnedInstances(ctx context.Context, arg GetUnpinnedInstancesParams) ([]GetUnpinnedInstancesRow, error) {
+	rows, err := q.db.Query(ctx, getUnpinnedInstances, arg.AfterID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnpinnedInstancesRow
+	for rows.Next() {
+		var i GetUnpinnedInstancesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TemplateName,
+			&i.Params,
+			&i.ChangeID,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -155,11 +421,124 @@ func (q *Queries) GetInstancesForUser(ctx context.Context, userID string) ([]Get
 	return items, nil
 }
 
+const normalizeInstance = `-- name: NormalizeInstance :exec
+UPDATE filter_instances
+SET params = $1
+WHERE id = $2
+  AND change_id = $3
+`
+
+type NormalizeInstanceParams struct {
+	Params   pgtype.JSONB
+	ID       int32
+	ChangeID int64
+}
+
+func (q *Queries) NormalizeInstance(ctx context.Context, arg NormalizeInstanceParams) error {
+	_, err := q.db.Exec(ctx, normalizeInstance,
+		arg.Params,
+		arg.ID,
+		arg.ChangeID,
+	)
+	return err
+}
+
+const pinInstance = `-- name: PinInstance :execrows
+UPDATE filter_instances
+SET pinned        = $3,
+    template_hash = $4,
+    updated_at    = NOW()
+WHERE (user_id = $1 AND template_name = $2)
+`
+
+type PinInstanceParams struct {
+	UserID       string
+	TemplateName string
+	Pinned       bool
+	TemplateHash string
+}
+
+func (q *Queries) PinInstance(ctx context.Context, arg PinInstanceParams) (int64, error) {
+	result, err := q.db.Exec(ctx, pinInstance,
+		arg.UserID,
+		arg.TemplateName,
+		arg.Pinned,
+		arg.TemplateHash,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordInstanceRenders = `-- name: RecordInstanceRenders :exec
+INSERT INTO instance_render_status (list_id, template_name, rendered_at, render_error)
+SELECT r.list_id, r.template_name, r.rendered_at, NULLIF(r.render_error, '')
+FROM (SELECT unnest($1::int[])            AS list_id,
+             unnest($2::text[])     AS template_name,
+             unnest($3::text[])      AS render_error,
+             unnest($4::timestamptz[]) AS rendered_at) r
+         JOIN filter_instances i ON i.list_id = r.list_id AND i.template_name = r.template_name
+WHERE coalesce(i.updated_at, i.created_at) <= r.rendered_at
+ON CONFLICT (list_id, template_name) DO UPDATE SET rendered_at  = excluded.rendered_at,
+                                                   render_error = excluded.render_error
+WHERE instance_render_status.rendered_at < excluded.rendered_at
+`
+
+type RecordInstanceRendersParams struct {
+	ListIds       []int32
+	TemplateNames []string
+	RenderErrors  []string
+	RenderedAt    []time.Time
+}
+
+func (q *Queries) RecordInstanceRenders(ctx context.Context, arg RecordInstanceRendersParams) error {
+	_, err := q.db.Exec(ctx, recordInstanceRenders,
+		arg.ListIds,
+		arg.TemplateNames,
+		arg.RenderErrors,
+		arg.RenderedAt,
+	)
+	return err
+}
+
+const restoreInstance = `-- name: RestoreInstance :exec
+INSERT INTO filter_instances (list_id, user_id, template_name, params, test_mode, pinned, template_hash, created_at,
+                              updated_at)
+VALUES ((SELECT id FROM filter_lists WHERE user_id = $1), $1, $2, $3, $4, $5, $6, $7, $8)
+`
+
+type RestoreInstanceParams struct {
+	UserID       string
+	TemplateName string
+	Params       pgtype.JSONB
+	TestMode     bool
+	Pinned       bool
+	TemplateHash string
+	CreatedAt    time.Time
+	UpdatedAt    sql.NullTime
+}
+
+func (q *Queries) RestoreInstance(ctx context.Context, arg RestoreInstanceParams) error {
+	_, err := q.db.Exec(ctx, restoreInstance,
+		arg.UserID,
+		arg.TemplateName,
+		arg.Params,
+		arg.TestMode,
+		arg.Pinned,
+		arg.TemplateHash,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
 const updateInstance = `-- name: UpdateInstance :exec
 UPDATE filter_instances
-SET params     = $3,
-    test_mode  = $4,
-    updated_at = NOW()
+SET params        = $3,
+    test_mode     = $4,
+    template_hash = CASE WHEN pinned THEN template_hash ELSE $5 END,
+    updated_at    = NOW()
 WHERE (user_id = $1 AND template_name = $2)
 `
 
@@ -168,6 +547,7 @@ type UpdateInstanceParams struct {
 	TemplateName string
 	Params       pgtype.JSONB
 	TestMode     bool
+	TemplateHash string
 }
 
 func (q *Queries) UpdateInstance(ctx context.Context, arg UpdateInstanceParams) error {
@@ -176,6 +556,7 @@ func (q *Queries) UpdateInstance(ctx context.Context, arg UpdateInstanceParams)
 		arg.TemplateName,
 		arg.Params,
 		arg.TestMode,
+		arg.TemplateHash,
 	)
 	return err
 }
@@ -8,10 +8,45 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const adoptEphemeralInstances = `-- name: AdoptEphemeralInstances :exec
+UPDATE filter_instances
+SET user_id = $1
+WHERE user_id = $2
+`
+
+type AdoptEphemeralInstancesParams struct {
+	NewUserID       string
+	EphemeralUserID string
+}
+
+func (q *Queries) AdoptEphemeralInstances(ctx context.Context, arg AdoptEphemeralInstancesParams) error {
+	_, err := q.db.Exec(ctx, adoptEphemeralInstances, arg.NewUserID, arg.EphemeralUserID)
+	return err
+}
+
+const adoptEphemeralList = `-- name: AdoptEphemeralList :exec
+UPDATE filter_lists
+SET user_id    = $1,
+    expires_at = NULL
+WHERE user_id = $2
+  AND expires_at > NOW()
+`
+
+type AdoptEphemeralListParams struct {
+	NewUserID       string
+	EphemeralUserID string
+}
+
+func (q *Queries) AdoptEphemeralList(ctx context.Context, arg AdoptEphemeralListParams) error {
+	_, err := q.db.Exec(ctx, adoptEphemeralList, arg.NewUserID, arg.EphemeralUserID)
+	return err
+}
+
 const countListsForUser = `-- name: CountListsForUser :one
 SELECT COUNT(*)
 FROM filter_lists
@@ -25,9 +60,39 @@ func (q *Queries) CountListsForUser(ctx context.Context, userID string) (int64,
 	return count, err
 }
 
+const createEphemeralList = `-- name: CreateEphemeralList :one
+WITH list AS (
+    INSERT INTO filter_lists (user_id, expires_at)
+        VALUES ($1, $2)
+        RETURNING id, token)
+INSERT
+INTO list_tokens (list_id, token, label)
+SELECT id, token, 'default'
+FROM list
+RETURNING token
+`
+
+type CreateEphemeralListParams struct {
+	UserID    string
+	ExpiresAt sql.NullTime
+}
+
+func (q *Queries) CreateEphemeralList(ctx context.Context, arg CreateEphemeralListParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, createEphemeralList, arg.UserID, arg.ExpiresAt)
+	var token uuid.UUID
+	err := row.Scan(&token)
+	return token, err
+}
+
 const createListForUser = `-- name: CreateListForUser :one
-INSERT INTO filter_lists (user_id)
-VALUES ($1)
+WITH list AS (
+    INSERT INTO filter_lists (user_id)
+        VALUES ($1)
+        RETURNING id, token)
+INSERT
+INTO list_tokens (list_id, token, label)
+SELECT id, token, 'default'
+FROM list
 RETURNING token
 `
 
@@ -38,23 +103,100 @@ func (q *Queries) CreateListForUser(ctx context.Context, userID string) (uuid.UU
 	return token, err
 }
 
+const deleteExpiredLists = `-- name: DeleteExpiredLists :execrows
+DELETE
+FROM filter_lists
+WHERE expires_at < NOW()
+`
+
+func (q *Queries) DeleteExpiredLists(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredLists)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteListForUser = `-- name: DeleteListForUser :exec
+DELETE
+FROM filter_lists
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteListForUser(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, deleteListForUser, userID)
+	return err
+}
+
+const getInactiveListUsers = `-- name: GetInactiveListUsers :many
+SELECT fl.user_id
+FROM filter_lists fl
+WHERE fl.expires_at IS NULL
+  AND coalesce(fl.downloaded_at, fl.created_at) < $1::timestamptz
+  AND EXISTS(SELECT 1 FROM filter_instances fi WHERE fi.user_id = fl.user_id)
+  AND NOT EXISTS(SELECT 1
+                 FROM filter_instances fi
+                 WHERE fi.user_id = fl.user_id
+                   AND coalesce(fi.updated_at, fi.created_at) >= $1::timestamptz)
+ORDER BY fl.user_id
+`
+
+func (q *Queries) GetInactiveListUsers(ctx context.Context, inactiveSince time.Time) ([]string, error) {
+	rows, err := q.db.Query(ctx, getInactiveListUsers, inactiveSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var user_id string
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getListForToken = `-- name: GetListForToken :one
 SELECT fl.id,
        fl.user_id,
        fl.downloaded_at,
+       fl.title,
        (SELECT max(coalesce(fi.updated_at, fi.created_at))
         from filter_instances fi
-        where fi.list_id = fl.id) as last_updated
+        where fi.list_id = fl.id) as last_updated,
+       (SELECT coalesce(string_agg(fi.template_name || ':' || fi.template_hash, ',' ORDER BY fi.template_name), '')
+        from filter_instances fi
+        where fi.list_id = fl.id
+          and fi.pinned)::text as pinned_versions,
+       greatest((SELECT max(fi.change_id) FROM filter_instances fi WHERE fi.list_id = fl.id),
+                (SELECT max(it.change_id) FROM instance_tombstones it WHERE it.user_id = fl.user_id),
+                0)::bigint as last_change
 FROM filter_lists fl
-WHERE token = $1
+         JOIN (SELECT lt.list_id
+               FROM list_tokens lt
+               WHERE lt.token = $1
+               UNION ALL
+               SELECT st.list_id
+               FROM list_share_tokens st
+               WHERE st.token = $1) t ON t.list_id = fl.id
+WHERE fl.expires_at IS NULL
+   OR fl.expires_at > NOW()
 LIMIT 1
 `
 
 type GetListForTokenRow struct {
-	ID           int32
-	UserID       string
-	DownloadedAt sql.NullTime
-	LastUpdated  interface{}
+	ID             int32
+	UserID         string
+	DownloadedAt   sql.NullTime
+	Title          sql.NullString
+	LastUpdated    interface{}
+	PinnedVersions string
+	LastChange     int64
 }
 
 func (q *Queries) GetListForToken(ctx context.Context, token uuid.UUID) (GetListForTokenRow, error) {
@@ -64,14 +206,19 @@ func (q *Queries) GetListForToken(ctx context.Context, token uuid.UUID) (GetList
 		&i.ID,
 		&i.UserID,
 		&i.DownloadedAt,
+		&i.Title,
 		&i.LastUpdated,
+		&i.PinnedVersions,
+		&i.LastChange,
 	)
 	return i, err
 }
 
 const getListForUser = `-- name: GetListForUser :one
-SELECT token,
+SELECT id,
+       token,
        downloaded_at,
+       title,
        (SELECT COUNT(*) FROM filter_instances WHERE filter_instances.user_id = $1) AS instance_count
 FROM filter_lists
 WHERE filter_lists.user_id = $1
@@ -79,22 +226,35 @@ LIMIT 1
 `
 
 type GetListForUserRow struct {
+	ID            int32
 	Token         uuid.UUID
 	DownloadedAt  sql.NullTime
+	Title         sql.NullString
 	InstanceCount int64
 }
 
 func (q *Queries) GetListForUser(ctx context.Context, userID string) (GetListForUserRow, error) {
 	row := q.db.QueryRow(ctx, getListForUser, userID)
 	var i GetListForUserRow
-	err := row.Scan(&i.Token, &i.DownloadedAt, &i.InstanceCount)
+	err := row.Scan(
+		&i.ID,
+		&i.Token,
+		&i.DownloadedAt,
+		&i.Title,
+		&i.InstanceCount,
+	)
 	return i, err
 }
 
 const markListDownloaded = `-- name: MarkListDownloaded :exec
+WITH used AS (
+    UPDATE list_tokens
+        SET last_used_at = NOW()
+        WHERE token = $1
+        RETURNING list_id)
 UPDATE filter_lists
 SET downloaded_at = NOW()
-WHERE token = $1
+WHERE id IN (SELECT list_id FROM used)
 `
 
 func (q *Queries) MarkListDownloaded(ctx context.Context, token uuid.UUID) error {
@@ -103,11 +263,19 @@ func (q *Queries) MarkListDownloaded(ctx context.Context, token uuid.UUID) error
 }
 
 const rotateListToken = `-- name: RotateListToken :exec
-UPDATE filter_lists
-SET token      = gen_random_uuid(),
-    downloaded_at = NULL
-WHERE user_id = $1
-  AND token = $2
+WITH rotated AS (
+    UPDATE filter_lists
+        SET token = gen_random_uuid(),
+            downloaded_at = NULL
+        WHERE user_id = $1
+            AND token = $2
+        RETURNING id, token)
+UPDATE list_tokens
+SET token        = rotated.token,
+    last_used_at = NULL
+FROM rotated
+WHERE list_tokens.list_id = rotated.id
+  AND list_tokens.token = $2
 `
 
 type RotateListTokenParams struct {
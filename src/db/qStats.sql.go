@@ -47,18 +47,67 @@ func (q *Queries) GetInstanceStats(ctx context.Context) ([]GetInstanceStatsRow,
 const getStats = `-- name: GetStats :one
 SELECT (SELECT COUNT(*) FROM filter_lists)                                                  as lists_total,
        (SELECT COUNT(*) FROM filter_lists WHERE downloaded_at IS NOT NULL)                  as lists_active,
-       (SELECT COUNT(*) FROM filter_lists WHERE downloaded_at >= NOW() - INTERVAL '7 DAYS') as lists_fresh
+       (SELECT COUNT(*) FROM filter_lists WHERE downloaded_at >= NOW() - INTERVAL '7 DAYS') as lists_fresh,
+       (SELECT COUNT(*) FROM filter_lists WHERE downloaded_at >= NOW() - INTERVAL '1 DAY')  as lists_daily,
+       (SELECT COUNT(*) FROM filter_instances)                                              as instances_total,
+       (SELECT COUNT(*) FROM user_preferences)                                              as users_total
 `
 
 type GetStatsRow struct {
-	ListsTotal  int64
-	ListsActive int64
-	ListsFresh  int64
+	ListsTotal     int64
+	ListsActive    int64
+	ListsFresh     int64
+	ListsDaily     int64
+	InstancesTotal int64
+	UsersTotal     int64
 }
 
 func (q *Queries) GetStats(ctx context.Context) (GetStatsRow, error) {
 	row := q.db.QueryRow(ctx, getStats)
 	var i GetStatsRow
-	err := row.Scan(&i.ListsTotal, &i.ListsActive, &i.ListsFresh)
+	err := row.Scan(
+		&i.ListsTotal,
+		&i.ListsActive,
+		&i.ListsFresh,
+		&i.ListsDaily,
+		&i.InstancesTotal,
+		&i.UsersTotal,
+	)
 	return i, err
 }
+
+const getTemplatePairs = `-- name: GetTemplatePairs :many
+SELECT a.template_name AS first_template,
+       b.template_name AS second_template,
+       COUNT(*)        AS total
+FROM filter_instances AS a
+         JOIN filter_instances AS b ON (a.list_id = b.list_id AND a.template_name < b.template_name)
+GROUP BY a.template_name, b.template_name
+HAVING COUNT(*) >= $1::bigint
+`
+
+type GetTemplatePairsRow struct {
+	FirstTemplate  string
+	SecondTemplate string
+	Total          int64
+}
+
+func (q *Queries) GetTemplatePairs(ctx context.Context, minCount int64) ([]GetTemplatePairsRow, error) {
+	rows, err := q.db.Query(ctx, getTemplatePairs, minCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTemplatePairsRow
+	for rows.Next() {
+		var i GetTemplatePairsRow
+		if err := rows.Scan(&i.FirstTemplate, &i.SecondTemplate, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
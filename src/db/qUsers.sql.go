@@ -12,24 +12,77 @@ import (
 )
 
 const addUserBan = `-- name: AddUserBan :exec
-INSERT INTO banned_users (user_id, reason)
-VALUES ($1,$2)
+INSERT INTO banned_users (user_id, reason, expires_at)
+VALUES ($1,$2,$3)
 `
 
 type AddUserBanParams struct {
-	UserID string
-	Reason string
+	UserID    string
+	Reason    string
+	ExpiresAt sql.NullTime
 }
 
 func (q *Queries) AddUserBan(ctx context.Context, arg AddUserBanParams) error {
-	_, err := q.db.Exec(ctx, addUserBan, arg.UserID, arg.Reason)
+	_, err := q.db.Exec(ctx, addUserBan, arg.UserID, arg.Reason, arg.ExpiresAt)
 	return err
 }
 
+const deleteUserPreferences = `-- name: DeleteUserPreferences :exec
+DELETE
+FROM user_preferences
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserPreferences(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, deleteUserPreferences, userID)
+	return err
+}
+
+const getActiveBans = `-- name: GetActiveBans :many
+SELECT user_id, reason, created_at, expires_at
+FROM banned_users
+WHERE lifted_at IS NULL
+  AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at DESC
+`
+
+type GetActiveBansRow struct {
+	UserID    string
+	Reason    string
+	CreatedAt time.Time
+	ExpiresAt sql.NullTime
+}
+
+func (q *Queries) GetActiveBans(ctx context.Context) ([]GetActiveBansRow, error) {
+	rows, err := q.db.Query(ctx, getActiveBans)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetActiveBansRow
+	for rows.Next() {
+		var i GetActiveBansRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Reason,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getBannedUsers = `-- name: GetBannedUsers :many
 SELECT user_id
 from banned_users
 WHERE lifted_at IS NULL
+  AND (expires_at IS NULL OR expires_at > NOW())
 `
 
 func (q *Queries) GetBannedUsers(ctx context.Context) ([]string, error) {
@@ -92,7 +145,7 @@ const liftUserBan = `-- name: LiftUserBan :exec
 UPDATE banned_users
 SET lift_reason     = $2,
     lifted_at = NOW()
-WHERE (user_id = $1)
+WHERE (user_id = $1 AND lifted_at IS NULL)
 `
 
 type LiftUserBanParams struct {
@@ -0,0 +1,9 @@
+-- name: LogAdminAction :exec
+INSERT INTO admin_audit_log (admin_id, action, target_id)
+VALUES ($1, $2, $3);
+
+-- name: GetAdminActions :many
+SELECT admin_id, action, target_id, created_at
+FROM admin_audit_log
+ORDER BY created_at DESC
+LIMIT $1;
@@ -0,0 +1,27 @@
+-- name: CreateCloneCode :exec
+INSERT INTO list_clone_codes (code_hash, user_id, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: ClaimCloneCode :one
+DELETE
+FROM list_clone_codes
+WHERE code_hash = $1
+  AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: DeleteCloneCodesForUser :exec
+DELETE
+FROM list_clone_codes
+WHERE user_id = $1;
+
+-- name: CopyInstances :execrows
+INSERT INTO filter_instances (list_id, user_id, template_name, params, test_mode, template_hash, pinned)
+SELECT (SELECT id FROM filter_lists WHERE filter_lists.user_id = @new_user_id),
+       @new_user_id,
+       template_name,
+       params,
+       test_mode,
+       template_hash,
+       pinned
+FROM filter_instances
+WHERE user_id = @old_user_id;
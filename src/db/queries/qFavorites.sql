@@ -0,0 +1,20 @@
+-- name: AddFavorite :exec
+INSERT INTO favorites (user_id, template_name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteFavorite :exec
+DELETE
+FROM favorites
+WHERE (user_id = $1 AND template_name = $2);
+
+-- name: DeleteFavoritesForUser :exec
+DELETE
+FROM favorites
+WHERE user_id = $1;
+
+-- name: GetFavoritesForUser :many
+SELECT template_name, created_at
+FROM favorites
+WHERE user_id = $1
+ORDER BY template_name;
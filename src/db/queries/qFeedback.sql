@@ -0,0 +1,42 @@
+-- name: AddTemplateFeedback :exec
+INSERT INTO template_feedback (user_id, template_name, working, browser, adblocker, comment)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: CountRecentFeedback :one
+SELECT COUNT(*)
+FROM template_feedback
+WHERE user_id = $1
+  AND template_name = $2
+  AND created_at > $3;
+
+-- name: GetFeedbackCounts :many
+SELECT template_name,
+       COUNT(*) FILTER (WHERE working)     AS working,
+       COUNT(*) FILTER (WHERE NOT working) AS not_working
+FROM template_feedback
+WHERE created_at > $1
+GROUP BY template_name
+ORDER BY not_working DESC, template_name;
+
+-- name: GetRecentFeedbackComments :many
+SELECT *
+FROM template_feedback
+WHERE comment != ''
+ORDER BY id DESC
+LIMIT $1;
+
+-- name: GetFeedbackForUser :many
+SELECT template_name, working, browser, adblocker, comment, created_at
+FROM template_feedback
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: StripFeedbackComment :exec
+UPDATE template_feedback
+SET comment = ''
+WHERE id = $1;
+
+-- name: DeleteFeedbackForUser :exec
+DELETE
+FROM template_feedback
+WHERE user_id = $1;
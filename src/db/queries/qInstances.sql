@@ -1,24 +1,34 @@
 -- name: GetInstancesForUser :many
-SELECT template_name, params, test_mode
+SELECT template_name, params, test_mode, pinned, template_hash
 FROM filter_instances
 WHERE user_id = $1;
 
 -- name: CreateInstance :exec
-INSERT INTO filter_instances (list_id, user_id, template_name, params, test_mode)
-VALUES ((SELECT id FROM filter_lists WHERE user_id = $1), $1, $2, $3, $4);
+INSERT INTO filter_instances (list_id, user_id, template_name, params, test_mode, template_hash)
+VALUES ((SELECT id FROM filter_lists WHERE user_id = $1), $1, $2, $3, $4, $5);
 
 -- name: UpdateInstance :exec
 UPDATE filter_instances
-SET params     = $3,
-    test_mode  = $4,
-    updated_at = NOW()
+SET params        = $3,
+    test_mode     = $4,
+    template_hash = CASE WHEN pinned THEN template_hash ELSE $5 END,
+    updated_at    = NOW()
 WHERE (user_id = $1 AND template_name = $2);
 
--- name: GetInstance :one
-SELECT params, test_mode
-FROM filter_instances
+-- name: PinInstance :execrows
+UPDATE filter_instances
+SET pinned        = $3,
+    template_hash = $4,
+    updated_at    = NOW()
 WHERE (user_id = $1 AND template_name = $2);
 
+-- name: GetInstance :one
+SELECT i.params, i.test_mode, i.pinned, i.template_hash, s.rendered_at, s.render_error
+FROM filter_instances i
+         LEFT JOIN instance_render_status s ON s.list_id = i.list_id AND s.template_name = i.template_name AND
+                                               s.rendered_at >= coalesce(i.updated_at, i.created_at)
+WHERE (i.user_id = $1 AND i.template_name = $2);
+
 -- name: CountInstances :one
 SELECT COUNT(*)
 FROM filter_instances
@@ -29,8 +39,75 @@ DELETE
 FROM filter_instances
 WHERE (user_id = $1 AND template_name = $2);
 
+-- name: DeleteInstanceForUndo :one
+DELETE
+FROM filter_instances
+WHERE (user_id = $1 AND template_name = $2)
+RETURNING params, test_mode, pinned, template_hash, created_at, updated_at;
+
+-- name: RestoreInstance :exec
+INSERT INTO filter_instances (list_id, user_id, template_name, params, test_mode, pinned, template_hash, created_at,
+                              updated_at)
+VALUES ((SELECT id FROM filter_lists WHERE user_id = $1), $1, $2, $3, $4, $5, $6, $7, $8);
+
 -- name: GetInstancesForList :many
-SELECT template_name, params, test_mode
+SELECT template_name, params, test_mode, pinned, template_hash
 FROM filter_instances
 WHERE list_id = $1
 ORDER BY template_name ASC;
+
+-- name: DeleteInstancesForUser :exec
+DELETE
+FROM filter_instances
+WHERE user_id = $1;
+
+-- name: GetInstanceHistoryForUser :many
+SELECT template_name, params, test_mode, created_at, updated_at
+FROM filter_instances
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetInstanceDetails :one
+SELECT params, test_mode, created_at, updated_at
+FROM filter_instances
+WHERE (user_id = $1 AND template_name = $2);
+
+-- name: GetInstancesForTemplates :many
+SELECT user_id, template_name
+FROM filter_instances
+WHERE template_name = ANY (@template_names::text[])
+ORDER BY user_id, template_name;
+
+-- name: GetInstanceRenderStatus :many
+SELECT s.template_name, s.rendered_at, s.render_error
+FROM instance_render_status s
+         JOIN filter_instances i ON i.list_id = s.list_id AND i.template_name = s.template_name
+WHERE i.user_id = $1
+  AND s.rendered_at >= coalesce(i.updated_at, i.created_at);
+
+-- name: RecordInstanceRenders :exec
+INSERT INTO instance_render_status (list_id, template_name, rendered_at, render_error)
+SELECT r.list_id, r.template_name, r.rendered_at, NULLIF(r.render_error, '')
+FROM (SELECT unnest(@list_ids::int[])            AS list_id,
+             unnest(@template_names::text[])     AS template_name,
+             unnest(@render_errors::text[])      AS render_error,
+             unnest(@rendered_at::timestamptz[]) AS rendered_at) r
+         JOIN filter_instances i ON i.list_id = r.list_id AND i.template_name = r.template_name
+WHERE coalesce(i.updated_at, i.created_at) <= r.rendered_at
+ON CONFLICT (list_id, template_name) DO UPDATE SET rendered_at  = excluded.rendered_at,
+                                                   render_error = excluded.render_error
+WHERE instance_render_status.rendered_at < excluded.rendered_at;
+
+-- name: GetUnpinnedInstances :many
+SELECT id, template_name, params, change_id
+FROM filter_instances
+WHERE id > @after_id
+  AND pinned = false
+ORDER BY id
+LIMIT @batch_size;
+
+-- name: NormalizeInstance :exec
+UPDATE filter_instances
+SET params = @params
+WHERE id = @id
+  AND change_id = @change_id;
@@ -0,0 +1,35 @@
+-- name: CreateListToken :one
+INSERT INTO list_tokens (list_id, label)
+SELECT id, @label::text
+FROM filter_lists
+WHERE user_id = @user_id
+  AND expires_at IS NULL
+LIMIT 1
+RETURNING token;
+
+-- name: GetListTokensForUser :many
+SELECT lt.id,
+       lt.token,
+       lt.label,
+       lt.created_at,
+       lt.last_used_at,
+       (lt.token = fl.token) AS is_default
+FROM list_tokens lt
+         JOIN filter_lists fl ON fl.id = lt.list_id
+WHERE fl.user_id = $1
+ORDER BY lt.created_at ASC;
+
+-- name: CountListTokensForUser :one
+SELECT COUNT(*)
+FROM list_tokens lt
+         JOIN filter_lists fl ON fl.id = lt.list_id
+WHERE fl.user_id = $1;
+
+-- name: DeleteListToken :execrows
+DELETE
+FROM list_tokens lt
+    USING filter_lists fl
+WHERE lt.id = @id
+  AND fl.id = lt.list_id
+  AND fl.user_id = @user_id
+  AND lt.token <> fl.token;
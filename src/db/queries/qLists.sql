@@ -1,11 +1,19 @@
 -- name: CreateListForUser :one
-INSERT INTO filter_lists (user_id)
-VALUES ($1)
+WITH list AS (
+    INSERT INTO filter_lists (user_id)
+        VALUES ($1)
+        RETURNING id, token)
+INSERT
+INTO list_tokens (list_id, token, label)
+SELECT id, token, 'default'
+FROM list
 RETURNING token;
 
 -- name: GetListForUser :one
-SELECT token,
+SELECT id,
+       token,
        downloaded_at,
+       title,
        (SELECT COUNT(*) FROM filter_instances WHERE filter_instances.user_id = $1) AS instance_count
 FROM filter_lists
 WHERE filter_lists.user_id = $1
@@ -17,24 +25,98 @@ FROM filter_lists
 WHERE user_id = $1;
 
 -- name: RotateListToken :exec
-UPDATE filter_lists
-SET token      = gen_random_uuid(),
-    downloaded_at = NULL
-WHERE user_id = $1
-  AND token = $2;
+WITH rotated AS (
+    UPDATE filter_lists
+        SET token = gen_random_uuid(),
+            downloaded_at = NULL
+        WHERE user_id = $1
+            AND token = $2
+        RETURNING id, token)
+UPDATE list_tokens
+SET token        = rotated.token,
+    last_used_at = NULL
+FROM rotated
+WHERE list_tokens.list_id = rotated.id
+  AND list_tokens.token = $2;
 
 -- name: GetListForToken :one
 SELECT fl.id,
        fl.user_id,
        fl.downloaded_at,
+       fl.title,
        (SELECT max(coalesce(fi.updated_at, fi.created_at))
         from filter_instances fi
-        where fi.list_id = fl.id) as last_updated
+        where fi.list_id = fl.id) as last_updated,
+       (SELECT coalesce(string_agg(fi.template_name || ':' || fi.template_hash, ',' ORDER BY fi.template_name), '')
+        from filter_instances fi
+        where fi.list_id = fl.id
+          and fi.pinned)::text as pinned_versions,
+       greatest((SELECT max(fi.change_id) FROM filter_instances fi WHERE fi.list_id = fl.id),
+                (SELECT max(it.change_id) FROM instance_tombstones it WHERE it.user_id = fl.user_id),
+                0)::bigint as last_change
 FROM filter_lists fl
-WHERE token = $1
+         JOIN (SELECT lt.list_id
+               FROM list_tokens lt
+               WHERE lt.token = $1
+               UNION ALL
+               SELECT st.list_id
+               FROM list_share_tokens st
+               WHERE st.token = $1) t ON t.list_id = fl.id
+WHERE fl.expires_at IS NULL
+   OR fl.expires_at > NOW()
 LIMIT 1;
 
 -- name: MarkListDownloaded :exec
+WITH used AS (
+    UPDATE list_tokens
+        SET last_used_at = NOW()
+        WHERE token = $1
+        RETURNING list_id)
 UPDATE filter_lists
 SET downloaded_at = NOW()
-WHERE token = $1;
+WHERE id IN (SELECT list_id FROM used);
+
+-- name: DeleteListForUser :exec
+DELETE
+FROM filter_lists
+WHERE user_id = $1;
+
+-- name: CreateEphemeralList :one
+WITH list AS (
+    INSERT INTO filter_lists (user_id, expires_at)
+        VALUES ($1, $2)
+        RETURNING id, token)
+INSERT
+INTO list_tokens (list_id, token, label)
+SELECT id, token, 'default'
+FROM list
+RETURNING token;
+
+-- name: AdoptEphemeralList :exec
+UPDATE filter_lists
+SET user_id    = @new_user_id,
+    expires_at = NULL
+WHERE user_id = @ephemeral_user_id
+  AND expires_at > NOW();
+
+-- name: AdoptEphemeralInstances :exec
+UPDATE filter_instances
+SET user_id = @new_user_id
+WHERE user_id = @ephemeral_user_id;
+
+-- name: DeleteExpiredLists :execrows
+DELETE
+FROM filter_lists
+WHERE expires_at < NOW();
+
+-- name: GetInactiveListUsers :many
+SELECT fl.user_id
+FROM filter_lists fl
+WHERE fl.expires_at IS NULL
+  AND coalesce(fl.downloaded_at, fl.created_at) < @inactive_since::timestamptz
+  AND EXISTS(SELECT 1 FROM filter_instances fi WHERE fi.user_id = fl.user_id)
+  AND NOT EXISTS(SELECT 1
+                 FROM filter_instances fi
+                 WHERE fi.user_id = fl.user_id
+                   AND coalesce(fi.updated_at, fi.created_at) >= @inactive_since::timestamptz)
+ORDER BY fl.user_id;
@@ -0,0 +1,22 @@
+-- name: CreateMergeCode :exec
+INSERT INTO merge_codes (code_hash, user_id, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: GetMergeCodeUser :one
+SELECT user_id
+FROM merge_codes
+WHERE code_hash = $1
+  AND expires_at > NOW();
+
+-- name: DeleteMergeCodesForUser :exec
+DELETE
+FROM merge_codes
+WHERE user_id = $1;
+
+-- name: MoveInstance :exec
+UPDATE filter_instances
+SET user_id    = @new_user_id,
+    list_id    = (SELECT id FROM filter_lists WHERE filter_lists.user_id = @new_user_id),
+    updated_at = NOW()
+WHERE user_id = @old_user_id
+  AND template_name = @template_name;
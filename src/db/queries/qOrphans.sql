@@ -0,0 +1,26 @@
+-- name: GetOrphanInstances :many
+SELECT i.id,
+       i.user_id,
+       i.template_name,
+       i.list_id,
+       (l.id IS NULL)::bool     AS missing_list,
+       COALESCE(own.id, 0)::int AS user_list_id
+FROM filter_instances i
+         LEFT JOIN filter_lists l ON l.id = i.list_id
+         LEFT JOIN filter_lists own ON own.user_id = i.user_id
+WHERE i.id > @after_id
+  AND (l.id IS NULL OR l.user_id != i.user_id)
+ORDER BY i.id
+LIMIT @batch_size;
+
+-- name: DeleteInstancesByID :execrows
+DELETE
+FROM filter_instances
+WHERE id = ANY (@ids::int[]);
+
+-- name: ReparentInstances :execrows
+UPDATE filter_instances i
+SET list_id = l.id
+FROM filter_lists l
+WHERE i.id = ANY (@ids::int[])
+  AND l.user_id = i.user_id;
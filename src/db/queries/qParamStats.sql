@@ -0,0 +1,30 @@
+-- name: GetBooleanParamCounts :many
+SELECT i.template_name,
+       p.key                                            AS param_name,
+       COUNT(*) FILTER (WHERE p.value = 'true'::jsonb)  AS enabled,
+       COUNT(*) FILTER (WHERE p.value = 'false'::jsonb) AS disabled
+FROM filter_instances i,
+     jsonb_each(i.params) p
+WHERE jsonb_typeof(p.value) = 'boolean'
+GROUP BY i.template_name, p.key;
+
+-- name: UpsertParamStats :exec
+INSERT INTO template_param_stats (template_name, param_name, enabled, disabled, computed_at)
+SELECT unnest(@template_names::text[]),
+       unnest(@param_names::text[]),
+       unnest(@enabled::bigint[]),
+       unnest(@disabled::bigint[]),
+       @computed_at::timestamptz
+ON CONFLICT (template_name, param_name) DO UPDATE SET enabled     = excluded.enabled,
+                                                      disabled    = excluded.disabled,
+                                                      computed_at = excluded.computed_at;
+
+-- name: DeleteParamStatsBefore :exec
+DELETE
+FROM template_param_stats
+WHERE computed_at < @computed_at::timestamptz;
+
+-- name: GetParamStats :many
+SELECT *
+FROM template_param_stats
+ORDER BY template_name, param_name;
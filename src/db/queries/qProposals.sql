@@ -0,0 +1,43 @@
+-- name: CreateProposal :exec
+INSERT INTO filter_proposals (user_id, template_name, target_site, description, example_rules)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: CountRecentProposals :one
+SELECT COUNT(*)
+FROM filter_proposals
+WHERE user_id = $1
+  AND created_at > $2;
+
+-- name: GetProposals :many
+SELECT *
+FROM filter_proposals
+ORDER BY id DESC
+LIMIT $1;
+
+-- name: GetProposalsForUser :many
+SELECT *
+FROM filter_proposals
+WHERE user_id = $1
+ORDER BY id DESC;
+
+-- name: GetProposalStatus :one
+SELECT status
+FROM filter_proposals
+WHERE id = $1;
+
+-- name: UpdateProposalStatus :exec
+UPDATE filter_proposals
+SET status            = $2,
+    status_changed_at = $3
+WHERE id = $1;
+
+-- name: CountProposalUpdates :one
+SELECT COUNT(*)
+FROM filter_proposals
+WHERE user_id = $1
+  AND status_changed_at > $2;
+
+-- name: DeleteProposalsForUser :exec
+DELETE
+FROM filter_proposals
+WHERE user_id = $1;
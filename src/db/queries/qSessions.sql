@@ -0,0 +1,26 @@
+-- name: TrackSession :one
+INSERT INTO user_sessions (session_id, user_id, user_agent)
+VALUES ($1, $2, $3)
+ON CONFLICT (session_id) DO UPDATE SET last_seen_at = NOW(),
+                                       user_agent   = EXCLUDED.user_agent
+RETURNING revoked_at;
+
+-- name: GetSessionsForUser :many
+SELECT session_id, user_agent, created_at, last_seen_at
+FROM user_sessions
+WHERE user_id = $1
+  AND revoked_at IS NULL
+ORDER BY last_seen_at DESC;
+
+-- name: RevokeOtherSessions :many
+UPDATE user_sessions
+SET revoked_at = NOW()
+WHERE user_id = $1
+  AND session_id != $2
+  AND revoked_at IS NULL
+RETURNING session_id;
+
+-- name: DeleteSessionsForUser :exec
+DELETE
+FROM user_sessions
+WHERE user_id = $1;
@@ -0,0 +1,37 @@
+-- name: CreateShareToken :one
+INSERT INTO list_share_tokens (list_id, label)
+SELECT id, @label::text
+FROM filter_lists
+WHERE user_id = @user_id
+  AND expires_at IS NULL
+LIMIT 1
+RETURNING token;
+
+-- name: GetShareTokensForUser :many
+SELECT st.id, st.token, st.label, st.created_at
+FROM list_share_tokens st
+         JOIN filter_lists fl ON fl.id = st.list_id
+WHERE fl.user_id = $1
+ORDER BY st.created_at ASC;
+
+-- name: CountShareTokensForUser :one
+SELECT COUNT(*)
+FROM list_share_tokens st
+         JOIN filter_lists fl ON fl.id = st.list_id
+WHERE fl.user_id = $1;
+
+-- name: DeleteShareToken :exec
+DELETE
+FROM list_share_tokens
+WHERE id = @id
+  AND list_id IN (SELECT fl.id FROM filter_lists fl WHERE fl.user_id = @user_id);
+
+-- name: GetListForShareToken :one
+SELECT fl.id,
+       fl.user_id,
+       st.label
+FROM list_share_tokens st
+         JOIN filter_lists fl ON fl.id = st.list_id
+WHERE st.token = $1
+  AND (fl.expires_at IS NULL OR fl.expires_at > NOW())
+LIMIT 1;
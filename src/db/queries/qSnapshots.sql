@@ -0,0 +1,38 @@
+-- name: CreateSnapshot :exec
+INSERT INTO list_snapshots (user_id, name, contents, instance_count, automatic)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetSnapshotsForUser :many
+SELECT id, name, instance_count, automatic, created_at
+FROM list_snapshots
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetSnapshot :one
+SELECT name, contents
+FROM list_snapshots
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: CountSnapshotsForUser :one
+SELECT COUNT(*)
+FROM list_snapshots
+WHERE user_id = $1
+  AND automatic = false;
+
+-- name: DeleteSnapshot :execrows
+DELETE
+FROM list_snapshots
+WHERE id = $1
+  AND user_id = $2;
+
+-- name: DeleteAutomaticSnapshots :exec
+DELETE
+FROM list_snapshots
+WHERE user_id = $1
+  AND automatic = true;
+
+-- name: DeleteSnapshotsForUser :exec
+DELETE
+FROM list_snapshots
+WHERE user_id = $1;
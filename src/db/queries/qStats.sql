@@ -1,7 +1,10 @@
 -- name: GetStats :one
 SELECT (SELECT COUNT(*) FROM filter_lists)                                                  as lists_total,
        (SELECT COUNT(*) FROM filter_lists WHERE downloaded_at IS NOT NULL)                  as lists_active,
-       (SELECT COUNT(*) FROM filter_lists WHERE downloaded_at >= NOW() - INTERVAL '7 DAYS') as lists_fresh;
+       (SELECT COUNT(*) FROM filter_lists WHERE downloaded_at >= NOW() - INTERVAL '7 DAYS') as lists_fresh,
+       (SELECT COUNT(*) FROM filter_lists WHERE downloaded_at >= NOW() - INTERVAL '1 DAY')  as lists_daily,
+       (SELECT COUNT(*) FROM filter_instances)                                              as instances_total,
+       (SELECT COUNT(*) FROM user_preferences)                                              as users_total;
 
 -- name: GetInstanceStats :many
 SELECT COUNT(*) as total,
@@ -10,3 +13,12 @@ SELECT COUNT(*) as total,
 FROM filter_instances
          JOIN filter_lists AS l ON (list_id = l.id)
 GROUP BY template_name;
+
+-- name: GetTemplatePairs :many
+SELECT a.template_name AS first_template,
+       b.template_name AS second_template,
+       COUNT(*)        AS total
+FROM filter_instances AS a
+         JOIN filter_instances AS b ON (a.list_id = b.list_id AND a.template_name < b.template_name)
+GROUP BY a.template_name, b.template_name
+HAVING COUNT(*) >= @min_count::bigint;
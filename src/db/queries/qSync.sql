@@ -0,0 +1,31 @@
+-- name: DeleteTombstonesForUser :exec
+DELETE
+FROM instance_tombstones
+WHERE user_id = $1;
+
+-- name: GetChangeCursor :one
+SELECT greatest((SELECT max(change_id) FROM filter_instances fi WHERE fi.user_id = $1),
+                (SELECT max(change_id) FROM instance_tombstones it WHERE it.user_id = $1),
+                0)::bigint AS cursor;
+
+-- name: GetInstanceChanges :many
+SELECT template_name, test_mode, change_id, false AS removed
+FROM filter_instances
+WHERE (user_id = $1 AND change_id > $2)
+UNION ALL
+SELECT template_name, false, change_id, true
+FROM instance_tombstones
+WHERE (user_id = $1 AND change_id > $2)
+ORDER BY change_id;
+
+-- name: GetSyncInstances :many
+SELECT template_name, test_mode, change_id
+FROM filter_instances
+WHERE user_id = $1
+ORDER BY change_id;
+
+-- name: SetInstanceTestMode :execrows
+UPDATE filter_instances
+SET test_mode  = $3,
+    updated_at = NOW()
+WHERE (user_id = $1 AND template_name = $2);
@@ -0,0 +1,15 @@
+-- name: AckTemplate :exec
+INSERT INTO template_acks (user_id, template_name, template_hash)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, template_name) DO UPDATE SET template_hash = EXCLUDED.template_hash,
+                                                   acked_at      = NOW();
+
+-- name: GetTemplateAcksForUser :many
+SELECT template_name, template_hash
+FROM template_acks
+WHERE user_id = $1;
+
+-- name: DeleteTemplateAcksForUser :exec
+DELETE
+FROM template_acks
+WHERE user_id = $1;
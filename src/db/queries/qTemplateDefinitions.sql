@@ -0,0 +1,22 @@
+-- name: AddTemplateDefinitions :exec
+INSERT INTO template_definitions (template_name, template_hash, definition)
+SELECT unnest(@template_names::text[]),
+       unnest(@template_hashes::text[]),
+       unnest(@definitions::text[])
+ON CONFLICT (template_name, template_hash) DO UPDATE SET seen_at = NOW();
+
+-- name: GetTemplateDefinition :one
+SELECT definition
+FROM template_definitions
+WHERE (template_name = $1 AND template_hash = $2);
+
+-- name: PruneTemplateDefinitions :exec
+DELETE
+FROM template_definitions td
+    USING (SELECT template_name,
+                  template_hash,
+                  row_number() OVER (PARTITION BY template_name ORDER BY seen_at DESC) AS position
+           FROM template_definitions) ranked
+WHERE td.template_name = ranked.template_name
+  AND td.template_hash = ranked.template_hash
+  AND ranked.position > @keep::int;
@@ -0,0 +1,19 @@
+-- name: GetLatestTemplateVersions :many
+SELECT DISTINCT ON (template_name) template_name, template_hash, created_at
+FROM template_versions
+ORDER BY template_name, id DESC;
+
+-- name: AddTemplateVersions :exec
+INSERT INTO template_versions (template_name, template_hash, title, change)
+SELECT unnest(@template_names::text[]),
+       unnest(@template_hashes::text[]),
+       unnest(@titles::text[]),
+       unnest(@changes::text[])
+ON CONFLICT (template_name, template_hash) DO NOTHING;
+
+-- name: GetTemplateVersions :many
+SELECT *
+FROM template_versions
+WHERE change != 'baseline'
+ORDER BY id DESC
+LIMIT $1;
@@ -0,0 +1,47 @@
+-- name: CreateApiToken :exec
+INSERT INTO api_tokens (user_id, label, token_hash, scopes)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetApiTokensForUser :many
+SELECT id, label, scopes, created_at, last_used_at
+FROM api_tokens
+WHERE user_id = $1
+  AND revoked_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: GetApiTokenForHash :one
+SELECT t.id,
+       t.user_id,
+       t.scopes,
+       EXISTS(SELECT 1
+              FROM banned_users b
+              WHERE b.user_id = t.user_id
+                AND b.lifted_at IS NULL
+                AND (b.expires_at IS NULL OR b.expires_at > NOW())) AS banned
+FROM api_tokens t
+WHERE t.token_hash = $1
+  AND t.revoked_at IS NULL
+LIMIT 1;
+
+-- name: MarkApiTokenUsed :exec
+UPDATE api_tokens
+SET last_used_at = NOW()
+WHERE id = $1;
+
+-- name: RevokeApiToken :exec
+UPDATE api_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1
+  AND id = $2
+  AND revoked_at IS NULL;
+
+-- name: DeleteApiTokensForUser :exec
+DELETE
+FROM api_tokens
+WHERE user_id = $1;
+
+-- name: GetAllApiTokensForUser :many
+SELECT label, scopes, created_at, last_used_at, revoked_at
+FROM api_tokens
+WHERE user_id = $1
+ORDER BY created_at ASC;
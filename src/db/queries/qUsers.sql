@@ -1,17 +1,25 @@
 -- name: GetBannedUsers :many
 SELECT user_id
 from banned_users
-WHERE lifted_at IS NULL;
+WHERE lifted_at IS NULL
+  AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: GetActiveBans :many
+SELECT user_id, reason, created_at, expires_at
+FROM banned_users
+WHERE lifted_at IS NULL
+  AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at DESC;
 
 -- name: AddUserBan :exec
-INSERT INTO banned_users (user_id, reason)
-VALUES ($1,$2);
+INSERT INTO banned_users (user_id, reason, expires_at)
+VALUES ($1,$2,$3);
 
 -- name: LiftUserBan :exec
 UPDATE banned_users
 SET lift_reason     = $2,
     lifted_at = NOW()
-WHERE (user_id = $1);
+WHERE (user_id = $1 AND lifted_at IS NULL);
 
 -- name: InitUserPreferences :one
 INSERT INTO user_preferences (user_id)
@@ -33,3 +41,8 @@ UPDATE user_preferences
 SET color_mode    = $2,
     beta_features = $3
 WHERE user_id = $1;
+
+-- name: DeleteUserPreferences :exec
+DELETE
+FROM user_preferences
+WHERE user_id = $1;
@@ -0,0 +1,42 @@
+-- name: GetWebhookForUser :one
+SELECT url, secret
+FROM user_webhooks
+WHERE user_id = $1;
+
+-- name: SetWebhookForUser :exec
+INSERT INTO user_webhooks (user_id, url, secret)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE SET url        = EXCLUDED.url,
+                                    secret     = EXCLUDED.secret,
+                                    created_at = NOW();
+
+-- name: DeleteWebhookForUser :exec
+DELETE
+FROM user_webhooks
+WHERE user_id = $1;
+
+-- name: LogWebhookDelivery :exec
+INSERT INTO webhook_deliveries (user_id, event, status_code, error, attempts)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetWebhookDeliveries :many
+SELECT event, status_code, error, attempts, created_at
+FROM webhook_deliveries
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: PruneWebhookDeliveries :exec
+DELETE
+FROM webhook_deliveries
+WHERE user_id = @user_id
+  AND id NOT IN (SELECT id
+                 FROM webhook_deliveries AS d
+                 WHERE d.user_id = @user_id
+                 ORDER BY created_at DESC, id DESC
+                 LIMIT @keep::integer);
+
+-- name: DeleteWebhookDeliveriesForUser :exec
+DELETE
+FROM webhook_deliveries
+WHERE user_id = $1;
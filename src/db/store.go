@@ -2,125 +2,231 @@ package db
 
 import (
 	"context"
-	"embed"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/DataDog/datadog-go/v5/statsd"
-	"github.com/golang-migrate/migrate/v4"
-	mpgx "github.com/golang-migrate/migrate/v4/database/pgx"
-	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
-	//go:embed migrations/*.up.sql
-	migrations embed.FS
-	NotFound   = pgx.ErrNoRows
+	NotFound = pgx.ErrNoRows
+	tracer   = otel.Tracer("github.com/letsblockit/letsblockit/src/db")
 )
 
-type migrateLogger struct {
-	db string
+type Store interface {
+	Querier
+	RunTx(e echo.Context, f TxFunc) error
+	RunTxContext(ctx context.Context, f TxFunc) error
+	Ping(ctx context.Context) error
+	PoolStats() PoolStats
+	Close()
 }
 
-func (m migrateLogger) Printf(format string, v ...interface{}) {
-	format = fmt.Sprintf("migrate[%s]: %s", m.db, format)
-	fmt.Printf(format, v...)
+type TxFunc func(context.Context, Querier) error
+
+type requestIDKey struct{}
+
+// WithRequestID stores the request ID in the context, to add it to RunTx errors
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
 }
 
-func (m migrateLogger) Verbose() bool {
-	return true
+// RequestID returns the request ID stored by WithRequestID, or an empty string
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
 }
 
-type Store interface {
-	Querier
-	RunTx(e echo.Context, f TxFunc) error
+// TxError wraps the errors returned by RunTx, to correlate them with the request logs
+type TxError struct {
+	RequestID string
+	Err       error
 }
 
-type TxFunc func(context.Context, Querier) error
+func (e *TxError) Error() string {
+	return fmt.Sprintf("request %s: %s", e.RequestID, e.Err)
+}
+
+func (e *TxError) Unwrap() error {
+	return e.Err
+}
+
+// SlowQueryLog logs the queries slower than Threshold with their sqlc name, a zero threshold disables it
+type SlowQueryLog struct {
+	Threshold time.Duration
+	Logger    echo.Logger
+}
+
+// PoolStats is a snapshot of the connection pool usage, acquire counters are cumulative
+type PoolStats struct {
+	Acquired        int32
+	Idle            int32
+	Total           int32
+	Max             int32
+	Acquires        int64
+	EmptyAcquires   int64 // Acquires that waited for a connection to be released or opened
+	AcquireDuration time.Duration
+}
 
 type pgxStore struct {
 	*Queries
 	pool *pgxpool.Pool
+	dsd  statsd.ClientInterface
+	slow SlowQueryLog
 }
 
 func (s *pgxStore) RunTx(e echo.Context, f TxFunc) error {
-	c := e.Request().Context()
-	return s.pool.BeginFunc(c, func(tx pgx.Tx) error {
-		return f(c, New(tx))
-	})
+	return s.RunTxContext(e.Request().Context(), f)
 }
 
-func Connect(databaseUrl, poolOptions string, dsd statsd.ClientInterface) (Store, error) {
-	pool, err := pgxpool.Connect(context.Background(), databaseUrl+poolOptions)
-	if err != nil {
-		return nil, fmt.Errorf("cannot connect: %w", err)
+// RunTxContext runs a transaction outside of a request, for the maintenance commands
+func (s *pgxStore) RunTxContext(ctx context.Context, f TxFunc) error {
+	c, span := tracer.Start(ctx, "db.RunTx")
+	defer span.End()
+	start := time.Now()
+	err := s.pool.BeginFunc(c, func(tx pgx.Tx) error {
+		// Queries are traced, but only the transaction duration is recorded as a metric
+		return f(c, New(&instrumentedDB{db: tx, slow: s.slow}))
+	})
+	_ = s.dsd.Distribution("letsblockit.pg_transaction_duration", float64(time.Since(start).Nanoseconds()),
+		[]string{fmt.Sprintf("success:%t", err == nil)}, 1)
+	if _, ok := err.(*echo.HTTPError); err != nil && !ok {
+		span.SetStatus(codes.Error, err.Error())
 	}
-	return &pgxStore{
-		Queries: New(&instrumentedDB{pool, dsd}),
-		pool:    pool,
-	}, nil
+	return wrapTxError(c, err)
 }
 
-func Migrate(databaseUrl string) error {
-	db, err := (&mpgx.Postgres{}).Open(databaseUrl)
-	if err != nil {
-		return fmt.Errorf("cannot open db for migration: %w", err)
-	}
-	source, err := iofs.New(migrations, "migrations")
-	if err != nil {
-		return fmt.Errorf("cannot open migration source: %w", err)
+// Ping checks that the pool can execute a query
+func (s *pgxStore) Ping(ctx context.Context) error {
+	_, err := s.pool.Exec(ctx, "SELECT 1")
+	return err
+}
+
+// PoolStats returns the current usage of the connection pool
+func (s *pgxStore) PoolStats() PoolStats {
+	stat := s.pool.Stat()
+	return PoolStats{
+		Acquired:        stat.AcquiredConns(),
+		Idle:            stat.IdleConns(),
+		Total:           stat.TotalConns(),
+		Max:             stat.MaxConns(),
+		Acquires:        stat.AcquireCount(),
+		EmptyAcquires:   stat.EmptyAcquireCount(),
+		AcquireDuration: stat.AcquireDuration(),
 	}
+}
 
-	instance, err := migrate.NewWithInstance("embedded", source, "pgx", db)
-	if err != nil {
-		return fmt.Errorf("cannot init migrator: %w", err)
+// Close waits for the acquired connections to be released, and closes the pool
+func (s *pgxStore) Close() {
+	s.pool.Close()
+}
+
+// wrapTxError adds the request ID to internal errors, HTTP errors are returned as-is for echo to render them
+func wrapTxError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
 	}
-	if c, err := pgx.ParseConfig(databaseUrl); err == nil {
-		instance.Log = &migrateLogger{db: c.Database}
+	if _, ok := err.(*echo.HTTPError); ok {
+		return err
 	}
-
-	if err = instance.Up(); err != nil {
-		if err == migrate.ErrNoChange {
-			instance.Log.Printf("No database migration to run\n")
-		} else {
-			return fmt.Errorf("migration error: %w", err)
-		}
+	if id := RequestID(ctx); id != "" {
+		return &TxError{RequestID: id, Err: err}
 	}
-	if err = source.Close(); err != nil {
-		return fmt.Errorf("cannot close migration source: %w", err)
+	return err
+}
+
+func Connect(databaseUrl, poolOptions string, dsd statsd.ClientInterface, slow SlowQueryLog) (Store, error) {
+	pool, err := pgxpool.Connect(context.Background(), databaseUrl+poolOptions)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect: %w", err)
 	}
-	return db.Close()
+	return &pgxStore{
+		Queries: New(&instrumentedDB{db: pool, dsd: dsd, slow: slow}),
+		pool:    pool,
+		dsd:     dsd,
+		slow:    slow,
+	}, nil
 }
 
+// instrumentedDB traces queries, records their duration if dsd is set, and logs the slow ones
 type instrumentedDB struct {
-	db  DBTX
-	dsd statsd.ClientInterface
+	db   DBTX
+	dsd  statsd.ClientInterface
+	slow SlowQueryLog
 }
 
 func (i instrumentedDB) Exec(ctx context.Context, q string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := startQuerySpan(ctx, q)
+	defer span.End()
 	start := time.Now()
 	tag, err := i.db.Exec(ctx, q, args...)
-	_ = i.dsd.Distribution("letsblockit.pg_request_duration", float64(time.Since(start).Nanoseconds()),
-		[]string{"type:exec"}, 1)
+	i.observe(ctx, "exec", q, start)
+	endQuerySpan(span, err)
 	return tag, err
 }
 
 func (i instrumentedDB) Query(ctx context.Context, q string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := startQuerySpan(ctx, q)
+	defer span.End()
 	start := time.Now()
 	rows, err := i.db.Query(ctx, q, args...)
-	_ = i.dsd.Distribution("letsblockit.pg_request_duration", float64(time.Since(start).Nanoseconds()),
-		[]string{"type:query"}, 1)
+	i.observe(ctx, "query", q, start)
+	endQuerySpan(span, err)
 	return rows, err
 }
 
+// QueryRow errors are only returned when scanning the row, they are not recorded in the span
 func (i instrumentedDB) QueryRow(ctx context.Context, q string, args ...interface{}) pgx.Row {
+	ctx, span := startQuerySpan(ctx, q)
+	defer span.End()
 	start := time.Now()
 	row := i.db.QueryRow(ctx, q, args...)
-	_ = i.dsd.Distribution("letsblockit.pg_request_duration", float64(time.Since(start).Nanoseconds()),
-		[]string{"type:query_row"}, 1)
+	i.observe(ctx, "query_row", q, start)
 	return row
 }
+
+// observe only parses the query name of slow queries, to keep the fast path cheap
+func (i instrumentedDB) observe(ctx context.Context, kind, q string, start time.Time) {
+	elapsed := time.Since(start)
+	if i.dsd != nil {
+		_ = i.dsd.Distribution("letsblockit.pg_request_duration", float64(elapsed.Nanoseconds()),
+			[]string{"type:" + kind}, 1)
+	}
+	if i.slow.Threshold > 0 && elapsed > i.slow.Threshold && i.slow.Logger != nil {
+		if id := RequestID(ctx); id != "" {
+			i.slow.Logger.Warnf("slow query: %s took %s, request %s", queryName(q), elapsed, id)
+		} else {
+			i.slow.Logger.Warnf("slow query: %s took %s", queryName(q), elapsed)
+		}
+	}
+}
+
+func startQuerySpan(ctx context.Context, q string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "db."+queryName(q), trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql")))
+}
+
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil && err != NotFound {
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// queryName returns the name of sqlc queries, set in their "-- name: GetListForToken :one" header
+func queryName(q string) string {
+	const prefix = "-- name: "
+	if !strings.HasPrefix(q, prefix) {
+		return "query"
+	}
+	name, _, _ := strings.Cut(q[len(prefix):], " ")
+	return name
+}
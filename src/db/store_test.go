@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowDB waits for delay before returning from Exec
+type slowDB struct {
+	DBTX
+	delay time.Duration
+}
+
+func (d slowDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	time.Sleep(d.delay)
+	return nil, nil
+}
+
+func (d slowDB) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	time.Sleep(d.delay)
+	return nil
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	var out strings.Builder
+	logger := echo.New().Logger
+	logger.SetOutput(&out)
+	logger.SetLevel(log.WARN)
+	ctx := WithRequestID(context.Background(), "abcd")
+
+	fast := instrumentedDB{db: slowDB{}, slow: SlowQueryLog{Threshold: time.Hour, Logger: logger}}
+	_, err := fast.Exec(ctx, deleteInstance)
+	require.NoError(t, err)
+	assert.Empty(t, out.String())
+
+	slow := instrumentedDB{db: slowDB{delay: 2 * time.Millisecond}, slow: SlowQueryLog{Threshold: time.Millisecond, Logger: logger}}
+	_, err = slow.Exec(ctx, deleteInstance)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "slow query: DeleteInstance took ")
+	assert.Contains(t, out.String(), "request abcd")
+	assert.NotContains(t, out.String(), "DELETE")
+
+	out.Reset()
+	_ = slow.QueryRow(context.Background(), "SELECT 1")
+	assert.Contains(t, out.String(), "slow query: query took ")
+	assert.NotContains(t, out.String(), "request")
+
+	disabled := instrumentedDB{db: slowDB{delay: 2 * time.Millisecond}, slow: SlowQueryLog{Logger: logger}}
+	out.Reset()
+	_, err = disabled.Exec(ctx, deleteInstance)
+	require.NoError(t, err)
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// DeleteUserData deletes all the data of a user, bans are kept to prevent evasion.
+// It must run in a transaction, to not leave a partially deleted account behind.
+func DeleteUserData(ctx context.Context, q Querier, user string) error {
+	if err := q.DeleteApiTokensForUser(ctx, user); err != nil {
+		return err
+	}
+	if err := q.DeleteSessionsForUser(ctx, user); err != nil {
+		return err
+	}
+	if err := q.DeleteTemplateAcksForUser(ctx, user); err != nil {
+		return err
+	}
+	if err := q.DeleteMergeCodesForUser(ctx, user); err != nil {
+		return err
+	}
+	if err := q.DeleteCloneCodesForUser(ctx, user); err != nil {
+		return err
+	}
+	if err := q.DeleteWebhookForUser(ctx, user); err != nil {
+		return err
+	}
+	if err := q.DeleteWebhookDeliveriesForUser(ctx, user); err != nil {
+		return err
+	}
+	if err := q.DeleteFeedbackForUser(ctx, user); err != nil {
+		return err
+	}
+	if err := q.DeleteProposalsForUser(ctx, user); err != nil {
+		return err
+	}
+	if err := q.DeleteSnapshotsForUser(ctx, user); err != nil {
+		return err
+	}
+	if err := q.DeleteFavoritesForUser(ctx, user); err != nil {
+		return err
+	}
+	if err := q.DeleteInstancesForUser(ctx, user); err != nil {
+		return err
+	}
+	if err := q.DeleteListForUser(ctx, user); err != nil {
+		return err
+	}
+	// The instance deletions above recorded tombstones for the sync API
+	if err := q.DeleteTombstonesForUser(ctx, user); err != nil {
+		return err
+	}
+	return q.DeleteUserPreferences(ctx, user)
+}
+
+// BannedPurgeReport summarizes a run of PurgeBannedUsers
+type BannedPurgeReport struct {
+	Users  []string // Users with an active ban
+	Purged int
+	Failed []string // Users whose batch failed, they are purged by the next run
+}
+
+// PurgeBannedUsers deletes the data of the users with an active ban, like if they had deleted their account.
+// Users are purged by batches of batchSize, each batch in its own transaction. A failed batch does not stop
+// the purge: its users are listed in the report, and an error is returned once all batches are done.
+func PurgeBannedUsers(ctx context.Context, store Store, dryRun bool, batchSize int32) (*BannedPurgeReport, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	users, err := store.GetBannedUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &BannedPurgeReport{Users: users}
+	if dryRun {
+		return report, nil
+	}
+
+	purged, failed, err := RunUserBatches(ctx, store, users, batchSize, DeleteUserData)
+	report.Purged, report.Failed = purged, failed
+	if err != nil {
+		return report, fmt.Errorf("%d of %d users could not be purged, last error: %w", len(failed), len(users), err)
+	}
+	return report, nil
+}
+
+// RunUserBatches runs fn for the users by batches of batchSize, each batch in its own transaction. A failed batch
+// does not stop the run: it returns the count of users done, the users of the failed batches and the last error.
+func RunUserBatches(ctx context.Context, store Store, users []string, batchSize int32,
+	fn func(ctx context.Context, q Querier, user string) error) (int, []string, error) {
+	var done int
+	var failed []string
+	var lastErr error
+	for start := 0; start < len(users); start += int(batchSize) {
+		end := start + int(batchSize)
+		if end > len(users) {
+			end = len(users)
+		}
+		batch := users[start:end]
+		if err := store.RunTxContext(ctx, func(ctx context.Context, q Querier) error {
+			for _, user := range batch {
+				if err := fn(ctx, q, user); err != nil {
+					return fmt.Errorf("user %s: %w", user, err)
+				}
+			}
+			return nil
+		}); err != nil {
+			failed = append(failed, batch...)
+			lastErr = err
+			continue
+		}
+		done += len(batch)
+	}
+	return done, failed, lastErr
+}
@@ -0,0 +1,46 @@
+package filters
+
+import (
+	"io"
+	"time"
+)
+
+// CheckResult holds the outcome of a template render with its default parameters,
+// or with one of its presets enabled if Preset is set
+type CheckResult struct {
+	Template string
+	Preset   string
+	Duration time.Duration
+	Err      error
+}
+
+// Check renders every template once with its default parameters, and once per preset,
+// to catch the errors that would only surface when users render their lists
+func (r *Repository) Check() []*CheckResult {
+	var results []*CheckResult
+	for _, tpl := range r.templateList {
+		results = append(results, r.checkRender(tpl, "", tpl.DefaultParams()))
+		for _, preset := range tpl.presets {
+			params := tpl.DefaultParams()
+			for _, other := range tpl.presets {
+				params[other.EnableKey] = other.EnableKey == preset.EnableKey
+			}
+			results = append(results, r.checkRender(tpl, preset.Name, params))
+		}
+	}
+	return results
+}
+
+func (r *Repository) checkRender(tpl *Template, preset string, params map[string]interface{}) *CheckResult {
+	start := time.Now()
+	err := r.Render(io.Discard, &Instance{
+		Template: tpl.Name,
+		Params:   params,
+	})
+	return &CheckResult{
+		Template: tpl.Name,
+		Preset:   preset,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+}
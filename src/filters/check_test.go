@@ -0,0 +1,35 @@
+package filters
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/letsblockit/letsblockit/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	repo, err := Load(data.Templates, data.Presets)
+	require.NoError(t, err)
+	var presets int
+	for _, result := range repo.Check() {
+		assert.NoError(t, result.Err, "%s rendered with preset %q", result.Template, result.Preset)
+		if result.Preset != "" {
+			presets++
+		}
+	}
+	assert.Greater(t, presets, 0, "presets are rendered")
+}
+
+func TestCheck_Broken(t *testing.T) {
+	repo, err := Load(fstest.MapFS{
+		"broken.yaml": {Data: []byte("title: Broken\ntemplate: \"{{> missing}}\"\n---\n\nBroken description")},
+	}, data.Presets)
+	require.NoError(t, err)
+	results := repo.Check()
+	require.Len(t, results, 1)
+	assert.Equal(t, "broken", results[0].Template)
+	assert.Empty(t, results[0].Preset)
+	assert.Error(t, results[0].Err)
+}
@@ -0,0 +1,121 @@
+package filters
+
+import (
+	"bufio"
+	"sort"
+	"strings"
+)
+
+// ClashKind tells how a rule of an instance clashes with the rules of another instance
+type ClashKind string
+
+const (
+	// ClashDuplicate is reported when both instances render the same rule
+	ClashDuplicate ClashKind = "duplicate"
+	// ClashUndone is reported when an exception rule of the other instance undoes the rule
+	ClashUndone ClashKind = "undone"
+	// ClashUndoes is reported when the rule is an exception undoing a rule of the other instance
+	ClashUndoes ClashKind = "undoes"
+)
+
+// RuleClash reports a rule of an instance clashing with a rule of another instance.
+// Unlike Overlap, clashes depend on the rendered rules, and only appear for some params.
+type RuleClash struct {
+	Other     string
+	Rule      string
+	OtherRule string
+	Kind      ClashKind
+}
+
+// parsedRule holds what FindRuleClashes compares between two rules
+type parsedRule struct {
+	line      string
+	target    string // Rule without its domains and exception markers, to match blocks and exceptions
+	domains   []string
+	exception bool
+}
+
+func parseRules(rendered string) []parsedRule {
+	var rules []parsedRule
+	seen := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(rendered))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '!' || line[0] == '[' || strings.HasPrefix(line, "# ") {
+			continue
+		}
+		if _, found := seen[line]; found {
+			continue
+		}
+		seen[line] = struct{}{}
+		rule := parsedRule{line: line}
+		if loc := cosmeticSeparator.FindStringIndex(line); loc != nil {
+			separator := line[loc[0]:loc[1]]
+			rule.exception = strings.Contains(separator, "@")
+			rule.target = strings.Replace(separator, "@", "", 1) + line[loc[1]:]
+			if loc[0] > 0 {
+				rule.domains = strings.Split(line[:loc[0]], ",")
+			}
+		} else if !hostsEntry.MatchString(line) {
+			rule.exception = strings.HasPrefix(line, "@@")
+			rule.target = strings.TrimPrefix(line, "@@")
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// undoes returns whether the exception rule applies to the same target as the blocking rule, on at least
+// one of its domains. Rules without domains apply everywhere.
+func (r parsedRule) undoes(block parsedRule) bool {
+	if !r.exception || block.exception || r.target == "" || r.target != block.target {
+		return false
+	}
+	if len(r.domains) == 0 || len(block.domains) == 0 {
+		return true
+	}
+	for _, domain := range r.domains {
+		for _, other := range block.domains {
+			if domain == other {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FindRuleClashes compares the rendered rules of an instance with the rendered rules of other instances,
+// keyed by template name. It returns the identical rules, and the block and exception rule pairs targeting
+// the same selector or network pattern, sorted by other template and rule.
+func FindRuleClashes(rendered string, others map[string]string) []RuleClash {
+	rules := parseRules(rendered)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var clashes []RuleClash
+	for other, otherRendered := range others {
+		for _, otherRule := range parseRules(otherRendered) {
+			for _, rule := range rules {
+				switch {
+				case rule.line == otherRule.line:
+					clashes = append(clashes, RuleClash{Other: other, Rule: rule.line, OtherRule: otherRule.line, Kind: ClashDuplicate})
+				case otherRule.undoes(rule):
+					clashes = append(clashes, RuleClash{Other: other, Rule: rule.line, OtherRule: otherRule.line, Kind: ClashUndone})
+				case rule.undoes(otherRule):
+					clashes = append(clashes, RuleClash{Other: other, Rule: rule.line, OtherRule: otherRule.line, Kind: ClashUndoes})
+				}
+			}
+		}
+	}
+	sort.Slice(clashes, func(i, j int) bool {
+		if clashes[i].Other != clashes[j].Other {
+			return clashes[i].Other < clashes[j].Other
+		}
+		if clashes[i].Rule != clashes[j].Rule {
+			return clashes[i].Rule < clashes[j].Rule
+		}
+		return clashes[i].OtherRule < clashes[j].OtherRule
+	})
+	return clashes
+}
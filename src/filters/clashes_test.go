@@ -0,0 +1,55 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindRuleClashes(t *testing.T) {
+	rendered := `! comment
+example.com##.sidebar
+example.com,example.org##.banner
+##.generic-ad
+||tracker.example.com^
+@@||cdn.example.com^$script
+0.0.0.0 ads.example.com
+`
+	assert.Equal(t, []RuleClash{{
+		Other:     "consent",
+		Rule:      "##.generic-ad",
+		OtherRule: "other.com#@#.generic-ad",
+		Kind:      ClashUndone,
+	}, {
+		Other:     "consent",
+		Rule:      "example.com,example.org##.banner",
+		OtherRule: "example.org#@#.banner",
+		Kind:      ClashUndone,
+	}, {
+		Other:     "custom-rules",
+		Rule:      "0.0.0.0 ads.example.com",
+		OtherRule: "0.0.0.0 ads.example.com",
+		Kind:      ClashDuplicate,
+	}, {
+		Other:     "custom-rules",
+		Rule:      "@@||cdn.example.com^$script",
+		OtherRule: "||cdn.example.com^$script",
+		Kind:      ClashUndoes,
+	}, {
+		Other:     "custom-rules",
+		Rule:      "example.com##.sidebar",
+		OtherRule: "example.com##.sidebar",
+		Kind:      ClashDuplicate,
+	}}, FindRuleClashes(rendered, map[string]string{
+		"consent": "example.org#@#.banner\nother.com#@#.generic-ad\nexample.net#@#.sidebar\n",
+		"custom-rules": "! example.com##.sidebar\nexample.com##.sidebar\nexample.com##.sidebar\n" +
+			"||cdn.example.com^$script\n0.0.0.0 ads.example.com\n||tracker.example.com^$image\n",
+		"unrelated": "example.com##.footer\n@@||tracker.example.org^\n",
+	}))
+}
+
+func TestFindRuleClashes_Empty(t *testing.T) {
+	assert.Empty(t, FindRuleClashes("", map[string]string{"other": "example.com##.sidebar"}))
+	assert.Empty(t, FindRuleClashes("! only comments\n\n", map[string]string{"other": "! only comments"}))
+	assert.Empty(t, FindRuleClashes("example.com##.sidebar", nil))
+}
@@ -0,0 +1,95 @@
+package filters
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+const maxDisplayNameLength = 80
+
+var displayNameFuncs = template.FuncMap{
+	"truncate": truncateHelper,
+}
+
+// parseDisplayName compiles the display name template of a filter template. Unknown params are only
+// detected when executing it, so it is executed once with the default value of every param.
+func parseDisplayName(tpl *Template) error {
+	if tpl.DisplayNameTemplate == "" {
+		return nil
+	}
+	compiled, err := template.New(tpl.Name).Funcs(displayNameFuncs).Option("missingkey=error").
+		Parse(tpl.DisplayNameTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid display name template: %w", err)
+	}
+	if err = compiled.Execute(io.Discard, tpl.DefaultParams()); err != nil {
+		return fmt.Errorf("invalid display name template: %w", err)
+	}
+	tpl.displayName = compiled
+	return nil
+}
+
+// DisplayName returns the name of an instance, to tell apart the instances of the same template.
+// It is rendered from the display name template on a single line, the template title is returned
+// if the template has none, or if it fails or renders an empty name.
+func (f *Template) DisplayName(params map[string]interface{}) string {
+	if f.displayName == nil {
+		return f.Title
+	}
+	values := f.DefaultParams()
+	for name, value := range params {
+		values[name] = value
+	}
+	var out strings.Builder
+	if err := f.displayName.Execute(&out, values); err != nil {
+		return f.Title
+	}
+	if name := cleanLine(out.String(), maxDisplayNameLength); name != "" {
+		return name
+	}
+	return f.Title
+}
+
+// HasDisplayName returns whether the instances of the template have their own display name
+func (f *Template) HasDisplayName() bool {
+	return f.displayName != nil
+}
+
+// truncateHelper puts a param value on a single line, and cuts it to length characters.
+// List values are joined with commas.
+func truncateHelper(length int, value interface{}) string {
+	var text string
+	switch values := value.(type) {
+	case []string:
+		text = strings.Join(values, ", ")
+	case []interface{}:
+		parts := make([]string, 0, len(values))
+		for _, v := range values {
+			parts = append(parts, fmt.Sprint(v))
+		}
+		text = strings.Join(parts, ", ")
+	case nil:
+	default:
+		text = fmt.Sprint(value)
+	}
+	text = cleanLine(text, 0)
+	if runes := []rune(text); length > 0 && len(runes) > length {
+		text = strings.TrimRight(string(runes[:length]), " ") + "…"
+	}
+	return text
+}
+
+// cleanLine replaces line breaks and other control characters by spaces, collapses consecutive spaces,
+// and truncates the result to maxLength characters if it is not zero
+func cleanLine(text string, maxLength int) string {
+	text = strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+	if runes := []rune(text); maxLength > 0 && len(runes) > maxLength {
+		text = strings.TrimRight(string(runes[:maxLength]), " ")
+	}
+	return text
+}
@@ -0,0 +1,118 @@
+package filters
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const displayNameTemplate = `title: Custom rules
+params:
+  - name: note
+    description: A note
+    type: string
+    default: ""
+  - name: rules
+    description: The rules
+    type: list
+    default: []
+display_name_template: %s
+template: "{{#each rules}}{{ . }}{{/each}}"
+---
+
+Description
+`
+
+func parseDisplayNameTemplate(t *testing.T, displayName string) (*Template, error) {
+	tpl, err := parseTemplate("custom", strings.NewReader(strings.Replace(displayNameTemplate, "%s", displayName, 1)))
+	require.NoError(t, err)
+	return tpl, parseDisplayName(tpl)
+}
+
+func TestParseDisplayName(t *testing.T) {
+	_, err := parseDisplayNameTemplate(t, `"Custom rules ({{ .note | truncate 30 }})"`)
+	assert.NoError(t, err)
+
+	_, err = parseDisplayNameTemplate(t, `"Custom rules ({{ .note "`)
+	assert.ErrorContains(t, err, "invalid display name template")
+
+	_, err = parseDisplayNameTemplate(t, `"Custom rules ({{ .unknown }})"`)
+	assert.ErrorContains(t, err, "invalid display name template")
+	assert.ErrorContains(t, err, "unknown")
+
+	_, err = parseDisplayNameTemplate(t, `"Custom rules ({{ .note | missing }})"`)
+	assert.ErrorContains(t, err, "invalid display name template")
+}
+
+func TestDisplayName(t *testing.T) {
+	tpl, err := parseDisplayNameTemplate(t, `"Custom rules{{ with .note }} ({{ . | truncate 20 }}){{ end }}"`)
+	require.NoError(t, err)
+	require.True(t, tpl.HasDisplayName())
+
+	for name, tc := range map[string]struct {
+		params   map[string]interface{}
+		expected string
+	}{
+		"defaults":   {nil, "Custom rules"},
+		"short note": {map[string]interface{}{"note": "work"}, "Custom rules (work)"},
+		"long note":  {map[string]interface{}{"note": "a very long note for these rules"}, "Custom rules (a very long note for…)"},
+		"newlines":   {map[string]interface{}{"note": "work\n! Title: x\r\n"}, "Custom rules (work ! Title: x)"},
+		"wrong type": {map[string]interface{}{"note": []interface{}{"one", 2}}, "Custom rules (one, 2)"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tpl.DisplayName(tc.params))
+		})
+	}
+
+	tpl, err = parseDisplayNameTemplate(t, `"{{ .note }}"`)
+	require.NoError(t, err)
+	assert.Equal(t, "Custom rules", tpl.DisplayName(nil), "empty names fall back to the title")
+	assert.Equal(t, strings.Repeat("a", maxDisplayNameLength),
+		tpl.DisplayName(map[string]interface{}{"note": strings.Repeat("a", 200)}))
+
+	tpl, err = parseDisplayNameTemplate(t, `""`)
+	require.NoError(t, err)
+	assert.False(t, tpl.HasDisplayName())
+	assert.Equal(t, "Custom rules", tpl.DisplayName(map[string]interface{}{"note": "work"}))
+}
+
+func TestTruncateHelper(t *testing.T) {
+	assert.Equal(t, "", truncateHelper(10, nil))
+	assert.Equal(t, "12", truncateHelper(10, 12))
+	assert.Equal(t, "one, two", truncateHelper(10, []string{"one", "two"}))
+	assert.Equal(t, "one, two,…", truncateHelper(9, []string{"one", "two", "three"}))
+	assert.Equal(t, "René…", truncateHelper(4, "René Coty"))
+	assert.Equal(t, "line one line two", truncateHelper(0, "line one\n\tline two\n"))
+}
+
+type displayNameRepository struct {
+	tpl *Template
+}
+
+func (r *displayNameRepository) Get(string) (*Template, error) {
+	return r.tpl, nil
+}
+
+func (r *displayNameRepository) Render(w io.Writer, _ *Instance) error {
+	_, err := io.WriteString(w, "rule\n")
+	return err
+}
+
+func TestInstanceRender_DisplayName(t *testing.T) {
+	tpl, err := parseDisplayNameTemplate(t, `"Custom rules ({{ .note }})"`)
+	require.NoError(t, err)
+
+	buf := &strings.Builder{}
+	instance := &Instance{Template: "custom", Params: map[string]interface{}{"note": "work\n||example.com^"}}
+	require.NoError(t, instance.Render(buf, &displayNameRepository{tpl}))
+	assert.Equal(t, "\n! custom: Custom rules (work ||example.com^)\nrule\n", buf.String())
+
+	tpl, err = parseDisplayNameTemplate(t, `""`)
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, instance.Render(buf, &displayNameRepository{tpl}))
+	assert.Equal(t, "\n! custom\nrule\n", buf.String())
+}
@@ -0,0 +1,49 @@
+package filters
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// DNSConversion holds the domains blocked by a list that DNS resolvers can block,
+// and the number of rules that cannot be enforced at the DNS level
+type DNSConversion struct {
+	Domains      []string
+	Untranslated int
+}
+
+var (
+	// Network rules blocking a whole domain, without options
+	dnsBlock = regexp.MustCompile(`^\|\|([a-z0-9.-]+\.[a-z0-9-]+)\^$`)
+	// Hosts file entries
+	hostsEntry = regexp.MustCompile(`^(?:0\.0\.0\.0|127\.0\.0\.1)\s+([a-z0-9.-]+\.[a-z0-9-]+)$`)
+)
+
+// ExtractDNSDomains returns the domains fully blocked by a rendered list, without duplicates.
+// Comments are ignored, other rules are counted as untranslated.
+func ExtractDNSDomains(list []byte) *DNSConversion {
+	out := &DNSConversion{}
+	seen := make(map[string]bool)
+	lines := bufio.NewScanner(bytes.NewReader(list))
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || line[0] == '!' || line[0] == '[' || strings.HasPrefix(line, "# ") {
+			continue
+		}
+		match := dnsBlock.FindStringSubmatch(line)
+		if match == nil {
+			match = hostsEntry.FindStringSubmatch(line)
+		}
+		if match == nil {
+			out.Untranslated++
+			continue
+		}
+		if domain := match[1]; !seen[domain] {
+			seen[domain] = true
+			out.Domains = append(out.Domains, domain)
+		}
+	}
+	return out
+}
@@ -0,0 +1,28 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractDNSDomains(t *testing.T) {
+	input := `! Title: My filters
+[Adblock Plus 2.0]
+
+||tracker.net^
+||ads.example.com^
+0.0.0.0 metrics.example.org
+||tracker.net^
+||ads.example.com^$third-party
+@@||example.com^
+||example.com/ads/*
+example.com##.sidebar
+||localhost^
+`
+	assert.Equal(t, &DNSConversion{
+		Domains:      []string{"tracker.net", "ads.example.com", "metrics.example.org"},
+		Untranslated: 5,
+	}, ExtractDNSDomains([]byte(input)))
+	assert.Equal(t, &DNSConversion{}, ExtractDNSDomains([]byte("! Only comments\n")))
+}
@@ -0,0 +1,179 @@
+package filters
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// ImportMatchRatio is the share of a template's rules that must be found in imported rules to map them to it
+const ImportMatchRatio = 0.5
+
+// ImportedTemplate is a template recognized in imported rules
+type ImportedTemplate struct {
+	Template *Template
+	Params   map[string]interface{}
+	Matched  int // Imported rules covered by the template
+	Rules    int // Rules rendered by the template with these parameters
+}
+
+// RulesImport holds the templates recognized in imported rules, and the rules to keep as custom rules
+type RulesImport struct {
+	Templates   []*ImportedTemplate
+	CustomRules string
+	Mapped      int // Imported rules covered by templates
+	Custom      int // Rules kept as custom rules, without comments and directives
+}
+
+// importLine is a line of imported rules, rules inside !#if blocks are kept verbatim
+type importLine struct {
+	text     string
+	rule     bool
+	verbatim bool
+}
+
+// ImportRules maps the rules of a raw uBlock Origin rules file to templates where possible, using their
+// default parameters and enabling the checkboxes and presets whose rules are found. Other rules are kept
+// as custom rules, with comments and preprocessor directives. Blank lines are dropped.
+func (r *Repository) ImportRules(input string) *RulesImport {
+	lines := parseImportLines(input)
+	available := make(map[string]bool)
+	for _, line := range lines {
+		if line.rule && !line.verbatim {
+			available[line.text] = true
+		}
+	}
+
+	out := &RulesImport{}
+	for _, tpl := range r.templateList {
+		if tpl.Name == CustomRulesFilterName {
+			continue
+		}
+		if imported := r.matchTemplate(tpl, available); imported != nil {
+			out.Templates = append(out.Templates, imported)
+		}
+	}
+
+	var custom strings.Builder
+	for _, line := range lines {
+		if line.rule && !line.verbatim && !available[line.text] {
+			out.Mapped++
+			continue
+		}
+		if line.rule {
+			out.Custom++
+		}
+		custom.WriteString(line.text)
+		custom.WriteByte('\n')
+	}
+	out.CustomRules = custom.String()
+	return out
+}
+
+func parseImportLines(input string) []importLine {
+	var lines []importLine
+	var depth int
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Buffer(nil, len(input)+1)
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case strings.HasPrefix(trimmed, "!#if"):
+			depth++
+			lines = append(lines, importLine{text: text, verbatim: true})
+		case strings.HasPrefix(trimmed, "!#endif"):
+			if depth > 0 {
+				depth--
+			}
+			lines = append(lines, importLine{text: text, verbatim: true})
+		case depth > 0:
+			lines = append(lines, importLine{text: text, rule: isImportedRule(trimmed), verbatim: true})
+		case trimmed == "":
+			continue
+		default:
+			lines = append(lines, importLine{text: trimmed, rule: isImportedRule(trimmed)})
+		}
+	}
+	return lines
+}
+
+func isImportedRule(line string) bool {
+	return line != "" && line[0] != '!' && line[0] != '[' && !strings.HasPrefix(line, "# ")
+}
+
+// matchTemplate enables the optional rules of a template that are found in the available rules,
+// then consumes its rules if enough of them are found
+func (r *Repository) matchTemplate(tpl *Template, available map[string]bool) *ImportedTemplate {
+	params := tpl.DefaultParams()
+	var switches []string
+	for _, param := range tpl.Params {
+		if param.Type == BooleanParam {
+			switches = append(switches, param.Name)
+		}
+		for _, preset := range param.Presets {
+			switches = append(switches, param.BuildPresetParamName(preset.Name))
+		}
+	}
+	for _, key := range switches {
+		params[key] = false
+	}
+
+	rules := r.renderImportRules(tpl, params)
+	for _, key := range switches {
+		params[key] = true
+		enabled := r.renderImportRules(tpl, params)
+		var added, found int
+		for rule := range enabled {
+			if !rules[rule] {
+				added++
+				if available[rule] {
+					found++
+				}
+			}
+		}
+		if added > 0 && float64(found) >= ImportMatchRatio*float64(added) {
+			rules = enabled
+		} else {
+			params[key] = false
+		}
+	}
+
+	var matched int
+	for rule := range rules {
+		if available[rule] {
+			matched++
+		}
+	}
+	if matched == 0 || float64(matched) < ImportMatchRatio*float64(len(rules)) {
+		return nil
+	}
+	for rule := range rules {
+		delete(available, rule)
+	}
+	return &ImportedTemplate{
+		Template: tpl,
+		Params:   params,
+		Matched:  matched,
+		Rules:    len(rules),
+	}
+}
+
+// renderImportRules returns the rules rendered by a template, without comments
+func (r *Repository) renderImportRules(tpl *Template, params map[string]interface{}) map[string]bool {
+	var buf bytes.Buffer
+	if err := renderTemplate(&buf, tpl, r.compiled[tpl.Name], &Instance{
+		Template: tpl.Name,
+		Params:   params,
+	}); err != nil {
+		return nil
+	}
+	rules := make(map[string]bool)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); isImportedRule(line) {
+			rules[line] = true
+		}
+	}
+	return rules
+}
@@ -0,0 +1,108 @@
+package filters
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var importTemplates = fstest.MapFS{
+	"custom-rules.yaml": {Data: []byte(`title: Custom rules
+params:
+  - name: rules
+    description: Rules
+    type: multiline
+    default: ""
+template: "{{rules}}"
+---
+
+Custom rules`)},
+	"hide-videos.yaml": {Data: []byte(`title: Hide videos
+params:
+  - name: shorts
+    description: Hide shorts
+    type: checkbox
+    default: true
+  - name: channels
+    description: Channels to hide
+    type: list
+    default: []
+    presets:
+      - name: news
+        description: News channels
+        values: [news1, news2]
+template: |
+  ! Hide videos
+  video.example##.sidebar
+  video.example##.comments
+  {{#if shorts}}
+  video.example##.shorts
+  {{/if}}
+  {{#each channels}}
+  video.example##[data-channel="{{.}}"]
+  {{/each}}
+---
+
+Hide videos`)},
+	"block-trackers.yaml": {Data: []byte(`title: Block trackers
+template: |
+  ||tracker1.example^
+  ||tracker2.example^
+  ||tracker3.example^
+  ||tracker4.example^
+---
+
+Block trackers`)},
+}
+
+func TestImportRules(t *testing.T) {
+	repo, err := Load(importTemplates, importTemplates)
+	require.NoError(t, err)
+
+	result := repo.ImportRules(`! My rules
+[Adblock Plus 2.0]
+
+video.example##.sidebar
+  video.example##.comments
+video.example##[data-channel="news1"]
+video.example##[data-channel="news2"]
+||tracker1.example^
+
+!#if env_firefox
+video.example##.shorts
+
+!#endif
+example.com##.ads
+video.example##.sidebar
+`)
+	require.Len(t, result.Templates, 1)
+	assert.Equal(t, "hide-videos", result.Templates[0].Template.Name)
+	assert.Equal(t, map[string]interface{}{
+		"shorts":                   false,
+		"channels":                 []interface{}{},
+		"channels---preset---news": true,
+	}, result.Templates[0].Params)
+	assert.Equal(t, 4, result.Templates[0].Matched)
+	assert.Equal(t, 4, result.Templates[0].Rules)
+
+	assert.Equal(t, 5, result.Mapped)
+	assert.Equal(t, 3, result.Custom)
+	assert.Equal(t, `! My rules
+[Adblock Plus 2.0]
+||tracker1.example^
+!#if env_firefox
+video.example##.shorts
+
+!#endif
+example.com##.ads
+`, result.CustomRules)
+}
+
+func TestImportRules_Empty(t *testing.T) {
+	repo, err := Load(importTemplates, importTemplates)
+	require.NoError(t, err)
+	assert.Equal(t, &RulesImport{}, repo.ImportRules(""))
+	assert.Equal(t, &RulesImport{CustomRules: "! Only a comment\n"}, repo.ImportRules("\n! Only a comment\n\n"))
+}
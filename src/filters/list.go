@@ -1,33 +1,63 @@
 package filters
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	tracer = otel.Tracer("github.com/letsblockit/letsblockit/src/filters")
+	// noopSpan replaces the instance spans when the list span is not sampled, as they would not be either
+	noopSpan = trace.SpanFromContext(context.Background())
 )
 
 const (
+	defaultHomepage    = "https://letsblock.it"
 	listHeaderTemplate = `! Title: letsblock.it - %s
 ! Expires: 12 hours
-! Homepage: https://letsblock.it
+! Homepage: %s
 ! License: https://github.com/letsblockit/letsblockit/blob/main/LICENSE.txt
 `
-	instanceHeaderTemplate = `
-! %s
-`
+	listMirrorTemplate = "! Alternate mirror: %s\n"
+
+	// DefaultListTitle is rendered for the lists without a title
+	DefaultListTitle   = "My filters"
+	maxListTitleLength = 100
 )
 
 type Instance struct {
-	Template string                 `yaml:"template" validate:"required"`
-	Params   map[string]interface{} `yaml:"params,omitempty"`
-	TestMode bool                   `yaml:"test_mode,omitempty"`
+	Template string                 `yaml:"template" json:"template" validate:"required"`
+	Params   map[string]interface{} `yaml:"params,omitempty" json:"params,omitempty"`
+	TestMode bool                   `yaml:"test_mode,omitempty" json:"test_mode,omitempty"`
 }
 
 type List struct {
-	Title     string      `yaml:"title" validate:"required"`
-	Instances []*Instance `yaml:"instances" validate:"dive,required"`
-	TestMode  bool        `yaml:"test_mode,omitempty"`
+	Title       string           `yaml:"title" json:"title" validate:"required"`
+	Instances   []*Instance      `yaml:"instances" json:"instances" validate:"dive,required"`
+	TestMode    bool             `yaml:"test_mode,omitempty" json:"test_mode,omitempty"`
+	Homepage    string           `yaml:"-" json:"-"` // Defaults to the official instance
+	Mirrors     []string         `yaml:"-" json:"-"` // Alternate download URLs, advertised in the header
+	SafeMode    bool             `yaml:"-" json:"-"` // Comment out the lines failing CheckRuleSyntax
+	Neutralized int              `yaml:"-" json:"-"` // Lines commented out by the last render in safe mode
+	SizeWarning int              `yaml:"-" json:"-"` // Warn before the rules if there are more, 0 to disable
+	Rules       int              `yaml:"-" json:"-"` // Rules written by the last render
+	Failures    map[string]error `yaml:"-" json:"-"` // Render errors of the last render, by template
+}
+
+// CleanListTitle makes a title safe to render in the list header comment: line breaks and other control
+// characters are replaced by spaces, consecutive spaces are collapsed, and long titles are truncated.
+func CleanListTitle(title string) string {
+	return cleanLine(title, maxListTitleLength)
 }
 
 type repository interface {
@@ -35,31 +65,182 @@ type repository interface {
 	Render(w io.Writer, instance *Instance) error
 }
 
+// Render writes the rules of the instance, after a comment with its template name, and its display name if
+// the template has a display name template
 func (i *Instance) Render(out io.Writer, repo repository) error {
-	_, e := fmt.Fprintf(out, instanceHeaderTemplate, i.Template)
+	header := "\n! " + i.Template + "\n"
+	if tpl, err := repo.Get(i.Template); err == nil && tpl != nil && tpl.HasDisplayName() {
+		header = "\n! " + i.Template + ": " + tpl.DisplayName(i.Params) + "\n"
+	}
+	_, e := io.WriteString(out, header)
 	if e != nil {
 		return e
 	}
 	return repo.Render(out, i)
 }
 
+// InstanceObserver is called after each instance is rendered, with the time it took
+type InstanceObserver func(template string, elapsed time.Duration)
+
 func (l *List) Render(out io.Writer, logger logger, repo repository) error {
-	_, err := fmt.Fprintf(out, listHeaderTemplate, l.Title)
+	return l.RenderObserved(context.Background(), out, logger, repo, nil)
+}
+
+// RenderObserved renders the list like Render, calling observe after each instance if not nil.
+// The context is checked between instances, its error is returned if it is done.
+func (l *List) RenderObserved(ctx context.Context, out io.Writer, logger logger, repo repository, observe InstanceObserver) error {
+	ctx, span := tracer.Start(ctx, "filters.RenderList", trace.WithAttributes(attribute.Int("instances", len(l.Instances))))
+	defer span.End()
+	homepage := l.Homepage
+	if homepage == "" {
+		homepage = defaultHomepage
+	}
+	_, err := fmt.Fprintf(out, listHeaderTemplate, CleanListTitle(l.Title), homepage)
 	if err != nil {
 		return err
 	}
+	for _, mirror := range l.Mirrors {
+		if _, err = fmt.Fprintf(out, listMirrorTemplate, mirror); err != nil {
+			return err
+		}
+	}
+	for _, overlap := range l.findOverlaps(repo) {
+		if _, err = fmt.Fprintf(out, listOverlapTemplate, overlap); err != nil {
+			return err
+		}
+	}
 
-	for _, i := range l.Instances {
+	// The rules are buffered if a size warning can be needed, as it is written before them
+	var buffer bytes.Buffer
+	body := out
+	if l.SizeWarning > 0 {
+		body = &buffer
+	}
+	counter := &ruleCounter{out: body}
+	var sizes []instanceSize
+	l.Neutralized = 0
+	l.Failures = nil
+	for n, i := range l.Instances {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		if l.TestMode {
 			i.TestMode = true
 		}
-		if err := i.Render(out, repo); err != nil {
+		start := time.Now()
+		instanceSpan := noopSpan
+		if span.IsRecording() {
+			_, instanceSpan = tracer.Start(ctx, "filters.RenderInstance", trace.WithAttributes(attribute.String("template", i.Template)))
+		}
+		before := counter.rules
+		if err := l.renderInstance(counter, logger, repo, i); err != nil {
 			logger.Warnf("skipping %s: %s", i.Template, err)
+			if l.Failures == nil {
+				l.Failures = make(map[string]error)
+			}
+			l.Failures[i.Template] = err
+			instanceSpan.SetStatus(codes.Error, err.Error())
+		}
+		instanceSpan.End()
+		if observe != nil {
+			observe(i.Template, time.Since(start))
+		}
+		if l.SizeWarning > 0 {
+			sizes = append(sizes, instanceSize{Template: i.Template, Position: n + 1, Rules: counter.rules - before})
 		}
 	}
-	return nil
+	l.Rules = counter.rules
+
+	if l.SizeWarning > 0 {
+		if l.Rules > l.SizeWarning {
+			if err = writeSizeWarning(out, l.Rules, l.SizeWarning, sizes); err != nil {
+				return err
+			}
+		}
+		_, err = buffer.WriteTo(out)
+	}
+	return err
+}
+
+// renderInstance renders an instance, commenting out its invalid lines in safe mode.
+// Invalid lines are logged unless they come from the user's custom rules, as the template is to blame.
+func (l *List) renderInstance(out io.Writer, logger logger, repo repository, i *Instance) error {
+	if !l.SafeMode {
+		return i.Render(out, repo)
+	}
+	var rendered strings.Builder
+	if err := i.Render(&rendered, repo); err != nil {
+		return err
+	}
+	lines := strings.SplitAfter(rendered.String(), "\n")
+	for n, line := range lines {
+		if err := CheckRuleSyntax(line); err != nil {
+			l.Neutralized++
+			if i.Template != CustomRulesFilterName {
+				logger.Warnf("template %s rendered an invalid line: %s: %s", i.Template, err, strings.TrimSpace(line))
+			}
+			lines[n] = InvalidRulePrefix + line
+		}
+	}
+	_, err := io.WriteString(out, strings.Join(lines, ""))
+	return err
+}
+
+// findOverlaps returns the overlaps between the templates of the list, unknown templates are skipped when rendering
+func (l *List) findOverlaps(repo repository) []Overlap {
+	templates := make([]*Template, 0, len(l.Instances))
+	for _, i := range l.Instances {
+		if tpl, err := repo.Get(i.Template); err == nil && tpl != nil {
+			templates = append(templates, tpl)
+		}
+	}
+	return FindOverlaps(templates)
 }
 
 func (l *List) Validate() error {
 	return validator.New().Struct(l)
 }
+
+// Subset restricts the list to a subset of its instances. If only is not empty, only the instances
+// of these templates are kept, and an error is returned if one of them has no instance in the list.
+// Instances of the templates in exclude are then removed.
+func (l *List) Subset(only, exclude []string) error {
+	if len(only) == 0 && len(exclude) == 0 {
+		return nil
+	}
+
+	keep := make(map[string]bool, len(only))
+	for _, name := range only {
+		keep[name] = false
+	}
+	excluded := make(map[string]struct{}, len(exclude))
+	for _, name := range exclude {
+		excluded[name] = struct{}{}
+	}
+
+	instances := make([]*Instance, 0, len(l.Instances))
+	for _, i := range l.Instances {
+		if len(keep) > 0 {
+			if _, found := keep[i.Template]; !found {
+				continue
+			}
+			keep[i.Template] = true
+		}
+		if _, found := excluded[i.Template]; found {
+			continue
+		}
+		instances = append(instances, i)
+	}
+
+	var missing []string
+	for _, name := range only {
+		if !keep[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no instance found for templates: %s", strings.Join(missing, ", "))
+	}
+	l.Instances = instances
+	return nil
+}
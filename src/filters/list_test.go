@@ -1,9 +1,12 @@
 package filters
 
 import (
+	"context"
 	"embed"
+	"io"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/letsblockit/letsblockit/src/filters/mocks"
@@ -46,6 +49,41 @@ func (s *ListTestSuite) TestRenderEmpty() {
 `, buf.String())
 }
 
+func (s *ListTestSuite) TestRenderTitle() {
+	buf := &strings.Builder{}
+	list := &List{Title: "Work | ads!\n! Homepage: https://example.com\r\n||example.com^"}
+	s.NoError(list.Render(buf, s.logger, s.repository))
+	s.Equal(`! Title: letsblock.it - Work | ads! ! Homepage: https://example.com ||example.com^
+! Expires: 12 hours
+! Homepage: https://letsblock.it
+! License: https://github.com/letsblockit/letsblockit/blob/main/LICENSE.txt
+`, buf.String())
+}
+
+func (s *ListTestSuite) TestRenderHomepage() {
+	buf := &strings.Builder{}
+	list := &List{Title: "Self-hosted", Homepage: "https://lists.example.com"}
+	s.NoError(list.Render(buf, s.logger, s.repository))
+	s.Equal(`! Title: letsblock.it - Self-hosted
+! Expires: 12 hours
+! Homepage: https://lists.example.com
+! License: https://github.com/letsblockit/letsblockit/blob/main/LICENSE.txt
+`, buf.String())
+}
+
+func (s *ListTestSuite) TestRenderMirrors() {
+	buf := &strings.Builder{}
+	list := &List{Title: "Mirrored", Mirrors: []string{"https://one.example.com/list/a", "https://two.example.com/list/a"}}
+	s.NoError(list.Render(buf, s.logger, s.repository))
+	s.Equal(`! Title: letsblock.it - Mirrored
+! Expires: 12 hours
+! Homepage: https://letsblock.it
+! License: https://github.com/letsblockit/letsblockit/blob/main/LICENSE.txt
+! Alternate mirror: https://one.example.com/list/a
+! Alternate mirror: https://two.example.com/list/a
+`, buf.String())
+}
+
 func (s *ListTestSuite) TestRenderOK() {
 	var list List
 	require.NoError(s.T(), yaml.Unmarshal(testList, &list))
@@ -69,6 +107,49 @@ Hello
 one
 two
 `, buf.String())
+	s.Len(list.Failures, 1)
+	s.Error(list.Failures["unknown"])
+}
+
+func (s *ListTestSuite) TestRenderObserved() {
+	var list List
+	require.NoError(s.T(), yaml.Unmarshal(testList, &list))
+
+	var observed []string
+	s.expectL.Warnf(gomock.Any(), "unknown", gomock.Any())
+	s.NoError(list.RenderObserved(context.Background(), io.Discard, s.logger, s.repository, func(template string, elapsed time.Duration) {
+		s.GreaterOrEqual(elapsed, time.Duration(0))
+		observed = append(observed, template)
+	}))
+	s.Equal([]string{"hello", "hello", "unknown", "simple"}, observed)
+}
+
+// slowRepository takes a fixed time to render each instance
+type slowRepository struct {
+	delay    time.Duration
+	rendered []string
+}
+
+func (r *slowRepository) Get(string) (*Template, error) {
+	return nil, nil
+}
+
+func (r *slowRepository) Render(w io.Writer, instance *Instance) error {
+	time.Sleep(r.delay)
+	r.rendered = append(r.rendered, instance.Template)
+	_, err := io.WriteString(w, instance.Template+"\n")
+	return err
+}
+
+func (s *ListTestSuite) TestRenderObserved_Cancelled() {
+	list := &List{Instances: []*Instance{{Template: "one"}, {Template: "two"}, {Template: "three"}}}
+	repo := &slowRepository{delay: 50 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 75*time.Millisecond)
+	defer cancel()
+
+	err := list.RenderObserved(ctx, io.Discard, s.logger, repo, nil)
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.Equal([]string{"one", "two"}, repo.rendered, "rendering stops at the next instance")
 }
 
 func (s *ListTestSuite) TestValidateOK() {
@@ -86,6 +167,61 @@ func (s *ListTestSuite) TestValidateOK() {
 	s.NoError(list.Validate())
 }
 
+func (s *ListTestSuite) TestSubset() {
+	newList := func() *List {
+		return &List{Instances: []*Instance{
+			{Template: "one"}, {Template: "two"}, {Template: "three"}, {Template: "two"},
+		}}
+	}
+	templates := func(l *List) []string {
+		var names []string
+		for _, i := range l.Instances {
+			names = append(names, i.Template)
+		}
+		return names
+	}
+
+	list := newList()
+	s.NoError(list.Subset(nil, nil))
+	s.Equal([]string{"one", "two", "three", "two"}, templates(list))
+
+	list = newList()
+	s.NoError(list.Subset([]string{"two", "three"}, nil))
+	s.Equal([]string{"two", "three", "two"}, templates(list))
+
+	list = newList()
+	s.NoError(list.Subset(nil, []string{"two", "unknown"}))
+	s.Equal([]string{"one", "three"}, templates(list))
+
+	list = newList()
+	s.NoError(list.Subset([]string{"one", "two"}, []string{"two"}))
+	s.Equal([]string{"one"}, templates(list))
+
+	list = newList()
+	s.EqualError(list.Subset([]string{"one", "four", "five"}, nil), "no instance found for templates: four, five")
+	s.Equal([]string{"one", "two", "three", "two"}, templates(list))
+}
+
 func TestListTestSuite(t *testing.T) {
 	suite.Run(t, new(ListTestSuite))
 }
+
+func BenchmarkListRender(b *testing.B) {
+	repository, err := Load(testTemplates, testTemplates)
+	require.NoError(b, err)
+	list := &List{Title: "Benchmark list"}
+	for i := 0; i < 50; i++ {
+		list.Instances = append(list.Instances, &Instance{Template: "hello"}, &Instance{
+			Template: "simple",
+			Params:   map[string]interface{}{"string_list": []interface{}{"one", "two", "three"}},
+		})
+	}
+	logger := mocks.NewMocklogger(gomock.NewController(b))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := list.Render(io.Discard, logger, repository); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package filters
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+)
+
+// OverlapKind tells how two templates overlap, declared in the conflicts_with and supersedes metadata
+type OverlapKind string
+
+const (
+	OverlapConflict   OverlapKind = "conflicts_with"
+	OverlapSupersedes OverlapKind = "supersedes"
+
+	listOverlapTemplate = "! Overlap: %s\n"
+)
+
+// Overlap reports two templates that should not be enabled together.
+// For OverlapSupersedes, Template already includes the rules of Other.
+type Overlap struct {
+	Template string
+	Other    string
+	Kind     OverlapKind
+}
+
+func (o Overlap) String() string {
+	if o.Kind == OverlapSupersedes {
+		return fmt.Sprintf("%s already includes the rules of %s", o.Template, o.Other)
+	}
+	return fmt.Sprintf("%s conflicts with %s", o.Template, o.Other)
+}
+
+// Involves returns whether the template is part of the overlap
+func (o Overlap) Involves(name string) bool {
+	return o.Template == name || o.Other == name
+}
+
+// FindOverlaps returns the overlapping pairs among the templates, sorted by template names.
+// Conflicts are reported once even if both templates declare them.
+func FindOverlaps(templates []*Template) []Overlap {
+	sorted := make([]*Template, len(templates))
+	copy(sorted, templates)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var overlaps []Overlap
+	for i, a := range sorted {
+		for _, b := range sorted[i+1:] {
+			switch {
+			case lo.Contains(a.Supersedes, b.Name):
+				overlaps = append(overlaps, Overlap{Template: a.Name, Other: b.Name, Kind: OverlapSupersedes})
+			case lo.Contains(b.Supersedes, a.Name):
+				overlaps = append(overlaps, Overlap{Template: b.Name, Other: a.Name, Kind: OverlapSupersedes})
+			case lo.Contains(a.ConflictsWith, b.Name) || lo.Contains(b.ConflictsWith, a.Name):
+				overlaps = append(overlaps, Overlap{Template: a.Name, Other: b.Name, Kind: OverlapConflict})
+			}
+		}
+	}
+	return overlaps
+}
+
+// FindOverlaps returns the overlapping pairs among the named templates, unknown names are ignored
+func (r *Repository) FindOverlaps(names []string) []Overlap {
+	templates := make([]*Template, 0, len(names))
+	for _, name := range names {
+		if tpl, found := r.templateMap[name]; found {
+			templates = append(templates, tpl)
+		}
+	}
+	return FindOverlaps(templates)
+}
+
+// OverlappingTemplates returns the sorted names of the templates declaring or targeted by an overlap
+func (r *Repository) OverlappingTemplates() []string {
+	names := make(map[string]struct{})
+	for _, tpl := range r.templateMap {
+		for _, others := range [][]string{tpl.ConflictsWith, tpl.Supersedes} {
+			for _, other := range others {
+				names[tpl.Name] = struct{}{}
+				names[other] = struct{}{}
+			}
+		}
+	}
+	return flattenTagMap(names)
+}
+
+// validateOverlaps checks that the overlap metadata only references other known templates
+func validateOverlaps(templates map[string]*Template) error {
+	for name, tpl := range templates {
+		for field, others := range map[OverlapKind][]string{
+			OverlapConflict:   tpl.ConflictsWith,
+			OverlapSupersedes: tpl.Supersedes,
+		} {
+			for _, other := range others {
+				if other == name {
+					return fmt.Errorf("template %s: %s cannot reference itself", name, field)
+				}
+				if _, found := templates[other]; !found {
+					return fmt.Errorf("template %s: %s references unknown template %s", name, field, other)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package filters
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang/mock/gomock"
+	"github.com/letsblockit/letsblockit/src/filters/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func overlapTemplate(metadata string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte("title: Test\n" + metadata + "template: rule\n---\n\nTest")}
+}
+
+var overlapTemplates = fstest.MapFS{
+	"cookie-banners.yaml":  overlapTemplate("conflicts_with: [consent-popups]\n"),
+	"consent-popups.yaml":  overlapTemplate("conflicts_with: [cookie-banners]\n"),
+	"annoyances.yaml":      overlapTemplate("supersedes: [consent-popups]\n"),
+	"unrelated-rules.yaml": overlapTemplate(""),
+}
+
+func TestFindOverlaps(t *testing.T) {
+	repo, err := Load(overlapTemplates, overlapTemplates)
+	require.NoError(t, err)
+
+	tpl, err := repo.Get("annoyances")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"consent-popups"}, tpl.Supersedes)
+
+	assert.Equal(t, []Overlap{
+		{Template: "annoyances", Other: "consent-popups", Kind: OverlapSupersedes},
+		{Template: "consent-popups", Other: "cookie-banners", Kind: OverlapConflict},
+	}, repo.FindOverlaps([]string{"cookie-banners", "unrelated-rules", "consent-popups", "annoyances", "unknown"}))
+	assert.Empty(t, repo.FindOverlaps([]string{"cookie-banners", "annoyances"}))
+	assert.Equal(t, []string{"annoyances", "consent-popups", "cookie-banners"}, repo.OverlappingTemplates())
+
+	assert.Equal(t, "annoyances already includes the rules of consent-popups",
+		Overlap{Template: "annoyances", Other: "consent-popups", Kind: OverlapSupersedes}.String())
+	assert.Equal(t, "consent-popups conflicts with cookie-banners",
+		Overlap{Template: "consent-popups", Other: "cookie-banners", Kind: OverlapConflict}.String())
+}
+
+func TestFindOverlaps_InvalidReferences(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"cookie-banners.yaml": overlapTemplate("conflicts_with: [unknown]\n"),
+	}, overlapTemplates)
+	assert.EqualError(t, err, "template cookie-banners: conflicts_with references unknown template unknown")
+
+	_, err = Load(fstest.MapFS{
+		"cookie-banners.yaml": overlapTemplate("supersedes: [cookie-banners]\n"),
+	}, overlapTemplates)
+	assert.EqualError(t, err, "template cookie-banners: supersedes cannot reference itself")
+}
+
+func TestRenderOverlaps(t *testing.T) {
+	repo, err := Load(overlapTemplates, overlapTemplates)
+	require.NoError(t, err)
+
+	buf := &strings.Builder{}
+	list := &List{Title: "Overlaps", Instances: []*Instance{
+		{Template: "cookie-banners"},
+		{Template: "consent-popups"},
+	}}
+	require.NoError(t, list.Render(buf, mocks.NewMocklogger(gomock.NewController(t)), repo))
+	assert.Equal(t, `! Title: letsblock.it - Overlaps
+! Expires: 12 hours
+! Homepage: https://letsblock.it
+! License: https://github.com/letsblockit/letsblockit/blob/main/LICENSE.txt
+! Overlap: consent-popups conflicts with cookie-banners
+
+! cookie-banners
+rule
+! consent-popups
+rule`, buf.String())
+}
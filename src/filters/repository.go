@@ -1,10 +1,15 @@
 package filters
 
 import (
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/imantung/mario"
@@ -15,55 +20,102 @@ import (
 const (
 	CustomRulesFilterName = "custom-rules"
 	CustomTagName         = "custom"
+
+	presetsPrefix = "filters/presets/"
 )
 
 // Repository holds parsed Templates ready for use
 type Repository struct {
-	main         *mario.Template
+	compiled     map[string]*mario.Template
 	templateMap  map[string]*Template
 	templateList []*Template
+	sourceMap    map[string]string
+	hashMap      map[string]string
 	tagList      []string
 }
 
+// Source is a set of template definitions, with their preset files
+type Source struct {
+	Name      string
+	Templates fs.FS
+	Presets   fs.FS
+}
+
+// DirSource returns the templates of a local folder, with the presets of its sibling presets folder
+func DirSource(dir string) Source {
+	return Source{
+		Name:      dir,
+		Templates: os.DirFS(dir),
+		Presets:   presetsDir(filepath.Join(dir, "..", "presets")),
+	}
+}
+
+// presetsDir maps the preset paths expected by parsePresets to a local folder
+type presetsDir string
+
+func (d presetsDir) Open(name string) (fs.File, error) {
+	if !strings.HasPrefix(name, presetsPrefix) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(strings.TrimPrefix(name, presetsPrefix))))
+}
+
 // Load parses template definitions from the given filesystem
 func Load(templates, presets fs.FS) (*Repository, error) {
-	main, err := mario.New().Parse("{{>(_template)}}")
-	main.WithHelperFunc("string_split", func(args string) []string {
-		return strings.Split(args, " ")
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse toplevel template: %w", err)
-	}
+	return LoadSources(Source{Name: "embedded", Templates: templates, Presets: presets})
+}
+
+// LoadSources parses template definitions from several sources, in order.
+// Templates replace the ones with the same name from previous sources.
+func LoadSources(sources ...Source) (*Repository, error) {
 	repo := &Repository{
-		main:        main,
+		compiled:    make(map[string]*mario.Template),
 		templateMap: make(map[string]*Template),
+		sourceMap:   make(map[string]string),
+		hashMap:     make(map[string]string),
 	}
-	allTags := make(map[string]struct{})
 
-	err = data.Walk(templates, filenameSuffix, func(name string, file io.Reader) error {
-		tpl, e := parseTemplate(name, file)
-		if e != nil {
-			return e
-		}
-		if e = parsePresets(tpl, presets); err != nil {
+	var err error
+	for _, source := range sources {
+		err = data.Walk(source.Templates, filenameSuffix, func(name string, file io.Reader) error {
+			tpl, e := parseTemplate(name, file)
+			if e != nil {
+				return e
+			}
+			if e = parsePresets(tpl, source.Presets); e != nil {
+				return e
+			}
+			if e = parseDisplayName(tpl); e != nil {
+				return e
+			}
+			repo.compiled[name], e = compileTemplate(tpl)
+			if e != nil {
+				return e
+			}
+			repo.templateMap[name] = tpl
+			repo.sourceMap[name] = source.Name
+			repo.hashMap[name], e = hashTemplate(tpl)
 			return e
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot load %s templates: %w", source.Name, err)
 		}
-		partial, e := mario.New().Parse(tpl.Template)
-		if e != nil {
-			return fmt.Errorf("failed to parse template template: %w", e)
-		}
-		_ = main.WithPartial(name, partial)
-		repo.templateMap[name] = tpl
+	}
+	if err = validateOverlaps(repo.templateMap); err != nil {
+		return nil, err
+	}
+
+	allTags := make(map[string]struct{})
+	for _, tpl := range repo.templateMap {
 		repo.templateList = append(repo.templateList, tpl)
 		for _, tag := range tpl.Tags {
 			allTags[tag] = struct{}{}
 		}
-		return nil
-	})
+	}
 	sortTemplates(repo.templateList)
 	repo.tagList = flattenTagMap(allTags)
 
-	return repo, err
+	return repo, nil
 }
 
 func (r *Repository) Get(name string) (*Template, error) {
@@ -82,6 +134,16 @@ func (r *Repository) GetTags() []string {
 	return r.tagList
 }
 
+// Source returns the name of the source a template was loaded from
+func (r *Repository) Source(name string) string {
+	return r.sourceMap[name]
+}
+
+// Hash returns a version identifier for a template, that changes when its parameters or rules change
+func (r *Repository) Hash(name string) string {
+	return r.hashMap[name]
+}
+
 func (r *Repository) Has(name string) bool {
 	_, found := r.templateMap[name]
 	return found
@@ -92,13 +154,24 @@ func (r *Repository) Render(w io.Writer, instance *Instance) error {
 	if !found {
 		return fmt.Errorf("template '%s' not found", instance.Template)
 	}
-	params := shallowCopy(instance.Params)
-	params["_template"] = instance.Template
+	return renderTemplate(w, tpl, r.compiled[instance.Template], instance)
+}
+
+func compileTemplate(tpl *Template) (*mario.Template, error) {
+	partial, err := mario.New().Parse(tpl.Template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template template: %w", err)
+	}
+	return partial.WithHelperFunc("string_split", splitHelper), nil
+}
+
+func renderTemplate(w io.Writer, tpl *Template, compiled *mario.Template, instance *Instance) error {
+	params := instance.Params
 
 	if instance.TestMode {
 		w = NewTestModeTransformer(w)
 	}
-	if err := r.main.Execute(w, params); err != nil {
+	if err := compiled.Execute(w, params); err != nil {
 		return err
 	}
 
@@ -109,7 +182,7 @@ func (r *Repository) Render(w io.Writer, instance *Instance) error {
 			}
 			params := shallowCopy(params)
 			params[preset.TargetKey] = preset.Value
-			if err := r.main.Execute(w, params); err != nil {
+			if err := compiled.Execute(w, params); err != nil {
 				return err
 			}
 		}
@@ -118,6 +191,10 @@ func (r *Repository) Render(w io.Writer, instance *Instance) error {
 	return nil
 }
 
+func splitHelper(args string) []string {
+	return strings.Split(args, " ")
+}
+
 func flattenTagMap(tags map[string]struct{}) []string {
 	out := make([]string, 0, len(tags))
 	for tag := range tags {
@@ -139,6 +216,18 @@ func sortTemplates(filters []*Template) {
 	})
 }
 
+// hashTemplate hashes the parameter definitions and rule template, ignoring descriptions and tests
+func hashTemplate(tpl *Template) (string, error) {
+	params, err := json.Marshal(tpl.Params)
+	if err != nil {
+		return "", err
+	}
+	hasher := fnv.New64()
+	_, _ = hasher.Write(params)
+	_, _ = hasher.Write([]byte(tpl.Template))
+	return strconv.FormatUint(hasher.Sum64(), 36), nil
+}
+
 func shallowCopy(input map[string]interface{}) map[string]interface{} {
 	output := make(map[string]interface{}, len(input))
 	for k, v := range input {
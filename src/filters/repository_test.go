@@ -1,6 +1,7 @@
 package filters
 
 import (
+	"os"
 	"testing"
 
 	"github.com/letsblockit/letsblockit/data"
@@ -16,3 +17,30 @@ func TestLoad(t *testing.T) {
 	require.Greater(t, len(repo.templateList), 0, "Expected at least one template")
 	require.Greater(t, len(repo.tagList), 0, "Expected at least one tag")
 }
+
+func TestLoadSources(t *testing.T) {
+	repo, err := LoadSources(
+		Source{Name: "embedded", Templates: data.Templates, Presets: data.Presets},
+		Source{Name: "testdata", Templates: os.DirFS("testdata/templates"), Presets: data.Presets},
+	)
+	require.NoError(t, err)
+	require.True(t, repo.Has(CustomRulesFilterName))
+	require.Equal(t, "embedded", repo.Source(CustomRulesFilterName))
+	require.True(t, repo.Has("simple"))
+	require.Equal(t, "testdata", repo.Source("simple"))
+	require.Equal(t, "", repo.Source("unknown"))
+	require.Len(t, repo.GetAll(), len(repo.templateMap))
+	require.Contains(t, repo.GetTags(), "tag1")
+	require.NotEmpty(t, repo.Hash("simple"))
+	require.NotEqual(t, repo.Hash("simple"), repo.Hash(CustomRulesFilterName))
+	require.Empty(t, repo.Hash("unknown"))
+
+	// Templates from later sources replace the previous ones
+	repo, err = LoadSources(
+		Source{Name: "first", Templates: data.Templates, Presets: data.Presets},
+		Source{Name: "second", Templates: data.Templates, Presets: data.Presets},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "second", repo.Source(CustomRulesFilterName))
+	require.Len(t, repo.GetAll(), len(repo.templateMap))
+}
@@ -0,0 +1,112 @@
+package filters
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// SafariMaxRules is the maximum number of rules Safari accepts in a content blocker
+const SafariMaxRules = 150000
+
+// SafariRule is a rule in Apple's content blocker JSON format
+type SafariRule struct {
+	Trigger SafariTrigger `json:"trigger"`
+	Action  SafariAction  `json:"action"`
+}
+
+// SafariTrigger selects the pages and resources a rule applies to
+type SafariTrigger struct {
+	URLFilter    string   `json:"url-filter"`
+	IfDomain     []string `json:"if-domain,omitempty"`
+	UnlessDomain []string `json:"unless-domain,omitempty"`
+	LoadType     []string `json:"load-type,omitempty"`
+}
+
+// SafariAction is applied to the resources matching the trigger
+type SafariAction struct {
+	Type     string `json:"type"`
+	Selector string `json:"selector,omitempty"`
+}
+
+// SafariConversion holds the rules translated to the content blocker format, and the rules that could not be
+type SafariConversion struct {
+	Rules        []*SafariRule
+	Untranslated []string
+}
+
+var (
+	// Extended selectors and actions that are specific to uBlock Origin and AdGuard
+	extendedSelector = regexp.MustCompile(`:(-abp-[a-z-]+|has-text|matches-[a-z]+|min-text-length|others|remove|remove-attr|remove-class|spath|style|upward|watch-attr|xpath)\(`)
+	// Network rules blocking a whole domain, with an optional party option
+	domainBlock = regexp.MustCompile(`^\|\|([a-z0-9.-]+)\^(?:\$(third-party|3p|first-party|1p))?$`)
+)
+
+// ConvertToSafari translates the domain blocks and the cosmetic rules hiding elements of a rendered list,
+// other rules are returned as untranslated. Comments are ignored.
+func ConvertToSafari(list []byte) *SafariConversion {
+	out := &SafariConversion{}
+	lines := bufio.NewScanner(bytes.NewReader(list))
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || line[0] == '!' || line[0] == '[' || strings.HasPrefix(line, "# ") {
+			continue
+		}
+		if rule := convertSafariRule(line); rule != nil {
+			out.Rules = append(out.Rules, rule)
+		} else {
+			out.Untranslated = append(out.Untranslated, line)
+		}
+	}
+	return out
+}
+
+func convertSafariRule(line string) *SafariRule {
+	if match := domainBlock.FindStringSubmatch(line); match != nil {
+		rule := &SafariRule{
+			Trigger: SafariTrigger{URLFilter: `^[^:]+://+([^:/]+\.)?` + regexp.QuoteMeta(match[1]) + `[:/]`},
+			Action:  SafariAction{Type: "block"},
+		}
+		switch match[2] {
+		case "third-party", "3p":
+			rule.Trigger.LoadType = []string{"third-party"}
+		case "first-party", "1p":
+			rule.Trigger.LoadType = []string{"first-party"}
+		}
+		return rule
+	}
+
+	domains, selector, found := strings.Cut(line, "##")
+	if !found || selector == "" || selector[0] == '+' || selector[0] == '^' || extendedSelector.MatchString(selector) {
+		return nil
+	}
+	// Exceptions and other cosmetic rule types use a different separator, that ends up in the domains
+	if strings.ContainsAny(domains, "#$@?%/|") {
+		return nil
+	}
+	rule := &SafariRule{
+		Trigger: SafariTrigger{URLFilter: ".*"},
+		Action:  SafariAction{Type: "css-display-none", Selector: selector},
+	}
+	if domains == "" {
+		return rule
+	}
+	for _, domain := range strings.Split(domains, ",") {
+		domain = strings.TrimSpace(domain)
+		switch {
+		case domain == "" || strings.HasSuffix(domain, ".*"):
+			// Entity matching is not supported
+			return nil
+		case domain[0] == '~':
+			rule.Trigger.UnlessDomain = append(rule.Trigger.UnlessDomain, "*"+strings.ToLower(domain[1:]))
+		default:
+			rule.Trigger.IfDomain = append(rule.Trigger.IfDomain, "*"+strings.ToLower(domain))
+		}
+	}
+	// A trigger cannot hold both lists
+	if len(rule.Trigger.IfDomain) > 0 && len(rule.Trigger.UnlessDomain) > 0 {
+		return nil
+	}
+	return rule
+}
@@ -0,0 +1,61 @@
+package filters
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertToSafari(t *testing.T) {
+	input := `! Title: My filters
+[Adblock Plus 2.0]
+
+##.generic-ad
+example.com,www.Example.org##.sidebar > div
+~example.com##.banner
+||tracker.net^
+||ads.example.com^$third-party
+example.com,~sub.example.com##.mixed
+youtube.com##ytd-rich-item-renderer:has-text(Shorts)
+example.com#@#.sidebar
+example.com#?#.ad:-abp-has(.sponsored)
+example.com##+js(nowebrtc)
+google.*##.entity
+||example.com/ads/*
+@@||example.com^
+/banner[0-9]+/
+`
+	conversion := ConvertToSafari([]byte(input))
+	assert.Equal(t, []string{
+		"example.com,~sub.example.com##.mixed",
+		"youtube.com##ytd-rich-item-renderer:has-text(Shorts)",
+		"example.com#@#.sidebar",
+		"example.com#?#.ad:-abp-has(.sponsored)",
+		"example.com##+js(nowebrtc)",
+		"google.*##.entity",
+		"||example.com/ads/*",
+		"@@||example.com^",
+		"/banner[0-9]+/",
+	}, conversion.Untranslated)
+
+	out, err := json.Marshal(conversion.Rules)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"trigger": {"url-filter": ".*"}, "action": {"type": "css-display-none", "selector": ".generic-ad"}},
+		{"trigger": {"url-filter": ".*", "if-domain": ["*example.com", "*www.example.org"]},
+		 "action": {"type": "css-display-none", "selector": ".sidebar > div"}},
+		{"trigger": {"url-filter": ".*", "unless-domain": ["*example.com"]},
+		 "action": {"type": "css-display-none", "selector": ".banner"}},
+		{"trigger": {"url-filter": "^[^:]+://+([^:/]+\\.)?tracker\\.net[:/]"}, "action": {"type": "block"}},
+		{"trigger": {"url-filter": "^[^:]+://+([^:/]+\\.)?ads\\.example\\.com[:/]", "load-type": ["third-party"]},
+		 "action": {"type": "block"}}
+	]`, string(out))
+}
+
+func TestConvertToSafari_Empty(t *testing.T) {
+	conversion := ConvertToSafari([]byte("! Only comments\n\n"))
+	assert.Empty(t, conversion.Rules)
+	assert.Empty(t, conversion.Untranslated)
+}
@@ -0,0 +1,69 @@
+package filters
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	listSizeWarningTemplate      = "! WARNING: this list holds %d rules, above the limit of %d: uBlock Origin can take a long time to load it.\n"
+	listContributionTemplate     = "! %s #%d contributed %d rules\n"
+	listSizeWarningContributions = 5 // Largest instances listed in the warning
+)
+
+// ruleCounter counts the rules written through it, skipping empty lines and comments
+type ruleCounter struct {
+	out   io.Writer
+	rules int
+	seen  bool // A character of the current line was seen
+}
+
+func (r *ruleCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		switch {
+		case b == '\n':
+			r.seen = false
+		case r.seen || b == ' ' || b == '\t' || b == '\r':
+		default:
+			r.seen = true
+			if b != '!' {
+				r.rules++
+			}
+		}
+	}
+	return r.out.Write(p)
+}
+
+// CountRules returns the number of rules in a rendered list, skipping empty lines and comments
+func CountRules(list []byte) int {
+	counter := &ruleCounter{out: io.Discard}
+	_, _ = counter.Write(list)
+	return counter.rules
+}
+
+// instanceSize is the number of rules rendered for an instance, with its 1-based position in the list
+type instanceSize struct {
+	Template string
+	Position int
+	Rules    int
+}
+
+// writeSizeWarning warns that the list holds too many rules, listing the instances contributing most of them
+func writeSizeWarning(out io.Writer, rules, threshold int, sizes []instanceSize) error {
+	if _, err := fmt.Fprintf(out, listSizeWarningTemplate, rules, threshold); err != nil {
+		return err
+	}
+	sort.SliceStable(sizes, func(i, j int) bool {
+		return sizes[i].Rules > sizes[j].Rules
+	})
+	for n, size := range sizes {
+		if n == listSizeWarningContributions || size.Rules == 0 {
+			break
+		}
+		if _, err := fmt.Fprintf(out, listContributionTemplate, size.Template, size.Position, size.Rules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
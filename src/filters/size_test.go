@@ -0,0 +1,66 @@
+package filters
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang/mock/gomock"
+	"github.com/letsblockit/letsblockit/src/filters/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountRules(t *testing.T) {
+	assert.Equal(t, 0, CountRules(nil))
+	assert.Equal(t, 3, CountRules([]byte("! Title: test\n\n||a.example^\n  ! indented comment\nexample.com##.ad\n\t\n##.banner")))
+}
+
+func TestRenderSizeWarning(t *testing.T) {
+	templates := fstest.MapFS{
+		"custom-rules.yaml": importTemplates["custom-rules.yaml"],
+		"hello.yaml":        {Data: []byte("title: Hello\ntemplate: |\n  ##.hello\n---\n\nHello")},
+	}
+	repo, err := Load(templates, templates)
+	require.NoError(t, err)
+	logger := mocks.NewMocklogger(gomock.NewController(t))
+
+	list := &List{Title: "Big", SizeWarning: 3, Instances: []*Instance{{
+		Template: "hello",
+	}, {
+		Template: CustomRulesFilterName,
+		Params:   map[string]interface{}{"rules": "##.one\n! comment\n##.two\n##.three\n"},
+	}}}
+	buf := &strings.Builder{}
+	require.NoError(t, list.Render(buf, logger, repo))
+	assert.Equal(t, 4, list.Rules)
+	assert.Equal(t, `! Title: letsblock.it - Big
+! Expires: 12 hours
+! Homepage: https://letsblock.it
+! License: https://github.com/letsblockit/letsblockit/blob/main/LICENSE.txt
+! WARNING: this list holds 4 rules, above the limit of 3: uBlock Origin can take a long time to load it.
+! custom-rules #2 contributed 3 rules
+! hello #1 contributed 1 rules
+
+! hello
+##.hello
+
+! custom-rules
+##.one
+! comment
+##.two
+##.three
+`, buf.String())
+
+	// No change at or below the threshold
+	unlimited := &strings.Builder{}
+	list.SizeWarning = 0
+	require.NoError(t, list.Render(unlimited, logger, repo))
+	assert.Equal(t, 4, list.Rules)
+
+	buf.Reset()
+	list.SizeWarning = 4
+	require.NoError(t, list.Render(buf, logger, repo))
+	assert.Equal(t, unlimited.String(), buf.String())
+	assert.NotContains(t, buf.String(), "WARNING")
+}
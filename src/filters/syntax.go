@@ -0,0 +1,115 @@
+package filters
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// cosmeticSeparator matches the separators of element hiding, procedural, scriptlet, style and HTML rules,
+// and of their exceptions
+var cosmeticSeparator = regexp.MustCompile(`#@?[?$%]?#`)
+
+var openingBrackets = map[byte]byte{')': '(', ']': '[', '}': '{'}
+
+// InvalidRulePrefix comments out the lines failing CheckRuleSyntax in safe mode
+const InvalidRulePrefix = "! INVALID: "
+
+var (
+	errEmptyDomain      = errors.New("empty domain")
+	errEmptyOption      = errors.New("empty network option")
+	errEmptySelector    = errors.New("empty selector")
+	errUnbalanced       = errors.New("unbalanced brackets")
+	errUnclosedScript   = errors.New("unclosed scriptlet arguments")
+	errUnclosedString   = errors.New("unclosed string")
+	errWhitespaceRule   = errors.New("whitespace in a network rule")
+	errWhitespaceDomain = errors.New("whitespace in the domains of a cosmetic rule")
+)
+
+// CheckRuleSyntax returns an error if a rendered line cannot be a valid uBlock Origin rule.
+// It only catches the mistakes breaking the rule, such as unbalanced brackets or stray whitespace:
+// lines passing the check can still be rejected by the adblocker. Comments and empty lines are valid.
+func CheckRuleSyntax(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '!' || line[0] == '[' || strings.HasPrefix(line, "# ") {
+		return nil
+	}
+	if loc := cosmeticSeparator.FindStringIndex(line); loc != nil {
+		return checkCosmeticRule(line[:loc[0]], line[loc[1]:])
+	}
+	if hostsEntry.MatchString(line) {
+		return nil
+	}
+	return checkNetworkRule(line)
+}
+
+func checkCosmeticRule(domains, body string) error {
+	if strings.ContainsAny(domains, " \t") {
+		return errWhitespaceDomain
+	}
+	if domains != "" {
+		for _, domain := range strings.Split(domains, ",") {
+			if domain == "" || domain == "~" {
+				return errEmptyDomain
+			}
+		}
+	}
+	body = strings.TrimPrefix(body, "^") // HTML filtering
+	if body == "" {
+		return errEmptySelector
+	}
+	if strings.HasPrefix(body, "+js(") && !strings.HasSuffix(body, ")") {
+		return errUnclosedScript
+	}
+	return checkBalanced(body)
+}
+
+func checkNetworkRule(line string) error {
+	pattern, options := strings.TrimPrefix(line, "@@"), ""
+	if pos := strings.LastIndexByte(pattern, '$'); pos >= 0 {
+		// Regular expressions can hold a $ anchor, the options are after their closing slash
+		if pattern[0] != '/' || pos > strings.LastIndexByte(pattern, '/') {
+			pattern, options = pattern[:pos], pattern[pos+1:]
+			if options == "" || options[0] == ',' || options[len(options)-1] == ',' || strings.Contains(options, ",,") {
+				return errEmptyOption
+			}
+		}
+	}
+	if strings.ContainsAny(pattern, " \t") || strings.ContainsAny(options, " \t") {
+		return errWhitespaceRule
+	}
+	return nil
+}
+
+// checkBalanced checks that the brackets of a selector are balanced, ignoring the ones in strings
+func checkBalanced(selector string) error {
+	var stack []byte
+	var quote byte
+	for i := 0; i < len(selector); i++ {
+		c := selector[i]
+		switch {
+		case c == '\\':
+			i++ // Skip the escaped character
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			stack = append(stack, c)
+		case c == ')' || c == ']' || c == '}':
+			if len(stack) == 0 || stack[len(stack)-1] != openingBrackets[c] {
+				return errUnbalanced
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	switch {
+	case quote != 0:
+		return errUnclosedString
+	case len(stack) > 0:
+		return errUnbalanced
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package filters
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang/mock/gomock"
+	"github.com/letsblockit/letsblockit/data"
+	"github.com/letsblockit/letsblockit/src/filters/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRuleSyntax(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"! comment",
+		"[Adblock Plus 2.0]",
+		"# hosts comment",
+		"!#if env_firefox",
+		"||tracker.example^",
+		"@@||example.com^$script,domain=a.example|b.example",
+		"/banner[0-9]+\\.png$/",
+		"/ads$/$image",
+		"0.0.0.0 tracker.example",
+		"example.com##.ad",
+		"~example.com,example.org#@#.ad",
+		"##div[class^=\"ad \"]:has-text(/sponsored (post|video)/)",
+		"www.youtube.com##ytd-browse #dismissible ytd-rich-grid-slim-media[is-short]:upward(ytd-rich-section-renderer)",
+		"example.com##+js(set-constant, ads, false)",
+		"example.com##^script:has-text(ads)",
+		"example.com#?#.ad:-abp-has(.sponsored)",
+		"example.com##.ad:style(margin: 0 !important)",
+	} {
+		assert.NoError(t, CheckRuleSyntax(line), line)
+	}
+
+	for line, expected := range map[string]error{
+		"hello from one":                    errWhitespaceRule,
+		"||example.com^$":                   errEmptyOption,
+		"||example.com^$script,,image":      errEmptyOption,
+		"example .com##.ad":                 errWhitespaceDomain,
+		"example.com,##.ad":                 errEmptyDomain,
+		"example.com##":                     errEmptySelector,
+		"example.com##^":                    errEmptySelector,
+		"example.com##div:has(.ad":          errUnbalanced,
+		"example.com##div[class=ad)":        errUnbalanced,
+		"example.com##div[title=\"ad]":      errUnclosedString,
+		"example.com##+js(set-constant, ad": errUnclosedScript,
+	} {
+		assert.Equal(t, expected, CheckRuleSyntax(line), line)
+	}
+}
+
+// The rules rendered by the embedded templates must pass the check, to not be commented out in safe mode
+func TestCheckRuleSyntax_Templates(t *testing.T) {
+	repo, err := Load(data.Templates, data.Presets)
+	require.NoError(t, err)
+	for _, tpl := range repo.GetAll() {
+		if tpl.Name == CustomRulesFilterName {
+			continue
+		}
+		for _, test := range tpl.Tests {
+			for _, line := range strings.Split(test.Output, "\n") {
+				assert.NoError(t, CheckRuleSyntax(line), "%s: %s", tpl.Name, line)
+			}
+		}
+	}
+}
+
+func TestRenderSafeMode(t *testing.T) {
+	templates := fstest.MapFS{
+		"custom-rules.yaml": importTemplates["custom-rules.yaml"],
+		"hello.yaml":        {Data: []byte("title: Hello\ntemplate: Hello world\n---\n\nHello")},
+	}
+	repo, err := Load(templates, templates)
+	require.NoError(t, err)
+	logger := mocks.NewMocklogger(gomock.NewController(t))
+	logger.EXPECT().Warnf("template %s rendered an invalid line: %s: %s", "hello", errWhitespaceRule, "Hello world")
+
+	buf := &strings.Builder{}
+	list := &List{Title: "Safe", SafeMode: true, Instances: []*Instance{{
+		Template: CustomRulesFilterName,
+		Params:   map[string]interface{}{"rules": "example.com##.ad\nexample.com##div:has(.ad\n"},
+	}, {
+		Template: "hello",
+	}}}
+	require.NoError(t, list.Render(buf, logger, repo))
+	assert.Equal(t, 2, list.Neutralized)
+	assert.Equal(t, `! Title: letsblock.it - Safe
+! Expires: 12 hours
+! Homepage: https://letsblock.it
+! License: https://github.com/letsblockit/letsblockit/blob/main/LICENSE.txt
+
+! custom-rules
+example.com##.ad
+! INVALID: example.com##div:has(.ad
+
+! hello
+! INVALID: Hello world`, buf.String())
+
+	// Lines are kept as-is without safe mode
+	buf.Reset()
+	list.SafeMode = false
+	require.NoError(t, list.Render(buf, logger, repo))
+	assert.Contains(t, buf.String(), "\nexample.com##div:has(.ad\n")
+}
@@ -1,5 +1,11 @@
 package filters
 
+import (
+	"fmt"
+	"sort"
+	"text/template"
+)
+
 var (
 	presetNameSeparator = "---preset---"
 	filenameSuffix      = ".yaml"
@@ -22,8 +28,14 @@ type Template struct {
 	Tags        []string    `validate:"dive,alphaunicode" yaml:",omitempty"`
 	Template    string      `validate:"required"`
 	Tests       []testCase
-	Description string        `validate:"required" yaml:"-"`
-	presets     []presetEntry `yaml:"-"` // Generated on parse from params and presets
+	Description string `validate:"required" yaml:"-"`
+	// Templates that should not be enabled alongside this one, see FindOverlaps
+	ConflictsWith []string `validate:"dive,required" yaml:"conflicts_with,omitempty"`
+	Supersedes    []string `validate:"dive,required" yaml:",omitempty"`
+	// Optional text/template over the instance params, rendered by DisplayName
+	DisplayNameTemplate string             `yaml:"display_name_template,omitempty"`
+	presets             []presetEntry      `yaml:"-"` // Generated on parse from params and presets
+	displayName         *template.Template `yaml:"-"` // Compiled on parse from DisplayNameTemplate
 }
 
 type presetEntry struct {
@@ -69,3 +81,105 @@ func (f *Template) HasTag(tag string) bool {
 func (p *Parameter) BuildPresetParamName(preset string) string {
 	return p.Name + presetNameSeparator + preset
 }
+
+// DefaultParams returns the default value for all parameters and presets of the template
+func (f *Template) DefaultParams() map[string]interface{} {
+	params := make(map[string]interface{})
+	for _, param := range f.Params {
+		params[param.Name] = param.Default
+		for _, preset := range param.Presets {
+			params[param.BuildPresetParamName(preset.Name)] = preset.Default
+		}
+	}
+	return params
+}
+
+// ParamError reports an invalid instance parameter
+type ParamError struct {
+	Param   string
+	Message string
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("parameter %s: %s", e.Param, e.Message)
+}
+
+// ValidateParams checks instance parameters against the template's parameter definitions,
+// returning one *ParamError per invalid or unknown parameter. Missing parameters are allowed.
+func (f *Template) ValidateParams(params map[string]interface{}) []error {
+	var errs []error
+	invalidType := func(name string, expected ParamType, value interface{}) {
+		errs = append(errs, &ParamError{
+			Param:   name,
+			Message: fmt.Sprintf("expected a %s value, got %T", expected, value),
+		})
+	}
+
+	known := make(map[string]struct{}, len(params))
+	for _, p := range f.Params {
+		known[p.Name] = struct{}{}
+		if value, found := params[p.Name]; found && !p.accepts(value) {
+			invalidType(p.Name, p.Type, value)
+		}
+		for _, preset := range p.Presets {
+			name := p.BuildPresetParamName(preset.Name)
+			known[name] = struct{}{}
+			if value, found := params[name]; found {
+				if _, ok := value.(bool); !ok {
+					invalidType(name, BooleanParam, value)
+				}
+			}
+		}
+	}
+
+	var unknown []string
+	for name := range params {
+		if _, found := known[name]; !found {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, &ParamError{Param: name, Message: "unknown parameter"})
+	}
+	return errs
+}
+
+// NormalizeParams returns the instance parameters without the invalid or unknown ones, for their default
+// value to be used instead, and whether some were dropped. The params map is not modified.
+func (f *Template) NormalizeParams(params map[string]interface{}) (map[string]interface{}, bool) {
+	errs := f.ValidateParams(params)
+	if len(errs) == 0 {
+		return params, false
+	}
+	normalized := shallowCopy(params)
+	for _, err := range errs {
+		delete(normalized, err.(*ParamError).Param)
+	}
+	return normalized, true
+}
+
+// accepts returns whether a parameter value is valid for the parameter type
+func (p *Parameter) accepts(value interface{}) bool {
+	switch p.Type {
+	case BooleanParam:
+		_, ok := value.(bool)
+		return ok
+	case StringParam, MultiLineParam:
+		_, ok := value.(string)
+		return ok
+	case StringListParam:
+		switch values := value.(type) {
+		case []string:
+			return true
+		case []interface{}:
+			for _, v := range values {
+				if _, ok := v.(string); !ok {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
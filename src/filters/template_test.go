@@ -41,19 +41,10 @@ func TestValidateTemplates(t *testing.T) {
 			assert.NoError(t, validate.Struct(filter), "Template did no pass input validation")
 		})
 
-		for i, tc := range filter.Tests {
-			t.Run(fmt.Sprintf("Test/%s/%d", name, i), func(t *testing.T) {
-				var buf strings.Builder
-				ctx := make(map[string]interface{})
-				for k, v := range tc.Params {
-					ctx[k] = v
-				}
-				assert.NoError(t, repo.Render(&buf, &Instance{
-					Template: filter.Name,
-					Params:   ctx,
-					TestMode: false,
-				}))
-				assert.Equal(t, tc.Output, buf.String())
+		for _, result := range repo.RunTests(filter) {
+			t.Run(fmt.Sprintf("Test/%s/%d", name, result.Index), func(t *testing.T) {
+				assert.NoError(t, result.Err)
+				assert.Equal(t, result.Expected, result.Output)
 			})
 		}
 
@@ -81,3 +72,49 @@ func checkRedundantPresetValues(f *Template, presets fs.FS) error {
 	}
 	return nil
 }
+
+func TestValidateParams(t *testing.T) {
+	repo, err := Load(testTemplates, testTemplates)
+	require.NoError(t, err)
+	tpl, err := repo.Get("simple")
+	require.NoError(t, err)
+
+	assert.Empty(t, tpl.ValidateParams(nil))
+	assert.EqualError(t, &ParamError{Param: "one", Message: "unknown parameter"}, "parameter one: unknown parameter")
+	assert.Empty(t, tpl.ValidateParams(map[string]interface{}{
+		"boolean_param": false,
+		"string_param":  "value",
+		"string_list":   []interface{}{"one", "two"},
+	}))
+	assert.Equal(t, []error{
+		&ParamError{Param: "boolean_param", Message: "expected a checkbox value, got string"},
+		&ParamError{Param: "string_param", Message: "expected a string value, got int"},
+		&ParamError{Param: "string_list", Message: "expected a list value, got []interface {}"},
+		&ParamError{Param: "one", Message: "unknown parameter"},
+		&ParamError{Param: "two", Message: "unknown parameter"},
+	}, tpl.ValidateParams(map[string]interface{}{
+		"boolean_param": "true",
+		"string_param":  12,
+		"string_list":   []interface{}{"one", 2},
+		"two":           true,
+		"one":           true,
+	}))
+}
+
+func TestNormalizeParams(t *testing.T) {
+	repo, err := Load(testTemplates, testTemplates)
+	require.NoError(t, err)
+	tpl, err := repo.Get("simple")
+	require.NoError(t, err)
+
+	valid := map[string]interface{}{"boolean_param": false, "string_list": []interface{}{"one", "two"}}
+	normalized, changed := tpl.NormalizeParams(valid)
+	assert.False(t, changed)
+	assert.Equal(t, valid, normalized)
+
+	params := map[string]interface{}{"boolean_param": "true", "string_param": "value", "removed": true}
+	normalized, changed = tpl.NormalizeParams(params)
+	assert.True(t, changed)
+	assert.Equal(t, map[string]interface{}{"string_param": "value"}, normalized)
+	assert.Len(t, params, 3, "the params are not modified")
+}
@@ -0,0 +1,43 @@
+package filters
+
+import (
+	"strings"
+	"time"
+)
+
+// TestResult holds the outcome of one of the test cases declared by a template
+type TestResult struct {
+	Template string
+	Index    int
+	Expected string
+	Output   string
+	Err      error
+	Duration time.Duration
+}
+
+func (r *TestResult) Passed() bool {
+	return r.Err == nil && r.Output == r.Expected
+}
+
+// RunTests renders the test cases declared by the template, and compares them to their expected output
+func (r *Repository) RunTests(tpl *Template) []*TestResult {
+	results := make([]*TestResult, 0, len(tpl.Tests))
+	for i, tc := range tpl.Tests {
+		var buf strings.Builder
+		start := time.Now()
+		err := r.Render(&buf, &Instance{
+			Template: tpl.Name,
+			Params:   tc.Params,
+			TestMode: false,
+		})
+		results = append(results, &TestResult{
+			Template: tpl.Name,
+			Index:    i,
+			Expected: tc.Output,
+			Output:   buf.String(),
+			Err:      err,
+			Duration: time.Since(start),
+		})
+	}
+	return results
+}
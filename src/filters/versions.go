@@ -0,0 +1,71 @@
+package filters
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/imantung/mario"
+	"gopkg.in/yaml.v3"
+)
+
+// versionDefinition holds the parts of a template needed to render it, with the preset values inlined
+type versionDefinition struct {
+	Title    string
+	Params   []Parameter `yaml:",omitempty"`
+	Template string
+}
+
+// TemplateVersion is a previous version of a template, restored from its definition
+type TemplateVersion struct {
+	template *Template
+	compiled *mario.Template
+}
+
+// Definition serializes a template for ParseDefinition, to keep rendering this version once it is updated
+func (r *Repository) Definition(name string) (string, error) {
+	tpl, found := r.templateMap[name]
+	if !found {
+		return "", fmt.Errorf("unknown template '%s'", name)
+	}
+	out, err := yaml.Marshal(&versionDefinition{
+		Title:    tpl.Title,
+		Params:   tpl.Params,
+		Template: tpl.Template,
+	})
+	return string(out), err
+}
+
+// ParseDefinition compiles a template version stored by Repository.Definition
+func ParseDefinition(name, definition string) (*TemplateVersion, error) {
+	var def versionDefinition
+	if err := yaml.Unmarshal([]byte(definition), &def); err != nil {
+		return nil, fmt.Errorf("invalid definition for %s: %w", name, err)
+	}
+	tpl := &Template{
+		Name:     name,
+		Title:    def.Title,
+		Params:   def.Params,
+		Template: def.Template,
+	}
+	if err := parsePresets(tpl, noPresets{}); err != nil {
+		return nil, err
+	}
+	compiled, err := compileTemplate(tpl)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateVersion{template: tpl, compiled: compiled}, nil
+}
+
+// Render renders an instance with this template version, ignoring its template name
+func (v *TemplateVersion) Render(w io.Writer, instance *Instance) error {
+	return renderTemplate(w, v.template, v.compiled, instance)
+}
+
+// noPresets is used for definitions, that already hold their preset values
+type noPresets struct{}
+
+func (noPresets) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
@@ -0,0 +1,49 @@
+package filters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/letsblockit/letsblockit/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinitionRoundTrip(t *testing.T) {
+	repo, err := Load(data.Templates, data.Presets)
+	require.NoError(t, err)
+
+	for _, tpl := range repo.GetAll() {
+		t.Run(tpl.Name, func(t *testing.T) {
+			definition, err := repo.Definition(tpl.Name)
+			require.NoError(t, err)
+			version, err := ParseDefinition(tpl.Name, definition)
+			require.NoError(t, err)
+
+			// Every preset is enabled, to check the preset values are kept
+			params := tpl.DefaultParams()
+			for _, preset := range tpl.presets {
+				params[preset.EnableKey] = true
+			}
+			instances := []*Instance{{Template: tpl.Name, Params: params}}
+			for _, test := range tpl.Tests {
+				instances = append(instances, &Instance{Template: tpl.Name, Params: test.Params})
+			}
+			for _, instance := range instances {
+				var expected, actual strings.Builder
+				require.NoError(t, repo.Render(&expected, instance))
+				require.NoError(t, version.Render(&actual, instance))
+				assert.Equal(t, expected.String(), actual.String())
+			}
+		})
+	}
+}
+
+func TestDefinitionUnknown(t *testing.T) {
+	repo, err := Load(data.Templates, data.Presets)
+	require.NoError(t, err)
+	_, err = repo.Definition("unknown")
+	assert.Error(t, err)
+	_, err = ParseDefinition("invalid", "not: [valid")
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+// Multi sends metrics to several clients, returning the first error
+type Multi []statsd.ClientInterface
+
+var _ statsd.ClientInterface = Multi(nil)
+
+// New returns a client sending to all the given clients, or a no-op client if none are given
+func New(clients ...statsd.ClientInterface) statsd.ClientInterface {
+	switch len(clients) {
+	case 0:
+		return &statsd.NoOpClient{}
+	case 1:
+		return clients[0]
+	default:
+		return Multi(clients)
+	}
+}
+
+func (m Multi) each(f func(statsd.ClientInterface) error) error {
+	var firstErr error
+	for _, c := range m {
+		if err := f(c); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m Multi) Gauge(name string, value float64, tags []string, rate float64) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.Gauge(name, value, tags, rate) })
+}
+
+func (m Multi) GaugeWithTimestamp(name string, value float64, tags []string, rate float64, timestamp time.Time) error {
+	return m.each(func(c statsd.ClientInterface) error {
+		return c.GaugeWithTimestamp(name, value, tags, rate, timestamp)
+	})
+}
+
+func (m Multi) Count(name string, value int64, tags []string, rate float64) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.Count(name, value, tags, rate) })
+}
+
+func (m Multi) CountWithTimestamp(name string, value int64, tags []string, rate float64, timestamp time.Time) error {
+	return m.each(func(c statsd.ClientInterface) error {
+		return c.CountWithTimestamp(name, value, tags, rate, timestamp)
+	})
+}
+
+func (m Multi) Histogram(name string, value float64, tags []string, rate float64) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.Histogram(name, value, tags, rate) })
+}
+
+func (m Multi) Distribution(name string, value float64, tags []string, rate float64) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.Distribution(name, value, tags, rate) })
+}
+
+func (m Multi) Decr(name string, tags []string, rate float64) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.Decr(name, tags, rate) })
+}
+
+func (m Multi) Incr(name string, tags []string, rate float64) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.Incr(name, tags, rate) })
+}
+
+func (m Multi) Set(name string, value string, tags []string, rate float64) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.Set(name, value, tags, rate) })
+}
+
+func (m Multi) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.Timing(name, value, tags, rate) })
+}
+
+func (m Multi) TimeInMilliseconds(name string, value float64, tags []string, rate float64) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.TimeInMilliseconds(name, value, tags, rate) })
+}
+
+func (m Multi) Event(e *statsd.Event) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.Event(e) })
+}
+
+func (m Multi) SimpleEvent(title, text string) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.SimpleEvent(title, text) })
+}
+
+func (m Multi) ServiceCheck(sc *statsd.ServiceCheck) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.ServiceCheck(sc) })
+}
+
+func (m Multi) SimpleServiceCheck(name string, status statsd.ServiceCheckStatus) error {
+	return m.each(func(c statsd.ClientInterface) error { return c.SimpleServiceCheck(name, status) })
+}
+
+func (m Multi) Close() error {
+	return m.each(func(c statsd.ClientInterface) error { return c.Close() })
+}
+
+func (m Multi) Flush() error {
+	return m.each(func(c statsd.ClientInterface) error { return c.Flush() })
+}
+
+func (m Multi) IsClosed() bool {
+	for _, c := range m {
+		if !c.IsClosed() {
+			return false
+		}
+	}
+	return true
+}
+
+// GetTelemetry returns the telemetry of the first client
+func (m Multi) GetTelemetry() statsd.Telemetry {
+	if len(m) == 0 {
+		return statsd.Telemetry{}
+	}
+	return m[0].GetTelemetry()
+}
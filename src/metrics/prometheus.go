@@ -0,0 +1,315 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+// Distribution names are suffixed with their unit, to pick their histogram buckets.
+// Durations are sent in nanoseconds, and exported in seconds.
+const (
+	durationSuffix = "_duration"
+	bytesSuffix    = "_bytes"
+)
+
+var (
+	// DurationBuckets are the buckets of durations, in seconds
+	DurationBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// SizeBuckets are the buckets of sizes, in bytes
+	SizeBuckets = []float64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20}
+	// DefaultBuckets are the buckets of other distributions, like item counts
+	DefaultBuckets = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500}
+)
+
+type metricKind string
+
+const (
+	counterKind   metricKind = "counter"
+	gaugeKind     metricKind = "gauge"
+	histogramKind metricKind = "histogram"
+)
+
+type series struct {
+	labels  string
+	value   float64
+	buckets []uint64
+	count   uint64
+}
+
+type family struct {
+	kind    metricKind
+	buckets []float64
+	series  map[string]*series
+}
+
+// Registry implements statsd.ClientInterface by aggregating metrics in memory, and serves them
+// in the Prometheus text format. Metric names are converted to the Prometheus conventions:
+// "letsblockit.list_download" with the "etag_match:true" tag is exported as
+// letsblockit_list_download_total{etag_match="true"}. Events, sets and service checks are ignored.
+type Registry struct {
+	sync.Mutex
+	durationBuckets []float64
+	sizeBuckets     []float64
+	defaultBuckets  []float64
+	families        map[string]*family
+}
+
+var _ statsd.ClientInterface = (*Registry)(nil)
+
+func NewRegistry() *Registry {
+	return &Registry{
+		durationBuckets: DurationBuckets,
+		sizeBuckets:     SizeBuckets,
+		defaultBuckets:  DefaultBuckets,
+		families:        make(map[string]*family),
+	}
+}
+
+func (r *Registry) get(name string, kind metricKind, tags []string) *series {
+	f, found := r.families[name]
+	if !found {
+		f = &family{kind: kind, series: make(map[string]*series)}
+		if kind == histogramKind {
+			f.buckets = r.bucketsFor(name)
+		}
+		r.families[name] = f
+	} else if f.kind != kind {
+		return nil // Conflicting types, drop the value
+	}
+	labels := formatLabels(tags)
+	s, found := f.series[labels]
+	if !found {
+		s = &series{labels: labels}
+		if kind == histogramKind {
+			s.buckets = make([]uint64, len(f.buckets))
+		}
+		f.series[labels] = s
+	}
+	return s
+}
+
+func (r *Registry) bucketsFor(name string) []float64 {
+	switch {
+	case strings.HasSuffix(name, durationSuffix+"_seconds"):
+		return r.durationBuckets
+	case strings.HasSuffix(name, bytesSuffix):
+		return r.sizeBuckets
+	default:
+		return r.defaultBuckets
+	}
+}
+
+func (r *Registry) add(name string, value int64, tags []string) {
+	r.Lock()
+	defer r.Unlock()
+	if s := r.get(convertName(name)+"_total", counterKind, tags); s != nil {
+		s.value += float64(value)
+	}
+}
+
+func (r *Registry) observe(name string, value float64, tags []string) {
+	name = convertName(name)
+	if strings.HasSuffix(name, durationSuffix) {
+		name += "_seconds"
+		value /= float64(time.Second)
+	}
+	r.Lock()
+	defer r.Unlock()
+	s := r.get(name, histogramKind, tags)
+	if s == nil {
+		return
+	}
+	s.value += value
+	s.count++
+	for i, bound := range r.families[name].buckets {
+		if value <= bound {
+			s.buckets[i]++
+		}
+	}
+}
+
+func (r *Registry) Gauge(name string, value float64, tags []string, _ float64) error {
+	r.Lock()
+	defer r.Unlock()
+	if s := r.get(convertName(name), gaugeKind, tags); s != nil {
+		s.value = value
+	}
+	return nil
+}
+
+func (r *Registry) GaugeWithTimestamp(name string, value float64, tags []string, rate float64, _ time.Time) error {
+	return r.Gauge(name, value, tags, rate)
+}
+
+func (r *Registry) Count(name string, value int64, tags []string, _ float64) error {
+	if value > 0 { // Prometheus counters can only go up
+		r.add(name, value, tags)
+	}
+	return nil
+}
+
+func (r *Registry) CountWithTimestamp(name string, value int64, tags []string, rate float64, _ time.Time) error {
+	return r.Count(name, value, tags, rate)
+}
+
+func (r *Registry) Histogram(name string, value float64, tags []string, _ float64) error {
+	r.observe(name, value, tags)
+	return nil
+}
+
+func (r *Registry) Distribution(name string, value float64, tags []string, _ float64) error {
+	r.observe(name, value, tags)
+	return nil
+}
+
+func (r *Registry) Decr(string, []string, float64) error {
+	return nil
+}
+
+func (r *Registry) Incr(name string, tags []string, _ float64) error {
+	r.add(name, 1, tags)
+	return nil
+}
+
+func (r *Registry) Set(string, string, []string, float64) error {
+	return nil
+}
+
+func (r *Registry) Timing(name string, value time.Duration, tags []string, _ float64) error {
+	r.observe(strings.TrimSuffix(name, durationSuffix)+durationSuffix, float64(value.Nanoseconds()), tags)
+	return nil
+}
+
+func (r *Registry) TimeInMilliseconds(name string, value float64, tags []string, rate float64) error {
+	return r.Timing(name, time.Duration(value*float64(time.Millisecond)), tags, rate)
+}
+
+func (r *Registry) Event(*statsd.Event) error {
+	return nil
+}
+
+func (r *Registry) SimpleEvent(string, string) error {
+	return nil
+}
+
+func (r *Registry) ServiceCheck(*statsd.ServiceCheck) error {
+	return nil
+}
+
+func (r *Registry) SimpleServiceCheck(string, statsd.ServiceCheckStatus) error {
+	return nil
+}
+
+func (r *Registry) Close() error {
+	return nil
+}
+
+func (r *Registry) Flush() error {
+	return nil
+}
+
+func (r *Registry) IsClosed() bool {
+	return false
+}
+
+func (r *Registry) GetTelemetry() statsd.Telemetry {
+	return statsd.Telemetry{}
+}
+
+// ServeHTTP writes all metrics in the Prometheus text format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = r.Write(w)
+}
+
+func (r *Registry) Write(w io.Writer) error {
+	r.Lock()
+	defer r.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := r.families[name]
+		_, _ = fmt.Fprintf(&b, "# TYPE %s %s\n", name, f.kind)
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := f.series[key]
+			if f.kind != histogramKind {
+				_, _ = fmt.Fprintf(&b, "%s%s %s\n", name, wrapLabels(s.labels), formatFloat(s.value))
+				continue
+			}
+			for i, bound := range f.buckets {
+				_, _ = fmt.Fprintf(&b, "%s_bucket%s %d\n", name,
+					wrapLabels(joinLabels(s.labels, `le="`+formatFloat(bound)+`"`)), s.buckets[i])
+			}
+			_, _ = fmt.Fprintf(&b, "%s_bucket%s %d\n", name, wrapLabels(joinLabels(s.labels, `le="+Inf"`)), s.count)
+			_, _ = fmt.Fprintf(&b, "%s_sum%s %s\n", name, wrapLabels(s.labels), formatFloat(s.value))
+			_, _ = fmt.Fprintf(&b, "%s_count%s %d\n", name, wrapLabels(s.labels), s.count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// convertName replaces the characters not allowed in Prometheus metric names
+func convertName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// formatLabels converts "key:value" statsd tags to sorted Prometheus labels, tags without a value are dropped
+func formatLabels(tags []string) string {
+	labels := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		key, value, found := strings.Cut(tag, ":")
+		if !found || key == "" {
+			continue
+		}
+		value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+		labels = append(labels, convertName(key)+`="`+value+`"`)
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ",")
+}
+
+func joinLabels(labels, extra string) string {
+	if labels == "" {
+		return extra
+	}
+	return labels + "," + extra
+}
+
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
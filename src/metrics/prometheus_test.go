@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Counters(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Incr("letsblockit.list_download", []string{"etag_present:true", "etag_match:false"}, 1))
+	require.NoError(t, r.Incr("letsblockit.list_download", []string{"etag_match:false", "etag_present:true"}, 1))
+	require.NoError(t, r.Incr("letsblockit.list_download", []string{"etag_match:true", "etag_present:true"}, 1))
+	require.NoError(t, r.Count("letsblockit.sessions_revoked", 3, nil, 1))
+	require.NoError(t, r.Count("letsblockit.sessions_revoked", -1, nil, 1))
+
+	var out strings.Builder
+	require.NoError(t, r.Write(&out))
+	assert.Equal(t, `# TYPE letsblockit_list_download_total counter
+letsblockit_list_download_total{etag_match="false",etag_present="true"} 2
+letsblockit_list_download_total{etag_match="true",etag_present="true"} 1
+# TYPE letsblockit_sessions_revoked_total counter
+letsblockit_sessions_revoked_total 3
+`, out.String())
+}
+
+func TestRegistry_Gauges(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Gauge("letsblockit.user_count", 12, nil, 1))
+	require.NoError(t, r.Gauge("letsblockit.user_count", 10, nil, 1))
+	require.NoError(t, r.Gauge("letsblockit.instance_count", 4, []string{"filter_name:youtube-cleanup", "ignored"}, 1))
+
+	var out strings.Builder
+	require.NoError(t, r.Write(&out))
+	assert.Equal(t, `# TYPE letsblockit_instance_count gauge
+letsblockit_instance_count{filter_name="youtube-cleanup"} 4
+# TYPE letsblockit_user_count gauge
+letsblockit_user_count 10
+`, out.String())
+}
+
+func TestRegistry_Durations(t *testing.T) {
+	r := NewRegistry()
+	r.durationBuckets = []float64{.01, .1}
+	require.NoError(t, r.Distribution("letsblockit.request_duration", float64(5*time.Millisecond), []string{"logged:true"}, 1))
+	require.NoError(t, r.Distribution("letsblockit.request_duration", float64(50*time.Millisecond), []string{"logged:true"}, 1))
+	require.NoError(t, r.Timing("letsblockit.render", 2*time.Second, nil, 1))
+
+	var out strings.Builder
+	require.NoError(t, r.Write(&out))
+	assert.Equal(t, `# TYPE letsblockit_render_duration_seconds histogram
+letsblockit_render_duration_seconds_bucket{le="0.01"} 0
+letsblockit_render_duration_seconds_bucket{le="0.1"} 0
+letsblockit_render_duration_seconds_bucket{le="+Inf"} 1
+letsblockit_render_duration_seconds_sum 2
+letsblockit_render_duration_seconds_count 1
+# TYPE letsblockit_request_duration_seconds histogram
+letsblockit_request_duration_seconds_bucket{logged="true",le="0.01"} 1
+letsblockit_request_duration_seconds_bucket{logged="true",le="0.1"} 2
+letsblockit_request_duration_seconds_bucket{logged="true",le="+Inf"} 2
+letsblockit_request_duration_seconds_sum{logged="true"} 0.055
+letsblockit_request_duration_seconds_count{logged="true"} 2
+`, out.String())
+}
+
+func TestRegistry_BucketsByUnit(t *testing.T) {
+	r := NewRegistry()
+	r.sizeBuckets = []float64{1024}
+	r.defaultBuckets = []float64{5}
+	require.NoError(t, r.Distribution("letsblockit.list_render_bytes", 2000, nil, 1))
+	require.NoError(t, r.Distribution("letsblockit.list_render_instances", 3, nil, 1))
+
+	var out strings.Builder
+	require.NoError(t, r.Write(&out))
+	assert.Contains(t, out.String(), `letsblockit_list_render_bytes_bucket{le="1024"} 0`)
+	assert.Contains(t, out.String(), `letsblockit_list_render_instances_bucket{le="5"} 1`)
+}
+
+func TestRegistry_ConflictingTypes(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Gauge("letsblockit.value", 1, nil, 1))
+	require.NoError(t, r.Histogram("letsblockit.value", 2, nil, 1))
+
+	var out strings.Builder
+	require.NoError(t, r.Write(&out))
+	assert.Equal(t, "# TYPE letsblockit_value gauge\nletsblockit_value 1\n", out.String())
+}
+
+func TestRegistry_EscapesLabels(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Incr("letsblockit.test", []string{`route:/a"b\c`}, 1))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, rec.Body.String(), `letsblockit_test_total{route="/a\"b\\c"} 1`)
+}
+
+func TestMulti(t *testing.T) {
+	first, second := NewRegistry(), NewRegistry()
+	client := New(first, second)
+	require.NoError(t, client.Incr("letsblockit.test", nil, 1))
+	for _, r := range []*Registry{first, second} {
+		var out strings.Builder
+		require.NoError(t, r.Write(&out))
+		assert.Contains(t, out.String(), "letsblockit_test_total 1\n")
+	}
+	assert.Same(t, first, New(first))
+}
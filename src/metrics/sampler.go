@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+// Metric classes, sampled at their own rate
+const (
+	DownloadClass = "download" // List downloads and renders, sent on every list request
+	RequestClass  = "request"  // Request durations and counts, sent on every request
+	OtherClass    = "other"
+	ErrorClass    = "error" // Errors are rare and must be accurate, they are never sampled
+)
+
+// SampleRates holds the sample rates of the metric classes, the missing classes are not sampled
+type SampleRates map[string]float64
+
+// ParseSampleRates parses class=rate values, it returns an error if a class is unknown or a rate is not in ]0, 1]
+func ParseSampleRates(values []string) (SampleRates, error) {
+	rates := make(SampleRates, len(values))
+	for _, value := range values {
+		class, raw, _ := strings.Cut(value, "=")
+		switch class {
+		case DownloadClass, RequestClass, OtherClass:
+		default:
+			return nil, fmt.Errorf("invalid sample rate class in %q, must be %s, %s or %s",
+				value, DownloadClass, RequestClass, OtherClass)
+		}
+		rate, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rate <= 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid sample rate in %q, must be between 0 (excluded) and 1", value)
+		}
+		rates[class] = rate
+	}
+	return rates, nil
+}
+
+// Classify returns the class of a metric, from its name
+func Classify(name string) string {
+	name = strings.TrimPrefix(name, "letsblockit.")
+	switch {
+	case name == "panic", strings.HasSuffix(name, "_error"), strings.HasSuffix(name, "_timeout"),
+		strings.HasSuffix(name, "_dropped"):
+		return ErrorClass
+	case strings.HasSuffix(name, "_download"), strings.HasPrefix(name, "list_render_"):
+		return DownloadClass
+	case strings.HasPrefix(name, "request_"):
+		return RequestClass
+	default:
+		return OtherClass
+	}
+}
+
+// Sampler multiplies the rate of the counters and distributions by the sample rate of their class.
+// The wrapped statsd client drops the sampled out values, and sends the rate for the agent to scale
+// the sampled values back. Gauges, sets and events are passed as-is. The rates can be changed at runtime.
+type Sampler struct {
+	statsd.ClientInterface
+	rates atomic.Pointer[SampleRates]
+}
+
+var _ statsd.ClientInterface = (*Sampler)(nil)
+
+func NewSampler(client statsd.ClientInterface, rates SampleRates) *Sampler {
+	s := &Sampler{ClientInterface: client}
+	s.SetRates(rates)
+	return s
+}
+
+// SetRates replaces the sample rates, that must be parsed by ParseSampleRates
+func (s *Sampler) SetRates(rates SampleRates) {
+	s.rates.Store(&rates)
+}
+
+func (s *Sampler) rate(name string, rate float64) float64 {
+	if classRate, found := (*s.rates.Load())[Classify(name)]; found {
+		return rate * classRate
+	}
+	return rate
+}
+
+func (s *Sampler) Count(name string, value int64, tags []string, rate float64) error {
+	return s.ClientInterface.Count(name, value, tags, s.rate(name, rate))
+}
+
+func (s *Sampler) Histogram(name string, value float64, tags []string, rate float64) error {
+	return s.ClientInterface.Histogram(name, value, tags, s.rate(name, rate))
+}
+
+func (s *Sampler) Distribution(name string, value float64, tags []string, rate float64) error {
+	return s.ClientInterface.Distribution(name, value, tags, s.rate(name, rate))
+}
+
+func (s *Sampler) Decr(name string, tags []string, rate float64) error {
+	return s.ClientInterface.Decr(name, tags, s.rate(name, rate))
+}
+
+func (s *Sampler) Incr(name string, tags []string, rate float64) error {
+	return s.ClientInterface.Incr(name, tags, s.rate(name, rate))
+}
+
+func (s *Sampler) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	return s.ClientInterface.Timing(name, value, tags, s.rate(name, rate))
+}
+
+func (s *Sampler) TimeInMilliseconds(name string, value float64, tags []string, rate float64) error {
+	return s.ClientInterface.TimeInMilliseconds(name, value, tags, s.rate(name, rate))
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rateRecorder records the rate the metrics are sent with
+type rateRecorder struct {
+	statsd.NoOpClient
+	rates map[string]float64
+}
+
+func (r *rateRecorder) Incr(name string, _ []string, rate float64) error {
+	r.rates[name] = rate
+	return nil
+}
+
+func (r *rateRecorder) Distribution(name string, _ float64, _ []string, rate float64) error {
+	r.rates[name] = rate
+	return nil
+}
+
+func (r *rateRecorder) Timing(name string, _ time.Duration, _ []string, rate float64) error {
+	r.rates[name] = rate
+	return nil
+}
+
+func (r *rateRecorder) Gauge(name string, _ float64, _ []string, rate float64) error {
+	r.rates[name] = rate
+	return nil
+}
+
+func TestClassify(t *testing.T) {
+	for name, class := range map[string]string{
+		"letsblockit.list_download":        DownloadClass,
+		"letsblockit.hot_list_download":    DownloadClass,
+		"letsblockit.list_format_download": DownloadClass,
+		"letsblockit.list_render_duration": DownloadClass,
+		"letsblockit.list_render_timeout":  ErrorClass,
+		"letsblockit.config_reload_error":  ErrorClass,
+		"letsblockit.webhook_dropped":      ErrorClass,
+		"letsblockit.panic":                ErrorClass,
+		"letsblockit.request_count":        RequestClass,
+		"letsblockit.request_duration":     RequestClass,
+		"letsblockit.user_count":           OtherClass,
+		"letsblockit.pg_request_duration":  OtherClass,
+	} {
+		assert.Equal(t, class, Classify(name), name)
+	}
+}
+
+func TestParseSampleRates(t *testing.T) {
+	rates, err := ParseSampleRates([]string{"download=0.1", "request=0.5", "other=1"})
+	require.NoError(t, err)
+	assert.Equal(t, SampleRates{DownloadClass: 0.1, RequestClass: 0.5, OtherClass: 1}, rates)
+
+	rates, err = ParseSampleRates(nil)
+	require.NoError(t, err)
+	assert.Empty(t, rates)
+
+	for _, invalid := range []string{"error=0.5", "unknown=0.5", "download", "download=abc", "download=0", "download=1.5"} {
+		_, err = ParseSampleRates([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	recorder := &rateRecorder{rates: make(map[string]float64)}
+	sampler := NewSampler(recorder, SampleRates{DownloadClass: 0.1, RequestClass: 0.5})
+
+	require.NoError(t, sampler.Incr("letsblockit.list_download", nil, 1))
+	require.NoError(t, sampler.Distribution("letsblockit.list_render_duration", 12, nil, 0.5))
+	require.NoError(t, sampler.Timing("letsblockit.request_duration", time.Second, nil, 1))
+	require.NoError(t, sampler.Incr("letsblockit.list_render_timeout", nil, 1))
+	require.NoError(t, sampler.Incr("letsblockit.user_data_exported", nil, 1))
+	require.NoError(t, sampler.Gauge("letsblockit.list_download_gauge", 3, nil, 1))
+	assert.Equal(t, map[string]float64{
+		"letsblockit.list_download":        0.1,
+		"letsblockit.list_render_duration": 0.05,
+		"letsblockit.request_duration":     0.5,
+		"letsblockit.list_render_timeout":  1,
+		"letsblockit.user_data_exported":   1,
+		"letsblockit.list_download_gauge":  1, // Gauges are not sampled
+	}, recorder.rates)
+
+	sampler.SetRates(SampleRates{OtherClass: 0.2})
+	require.NoError(t, sampler.Incr("letsblockit.list_download", nil, 1))
+	require.NoError(t, sampler.Incr("letsblockit.user_data_exported", nil, 1))
+	assert.Equal(t, 1.0, recorder.rates["letsblockit.list_download"])
+	assert.Equal(t, 0.2, recorder.rates["letsblockit.user_data_exported"])
+}
+
+func TestSampler_PrometheusKeepsAllValues(t *testing.T) {
+	registry := NewRegistry()
+	client := New(NewSampler(&statsd.NoOpClient{}, SampleRates{DownloadClass: 0.1}), registry)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, client.Incr("letsblockit.list_download", nil, 1))
+	}
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(), "letsblockit_list_download_total 5\n")
+}
@@ -0,0 +1,79 @@
+package news
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"time"
+
+	"github.com/letsblockit/letsblockit/data"
+	"github.com/russross/blackfriday/v2"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	announcementSuffix    = ".md"
+	announcementSeparator = []byte("\n---")
+	newLine               = []byte("\n")
+)
+
+// Announcement is a news entry written by the maintainers, loaded from the data/news folder
+type Announcement struct {
+	Name        string    `yaml:"-"`
+	Title       string    `yaml:"title"`
+	PublishedAt time.Time `yaml:"date"`
+	Description string    `yaml:"-"`
+}
+
+func (a Announcement) Date() string {
+	return a.PublishedAt.Format("2006-01-02")
+}
+
+// LoadAnnouncements parses announcement files, and returns them sorted from newest to oldest.
+// Files hold a yaml header with the title and date, followed by a separator and a markdown body.
+func LoadAnnouncements(files fs.FS, officialInstance bool, tp templateProvider) ([]*Announcement, error) {
+	var announcements []*Announcement
+	renderer := initRenderer(officialInstance, tp)
+	err := data.Walk(files, announcementSuffix, func(name string, file io.Reader) error {
+		a, err := parseAnnouncement(name, file, renderer)
+		if err != nil {
+			return err
+		}
+		announcements = append(announcements, a)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot load announcements: %w", err)
+	}
+	sort.SliceStable(announcements, func(i, j int) bool {
+		return announcements[i].PublishedAt.After(announcements[j].PublishedAt)
+	})
+	return announcements, nil
+}
+
+func parseAnnouncement(name string, reader io.Reader, renderer blackfriday.Option) (*Announcement, error) {
+	input, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	pos := bytes.Index(input, announcementSeparator)
+	if pos < 0 {
+		return nil, errors.New("separator not found")
+	}
+
+	a := &Announcement{Name: name}
+	if err = yaml.Unmarshal(input[:pos+1], a); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	if a.Title == "" || a.PublishedAt.IsZero() {
+		return nil, errors.New("missing title or date")
+	}
+
+	pos += len(announcementSeparator)
+	pos += bytes.Index(input[pos:], newLine)
+	a.Description = string(blackfriday.Run(input[pos:], renderer))
+	return a, nil
+}
@@ -21,6 +21,7 @@ type Context struct {
 	Page             *page
 	Sidebar          *page
 	NoBoost          bool
+	StatusCode       int // Defaults to 200
 	HotReload        bool
 	OfficialInstance bool
 	GreyLogo         bool
@@ -30,12 +31,16 @@ type Context struct {
 	NavigationLinks interface{}
 	Title           string
 
-	UserID         string
-	UserLoggedIn   bool
-	UserHasAccount bool
-	HasNews        bool
-	Preferences    *db.UserPreference
-	CSRFToken      string
+	UserID             string
+	UserLoggedIn       bool
+	UserHasAccount     bool
+	UserIsAdmin        bool
+	UserIsEphemeral    bool
+	UserIsImpersonated bool
+	HasNews            bool
+	UnreadNews         int
+	Preferences        *db.UserPreference
+	CSRFToken          string
 
 	Data ContextData
 }
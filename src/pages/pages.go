@@ -153,7 +153,11 @@ func (p *Pages) Render(c echo.Context, name string, data *Context) error {
 	if err := tpl.Execute(buf, data); err != nil {
 		return err
 	}
-	return c.HTMLBlob(http.StatusOK, buf.Bytes())
+	code := http.StatusOK
+	if data.StatusCode != 0 {
+		code = data.StatusCode
+	}
+	return c.HTMLBlob(code, buf.Bytes())
 }
 
 func (p *Pages) RenderWithSidebar(c echo.Context, name, sidebar string, data *Context) error {
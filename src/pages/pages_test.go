@@ -1,14 +1,36 @@
 package pages
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/data"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+var postFormPattern = regexp.MustCompile(`(?is)<form[^>]*method="post".*?</form>`)
+
+// Check that all POST forms submit the CSRF token, as all form handlers require it
+func TestFormsHaveCSRFToken(t *testing.T) {
+	require.NoError(t, data.Walk(data.Pages, ".hbs", func(name string, file io.Reader) error {
+		contents, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		for _, form := range postFormPattern.FindAll(contents, -1) {
+			assert.True(t, strings.Contains(string(form), "{{{csrf @root}}}"),
+				"form without csrf token in %s: %s", name, form)
+		}
+		return nil
+	}))
+}
+
 func nilHandler(_ echo.Context) error { return nil }
 
 func TestRedirect(t *testing.T) {
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+const banRefreshInterval = time.Minute
+
+// banInfo holds the ban information displayed in the admin page
+type banInfo struct {
+	UserID    string
+	Reason    string
+	CreatedAt string
+	ExpiresAt string
+}
+
+// requireAdmin returns a 404 error to non-admin users, to not disclose the existence of admin pages
+func requireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !auth.IsAdmin(c) {
+			return echo.ErrNotFound
+		}
+		return next(c)
+	}
+}
+
+func (s *Server) adminBans(c echo.Context) error {
+	stored, err := s.store.GetActiveBans(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	bans := make([]banInfo, 0, len(stored))
+	for _, b := range stored {
+		info := banInfo{
+			UserID:    b.UserID,
+			Reason:    b.Reason,
+			CreatedAt: b.CreatedAt.Format(time.RFC3339),
+		}
+		if b.ExpiresAt.Valid {
+			info.ExpiresAt = b.ExpiresAt.Time.Format(time.RFC3339)
+		}
+		bans = append(bans, info)
+	}
+
+	hc := s.buildPageContext(c, "Banned users")
+	hc.NoBoost = true
+	hc.Add("bans", bans)
+	return s.pages.Render(c, "admin-bans", hc)
+}
+
+// adminAddBan bans a user, for a number of days if the duration is set, else permanently
+func (s *Server) adminAddBan(c echo.Context) error {
+	formParams, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+	params := db.AddUserBanParams{
+		UserID: strings.TrimSpace(formParams.Get("user_id")),
+		Reason: strings.TrimSpace(formParams.Get("reason")),
+	}
+	if params.UserID == "" || params.Reason == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid arguments")
+	}
+	if days := formParams.Get("days"); days != "" {
+		count, err := strconv.Atoi(days)
+		if err != nil || count <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid ban duration")
+		}
+		params.ExpiresAt = sql.NullTime{
+			Time:  s.now().AddDate(0, 0, count),
+			Valid: true,
+		}
+	}
+	if err = s.bans.Ban(c.Request().Context(), params); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("admin-bans"))
+}
+
+func (s *Server) adminLiftBan(c echo.Context) error {
+	formParams, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+	params := db.LiftUserBanParams{UserID: formParams.Get("user_id")}
+	if params.UserID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid arguments")
+	}
+	if reason := strings.TrimSpace(formParams.Get("lift_reason")); reason != "" {
+		params.LiftReason = sql.NullString{String: reason, Valid: true}
+	}
+	if err = s.bans.Lift(c.Request().Context(), params); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("admin-bans"))
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/letsblockit/letsblockit/src/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bannedUser = "banned-user"
+
+func (s *ServerTestSuite) setUserAdmin() {
+	s.T().Helper()
+	s.server.options.AdminUsers = []string{s.user}
+	s.server.echo = echo.New()
+	s.server.setupRouter()
+	var err error
+	s.server.bans, err = users.LoadUserBans(s.store)
+	require.NoError(s.T(), err)
+}
+
+func (s *ServerTestSuite) TestAdminBans_NotAdmin() {
+	req := httptest.NewRequest(http.MethodGet, "/admin/bans", nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestAdminBans_List() {
+	s.setUserAdmin()
+	require.NoError(s.T(), s.store.AddUserBan(context.Background(), db.AddUserBanParams{
+		UserID: bannedUser,
+		Reason: "spamming",
+	}))
+	bans, err := s.store.GetActiveBans(context.Background())
+	require.NoError(s.T(), err)
+	require.Len(s.T(), bans, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/bans", nil)
+	s.expectRender("admin-bans", pages.ContextData{
+		"bans": []banInfo{{
+			UserID:    bannedUser,
+			Reason:    "spamming",
+			CreatedAt: bans[0].CreatedAt.Format(time.RFC3339),
+		}},
+	})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestAdminBans_BanAndLift() {
+	s.setUserAdmin()
+	token, err := s.store.CreateListForUser(context.Background(), bannedUser)
+	require.NoError(s.T(), err)
+
+	f := make(url.Values)
+	f.Add("user_id", bannedUser)
+	f.Add("reason", "secret reason")
+	f.Add("days", "3")
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/admin/bans", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/admin/bans")
+	s.runRequest(req, assertOk)
+
+	// The list is emptied, and the reason is not disclosed
+	req = httptest.NewRequest(http.MethodGet, "/list/"+token.String(), nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.Equal(t, defaultBannedListBody, rec.Body.String())
+		assert.NotContains(t, rec.Body.String(), "secret reason")
+	})
+
+	f = make(url.Values)
+	f.Add("user_id", bannedUser)
+	f.Add("lift_reason", "appeal accepted")
+	f.Add(csrfLookup, s.csrf)
+	req = httptest.NewRequest(http.MethodPost, "/admin/bans/lift", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/admin/bans")
+	s.runRequest(req, assertOk)
+
+	req = httptest.NewRequest(http.MethodGet, "/list/"+token.String(), nil)
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestAdminBans_AddNotAdmin() {
+	f := make(url.Values)
+	f.Add("user_id", bannedUser)
+	f.Add("reason", "testing")
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/admin/bans", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	bans, err := s.store.GetActiveBans(context.Background())
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), bans)
+}
+
+func (s *ServerTestSuite) TestAdminRoutes_Anonymous() {
+	s.user = ""
+	for _, path := range []string{"/admin/bans", "/admin/unknown"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusNotFound, rec.Code, path)
+		})
+	}
+}
+
+func (s *ServerTestSuite) TestAdminRoutes_Admin() {
+	s.setUserAdmin()
+	req := httptest.NewRequest(http.MethodGet, "/admin/bans", nil)
+	s.expectP.Render(gomock.Any(), "admin-bans", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.True(s.T(), hc.UserIsAdmin)
+			return nil
+		})
+	s.runRequest(req, assertOk)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/unknown", nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
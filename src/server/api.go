@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+)
+
+// apiInstanceRequest is the body accepted when creating or updating an instance
+type apiInstanceRequest struct {
+	Params   map[string]interface{} `json:"params"`
+	TestMode bool                   `json:"test_mode"`
+}
+
+func (s *Server) apiListInstances(c echo.Context) error {
+	stored, err := s.store.GetInstancesForUser(c.Request().Context(), getApiUser(c))
+	if err != nil {
+		return err
+	}
+	rows := make([]db.GetInstancesForListRow, 0, len(stored))
+	for _, r := range stored {
+		rows = append(rows, db.GetInstancesForListRow(r))
+	}
+	list, err := convertFilterList(filters.DefaultListTitle, rows)
+	if err != nil {
+		return err
+	}
+	if list.Instances == nil {
+		list.Instances = []*filters.Instance{}
+	}
+	return c.JSON(http.StatusOK, list.Instances)
+}
+
+func (s *Server) apiGetInstance(c echo.Context) error {
+	filter, err := s.config().filters.Get(c.Param("name"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "unknown template")
+	}
+	stored, err := s.store.GetInstance(c.Request().Context(), db.GetInstanceParams{
+		UserID:       getApiUser(c),
+		TemplateName: filter.Name,
+	})
+	switch {
+	case err == db.NotFound:
+		return echo.NewHTTPError(http.StatusNotFound, "template not in list")
+	case err != nil:
+		return err
+	}
+	instance := &filters.Instance{
+		Template: filter.Name,
+		Params:   make(map[string]interface{}),
+		TestMode: stored.TestMode,
+	}
+	if err = stored.Params.AssignTo(&instance.Params); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, instance)
+}
+
+// apiPutInstance creates or replaces the instance of a template, after validating its parameters
+func (s *Server) apiPutInstance(c echo.Context) error {
+	filter, err := s.config().filters.Get(c.Param("name"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "unknown template")
+	}
+	var request apiInstanceRequest
+	if err = c.Bind(&request); err != nil {
+		return err
+	}
+	if errs := filter.ValidateParams(request.Params); len(errs) > 0 {
+		messages := make([]string, 0, len(errs))
+		for _, e := range errs {
+			messages = append(messages, e.Error())
+		}
+		return &apiError{
+			Status:  http.StatusBadRequest,
+			Message: "invalid parameters: " + strings.Join(messages, "; "),
+			Fields:  paramFieldErrors(errs),
+		}
+	}
+
+	// Missing parameters are set to their default value
+	instance := &filters.Instance{
+		Template: filter.Name,
+		Params:   filter.DefaultParams(),
+		TestMode: request.TestMode,
+	}
+	for name, value := range request.Params {
+		instance.Params[name] = value
+	}
+	if err = s.upsertFilterParams(c, getApiUser(c), instance); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, instance)
+}
+
+func (s *Server) apiDeleteInstance(c echo.Context) error {
+	filter, err := s.config().filters.Get(c.Param("name"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "unknown template")
+	}
+	if err = s.store.DeleteInstance(c.Request().Context(), db.DeleteInstanceParams{
+		UserID:       getApiUser(c),
+		TemplateName: filter.Name,
+	}); err != nil {
+		return err
+	}
+	s.notifyListChange(getApiUser(c), webhookInstanceDeleted, filter.Name)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// apiExportList returns the list definition as YAML, in the same format as exportList
+func (s *Server) apiExportList(c echo.Context) error {
+	var storedList db.GetListForUserRow
+	var storedInstances []db.GetInstancesForListRow
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		var e error
+		storedList, e = q.GetListForUser(ctx, getApiUser(c))
+		switch {
+		case e == db.NotFound:
+			return echo.NewHTTPError(http.StatusNotFound, "no list for this user")
+		case e != nil:
+			return e
+		}
+		storedInstances, e = q.GetInstancesForList(ctx, storedList.ID)
+		return e
+	}); err != nil {
+		return err
+	}
+	return s.writeListExport(c, storedList.Token.String(), listTitle(storedList.Title), storedInstances)
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *ServerTestSuite) runApiRequest(req *http.Request, token string, checks func(*testing.T, *httptest.ResponseRecorder)) {
+	s.T().Helper()
+	s.csrf, s.user = "", "" // Authentication should only rely on the token
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	s.runRequest(req, checks)
+}
+
+func (s *ServerTestSuite) TestApi_MissingToken() {
+	req := httptest.NewRequest(http.MethodGet, "/api/instances", nil)
+	s.runApiRequest(req, "", func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "Bearer", rec.Header().Get(echo.HeaderWWWAuthenticate))
+	})
+}
+
+func (s *ServerTestSuite) TestApi_InvalidToken() {
+	req := httptest.NewRequest(http.MethodGet, "/api/instances", nil)
+	s.runApiRequest(req, apiTokenPrefix+"invalid", func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestApi_MissingScope() {
+	token := s.createApiToken(scopeRead)
+	req := httptest.NewRequest(http.MethodDelete, "/api/instances/filter1", nil)
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestApi_BannedUser() {
+	token := s.createApiToken(scopeRead)
+	require.NoError(s.T(), s.store.AddUserBan(context.Background(), db.AddUserBanParams{
+		UserID: s.user,
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/instances", nil)
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestApi_MarksTokenUsed() {
+	token := s.createApiToken(scopeRead)
+	user := s.user
+	req := httptest.NewRequest(http.MethodGet, "/api/instances", nil)
+	s.runApiRequest(req, token, assertOk)
+
+	tokens, err := s.store.GetApiTokensForUser(context.Background(), user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), tokens, 1)
+	require.True(s.T(), tokens[0].LastUsedAt.Valid)
+}
+
+func (s *ServerTestSuite) TestApi_ListInstances() {
+	token := s.createApiToken(scopeRead)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   filter2Custom,
+	}))
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1", TestMode: true}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/instances", nil)
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		var instances []*filters.Instance
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &instances))
+		s.requireJSONEq([]*filters.Instance{{
+			Template: "filter1",
+			TestMode: true,
+		}, {
+			Template: "filter2",
+			Params:   filter2Custom,
+		}}, instances)
+	})
+}
+
+func (s *ServerTestSuite) TestApi_GetInstance() {
+	token := s.createApiToken(scopeRead)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   filter2Custom,
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/instances/filter2", nil)
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		s.requireJSONEq(&filters.Instance{
+			Template: "filter2",
+			Params:   filter2Custom,
+		}, json.RawMessage(rec.Body.Bytes()))
+	})
+
+	req = httptest.NewRequest(http.MethodGet, "/api/instances/filter1", nil)
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestApi_PutInstance() {
+	token := s.createApiToken(scopeRead, scopeWrite)
+	user := s.user
+
+	req := httptest.NewRequest(http.MethodPut, "/api/instances/filter2",
+		strings.NewReader(`{"params": {"one": "blep"}, "test_mode": true}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	s.runApiRequest(req, token, assertOk)
+
+	stored, err := s.store.GetInstance(context.Background(), db.GetInstanceParams{
+		UserID:       user,
+		TemplateName: "filter2",
+	})
+	require.NoError(s.T(), err)
+	require.True(s.T(), stored.TestMode)
+	s.requireJSONEq(map[string]any{
+		"one":                    "blep",
+		"two":                    true,
+		"three":                  []string{"a", "b"},
+		"three---preset---dummy": false,
+	}, stored.Params)
+}
+
+func (s *ServerTestSuite) TestApi_PutInstanceInvalid() {
+	token := s.createApiToken(scopeWrite)
+	user := s.user
+
+	req := httptest.NewRequest(http.MethodPut, "/api/instances/filter2",
+		strings.NewReader(`{"params": {"one": true, "unknown": "value"}}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, problemMIME, rec.Header().Get(echo.HeaderContentType))
+		assert.Contains(t, rec.Body.String(), "parameter one: expected a string value")
+		assert.Contains(t, rec.Body.String(), "parameter unknown: unknown parameter")
+		var body problem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Contains(t, body.FieldErrors, "params.one")
+		assert.Contains(t, body.FieldErrors, "params.unknown")
+	})
+
+	s.user = user
+	s.requireInstanceCount("filter2", 0)
+}
+
+func (s *ServerTestSuite) TestApi_DeleteInstance() {
+	token := s.createApiToken(scopeWrite)
+	user := s.user
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/instances/filter1", nil)
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	s.user = user
+	s.requireInstanceCount("filter1", 0)
+}
+
+func (s *ServerTestSuite) TestApi_Export() {
+	token := s.createApiToken(scopeRead)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+	list, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/export", nil)
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.Equal(t, `# letsblock.it filter list export
+#
+# List token: `+list.Token.String()+`
+# Export date: 2020-06-02
+#
+# You can edit this file and render it locally, check out instructions at:
+# https://github.com/letsblockit/letsblockit/tree/main/cmd/render/README.md
+
+title: My filters
+instances:
+    - template: filter1
+`, rec.Body.String())
+	})
+}
@@ -0,0 +1,328 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// apiV1Filter is the representation of a filter instance in the v1 API
+type apiV1Filter struct {
+	Template  string                 `json:"template"`
+	Title     string                 `json:"title"`
+	Params    map[string]interface{} `json:"params"`
+	TestMode  bool                   `json:"test_mode"`
+	Starred   bool                   `json:"starred"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt *time.Time             `json:"updated_at,omitempty"`
+}
+
+// apiV1FilterList is returned when listing the filters of a user, with the starred templates
+type apiV1FilterList struct {
+	Filters   []*apiV1Filter `json:"filters"`
+	Favorites []string       `json:"favorites,omitempty"`
+}
+
+// apiV1CreateRequest is the body accepted when creating an instance
+type apiV1CreateRequest struct {
+	Template string                 `json:"template"`
+	Params   map[string]interface{} `json:"params"`
+	TestMode bool                   `json:"test_mode"`
+}
+
+// apiError is returned by the v1 API handlers, and rendered as an error envelope
+type apiError struct {
+	Status    int               `json:"status"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+// apiV1ErrorBody is the error envelope of the v1 API
+type apiV1ErrorBody struct {
+	Error *apiError `json:"error"`
+}
+
+func newApiError(status int, message string) *apiError {
+	return &apiError{Status: status, Message: message}
+}
+
+// apiV1Errors renders all errors as a JSON object: {"error": {"status": 404, "message": "...", "request_id": "..."}}
+func apiV1Errors(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := next(c)
+		if err == nil || c.Response().Committed {
+			return err
+		}
+		var body *apiError
+		var httpErr *echo.HTTPError
+		switch {
+		case errors.As(err, &body):
+		case errors.As(err, &httpErr):
+			body = newApiError(httpErr.Code, http.StatusText(httpErr.Code))
+			if message, ok := httpErr.Message.(string); ok {
+				body.Message = message
+			}
+		default:
+			c.Logger().Error(err)
+			body = newApiError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+		body.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+		return c.JSON(body.Status, &apiV1ErrorBody{Error: body})
+	}
+}
+
+// apiV1Negotiate only serves clients accepting JSON, and only accepts JSON request bodies.
+// Requiring a JSON body also protects session-authenticated requests against CSRF,
+// as browsers cannot send them cross-origin without a CORS preflight.
+func apiV1Negotiate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !acceptsJSON(c.Request().Header.Get(echo.HeaderAccept)) {
+			return newApiError(http.StatusNotAcceptable, "this API only serves application/json")
+		}
+		if m := c.Request().Method; m == http.MethodPost || m == http.MethodPut {
+			mediaType, _, err := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+			if err != nil || mediaType != echo.MIMEApplicationJSON {
+				return newApiError(http.StatusUnsupportedMediaType, "request bodies must be application/json")
+			}
+		}
+		return next(c)
+	}
+}
+
+func acceptsJSON(header string) bool {
+	if header == "" {
+		return true
+	}
+	for _, part := range strings.Split(header, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case echo.MIMEApplicationJSON, "application/*", "*/*":
+			return true
+		}
+	}
+	return false
+}
+
+// apiV1Auth authenticates requests with an API token if present, or falls back to the browser session
+func (s *Server) apiV1Auth(sessionAuth echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withToken := s.bearerAuth(next)
+		withSession := sessionAuth(func(c echo.Context) error {
+			user, session := auth.GetUserId(c), auth.GetSessionId(c)
+			switch {
+			case user == "":
+				return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+			case s.bans.IsBanned(user):
+				return echo.ErrForbidden
+			}
+			if session != "" {
+				revoked, err := s.sessions.IsRevoked(c.Request().Context(), user, session, c.Request().UserAgent())
+				if err != nil {
+					return err
+				}
+				if revoked {
+					s.securityLog.log(c, eventRevokedSession)
+					return echo.NewHTTPError(http.StatusUnauthorized, "this session has been revoked")
+				}
+			}
+			c.Set(apiUserContextKey, user)
+			return next(c)
+		})
+		return func(c echo.Context) error {
+			if c.Request().Header.Get(echo.HeaderAuthorization) != "" {
+				return withToken(c)
+			}
+			return withSession(c)
+		}
+	}
+}
+
+func (s *Server) apiV1ListFilters(c echo.Context) error {
+	stored, err := s.store.GetInstanceHistoryForUser(c.Request().Context(), getApiUser(c))
+	if err != nil {
+		return err
+	}
+	favorites, err := getFavorites(c.Request().Context(), s.store, getApiUser(c))
+	if err != nil {
+		return err
+	}
+	out := make([]*apiV1Filter, 0, len(stored))
+	for _, i := range stored {
+		filter, err := s.buildApiV1Filter(i.TemplateName, db.GetInstanceDetailsRow{
+			Params:    i.Params,
+			TestMode:  i.TestMode,
+			CreatedAt: i.CreatedAt,
+			UpdatedAt: i.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		filter.Starred = favorites[i.TemplateName]
+		out = append(out, filter)
+	}
+	list := &apiV1FilterList{Filters: out}
+	for name := range favorites {
+		list.Favorites = append(list.Favorites, name)
+	}
+	sort.Strings(list.Favorites)
+	return c.JSON(http.StatusOK, list)
+}
+
+func (s *Server) apiV1GetFilter(c echo.Context) error {
+	filter, err := s.getApiV1Filter(c.Request().Context(), s.store, getApiUser(c), c.Param("name"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, filter)
+}
+
+// apiV1CreateFilter adds a filter to the user's list, missing parameters are set to their default value
+func (s *Server) apiV1CreateFilter(c echo.Context) error {
+	var request apiV1CreateRequest
+	if err := c.Bind(&request); err != nil {
+		return newApiError(http.StatusBadRequest, "invalid JSON body")
+	}
+	template, err := s.config().filters.Get(request.Template)
+	if err != nil {
+		return &apiError{
+			Status:  http.StatusUnprocessableEntity,
+			Message: "unknown template",
+			Fields:  map[string]string{"template": "unknown template"},
+		}
+	}
+	user := getApiUser(c)
+	count, err := s.store.CountInstances(c.Request().Context(), db.CountInstancesParams{
+		UserID:       user,
+		TemplateName: template.Name,
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return newApiError(http.StatusConflict, "this filter is already in your list, use PUT to update it")
+	}
+	return s.saveApiV1Filter(c, http.StatusCreated, user, template, request.Params, request.TestMode)
+}
+
+// apiV1UpdateFilter replaces the parameters of a filter, missing parameters are set to their default value
+func (s *Server) apiV1UpdateFilter(c echo.Context) error {
+	user := getApiUser(c)
+	if _, err := s.getApiV1Filter(c.Request().Context(), s.store, user, c.Param("name")); err != nil {
+		return err
+	}
+	var request apiInstanceRequest
+	if err := c.Bind(&request); err != nil {
+		return newApiError(http.StatusBadRequest, "invalid JSON body")
+	}
+	template, _ := s.config().filters.Get(c.Param("name"))
+	return s.saveApiV1Filter(c, http.StatusOK, user, template, request.Params, request.TestMode)
+}
+
+func (s *Server) apiV1DeleteFilter(c echo.Context) error {
+	user := getApiUser(c)
+	if _, err := s.getApiV1Filter(c.Request().Context(), s.store, user, c.Param("name")); err != nil {
+		return err
+	}
+	if err := s.store.DeleteInstance(c.Request().Context(), db.DeleteInstanceParams{
+		UserID:       user,
+		TemplateName: c.Param("name"),
+	}); err != nil {
+		return err
+	}
+	s.notifyListChange(user, webhookInstanceDeleted, c.Param("name"))
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) saveApiV1Filter(c echo.Context, status int, user string, template *filters.Template, params map[string]interface{}, testMode bool) error {
+	if errs := template.ValidateParams(params); len(errs) > 0 {
+		return &apiError{
+			Status:  http.StatusUnprocessableEntity,
+			Message: "invalid parameters",
+			Fields:  paramFieldErrors(errs),
+		}
+	}
+
+	instance := &filters.Instance{
+		Template: template.Name,
+		Params:   template.DefaultParams(),
+		TestMode: testMode,
+	}
+	for name, value := range params {
+		instance.Params[name] = value
+	}
+	if err := s.upsertFilterParams(c, user, instance); err != nil {
+		return err
+	}
+	filter, err := s.getApiV1Filter(c.Request().Context(), s.store, user, template.Name)
+	if err != nil {
+		return err
+	}
+	return c.JSON(status, filter)
+}
+
+// getApiV1Filter returns a filter of the user's list, or a 404 error if it is not found
+func (s *Server) getApiV1Filter(ctx context.Context, q db.Querier, user, name string) (*apiV1Filter, error) {
+	if _, err := s.config().filters.Get(name); err != nil {
+		return nil, newApiError(http.StatusNotFound, "unknown template")
+	}
+	stored, err := q.GetInstanceDetails(ctx, db.GetInstanceDetailsParams{
+		UserID:       user,
+		TemplateName: name,
+	})
+	switch {
+	case err == db.NotFound:
+		return nil, newApiError(http.StatusNotFound, "this filter is not in your list")
+	case err != nil:
+		return nil, err
+	}
+	filter, err := s.buildApiV1Filter(name, stored)
+	if err != nil {
+		return nil, err
+	}
+	favorites, err := getFavorites(ctx, q, user)
+	if err != nil {
+		return nil, err
+	}
+	filter.Starred = favorites[name]
+	return filter, nil
+}
+
+func (s *Server) buildApiV1Filter(name string, stored db.GetInstanceDetailsRow) (*apiV1Filter, error) {
+	filter := &apiV1Filter{
+		Template:  name,
+		Title:     name,
+		Params:    make(map[string]interface{}),
+		TestMode:  stored.TestMode,
+		CreatedAt: stored.CreatedAt,
+	}
+	if template, err := s.config().filters.Get(name); err == nil {
+		filter.Title = template.Title
+	}
+	if err := stored.Params.AssignTo(&filter.Params); err != nil {
+		return nil, err
+	}
+	if filter.Params == nil {
+		filter.Params = make(map[string]interface{})
+	}
+	if stored.UpdatedAt.Valid {
+		filter.UpdatedAt = &stored.UpdatedAt.Time
+	}
+	return filter, nil
+}
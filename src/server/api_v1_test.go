@@ -0,0 +1,221 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newApiV1Request(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	return req
+}
+
+func decodeApiV1Filter(t *testing.T, rec *httptest.ResponseRecorder) *apiV1Filter {
+	var filter apiV1Filter
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&filter))
+	return &filter
+}
+
+func decodeApiV1Error(t *testing.T, rec *httptest.ResponseRecorder) *apiError {
+	var body map[string]*apiError
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	require.NotNil(t, body["error"])
+	return body["error"]
+}
+
+func (s *ServerTestSuite) TestApiV1_ListFilters() {
+	s.addInstance(s.user, "filter2", map[string]interface{}{"one": "1"})
+	s.addInstance(s.user, "filter1", nil)
+
+	req := newApiV1Request(http.MethodGet, "/api/v1/filters", "")
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		var body map[string][]*apiV1Filter
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		if assert.Len(t, body["filters"], 2) {
+			assert.Equal(t, "filter2", body["filters"][0].Template)
+			assert.Equal(t, "Second filter", body["filters"][0].Title)
+			assert.Equal(t, map[string]interface{}{"one": "1"}, body["filters"][0].Params)
+			assert.False(t, body["filters"][0].CreatedAt.IsZero())
+			assert.Equal(t, "filter1", body["filters"][1].Template)
+			assert.Empty(t, body["filters"][1].Params)
+		}
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_ListFiltersWithToken() {
+	s.addInstance(s.user, "filter1", nil)
+	token := s.createApiToken(scopeRead)
+	req := newApiV1Request(http.MethodGet, "/api/v1/filters", "")
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.Contains(t, rec.Body.String(), `"template":"filter1"`)
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_GetFilter() {
+	s.addInstance(s.user, "filter2", map[string]interface{}{"one": "1"})
+	req := newApiV1Request(http.MethodGet, "/api/v1/filters/filter2", "")
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		filter := decodeApiV1Filter(t, rec)
+		assert.Equal(t, "filter2", filter.Template)
+		assert.Equal(t, map[string]interface{}{"one": "1"}, filter.Params)
+		assert.False(t, filter.TestMode)
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_GetFilterNotInList() {
+	req := newApiV1Request(http.MethodGet, "/api/v1/filters/filter1", "")
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+		body := decodeApiV1Error(t, rec)
+		assert.Equal(t, http.StatusNotFound, body.Status)
+		assert.Equal(t, "this filter is not in your list", body.Message)
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_CreateFilter() {
+	req := newApiV1Request(http.MethodPost, "/api/v1/filters",
+		`{"template": "filter2", "params": {"two": false}, "test_mode": true}`)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body)
+		filter := decodeApiV1Filter(t, rec)
+		assert.Equal(t, "filter2", filter.Template)
+		assert.True(t, filter.TestMode)
+		assert.Equal(t, false, filter.Params["two"])
+		assert.Equal(t, "default", filter.Params["one"]) // Default value
+	})
+	s.requireInstanceCount("filter2", 1)
+}
+
+func (s *ServerTestSuite) TestApiV1_CreateFilterConflict() {
+	s.addInstance(s.user, "filter2", nil)
+	req := newApiV1Request(http.MethodPost, "/api/v1/filters", `{"template": "filter2"}`)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, http.StatusConflict, decodeApiV1Error(t, rec).Status)
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_CreateFilterInvalidParams() {
+	req := newApiV1Request(http.MethodPost, "/api/v1/filters",
+		`{"template": "filter2", "params": {"two": "yes", "unknown": 1}}`)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		body := decodeApiV1Error(t, rec)
+		assert.Equal(t, "invalid parameters", body.Message)
+		assert.Equal(t, map[string]string{
+			"params.two":     "expected a " + string(filters.BooleanParam) + " value, got string",
+			"params.unknown": "unknown parameter",
+		}, body.Fields)
+	})
+	s.requireInstanceCount("filter2", 0)
+}
+
+func (s *ServerTestSuite) TestApiV1_CreateFilterUnknownTemplate() {
+	req := newApiV1Request(http.MethodPost, "/api/v1/filters", `{"template": "unknown"}`)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, map[string]string{"template": "unknown template"}, decodeApiV1Error(t, rec).Fields)
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_UpdateFilter() {
+	s.addInstance(s.user, "filter2", map[string]interface{}{"one": "custom"})
+	req := newApiV1Request(http.MethodPut, "/api/v1/filters/filter2", `{"params": {"two": false}}`)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		filter := decodeApiV1Filter(t, rec)
+		assert.Equal(t, "default", filter.Params["one"]) // Reset to default value
+		assert.Equal(t, false, filter.Params["two"])
+		assert.NotNil(t, filter.UpdatedAt)
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_UpdateFilterNotInList() {
+	req := newApiV1Request(http.MethodPut, "/api/v1/filters/filter2", `{"params": {}}`)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	s.requireInstanceCount("filter2", 0)
+}
+
+func (s *ServerTestSuite) TestApiV1_DeleteFilter() {
+	s.addInstance(s.user, "filter1", nil)
+	req := newApiV1Request(http.MethodDelete, "/api/v1/filters/filter1", "")
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+	s.requireInstanceCount("filter1", 0)
+}
+
+func (s *ServerTestSuite) TestApiV1_DeleteFilterWithReadToken() {
+	s.addInstance(s.user, "filter1", nil)
+	token := s.createApiToken(scopeRead)
+	req := newApiV1Request(http.MethodDelete, "/api/v1/filters/filter1", "")
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "token is missing the write scope", decodeApiV1Error(t, rec).Message)
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_Unauthenticated() {
+	s.user = ""
+	req := newApiV1Request(http.MethodGet, "/api/v1/filters", "")
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, http.StatusUnauthorized, decodeApiV1Error(t, rec).Status)
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_BannedUser() {
+	s.setUserBanned()
+	req := newApiV1Request(http.MethodGet, "/api/v1/filters", "")
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_NotAcceptable() {
+	req := newApiV1Request(http.MethodGet, "/api/v1/filters", "")
+	req.Header.Set(echo.HeaderAccept, echo.MIMETextHTML)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_FormBodyRejected() {
+	req := newApiV1Request(http.MethodPost, "/api/v1/filters", "template=filter1")
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	})
+	s.requireInstanceCount("filter1", 0)
+}
+
+func TestAcceptsJSON(t *testing.T) {
+	for header, expected := range map[string]bool{
+		"":                                true,
+		"*/*":                             true,
+		"application/json":                true,
+		"text/html, application/*;q=0.8":  true,
+		"text/html":                       false,
+		"text/html,application/xhtml+xml": false,
+	} {
+		assert.Equal(t, expected, acceptsJSON(header), header)
+	}
+}
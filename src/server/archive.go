@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/letsblockit/letsblockit/src/db"
+)
+
+// archivedSnapshotName names the snapshot holding the filters of an archived list
+const archivedSnapshotName = "archived"
+
+// ArchiveReport summarizes a run of ArchiveInactiveLists
+type ArchiveReport struct {
+	Users    []string // Users whose list is inactive
+	Archived int
+	Failed   []string // Users whose batch failed, they are archived by the next run
+}
+
+// ArchiveInactiveLists archives the lists not downloaded, and whose filters were not changed, since inactiveSince.
+// Their filters are stored in a snapshot then deleted, users can restore them from their snapshots page.
+// Lists are archived by batches of batchSize users, each batch in its own transaction.
+func ArchiveInactiveLists(ctx context.Context, store db.Store, inactiveSince time.Time, dryRun bool, batchSize int32) (*ArchiveReport, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	users, err := store.GetInactiveListUsers(ctx, inactiveSince)
+	if err != nil {
+		return nil, err
+	}
+	report := &ArchiveReport{Users: users}
+	if dryRun {
+		return report, nil
+	}
+
+	report.Archived, report.Failed, err = db.RunUserBatches(ctx, store, users, batchSize, archiveList)
+	if err != nil {
+		return report, fmt.Errorf("%d of %d lists could not be archived, last error: %w", len(report.Failed), len(users), err)
+	}
+	return report, nil
+}
+
+func archiveList(ctx context.Context, q db.Querier, user string) error {
+	if err := takeSnapshot(ctx, q, user, archivedSnapshotName, false); err != nil {
+		return err
+	}
+	return q.DeleteInstancesForUser(ctx, user)
+}
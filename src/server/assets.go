@@ -0,0 +1,116 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/vearutop/statigz"
+)
+
+const (
+	assetsRoot      = "assets"
+	assetHashLength = 10
+
+	// Hashed assets change name when their contents change, they can be cached forever.
+	// Un-hashed paths are kept for external links and cached pages, with a short caching.
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	assetCacheControl     = "public, max-age=300"
+)
+
+// hashedAssetPattern matches the hash inserted before the extension: /assets/dist/main.0123456789.css
+var hashedAssetPattern = regexp.MustCompile(`\.[0-9a-f]{10}(\.[^./]+)$`)
+
+// assetServer serves the embedded static files under their content-hashed names, computed at startup.
+// The files are served by statigz, that picks the precompressed variants if the client supports them.
+type assetServer struct {
+	files  http.Handler
+	hashed map[string]string // Un-hashed path to hashed path
+	paths  map[string]string // Hashed path to un-hashed path
+}
+
+func newAssetServer(files fs.ReadDirFS) (*assetServer, error) {
+	// Precompressed variants are hashed together, in lexical order, under the name they are served as
+	hashers := make(map[string]hash.Hash)
+	err := fs.WalkDir(files, assetsRoot, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		served := "/" + strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".br")
+		hasher, found := hashers[served]
+		if !found {
+			hasher = sha256.New()
+			hashers[served] = hasher
+		}
+		file, err := files.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(hasher, file)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a := &assetServer{
+		files:  statigz.FileServer(files),
+		hashed: make(map[string]string, len(hashers)),
+		paths:  make(map[string]string, len(hashers)),
+	}
+	for served, hasher := range hashers {
+		ext := path.Ext(served)
+		hashed := strings.TrimSuffix(served, ext) + "." + hex.EncodeToString(hasher.Sum(nil))[:assetHashLength] + ext
+		a.hashed[served] = hashed
+		a.paths[hashed] = served
+	}
+	return a, nil
+}
+
+// url returns the hashed path of an asset, or the given path if it is not a known asset
+func (a *assetServer) url(p string) string {
+	if hashed, found := a.hashed[p]; found {
+		return hashed
+	}
+	return p
+}
+
+func (a *assetServer) helpers() map[string]interface{} {
+	return map[string]interface{}{
+		"asset": a.url,
+	}
+}
+
+// outdated returns whether the path is a hashed name of a known asset, but not its current one
+func (a *assetServer) outdated(p string) bool {
+	loc := hashedAssetPattern.FindStringSubmatchIndex(p)
+	if loc == nil {
+		return false
+	}
+	_, known := a.hashed[p[:loc[0]]+p[loc[2]:loc[3]]]
+	return known
+}
+
+func (a *assetServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	served, found := a.paths[r.URL.Path]
+	switch {
+	case found:
+		w.Header().Set("Cache-Control", immutableCacheControl)
+		r = r.Clone(r.Context())
+		r.URL.Path, r.URL.RawPath = served, ""
+	case a.outdated(r.URL.Path):
+		// Do not serve the current contents under a previous name, as they would be cached forever
+		http.NotFound(w, r)
+		return
+	default:
+		w.Header().Set("Cache-Control", assetCacheControl)
+	}
+	a.files.ServeHTTP(w, r)
+}
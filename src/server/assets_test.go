@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/imantung/mario"
+	"github.com/letsblockit/letsblockit/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipped(t *testing.T, contents string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(contents))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func buildTestAssets(t *testing.T, css string) *assetServer {
+	assets, err := newAssetServer(fstest.MapFS{
+		"assets/dist/main.css.gz":    {Data: gzipped(t, css)},
+		"assets/images/logo.svg":     {Data: []byte("<svg></svg>")},
+		"assets/images/favicon.ico":  {Data: []byte("icon")},
+		"assets/images/pages/a.webm": {Data: []byte("video")},
+	})
+	require.NoError(t, err)
+	return assets
+}
+
+func TestAssetServer_URL(t *testing.T) {
+	assets := buildTestAssets(t, "body {}")
+	hashed := assets.url("/assets/dist/main.css")
+	assert.Regexp(t, `^/assets/dist/main\.[0-9a-f]{10}\.css$`, hashed)
+	assert.Regexp(t, `^/assets/images/pages/a\.[0-9a-f]{10}\.webm$`, assets.url("/assets/images/pages/a.webm"))
+	assert.Equal(t, "/assets/unknown.css", assets.url("/assets/unknown.css"))
+
+	// Stable across restarts, changed with the contents
+	assert.Equal(t, hashed, buildTestAssets(t, "body {}").url("/assets/dist/main.css"))
+	assert.NotEqual(t, hashed, buildTestAssets(t, "body { margin: 0 }").url("/assets/dist/main.css"))
+	assert.Equal(t, assets.url("/assets/images/logo.svg"),
+		buildTestAssets(t, "body { margin: 0 }").url("/assets/images/logo.svg"))
+}
+
+func TestAssetServer_Helper(t *testing.T) {
+	assets := buildTestAssets(t, "body {}")
+	tpl, err := mario.New().Parse(`<link href="{{asset "/assets/dist/main.css"}}">`)
+	require.NoError(t, err)
+	for name, helper := range assets.helpers() {
+		tpl.WithHelperFunc(name, helper)
+	}
+	var out bytes.Buffer
+	require.NoError(t, tpl.Execute(&out, nil))
+	assert.Equal(t, `<link href="`+assets.url("/assets/dist/main.css")+`">`, out.String())
+}
+
+func TestAssetServer_Serve(t *testing.T) {
+	assets := buildTestAssets(t, "body {}")
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		assets.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := serve(assets.url("/assets/dist/main.css"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "body {}", rec.Body.String())
+	assert.Equal(t, immutableCacheControl, rec.Header().Get("Cache-Control"))
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/css")
+
+	rec = serve("/assets/dist/main.css")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "body {}", rec.Body.String())
+	assert.Equal(t, assetCacheControl, rec.Header().Get("Cache-Control"))
+
+	rec = serve(assets.url("/assets/images/logo.svg"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "<svg></svg>", rec.Body.String())
+
+	for _, target := range []string{
+		"/assets/dist/main.0123456789.css",
+		buildTestAssets(t, "body { margin: 0 }").url("/assets/dist/main.css"),
+		"/assets/dist/other.0123456789.css",
+		"/assets/dist/other.css",
+	} {
+		rec = serve(target)
+		assert.Equal(t, http.StatusNotFound, rec.Code, target)
+		assert.NotEqual(t, immutableCacheControl, rec.Header().Get("Cache-Control"), target)
+	}
+}
+
+func TestAssetServer_EmbeddedFiles(t *testing.T) {
+	assets, err := newAssetServer(data.Assets)
+	require.NoError(t, err)
+	for _, p := range []string{"/assets/dist/main.css", "/assets/dist/main.js", "/assets/images/logo.svg"} {
+		rec := httptest.NewRecorder()
+		assets.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, assets.url(p), nil))
+		assert.Equal(t, http.StatusOK, rec.Code, p)
+		assert.NotEqual(t, p, assets.url(p))
+	}
+}
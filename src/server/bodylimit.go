@@ -0,0 +1,51 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// limitedBody records whether the handler tried to read past the body limit, as
+// handlers usually return a generic parsing error when the body is truncated
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// limitBody returns a 413 error for request bodies larger than limit bytes, without reading
+// them if their length is announced. A zero limit disables the check.
+func limitBody(limit int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if limit <= 0 {
+			return next
+		}
+		tooLarge := echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("request body is larger than the %dKB limit", limit>>10))
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.ContentLength > limit {
+				return tooLarge
+			}
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(c.Response(), req.Body, limit)}
+			req.Body = body
+			err := next(c)
+			if body.exceeded && !c.Response().Committed {
+				return tooLarge
+			}
+			return err
+		}
+	}
+}
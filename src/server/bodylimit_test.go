@@ -0,0 +1,98 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBodyLimitTestEcho() *echo.Echo {
+	e := echo.New()
+	e.Logger.SetOutput(io.Discard)
+	e.HTTPErrorHandler = (&Server{}).handleError
+	e.POST("/form", func(c echo.Context) error {
+		if _, err := c.FormParams(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid form")
+		}
+		return c.String(http.StatusOK, c.FormValue("rules"))
+	}, limitBody(1<<10))
+	v1 := e.Group("/api/v1", apiV1Errors, limitBody(1<<10))
+	v1.PUT("/filters/:name", func(c echo.Context) error {
+		var request apiInstanceRequest
+		if err := c.Bind(&request); err != nil {
+			return newApiError(http.StatusBadRequest, "invalid JSON body")
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+	return e
+}
+
+// buildForm returns a form body of exactly size bytes
+func buildForm(size int) string {
+	prefix := "rules="
+	return prefix + strings.Repeat("a", size-len(prefix))
+}
+
+func TestLimitBody_Form(t *testing.T) {
+	e := newBodyLimitTestEcho()
+	for name, tc := range map[string]struct {
+		size     int
+		chunked  bool
+		expected int
+	}{
+		"at the limit":         {size: 1 << 10, expected: http.StatusOK},
+		"over the limit":       {size: 1<<10 + 1, expected: http.StatusRequestEntityTooLarge},
+		"chunked at the limit": {size: 1 << 10, chunked: true, expected: http.StatusOK},
+		"chunked over limit":   {size: 1<<10 + 1, chunked: true, expected: http.StatusRequestEntityTooLarge},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/form", strings.NewReader(buildForm(tc.size)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expected, rec.Code)
+			if tc.expected == http.StatusOK {
+				assert.Len(t, rec.Body.String(), tc.size-len("rules="))
+			} else {
+				assert.Contains(t, rec.Body.String(), "request body is larger than the 1KB limit")
+			}
+		})
+	}
+}
+
+func TestLimitBody_ApiV1(t *testing.T) {
+	e := newBodyLimitTestEcho()
+	params := strings.Repeat("a", 1<<10)
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/filters/filter1",
+		strings.NewReader(`{"params":{"one":"`+params+`"}}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"message":"request body is larger than the 1KB limit"`)
+
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/filters/filter1", strings.NewReader(`{"params":{"one":"1"}}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestLimitBody_Disabled(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(buildForm(1<<20)))
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+	assert.NoError(t, limitBody(0)(func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		assert.Len(t, body, 1<<20)
+		return err
+	})(c))
+}
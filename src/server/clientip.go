@@ -0,0 +1,145 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const realIPHeader = "X-Real-IP"
+
+// validForwardedHost matches hostnames with an optional port, to avoid injecting rules in the lists
+var validForwardedHost = regexp.MustCompile(`^[A-Za-z0-9.-]{1,253}(:[0-9]{1,5})?$`)
+
+// parseTrustedProxies parses the IP ranges of the trusted proxies, single addresses are accepted too
+func parseTrustedProxies(trustedProxies []string) ([]*net.IPNet, error) {
+	var ranges []*net.IPNet
+	for _, r := range trustedProxies {
+		if !strings.Contains(r, "/") { // Single address
+			if ip := net.ParseIP(r); ip != nil && ip.To4() != nil {
+				r += "/32"
+			} else {
+				r += "/128"
+			}
+		}
+		_, ipRange, err := net.ParseCIDR(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy range: %w", err)
+		}
+		ranges = append(ranges, ipRange)
+	}
+	return ranges, nil
+}
+
+// buildIPExtractor returns an extractor reading the client IP from the header set by the trusted proxies.
+// Only the hops in the trusted ranges are skipped, and the IP of the connection is used if no range is set.
+func buildIPExtractor(ranges []*net.IPNet, header string) echo.IPExtractor {
+	if len(ranges) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	if strings.EqualFold(header, realIPHeader) {
+		return extractIPFromRealIPHeader(ranges)
+	}
+
+	trustOptions := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, r := range ranges {
+		trustOptions = append(trustOptions, echo.TrustIPRange(r))
+	}
+	return echo.ExtractIPFromXFFHeader(trustOptions...)
+}
+
+// isTrustedProxy returns whether the request comes from one of the trusted proxies
+func isTrustedProxy(ranges []*net.IPNet, req *http.Request) bool {
+	ip := net.ParseIP(echo.ExtractIPDirect()(req))
+	if ip == nil {
+		return false
+	}
+	for _, r := range ranges {
+		if r.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractIPFromRealIPHeader reads the header if the connection comes from a trusted proxy.
+// Echo's implementation checks the header value instead, allowing any client to spoof it.
+func extractIPFromRealIPHeader(ranges []*net.IPNet) echo.IPExtractor {
+	direct := echo.ExtractIPDirect()
+	return func(req *http.Request) string {
+		if isTrustedProxy(ranges, req) {
+			realIP := strings.TrimSuffix(strings.TrimPrefix(req.Header.Get(echo.HeaderXRealIP), "["), "]")
+			if net.ParseIP(realIP) != nil {
+				return realIP
+			}
+		}
+		return direct(req)
+	}
+}
+
+// clientIP returns the IP of the client, as seen by the trusted proxies. Features needing
+// the client IP must use it instead of reading headers, that can be spoofed.
+func clientIP(c echo.Context) string {
+	if extract := c.Echo().IPExtractor; extract != nil {
+		return extract(c.Request())
+	}
+	return echo.ExtractIPDirect()(c.Request())
+}
+
+// publicHost returns the host clients reach the instance at: the configured public hostname,
+// the host forwarded by a trusted proxy, or the host of the request.
+func (s *Server) publicHost(c echo.Context) string {
+	if hostname := s.config().options.PublicHostname; hostname != "" {
+		return hostname
+	}
+	return s.requestHost(c)
+}
+
+// requestHost returns the host requested by the client, as forwarded by a trusted proxy
+func (s *Server) requestHost(c echo.Context) string {
+	req := c.Request()
+	if isTrustedProxy(s.proxyRanges, req) {
+		if host := forwardedHost(req); host != "" {
+			return host
+		}
+	}
+	return req.Host
+}
+
+// forwardedHost reads the original host from the X-Forwarded-Host or Forwarded headers.
+// When several proxies are chained, the first one is the host requested by the client.
+func forwardedHost(req *http.Request) string {
+	var host string
+	if value := req.Header.Get("X-Forwarded-Host"); value != "" {
+		host, _, _ = strings.Cut(value, ",")
+	} else if value = req.Header.Get("Forwarded"); value != "" {
+		first, _, _ := strings.Cut(value, ",")
+		for _, pair := range strings.Split(first, ";") {
+			key, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			if strings.EqualFold(key, "host") {
+				host = strings.Trim(v, `"`)
+			}
+		}
+	}
+	host = strings.TrimSpace(host)
+	if !validForwardedHost.MatchString(host) {
+		return ""
+	}
+	return host
+}
+
+// stripPort returns the hostname without its port, as expected in cosmetic filters
+func stripPort(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		return hostname
+	}
+	return host
+}
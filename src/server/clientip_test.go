@@ -0,0 +1,122 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func extractClientIP(t *testing.T, extractor echo.IPExtractor, remoteAddr string, headers map[string]string) string {
+	e := echo.New()
+	e.IPExtractor = extractor
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return clientIP(e.NewContext(req, httptest.NewRecorder()))
+}
+
+func buildTestIPExtractor(t *testing.T, trustedProxies []string, header string) echo.IPExtractor {
+	ranges, err := parseTrustedProxies(trustedProxies)
+	require.NoError(t, err)
+	return buildIPExtractor(ranges, header)
+}
+
+func TestClientIP_Direct(t *testing.T) {
+	extractor := buildTestIPExtractor(t, nil, "X-Forwarded-For")
+	spoofed := map[string]string{
+		echo.HeaderXForwardedFor: "203.0.113.7",
+		echo.HeaderXRealIP:       "203.0.113.8",
+	}
+	assert.Equal(t, "10.0.0.2", extractClientIP(t, extractor, "10.0.0.2:1234", spoofed),
+		"headers are ignored without trusted proxies, even from private addresses")
+	assert.Equal(t, "192.0.2.1", extractClientIP(t, nil, "192.0.2.1:1234", spoofed),
+		"the connection IP is used without an extractor")
+}
+
+func TestClientIP_TrustedProxies(t *testing.T) {
+	extractor := buildTestIPExtractor(t, []string{"10.0.0.0/24", "192.0.2.10"}, "X-Forwarded-For")
+
+	for name, tc := range map[string]struct {
+		remote, xff, expected string
+	}{
+		"direct client":               {"198.51.100.4:1234", "", "198.51.100.4"},
+		"spoofed from untrusted":      {"198.51.100.4:1234", "203.0.113.7", "198.51.100.4"},
+		"spoofed from untrusted lan":  {"10.0.1.5:1234", "203.0.113.7", "10.0.1.5"},
+		"trusted proxy":               {"10.0.0.2:1234", "203.0.113.7", "203.0.113.7"},
+		"trusted single address":      {"192.0.2.10:1234", "203.0.113.7", "203.0.113.7"},
+		"chained trusted proxies":     {"10.0.0.2:1234", "203.0.113.7, 192.0.2.10, 10.0.0.3", "203.0.113.7"},
+		"spoofed before trusted hops": {"10.0.0.2:1234", "1.1.1.1, 203.0.113.7, 10.0.0.3", "203.0.113.7"},
+		"untrusted hop in the chain":  {"10.0.0.2:1234", "203.0.113.7, 198.51.100.9", "198.51.100.9"},
+		"loopback is not trusted":     {"127.0.0.1:1234", "203.0.113.7", "127.0.0.1"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tc.xff != "" {
+				headers[echo.HeaderXForwardedFor] = tc.xff
+			}
+			assert.Equal(t, tc.expected, extractClientIP(t, extractor, tc.remote, headers))
+		})
+	}
+}
+
+func TestClientIP_RealIPHeader(t *testing.T) {
+	extractor := buildTestIPExtractor(t, []string{"10.0.0.0/24"}, "X-Real-IP")
+	headers := map[string]string{
+		echo.HeaderXRealIP:       "203.0.113.7",
+		echo.HeaderXForwardedFor: "203.0.113.8",
+	}
+	assert.Equal(t, "203.0.113.7", extractClientIP(t, extractor, "10.0.0.2:1234", headers))
+	assert.Equal(t, "198.51.100.4", extractClientIP(t, extractor, "198.51.100.4:1234", headers))
+}
+
+func TestParseTrustedProxies_InvalidRange(t *testing.T) {
+	_, err := parseTrustedProxies([]string{"10.0.0.0/33"})
+	assert.ErrorContains(t, err, "invalid trusted proxy range")
+	_, err = parseTrustedProxies([]string{"not-an-ip"})
+	assert.Error(t, err)
+}
+
+func TestPublicHost(t *testing.T) {
+	ranges, err := parseTrustedProxies([]string{"10.0.0.0/24"})
+	require.NoError(t, err)
+	proxied := &Server{options: &Options{}, proxyRanges: ranges}
+	configured := &Server{options: &Options{PublicHostname: "lists.example.com"}, proxyRanges: ranges}
+
+	for name, tc := range map[string]struct {
+		server          *Server
+		remote, header  string
+		value, expected string
+	}{
+		"direct":                   {proxied, "198.51.100.4:1234", "", "", "localhost:8765"},
+		"spoofed from untrusted":   {proxied, "198.51.100.4:1234", "X-Forwarded-Host", "evil.example", "localhost:8765"},
+		"trusted proxy":            {proxied, "10.0.0.2:1234", "X-Forwarded-Host", "blocks.example.org", "blocks.example.org"},
+		"chained proxies":          {proxied, "10.0.0.2:1234", "X-Forwarded-Host", "blocks.example.org, internal:8080", "blocks.example.org"},
+		"forwarded header":         {proxied, "10.0.0.2:1234", "Forwarded", `for=203.0.113.7;host="blocks.example.org:8443";proto=https`, "blocks.example.org:8443"},
+		"injection is ignored":     {proxied, "10.0.0.2:1234", "X-Forwarded-Host", "a.org\n##body", "localhost:8765"},
+		"configured hostname":      {configured, "198.51.100.4:1234", "", "", "lists.example.com"},
+		"configured over proxy":    {configured, "10.0.0.2:1234", "X-Forwarded-Host", "blocks.example.org", "lists.example.com"},
+		"no proxy ranges disabled": {&Server{options: &Options{}}, "10.0.0.2:1234", "X-Forwarded-Host", "blocks.example.org", "localhost:8765"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost:8765/list/token", nil)
+			req.RemoteAddr = tc.remote
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+			assert.Equal(t, tc.expected, tc.server.publicHost(c))
+		})
+	}
+}
+
+func TestStripPort(t *testing.T) {
+	assert.Equal(t, "localhost", stripPort("localhost:8765"))
+	assert.Equal(t, "letsblock.it", stripPort("letsblock.it"))
+	assert.Equal(t, "::1", stripPort("[::1]:8765"))
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// Users can give a copy of their list to another account: the source account generates a clone code,
+// that the other account redeems once to copy all filters into its empty list, with a new token.
+// Users hold a single list, the source account cannot duplicate its own list.
+const (
+	cloneCodePrefix   = "lbic_"
+	cloneCodeLength   = 16 // Random bytes in a code
+	cloneCodeLifetime = 24 * time.Hour
+	auditListClone    = "list_clone"
+)
+
+func generateCloneCode() (string, []byte, error) {
+	secret := make([]byte, cloneCodeLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("cannot generate clone code: %w", err)
+	}
+	code := cloneCodePrefix + hex.EncodeToString(secret)
+	return code, hashApiToken(code), nil
+}
+
+// createCloneCode generates a code to copy the user's list into another account, replacing any previous code
+func (s *Server) createCloneCode(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	code, hash, err := generateCloneCode()
+	if err != nil {
+		return err
+	}
+	expiresAt := s.now().Add(cloneCodeLifetime)
+	if err = s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		if err := q.DeleteCloneCodesForUser(ctx, user); err != nil {
+			return err
+		}
+		return q.CreateCloneCode(ctx, db.CreateCloneCodeParams{
+			CodeHash:  hash,
+			UserID:    user,
+			ExpiresAt: expiresAt,
+		})
+	}); err != nil {
+		return err
+	}
+	return s.renderUserAccount(c, func(hc *pages.Context) {
+		hc.Add("clone_code", code)
+		hc.Add("clone_code_expiry", expiresAt.UTC().Format(time.RFC1123))
+	})
+}
+
+// cloneList copies the filters of the account holding the clone code into the user's empty list
+func (s *Server) cloneList(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	code := strings.TrimSpace(c.FormValue("code"))
+	if !strings.HasPrefix(code, cloneCodePrefix) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid clone code")
+	}
+
+	var templates []string
+	var listURL string
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		from, err := q.ClaimCloneCode(ctx, hashApiToken(code))
+		switch {
+		case err == db.NotFound:
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid or expired clone code")
+		case err != nil:
+			return err
+		case from == user:
+			return echo.NewHTTPError(http.StatusBadRequest, "this clone code has been generated by this account, please log into the other account to use it")
+		case s.bans.IsBanned(from) || s.bans.IsBanned(user):
+			return echo.NewHTTPError(http.StatusForbidden, "banned accounts cannot clone lists")
+		}
+
+		instances, err := q.GetInstancesForUser(ctx, from)
+		if err != nil {
+			return err
+		}
+		if err = checkCloneDestination(ctx, q, user, len(instances)); err != nil {
+			return err
+		}
+		if _, err = q.CopyInstances(ctx, db.CopyInstancesParams{NewUserID: user, OldUserID: from}); err != nil {
+			return err
+		}
+		for _, i := range instances {
+			templates = append(templates, i.TemplateName)
+		}
+		list, err := q.GetListForUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		listURL = s.listURL(c, list.Token)
+		return q.LogAdminAction(ctx, db.LogAdminActionParams{
+			AdminID:  user,
+			Action:   auditListClone,
+			TargetID: from,
+		})
+	}); err != nil {
+		return err
+	}
+
+	for _, template := range templates {
+		s.notifyListChange(user, webhookInstanceCreated, template)
+	}
+	_ = s.statsd.Incr("letsblockit.list_cloned", nil, 1)
+	return s.renderUserAccount(c, func(hc *pages.Context) {
+		hc.Add("cloned_filters", len(templates))
+		hc.Add("cloned_list_url", listURL)
+	})
+}
+
+// checkCloneDestination creates the destination list if needed, and checks that it can receive the copied filters
+func checkCloneDestination(ctx context.Context, q db.Querier, user string, count int) error {
+	info, err := q.GetListForUser(ctx, user)
+	switch {
+	case err == db.NotFound && strings.HasPrefix(user, ephemeralUserPrefix):
+		return echo.NewHTTPError(http.StatusForbidden, "your temporary list has expired")
+	case err == db.NotFound:
+		_, err = q.CreateListForUser(ctx, user)
+		return err
+	case err != nil:
+		return err
+	case info.InstanceCount > 0:
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"your list already has filters, remove them or take a snapshot before cloning another list")
+	case strings.HasPrefix(user, ephemeralUserPrefix) && count > ephemeralMaxInstances:
+		return echo.NewHTTPError(http.StatusForbidden,
+			fmt.Sprintf("temporary lists are limited to %d filters, create an account to clone this list", ephemeralMaxInstances))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cloneSourceUser = "clone-source"
+
+// createCloneCode stores a clone code for the clone source account
+func (s *ServerTestSuite) createCloneCode() string {
+	s.T().Helper()
+	code, hash, err := generateCloneCode()
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.store.CreateCloneCode(context.Background(), db.CreateCloneCodeParams{
+		CodeHash:  hash,
+		UserID:    cloneSourceUser,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	return code
+}
+
+func (s *ServerTestSuite) cloneListRequest(code string) *http.Request {
+	f := make(url.Values)
+	f.Add("code", code)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "http://my.do.main/user/clone", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) TestCreateCloneCode_OK() {
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/clone/code", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Render(gomock.Any(), "user-account", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			code, ok := hc.Data["clone_code"].(string)
+			require.True(s.T(), ok)
+			assert.True(s.T(), strings.HasPrefix(code, cloneCodePrefix))
+			return nil
+		})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestCloneList_OK() {
+	s.addInstance(cloneSourceUser, "filter1", nil)
+	s.addInstance(cloneSourceUser, "filter2", filter2Custom)
+	code := s.createCloneCode()
+
+	s.expectP.Render(gomock.Any(), "user-account", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.Equal(s.T(), 2, hc.Data["cloned_filters"])
+			list, err := s.store.GetListForUser(context.Background(), s.user)
+			require.NoError(s.T(), err)
+			assert.Equal(s.T(), "http://my.do.main/list/"+list.Token.String()+".txt", hc.Data["cloned_list_url"])
+			return nil
+		})
+	s.runRequest(s.cloneListRequest(code), assertOk)
+
+	// Both lists hold the same filters, with different tokens
+	source, err := s.store.GetListForUser(context.Background(), cloneSourceUser)
+	require.NoError(s.T(), err)
+	clone, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	s.NotEqual(source.Token, clone.Token)
+	s.EqualValues(2, source.InstanceCount)
+	s.EqualValues(2, clone.InstanceCount)
+	copied, err := s.store.GetInstance(context.Background(), db.GetInstanceParams{
+		UserID:       s.user,
+		TemplateName: "filter2",
+	})
+	require.NoError(s.T(), err)
+	s.requireJSONEq(filter2Custom, copied.Params)
+
+	actions, err := s.store.GetAdminActions(context.Background(), auditLogPageSize)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), actions, 1)
+	s.Equal(auditListClone, actions[0].Action)
+	s.Equal(cloneSourceUser, actions[0].TargetID)
+
+	// The code is single-use
+	_, err = s.store.ClaimCloneCode(context.Background(), hashApiToken(code))
+	s.ErrorIs(err, db.NotFound)
+}
+
+func (s *ServerTestSuite) TestCloneList_Errors() {
+	s.addInstance(cloneSourceUser, "filter1", nil)
+
+	// The destination list must be empty
+	s.addInstance(s.user, "filter2", nil)
+	code := s.createCloneCode()
+	s.runRequest(s.cloneListRequest(code), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	s.requireInstanceCount("filter1", 0)
+
+	// The failed attempt does not consume the code
+	_, err := s.store.ClaimCloneCode(context.Background(), hashApiToken(code))
+	s.NoError(err)
+
+	for _, invalid := range []string{"", "lbic_unknown", code} {
+		s.runRequest(s.cloneListRequest(invalid), func(t *testing.T, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code, invalid)
+		})
+	}
+}
+
+func (s *ServerTestSuite) TestCloneList_SameAccount() {
+	s.user = cloneSourceUser
+	code := s.createCloneCode()
+	s.runRequest(s.cloneListRequest(code), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
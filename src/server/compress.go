@@ -0,0 +1,244 @@
+package server
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+
+	// minCompressSize is the body size under which compression costs more than it saves
+	minCompressSize = 1024
+)
+
+// encodedPaths are the routes compressed by encodeResponse, the generic gzip middleware must skip them
+var encodedPaths = map[string]bool{
+	"/list/:token":   true,
+	"/export/:token": true,
+	"/api/export":    true,
+}
+
+// checkCompressionLevels validates the levels of both encodings, zero disabling them
+func checkCompressionLevels(options *Options) error {
+	if options.GzipLevel < 0 || options.GzipLevel > gzip.BestCompression {
+		return fmt.Errorf("invalid gzip level %d, it must be between 0 and %d", options.GzipLevel, gzip.BestCompression)
+	}
+	if options.BrotliLevel < 0 || options.BrotliLevel > brotli.BestCompression {
+		return fmt.Errorf("invalid brotli level %d, it must be between 0 and %d", options.BrotliLevel, brotli.BestCompression)
+	}
+	return nil
+}
+
+// negotiateEncoding returns the encoding with the highest quality in the Accept-Encoding header, brotli
+// winning ties. An empty result means the body must be sent as-is.
+func negotiateEncoding(header string, brotliEnabled, gzipEnabled bool) string {
+	var best string
+	var bestQuality float64
+	for _, entry := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(entry, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		quality := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			var err error
+			if quality, err = strconv.ParseFloat(params[2:], 64); err != nil {
+				continue
+			}
+		}
+		if name == "*" && brotliEnabled {
+			name = encodingBrotli
+		} else if name == "*" && gzipEnabled {
+			name = encodingGzip
+		}
+		switch {
+		case name == encodingBrotli && brotliEnabled, name == encodingGzip && gzipEnabled:
+		default:
+			continue
+		}
+		if quality <= 0 {
+			continue // Explicitly refused
+		}
+		if quality > bestQuality || (quality == bestQuality && name == encodingBrotli) {
+			best, bestQuality = name, quality
+		}
+	}
+	return best
+}
+
+// encodedETag returns the etag of a compressed response. Each encoding gets its own etag, for
+// caches to not serve a body in an encoding the client did not accept. The suffix of quoted
+// etags is added inside the quotes.
+func encodedETag(etag, encoding string) string {
+	if strings.HasSuffix(etag, `"`) {
+		return etag[:len(etag)-1] + "-" + encoding + `"`
+	}
+	return etag + "-" + encoding
+}
+
+// decodedETag strips the encoding suffix added by encodedETag, and the weak prefix added by
+// proxies compressing responses, to compare the etag with the one of the content
+func decodedETag(etag string) string {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, encoding := range []string{encodingBrotli, encodingGzip} {
+		if trimmed := strings.TrimSuffix(etag, "-"+encoding); trimmed != etag {
+			return trimmed
+		}
+	}
+	return etag
+}
+
+// encodeResponse compresses the response with brotli or gzip, depending on what the client accepts.
+// Bodies smaller than minCompressSize are sent as-is.
+func (s *Server) encodeResponse(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res := c.Response()
+		res.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+		options := s.config().options
+		encoding := negotiateEncoding(c.Request().Header.Get(echo.HeaderAcceptEncoding),
+			options.BrotliLevel > 0, options.GzipLevel > 0)
+		if encoding == "" {
+			return next(c)
+		}
+
+		w := &encodingWriter{ResponseWriter: res.Writer, encoding: encoding}
+		switch encoding {
+		case encodingBrotli:
+			w.level = options.BrotliLevel
+		case encodingGzip:
+			w.level = options.GzipLevel
+		}
+		res.Writer = w
+		defer func() {
+			// Errors are rendered by echo once the middlewares returned, they are written as-is
+			res.Writer = w.ResponseWriter
+		}()
+		err := next(c)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+}
+
+// encodingWriter buffers the start of the body, and compresses it once it reaches minCompressSize
+type encodingWriter struct {
+	http.ResponseWriter
+	encoding string
+	level    int
+	status   int
+	buffer   []byte
+	encoder  io.WriteCloser
+	direct   bool // The body is not compressed, or the encoder is set up
+}
+
+func (w *encodingWriter) WriteHeader(status int) {
+	if w.status != 0 || w.direct {
+		return
+	}
+	w.status = status
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		_ = w.passThrough()
+	}
+}
+
+func (w *encodingWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.direct {
+		if w.encoder != nil {
+			return w.encoder.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	w.buffer = append(w.buffer, p...)
+	if len(w.buffer) < minCompressSize {
+		return len(p), nil
+	}
+	if err := w.startEncoding(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush sends the buffered body, compressed or not depending on its size so far
+func (w *encodingWriter) Flush() {
+	if !w.direct {
+		if len(w.buffer) >= minCompressSize {
+			_ = w.startEncoding()
+		} else {
+			_ = w.passThrough()
+		}
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close sends the body if it is still buffered, and flushes the encoder
+func (w *encodingWriter) Close() error {
+	if !w.direct {
+		if w.status == 0 && len(w.buffer) == 0 {
+			return nil // Nothing was written, the error handler will write the response
+		}
+		return w.passThrough()
+	}
+	if w.encoder != nil {
+		return w.encoder.Close()
+	}
+	return nil
+}
+
+// passThrough sends the headers and the buffered body without compressing them
+func (w *encodingWriter) passThrough() error {
+	w.direct = true
+	w.sendHeader()
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buffer)
+	w.buffer = nil
+	return err
+}
+
+func (w *encodingWriter) startEncoding() error {
+	w.direct = true
+	header := w.Header()
+	header.Set(echo.HeaderContentEncoding, w.encoding)
+	header.Del(echo.HeaderContentLength)
+	if etag := header.Get("Etag"); etag != "" {
+		header.Set("Etag", encodedETag(etag, w.encoding))
+	}
+	w.sendHeader()
+	switch w.encoding {
+	case encodingBrotli:
+		w.encoder = brotli.NewWriterLevel(w.ResponseWriter, w.level)
+	default:
+		encoder, err := gzip.NewWriterLevel(w.ResponseWriter, w.level)
+		if err != nil {
+			return err
+		}
+		w.encoder = encoder
+	}
+	_, err := w.encoder.Write(w.buffer)
+	w.buffer = nil
+	return err
+}
+
+func (w *encodingWriter) sendHeader() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/andybalholm/brotli"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]struct {
+		header         string
+		brotli, gzip   bool
+		expectedResult string
+	}{
+		"empty":            {"", true, true, ""},
+		"identity":         {"identity", true, true, ""},
+		"gzip":             {"gzip, deflate", true, true, encodingGzip},
+		"brotli":           {"br", true, true, encodingBrotli},
+		"both":             {"gzip, deflate, br", true, true, encodingBrotli},
+		"case":             {"GZIP", true, true, encodingGzip},
+		"gzip preferred":   {"br;q=0.5, gzip;q=0.8", true, true, encodingGzip},
+		"brotli refused":   {"br;q=0, gzip", true, true, encodingGzip},
+		"all refused":      {"br;q=0, gzip;q=0", true, true, ""},
+		"invalid quality":  {"br;q=high, gzip", true, true, encodingGzip},
+		"wildcard":         {"*", true, true, encodingBrotli},
+		"brotli disabled":  {"gzip, br", false, true, encodingGzip},
+		"gzip disabled":    {"gzip", true, false, ""},
+		"wildcard no br":   {"*", false, true, encodingGzip},
+		"spaces in params": {"gzip ; q=0.2, br ;q=0.1", true, true, encodingGzip},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedResult, negotiateEncoding(tc.header, tc.brotli, tc.gzip))
+		})
+	}
+}
+
+func TestDecodedETag(t *testing.T) {
+	assert.Equal(t, "abcd", decodedETag("abcd"))
+	assert.Equal(t, "abcd", decodedETag(encodedETag("abcd", encodingBrotli)))
+	assert.Equal(t, "abcd", decodedETag(encodedETag("abcd", encodingGzip)))
+	assert.Equal(t, "abcd", decodedETag("W/abcd-gzip"))
+	assert.Equal(t, "", decodedETag(""))
+}
+
+func TestEncodedETag_Quoted(t *testing.T) {
+	assert.Equal(t, `W/"abcd-br"`, encodedETag(`W/"abcd"`, encodingBrotli))
+	assert.Equal(t, `"abcd-gzip"`, encodedETag(`"abcd"`, encodingGzip))
+	assert.Equal(t, "abcd-gzip", encodedETag("abcd", encodingGzip))
+}
+
+func TestCheckCompressionLevels(t *testing.T) {
+	assert.NoError(t, checkCompressionLevels(&Options{GzipLevel: 9, BrotliLevel: 11}))
+	assert.NoError(t, checkCompressionLevels(&Options{}))
+	assert.Error(t, checkCompressionLevels(&Options{GzipLevel: 10}))
+	assert.Error(t, checkCompressionLevels(&Options{BrotliLevel: 12}))
+	assert.Error(t, checkCompressionLevels(&Options{GzipLevel: -1}))
+}
+
+func newEncodingTestEcho(options *Options) *echo.Echo {
+	s := &Server{options: options}
+	e := echo.New()
+	e.Logger.SetOutput(io.Discard)
+	e.GET("/body/:size", func(c echo.Context) error {
+		size := len(c.Param("size")) << 10
+		c.Response().Header().Set("Etag", "abcd")
+		return c.String(http.StatusOK, strings.Repeat("a", size))
+	}, s.encodeResponse)
+	e.GET("/weak/:size", func(c echo.Context) error {
+		size := len(c.Param("size")) << 10
+		c.Response().Header().Set("Etag", weakETag("abcd"))
+		return c.String(http.StatusOK, strings.Repeat("a", size))
+	}, s.encodeResponse)
+	e.GET("/tiny", func(c echo.Context) error {
+		c.Response().Header().Set("Etag", "abcd")
+		return c.String(http.StatusOK, "tiny")
+	}, s.encodeResponse)
+	e.GET("/not-modified", func(c echo.Context) error {
+		return c.NoContent(http.StatusNotModified)
+	}, s.encodeResponse)
+	e.GET("/error", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusForbidden, "nope")
+	}, s.encodeResponse)
+	return e
+}
+
+func runEncodingRequest(e *echo.Echo, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set(echo.HeaderAcceptEncoding, acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestEncodeResponse(t *testing.T) {
+	e := newEncodingTestEcho(&Options{GzipLevel: 6, BrotliLevel: 5})
+	expected := strings.Repeat("a", 3<<10)
+
+	rec := runEncodingRequest(e, "/body/xxx", "gzip, br")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, encodingBrotli, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, echo.HeaderAcceptEncoding, rec.Header().Get(echo.HeaderVary))
+	assert.Equal(t, "abcd-br", rec.Header().Get("Etag"))
+	body, err := io.ReadAll(brotli.NewReader(rec.Body))
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(body))
+
+	rec = runEncodingRequest(e, "/body/xxx", "gzip")
+	assert.Equal(t, encodingGzip, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, "abcd-gzip", rec.Header().Get("Etag"))
+	reader, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	body, err = io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(body))
+
+	rec = runEncodingRequest(e, "/body/xxx", "")
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, echo.HeaderAcceptEncoding, rec.Header().Get(echo.HeaderVary))
+	assert.Equal(t, "abcd", rec.Header().Get("Etag"))
+	assert.Equal(t, expected, rec.Body.String())
+
+	rec = runEncodingRequest(e, "/weak/xxx", "br")
+	assert.Equal(t, encodingBrotli, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, []string{`W/"abcd-br"`}, rec.Header().Values("Etag"))
+	rec = runEncodingRequest(e, "/weak/xxx", "")
+	assert.Equal(t, []string{`W/"abcd"`}, rec.Header().Values("Etag"))
+}
+
+func TestEncodeResponse_Uncompressed(t *testing.T) {
+	e := newEncodingTestEcho(&Options{GzipLevel: 6, BrotliLevel: 5})
+
+	rec := runEncodingRequest(e, "/tiny", "br")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, echo.HeaderAcceptEncoding, rec.Header().Get(echo.HeaderVary))
+	assert.Equal(t, "abcd", rec.Header().Get("Etag"))
+	assert.Equal(t, "tiny", rec.Body.String())
+
+	rec = runEncodingRequest(e, "/not-modified", "br")
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Empty(t, rec.Body.String())
+
+	rec = runEncodingRequest(e, "/error", "br")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Contains(t, rec.Body.String(), "nope")
+
+	e = newEncodingTestEcho(&Options{GzipLevel: 6})
+	rec = runEncodingRequest(e, "/body/xxx", "br")
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, strings.Repeat("a", 3<<10), rec.Body.String())
+}
+
+// BenchmarkEncodeList reports the size of a list holding all templates and presets, for each encoding and level
+func BenchmarkEncodeList(b *testing.B) {
+	repo, rows := benchmarkRows(b, true)
+	s := &Server{filters: repo, statsd: &statsd.NoOpClient{}, options: &Options{}}
+	logger := echo.New().Logger
+	logger.SetOutput(io.Discard)
+	body, err := s.renderListBody(context.Background(), logger, 0, uuid.New(), filters.DefaultListTitle, rows, false)
+	require.NoError(b, err)
+
+	encoders := []struct {
+		name       string
+		newEncoder func(w io.Writer) io.WriteCloser
+	}{
+		{"gzip-6", func(w io.Writer) io.WriteCloser { z, _ := gzip.NewWriterLevel(w, 6); return z }},
+		{"gzip-9", func(w io.Writer) io.WriteCloser { z, _ := gzip.NewWriterLevel(w, 9); return z }},
+		{"br-5", func(w io.Writer) io.WriteCloser { return brotli.NewWriterLevel(w, 5) }},
+		{"br-11", func(w io.Writer) io.WriteCloser { return brotli.NewWriterLevel(w, 11) }},
+	}
+	for _, enc := range encoders {
+		b.Run(enc.name, func(b *testing.B) {
+			var out bytes.Buffer
+			for i := 0; i < b.N; i++ {
+				out.Reset()
+				encoder := enc.newEncoder(&out)
+				_, _ = encoder.Write(body)
+				_ = encoder.Close()
+			}
+			b.ReportMetric(float64(len(body)), "raw-bytes")
+			b.ReportMetric(float64(out.Len()), "encoded-bytes")
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// corsMaxAge is the time browsers can cache the preflight responses for, in seconds
+const corsMaxAge = 3600
+
+// buildCORSMiddleware allows browsers to call the JSON API from the given origins, with their cookies
+// for the session-authenticated routes. Lists can be fetched from any origin, without credentials.
+// It is registered for all routes, as the preflight requests do not match the route methods.
+func buildCORSMiddleware(apiOrigins []string) echo.MiddlewareFunc {
+	api := middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: apiOrigins,
+		AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowHeaders: []string{
+			echo.HeaderAuthorization, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderIfModifiedSince, "If-None-Match",
+		},
+		AllowCredentials: true,
+		ExposeHeaders:    []string{"Etag", echo.HeaderXRequestID, echo.HeaderRetryAfter},
+		MaxAge:           corsMaxAge,
+	})
+	lists := middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{http.MethodGet, http.MethodHead},
+		AllowHeaders:  []string{echo.HeaderIfModifiedSince, "If-None-Match"},
+		ExposeHeaders: []string{"Etag"},
+		MaxAge:        corsMaxAge,
+	})
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		apiNext, listsNext := api(next), lists(next)
+		return func(c echo.Context) error {
+			switch p := c.Request().URL.Path; {
+			case len(apiOrigins) > 0 && strings.HasPrefix(p, "/api/"):
+				return apiNext(c)
+			case strings.HasPrefix(p, "/list/"):
+				return listsNext(c)
+			default:
+				return next(c)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCORSTestEcho(origins []string) *echo.Echo {
+	e := echo.New()
+	e.Use(buildCORSMiddleware(origins))
+	handler := func(c echo.Context) error {
+		c.Response().Header().Set("Etag", "abcd")
+		return c.String(http.StatusOK, "ok")
+	}
+	e.GET("/api/v1/filters", handler)
+	e.PUT("/api/v1/filters/:name", handler)
+	e.GET("/list/:token", handler)
+	e.GET("/filters", handler)
+	return e
+}
+
+func runCORSRequest(e *echo.Echo, method, path, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		req.Header.Set(echo.HeaderOrigin, origin)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+var preflightHeaders = map[string]string{echo.HeaderAccessControlRequestMethod: http.MethodPut}
+
+func TestCORS_AllowedOrigin(t *testing.T) {
+	e := newCORSTestEcho([]string{"https://dashboard.example.com", "chrome-extension://abcdef"})
+
+	rec := runCORSRequest(e, http.MethodGet, "/api/v1/filters", "https://dashboard.example.com", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://dashboard.example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "true", rec.Header().Get(echo.HeaderAccessControlAllowCredentials))
+	assert.Contains(t, rec.Header().Get(echo.HeaderAccessControlExposeHeaders), "Etag")
+	assert.Contains(t, rec.Header().Values(echo.HeaderVary), echo.HeaderOrigin)
+
+	rec = runCORSRequest(e, http.MethodOptions, "/api/v1/filters/test", "chrome-extension://abcdef", preflightHeaders)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "chrome-extension://abcdef", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "true", rec.Header().Get(echo.HeaderAccessControlAllowCredentials))
+	assert.Contains(t, rec.Header().Get(echo.HeaderAccessControlAllowMethods), http.MethodPut)
+	assert.Contains(t, rec.Header().Get(echo.HeaderAccessControlAllowHeaders), echo.HeaderAuthorization)
+	assert.Equal(t, "3600", rec.Header().Get(echo.HeaderAccessControlMaxAge))
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestCORS_BlockedOrigin(t *testing.T) {
+	e := newCORSTestEcho([]string{"https://dashboard.example.com"})
+
+	rec := runCORSRequest(e, http.MethodGet, "/api/v1/filters", "https://evil.example.com", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowCredentials))
+
+	rec = runCORSRequest(e, http.MethodOptions, "/api/v1/filters/test", "https://evil.example.com", preflightHeaders)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowMethods))
+
+	// Pages are never allowed cross-origin
+	rec = runCORSRequest(e, http.MethodGet, "/filters", "https://dashboard.example.com", nil)
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+}
+
+func TestCORS_NoOriginsConfigured(t *testing.T) {
+	e := newCORSTestEcho(nil)
+	rec := runCORSRequest(e, http.MethodGet, "/api/v1/filters", "https://dashboard.example.com", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+}
+
+func TestCORS_Lists(t *testing.T) {
+	e := newCORSTestEcho(nil)
+
+	rec := runCORSRequest(e, http.MethodGet, "/list/"+uuid.NewString(), "https://preview.example.com", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "*", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowCredentials))
+	assert.Equal(t, "Etag", rec.Header().Get(echo.HeaderAccessControlExposeHeaders))
+
+	rec = runCORSRequest(e, http.MethodOptions, "/list/"+uuid.NewString(), "https://preview.example.com",
+		map[string]string{echo.HeaderAccessControlRequestMethod: http.MethodGet})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "*", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "GET,HEAD", rec.Header().Get(echo.HeaderAccessControlAllowMethods))
+	assert.Equal(t, "If-Modified-Since,If-None-Match", rec.Header().Get(echo.HeaderAccessControlAllowHeaders))
+}
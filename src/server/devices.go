@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// Users can subscribe each of their devices with a separate list token, to revoke them one by one.
+// The default token is the one shown in the filter pages, it can only be rotated from the account page.
+const (
+	listTokensPerList = 10
+	listTokenMaxLabel = 64
+)
+
+// deviceInfo holds the device token information displayed in the devices page
+type deviceInfo struct {
+	ID         int32
+	Label      string
+	ListURL    string
+	IsDefault  bool
+	CreatedAt  string
+	LastUsedAt string
+}
+
+func (s *Server) userDevices(c echo.Context) error {
+	hc := s.buildPageContext(c, "My devices")
+	hc.NoBoost = true
+	stored, err := s.store.GetListTokensForUser(c.Request().Context(), hc.UserID)
+	if err != nil {
+		return err
+	}
+	devices := make([]deviceInfo, 0, len(stored))
+	for _, t := range stored {
+		info := deviceInfo{
+			ID:        t.ID,
+			Label:     t.Label,
+			ListURL:   s.listURL(c, t.Token),
+			IsDefault: t.IsDefault,
+			CreatedAt: t.CreatedAt.Format(apiTokenDateFormat),
+		}
+		if t.LastUsedAt.Valid {
+			info.LastUsedAt = t.LastUsedAt.Time.Format(apiTokenDateFormat)
+		}
+		devices = append(devices, info)
+	}
+	hc.Add("devices", devices)
+	hc.Add("can_add_device", len(devices) < listTokensPerList)
+	return s.pages.Render(c, "user-devices", hc)
+}
+
+// createDeviceToken adds a list token for a new device, up to listTokensPerList
+func (s *Server) createDeviceToken(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return errors.New("invalid user session")
+	}
+	label := strings.TrimSpace(c.FormValue("label"))
+	if label == "" || len(label) > listTokenMaxLabel {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid arguments")
+	}
+
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		count, err := q.CountListTokensForUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		if count >= listTokensPerList {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"you can subscribe up to "+strconv.Itoa(listTokensPerList)+" devices, revoke one first")
+		}
+		_, err = q.CreateListToken(ctx, db.CreateListTokenParams{
+			Label:  label,
+			UserID: user,
+		})
+		if err == db.NotFound {
+			return echo.NewHTTPError(http.StatusBadRequest, "you need a filter list to subscribe a device")
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("user-devices"))
+}
+
+// revokeDeviceToken deletes a device token, the downloads of this device will fail from now on
+func (s *Server) revokeDeviceToken(c echo.Context) error {
+	id, err := strconv.ParseInt(c.FormValue("id"), 10, 32)
+	user := auth.GetUserId(c)
+	if user == "" || err != nil {
+		return errors.New("invalid arguments")
+	}
+	deleted, err := s.store.DeleteListToken(c.Request().Context(), db.DeleteListTokenParams{
+		ID:     int32(id),
+		UserID: user,
+	})
+	switch {
+	case err != nil:
+		return err
+	case deleted == 0:
+		return echo.NewHTTPError(http.StatusBadRequest, "unknown token, the default token can only be rotated")
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("user-devices"))
+}
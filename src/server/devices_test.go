@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *ServerTestSuite) revokeDevice(id int32) *http.Request {
+	f := make(url.Values)
+	f.Add("id", strconv.Itoa(int(id)))
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/devices/revoke", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) TestDevices_Lifecycle() {
+	listToken, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   filter2Custom,
+	}))
+
+	f := make(url.Values)
+	f.Add("label", "work laptop")
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/devices", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/devices")
+	s.runRequest(req, assertOk)
+
+	devices, err := s.store.GetListTokensForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), devices, 2)
+	assert.Equal(s.T(), "default", devices[0].Label)
+	assert.Equal(s.T(), listToken, devices[0].Token)
+	assert.True(s.T(), devices[0].IsDefault)
+	assert.Equal(s.T(), "work laptop", devices[1].Label)
+	assert.False(s.T(), devices[1].IsDefault)
+
+	// Both tokens render the same filters, downloads are recorded per token
+	for _, token := range []string{listToken.String(), devices[1].Token.String()} {
+		rec := httptest.NewRecorder()
+		s.server.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list/"+token, nil))
+		s.Equal(http.StatusOK, rec.Code)
+		s.Contains(rec.Body.String(), filter2CustomOutput)
+	}
+	devices, err = s.store.GetListTokensForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), devices[0].LastUsedAt.Valid)
+	assert.True(s.T(), devices[1].LastUsedAt.Valid)
+
+	// The default token cannot be revoked, other tokens can
+	s.runRequest(s.revokeDevice(devices[0].ID), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/devices")
+	s.runRequest(s.revokeDevice(devices[1].ID), assertOk)
+
+	for token, code := range map[string]int{
+		listToken.String():        http.StatusOK,
+		devices[1].Token.String(): http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		s.server.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list/"+token, nil))
+		s.Equal(code, rec.Code, token)
+	}
+}
+
+func (s *ServerTestSuite) TestDevices_Page() {
+	listToken, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	devices, err := s.store.GetListTokensForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), devices, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "http://my.do.main/user/devices", nil)
+	s.expectRender("user-devices", pages.ContextData{
+		"devices": []deviceInfo{{
+			ID:        devices[0].ID,
+			Label:     "default",
+			ListURL:   "http://my.do.main/list/" + listToken.String() + ".txt",
+			IsDefault: true,
+			CreatedAt: devices[0].CreatedAt.Format(apiTokenDateFormat),
+		}},
+		"can_add_device": true,
+	})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestDevices_RotateDefaultToken() {
+	oldToken, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.store.RotateListToken(context.Background(), db.RotateListTokenParams{
+		UserID: s.user,
+		Token:  oldToken,
+	}))
+
+	list, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	devices, err := s.store.GetListTokensForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), devices, 1)
+	assert.Equal(s.T(), list.Token, devices[0].Token)
+	assert.True(s.T(), devices[0].IsDefault)
+
+	_, err = s.store.GetListForToken(context.Background(), oldToken)
+	assert.ErrorIs(s.T(), err, db.NotFound)
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+	"golang.org/x/time/rate"
+)
+
+// Visitors without an account can try the service with an ephemeral list, identified by
+// a signed cookie. The list is adopted by their account if they sign up before it expires.
+const (
+	ephemeralCookieName    = "lbi_try"
+	ephemeralUserPrefix    = "ephemeral:"
+	ephemeralListLifetime  = 48 * time.Hour
+	ephemeralMaxInstances  = 10
+	ephemeralPurgeInterval = time.Hour
+)
+
+// buildEphemeralRateLimiter limits ephemeral list creations to a few per hour for each IP
+func buildEphemeralRateLimiter() echo.MiddlewareFunc {
+	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate:      rate.Every(20 * time.Minute),
+			Burst:     3,
+			ExpiresIn: time.Hour,
+		}),
+	})
+}
+
+// signEphemeralCookie returns a cookie value holding the list ID and its expiry
+func (s *Server) signEphemeralCookie(id uuid.UUID, expiresAt time.Time) string {
+	payload := id.String() + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + s.cookieSignature(payload)
+}
+
+// parseEphemeralCookie returns the ephemeral user of a cookie, if its signature is valid and the list has not expired
+func (s *Server) parseEphemeralCookie(value string) (string, time.Time, bool) {
+	split := strings.LastIndex(value, ".")
+	if split < 0 || !hmac.Equal([]byte(value[split+1:]), []byte(s.cookieSignature(value[:split]))) {
+		return "", time.Time{}, false
+	}
+	id, expiry, found := strings.Cut(value[:split], ".")
+	timestamp, err := strconv.ParseInt(expiry, 10, 64)
+	if !found || err != nil {
+		return "", time.Time{}, false
+	}
+	expiresAt := time.Unix(timestamp, 0)
+	if !expiresAt.After(s.now()) {
+		return "", time.Time{}, false
+	}
+	return ephemeralUserPrefix + id, expiresAt, true
+}
+
+func (s *Server) cookieSignature(payload string) string {
+	mac := hmac.New(sha256.New, s.cookieKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) setEphemeralCookie(c echo.Context, value string, expiresAt time.Time) {
+	c.SetCookie(&http.Cookie{
+		Name:     ephemeralCookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ephemeralSession authenticates visitors with an ephemeral list, and hands the list over to
+// their account when they log in. It must run after the auth backend's middleware.
+func (s *Server) ephemeralSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cookie, err := c.Cookie(ephemeralCookieName)
+		if err != nil {
+			return next(c)
+		}
+		ephemeralUser, expiresAt, valid := s.parseEphemeralCookie(cookie.Value)
+		switch user := auth.GetUserId(c); {
+		case !valid:
+			s.setEphemeralCookie(c, "", time.Unix(0, 0))
+		case user == "":
+			auth.SetEphemeralUser(c, ephemeralUser)
+			c.Set(ephemeralCookieName, expiresAt)
+		default:
+			if err = s.adoptEphemeralList(c, user, ephemeralUser); err != nil {
+				return err
+			}
+			s.setEphemeralCookie(c, "", time.Unix(0, 0))
+		}
+		return next(c)
+	}
+}
+
+// startEphemeralList creates an ephemeral list for an anonymous visitor
+func (s *Server) startEphemeralList(c echo.Context) error {
+	if auth.GetUserId(c) != "" {
+		return s.pages.RedirectToPage(c, "list-filters")
+	}
+	id := uuid.New()
+	expiresAt := s.now().Add(ephemeralListLifetime)
+	if _, err := s.store.CreateEphemeralList(c.Request().Context(), db.CreateEphemeralListParams{
+		UserID:    ephemeralUserPrefix + id.String(),
+		ExpiresAt: sql.NullTime{Time: expiresAt, Valid: true},
+	}); err != nil {
+		return err
+	}
+	_ = s.statsd.Incr("letsblockit.ephemeral_list_created", nil, 1)
+	s.setEphemeralCookie(c, s.signEphemeralCookie(id, expiresAt), expiresAt)
+	return s.pages.RedirectToPage(c, "list-filters")
+}
+
+// adoptEphemeralList hands an ephemeral list over to a user, if they do not have filters yet.
+// Otherwise, the ephemeral list is left to expire.
+func (s *Server) adoptEphemeralList(c echo.Context, user, ephemeralUser string) error {
+	return s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		info, err := q.GetListForUser(ctx, user)
+		switch {
+		case err == db.NotFound:
+		case err != nil:
+			return err
+		case info.InstanceCount > 0:
+			return nil
+		default:
+			if err = q.DeleteListForUser(ctx, user); err != nil {
+				return err
+			}
+		}
+		if err = q.AdoptEphemeralList(ctx, db.AdoptEphemeralListParams{
+			NewUserID:       user,
+			EphemeralUserID: ephemeralUser,
+		}); err != nil {
+			return err
+		}
+		_ = s.statsd.Incr("letsblockit.ephemeral_list_adopted", nil, 1)
+		return q.AdoptEphemeralInstances(ctx, db.AdoptEphemeralInstancesParams{
+			NewUserID:       user,
+			EphemeralUserID: ephemeralUser,
+		})
+	})
+}
+
+// requireAccount restricts account management features to users with an account
+func requireAccount(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if auth.IsEphemeral(c) {
+			return echo.NewHTTPError(http.StatusForbidden, "an account is required")
+		}
+		return next(c)
+	}
+}
+
+// checkEphemeralLimit returns an error if an ephemeral user cannot add a new instance to their list
+func checkEphemeralLimit(ctx context.Context, q db.Querier, user string) error {
+	if !strings.HasPrefix(user, ephemeralUserPrefix) {
+		return nil
+	}
+	info, err := q.GetListForUser(ctx, user)
+	switch {
+	case err == db.NotFound:
+		return echo.NewHTTPError(http.StatusForbidden, "your temporary list has expired")
+	case err != nil:
+		return err
+	case info.InstanceCount >= ephemeralMaxInstances:
+		return echo.NewHTTPError(http.StatusForbidden,
+			fmt.Sprintf("temporary lists are limited to %d filters, create an account to add more", ephemeralMaxInstances))
+	}
+	return nil
+}
+
+// purgeEphemeralLists deletes expired ephemeral lists, and their instances, on every interval until ctx is cancelled
+func purgeEphemeralLists(ctx context.Context, log echo.Logger, store db.Store) {
+	ticker := time.NewTicker(ephemeralPurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := store.DeleteExpiredLists(ctx)
+			if err != nil {
+				log.Error("cannot purge ephemeral lists: " + err.Error())
+			} else if count > 0 {
+				log.Infof("purged %d expired ephemeral lists", count)
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startEphemeralList runs the try-it form as an anonymous visitor, and returns the ephemeral cookie
+func (s *ServerTestSuite) startEphemeralList() *http.Cookie {
+	s.T().Helper()
+	s.user = ""
+	s.server.now = time.Now // Expiry is checked by the database
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/try", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.RedirectToPage(gomock.Any(), "list-filters")
+
+	var cookie *http.Cookie
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == ephemeralCookieName {
+				cookie = c
+			}
+		}
+	})
+	require.NotNil(s.T(), cookie)
+	return cookie
+}
+
+func (s *ServerTestSuite) TestEphemeral_CookieRoundTrip() {
+	id := uuid.New()
+	expiresAt := fixedNow.Add(time.Hour).Truncate(time.Second)
+	value := s.server.signEphemeralCookie(id, expiresAt)
+
+	user, expiry, valid := s.server.parseEphemeralCookie(value)
+	s.True(valid)
+	s.Equal(ephemeralUserPrefix+id.String(), user)
+	s.True(expiresAt.Equal(expiry))
+
+	_, _, valid = s.server.parseEphemeralCookie(strings.Replace(value, id.String(), uuid.NewString(), 1))
+	s.False(valid, "tampered cookie")
+	_, _, valid = s.server.parseEphemeralCookie(s.server.signEphemeralCookie(id, fixedNow.Add(-time.Second)))
+	s.False(valid, "expired cookie")
+	_, _, valid = s.server.parseEphemeralCookie("invalid")
+	s.False(valid, "invalid cookie")
+}
+
+func (s *ServerTestSuite) TestEphemeral_CreateAndUse() {
+	cookie := s.startEphemeralList()
+	ephemeralUser, _, valid := s.server.parseEphemeralCookie(cookie.Value)
+	require.True(s.T(), valid)
+
+	f := buildFilter2CustomBody()
+	f.Add("__save", "")
+	req := httptest.NewRequest(http.MethodPost, "/filters/filter2", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.AddCookie(cookie)
+	s.expectP.RedirectToPage(gomock.Any(), "list-filters")
+	s.runRequest(req, assertOk)
+
+	count, err := s.store.CountInstances(context.Background(), db.CountInstancesParams{
+		UserID:       ephemeralUser,
+		TemplateName: "filter2",
+	})
+	require.NoError(s.T(), err)
+	s.EqualValues(1, count)
+
+	// Account management is not available
+	req = httptest.NewRequest(http.MethodPost, "/user/api-tokens", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.AddCookie(cookie)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestEphemeral_AccountPage() {
+	cookie := s.startEphemeralList()
+	req := httptest.NewRequest(http.MethodGet, "/user/account", nil)
+	req.AddCookie(cookie)
+	s.expectP.Render(gomock.Any(), "user-account", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.True(s.T(), hc.UserLoggedIn)
+			assert.True(s.T(), hc.UserIsEphemeral)
+			assert.Nil(s.T(), hc.Preferences)
+			assert.Equal(s.T(), cookie.Expires.UTC().Format(time.RFC1123), hc.Data["ephemeral_expires"])
+			assert.NotContains(s.T(), hc.Data, "api_tokens")
+			return nil
+		})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestEphemeral_InstanceLimit() {
+	cookie := s.startEphemeralList()
+	ephemeralUser, _, _ := s.server.parseEphemeralCookie(cookie.Value)
+	for i := 0; i < ephemeralMaxInstances; i++ {
+		require.NoError(s.T(), s.store.CreateInstance(context.Background(), db.CreateInstanceParams{
+			UserID:       ephemeralUser,
+			TemplateName: fmt.Sprintf("template-%d", i),
+		}))
+	}
+	err := s.server.upsertFilterParams(s.c, ephemeralUser, &filters.Instance{Template: "filter2"})
+	s.Equal(http.StatusForbidden, err.(*echo.HTTPError).Code)
+}
+
+func (s *ServerTestSuite) TestEphemeral_AdoptedOnLogin() {
+	user := s.user
+	cookie := s.startEphemeralList()
+	ephemeralUser, _, _ := s.server.parseEphemeralCookie(cookie.Value)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, ephemeralUser, &filters.Instance{Template: "filter2"}))
+
+	s.user = user
+	req := httptest.NewRequest(http.MethodGet, "/user/account", nil)
+	req.AddCookie(cookie)
+	s.expectP.Render(gomock.Any(), "user-account", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.False(s.T(), hc.UserIsEphemeral)
+			assert.EqualValues(s.T(), 1, hc.Data["filter_count"])
+			return nil
+		})
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.Contains(t, rec.Header().Get("Set-Cookie"), ephemeralCookieName+"=;")
+	})
+	s.requireInstanceCount("filter2", 1)
+	_, err := s.store.GetListForUser(context.Background(), ephemeralUser)
+	s.ErrorIs(err, db.NotFound)
+}
+
+func (s *ServerTestSuite) TestEphemeral_ExpiredListNotServed() {
+	token, err := s.store.CreateEphemeralList(context.Background(), db.CreateEphemeralListParams{
+		UserID:    ephemeralUserPrefix + uuid.NewString(),
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+	})
+	require.NoError(s.T(), err)
+	req := httptest.NewRequest(http.MethodGet, "/list/"+token.String(), nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	count, err := s.store.DeleteExpiredLists(context.Background())
+	require.NoError(s.T(), err)
+	s.EqualValues(1, count)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// requestETags holds the validators sent in the If-None-Match header of a request. They are compared
+// without their weak prefix, quotes and encoding suffix: proxies weaken the etags of the responses they
+// compress, and subscribers can still send the unquoted etags served by previous versions.
+type requestETags struct {
+	any    bool // The header is *, matching any etag
+	values []string
+}
+
+// weakETag formats an etag as a quoted weak validator, for the ETag header of the list downloads.
+// The lists are only equivalent across encodings and re-renders, not byte-for-byte identical.
+func weakETag(etag string) string {
+	return `W/"` + etag + `"`
+}
+
+// strongETag formats an etag as a quoted strong validator, for responses that are byte-for-byte identical
+// as long as their etag does not change.
+func strongETag(etag string) string {
+	return `"` + etag + `"`
+}
+
+func getRequestETags(c echo.Context) requestETags {
+	return parseIfNoneMatch(c.Request().Header.Get("If-None-Match"))
+}
+
+// parseIfNoneMatch parses a comma-separated list of etags, malformed values are skipped
+func parseIfNoneMatch(header string) requestETags {
+	var out requestETags
+	for header != "" {
+		header = strings.TrimLeft(header, " \t,")
+		if header == "" {
+			break
+		}
+		header = strings.TrimPrefix(header, "W/")
+		var value string
+		quoted := header != "" && header[0] == '"'
+		if quoted {
+			end := strings.IndexByte(header[1:], '"')
+			if end < 0 {
+				// Unterminated quoted value, nothing after it can be trusted
+				break
+			}
+			value, header = header[1:end+1], header[end+2:]
+			// Skip anything between the closing quote and the next comma
+			if next := strings.IndexByte(header, ','); next >= 0 {
+				header = header[next:]
+			} else {
+				header = ""
+			}
+		} else if next := strings.IndexByte(header, ','); next >= 0 {
+			value, header = strings.TrimSpace(header[:next]), header[next:]
+		} else {
+			value, header = strings.TrimSpace(header), ""
+		}
+		switch value = decodedETag(value); {
+		case value == "":
+		case value == "*" && !quoted:
+			out.any = true
+		default:
+			out.values = append(out.values, value)
+		}
+	}
+	return out
+}
+
+// present returns whether the request holds at least one etag
+func (r requestETags) present() bool {
+	return r.any || len(r.values) > 0
+}
+
+// match returns whether one of the request etags matches a response etag
+func (r requestETags) match(etag string) bool {
+	if etag == "" {
+		return false
+	}
+	if r.any {
+		return true
+	}
+	etag = decodedETag(etag)
+	for _, value := range r.values {
+		if value == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIfNoneMatch(t *testing.T) {
+	tests := map[string]struct {
+		header   string
+		expected requestETags
+	}{
+		"empty":              {"", requestETags{}},
+		"single unquoted":    {"abcd", requestETags{values: []string{"abcd"}}},
+		"single quoted":      {`"abcd"`, requestETags{values: []string{"abcd"}}},
+		"encoded":            {"abcd-br", requestETags{values: []string{"abcd"}}},
+		"weak":               {`W/"abcd-gzip"`, requestETags{values: []string{"abcd"}}},
+		"multiple":           {`"one", W/"two" ,three`, requestETags{values: []string{"one", "two", "three"}}},
+		"comma in quotes":    {`"a,b", "c"`, requestETags{values: []string{"a,b", "c"}}},
+		"wildcard":           {"*", requestETags{any: true}},
+		"quoted wildcard":    {`"*"`, requestETags{values: []string{"*"}}},
+		"empty values":       {` , ,"",abcd,`, requestETags{values: []string{"abcd"}}},
+		"unterminated quote": {`"one", "two`, requestETags{values: []string{"one"}}},
+		"garbage after tag":  {`"one"xyz, "two"`, requestETags{values: []string{"one", "two"}}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseIfNoneMatch(tc.header))
+		})
+	}
+}
+
+func TestRequestETags_Match(t *testing.T) {
+	assert.False(t, requestETags{}.present())
+	assert.False(t, requestETags{}.match("abcd"))
+
+	etags := parseIfNoneMatch(`"one", W/"abcd-gzip"`)
+	assert.True(t, etags.present())
+	assert.True(t, etags.match("abcd"))
+	assert.True(t, etags.match("one"))
+	assert.True(t, etags.match("abcd-br"), "encodings of the same content match")
+	assert.False(t, etags.match("two"))
+	assert.False(t, etags.match(""))
+
+	wildcard := parseIfNoneMatch("*")
+	assert.True(t, wildcard.present())
+	assert.True(t, wildcard.match("anything"))
+	assert.False(t, wildcard.match(""))
+}
+
+func TestWeakETag(t *testing.T) {
+	assert.Equal(t, `W/"abcd"`, weakETag("abcd"))
+
+	// Clients can send the quoted etag, or the unquoted one served by previous versions
+	for _, header := range []string{weakETag("abcd"), `"abcd"`, "abcd", `W/"abcd-gzip"`, "abcd-br"} {
+		assert.True(t, parseIfNoneMatch(header).match("abcd"), header)
+	}
+}
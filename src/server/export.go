@@ -0,0 +1,460 @@
+package server
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+const (
+	dataExportPath = "/user/data-export"
+	// dataExportMaxSize caps the size of the stored filter parameters, to keep the export fast
+	dataExportMaxSize = 10 << 20
+)
+
+// exportedPreferences holds the user preferences and the news read marker
+type exportedPreferences struct {
+	ColorMode        string            `json:"color_mode"`
+	BetaFeatures     bool              `json:"beta_features"`
+	NewsReadUntil    time.Time         `json:"news_read_until"`
+	TemplateVersions map[string]string `json:"seen_template_versions,omitempty"`
+}
+
+// exportedInstance holds a filter instance, with its creation and last update dates
+type exportedInstance struct {
+	Template  string                 `json:"template"`
+	Params    map[string]interface{} `json:"params,omitempty"`
+	TestMode  bool                   `json:"test_mode"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt *time.Time             `json:"updated_at,omitempty"`
+}
+
+// exportedApiToken holds the metadata of an API token, the token itself is never stored
+type exportedApiToken struct {
+	Label      string     `json:"label"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type exportedSession struct {
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// exportedWebhook holds the webhook settings and recent deliveries, the secret is not exported
+type exportedWebhook struct {
+	URL        string                    `json:"url"`
+	Deliveries []exportedWebhookDelivery `json:"deliveries"`
+}
+
+type exportedWebhookDelivery struct {
+	Event      string    `json:"event"`
+	StatusCode int32     `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Attempts   int32     `json:"attempts"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// exportedFeedback holds a feedback sent on a template, stripped comments are left empty
+type exportedFeedback struct {
+	Template  string    `json:"template"`
+	Working   bool      `json:"working"`
+	Browser   string    `json:"browser"`
+	Adblocker string    `json:"adblocker"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// exportedProposal holds a filter template proposal and its review status
+type exportedProposal struct {
+	Template        string     `json:"template"`
+	TargetSite      string     `json:"target_site"`
+	Description     string     `json:"description"`
+	ExampleRules    string     `json:"example_rules"`
+	Status          string     `json:"status"`
+	CreatedAt       time.Time  `json:"created_at"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+}
+
+// exportedShareToken holds a read-only share token of the list
+type exportedShareToken struct {
+	Label     string    `json:"label"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// exportedDevice holds a device token of the list, and its last download date
+type exportedDevice struct {
+	Label      string     `json:"label"`
+	Token      string     `json:"token"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// exportedSnapshot holds a snapshot of the list, its contents use the list export format
+type exportedSnapshot struct {
+	Name      string    `json:"name"`
+	Automatic bool      `json:"automatic"`
+	Contents  string    `json:"contents"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// exportedFavorite holds a template starred by the user
+type exportedFavorite struct {
+	Template  string    `json:"template"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// userDataExport holds all the data stored about a user
+type userDataExport struct {
+	listToken   string
+	list        *filters.List
+	preferences *exportedPreferences
+	instances   []exportedInstance
+	apiTokens   []exportedApiToken
+	sessions    []exportedSession
+	webhook     *exportedWebhook
+	feedback    []exportedFeedback
+	proposals   []exportedProposal
+	shareTokens []exportedShareToken
+	devices     []exportedDevice
+	snapshots   []exportedSnapshot
+	favorites   []exportedFavorite
+}
+
+// exportUserData streams a zip file holding all the data stored about the user.
+// Banned users can still access it, see the ban middleware.
+func (s *Server) exportUserData(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	if auth.IsImpersonating(c) {
+		return echo.NewHTTPError(http.StatusForbidden, "data exports are not available while impersonating a user")
+	}
+
+	var export userDataExport
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		return loadUserData(ctx, q, user, &export)
+	}); err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("Content-Type", "application/zip")
+	c.Response().Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"letsblockit-data-%s.zip\"", s.now().Format("2006-01-02")))
+	c.Response().WriteHeader(http.StatusOK)
+	if err := s.writeUserData(c.Response(), &export); err != nil {
+		c.Logger().Warnf("failed to write data export for %s: %s", user, err)
+	}
+	_ = s.statsd.Incr("letsblockit.user_data_exported", nil, 1)
+	return nil
+}
+
+func loadUserData(ctx context.Context, q db.Querier, user string, export *userDataExport) error {
+	instances, err := q.GetInstanceHistoryForUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	size := 0
+	for _, i := range instances {
+		size += len(i.Params.Bytes)
+	}
+	if size > dataExportMaxSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "your data is too large to be exported, please contact us")
+	}
+	for _, i := range instances {
+		instance := exportedInstance{
+			Template:  i.TemplateName,
+			TestMode:  i.TestMode,
+			CreatedAt: i.CreatedAt,
+		}
+		if err = i.Params.AssignTo(&instance.Params); err != nil {
+			return err
+		}
+		if i.UpdatedAt.Valid {
+			instance.UpdatedAt = &i.UpdatedAt.Time
+		}
+		export.instances = append(export.instances, instance)
+	}
+
+	switch info, err := q.GetListForUser(ctx, user); err {
+	case nil:
+		list, err := q.GetListForToken(ctx, info.Token)
+		if err != nil {
+			return err
+		}
+		listInstances, err := q.GetInstancesForList(ctx, list.ID)
+		if err != nil {
+			return err
+		}
+		if export.list, err = convertFilterList(listTitle(info.Title), listInstances); err != nil {
+			return err
+		}
+		export.listToken = info.Token.String()
+	case db.NotFound: // ok
+	default:
+		return err
+	}
+
+	switch prefs, err := q.GetUserPreferences(ctx, user); err {
+	case nil:
+		export.preferences = &exportedPreferences{
+			ColorMode:     string(prefs.ColorMode),
+			BetaFeatures:  prefs.BetaFeatures,
+			NewsReadUntil: prefs.NewsCursor,
+		}
+		acks, err := q.GetTemplateAcksForUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		for _, a := range acks {
+			if export.preferences.TemplateVersions == nil {
+				export.preferences.TemplateVersions = make(map[string]string)
+			}
+			export.preferences.TemplateVersions[a.TemplateName] = a.TemplateHash
+		}
+	case db.NotFound: // ok
+	default:
+		return err
+	}
+
+	tokens, err := q.GetAllApiTokensForUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		token := exportedApiToken{
+			Label:     t.Label,
+			Scopes:    t.Scopes,
+			CreatedAt: t.CreatedAt,
+		}
+		if t.LastUsedAt.Valid {
+			token.LastUsedAt = &t.LastUsedAt.Time
+		}
+		if t.RevokedAt.Valid {
+			token.RevokedAt = &t.RevokedAt.Time
+		}
+		export.apiTokens = append(export.apiTokens, token)
+	}
+
+	sessions, err := q.GetSessionsForUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		export.sessions = append(export.sessions, exportedSession{
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+		})
+	}
+
+	switch webhook, err := q.GetWebhookForUser(ctx, user); err {
+	case nil:
+		export.webhook = &exportedWebhook{URL: webhook.Url, Deliveries: []exportedWebhookDelivery{}}
+		deliveries, err := q.GetWebhookDeliveries(ctx, db.GetWebhookDeliveriesParams{
+			UserID: user,
+			Limit:  webhookKeptDeliveries,
+		})
+		if err != nil {
+			return err
+		}
+		for _, d := range deliveries {
+			export.webhook.Deliveries = append(export.webhook.Deliveries, exportedWebhookDelivery(d))
+		}
+	case db.NotFound: // ok
+	default:
+		return err
+	}
+
+	feedback, err := q.GetFeedbackForUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	for _, f := range feedback {
+		export.feedback = append(export.feedback, exportedFeedback{
+			Template:  f.TemplateName,
+			Working:   f.Working,
+			Browser:   f.Browser,
+			Adblocker: f.Adblocker,
+			Comment:   f.Comment,
+			CreatedAt: f.CreatedAt,
+		})
+	}
+
+	proposals, err := q.GetProposalsForUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	for _, p := range proposals {
+		proposal := exportedProposal{
+			Template:     p.TemplateName,
+			TargetSite:   p.TargetSite,
+			Description:  p.Description,
+			ExampleRules: p.ExampleRules,
+			Status:       string(p.Status),
+			CreatedAt:    p.CreatedAt,
+		}
+		if p.StatusChangedAt.Valid {
+			proposal.StatusChangedAt = &p.StatusChangedAt.Time
+		}
+		export.proposals = append(export.proposals, proposal)
+	}
+
+	shareTokens, err := q.GetShareTokensForUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	for _, t := range shareTokens {
+		export.shareTokens = append(export.shareTokens, exportedShareToken{
+			Label:     t.Label,
+			Token:     t.Token.String(),
+			CreatedAt: t.CreatedAt,
+		})
+	}
+
+	devices, err := q.GetListTokensForUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	for _, d := range devices {
+		device := exportedDevice{
+			Label:     d.Label,
+			Token:     d.Token.String(),
+			CreatedAt: d.CreatedAt,
+		}
+		if d.LastUsedAt.Valid {
+			device.LastUsedAt = &d.LastUsedAt.Time
+		}
+		export.devices = append(export.devices, device)
+	}
+
+	snapshots, err := q.GetSnapshotsForUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	for _, info := range snapshots {
+		snapshot, err := q.GetSnapshot(ctx, db.GetSnapshotParams{ID: info.ID, UserID: user})
+		if err != nil {
+			return err
+		}
+		export.snapshots = append(export.snapshots, exportedSnapshot{
+			Name:      info.Name,
+			Automatic: info.Automatic,
+			Contents:  snapshot.Contents,
+			CreatedAt: info.CreatedAt,
+		})
+	}
+
+	favorites, err := q.GetFavoritesForUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	for _, f := range favorites {
+		export.favorites = append(export.favorites, exportedFavorite{
+			Template:  f.TemplateName,
+			CreatedAt: f.CreatedAt,
+		})
+	}
+	return nil
+}
+
+// writeUserData writes the zip file, adding a file for each type of data present
+func (s *Server) writeUserData(w io.Writer, export *userDataExport) error {
+	zw := zip.NewWriter(w)
+	addFile := func(name string, encode func(io.Writer) error) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: s.now(),
+		})
+		if err != nil {
+			return err
+		}
+		return encode(f)
+	}
+	addJSON := func(name string, value any) error {
+		return addFile(name, func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(value)
+		})
+	}
+
+	if export.list != nil {
+		if err := addFile("filter-list.yaml", func(w io.Writer) error {
+			return s.encodeListExport(w, export.listToken, export.list)
+		}); err != nil {
+			return err
+		}
+	}
+	if len(export.instances) > 0 {
+		if err := addJSON("filter-history.json", export.instances); err != nil {
+			return err
+		}
+	}
+	if export.preferences != nil {
+		if err := addJSON("preferences.json", export.preferences); err != nil {
+			return err
+		}
+	}
+	if len(export.apiTokens) > 0 {
+		if err := addJSON("api-tokens.json", export.apiTokens); err != nil {
+			return err
+		}
+	}
+	if len(export.sessions) > 0 {
+		if err := addJSON("sessions.json", export.sessions); err != nil {
+			return err
+		}
+	}
+	if export.webhook != nil {
+		if err := addJSON("webhook.json", export.webhook); err != nil {
+			return err
+		}
+	}
+	if len(export.feedback) > 0 {
+		if err := addJSON("feedback.json", export.feedback); err != nil {
+			return err
+		}
+	}
+	if len(export.proposals) > 0 {
+		if err := addJSON("proposals.json", export.proposals); err != nil {
+			return err
+		}
+	}
+	if len(export.shareTokens) > 0 {
+		if err := addJSON("share-tokens.json", export.shareTokens); err != nil {
+			return err
+		}
+	}
+	if len(export.devices) > 0 {
+		if err := addJSON("devices.json", export.devices); err != nil {
+			return err
+		}
+	}
+	if len(export.snapshots) > 0 {
+		if err := addJSON("snapshots.json", export.snapshots); err != nil {
+			return err
+		}
+	}
+	if len(export.favorites) > 0 {
+		if err := addJSON("favorites.json", export.favorites); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readExportZip(t *testing.T, rec *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		contents, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		files[f.Name] = string(contents)
+	}
+	return files
+}
+
+func (s *ServerTestSuite) TestExportUserData_OK() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   map[string]interface{}{"one": "blep"},
+	}))
+	token := s.createApiToken("read")
+	_, err := s.store.InitUserPreferences(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "/user/data-export", nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+		files := readExportZip(t, rec)
+		assert.Len(t, files, 5)
+		assert.Contains(t, files["filter-list.yaml"], "template: filter2")
+		assert.NotContains(t, files["api-tokens.json"], token)
+
+		var instances []exportedInstance
+		require.NoError(t, json.Unmarshal([]byte(files["filter-history.json"]), &instances))
+		require.Len(t, instances, 1)
+		assert.Equal(t, "filter2", instances[0].Template)
+		assert.Equal(t, map[string]interface{}{"one": "blep"}, instances[0].Params)
+
+		var prefs exportedPreferences
+		require.NoError(t, json.Unmarshal([]byte(files["preferences.json"]), &prefs))
+		assert.Equal(t, s.server.filters.Hash("filter2"), prefs.TemplateVersions["filter2"])
+
+		var tokens []exportedApiToken
+		require.NoError(t, json.Unmarshal([]byte(files["api-tokens.json"]), &tokens))
+		require.Len(t, tokens, 1)
+		assert.Equal(t, []string{"read"}, tokens[0].Scopes)
+
+		var devices []exportedDevice
+		require.NoError(t, json.Unmarshal([]byte(files["devices.json"]), &devices))
+		require.Len(t, devices, 1)
+		assert.Equal(t, "default", devices[0].Label)
+	})
+}
+
+func (s *ServerTestSuite) TestExportUserData_BannedUser() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+	s.setUserBanned()
+
+	req := httptest.NewRequest(http.MethodGet, "/user/data-export", nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.Contains(t, readExportZip(t, rec), "filter-list.yaml")
+	})
+
+	req = httptest.NewRequest(http.MethodGet, "/user/account", nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestExportUserData_Ephemeral() {
+	cookie := s.startEphemeralList()
+	req := httptest.NewRequest(http.MethodGet, "/user/data-export", nil)
+	req.AddCookie(cookie)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
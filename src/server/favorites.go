@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// getFavorites returns the names of the templates starred by a user, with or without an instance
+func getFavorites(ctx context.Context, q db.Querier, user string) (map[string]bool, error) {
+	favorites, err := q.GetFavoritesForUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool, len(favorites))
+	for _, f := range favorites {
+		out[f.TemplateName] = true
+	}
+	return out, nil
+}
+
+// toggleFavorite stars or unstars a template, then redirects to the template page
+func (s *Server) toggleFavorite(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return errors.New("invalid user session")
+	}
+	name := c.Param("name")
+	starred := c.FormValue("starred") == "true"
+
+	if starred {
+		// Templates that have been removed can still be unstarred
+		if !s.config().filters.Has(name) {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		if err := s.store.AddFavorite(c.Request().Context(), db.AddFavoriteParams{
+			UserID:       user,
+			TemplateName: name,
+		}); err != nil {
+			return err
+		}
+	} else if err := s.store.DeleteFavorite(c.Request().Context(), db.DeleteFavoriteParams{
+		UserID:       user,
+		TemplateName: name,
+	}); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("view-filter", name))
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *ServerTestSuite) toggleFavoriteRequest(name string, starred bool) *http.Request {
+	f := make(url.Values)
+	if starred {
+		f.Add("starred", "true")
+	}
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/filters/"+name+"/favorite", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) addFavorite(name string) {
+	require.NoError(s.T(), s.store.AddFavorite(context.Background(), db.AddFavoriteParams{
+		UserID:       s.user,
+		TemplateName: name,
+	}))
+}
+
+func (s *ServerTestSuite) TestToggleFavorite_OK() {
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters/filter2")
+	s.runRequest(s.toggleFavoriteRequest("filter2", true), assertOk)
+	favorites, err := getFavorites(context.Background(), s.store, s.user)
+	require.NoError(s.T(), err)
+	s.Equal(map[string]bool{"filter2": true}, favorites)
+
+	// Starring twice is a no-op
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters/filter2")
+	s.runRequest(s.toggleFavoriteRequest("filter2", true), assertOk)
+
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters/filter2")
+	s.runRequest(s.toggleFavoriteRequest("filter2", false), assertOk)
+	favorites, err = getFavorites(context.Background(), s.store, s.user)
+	require.NoError(s.T(), err)
+	s.Empty(favorites)
+	s.requireInstanceCount("filter2", 0)
+}
+
+func (s *ServerTestSuite) TestToggleFavorite_UnknownTemplate() {
+	s.runRequest(s.toggleFavoriteRequest("unknown", true), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestToggleFavorite_Ephemeral() {
+	cookie := s.startEphemeralList()
+	req := s.toggleFavoriteRequest("filter2", true)
+	req.AddCookie(cookie)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestListFilters_Favorites() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+	list, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	s.addFavorite("filter1")
+	s.addFavorite("filter3")
+
+	s.expectRender("list-filters", pages.ContextData{
+		"filter_tags":       filterTags,
+		"favorites":         map[string]bool{"filter1": true, "filter3": true},
+		"favorites_only":    true,
+		"active_filters":    []*filters.Template{filter1},
+		"available_filters": []*filters.Template{filter3},
+		"list_downloaded":   false,
+		"list_token":        list.Token.String(),
+	})
+	s.runRequest(httptest.NewRequest(http.MethodGet, "/filters?favorites", nil), assertOk)
+}
+
+func (s *ServerTestSuite) TestViewFilter_Starred() {
+	s.addFavorite("filter2")
+	s.expectRender("view-filter", pages.ContextData{
+		"filter":    filter2,
+		"rendered":  filter2DefaultOutput,
+		"params":    filter2Defaults,
+		"test_mode": false,
+		"starred":   true,
+	})
+	s.runRequest(httptest.NewRequest(http.MethodGet, "/filters/filter2", nil), assertOk)
+}
+
+func (s *ServerTestSuite) TestApiV1_ListFiltersStarred() {
+	s.addInstance(s.user, "filter1", nil)
+	s.addInstance(s.user, "filter2", nil)
+	s.addFavorite("filter2")
+	s.addFavorite("filter3")
+
+	req := newApiV1Request(http.MethodGet, "/api/v1/filters", "")
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		var body apiV1FilterList
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		require.Len(t, body.Filters, 2)
+		for _, f := range body.Filters {
+			assert.Equal(t, f.Template == "filter2", f.Starred, f.Template)
+		}
+		assert.Equal(t, []string{"filter2", "filter3"}, body.Favorites)
+	})
+
+	req = newApiV1Request(http.MethodGet, "/api/v1/filters/filter2", "")
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.True(t, decodeApiV1Filter(t, rec).Starred)
+	})
+}
+
+func (s *ServerTestSuite) TestFavorites_ExportAndDelete() {
+	s.addFavorite("filter3")
+	s.runRequest(httptest.NewRequest(http.MethodGet, "/user/data-export", nil), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		var favorites []exportedFavorite
+		require.NoError(t, json.Unmarshal([]byte(readExportZip(t, rec)["favorites.json"]), &favorites))
+		require.Len(t, favorites, 1)
+		assert.Equal(t, "filter3", favorites[0].Template)
+	})
+
+	f := make(url.Values)
+	f.Add("confirm", deleteAccountPhrase)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/delete-account", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/")
+	s.runRequest(req, assertOk)
+	favorites, err := getFavorites(context.Background(), s.store, s.user)
+	require.NoError(s.T(), err)
+	s.Empty(favorites)
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// Users can report whether a template works for them, to help maintainers spot broken filters.
+// Comments are only displayed to admins, who can strip abusive ones.
+const (
+	feedbackInterval     = 24 * time.Hour
+	feedbackPeriod       = 30 * 24 * time.Hour
+	feedbackCommentLimit = 280
+	feedbackCommentCount = 50
+	auditFeedbackStrip   = "feedback_strip"
+)
+
+// feedbackBrowsers and feedbackAdblockers must match the choices of the view-filter-feedback form
+var (
+	feedbackBrowsers   = []string{"chrome", "edge", "firefox", "safari", "other"}
+	feedbackAdblockers = []string{"ublock-origin", "adblock-plus", "adguard", "other"}
+)
+
+// feedbackComment holds a feedback comment displayed in the admin view
+type feedbackComment struct {
+	ID        int32
+	UserID    string
+	Template  string
+	Working   bool
+	Browser   string
+	Adblocker string
+	Comment   string
+	CreatedAt string
+}
+
+// cleanFeedbackComment trims the comment and removes control characters, it returns false if it is too long
+func cleanFeedbackComment(comment string) (string, bool) {
+	comment = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, comment))
+	return comment, len([]rune(comment)) <= feedbackCommentLimit
+}
+
+func isFeedbackChoice(choices []string, value string) bool {
+	for _, choice := range choices {
+		if value == choice {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFeedback validates the feedback form, returning a 400 error on invalid values
+func parseFeedback(c echo.Context) (db.AddTemplateFeedbackParams, error) {
+	var params db.AddTemplateFeedbackParams
+	formParams, err := c.FormParams()
+	if err != nil {
+		return params, err
+	}
+	switch formParams.Get("working") {
+	case "yes":
+		params.Working = true
+	case "no":
+		params.Working = false
+	default:
+		return params, echo.NewHTTPError(http.StatusBadRequest, "invalid feedback")
+	}
+	params.Browser = formParams.Get("browser")
+	params.Adblocker = formParams.Get("adblocker")
+	if !isFeedbackChoice(feedbackBrowsers, params.Browser) || !isFeedbackChoice(feedbackAdblockers, params.Adblocker) {
+		return params, echo.NewHTTPError(http.StatusBadRequest, "invalid browser or adblocker")
+	}
+	var valid bool
+	if params.Comment, valid = cleanFeedbackComment(formParams.Get("comment")); !valid {
+		return params, echo.NewHTTPError(http.StatusBadRequest,
+			"comments are limited to "+strconv.Itoa(feedbackCommentLimit)+" characters")
+	}
+	return params, nil
+}
+
+// sendFeedback stores the feedback of a user on a template, at most once a day per template
+func (s *Server) sendFeedback(c echo.Context) error {
+	filter, err := s.config().filters.Get(c.Param("name"))
+	if err != nil {
+		return echo.ErrNotFound
+	}
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	params, err := parseFeedback(c)
+	if err != nil {
+		return err
+	}
+	params.UserID = user
+	params.TemplateName = filter.Name
+
+	recent, err := s.store.CountRecentFeedback(c.Request().Context(), db.CountRecentFeedbackParams{
+		UserID:       user,
+		TemplateName: filter.Name,
+		CreatedAt:    s.now().Add(-feedbackInterval),
+	})
+	if err != nil {
+		return err
+	}
+	if recent > 0 {
+		return echo.NewHTTPError(http.StatusTooManyRequests, "you already sent feedback on this filter today")
+	}
+	if err = s.store.AddTemplateFeedback(c.Request().Context(), params); err != nil {
+		return err
+	}
+	_ = s.statsd.Incr("letsblockit.template_feedback", workingTag.tags(params.Working), 1)
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("view-filter", filter.Name)+"?feedback_sent=true")
+}
+
+// templateFeedback lists the feedback counts per template over the last thirty days.
+// Admins also get the recent comments.
+func (s *Server) templateFeedback(c echo.Context) error {
+	counts, err := s.store.GetFeedbackCounts(c.Request().Context(), s.now().Add(-feedbackPeriod))
+	if err != nil {
+		return err
+	}
+	hc := s.buildPageContext(c, "Filter template feedback")
+	hc.Add("feedback_counts", counts)
+	hc.Add("feedback_days", int(feedbackPeriod.Hours()/24))
+
+	if auth.IsAdmin(c) {
+		stored, err := s.store.GetRecentFeedbackComments(c.Request().Context(), feedbackCommentCount)
+		if err != nil {
+			return err
+		}
+		comments := make([]feedbackComment, 0, len(stored))
+		for _, f := range stored {
+			comments = append(comments, feedbackComment{
+				ID:        f.ID,
+				UserID:    f.UserID,
+				Template:  f.TemplateName,
+				Working:   f.Working,
+				Browser:   f.Browser,
+				Adblocker: f.Adblocker,
+				Comment:   f.Comment,
+				CreatedAt: f.CreatedAt.Format(time.RFC3339),
+			})
+		}
+		hc.NoBoost = true
+		hc.Add("feedback_comments", comments)
+	}
+	return s.pages.Render(c, "template-feedback", hc)
+}
+
+// adminStripFeedback removes the comment of a feedback, keeping its working status in the counts
+func (s *Server) adminStripFeedback(c echo.Context) error {
+	id, err := strconv.ParseInt(c.FormValue("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid arguments")
+	}
+	if err = s.store.StripFeedbackComment(c.Request().Context(), int32(id)); err != nil {
+		return err
+	}
+	if err = s.store.LogAdminAction(c.Request().Context(), db.LogAdminActionParams{
+		AdminID:  auth.GetRealUserId(c),
+		Action:   auditFeedbackStrip,
+		TargetID: strconv.FormatInt(id, 10),
+	}); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("template-feedback"))
+}
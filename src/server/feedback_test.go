@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanFeedbackComment(t *testing.T) {
+	comment, valid := cleanFeedbackComment("  broken\non\tyoutube \x00 ")
+	assert.True(t, valid)
+	assert.Equal(t, "broken on youtube", comment)
+
+	_, valid = cleanFeedbackComment(strings.Repeat("é", feedbackCommentLimit))
+	assert.True(t, valid, "the limit is in characters, not bytes")
+	_, valid = cleanFeedbackComment(strings.Repeat("a", feedbackCommentLimit+1))
+	assert.False(t, valid)
+}
+
+func TestParseFeedback(t *testing.T) {
+	parse := func(working, browser, adblocker, comment string) (db.AddTemplateFeedbackParams, error) {
+		f := make(url.Values)
+		f.Add("working", working)
+		f.Add("browser", browser)
+		f.Add("adblocker", adblocker)
+		f.Add("comment", comment)
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		return parseFeedback(echo.New().NewContext(req, httptest.NewRecorder()))
+	}
+
+	params, err := parse("no", "firefox", "ublock-origin", " hides the wrong block ")
+	require.NoError(t, err)
+	assert.Equal(t, db.AddTemplateFeedbackParams{
+		Working:   false,
+		Browser:   "firefox",
+		Adblocker: "ublock-origin",
+		Comment:   "hides the wrong block",
+	}, params)
+
+	for name, values := range map[string][]string{
+		"unknown status":    {"maybe", "firefox", "ublock-origin", ""},
+		"unknown browser":   {"yes", "netscape", "ublock-origin", ""},
+		"unknown adblocker": {"yes", "firefox", "unknown", ""},
+		"long comment":      {"yes", "firefox", "adguard", strings.Repeat("a", feedbackCommentLimit+1)},
+	} {
+		_, err = parse(values[0], values[1], values[2], values[3])
+		if assert.Error(t, err, name) {
+			assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code, name)
+		}
+	}
+}
+
+func (s *ServerTestSuite) sendFeedback(filter string, working string, comment string) *http.Request {
+	f := make(url.Values)
+	f.Add("working", working)
+	f.Add("browser", "firefox")
+	f.Add("adblocker", "ublock-origin")
+	f.Add("comment", comment)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/filters/"+filter+"/feedback", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) TestSendFeedback_OK() {
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters/filter1?feedback_sent=true")
+	s.runRequest(s.sendFeedback("filter1", "no", "broken"), assertOk)
+
+	// A second feedback on the same template is rejected, other templates are accepted
+	s.runRequest(s.sendFeedback("filter1", "yes", ""), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	})
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters/filter2?feedback_sent=true")
+	s.runRequest(s.sendFeedback("filter2", "yes", ""), assertOk)
+
+	counts, err := s.store.GetFeedbackCounts(context.Background(), fixedNow.Add(-time.Hour))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []db.GetFeedbackCountsRow{
+		{TemplateName: "filter1", Working: 0, NotWorking: 1},
+		{TemplateName: "filter2", Working: 1, NotWorking: 0},
+	}, counts)
+}
+
+func (s *ServerTestSuite) TestSendFeedback_UnknownTemplate() {
+	s.runRequest(s.sendFeedback("unknown", "no", ""), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestSendFeedback_Anonymous() {
+	s.user = ""
+	s.runRequest(s.sendFeedback("filter1", "no", ""), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestTemplateFeedback_CountsOnly() {
+	require.NoError(s.T(), s.store.AddTemplateFeedback(context.Background(), db.AddTemplateFeedbackParams{
+		UserID:       s.user,
+		TemplateName: "filter1",
+		Browser:      "firefox",
+		Adblocker:    "ublock-origin",
+		Comment:      "private comment",
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/filters/feedback", nil)
+	s.expectRender("template-feedback", pages.ContextData{
+		"feedback_counts": []db.GetFeedbackCountsRow{{TemplateName: "filter1", NotWorking: 1}},
+		"feedback_days":   30,
+	})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestTemplateFeedback_AdminStrip() {
+	s.setUserAdmin()
+	require.NoError(s.T(), s.store.AddTemplateFeedback(context.Background(), db.AddTemplateFeedbackParams{
+		UserID:       s.user,
+		TemplateName: "filter1",
+		Working:      true,
+		Browser:      "firefox",
+		Adblocker:    "ublock-origin",
+		Comment:      "abusive comment",
+	}))
+	stored, err := s.store.GetRecentFeedbackComments(context.Background(), 10)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), stored, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/filters/feedback", nil)
+	s.expectRender("template-feedback", pages.ContextData{
+		"feedback_counts": []db.GetFeedbackCountsRow{{TemplateName: "filter1", Working: 1}},
+		"feedback_days":   30,
+		"feedback_comments": []feedbackComment{{
+			ID:        stored[0].ID,
+			UserID:    s.user,
+			Template:  "filter1",
+			Working:   true,
+			Browser:   "firefox",
+			Adblocker: "ublock-origin",
+			Comment:   "abusive comment",
+			CreatedAt: stored[0].CreatedAt.Format(time.RFC3339),
+		}},
+	})
+	s.runRequest(req, assertOk)
+
+	f := make(url.Values)
+	f.Add("id", strconv.Itoa(int(stored[0].ID)))
+	f.Add(csrfLookup, s.csrf)
+	req = httptest.NewRequest(http.MethodPost, "/admin/feedback/strip", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters/feedback")
+	s.runRequest(req, assertOk)
+
+	stored, err = s.store.GetRecentFeedbackComments(context.Background(), 10)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), stored)
+	actions, err := s.store.GetAdminActions(context.Background(), 10)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), actions, 1)
+	assert.Equal(s.T(), auditFeedbackStrip, actions[0].Action)
+}
+
+func (s *ServerTestSuite) TestAdminStripFeedback_NotAdmin() {
+	f := make(url.Values)
+	f.Add("id", "1")
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/admin/feedback/strip", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
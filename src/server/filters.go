@@ -22,20 +22,38 @@ const (
 func (s *Server) listFilters(c echo.Context) error {
 	tag := c.Param("tag")
 	hc := s.buildPageContext(c, "Available uBlock filter templates")
+	repo := s.config().filters
 	if tag != "" {
 		hc.Title = "Available filter templates for " + tag
 		hc.Add("tag_search", tag)
 	}
 
-	hc.Add("filter_tags", s.filters.GetTags())
+	hc.Add("filter_tags", repo.GetTags())
+	if s.options.SuggestionRefresh > 0 {
+		hc.Add("suggestions_enabled", true)
+	}
+	if counts := s.usageCounts(); len(counts) > 0 {
+		hc.Add("usage_counts", counts)
+	}
 	var activeNames map[string]struct{}
+	var favorites map[string]bool
+	favoritesOnly := c.QueryParams().Has("favorites")
 	if hc.UserLoggedIn {
 		var updatedFilters map[string]bool
 		var testingFilters map[string]bool
+		var displayNames map[string]string
+		var instanceNames []string
 		activeNames = make(map[string]struct{})
 		instances, _ := s.store.GetInstancesForUser(c.Request().Context(), hc.UserID)
 		for _, instance := range instances {
 			activeNames[instance.TemplateName] = struct{}{}
+			instanceNames = append(instanceNames, instance.TemplateName)
+			if name := s.instanceDisplayName(repo, instance); name != "" {
+				if displayNames == nil {
+					displayNames = make(map[string]string)
+				}
+				displayNames[instance.TemplateName] = name
+			}
 			if s.hasMissingParams(instance) {
 				if updatedFilters == nil {
 					updatedFilters = make(map[string]bool)
@@ -54,6 +72,20 @@ func (s *Server) listFilters(c echo.Context) error {
 				hc.Add("list_token", info.Token.String())
 				hc.Add("list_downloaded", info.DownloadedAt.Valid)
 			}
+			updates, err := s.getTemplateUpdates(c.Request().Context(), s.store, hc.UserID, instanceNames)
+			if err != nil {
+				return err
+			}
+			if len(updates) > 0 {
+				hc.Add("template_updates", updates)
+			}
+			if statuses, err := s.store.GetInstanceRenderStatus(c.Request().Context(), hc.UserID); err == nil && len(statuses) > 0 {
+				views := make(map[string]renderStatusView, len(statuses))
+				for _, status := range statuses {
+					views[status.TemplateName] = newRenderStatusView(status.RenderedAt, status.RenderError)
+				}
+				hc.Add("render_statuses", views)
+			}
 		}
 		if len(updatedFilters) > 0 {
 			hc.Add("updated_filters", updatedFilters)
@@ -61,19 +93,41 @@ func (s *Server) listFilters(c echo.Context) error {
 		if len(testingFilters) > 0 {
 			hc.Add("testing_filters", testingFilters)
 		}
+		if len(displayNames) > 0 {
+			hc.Add("display_names", displayNames)
+		}
+		if !hc.UserIsEphemeral {
+			var err error
+			if favorites, err = getFavorites(c.Request().Context(), s.store, hc.UserID); err != nil {
+				return err
+			}
+			if len(favorites) > 0 {
+				hc.Add("favorites", favorites)
+			}
+		}
+		if removed := s.undo.get(hc.UserID, s.now()); len(removed) > 0 {
+			hc.Add("removed_filters", removed)
+		}
+	}
+	if favoritesOnly {
+		hc.Title = "Your favorite filter templates"
+		hc.Add("favorites_only", true)
 	}
 
 	// Template and group filters, or quick return on homepage
-	if len(activeNames) == 0 && len(tag) == 0 {
-		hc.Add("available_filters", s.filters.GetAll())
+	if len(activeNames) == 0 && len(tag) == 0 && !favoritesOnly {
+		hc.Add("available_filters", repo.GetAll())
 	} else {
 		var active, available []*filters.Template
-		for _, f := range s.filters.GetAll() {
+		for _, f := range repo.GetAll() {
 			if tag != "" {
 				if !f.HasTag(tag) {
 					continue
 				}
 			}
+			if favoritesOnly && !favorites[f.Name] {
+				continue
+			}
 			if _, ok := activeNames[f.Name]; ok {
 				active = append(active, f)
 			} else {
@@ -86,19 +140,34 @@ func (s *Server) listFilters(c echo.Context) error {
 	return s.pages.Render(c, "list-filters", hc)
 }
 
+// usageCounts returns the rounded usage counts to display, or nil if disabled
+func (s *Server) usageCounts() map[string]string {
+	if !s.options.UsageCounts {
+		return nil
+	}
+	return s.suggestions.Load().UsageCounts()
+}
+
 func (s *Server) viewFilter(c echo.Context) error {
-	filter, err := s.filters.Get(c.Param("name"))
+	repo := s.config().filters
+	filter, err := repo.Get(c.Param("name"))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound)
 	}
 	hc := s.buildPageContext(c, filter.Title)
 	hc.Add("filter", filter)
+	if count, found := s.usageCounts()[filter.Name]; found {
+		hc.Add("usage_count", count)
+	}
 
 	// Parse filters param and render output if non-empty
 	instance, action, err := parseFilterParams(c, filter)
 	if err != nil {
 		return err
 	}
+	if action != actionRender && hc.UserIsImpersonated {
+		return echo.NewHTTPError(http.StatusForbidden, "changes are disabled while impersonating a user")
+	}
 
 	switch {
 	case hc.UserLoggedIn && action == actionSave:
@@ -112,12 +181,23 @@ func (s *Server) viewFilter(c echo.Context) error {
 		}
 		hc.Add("saved_ok", true)
 		hc.Add("has_instance", true)
+		overlaps, err := s.findOverlapWarnings(c.Request().Context(), hc.UserID, filter.Name)
+		if err != nil {
+			return err
+		}
+		if len(overlaps) > 0 {
+			hc.Add("overlaps", overlaps)
+		}
+		clashes, err := s.findClashWarnings(c.Request().Context(), hc.UserID, instance)
+		if err != nil {
+			return err
+		}
+		if len(clashes) > 0 {
+			hc.Add("clashes", clashes)
+		}
 	case hc.UserLoggedIn && action == actionDelete:
-		// Handle deletion if requested, remove all instances matching a given name
-		if err = s.store.DeleteInstance(c.Request().Context(), db.DeleteInstanceParams{
-			UserID:       hc.UserID,
-			TemplateName: filter.Name,
-		}); err != nil {
+		// Handle deletion if requested, the instance can be restored during the undo grace period
+		if err = s.removeInstance(c.Request().Context(), hc.UserID, filter.Name, filter.Title); err != nil {
 			return err
 		}
 		return s.pages.RedirectToPage(c, "list-filters")
@@ -136,6 +216,15 @@ func (s *Server) viewFilter(c echo.Context) error {
 				}
 			}
 			instance.TestMode = stored.TestMode
+			if stored.RenderedAt.Valid {
+				hc.Add("render_status", newRenderStatusView(stored.RenderedAt.Time, stored.RenderError))
+			}
+			if stored.Pinned {
+				hc.Add("pinned", true)
+				if stored.TemplateHash != "" && stored.TemplateHash != repo.Hash(filter.Name) {
+					hc.Add("pinned_outdated", true)
+				}
+			}
 		case db.NotFound: // ok
 		default:
 			return err
@@ -145,13 +234,7 @@ func (s *Server) viewFilter(c echo.Context) error {
 	if instance.Params == nil {
 		// If no params found, inject the default values
 		if len(filter.Params) > 0 {
-			instance.Params = make(map[string]interface{})
-			for _, param := range filter.Params {
-				instance.Params[param.Name] = param.Default
-				for _, preset := range param.Presets {
-					instance.Params[param.BuildPresetParamName(preset.Name)] = preset.Default
-				}
-			}
+			instance.Params = filter.DefaultParams()
 		}
 	} else {
 		// Check whether new params have been added
@@ -174,17 +257,30 @@ func (s *Server) viewFilter(c echo.Context) error {
 
 	// Render the filter template
 	var buf strings.Builder
-	if err = s.filters.Render(&buf, instance); err != nil {
+	if err = repo.Render(&buf, instance); err != nil {
 		return err
 	}
 	hc.Add("rendered", buf.String())
 	hc.Add("params", instance.Params)
 	hc.Add("test_mode", instance.TestMode)
+	if c.QueryParam("feedback_sent") != "" {
+		hc.Add("feedback_sent", true)
+	}
+	if hc.UserLoggedIn && !hc.UserIsEphemeral {
+		favorites, err := getFavorites(c.Request().Context(), s.store, hc.UserID)
+		if err != nil {
+			return err
+		}
+		if favorites[filter.Name] {
+			hc.Add("starred", true)
+		}
+	}
 	return s.pages.Render(c, "view-filter", hc)
 }
 
 func (s *Server) viewFilterRender(c echo.Context) error {
-	filter, err := s.filters.Get(c.Param("name"))
+	repo := s.config().filters
+	filter, err := repo.Get(c.Param("name"))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound)
 	}
@@ -205,7 +301,7 @@ func (s *Server) viewFilterRender(c echo.Context) error {
 
 	// Render the filter template
 	var buf strings.Builder
-	if err = s.filters.Render(&buf, instance); err != nil {
+	if err = repo.Render(&buf, instance); err != nil {
 		return err
 	}
 	hc := s.buildPageContext(c, "")
@@ -232,7 +328,9 @@ func (s *Server) upsertFilterParams(c echo.Context, user string, instance *filte
 			return err
 		}
 	}
-	return s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+	event := webhookInstanceUpdated
+	hash := s.config().filters.Hash(instance.Template)
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
 		count, err := q.CountInstances(ctx, db.CountInstancesParams{
 			UserID:       user,
 			TemplateName: instance.Template,
@@ -241,26 +339,41 @@ func (s *Server) upsertFilterParams(c echo.Context, user string, instance *filte
 			return err
 		}
 		if count == 0 {
+			event = webhookInstanceCreated
+			if err = checkEphemeralLimit(ctx, q, user); err != nil {
+				return err
+			}
 			if listCount, _ := q.CountListsForUser(ctx, user); listCount == 0 {
 				if _, err := q.CreateListForUser(ctx, user); err != nil {
 					return err
 				}
 			}
-			return q.CreateInstance(ctx, db.CreateInstanceParams{
+			err = q.CreateInstance(ctx, db.CreateInstanceParams{
 				UserID:       user,
 				TemplateName: instance.Template,
 				Params:       out,
 				TestMode:     instance.TestMode,
+				TemplateHash: hash,
 			})
 		} else {
-			return q.UpdateInstance(ctx, db.UpdateInstanceParams{
+			err = q.UpdateInstance(ctx, db.UpdateInstanceParams{
 				UserID:       user,
 				TemplateName: instance.Template,
 				Params:       out,
 				TestMode:     instance.TestMode,
+				TemplateHash: hash,
 			})
 		}
-	})
+		if err != nil {
+			return err
+		}
+		// Saving an instance means the user has seen the latest version of the template
+		return s.ackTemplate(ctx, q, user, instance.Template)
+	}); err != nil {
+		return err
+	}
+	s.notifyListChange(user, event, instance.Template)
+	return nil
 }
 
 func parseFilterParams(c echo.Context, filter *filters.Template) (*filters.Instance, filterAction, error) {
@@ -314,8 +427,21 @@ func parseFilterParams(c echo.Context, filter *filters.Template) (*filters.Insta
 	return instance, action, err
 }
 
+// instanceDisplayName returns the display name of an instance, or an empty string if its template has none
+func (s *Server) instanceDisplayName(repo *filters.Repository, instance db.GetInstancesForUserRow) string {
+	filter, err := repo.Get(instance.TemplateName)
+	if err != nil || !filter.HasDisplayName() {
+		return ""
+	}
+	params := make(map[string]interface{})
+	if err := instance.Params.AssignTo(&params); err != nil {
+		return ""
+	}
+	return filter.DisplayName(params)
+}
+
 func (s *Server) hasMissingParams(instance db.GetInstancesForUserRow) bool {
-	filter, err := s.filters.Get(instance.TemplateName)
+	filter, err := s.config().filters.Get(instance.TemplateName)
 	if err != nil || len(filter.Params) == 0 {
 		return false
 	}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+)
+
+// Lists can be downloaded in other formats than uBlock's, by adding a suffix to their URL. These formats
+// are translated from the rendered list, keeping the rules they can express. The number of rules that could
+// not be translated is returned in a header.
+const untranslatedHeader = "X-Untranslated-Rules"
+
+// listFormat writes a rendered list in another format, body is nil for the lists of banned users
+type listFormat struct {
+	name         string // Added to the etag
+	suffix       string
+	write        func(s *Server, c echo.Context, token uuid.UUID, body []byte) error
+	downloadTags boolTagSet
+}
+
+func newListFormat(name, suffix string, write func(s *Server, c echo.Context, token uuid.UUID, body []byte) error) *listFormat {
+	return &listFormat{
+		name:         name,
+		suffix:       suffix,
+		write:        write,
+		downloadTags: newBoolTagSet([]string{"format:" + name}, "etag_match"),
+	}
+}
+
+var listFormats = []*listFormat{
+	newListFormat("safari", ".safari.json", (*Server).writeSafariList),
+	newListFormat("dnsmasq", ".dnsmasq.conf", (*Server).writeDnsmasqList),
+	newListFormat("unbound", ".unbound.conf", (*Server).writeUnboundList),
+}
+
+// findListFormat returns the format matching the suffix of a list URL, and the list token without it
+func findListFormat(name string) (*listFormat, string) {
+	for _, format := range listFormats {
+		if strings.HasSuffix(name, format.suffix) {
+			return format, strings.TrimSuffix(name, format.suffix)
+		}
+	}
+	return nil, name
+}
+
+// renderListFormat renders a list and converts it to another format. Like renderList, knowing the token is enough.
+func (s *Server) renderListFormat(c echo.Context, token uuid.UUID, format *listFormat) error {
+	requestETags, listETag := getRequestETags(c), ""
+	var banned bool
+	var listID int32
+	var storedInstances []db.GetInstancesForListRow
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		storedList, e := q.GetListForToken(ctx, token)
+		switch {
+		case e == db.NotFound:
+			s.countInvalidToken(false)
+			return echo.ErrNotFound
+		case e != nil:
+			return fmt.Errorf("failed to get list: %w", e)
+		case s.bans.IsBanned(storedList.UserID):
+			banned = true
+			return nil
+		}
+		if c.Request().Header.Get("Referer") == "" {
+			if e = q.MarkListDownloaded(ctx, token); e != nil {
+				return fmt.Errorf("failed to mark list download: %w", e)
+			}
+		}
+		// The title is not rendered in the other formats, it does not change their etag
+		listETag = s.buildContentETag(storedList) + "-" + format.name
+		if requestETags.match(listETag) {
+			return nil
+		}
+		listID = storedList.ID
+		storedInstances, e = q.GetInstancesForList(ctx, storedList.ID)
+		if e != nil {
+			return fmt.Errorf("failed to get instances: %w", e)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Serve an empty list to banned users, for subscribers to drop its rules
+	if banned {
+		listETag = bannedListETag + "-" + format.name
+	}
+	etagMatch := requestETags.match(listETag)
+	_ = s.statsd.Incr("letsblockit.list_format_download", format.downloadTags.of(etagMatch), 1)
+	if etagMatch {
+		return c.NoContent(http.StatusNotModified)
+	}
+	c.Response().Header().Set("Etag", weakETag(listETag))
+	if banned {
+		return format.write(s, c, token, nil)
+	}
+
+	body, err := s.renderListBody(c.Request().Context(), c.Logger(), listID, token, filters.DefaultListTitle, storedInstances, false)
+	if err != nil {
+		return err
+	}
+	return format.write(s, c, token, body)
+}
+
+// dnsListHeader is written at the top of the DNS formats, dnsmasq and unbound both use # for comments
+const dnsListHeader = `# Title: letsblock.it - My filters
+# Generated: %s
+# Source list: %s
+# Only the rules blocking whole domains are included, %d other rules could not be converted
+`
+
+// writeDNSList writes the domains blocked by a list, with one line per domain
+func (s *Server) writeDNSList(c echo.Context, token uuid.UUID, body []byte, line func(string) string) error {
+	conversion := filters.ExtractDNSDomains(body)
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+	c.Response().Header().Set(untranslatedHeader, strconv.Itoa(conversion.Untranslated))
+	c.Response().WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprintf(c.Response(), dnsListHeader, s.now().UTC().Format(time.RFC3339), token, conversion.Untranslated); err != nil {
+		return err
+	}
+	for _, domain := range conversion.Domains {
+		if _, err := fmt.Fprintln(c.Response(), line(domain)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDnsmasqList writes an address directive for each blocked domain, with a null address
+func (s *Server) writeDnsmasqList(c echo.Context, token uuid.UUID, body []byte) error {
+	return s.writeDNSList(c, token, body, func(domain string) string {
+		return "address=/" + domain + "/#"
+	})
+}
+
+// writeUnboundList writes a local-zone statement for each blocked domain, to include in the server clause
+func (s *Server) writeUnboundList(c echo.Context, token uuid.UUID, body []byte) error {
+	return s.writeDNSList(c, token, body, func(domain string) string {
+		return `local-zone: "` + domain + `." always_nxdomain`
+	})
+}
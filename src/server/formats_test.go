@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *ServerTestSuite) TestRenderSafariList_OK() {
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+
+	req := httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String()+".safari.json", nil)
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("1", rec.Header().Get(untranslatedHeader))
+	s.Empty(rec.Header().Get("Warning"))
+	s.JSONEq(`[{"trigger": {"url-filter": ".*", "if-domain": ["*my.do.main"]},
+		"action": {"type": "css-display-none", "selector": "#install-prompt-`+token.String()+`"}}]`, rec.Body.String())
+	etag := rec.Header().Get("Etag")
+	s.True(strings.HasPrefix(etag, `W/"`))
+	s.True(strings.HasSuffix(etag, `-safari"`))
+
+	req = httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String()+".safari.json", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(http.StatusNotModified, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String()+".safari.json?report", nil)
+	rec = httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(http.StatusOK, rec.Code)
+	var report safariReport
+	require.NoError(s.T(), json.NewDecoder(rec.Body).Decode(&report))
+	s.Equal(safariReport{Rules: 1, MaxRules: filters.SafariMaxRules, Untranslated: []string{"hello from one"}}, report)
+}
+
+func (s *ServerTestSuite) TestRenderSafariList_NotFound() {
+	for _, name := range []string{"invalid.safari.json", "ce4ea5e5-1a24-4a68-9b4f-2b5a0d22b6bb.safari.json"} {
+		rec := httptest.NewRecorder()
+		s.server.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list/"+name, nil))
+		s.Equal(http.StatusNotFound, rec.Code, name)
+	}
+}
+
+func TestFindListFormat(t *testing.T) {
+	format, name := findListFormat("token.dnsmasq.conf")
+	require.NotNil(t, format)
+	assert.Equal(t, "dnsmasq", format.name)
+	assert.Equal(t, "token", name)
+	format, name = findListFormat("token.txt")
+	assert.Nil(t, format)
+	assert.Equal(t, "token.txt", name)
+}
+
+func TestWriteDNSLists(t *testing.T) {
+	s := &Server{now: func() time.Time { return fixedNow }}
+	token := uuid.New()
+	body := []byte("! comment\n||tracker.net^\nexample.com##.ad\n")
+	header := "# Title: letsblock.it - My filters\n# Generated: " + fixedNow.UTC().Format(time.RFC3339) +
+		"\n# Source list: " + token.String() +
+		"\n# Only the rules blocking whole domains are included, 1 other rules could not be converted\n"
+
+	for expected, write := range map[string]func(*Server, echo.Context, uuid.UUID, []byte) error{
+		"address=/tracker.net/#\n":                       (*Server).writeDnsmasqList,
+		"local-zone: \"tracker.net.\" always_nxdomain\n": (*Server).writeUnboundList,
+	} {
+		rec := httptest.NewRecorder()
+		require.NoError(t, write(s, echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec), token, body))
+		assert.Equal(t, header+expected, rec.Body.String())
+		assert.Equal(t, "1", rec.Header().Get(untranslatedHeader))
+		assert.Equal(t, echo.MIMETextPlainCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	}
+}
+
+func (s *ServerTestSuite) TestRenderDNSLists_OK() {
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+
+	for _, suffix := range []string{".dnsmasq.conf", ".unbound.conf"} {
+		req := httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String()+suffix, nil)
+		rec := httptest.NewRecorder()
+		s.server.echo.ServeHTTP(rec, req)
+		s.Equal(http.StatusOK, rec.Code, suffix)
+		s.Contains(rec.Body.String(), "# Source list: "+token.String(), suffix)
+		s.Equal("1", rec.Header().Get(untranslatedHeader), suffix)
+		etag := rec.Header().Get("Etag")
+
+		req = httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String()+suffix, nil)
+		req.Header.Set("If-None-Match", etag)
+		rec = httptest.NewRecorder()
+		s.server.echo.ServeHTTP(rec, req)
+		s.Equal(http.StatusNotModified, rec.Code, suffix)
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"zgo.at/zcache/v2"
+)
+
+// guessLimiter tracks the invalid list tokens requested by each IP, in a sliding window
+type guessLimiter struct {
+	sync.Mutex
+	limit    int
+	window   time.Duration
+	now      func() time.Time
+	failures *zcache.Cache[string, []time.Time]
+}
+
+// newGuessLimiter returns a limiter allowing limit failures per window, or nil if limit is zero
+func newGuessLimiter(limit int, window time.Duration, now func() time.Time) *guessLimiter {
+	if limit <= 0 || window <= 0 {
+		return nil
+	}
+	return &guessLimiter{
+		limit:    limit,
+		window:   window,
+		now:      now,
+		failures: zcache.New[string, []time.Time](window, window),
+	}
+}
+
+// Blocked returns true if this IP already exceeded the limit during the window
+func (l *guessLimiter) Blocked(ip string) bool {
+	if l == nil {
+		return false
+	}
+	l.Lock()
+	defer l.Unlock()
+	return len(l.recent(ip, l.now())) > l.limit
+}
+
+// Record adds a failure for this IP, and returns true if it exceeded the limit during the window.
+// Only the latest limit+1 failures are kept, as older ones cannot change the outcome.
+func (l *guessLimiter) Record(ip string) bool {
+	if l == nil {
+		return false
+	}
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	failures := append(l.recent(ip, now), now)
+	if len(failures) > l.limit+1 {
+		failures = failures[len(failures)-l.limit-1:]
+	}
+	l.failures.Set(ip, failures)
+	return len(failures) > l.limit
+}
+
+// recent returns the failures of this IP that are still in the window, caller must hold the lock
+func (l *guessLimiter) recent(ip string, now time.Time) []time.Time {
+	start := now.Add(-l.window)
+	previous, _ := l.failures.Get(ip)
+	failures := make([]time.Time, 0, len(previous)+1)
+	for _, f := range previous {
+		if f.After(start) {
+			failures = append(failures, f)
+		}
+	}
+	return failures
+}
+
+// limitListGuesses returns a 429 error to IPs requesting too many invalid list tokens.
+// Throttled IPs are rejected before the token lookup, to not let them hit the database.
+func (s *Server) limitListGuesses(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		limiter := s.config().listGuesses
+		if limiter.Blocked(clientIP(c)) {
+			return s.blockListGuess(c)
+		}
+		err := next(c)
+		var httpErr *echo.HTTPError
+		if !errors.As(err, &httpErr) || httpErr.Code != http.StatusNotFound {
+			return err
+		}
+		s.securityLog.log(c, eventInvalidListToken)
+		if limiter.Record(clientIP(c)) {
+			return s.blockListGuess(c)
+		}
+		return err
+	}
+}
+
+func (s *Server) blockListGuess(c echo.Context) error {
+	_ = s.statsd.Incr("letsblockit.list_guess_blocked", nil, 1)
+	s.securityLog.log(c, eventListGuessBlocked)
+	return echo.NewHTTPError(http.StatusTooManyRequests, "too many invalid list tokens, please retry later")
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuessLimiter(t *testing.T) {
+	now := fixedNow
+	limiter := newGuessLimiter(2, time.Minute, func() time.Time { return now })
+
+	assert.False(t, limiter.Record("1.2.3.4"))
+	assert.False(t, limiter.Record("1.2.3.4"))
+	assert.True(t, limiter.Record("1.2.3.4"))
+	assert.False(t, limiter.Record("5.6.7.8"), "other IPs are not impacted")
+
+	// Older failures leave the window
+	now = now.Add(50 * time.Second)
+	assert.True(t, limiter.Record("1.2.3.4"))
+	now = now.Add(15 * time.Second)
+	assert.False(t, limiter.Record("1.2.3.4"))
+	assert.True(t, limiter.Record("1.2.3.4"))
+}
+
+func TestGuessLimiter_Blocked(t *testing.T) {
+	now := fixedNow
+	limiter := newGuessLimiter(2, time.Minute, func() time.Time { return now })
+
+	for i := 0; i < 10; i++ {
+		limiter.Record("1.2.3.4")
+		now = now.Add(time.Second)
+	}
+	assert.True(t, limiter.Blocked("1.2.3.4"))
+	assert.False(t, limiter.Blocked("5.6.7.8"))
+	failures, _ := limiter.failures.Get("1.2.3.4")
+	assert.Len(t, failures, 3, "only limit+1 failures are kept")
+
+	now = now.Add(time.Minute)
+	assert.False(t, limiter.Blocked("1.2.3.4"))
+}
+
+func TestLimitListGuesses_BlocksBeforeLookup(t *testing.T) {
+	s := &Server{statsd: &statsd.NoOpClient{}}
+	s.live.Store(&liveConfig{listGuesses: newGuessLimiter(1, time.Minute, func() time.Time { return fixedNow })})
+	var lookups int
+	validToken := false
+	handler := s.limitListGuesses(func(c echo.Context) error {
+		lookups++
+		if validToken {
+			return c.NoContent(http.StatusOK)
+		}
+		return echo.ErrNotFound
+	})
+	run := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/list/token", nil)
+		req.RemoteAddr = "203.0.113.7:5123"
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		if err := handler(c); err != nil {
+			return err.(*echo.HTTPError).Code
+		}
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, run())
+	assert.Equal(t, http.StatusTooManyRequests, run())
+	assert.Equal(t, 2, lookups)
+
+	// Valid tokens are rejected without lookup once the IP is throttled
+	validToken = true
+	assert.Equal(t, http.StatusTooManyRequests, run())
+	assert.Equal(t, 2, lookups)
+}
+
+func TestGuessLimiter_Disabled(t *testing.T) {
+	limiter := newGuessLimiter(0, time.Minute, time.Now)
+	assert.Nil(t, limiter)
+	for i := 0; i < 10; i++ {
+		assert.False(t, limiter.Record("1.2.3.4"))
+	}
+	assert.False(t, limiter.Blocked("1.2.3.4"))
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// Probes for container orchestrators: liveness only checks the process is responsive,
+// readiness checks the dependencies needed to serve requests.
+const (
+	livenessPath     = "/healthz"
+	readinessPath    = "/readyz"
+	readinessTimeout = 2 * time.Second
+	checkOk          = "ok"
+	checkFailing     = "failing"
+)
+
+type readinessReport struct {
+	Status      string            `json:"status"`
+	Maintenance bool              `json:"maintenance,omitempty"`
+	Checks      map[string]string `json:"checks,omitempty"`
+}
+
+func isHealthPath(p string) bool {
+	return p == healthPath || p == livenessPath || p == readinessPath
+}
+
+// healthCheck fails once shutdown is initiated, for load balancers to stop routing requests
+func (s *Server) healthCheck(c echo.Context) error {
+	if s.shuttingDown.Load() {
+		return c.String(http.StatusServiceUnavailable, "shutting down")
+	}
+	return c.String(http.StatusOK, "OK")
+}
+
+func (s *Server) livenessCheck(c echo.Context) error {
+	return c.JSON(http.StatusOK, &readinessReport{Status: checkOk, Maintenance: s.maintenance.Load()})
+}
+
+// readinessCheck runs the dependency checks concurrently. Errors are logged but not
+// returned, as the endpoint is not authenticated.
+func (s *Server) readinessCheck(c echo.Context) error {
+	if s.shuttingDown.Load() {
+		return c.JSON(http.StatusServiceUnavailable, &readinessReport{Status: "shutting down"})
+	}
+
+	checks := map[string]func(context.Context) error{
+		"database": func(ctx context.Context) error {
+			if s.store == nil {
+				return errors.New("not connected")
+			}
+			return s.store.Ping(ctx)
+		},
+		"filters": func(context.Context) error {
+			if repo := s.config().filters; repo == nil || len(repo.GetAll()) == 0 {
+				return errors.New("no filter template loaded")
+			}
+			return nil
+		},
+		"templates": func(context.Context) error { return s.checkBrokenTemplates() },
+	}
+	if checker, ok := s.auth.(auth.HealthChecker); ok {
+		checks["auth"] = checker.CheckHealth
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), readinessTimeout)
+	defer cancel()
+	// Lists are still served during maintenance, the instance is kept ready
+	report := &readinessReport{
+		Status:      checkOk,
+		Maintenance: s.maintenance.Load(),
+		Checks:      make(map[string]string, len(checks)),
+	}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) error) {
+			defer wg.Done()
+			err := check(ctx)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				c.Logger().Warnf("readiness check %s failed: %s", name, err)
+				report.Checks[name] = checkFailing
+				report.Status = checkFailing
+			} else {
+				report.Checks[name] = checkOk
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	if report.Status != checkOk {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingAuthBackend struct {
+	auth.Backend
+}
+
+func (failingAuthBackend) CheckHealth(context.Context) error {
+	return errors.New("connection refused")
+}
+
+func runHealthCheck(t *testing.T, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, readinessPath, nil), rec)
+	require.NoError(t, handler(c))
+	return rec
+}
+
+func (s *ServerTestSuite) TestReadinessCheck_OK() {
+	s.user = ""
+	req := httptest.NewRequest(http.MethodGet, readinessPath, nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.JSONEq(t, `{"status":"ok","checks":{"database":"ok","filters":"ok","templates":"ok"}}`, rec.Body.String())
+	})
+}
+
+func (s *ServerTestSuite) TestLivenessCheck() {
+	s.user = ""
+	req := httptest.NewRequest(http.MethodGet, livenessPath, nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+	})
+}
+
+func TestReadinessCheck_Failing(t *testing.T) {
+	s := &Server{auth: failingAuthBackend{}}
+	rec := runHealthCheck(t, s.readinessCheck)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"failing","checks":{"auth":"failing","database":"failing","filters":"failing","templates":"ok"}}`,
+		rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "connection refused")
+}
+
+func TestReadinessCheck_ShuttingDown(t *testing.T) {
+	s := &Server{}
+	s.shuttingDown.Store(true)
+	rec := runHealthCheck(t, s.readinessCheck)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"shutting down"}`, rec.Body.String())
+
+	rec = runHealthCheck(t, s.livenessCheck)
+	assert.Equal(t, http.StatusOK, rec.Code, "liveness is not affected by the shutdown")
+}
+
+func TestHealthChecks_Maintenance(t *testing.T) {
+	s := &Server{auth: failingAuthBackend{}}
+	s.maintenance.Store(true)
+	rec := runHealthCheck(t, s.livenessCheck)
+	assert.JSONEq(t, `{"status":"ok","maintenance":true}`, rec.Body.String())
+
+	rec = runHealthCheck(t, s.readinessCheck)
+	assert.JSONEq(t, `{"status":"failing","maintenance":true,"checks":{"auth":"failing","database":"failing","filters":"failing","templates":"ok"}}`,
+		rec.Body.String())
+}
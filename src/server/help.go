@@ -1,7 +1,6 @@
 package server
 
 import (
-	"net/url"
 	"sync"
 
 	"github.com/labstack/echo/v4"
@@ -33,6 +32,9 @@ var helpMenu = []helpSection{{
 	}, {
 		Code:  "remove-list",
 		Title: "Remove letsblock.it filters from uBlock",
+	}, {
+		Code:  "api",
+		Title: "Manage your filters with the API",
 	}},
 }, {
 	Title: "About",
@@ -75,15 +77,8 @@ func (s *Server) helpPages(c echo.Context) error {
 		info, err := s.store.GetListForUser(c.Request().Context(), hc.UserID)
 		if err == nil {
 			hc.Add("has_filters", info.InstanceCount > 0)
-			listUrl := url.URL{
-				Scheme: c.Scheme(),
-				Host:   c.Request().Host,
-				Path:   c.Echo().Reverse("render-filterlist", info.Token.String()) + renderListSuffix,
-			}
-			if s.options.ListDownloadDomain != "" {
-				listUrl.Host = s.options.ListDownloadDomain
-			}
-			hc.Add("list_url", listUrl.String())
+			hc.Add("list_url", s.listURL(c, info.Token))
+			hc.Add("list_title", listTitle(info.Title))
 		}
 	}
 
@@ -2,7 +2,6 @@ package server
 
 import (
 	"fmt"
-	"net/url"
 	"strings"
 
 	"github.com/letsblockit/letsblockit/src/db"
@@ -27,10 +26,8 @@ func buildHelpers(e echoInterface) map[string]interface{} {
 		"href": func(route string, args string) string {
 			return href(e, route, args)
 		},
-		"abp_subscribe_href": func(listUrl string) string {
-			return fmt.Sprintf("abp:subscribe?location=%s&title=%s",
-				url.QueryEscape(listUrl),
-				url.QueryEscape("letsblock.it - My filters"))
+		"abp_subscribe_href": func(listUrl, title string) string {
+			return subscribeHref("abp:subscribe", listUrl, subscribeTitle(title))
 		},
 		"lookup_list": func(obj map[string]interface{}, key string) []string {
 			switch values := obj[key].(type) {
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// hotList is a list downloaded often enough to be pre-rendered in the background
+type hotList struct {
+	key       string
+	token     uuid.UUID
+	testMode  bool
+	userID    string
+	downloads int
+	since     uint64 // Version of the store when the list was elected or last invalidated
+	etag      string
+	body      []byte
+}
+
+// hotLists counts list downloads, and keeps the pre-rendered body of the lists downloaded more
+// than threshold times per refresh interval. Bodies are stored with the etag they were rendered
+// for, and only served for that etag.
+type hotLists struct {
+	sync.Mutex
+	threshold int
+	maxBytes  int
+	size      int
+	version   uint64
+	downloads map[string]*hotList
+	lists     map[string]*hotList
+}
+
+// newHotLists returns a store keeping up to maxBytes of bodies, or nil if threshold or maxBytes are zero
+func newHotLists(threshold, maxBytes int) *hotLists {
+	if threshold <= 0 || maxBytes <= 0 {
+		return nil
+	}
+	return &hotLists{
+		threshold: threshold,
+		maxBytes:  maxBytes,
+		downloads: make(map[string]*hotList),
+		lists:     make(map[string]*hotList),
+	}
+}
+
+// Record counts a download of a list, to elect it on the next rotation
+func (h *hotLists) Record(key string, token uuid.UUID, testMode bool, userID string) {
+	if h == nil {
+		return
+	}
+	h.Lock()
+	defer h.Unlock()
+	if candidate, found := h.downloads[key]; found {
+		candidate.downloads++
+		return
+	}
+	h.downloads[key] = &hotList{key: key, token: token, testMode: testMode, userID: userID, downloads: 1}
+}
+
+// IsHot returns whether the list was elected during the last rotation
+func (h *hotLists) IsHot(key string) bool {
+	if h == nil {
+		return false
+	}
+	h.Lock()
+	defer h.Unlock()
+	_, found := h.lists[key]
+	return found
+}
+
+// Get returns the pre-rendered body of a hot list, if it was rendered for this etag
+func (h *hotLists) Get(key, etag string) ([]byte, bool) {
+	if h == nil {
+		return nil, false
+	}
+	h.Lock()
+	defer h.Unlock()
+	list, found := h.lists[key]
+	if !found || list.body == nil || list.etag != etag {
+		return nil, false
+	}
+	return list.body, true
+}
+
+// Version must be read before reading the list from the database, for Store to
+// discard the bodies rendered from data older than the last invalidation
+func (h *hotLists) Version() uint64 {
+	if h == nil {
+		return 0
+	}
+	h.Lock()
+	defer h.Unlock()
+	return h.version
+}
+
+// Store keeps the body of a hot list, evicting the bodies of less downloaded lists if needed.
+// It returns false if the list is not hot, was invalidated since version, or does not fit.
+func (h *hotLists) Store(key string, version uint64, etag string, body []byte) bool {
+	if h == nil {
+		return false
+	}
+	h.Lock()
+	defer h.Unlock()
+	list, found := h.lists[key]
+	if !found || version < list.since {
+		return false
+	}
+	h.size -= len(list.body)
+	list.etag, list.body = "", nil
+	if len(body) > h.maxBytes {
+		return false
+	}
+	if h.size+len(body) > h.maxBytes {
+		colder := make([]*hotList, 0, len(h.lists))
+		for _, l := range h.lists {
+			if l.body != nil && l.downloads < list.downloads {
+				colder = append(colder, l)
+			}
+		}
+		sort.Slice(colder, func(i, j int) bool { return colder[i].downloads < colder[j].downloads })
+		for _, l := range colder {
+			if h.size+len(body) <= h.maxBytes {
+				break
+			}
+			h.size -= len(l.body)
+			l.etag, l.body = "", nil
+		}
+		if h.size+len(body) > h.maxBytes {
+			return false
+		}
+	}
+	list.etag, list.body = etag, body
+	h.size += len(body)
+	return true
+}
+
+// Invalidate drops the bodies of a user's lists, it must be called once their changes are
+// committed, for the next download to render them again
+func (h *hotLists) Invalidate(userID string) {
+	if h == nil {
+		return
+	}
+	h.Lock()
+	defer h.Unlock()
+	h.version++
+	for _, list := range h.lists {
+		if list.userID == userID {
+			h.size -= len(list.body)
+			list.etag, list.body, list.since = "", nil, h.version
+		}
+	}
+}
+
+// Rotate elects the lists downloaded at least threshold times since the last rotation, and
+// returns them for the refresher to check their etag. Lists staying hot keep their body.
+func (h *hotLists) Rotate() []hotList {
+	if h == nil {
+		return nil
+	}
+	h.Lock()
+	defer h.Unlock()
+	h.version++
+	elected := make(map[string]*hotList, len(h.lists))
+	refs := make([]hotList, 0, len(h.lists))
+	for key, candidate := range h.downloads {
+		if candidate.downloads < h.threshold {
+			continue
+		}
+		if list, found := h.lists[key]; found {
+			list.downloads = candidate.downloads
+			candidate = list
+		} else {
+			candidate.since = h.version
+		}
+		elected[key] = candidate
+		refs = append(refs, hotList{key: key, token: candidate.token, testMode: candidate.testMode,
+			userID: candidate.userID, etag: candidate.etag})
+	}
+	for key, list := range h.lists {
+		if _, found := elected[key]; !found {
+			h.size -= len(list.body)
+		}
+	}
+	h.lists = elected
+	h.downloads = make(map[string]*hotList)
+	sort.Slice(refs, func(i, j int) bool { return refs[i].key < refs[j].key })
+	return refs
+}
+
+// Len returns the number of hot lists, and the total size of their bodies
+func (h *hotLists) Len() (int, int) {
+	if h == nil {
+		return 0, 0
+	}
+	h.Lock()
+	defer h.Unlock()
+	return len(h.lists), h.size
+}
+
+// refreshHotLists elects the hot lists every interval, and renders the ones that changed, until ctx is done
+func (s *Server) refreshHotLists(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, list := range s.hotLists.Rotate() {
+				if err := s.refreshHotList(ctx, list); err != nil {
+					s.echo.Logger.Warnf("cannot refresh hot list: %s", err)
+				}
+			}
+			count, size := s.hotLists.Len()
+			_ = s.statsd.Gauge("letsblockit.hot_lists", float64(count), nil, 1)
+			_ = s.statsd.Gauge("letsblockit.hot_lists_bytes", float64(size), nil, 1)
+		}
+	}
+}
+
+// refreshHotList renders a hot list if its etag changed since its last render
+func (s *Server) refreshHotList(ctx context.Context, list hotList) error {
+	version := s.hotLists.Version()
+	storedList, err := s.store.GetListForToken(ctx, list.token)
+	if err != nil {
+		return fmt.Errorf("failed to get list: %w", err)
+	}
+	etag := s.buildListETag(storedList)
+	if etag == list.etag || s.bans.IsBanned(storedList.UserID) {
+		return nil
+	}
+	renderCtx, cancel := ctx, context.CancelFunc(func() {})
+	if timeout := s.config().options.RenderTimeout; timeout > 0 {
+		renderCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+	storedInstances, err := s.store.GetInstancesForList(renderCtx, storedList.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get instances: %w", err)
+	}
+	body, err := s.renderListBody(renderCtx, s.echo.Logger, storedList.ID, list.token, listTitle(storedList.Title), storedInstances, list.testMode)
+	if err != nil {
+		return err
+	}
+	stored := s.hotLists.Store(list.key, version, etag, body)
+	_ = s.statsd.Incr("letsblockit.hot_list_refresh", storedTag.tags(stored), 1)
+	return nil
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHotLists_Disabled(t *testing.T) {
+	assert.Nil(t, newHotLists(0, 1<<20))
+	assert.Nil(t, newHotLists(2, 0))
+
+	var hot *hotLists
+	hot.Record("key", uuid.New(), false, "user")
+	assert.Empty(t, hot.Rotate())
+	assert.False(t, hot.Store("key", hot.Version(), "etag", []byte("body")))
+	_, found := hot.Get("key", "etag")
+	assert.False(t, found)
+	hot.Invalidate("user")
+}
+
+func TestHotLists_Election(t *testing.T) {
+	hot := newHotLists(2, 1<<20)
+	token := uuid.New()
+	hot.Record("hot", token, false, "user")
+	hot.Record("hot", token, false, "user")
+	hot.Record("cold", uuid.New(), false, "other")
+	assert.False(t, hot.Store("hot", hot.Version(), "etag", []byte("body")), "lists are elected on rotation")
+
+	refs := hot.Rotate()
+	require.Len(t, refs, 1)
+	assert.Equal(t, "hot", refs[0].key)
+	assert.Equal(t, token, refs[0].token)
+	assert.True(t, hot.IsHot("hot"))
+	assert.False(t, hot.IsHot("cold"))
+
+	assert.True(t, hot.Store("hot", hot.Version(), "etag", []byte("body")))
+	body, found := hot.Get("hot", "etag")
+	assert.True(t, found)
+	assert.Equal(t, "body", string(body))
+	_, found = hot.Get("hot", "other-etag")
+	assert.False(t, found, "bodies are only served for their etag")
+
+	// Lists staying hot keep their body, the others are dropped
+	hot.Record("hot", token, false, "user")
+	hot.Record("hot", token, false, "user")
+	refs = hot.Rotate()
+	require.Len(t, refs, 1)
+	assert.Equal(t, "etag", refs[0].etag)
+	_, found = hot.Get("hot", "etag")
+	assert.True(t, found)
+
+	assert.Empty(t, hot.Rotate())
+	assert.False(t, hot.IsHot("hot"))
+	count, size := hot.Len()
+	assert.Zero(t, count)
+	assert.Zero(t, size)
+}
+
+func TestHotLists_Invalidate(t *testing.T) {
+	hot := newHotLists(1, 1<<20)
+	hot.Record("user:false", uuid.New(), false, "user")
+	hot.Record("user:true", uuid.New(), true, "user")
+	hot.Record("other:false", uuid.New(), false, "other")
+	hot.Rotate()
+	for _, key := range []string{"user:false", "user:true", "other:false"} {
+		require.True(t, hot.Store(key, hot.Version(), "etag", []byte("body")))
+	}
+
+	// Renders started before the invalidation are discarded, as they can hold old rules
+	version := hot.Version()
+	hot.Invalidate("user")
+	for _, key := range []string{"user:false", "user:true"} {
+		_, found := hot.Get(key, "etag")
+		assert.False(t, found, key)
+		assert.True(t, hot.IsHot(key), "invalidated lists stay hot")
+		assert.False(t, hot.Store(key, version, "etag", []byte("old body")))
+	}
+	_, found := hot.Get("other:false", "etag")
+	assert.True(t, found, "other users are not affected")
+
+	assert.True(t, hot.Store("user:false", hot.Version(), "etag", []byte("new body")))
+	body, _ := hot.Get("user:false", "etag")
+	assert.Equal(t, "new body", string(body))
+	_, size := hot.Len()
+	assert.Equal(t, len("new body")+len("body"), size)
+}
+
+func TestHotLists_MemoryBound(t *testing.T) {
+	hot := newHotLists(1, 10)
+	for key, downloads := range map[string]int{"a": 1, "b": 2, "c": 3} {
+		for i := 0; i < downloads; i++ {
+			hot.Record(key, uuid.New(), false, key)
+		}
+	}
+	hot.Rotate()
+
+	assert.False(t, hot.Store("a", hot.Version(), "etag", []byte("more than 10 bytes")))
+	assert.True(t, hot.Store("a", hot.Version(), "etag", []byte("aaaaaa")))
+	assert.False(t, hot.Store("b", hot.Version(), "etag", []byte("bbbbbbbbbbb")))
+	assert.True(t, hot.Store("b", hot.Version(), "etag", []byte("bbbb")))
+	assert.True(t, hot.Store("c", hot.Version(), "etag", []byte("cccccc")), "colder lists are evicted")
+
+	_, found := hot.Get("a", "etag")
+	assert.False(t, found)
+	_, found = hot.Get("b", "etag")
+	assert.True(t, found)
+	assert.False(t, hot.Store("a", hot.Version(), "etag", []byte("aaaaaa")), "hotter lists are kept")
+	_, size := hot.Len()
+	assert.Equal(t, 10, size)
+}
+
+func (s *ServerTestSuite) TestRenderList_HotList() {
+	registry := metrics.NewRegistry()
+	s.server.statsd = registry
+	s.server.hotLists = newHotLists(2, 1<<20)
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+	s.addInstance(s.user, "filter2", filter2Custom)
+	apiToken := s.createApiToken(scopeWrite)
+	user := s.user
+
+	download := func() string {
+		req := httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String(), nil)
+		rec := httptest.NewRecorder()
+		s.server.echo.ServeHTTP(rec, req)
+		s.Equal(200, rec.Code)
+		return rec.Body.String()
+	}
+	fresh := download()
+	s.Equal(fresh, download())
+	refs := s.server.hotLists.Rotate()
+	s.Require().Len(refs, 1)
+	s.Require().NoError(s.server.refreshHotList(context.Background(), refs[0]))
+	s.Equal(fresh, download(), "hot lists are served from memory")
+
+	// Deleting the oldest instance does not change the etag, the edit invalidates the hot list
+	req := httptest.NewRequest(http.MethodDelete, "/api/instances/filter1", nil)
+	s.runApiRequest(req, apiToken, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+	s.user = user
+	edited := download()
+	s.NotEqual(fresh, edited)
+	s.Equal(edited, download(), "the new body is stored")
+
+	var out strings.Builder
+	require.NoError(s.T(), registry.Write(&out))
+	s.Contains(out.String(), `letsblockit_hot_list_download_total{hit="true"} 2`)
+	s.Contains(out.String(), `letsblockit_hot_list_download_total{hit="false"} 1`)
+	s.Contains(out.String(), `letsblockit_hot_list_refresh_total{stored="true"} 1`)
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// Admins can browse the site as another user to troubleshoot their list, in read-only mode.
+// The impersonation state is kept in a cookie that is only valid for the admin who started it.
+const (
+	impersonationCookieName = "lbi_impersonate"
+	impersonationLifetime   = time.Hour
+	impersonationStopPath   = "/admin/impersonate/stop"
+	auditImpersonationStart = "impersonation_start"
+	auditImpersonationStop  = "impersonation_stop"
+	auditLogPageSize        = 50
+)
+
+// auditEntry holds the audit log information displayed in the admin page
+type auditEntry struct {
+	AdminID   string
+	Action    string
+	TargetID  string
+	CreatedAt string
+}
+
+// signImpersonationCookie returns a cookie value holding the target user and the expiry.
+// The admin ID is part of the signature, for the cookie to be rejected for any other user.
+func (s *Server) signImpersonationCookie(admin, target string, expiresAt time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(target)) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + s.cookieSignature("impersonate:"+admin+":"+payload)
+}
+
+// parseImpersonationCookie returns the impersonated user, if the cookie has been signed for this admin and has not expired
+func (s *Server) parseImpersonationCookie(value, admin string) (string, bool) {
+	split := strings.LastIndex(value, ".")
+	if split < 0 || !hmac.Equal([]byte(value[split+1:]), []byte(s.cookieSignature("impersonate:"+admin+":"+value[:split]))) {
+		return "", false
+	}
+	encoded, expiry, found := strings.Cut(value[:split], ".")
+	timestamp, err := strconv.ParseInt(expiry, 10, 64)
+	if !found || err != nil || !time.Unix(timestamp, 0).After(s.now()) {
+		return "", false
+	}
+	target, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(target) == 0 {
+		return "", false
+	}
+	return string(target), true
+}
+
+func (s *Server) setImpersonationCookie(c echo.Context, value string, expiresAt time.Time) {
+	c.SetCookie(&http.Cookie{
+		Name:     impersonationCookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// impersonation switches the user ID to the impersonated user, and rejects all non-GET requests
+// except the one stopping the impersonation. It must run after the admin and session middlewares,
+// for them to use the admin's ID.
+func (s *Server) impersonation(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cookie, err := c.Cookie(impersonationCookieName)
+		if err != nil {
+			return next(c)
+		}
+		target, valid := s.parseImpersonationCookie(cookie.Value, auth.GetUserId(c))
+		if !valid || !auth.IsAdmin(c) {
+			s.setImpersonationCookie(c, "", time.Unix(0, 0))
+			return next(c)
+		}
+		auth.SetImpersonatedUser(c, target)
+		if m := c.Request().Method; m != http.MethodGet && m != http.MethodHead && c.Path() != impersonationStopPath {
+			return echo.NewHTTPError(http.StatusForbidden, "changes are disabled while impersonating a user")
+		}
+		return next(c)
+	}
+}
+
+func (s *Server) adminImpersonation(c echo.Context) error {
+	stored, err := s.store.GetAdminActions(c.Request().Context(), auditLogPageSize)
+	if err != nil {
+		return err
+	}
+	entries := make([]auditEntry, 0, len(stored))
+	for _, e := range stored {
+		entries = append(entries, auditEntry{
+			AdminID:   e.AdminID,
+			Action:    e.Action,
+			TargetID:  e.TargetID,
+			CreatedAt: e.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	hc := s.buildPageContext(c, "Impersonate a user")
+	hc.NoBoost = true
+	hc.Add("audit_log", entries)
+	return s.pages.Render(c, "admin-impersonation", hc)
+}
+
+// startImpersonation records the impersonation in the audit log, then redirects the admin to the user's filters
+func (s *Server) startImpersonation(c echo.Context) error {
+	formParams, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+	admin, target := auth.GetRealUserId(c), strings.TrimSpace(formParams.Get("user_id"))
+	if target == "" || target == admin {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid arguments")
+	}
+	if err = s.store.LogAdminAction(c.Request().Context(), db.LogAdminActionParams{
+		AdminID:  admin,
+		Action:   auditImpersonationStart,
+		TargetID: target,
+	}); err != nil {
+		return err
+	}
+	expiresAt := s.now().Add(impersonationLifetime)
+	s.setImpersonationCookie(c, s.signImpersonationCookie(admin, target, expiresAt), expiresAt)
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("list-filters"))
+}
+
+func (s *Server) stopImpersonation(c echo.Context) error {
+	if auth.IsImpersonating(c) {
+		if err := s.store.LogAdminAction(c.Request().Context(), db.LogAdminActionParams{
+			AdminID:  auth.GetRealUserId(c),
+			Action:   auditImpersonationStop,
+			TargetID: auth.GetUserId(c),
+		}); err != nil {
+			return err
+		}
+	}
+	s.setImpersonationCookie(c, "", time.Unix(0, 0))
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("admin-impersonation"))
+}
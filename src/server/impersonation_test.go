@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const impersonatedUser = "impersonated-user"
+
+// startImpersonation runs the impersonation form as an admin, and returns the impersonation cookie
+func (s *ServerTestSuite) startImpersonation() *http.Cookie {
+	s.T().Helper()
+	s.setUserAdmin()
+	f := make(url.Values)
+	f.Add("user_id", impersonatedUser)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/admin/impersonate", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters")
+
+	var cookie *http.Cookie
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == impersonationCookieName {
+				cookie = c
+			}
+		}
+	})
+	require.NotNil(s.T(), cookie)
+	return cookie
+}
+
+func (s *ServerTestSuite) TestImpersonation_CookieRoundTrip() {
+	expiresAt := fixedNow.Add(time.Hour)
+	value := s.server.signImpersonationCookie(s.user, impersonatedUser, expiresAt)
+
+	target, valid := s.server.parseImpersonationCookie(value, s.user)
+	s.True(valid)
+	s.Equal(impersonatedUser, target)
+
+	_, valid = s.server.parseImpersonationCookie(value, "other-admin")
+	s.False(valid, "other admin")
+	_, valid = s.server.parseImpersonationCookie(s.server.signImpersonationCookie(s.user, impersonatedUser, fixedNow.Add(-time.Second)), s.user)
+	s.False(valid, "expired cookie")
+	_, valid = s.server.parseImpersonationCookie("invalid", s.user)
+	s.False(valid, "invalid cookie")
+}
+
+func (s *ServerTestSuite) TestImpersonation_ReadOnly() {
+	require.NoError(s.T(), s.store.CreateInstance(context.Background(), db.CreateInstanceParams{
+		UserID:       impersonatedUser,
+		TemplateName: "filter2",
+	}))
+	cookie := s.startImpersonation()
+
+	// Read paths see the impersonated user
+	req := httptest.NewRequest(http.MethodGet, "/filters/filter2", nil)
+	req.AddCookie(cookie)
+	s.expectP.Render(gomock.Any(), "view-filter", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.Equal(s.T(), impersonatedUser, hc.UserID)
+			assert.True(s.T(), hc.UserIsImpersonated)
+			assert.Equal(s.T(), true, hc.Data["has_instance"])
+			return nil
+		})
+	s.runRequest(req, assertOk)
+
+	// Changes are rejected, whatever the method
+	f := buildFilter2CustomBody()
+	f.Add("__save", "")
+	f.Add(csrfLookup, s.csrf)
+	req = httptest.NewRequest(http.MethodPost, "/filters/filter2", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.AddCookie(cookie)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+	req = httptest.NewRequest(http.MethodGet, "/filters/filter2?__disable=", nil)
+	req.AddCookie(cookie)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+	count, err := s.store.CountInstances(context.Background(), db.CountInstancesParams{
+		UserID:       impersonatedUser,
+		TemplateName: "filter2",
+	})
+	require.NoError(s.T(), err)
+	s.EqualValues(1, count)
+}
+
+func (s *ServerTestSuite) TestImpersonation_StartAndStopAreAudited() {
+	cookie := s.startImpersonation()
+
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/admin/impersonate/stop", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.AddCookie(cookie)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/admin/impersonate")
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		require.Len(t, rec.Result().Cookies(), 1)
+		assert.Equal(t, "", rec.Result().Cookies()[0].Value)
+	})
+
+	actions, err := s.store.GetAdminActions(context.Background(), auditLogPageSize)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), actions, 2)
+	s.ElementsMatch([]string{auditImpersonationStart, auditImpersonationStop}, []string{actions[0].Action, actions[1].Action})
+	for _, a := range actions {
+		s.Equal(s.user, a.AdminID)
+		s.Equal(impersonatedUser, a.TargetID)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/impersonate", nil)
+	s.expectP.Render(gomock.Any(), "admin-impersonation", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.False(s.T(), hc.UserIsImpersonated)
+			assert.Len(s.T(), hc.Data["audit_log"], 2)
+			return nil
+		})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestImpersonation_IgnoredForNonAdmins() {
+	cookie := s.startImpersonation()
+	s.server.options.AdminUsers = nil
+	s.server.echo = echo.New()
+	s.server.setupRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/filters", nil)
+	req.AddCookie(cookie)
+	s.expectP.Render(gomock.Any(), "list-filters", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.Equal(s.T(), s.user, hc.UserID)
+			assert.False(s.T(), hc.UserIsImpersonated)
+			return nil
+		})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestImpersonation_InvalidTarget() {
+	s.setUserAdmin()
+	f := make(url.Values)
+	f.Add("user_id", s.user)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/admin/impersonate", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
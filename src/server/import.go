@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// Users can import an existing uBlock Origin rules file: the rules matching templates are mapped to them,
+// other rules are appended to the custom rules filter. A preview is shown before applying the import.
+const (
+	importMaxCustomRules = 64 << 10 // Maximum size of the resulting custom rules, in bytes
+	importAdd            = "add"    // The template is added to the list
+)
+
+// importStep describes what happens to a template recognized in the imported rules
+type importStep struct {
+	Template string
+	Title    string
+	Action   string // importAdd, mergeKeep or mergeConcatenate
+	Matched  int
+	Rules    int
+	params   map[string]interface{}
+}
+
+// importPlan holds the changes made to the user's list by an import
+type importPlan struct {
+	Steps       []importStep
+	Mapped      int
+	Custom      int
+	CustomRules string // Imported custom rules, appended to the existing ones
+	customTotal string
+}
+
+// importRulesForm shows the form to paste or upload the rules to import
+func (s *Server) importRulesForm(c echo.Context) error {
+	hc := s.buildPageContext(c, "Import my rules")
+	hc.NoBoost = true
+	return s.pages.Render(c, "rules-import", hc)
+}
+
+// previewRulesImport parses the submitted rules and shows how they would be imported
+func (s *Server) previewRulesImport(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	input, err := readImportedRules(c)
+	if err != nil {
+		return err
+	}
+	var plan *importPlan
+	if err = s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		plan, err = s.buildImportPlan(ctx, q, user, input)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	hc := s.buildPageContext(c, "Import my rules")
+	hc.NoBoost = true
+	hc.Add("import_rules", input)
+	hc.Add("import_plan", plan)
+	return s.pages.Render(c, "rules-import", hc)
+}
+
+// importRules applies a previewed import, the submitted rules are parsed again
+func (s *Server) importRules(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	if c.FormValue("confirm") != "on" {
+		return echo.NewHTTPError(http.StatusBadRequest, "please confirm the import")
+	}
+	input, err := readImportedRules(c)
+	if err != nil {
+		return err
+	}
+	var plan *importPlan
+	if err = s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		if plan, err = s.buildImportPlan(ctx, q, user, input); err != nil {
+			return err
+		}
+		return s.applyImportPlan(ctx, q, user, plan)
+	}); err != nil {
+		return err
+	}
+
+	for _, step := range plan.Steps {
+		switch step.Action {
+		case importAdd:
+			s.notifyListChange(user, webhookInstanceCreated, step.Template)
+		case mergeConcatenate:
+			s.notifyListChange(user, webhookInstanceUpdated, step.Template)
+		}
+	}
+	_ = s.statsd.Incr("letsblockit.rules_imported", nil, 1)
+	return s.pages.RedirectToPage(c, "list-filters")
+}
+
+// readImportedRules returns the uploaded rules file, or the rules pasted in the form
+func readImportedRules(c echo.Context) (string, error) {
+	input := c.FormValue("rules")
+	if file, err := c.FormFile("file"); err == nil {
+		reader, err := file.Open()
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return "", err
+		}
+		input = string(content)
+	}
+	if strings.TrimSpace(input) == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "please provide the rules to import")
+	}
+	return input, nil
+}
+
+// buildImportPlan maps the imported rules to templates: templates already in the list are kept as is,
+// and the other rules are appended to the existing custom rules.
+func (s *Server) buildImportPlan(ctx context.Context, q db.Querier, user, input string) (*importPlan, error) {
+	existing, err := q.GetInstancesForUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	existingNames := make(map[string]struct{}, len(existing))
+	var existingRules string
+	for _, i := range existing {
+		existingNames[i.TemplateName] = struct{}{}
+		if i.TemplateName == filters.CustomRulesFilterName && i.Params.Status == pgtype.Present {
+			params := make(map[string]interface{})
+			if err = i.Params.AssignTo(&params); err != nil {
+				return nil, err
+			}
+			existingRules, _ = params["rules"].(string)
+		}
+	}
+
+	repo := s.config().filters
+	result := repo.ImportRules(input)
+	plan := &importPlan{
+		Mapped:      result.Mapped,
+		Custom:      result.Custom,
+		CustomRules: result.CustomRules,
+	}
+	for _, t := range result.Templates {
+		step := importStep{
+			Template: t.Template.Name,
+			Title:    t.Template.Title,
+			Action:   importAdd,
+			Matched:  t.Matched,
+			Rules:    t.Rules,
+			params:   t.Params,
+		}
+		if _, found := existingNames[step.Template]; found {
+			step.Action = mergeKeep
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+
+	if result.CustomRules != "" && repo.Has(filters.CustomRulesFilterName) {
+		template, _ := repo.Get(filters.CustomRulesFilterName)
+		step := importStep{
+			Template: template.Name,
+			Title:    template.Title,
+			Action:   importAdd,
+			Matched:  result.Custom,
+		}
+		plan.customTotal = result.CustomRules
+		if _, found := existingNames[filters.CustomRulesFilterName]; found {
+			step.Action = mergeConcatenate
+			plan.customTotal = joinRules(existingRules, result.CustomRules)
+		}
+		if len(plan.customTotal) > importMaxCustomRules {
+			return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf(
+				"the custom rules would exceed the %d KiB limit, please remove some rules from the file", importMaxCustomRules>>10))
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	return plan, nil
+}
+
+func (s *Server) applyImportPlan(ctx context.Context, q db.Querier, user string, plan *importPlan) error {
+	if count, err := q.CountListsForUser(ctx, user); err != nil {
+		return err
+	} else if count == 0 {
+		if _, err = q.CreateListForUser(ctx, user); err != nil {
+			return err
+		}
+	}
+	repo := s.config().filters
+	for _, step := range plan.Steps {
+		params := step.params
+		if step.Template == filters.CustomRulesFilterName {
+			params = map[string]interface{}{"rules": plan.customTotal}
+		}
+		var out pgtype.JSONB
+		if err := out.Set(params); err != nil {
+			return err
+		}
+		var err error
+		switch step.Action {
+		case importAdd:
+			err = q.CreateInstance(ctx, db.CreateInstanceParams{
+				UserID:       user,
+				TemplateName: step.Template,
+				Params:       out,
+				TemplateHash: repo.Hash(step.Template),
+			})
+		case mergeConcatenate:
+			var stored db.GetInstanceRow
+			if stored, err = q.GetInstance(ctx, db.GetInstanceParams{UserID: user, TemplateName: step.Template}); err != nil {
+				return err
+			}
+			err = q.UpdateInstance(ctx, db.UpdateInstanceParams{
+				UserID:       user,
+				TemplateName: step.Template,
+				Params:       out,
+				TestMode:     stored.TestMode,
+				TemplateHash: stored.TemplateHash,
+			})
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if step.Action == importAdd {
+			if err = s.ackTemplate(ctx, q, user, step.Template); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const importedRules = `! My rules
+hello from one
+
+hello a default
+hello b default
+!#if env_firefox
+hello from one
+!#endif
+my-rule
+`
+
+func (s *ServerTestSuite) rulesImportRequest(path, rules string, confirm bool) *http.Request {
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	f.Add("rules", rules)
+	if confirm {
+		f.Add("confirm", "on")
+	}
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) TestImportRules_PreviewAndImport() {
+	s.addInstance(s.user, "filter1", nil)
+	s.addInstance(s.user, filters.CustomRulesFilterName, map[string]interface{}{"rules": "existing-rule\n"})
+	customRules := "! My rules\n!#if env_firefox\nhello from one\n!#endif\nmy-rule\n"
+
+	s.expectP.Render(gomock.Any(), "rules-import", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.Equal(s.T(), importedRules, hc.Data["import_rules"])
+			plan, ok := hc.Data["import_plan"].(*importPlan)
+			require.True(s.T(), ok)
+			assert.Equal(s.T(), 3, plan.Mapped)
+			assert.Equal(s.T(), 2, plan.Custom)
+			assert.Equal(s.T(), customRules, plan.CustomRules)
+			require.Len(s.T(), plan.Steps, 3)
+			assert.Equal(s.T(), []string{mergeKeep, importAdd, mergeConcatenate},
+				[]string{plan.Steps[0].Action, plan.Steps[1].Action, plan.Steps[2].Action})
+			assert.Equal(s.T(), "filter2", plan.Steps[1].Template)
+			assert.Equal(s.T(), 2, plan.Steps[1].Matched)
+			assert.Equal(s.T(), 2, plan.Steps[1].Rules)
+			return nil
+		})
+	s.runRequest(s.rulesImportRequest("/user/import/preview", importedRules, false), assertOk)
+	s.requireInstanceCount("filter2", 0)
+
+	s.expectP.RedirectToPage(gomock.Any(), "list-filters")
+	s.runRequest(s.rulesImportRequest("/user/import", importedRules, true), assertOk)
+
+	filter2, err := s.store.GetInstance(context.Background(), db.GetInstanceParams{
+		UserID:       s.user,
+		TemplateName: "filter2",
+	})
+	require.NoError(s.T(), err)
+	s.requireJSONEq(map[string]interface{}{
+		"one":                    "default",
+		"two":                    true,
+		"three":                  []string{"a", "b"},
+		"three---preset---dummy": false,
+	}, filter2.Params)
+	custom, err := s.store.GetInstance(context.Background(), db.GetInstanceParams{
+		UserID:       s.user,
+		TemplateName: filters.CustomRulesFilterName,
+	})
+	require.NoError(s.T(), err)
+	s.requireJSONEq(map[string]interface{}{"rules": "existing-rule\n" + customRules}, custom.Params)
+}
+
+func (s *ServerTestSuite) TestImportRules_NotConfirmed() {
+	s.runRequest(s.rulesImportRequest("/user/import", importedRules, false), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	s.requireInstanceCount("filter2", 0)
+}
+
+func (s *ServerTestSuite) TestImportRules_Empty() {
+	s.runRequest(s.rulesImportRequest("/user/import/preview", "\n\n", false), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestImportRules_TooLarge() {
+	rules := strings.Repeat("example.com##.ad\n", importMaxCustomRules/16)
+	s.runRequest(s.rulesImportRequest("/user/import/preview", rules, false), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+}
@@ -1,10 +1,18 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,6 +23,12 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// maxPooledBufferSize is the capacity above which render buffers are not reused, to release the memory
+const maxPooledBufferSize = 1 << 20
+
+// renderBuffers holds the buffers lists are rendered in, to avoid growing a new one for each render
+var renderBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
 const listExportTemplate = `# letsblock.it filter list export
 #
 # List token: %s
@@ -25,24 +39,62 @@ const listExportTemplate = `# letsblock.it filter list export
 
 `
 
+// defaultBannedListBody is served instead of the lists of banned users, it can be overridden with the banned-list-file option
+const defaultBannedListBody = `! Title: letsblock.it - List unavailable
+! Expires: 1 day
+! Homepage: https://letsblock.it
+!
+! This filter list is not available anymore, and has been emptied.
+! You can remove it from your adblocker's settings.
+`
+
+// ruleCountHeader returns the number of rules in the served list, for subscribers to monitor its size
+const ruleCountHeader = "X-Rule-Count"
+
+// Stale lists are served when the database is unreachable, with a warning for their subscribers
+const (
+	staleListHeader  = `110 - "Response is Stale"`
+	staleListWarning = `
+! Stale copy: this instance cannot reach its database, these rules may be outdated
+`
+)
+
+const bannedListETag = "banned"
 const renderListSuffix = ".txt"
+const listDefinitionSuffix = ".yaml"
 const installPromptFilterTemplate = `
 ! Hide the list install prompt for that list
 %s###install-prompt-%s
 `
 
 func (s *Server) renderList(c echo.Context) error {
+	if format, name := findListFormat(c.Param("token")); format != nil {
+		token, err := uuid.Parse(name)
+		if err != nil {
+			s.countInvalidToken(true)
+			return echo.ErrNotFound
+		}
+		return s.renderListFormat(c, token, format)
+	}
 	token, err := uuid.Parse(strings.TrimSuffix(c.Param("token"), renderListSuffix))
 	if err != nil {
+		s.countInvalidToken(true)
 		return echo.ErrNotFound
 	}
 
-	// In order to reduce resource consumption, we compute an etag based on:
-	//   - a hash of the filter templates
-	//   - the latest change to any parameter in the list
-	requestETag, listETag := getEtag(c), s.filterHash
-	etagPresent, etagMatch := requestETag != "", false
+	// In order to reduce resource consumption, we compute an etag, see buildListETag
+	requestETags, listETag := getRequestETags(c), ""
+	etagPresent, etagMatch := requestETags.present(), false
+	_, testMode := c.QueryParams()["test_mode"]
 
+	var banned, cacheHit, hotHit bool
+	var cacheKey string
+	listKey := token.String() + ":" + strconv.FormatBool(testMode)
+	hotVersion := s.hotLists.Version()
+	var body []byte
+	// The render deadline starts once the etag is checked, to not affect the not modified responses
+	renderCtx, cancelRender := c.Request().Context(), context.CancelFunc(func() {})
+	defer func() { cancelRender() }()
 	var storedList db.GetListForTokenRow
 	var storedInstances []db.GetInstancesForListRow
 	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
@@ -50,11 +102,13 @@ func (s *Server) renderList(c echo.Context) error {
 		storedList, e = q.GetListForToken(ctx, token)
 		switch {
 		case e == db.NotFound:
+			s.countInvalidToken(false)
 			return echo.ErrNotFound
 		case e != nil:
 			return fmt.Errorf("failed to get list: %w", e)
 		case s.bans.IsBanned(storedList.UserID):
-			return echo.ErrForbidden
+			banned = true
+			return nil
 		}
 
 		if c.Request().Header.Get("Referer") == "" {
@@ -64,78 +118,255 @@ func (s *Server) renderList(c echo.Context) error {
 			}
 		}
 
-		if ts, ok := storedList.LastUpdated.(time.Time); ok {
-			listETag += ts.UTC().Format("15040520060102")
-		}
-		etagMatch = listETag == requestETag
+		listETag = s.buildListETag(storedList)
+		etagMatch = requestETags.match(listETag)
 		if etagMatch {
 			return nil
 		}
 
-		storedInstances, e = q.GetInstancesForList(ctx, storedList.ID)
+		s.hotLists.Record(listKey, token, testMode, storedList.UserID)
+		if body, hotHit = s.hotLists.Get(listKey, listETag); hotHit {
+			return nil
+		}
+		// The etag only changes with the list contents, but is not unique across lists
+		cacheKey = listKey + ":" + listETag
+		if body, cacheHit = s.listCache.Get(cacheKey); cacheHit {
+			return nil
+		}
+
+		if timeout := s.config().options.RenderTimeout; timeout > 0 {
+			renderCtx, cancelRender = context.WithTimeout(ctx, timeout)
+		}
+		storedInstances, e = q.GetInstancesForList(renderCtx, storedList.ID)
 		if e != nil {
 			return fmt.Errorf("failed to get instances: %w", e)
 		}
 		return nil
 	}); err != nil {
-		return err
+		// Subscribers keep their latest rules while the database is unavailable
+		var httpErr *echo.HTTPError
+		if !errors.As(err, &httpErr) && renderCtx.Err() == nil {
+			if stale, found := s.listCache.GetStale(listKey); found {
+				c.Logger().Warnf("serving a stale list: %s", err)
+				_ = s.statsd.Incr("letsblockit.list_render_cache", staleHitTags, 1)
+				c.Response().Header().Set("Warning", staleListHeader)
+				// Copy the cached body before appending the warning comment
+				return s.writeList(c, token, append(stale[:len(stale):len(stale)], staleListWarning...))
+			}
+		}
+		return s.checkRenderTimeout(c, renderCtx, len(storedInstances), err)
 	}
 
-	_ = s.statsd.Incr("letsblockit.list_download", []string{
-		fmt.Sprintf("etag_present:%t", etagPresent),
-		fmt.Sprintf("etag_match:%t", etagMatch),
-	}, 1)
+	if banned {
+		// Serve an empty list, for subscribers to drop its rules instead of retrying forever
+		listETag = bannedListETag
+		etagMatch = requestETags.match(bannedListETag)
+	}
+	// The etag hit ratio is computed from this counter, eg. with prometheus:
+	//   sum(rate(letsblockit_list_download_total{etag_match="true"}[5m])) / sum(rate(letsblockit_list_download_total[5m]))
+	_ = s.statsd.Incr("letsblockit.list_download", listDownloadTags.of(etagPresent, etagMatch, banned), 1)
 	if etagMatch {
 		return c.NoContent(http.StatusNotModified)
 	}
+	if banned {
+		c.Response().Header().Set("Etag", weakETag(bannedListETag))
+		return c.String(http.StatusOK, s.bannedListBody())
+	}
+
+	c.Response().Header().Set("Etag", weakETag(listETag))
+	if hotHit || s.hotLists.IsHot(listKey) {
+		_ = s.statsd.Incr("letsblockit.hot_list_download", hitTag.tags(hotHit), 1)
+	}
+	if hotHit {
+		return s.writeList(c, token, body)
+	}
+	if s.listCache != nil {
+		_ = s.statsd.Incr("letsblockit.list_render_cache", hitTag.tags(cacheHit), 1)
+	}
+	if !cacheHit {
+		if body, err = s.renderListBody(renderCtx, c.Logger(), storedList.ID, token, listTitle(storedList.Title), storedInstances, testMode); err != nil {
+			return s.checkRenderTimeout(c, renderCtx, len(storedInstances), err)
+		}
+		s.listCache.Set(cacheKey, listKey, body)
+	}
+	// Hot lists invalidated by an edit get their new body from the next download
+	s.hotLists.Store(listKey, hotVersion, listETag, body)
+	return s.writeList(c, token, body)
+}
 
-	c.Response().Header().Set("Etag", listETag)
+// buildListETag computes the etag of a rendered list, from its contents and its title rendered in the header
+func (s *Server) buildListETag(storedList db.GetListForTokenRow) string {
+	return s.buildContentETag(storedList) + titleETag(storedList.Title)
+}
 
-	list, err := convertFilterList(storedInstances)
-	if err != nil {
-		return fmt.Errorf("failed to convert list: %w", err)
+// buildContentETag computes the etag of the list rules from the hash of the filter templates, the latest
+// change to any parameter in the list, and the template versions its instances are pinned to.
+// The latest change id is added as removing an older instance does not change the latest update time.
+func (s *Server) buildContentETag(storedList db.GetListForTokenRow) string {
+	etag := s.config().filterHash
+	if ts, ok := storedList.LastUpdated.(time.Time); ok {
+		etag += ts.UTC().Format("15040520060102")
 	}
-	if _, ok := c.QueryParams()["test_mode"]; ok {
-		list.TestMode = true
+	if storedList.LastChange > 0 {
+		etag += "-c" + strconv.FormatInt(storedList.LastChange, 36)
 	}
+	return etag + pinnedETag(storedList.PinnedVersions)
+}
 
-	if err = list.Render(c.Response(), c.Logger(), s.filters); err != nil {
-		return fmt.Errorf("failed to render list: %w", err)
+// titleETag hashes the title of a list, to add it to its etag. The lists rendered with the default title
+// keep the same etag, to not invalidate their cached copies.
+func titleETag(title sql.NullString) string {
+	rendered := listTitle(title)
+	if rendered == filters.DefaultListTitle {
+		return ""
 	}
+	hasher := fnv.New32a()
+	_, _ = hasher.Write([]byte(rendered))
+	return "-t" + strconv.FormatUint(uint64(hasher.Sum32()), 36)
+}
 
-	if s.options.OfficialInstance {
-		_, err = fmt.Fprintf(c.Response(), installPromptFilterTemplate, mainDomain, token)
-	} else {
-		_, err = fmt.Fprintf(c.Response(), installPromptFilterTemplate, c.Request().Host, token)
+// listTitle returns the title to render for a list, cleaned up for the header comment
+func listTitle(title sql.NullString) string {
+	if cleaned := filters.CleanListTitle(title.String); cleaned != "" {
+		return cleaned
 	}
+	return filters.DefaultListTitle
+}
+
+// writeList writes a rendered list body, followed by the install prompt rule for the request host
+func (s *Server) writeList(c echo.Context, token uuid.UUID, body []byte) error {
+	c.Response().Header().Set(ruleCountHeader, strconv.Itoa(filters.CountRules(body)))
+	out := &countingWriter{w: c.Response()}
+	if _, err := out.Write(body); err != nil {
+		return err
+	}
+	host := mainDomain
+	if !s.options.OfficialInstance {
+		host = stripPort(s.publicHost(c))
+	}
+	_, err := fmt.Fprintf(out, installPromptFilterTemplate, host, token)
+	_ = s.statsd.Distribution("letsblockit.list_render_bytes", float64(out.written), nil, 1)
 
 	return err
 }
 
+// renderListBody renders the filters of a list, without the install prompt that depends on the request host.
+// The outcome of the instance renders is recorded for the list ID, unless it is zero.
+func (s *Server) renderListBody(ctx context.Context, logger echo.Logger, listID int32, token uuid.UUID, title string, storedInstances []db.GetInstancesForListRow, testMode bool) ([]byte, error) {
+	list, err := convertFilterList(title, storedInstances)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert list: %w", err)
+	}
+	list.TestMode = testMode
+	config := s.config()
+	list.SafeMode = config.options.ListSafeMode
+	list.SizeWarning = config.options.RuleCountWarning
+	if hostname := config.options.PublicHostname; hostname != "" && !s.options.OfficialInstance {
+		list.Homepage = "https://" + hostname
+	}
+	for _, mirror := range config.options.ListMirrors {
+		list.Mirrors = append(list.Mirrors, strings.TrimRight(mirror, "/")+"/list/"+token.String())
+	}
+
+	// Per-template durations are only recorded for a sample of the renders, to limit the metrics volume
+	var observe filters.InstanceObserver
+	if rate := config.options.RenderSampleRate; rate > 0 && (rate >= 1 || rand.Float64() < rate) {
+		observe = func(template string, elapsed time.Duration) {
+			s.renderTimings.record(template, elapsed)
+			_ = s.statsd.Distribution("letsblockit.template_render_duration", float64(elapsed.Nanoseconds()),
+				[]string{"template:" + template}, 1)
+		}
+	}
+
+	repo, err := s.pinnedRepository(ctx, logger, config.filters, storedInstances)
+	if err != nil {
+		return nil, err
+	}
+
+	out := renderBuffers.Get().(*bytes.Buffer)
+	defer releaseRenderBuffer(out)
+	start := time.Now()
+	if err = list.RenderObserved(ctx, out, logger, repo, observe); err != nil {
+		return nil, fmt.Errorf("failed to render list: %w", err)
+	}
+	elapsed := time.Since(start)
+	s.recordRenderStatus(listID, list)
+	_, span := tracer.Start(ctx, "metrics.RenderList")
+	defer span.End()
+	_ = s.statsd.Distribution("letsblockit.list_render_duration", float64(elapsed.Nanoseconds()), nil, 1)
+	_ = s.statsd.Distribution("letsblockit.list_render_instances", float64(len(list.Instances)), nil, 1)
+	_ = s.statsd.Distribution("letsblockit.list_render_rules", float64(list.Rules), nil, 1)
+	if list.SafeMode {
+		_ = s.statsd.Distribution("letsblockit.list_render_neutralized", float64(list.Neutralized), nil, 1)
+	}
+	if threshold := config.options.SlowRenderThreshold; threshold > 0 && elapsed > threshold {
+		logger.Warnf("slow list render: %d instances took %s", len(list.Instances), elapsed)
+	}
+	// The buffer is reused by the next renders, but the body can be cached: return a copy
+	body := make([]byte, out.Len())
+	copy(body, out.Bytes())
+	return body, nil
+}
+
+// releaseRenderBuffer returns a buffer to the pool, unless a large list made it grow too much
+func releaseRenderBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	renderBuffers.Put(buf)
+}
+
+// checkRenderTimeout returns a 503 error if the render deadline was exceeded, or err otherwise
+func (s *Server) checkRenderTimeout(c echo.Context, renderCtx context.Context, instances int, err error) error {
+	if renderCtx.Err() != context.DeadlineExceeded {
+		return err
+	}
+	c.Logger().Warnf("list render timed out after %s: %d instances", s.config().options.RenderTimeout, instances)
+	_ = s.statsd.Incr("letsblockit.list_render_timeout", nil, 1)
+	return echo.NewHTTPError(http.StatusServiceUnavailable, "list rendering timed out, please retry later")
+}
+
+// countingWriter counts the bytes written, to measure the size of rendered lists
+type countingWriter struct {
+	w       io.Writer
+	written int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.written += int64(n)
+	return n, err
+}
+
+func (s *Server) bannedListBody() string {
+	if body := s.config().bannedList; body != "" {
+		return body
+	}
+	return defaultBannedListBody
+}
+
 func (s *Server) exportList(c echo.Context) error {
 	token, err := uuid.Parse(c.Param("token"))
 	if err != nil {
 		return echo.ErrNotFound
 	}
 
-	var storedInstances []db.GetInstancesForListRow
-	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
-		storedList, e := q.GetListForToken(ctx, token)
-		switch {
-		case e == db.NotFound:
-			return echo.ErrNotFound
-		case e != nil:
-			return e
-		case auth.GetUserId(c) != storedList.UserID:
+	storedList, storedInstances, err := s.getInstancesForToken(c, token, func(storedList db.GetListForTokenRow) error {
+		if auth.GetUserId(c) != storedList.UserID {
 			return echo.ErrForbidden
 		}
-		storedInstances, e = q.GetInstancesForList(ctx, storedList.ID)
-		return e
-	}); err != nil {
+		return nil
+	})
+	if err != nil {
 		return err
 	}
+	return s.writeListExport(c, token.String(), listTitle(storedList.Title), storedInstances)
+}
 
-	list, err := convertFilterList(storedInstances)
+// writeListExport writes the list definition as a YAML file download
+func (s *Server) writeListExport(c echo.Context, token, title string, storedInstances []db.GetInstancesForListRow) error {
+	list, err := convertFilterList(title, storedInstances)
 	if err != nil {
 		return err
 	}
@@ -143,26 +374,100 @@ func (s *Server) exportList(c echo.Context) error {
 	c.Response().Header().Set("Content-Type", "text/yaml")
 	c.Response().Header().Set("Content-Disposition", "attachment; filename=\"exported-filter-list.yaml\"")
 	c.Response().WriteHeader(200)
-	_, err = fmt.Fprintf(c.Response(), listExportTemplate, token, s.now().Format("2006-01-02"))
+	_ = s.encodeListExport(c.Response(), token, list)
+	return nil
+}
+
+func (s *Server) encodeListExport(w io.Writer, token string, list *filters.List) error {
+	if _, err := fmt.Fprintf(w, listExportTemplate, token, s.now().Format("2006-01-02")); err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := node.Encode(list); err != nil {
+		return err
+	}
+	s.commentDisplayNames(&node, list)
+	return yaml.NewEncoder(w).Encode(&node)
+}
+
+// commentDisplayNames adds the display name of the instances above them, for the templates that have one
+func (s *Server) commentDisplayNames(node *yaml.Node, list *filters.List) {
+	repo := s.config().filters
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "instances" {
+			continue
+		}
+		items := node.Content[i+1].Content
+		for n, instance := range list.Instances {
+			if n >= len(items) {
+				break
+			}
+			if tpl, err := repo.Get(instance.Template); err == nil && tpl.HasDisplayName() {
+				items[n].HeadComment = tpl.DisplayName(instance.Params)
+			}
+		}
+	}
+}
+
+// listDefinition returns the list definition as YAML, for use by the render CLI.
+// Like renderList, knowing the list token is enough to access it.
+func (s *Server) listDefinition(c echo.Context) error {
+	token, err := uuid.Parse(strings.TrimSuffix(c.Param("token"), listDefinitionSuffix))
 	if err != nil {
+		return echo.ErrNotFound
+	}
+
+	storedList, storedInstances, err := s.getInstancesForToken(c, token, func(storedList db.GetListForTokenRow) error {
+		if s.bans.IsBanned(storedList.UserID) {
+			return echo.ErrForbidden
+		}
 		return nil
+	})
+	if err != nil {
+		return err
 	}
-	err = yaml.NewEncoder(c.Response()).Encode(&list)
+
+	list, err := convertFilterList(listTitle(storedList.Title), storedInstances)
 	if err != nil {
-		return nil
+		return fmt.Errorf("failed to convert list: %w", err)
 	}
-	return nil
+
+	c.Response().Header().Set(echo.HeaderContentType, "application/yaml")
+	c.Response().WriteHeader(http.StatusOK)
+	return yaml.NewEncoder(c.Response()).Encode(&list)
 }
 
-func convertFilterList(storedInstances []db.GetInstancesForListRow) (*filters.List, error) {
-	list := &filters.List{Title: "My filters"}
-	var customFilterInstances []*filters.Instance
-	for _, storedInstance := range storedInstances {
-		instance := &filters.Instance{
-			Template: storedInstance.TemplateName,
-			Params:   make(map[string]interface{}),
-			TestMode: storedInstance.TestMode,
+// getInstancesForToken retrieves a list and its instances, if checkAccess accepts the list
+func (s *Server) getInstancesForToken(c echo.Context, token uuid.UUID, checkAccess func(db.GetListForTokenRow) error) (db.GetListForTokenRow, []db.GetInstancesForListRow, error) {
+	var storedList db.GetListForTokenRow
+	var storedInstances []db.GetInstancesForListRow
+	err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		var e error
+		storedList, e = q.GetListForToken(ctx, token)
+		switch {
+		case e == db.NotFound:
+			return echo.ErrNotFound
+		case e != nil:
+			return e
+		}
+		if e = checkAccess(storedList); e != nil {
+			return e
 		}
+		storedInstances, e = q.GetInstancesForList(ctx, storedList.ID)
+		return e
+	})
+	return storedList, storedInstances, err
+}
+
+func convertFilterList(title string, storedInstances []db.GetInstancesForListRow) (*filters.List, error) {
+	list := &filters.List{Title: title, Instances: make([]*filters.Instance, 0, len(storedInstances))}
+	var customFilterInstances []*filters.Instance
+	instances := make([]filters.Instance, len(storedInstances))
+	for i, storedInstance := range storedInstances {
+		instance := &instances[i]
+		instance.Template = storedInstance.TemplateName
+		instance.TestMode = storedInstance.TestMode
+		// The params map is allocated while decoding
 		err := storedInstance.Params.AssignTo(&instance.Params)
 		if err != nil {
 			return nil, err
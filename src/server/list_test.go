@@ -2,16 +2,27 @@ package server
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/DataDog/datadog-go/v5/statsd"
 	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/data"
+	"github.com/letsblockit/letsblockit/src/db"
 	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/metrics"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 func (s *ServerTestSuite) TestRenderList_NotFound() {
@@ -29,6 +40,33 @@ func (s *ServerTestSuite) TestRenderList_NotUUID() {
 	})
 }
 
+func (s *ServerTestSuite) TestRenderList_GuessingIsThrottled() {
+	s.server.listGuesses = newGuessLimiter(2, time.Minute, s.server.now)
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	for _, expected := range []int{404, 404, 429, 429} {
+		req := httptest.NewRequest(http.MethodGet, "/list/"+uuid.NewString(), nil)
+		s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, expected, rec.Code)
+		})
+	}
+
+	// Valid tokens are throttled too, other IPs are not
+	req := httptest.NewRequest(http.MethodGet, "/list/"+token.String(), nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, 429, rec.Code)
+	})
+	req = httptest.NewRequest(http.MethodGet, "/list/"+token.String(), nil)
+	req.RemoteAddr = "192.0.2.2:1234"
+	s.runRequest(req, assertOk)
+	req = httptest.NewRequest(http.MethodGet, "/list/invalid", nil)
+	req.RemoteAddr = "192.0.2.2:1234"
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, 404, rec.Code)
+	})
+}
+
 func (s *ServerTestSuite) TestRenderList_OK() {
 	token, err := s.store.CreateListForUser(context.Background(), s.user)
 	require.NoError(s.T(), err)
@@ -114,6 +152,80 @@ func (s *ServerTestSuite) TestRenderList_TxtSuffix() {
 my.do.main###install-prompt-`+token.String()+"\n", rec.Body.String())
 }
 
+func (s *ServerTestSuite) TestRenderList_Metrics() {
+	registry := metrics.NewRegistry()
+	s.server.statsd = registry
+	s.server.options.RenderSampleRate = 1
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	s.addInstance(s.user, "filter1", nil)
+	s.addInstance(s.user, "filter2", filter2Custom)
+
+	req := httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String(), nil)
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+
+	var out strings.Builder
+	require.NoError(s.T(), registry.Write(&out))
+	s.Contains(out.String(), "letsblockit_list_render_duration_seconds_count 1\n")
+	s.Contains(out.String(), "letsblockit_list_render_instances_sum 2\n")
+	s.Contains(out.String(), fmt.Sprintf("letsblockit_list_render_bytes_sum %d\n", rec.Body.Len()))
+	s.Contains(out.String(), `letsblockit_template_render_duration_seconds_count{template="filter1"} 1`)
+	s.Contains(out.String(), `letsblockit_template_render_duration_seconds_count{template="filter2"} 1`)
+}
+
+func (s *ServerTestSuite) TestRenderList_Cache() {
+	registry := metrics.NewRegistry()
+	s.server.statsd = registry
+	s.server.listCache = newListCache(1 << 20)
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	s.addInstance(s.user, "filter2", filter2Custom)
+
+	var bodies []string
+	for _, target := range []string{"http://my.do.main/list/", "http://other.do.main/list/", "http://my.do.main/list/"} {
+		req := httptest.NewRequest(http.MethodGet, target+token.String(), nil)
+		rec := httptest.NewRecorder()
+		s.server.echo.ServeHTTP(rec, req)
+		s.Equal(200, rec.Code)
+		bodies = append(bodies, rec.Body.String())
+	}
+	s.Equal(bodies[0], bodies[2])
+	s.Equal(strings.ReplaceAll(bodies[0], "my.do.main", "other.do.main"), bodies[1], "install prompt is not cached")
+
+	// Test mode renders are cached separately
+	req := httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String()+"?test_mode", nil)
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.NotEqual(bodies[0], rec.Body.String())
+
+	var out strings.Builder
+	require.NoError(s.T(), registry.Write(&out))
+	s.Contains(out.String(), `letsblockit_list_render_cache_total{hit="false"} 2`)
+	s.Contains(out.String(), `letsblockit_list_render_cache_total{hit="true"} 2`)
+	s.Contains(out.String(), "letsblockit_list_render_duration_seconds_count 2\n")
+	count, _ := s.server.listCache.Len()
+	s.Equal(2, count)
+
+	// Removing an older instance does not change the latest update time, but invalidates the cached body
+	s.addInstance(s.user, "filter1", nil)
+	req = httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String(), nil)
+	rec = httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Contains(rec.Body.String(), "! filter2\n")
+	require.NoError(s.T(), s.store.DeleteInstance(context.Background(), db.DeleteInstanceParams{
+		UserID:       s.user,
+		TemplateName: "filter2",
+	}))
+	req = httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String(), nil)
+	rec = httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+	s.Contains(rec.Body.String(), "! filter1\n")
+	s.NotContains(rec.Body.String(), "! filter2\n")
+}
+
 func (s *ServerTestSuite) TestRenderList_ETag() {
 	token, err := s.store.CreateListForUser(context.Background(), s.user)
 	require.NoError(s.T(), err)
@@ -124,7 +236,7 @@ func (s *ServerTestSuite) TestRenderList_ETag() {
 	s.server.echo.ServeHTTP(rec, req)
 	s.Equal(200, rec.Code)
 	etag1 := rec.Header().Get("etag")
-	s.Equal(etag1, s.server.filterHash)
+	s.Equal([]string{`W/"` + s.server.filterHash + `"`}, rec.Header().Values("Etag"))
 
 	// Repeat request should get a 304
 	req.Header.Set("If-None-Match", etag1)
@@ -132,6 +244,12 @@ func (s *ServerTestSuite) TestRenderList_ETag() {
 	s.server.echo.ServeHTTP(rec, req)
 	s.Equal(http.StatusNotModified, rec.Code)
 
+	// The unquoted etags served by previous versions still match
+	req.Header.Set("If-None-Match", s.server.filterHash)
+	rec = httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(http.StatusNotModified, rec.Code)
+
 	// Adding a filter instance changes the etag
 	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "custom-rules"}))
 	rec = httptest.NewRecorder()
@@ -139,8 +257,8 @@ func (s *ServerTestSuite) TestRenderList_ETag() {
 	s.Equal(200, rec.Code)
 	etag2 := rec.Header().Get("etag")
 	s.NotEqual(etag1, etag2)
-	s.Len(etag2, 27)
-	s.True(strings.HasPrefix(etag2, s.server.filterHash))
+	s.Regexp(`\d{14}-c[0-9a-z]+"$`, etag2)
+	s.True(strings.HasPrefix(etag2, `W/"`+s.server.filterHash))
 
 	// A change to the template hash changes the etag too
 	req.Header.Set("If-None-Match", etag2)
@@ -150,8 +268,84 @@ func (s *ServerTestSuite) TestRenderList_ETag() {
 	s.Equal(200, rec.Code)
 	etag3 := rec.Header().Get("etag")
 	s.NotEqual(etag3, etag2)
-	s.Len(etag3, 27)
-	s.True(strings.HasPrefix(etag3, s.server.filterHash))
+	s.Regexp(`\d{14}-c[0-9a-z]+"$`, etag3)
+	s.True(strings.HasPrefix(etag3, `W/"`+s.server.filterHash))
+}
+
+func (s *ServerTestSuite) TestRenderList_ETagTransition() {
+	registry := metrics.NewRegistry()
+	s.server.statsd = registry
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	// Both the quoted etag and the legacy unquoted one are counted as matches
+	for _, header := range []string{`W/"` + s.server.filterHash + `"`, s.server.filterHash, `"outdated"`} {
+		req := httptest.NewRequest(http.MethodGet, "/list/"+token.String(), nil)
+		req.Header.Set("If-None-Match", header)
+		rec := httptest.NewRecorder()
+		s.server.echo.ServeHTTP(rec, req)
+		if header == `"outdated"` {
+			s.Equal(http.StatusOK, rec.Code)
+		} else {
+			s.Equal(http.StatusNotModified, rec.Code, header)
+		}
+	}
+
+	var out strings.Builder
+	require.NoError(s.T(), registry.Write(&out))
+	s.Contains(out.String(), `letsblockit_list_download_total{banned="false",etag_match="true",etag_present="true"} 2`)
+	s.Contains(out.String(), `letsblockit_list_download_total{banned="false",etag_match="false",etag_present="true"} 1`)
+}
+
+func (s *ServerTestSuite) TestRenderList_Timeout() {
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	s.addInstance(s.user, "filter2", filter2Custom)
+
+	req := httptest.NewRequest(http.MethodGet, "/list/"+token.String(), nil)
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+	etag := rec.Header().Get("etag")
+
+	// Not modified responses are not affected by the deadline
+	s.server.options.RenderTimeout = time.Nanosecond
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(http.StatusNotModified, rec.Code)
+
+	req.Header.Del("If-None-Match")
+	rec = httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(http.StatusServiceUnavailable, rec.Code)
+	s.Contains(rec.Body.String(), "list rendering timed out")
+}
+
+func (s *ServerTestSuite) TestRenderList_ProxyETags() {
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	req := httptest.NewRequest(http.MethodGet, "/list/"+token.String(), nil)
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+	etag := strings.TrimSuffix(strings.TrimPrefix(rec.Header().Get("etag"), `W/"`), `"`)
+
+	for _, header := range []string{
+		`"outdated", W/"` + etag + `-gzip"`,
+		`W/"` + etag + `"`,
+		"*",
+	} {
+		req.Header.Set("If-None-Match", header)
+		rec = httptest.NewRecorder()
+		s.server.echo.ServeHTTP(rec, req)
+		s.Equal(http.StatusNotModified, rec.Code, header)
+	}
+
+	req.Header.Set("If-None-Match", `"outdated", "`+etag)
+	rec = httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(http.StatusOK, rec.Code)
 }
 
 func (s *ServerTestSuite) TestRenderList_OfficialInstance() {
@@ -172,6 +366,92 @@ func (s *ServerTestSuite) TestRenderList_OfficialInstance() {
 letsblock.it###install-prompt-`+token.String()+"\n", rec.Body.String())
 }
 
+func (s *ServerTestSuite) TestRenderList_BehindProxy() {
+	s.server.proxyRanges, _ = parseTrustedProxies([]string{"10.0.0.0/24"})
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8765/list/"+token.String(), nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Set("X-Forwarded-Host", "blocks.example.org")
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+	s.Equal(`! Title: letsblock.it - My filters
+! Expires: 12 hours
+! Homepage: https://letsblock.it
+! License: https://github.com/letsblockit/letsblockit/blob/main/LICENSE.txt
+
+! Hide the list install prompt for that list
+blocks.example.org###install-prompt-`+token.String()+"\n", rec.Body.String())
+}
+
+func (s *ServerTestSuite) TestRenderList_PublicHostname() {
+	s.server.options.PublicHostname = "lists.example.com"
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8765/list/"+token.String(), nil)
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+	s.Equal(`! Title: letsblock.it - My filters
+! Expires: 12 hours
+! Homepage: https://lists.example.com
+! License: https://github.com/letsblockit/letsblockit/blob/main/LICENSE.txt
+
+! Hide the list install prompt for that list
+lists.example.com###install-prompt-`+token.String()+"\n", rec.Body.String())
+}
+
+func (s *ServerTestSuite) TestRenderList_Mirrors() {
+	s.server.options.ListMirrors = []string{"https://mirror.example.com/", "https://backup.example.org"}
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8765/list/"+token.String(), nil)
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+	s.Equal(`! Title: letsblock.it - My filters
+! Expires: 12 hours
+! Homepage: https://letsblock.it
+! License: https://github.com/letsblockit/letsblockit/blob/main/LICENSE.txt
+! Alternate mirror: https://mirror.example.com/list/`+token.String()+`
+! Alternate mirror: https://backup.example.org/list/`+token.String()+`
+
+! Hide the list install prompt for that list
+localhost###install-prompt-`+token.String()+"\n", rec.Body.String())
+}
+
+func (s *ServerTestSuite) TestRenderList_SafeMode() {
+	s.server.options.ListSafeMode = true
+	s.addInstance(s.user, "custom-rules", map[string]interface{}{"rules": "example.com##.ad\nexample.com##div:has(.ad"})
+	list, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "/list/"+list.Token.String(), nil)
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+	s.Contains(rec.Body.String(), "\nexample.com##.ad\n! INVALID: example.com##div:has(.ad")
+}
+
+func (s *ServerTestSuite) TestRenderList_SizeWarning() {
+	s.server.options.RuleCountWarning = 2
+	s.addInstance(s.user, "custom-rules", map[string]interface{}{"rules": "##.one\n##.two\n##.three"})
+	list, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "/list/"+list.Token.String(), nil)
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+	s.Equal("3", rec.Header().Get(ruleCountHeader))
+	s.Contains(rec.Body.String(), "! WARNING: this list holds 3 rules, above the limit of 2")
+	s.Contains(rec.Body.String(), "! custom-rules #1 contributed 3 rules\n")
+}
+
 func (s *ServerTestSuite) TestRenderList_WithReferer() {
 	token, err := s.store.CreateListForUser(context.Background(), s.user)
 	require.NoError(s.T(), err)
@@ -195,7 +475,28 @@ func (s *ServerTestSuite) TestRenderList_BannedUser() {
 	req.Header.Set("Referer", "https://letsblock.it/user/account")
 	rec := httptest.NewRecorder()
 	s.server.echo.ServeHTTP(rec, req)
-	s.Equal(403, rec.Code)
+	s.Equal(200, rec.Code)
+	s.Equal(defaultBannedListBody, rec.Body.String())
+	s.Equal(`W/"`+bannedListETag+`"`, rec.Header().Get("Etag"))
+
+	req = httptest.NewRequest(http.MethodGet, "/list/"+token.String(), nil)
+	req.Header.Set("If-None-Match", bannedListETag)
+	rec = httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(304, rec.Code)
+}
+
+func (s *ServerTestSuite) TestRenderList_BannedUserCustomBody() {
+	s.setUserBanned()
+	s.server.bannedList = "! Custom message\n"
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "/list/"+token.String(), nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.Equal(t, "! Custom message\n", rec.Body.String())
+	})
 }
 
 func (s *ServerTestSuite) TestExportList_OK() {
@@ -259,3 +560,161 @@ func (s *ServerTestSuite) TestExportList_BadUser() {
 	s.server.echo.ServeHTTP(rec, req)
 	s.Equal(403, rec.Code)
 }
+
+func (s *ServerTestSuite) TestListDefinition_OK() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   map[string]any{"one": "blep"},
+	}))
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+
+	list, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	// Does not require a user session
+	s.user = ""
+	for _, suffix := range []string{"", ".yaml"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/list/"+list.Token.String()+suffix, nil)
+		rec := httptest.NewRecorder()
+		s.server.echo.ServeHTTP(rec, req)
+		s.Equal(200, rec.Code)
+		s.Equal("application/yaml", rec.Header().Get("Content-Type"))
+		s.Equal(`title: My filters
+instances:
+    - template: filter1
+    - template: filter2
+      params:
+        one: blep
+`, rec.Body.String())
+	}
+}
+
+func (s *ServerTestSuite) TestListDefinition_NotFound() {
+	req := httptest.NewRequest(http.MethodGet, "/api/list/"+uuid.New().String(), nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, 404, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestListDefinition_BannedUser() {
+	s.setUserBanned()
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/list/"+token.String(), nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, 403, rec.Code)
+	})
+}
+
+// benchmarkRows returns a list holding one instance of each template with its default params,
+// and all their presets enabled if withPresets is set
+func benchmarkRows(b *testing.B, withPresets bool) (*filters.Repository, []db.GetInstancesForListRow) {
+	repo, err := filters.Load(data.Templates, data.Presets)
+	require.NoError(b, err)
+	var rows []db.GetInstancesForListRow
+	for _, tpl := range repo.GetAll() {
+		params := make(map[string]interface{}, len(tpl.Params))
+		for _, p := range tpl.Params {
+			params[p.Name] = p.Default
+			for _, preset := range p.Presets {
+				params[p.BuildPresetParamName(preset.Name)] = withPresets
+			}
+		}
+		var stored pgtype.JSONB
+		require.NoError(b, stored.Set(params))
+		rows = append(rows, db.GetInstancesForListRow{TemplateName: tpl.Name, Params: stored})
+	}
+	return repo, rows
+}
+
+func TestListTitle(t *testing.T) {
+	assert.Equal(t, filters.DefaultListTitle, listTitle(sql.NullString{}))
+	assert.Equal(t, filters.DefaultListTitle, listTitle(sql.NullString{String: " \n\t", Valid: true}))
+	assert.Equal(t, "Work | ads! ! Expires: 1 day", listTitle(sql.NullString{String: "Work | ads!\r\n! Expires: 1 day", Valid: true}))
+	assert.Equal(t, strings.Repeat("a", 100), listTitle(sql.NullString{String: strings.Repeat("a", 150), Valid: true}))
+}
+
+func TestTitleETag(t *testing.T) {
+	// Unnamed lists keep their etag, named lists get a different one per rendered title
+	assert.Empty(t, titleETag(sql.NullString{}))
+	assert.Empty(t, titleETag(sql.NullString{String: filters.DefaultListTitle, Valid: true}))
+	named := titleETag(sql.NullString{String: "Work | ads!", Valid: true})
+	assert.NotEmpty(t, named)
+	assert.NotEqual(t, named, titleETag(sql.NullString{String: "Home | ads!", Valid: true}))
+	assert.Equal(t, named, titleETag(sql.NullString{String: "Work |\n ads!", Valid: true}), "same rendered title")
+
+	s := &Server{filterHash: "hash", options: &Options{}}
+	storedList := db.GetListForTokenRow{Title: sql.NullString{String: "Work | ads!", Valid: true}}
+	assert.Equal(t, "hash"+named, s.buildListETag(storedList))
+	assert.Equal(t, "hash", s.buildContentETag(storedList), "other formats do not render the title")
+}
+
+func TestListTitle_RenderAndExport(t *testing.T) {
+	repo, err := filters.Load(data.Templates, data.Presets)
+	require.NoError(t, err)
+	s := &Server{filters: repo, statsd: &statsd.NoOpClient{}, options: &Options{}, now: func() time.Time { return fixedNow }}
+	title := listTitle(sql.NullString{String: "Work | ads!\n||example.com^", Valid: true})
+	var params pgtype.JSONB
+	require.NoError(t, params.Set(map[string]interface{}{"rules": "example.org##.ad\n"}))
+	rows := []db.GetInstancesForListRow{{TemplateName: filters.CustomRulesFilterName, Params: params}}
+
+	body, err := s.renderListBody(context.Background(), echo.New().Logger, 0, uuid.New(), title, rows, false)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(body), "! Title: letsblock.it - Work | ads! ||example.com^\n! Expires: 12 hours\n"))
+	assert.Equal(t, 1, filters.CountRules(body), "the title is not parsed as a rule")
+
+	list, err := convertFilterList(title, rows)
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, s.encodeListExport(&out, "token", list))
+	assert.Contains(t, out.String(), "title: Work | ads! ||example.com^\n")
+}
+
+func TestExportList_DisplayNames(t *testing.T) {
+	repo, err := filters.Load(fstest.MapFS{
+		"named.yaml": {Data: []byte("title: Named\nparams:\n  - name: note\n    type: string\n    default: \"\"\n" +
+			"display_name_template: \"Named ({{ .note }})\"\ntemplate: \"named##rule\"\n---\n\nNamed template")},
+		"plain.yaml": {Data: []byte("title: Plain\ntemplate: \"plain##rule\"\n---\n\nPlain template")},
+	}, fstest.MapFS{})
+	require.NoError(t, err)
+	s := &Server{filters: repo, options: &Options{}, now: func() time.Time { return fixedNow }}
+	list := &filters.List{Title: filters.DefaultListTitle, Instances: []*filters.Instance{
+		{Template: "named", Params: map[string]interface{}{"note": "work\n# injected"}},
+		{Template: "plain"},
+	}}
+
+	var out strings.Builder
+	require.NoError(t, s.encodeListExport(&out, "token", list))
+	assert.Contains(t, out.String(), "instances:\n    # Named (work # injected)\n    - template: named\n")
+	assert.Contains(t, out.String(), "\n    - template: plain\n")
+
+	var decoded filters.List
+	require.NoError(t, yaml.Unmarshal([]byte(out.String()), &decoded))
+	assert.Len(t, decoded.Instances, 2)
+}
+
+func BenchmarkConvertFilterList(b *testing.B) {
+	_, rows := benchmarkRows(b, false)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := convertFilterList(filters.DefaultListTitle, rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderListBody(b *testing.B) {
+	repo, rows := benchmarkRows(b, false)
+	s := &Server{filters: repo, statsd: &statsd.NoOpClient{}, options: &Options{}}
+	logger := echo.New().Logger
+	logger.SetOutput(io.Discard)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.renderListBody(context.Background(), logger, 0, uuid.New(), filters.DefaultListTitle, rows, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
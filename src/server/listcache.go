@@ -0,0 +1,115 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+)
+
+// listCacheEntryOverhead approximates the memory used by an entry besides its key and body
+const listCacheEntryOverhead = 128
+
+type listCacheEntry struct {
+	key     string
+	listKey string
+	body    []byte
+}
+
+// listCache is an LRU cache of rendered list bodies, bounded by their total size.
+// The latest body of each list is also indexed, to be served if the database is unavailable.
+type listCache struct {
+	sync.Mutex
+	maxBytes int
+	size     int
+	entries  map[string]*list.Element
+	latest   map[string]*list.Element
+	order    *list.List // Most recently used first
+}
+
+// newListCache returns a cache holding up to maxBytes, or nil if maxBytes is zero
+func newListCache(maxBytes int) *listCache {
+	if maxBytes <= 0 {
+		return nil
+	}
+	return &listCache{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		latest:   make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func entrySize(key string, body []byte) int {
+	return len(key) + len(body) + listCacheEntryOverhead
+}
+
+// Get returns the cached body and marks it as recently used
+func (l *listCache) Get(key string) ([]byte, bool) {
+	if l == nil {
+		return nil, false
+	}
+	l.Lock()
+	defer l.Unlock()
+	element, found := l.entries[key]
+	if !found {
+		return nil, false
+	}
+	l.order.MoveToFront(element)
+	return element.Value.(*listCacheEntry).body, true
+}
+
+// GetStale returns the latest body stored for a list, whatever its version
+func (l *listCache) GetStale(listKey string) ([]byte, bool) {
+	if l == nil {
+		return nil, false
+	}
+	l.Lock()
+	defer l.Unlock()
+	element, found := l.latest[listKey]
+	if !found {
+		return nil, false
+	}
+	return element.Value.(*listCacheEntry).body, true
+}
+
+// Set stores the body of a list version, evicting the least recently used entries to stay within
+// the size limit. Bodies larger than the limit are not stored.
+func (l *listCache) Set(key, listKey string, body []byte) {
+	if l == nil {
+		return
+	}
+	size := entrySize(key, body)
+	if size > l.maxBytes {
+		return
+	}
+	l.Lock()
+	defer l.Unlock()
+	if element, found := l.entries[key]; found {
+		l.remove(element)
+	}
+	for l.size+size > l.maxBytes {
+		l.remove(l.order.Back())
+	}
+	element := l.order.PushFront(&listCacheEntry{key: key, listKey: listKey, body: body})
+	l.entries[key] = element
+	l.latest[listKey] = element
+	l.size += size
+}
+
+func (l *listCache) remove(element *list.Element) {
+	entry := l.order.Remove(element).(*listCacheEntry)
+	delete(l.entries, entry.key)
+	if l.latest[entry.listKey] == element {
+		delete(l.latest, entry.listKey)
+	}
+	l.size -= entrySize(entry.key, entry.body)
+}
+
+// Len returns the number of entries and their total size
+func (l *listCache) Len() (int, int) {
+	if l == nil {
+		return 0, 0
+	}
+	l.Lock()
+	defer l.Unlock()
+	return len(l.entries), l.size
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListCache_Disabled(t *testing.T) {
+	cache := newListCache(0)
+	assert.Nil(t, cache)
+	cache.Set("key", "key", []byte("body"))
+	_, found := cache.Get("key")
+	assert.False(t, found)
+}
+
+func TestListCache_Accounting(t *testing.T) {
+	cache := newListCache(1024)
+	cache.Set("one", "one", []byte("first"))
+	cache.Set("two", "two", []byte("second"))
+	count, size := cache.Len()
+	assert.Equal(t, 2, count)
+	assert.Equal(t, entrySize("one", []byte("first"))+entrySize("two", []byte("second")), size)
+
+	// Replacing an entry updates its size
+	cache.Set("one", "one", []byte("replaced body"))
+	count, size = cache.Len()
+	assert.Equal(t, 2, count)
+	assert.Equal(t, entrySize("one", []byte("replaced body"))+entrySize("two", []byte("second")), size)
+	body, found := cache.Get("one")
+	assert.True(t, found)
+	assert.Equal(t, "replaced body", string(body))
+}
+
+func TestListCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	body := []byte(strings.Repeat("a", 100))
+	cache := newListCache(3 * entrySize("k1", body))
+	cache.Set("k1", "k1", body)
+	cache.Set("k2", "k2", body)
+	cache.Set("k3", "k3", body)
+	_, found := cache.Get("k1") // k2 is now the least recently used
+	assert.True(t, found)
+
+	cache.Set("k4", "k4", body)
+	_, found = cache.Get("k2")
+	assert.False(t, found)
+	for _, key := range []string{"k1", "k3", "k4"} {
+		_, found = cache.Get(key)
+		assert.True(t, found, key)
+	}
+	count, size := cache.Len()
+	assert.Equal(t, 3, count)
+	assert.Equal(t, 3*entrySize("k1", body), size)
+
+	// Larger entries evict as many entries as needed
+	cache.Set("large", "large", []byte(strings.Repeat("b", 250)))
+	count, size = cache.Len()
+	assert.Equal(t, 2, count)
+	assert.LessOrEqual(t, size, cache.maxBytes)
+	_, found = cache.Get("k4")
+	assert.True(t, found, "the most recent entry is kept")
+}
+
+func TestListCache_SkipsOversizedBodies(t *testing.T) {
+	cache := newListCache(200)
+	cache.Set("small", "small", []byte("body"))
+	cache.Set("large", "large", []byte(strings.Repeat("a", 200)))
+	_, found := cache.Get("large")
+	assert.False(t, found)
+	_, found = cache.Get("small")
+	assert.True(t, found, "existing entries are kept")
+}
+
+func TestListCache_Stale(t *testing.T) {
+	cache := newListCache(1024)
+	cache.Set("list:v1", "list", []byte("first"))
+	cache.Set("list:v2", "list", []byte("second"))
+	body, found := cache.GetStale("list")
+	assert.True(t, found)
+	assert.Equal(t, "second", string(body), "the latest version is returned")
+
+	// Evicting the latest version drops the stale entry, older versions are not served
+	cache.remove(cache.entries["list:v2"])
+	_, found = cache.GetStale("list")
+	assert.False(t, found)
+	_, found = cache.Get("list:v1")
+	assert.True(t, found)
+
+	var disabled *listCache
+	_, found = disabled.GetStale("list")
+	assert.False(t, found)
+}
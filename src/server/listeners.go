@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+const (
+	publicRoutes  = "public"  // Pages, lists and API
+	privateRoutes = "private" // Health checks, metrics and admin pages
+
+	defaultSocketMode = 0o660
+	unixRemoteAddr    = "127.0.0.1:0"
+)
+
+// listenSpec is a parsed --listen value, like tcp://127.0.0.1:8765 or unix:///run/letsblockit.sock?mode=0660.
+// The routes parameter restricts the listener to one of the route groups, both are served by default.
+type listenSpec struct {
+	spec     string
+	network  string
+	address  string
+	mode     os.FileMode
+	routes   map[string]bool
+	listener net.Listener
+
+	challenge bool // Serves the ACME challenges and redirects to HTTPS instead of the routes
+}
+
+type listenSpecKey struct{}
+
+func parseListenSpec(spec string) (*listenSpec, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen spec %s: %w", spec, err)
+	}
+	l := &listenSpec{
+		spec:    spec,
+		network: u.Scheme,
+		mode:    defaultSocketMode,
+		routes:  map[string]bool{publicRoutes: true, privateRoutes: true},
+	}
+	switch u.Scheme {
+	case "tcp":
+		l.address = u.Host
+	case "unix":
+		l.address = u.Path
+	default:
+		return nil, fmt.Errorf("invalid listen spec %s: unsupported network %q", spec, u.Scheme)
+	}
+	if l.address == "" {
+		return nil, fmt.Errorf("invalid listen spec %s: missing address", spec)
+	}
+
+	query := u.Query()
+	if mode := query.Get("mode"); mode != "" {
+		if l.network != "unix" {
+			return nil, fmt.Errorf("invalid listen spec %s: mode is only supported for unix sockets", spec)
+		}
+		parsed, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || parsed > 0o777 {
+			return nil, fmt.Errorf("invalid listen spec %s: invalid mode %s", spec, mode)
+		}
+		l.mode = os.FileMode(parsed)
+	}
+	if group := query.Get("routes"); group != "" {
+		if group != publicRoutes && group != privateRoutes {
+			return nil, fmt.Errorf("invalid listen spec %s: unknown route group %q", spec, group)
+		}
+		l.routes = map[string]bool{group: true}
+	}
+	return l, nil
+}
+
+// listen opens the listener. Stale unix sockets left by a crash are removed, but not the ones still in use.
+func (l *listenSpec) listen() error {
+	if l.network != "unix" {
+		var err error
+		l.listener, err = net.Listen(l.network, l.address)
+		return err
+	}
+
+	if info, err := os.Stat(l.address); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("cannot listen on %s: file exists and is not a socket", l.address)
+		}
+		if conn, err := net.DialTimeout("unix", l.address, time.Second); err == nil {
+			_ = conn.Close()
+			return fmt.Errorf("cannot listen on %s: socket is in use", l.address)
+		}
+		if err := os.Remove(l.address); err != nil {
+			return fmt.Errorf("cannot remove stale socket: %w", err)
+		}
+	}
+	listener, err := net.Listen("unix", l.address)
+	if err != nil {
+		return err
+	}
+	// The socket file is removed when the listener is closed
+	if err = os.Chmod(l.address, l.mode); err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("cannot set socket permissions: %w", err)
+	}
+	l.listener = listener
+	return nil
+}
+
+// routeGroup returns the route group of a request path
+func routeGroup(path string) string {
+	if isHealthPath(path) || path == metricsPath || path == "/admin" || strings.HasPrefix(path, "/admin/") {
+		return privateRoutes
+	}
+	return publicRoutes
+}
+
+// bindListener returns a 404 error for the routes not bound to the listener the request came from.
+// Requests from unix sockets get a loopback remote address, to trust them like a proxy on the same host.
+func bindListener(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		if spec, ok := req.Context().Value(listenSpecKey{}).(*listenSpec); ok {
+			if !spec.routes[routeGroup(req.URL.Path)] {
+				return echo.ErrNotFound
+			}
+			if spec.network == "unix" {
+				req.RemoteAddr = unixRemoteAddr
+			}
+		}
+		return next(c)
+	}
+}
+
+// buildListenSpecs parses the --listen values, the --address is used if none is set.
+// With TLS enabled, the challenge listener is added after them.
+func (s *Server) buildListenSpecs() ([]*listenSpec, error) {
+	var specs []*listenSpec
+	if len(s.options.Listen) == 0 {
+		specs = append(specs, &listenSpec{
+			spec:    "tcp://" + s.options.Address,
+			network: "tcp",
+			address: s.options.Address,
+			routes:  map[string]bool{publicRoutes: true, privateRoutes: true},
+		})
+	} else {
+		if s.options.UseSystemdSocket {
+			return nil, errors.New("cannot use a systemd socket with listen specs")
+		}
+		for _, value := range s.options.Listen {
+			spec, err := parseListenSpec(value)
+			if err != nil {
+				return nil, err
+			}
+			specs = append(specs, spec)
+		}
+	}
+	if s.options.TlsDomain != "" {
+		specs = append(specs, s.buildChallengeSpec())
+	}
+	return specs, nil
+}
+
+// serveListeners opens the listeners that are not open yet, and serves requests on all of them. It returns
+// when one of them fails, the others are closed by shutdown.
+func (s *Server) serveListeners(specs []*listenSpec) error {
+	for _, spec := range specs {
+		if spec.listener != nil {
+			continue
+		}
+		if err := spec.listen(); err != nil {
+			for _, opened := range specs {
+				if opened.listener != nil {
+					_ = opened.listener.Close()
+				}
+			}
+			return err
+		}
+	}
+
+	errs := make(chan error, len(specs))
+	s.serversLock.Lock()
+	for _, spec := range specs {
+		spec := spec
+		server := &http.Server{
+			Handler:           s.echo,
+			ReadHeaderTimeout: 10 * time.Second,
+			ConnContext: func(ctx context.Context, _ net.Conn) context.Context {
+				return context.WithValue(ctx, listenSpecKey{}, spec)
+			},
+		}
+		s.servers = append(s.servers, server)
+		switch {
+		case spec.challenge:
+			server.Handler = s.certs.HTTPHandler(nil)
+			fmt.Println("Serving ACME challenges on", spec.spec)
+			go func() { errs <- server.Serve(spec.listener) }()
+		case s.certs != nil && spec.network == "tcp":
+			// Unix sockets are used by reverse proxies on the same host, that terminate TLS
+			server.TLSConfig = buildTLSConfig(s.certs)
+			fmt.Println("Listening with TLS on", spec.spec)
+			go func() { errs <- server.ServeTLS(spec.listener, "", "") }()
+		case s.options.H2c:
+			enableH2c(server)
+			fmt.Println("Listening with h2c on", spec.spec)
+			go func() { errs <- server.Serve(spec.listener) }()
+		default:
+			fmt.Println("Listening on", spec.spec)
+			go func() { errs <- server.Serve(spec.listener) }()
+		}
+	}
+	s.serversLock.Unlock()
+
+	for range specs {
+		if err := <-errs; err != http.ErrServerClosed {
+			return err
+		}
+	}
+	return nil
+}
+
+// enableH2c accepts HTTP/2 without TLS, for reverse proxies speaking h2c to their upstreams. The h2c connections
+// are hijacked from the server: registering the HTTP/2 server sends them a GOAWAY on shutdown.
+func enableH2c(server *http.Server) {
+	h2s := &http2.Server{}
+	_ = http2.ConfigureServer(server, h2s) // Only fails on TLS configurations, that h2c does not use
+	server.Handler = h2c.NewHandler(server.Handler, h2s)
+}
+
+// shutdownServers stops the listeners and drains the in-flight requests of all servers
+func (s *Server) shutdownServers(ctx context.Context) error {
+	err := s.echo.Shutdown(ctx)
+	s.serversLock.Lock()
+	defer s.serversLock.Unlock()
+	for _, server := range s.servers {
+		if e := server.Shutdown(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
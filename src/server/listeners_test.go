@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+func TestParseListenSpec(t *testing.T) {
+	spec, err := parseListenSpec("tcp://0.0.0.0:8765")
+	require.NoError(t, err)
+	assert.Equal(t, "tcp", spec.network)
+	assert.Equal(t, "0.0.0.0:8765", spec.address)
+	assert.Equal(t, map[string]bool{publicRoutes: true, privateRoutes: true}, spec.routes)
+
+	spec, err = parseListenSpec("unix:///run/letsblockit.sock?mode=0600&routes=public")
+	require.NoError(t, err)
+	assert.Equal(t, "unix", spec.network)
+	assert.Equal(t, "/run/letsblockit.sock", spec.address)
+	assert.Equal(t, os.FileMode(0o600), spec.mode)
+	assert.Equal(t, map[string]bool{publicRoutes: true}, spec.routes)
+
+	spec, err = parseListenSpec("tcp://127.0.0.1:9090?routes=private")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{privateRoutes: true}, spec.routes)
+
+	for _, invalid := range []string{
+		"127.0.0.1:8765",
+		"udp://127.0.0.1:8765",
+		"tcp://",
+		"unix://",
+		"tcp://127.0.0.1:8765?mode=0600",
+		"unix:///run/letsblockit.sock?mode=999",
+		"tcp://127.0.0.1:8765?routes=admin",
+	} {
+		_, err = parseListenSpec(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRouteGroup(t *testing.T) {
+	for _, path := range []string{healthPath, livenessPath, readinessPath, metricsPath, "/admin", "/admin/bans"} {
+		assert.Equal(t, privateRoutes, routeGroup(path), path)
+	}
+	for _, path := range []string{"/", "/list/abcd", "/api/v1/filters", "/administration"} {
+		assert.Equal(t, publicRoutes, routeGroup(path), path)
+	}
+}
+
+func TestBuildListenSpecs(t *testing.T) {
+	s := &Server{options: &Options{Address: "127.0.0.1:8765"}}
+	specs, err := s.buildListenSpecs()
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+	assert.Equal(t, "127.0.0.1:8765", specs[0].address)
+
+	s.options.Listen = []string{"tcp://127.0.0.1:8765", "unix:///run/letsblockit.sock"}
+	specs, err = s.buildListenSpecs()
+	require.NoError(t, err)
+	assert.Len(t, specs, 2)
+
+	s.options.UseSystemdSocket = true
+	_, err = s.buildListenSpecs()
+	assert.Error(t, err)
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+	spec, err := parseListenSpec("unix://" + path + "?mode=0600")
+	require.NoError(t, err)
+	require.NoError(t, spec.listen())
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Sockets in use are not removed
+	other, _ := parseListenSpec("unix://" + path)
+	assert.ErrorContains(t, other.listen(), "socket is in use")
+
+	// Stale sockets are removed
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: filepath.Join(t.TempDir(), "stale.sock"), Net: "unix"})
+	require.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+	reused, _ := parseListenSpec("unix://" + stale.Addr().String())
+	require.NoError(t, reused.listen())
+	require.NoError(t, reused.listener.Close())
+
+	// Other files are not removed
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	notSocket, _ := parseListenSpec("unix://" + file)
+	assert.ErrorContains(t, notSocket.listen(), "not a socket")
+
+	require.NoError(t, spec.listener.Close())
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist, "socket is removed on close")
+}
+
+func TestServeListeners(t *testing.T) {
+	s := &Server{echo: echo.New(), options: &Options{ShutdownTimeout: time.Second}, statsd: &statsd.NoOpClient{}}
+	s.echo.Pre(bindListener)
+	s.echo.GET(healthPath, s.healthCheck)
+	s.echo.GET("/ip", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.RealIP())
+	})
+
+	socket := filepath.Join(t.TempDir(), "test.sock")
+	public, err := parseListenSpec("unix://" + socket + "?routes=public")
+	require.NoError(t, err)
+	private, err := parseListenSpec("tcp://127.0.0.1:0?routes=private")
+	require.NoError(t, err)
+	require.NoError(t, private.listen())
+
+	served := make(chan error, 1)
+	go func() { served <- s.serveListeners([]*listenSpec{public, private}) }()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(socket)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	get := func(client *http.Client, target string) (int, string) {
+		resp, err := client.Get(target)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get(unixClient, "http://unix/ip")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "127.0.0.1", body)
+	code, _ = get(unixClient, "http://unix"+healthPath)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	tcpTarget := "http://" + private.listener.Addr().String()
+	code, _ = get(http.DefaultClient, tcpTarget+healthPath)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = get(http.DefaultClient, tcpTarget+"/ip")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	require.NoError(t, s.shutdownServers(context.Background()))
+	require.NoError(t, <-served)
+	_, err = os.Stat(socket)
+	assert.ErrorIs(t, err, os.ErrNotExist, "socket is removed on shutdown")
+	_, err = http.Get(tcpTarget + healthPath)
+	assert.Error(t, err)
+}
+
+func TestServeListeners_H2c(t *testing.T) {
+	registry := metrics.NewRegistry()
+	s := &Server{echo: echo.New(), options: &Options{H2c: true}, statsd: registry}
+	s.echo.Use(buildRequestIDMiddleware(), buildDogstatsMiddleware(registry, s.echo))
+	chunks := []string{"! Title: letsblock.it - My filters\n", "example.com##.ad\n", "example.org##.banner\n"}
+	s.echo.GET("/list/:token", func(c echo.Context) error {
+		c.Response().WriteHeader(http.StatusOK)
+		for _, chunk := range chunks {
+			if _, err := io.WriteString(c.Response(), chunk); err != nil {
+				return err
+			}
+			c.Response().Flush()
+		}
+		return nil
+	})
+
+	spec, err := parseListenSpec("tcp://127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, spec.listen())
+	served := make(chan error, 1)
+	go func() { served <- s.serveListeners([]*listenSpec{spec}) }()
+
+	// Prior knowledge h2c, as used by reverse proxies
+	h2cClient := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	target := "http://" + spec.listener.Addr().String() + "/list/" + uuid.NewString()
+	for i := 0; i < 2; i++ {
+		resp, err := h2cClient.Get(target)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, "HTTP/2.0", resp.Proto)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, strings.Join(chunks, ""), string(body))
+		assert.NotEmpty(t, resp.Header.Get(echo.HeaderXRequestID))
+	}
+
+	// HTTP/1.1 clients are still served
+	resp, err := http.Get(target)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "HTTP/1.1", resp.Proto)
+
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(),
+		`letsblockit_request_count_total{logged="false",method="GET",route="/list/:token",status="200"} 3`)
+
+	require.NoError(t, s.shutdownServers(context.Background()))
+	require.NoError(t, <-served)
+	_, err = h2cClient.Get(target)
+	assert.Error(t, err)
+}
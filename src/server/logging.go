@@ -0,0 +1,211 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/labstack/gommon/log"
+	"github.com/labstack/gommon/random"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// panicListBody is served when rendering a list panics, as adblockers expect a filter list
+const panicListBody = `! Title: letsblock.it - Temporary error
+!
+! This filter list could not be generated because of an internal error.
+! It will be updated again on the next scheduled update.
+`
+
+// Incoming request IDs are kept if they look like the ones generated by proxies, to correlate their logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// buildRequestIDMiddleware assigns an ID to each request, and sets up the request logger
+func buildRequestIDMiddleware() echo.MiddlewareFunc {
+	return middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, id string) {
+			if !validRequestID.MatchString(id) {
+				id = random.String(32)
+				c.Response().Header().Set(echo.HeaderXRequestID, id)
+			}
+			req := c.Request()
+			req.Header.Set(echo.HeaderXRequestID, id) // Read by the access logger
+			c.SetRequest(req.WithContext(db.WithRequestID(req.Context(), id)))
+			c.SetLogger(&requestLogger{Logger: c.Logger(), c: c})
+		},
+	})
+}
+
+// hashUserID identifies users in logs without writing their ID, that can be an email address with proxy auth
+func hashUserID(user string) string {
+	if user == "" {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(user))
+	return hex.EncodeToString(hash[:8])
+}
+
+// logUserHash writes the user hash for the ${custom} tag of the access logs
+func logUserHash(c echo.Context, buf *bytes.Buffer) (int, error) {
+	return buf.WriteString(hashUserID(auth.GetUserId(c)))
+}
+
+// requestLogger adds the request fields to the JSON log lines. The fields are read
+// when logging, to include the user and status once they are known.
+type requestLogger struct {
+	echo.Logger
+	c echo.Context
+}
+
+func (l *requestLogger) fields(j log.JSON) log.JSON {
+	fields := log.JSON{"request_id": l.c.Response().Header().Get(echo.HeaderXRequestID)}
+	if user := hashUserID(auth.GetUserId(l.c)); user != "" {
+		fields["user"] = user
+	}
+	httpFields := log.JSON{"route": l.c.Path()}
+	if l.c.Response().Committed {
+		httpFields["status"] = l.c.Response().Status
+	}
+	fields["http"] = httpFields
+	for k, v := range j {
+		fields[k] = v
+	}
+	return fields
+}
+
+func (l *requestLogger) Debug(i ...interface{}) {
+	l.Logger.Debugj(l.fields(log.JSON{"message": fmt.Sprint(i...)}))
+}
+
+func (l *requestLogger) Debugf(format string, args ...interface{}) {
+	l.Logger.Debugj(l.fields(log.JSON{"message": fmt.Sprintf(format, args...)}))
+}
+
+func (l *requestLogger) Debugj(j log.JSON) {
+	l.Logger.Debugj(l.fields(j))
+}
+
+func (l *requestLogger) Info(i ...interface{}) {
+	l.Logger.Infoj(l.fields(log.JSON{"message": fmt.Sprint(i...)}))
+}
+
+func (l *requestLogger) Infof(format string, args ...interface{}) {
+	l.Logger.Infoj(l.fields(log.JSON{"message": fmt.Sprintf(format, args...)}))
+}
+
+func (l *requestLogger) Infoj(j log.JSON) {
+	l.Logger.Infoj(l.fields(j))
+}
+
+func (l *requestLogger) Warn(i ...interface{}) {
+	l.Logger.Warnj(l.fields(log.JSON{"message": fmt.Sprint(i...)}))
+}
+
+func (l *requestLogger) Warnf(format string, args ...interface{}) {
+	l.Logger.Warnj(l.fields(log.JSON{"message": fmt.Sprintf(format, args...)}))
+}
+
+func (l *requestLogger) Warnj(j log.JSON) {
+	l.Logger.Warnj(l.fields(j))
+}
+
+func (l *requestLogger) Error(i ...interface{}) {
+	l.Logger.Errorj(l.fields(log.JSON{"message": fmt.Sprint(i...)}))
+}
+
+func (l *requestLogger) Errorf(format string, args ...interface{}) {
+	l.Logger.Errorj(l.fields(log.JSON{"message": fmt.Sprintf(format, args...)}))
+}
+
+func (l *requestLogger) Errorj(j log.JSON) {
+	l.Logger.Errorj(l.fields(j))
+}
+
+// recoverPanics turns panics into 500 errors. The stack is logged with the request fields,
+// but never sent to the client.
+func (s *Server) recoverPanics(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+			stack := make([]byte, 8<<10)
+			stack = stack[:runtime.Stack(stack, false)]
+			c.Logger().Errorj(log.JSON{"message": fmt.Sprintf("panic: %v", r), "stack": string(stack)})
+			_ = s.statsd.Incr("letsblockit.panic", []string{"route:" + routeTag(c)}, 1)
+
+			switch {
+			case c.Response().Committed:
+				err = nil
+			case strings.HasPrefix(c.Request().URL.Path, "/list/"):
+				err = c.String(http.StatusInternalServerError, panicListBody)
+			default:
+				err = echo.NewHTTPError(http.StatusInternalServerError)
+			}
+		}()
+		return next(c)
+	}
+}
+
+// handleError renders errors like echo's default handler, adding the request ID for users
+// to quote when reporting issues. API clients get problem+json bodies, and list subscribers
+// comment-only bodies. Errors that are not HTTP errors are logged with the request fields.
+func (s *Server) handleError(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	var fields map[string]string
+	var apiErr *apiError
+	he, ok := err.(*echo.HTTPError)
+	if !ok && errors.As(err, &apiErr) {
+		he, ok = &echo.HTTPError{Code: apiErr.Status, Message: apiErr.Message}, true
+		fields = apiErr.Fields
+	}
+	if !ok {
+		he = &echo.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+		}
+	} else if herr, ok := he.Internal.(*echo.HTTPError); ok {
+		he = herr
+	}
+
+	requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+	var sendErr error
+	switch {
+	case c.Request().Method == http.MethodHead:
+		sendErr = c.NoContent(he.Code)
+	case strings.HasPrefix(c.Request().URL.Path, "/list/"):
+		sendErr = c.String(he.Code, listErrorBody(he.Message, he.Code, requestID))
+	case wantsProblem(c.Request()):
+		p := newProblem(he.Code, he.Message, requestID)
+		p.FieldErrors = fields
+		sendErr = writeProblem(c, p)
+	default:
+		body := he.Message
+		if m, ok := body.(string); ok {
+			body = echo.Map{"message": m, "request_id": requestID}
+		}
+		sendErr = c.JSON(he.Code, body)
+	}
+	if !ok {
+		c.Logger().Error(err)
+	}
+	if sendErr != nil {
+		c.Logger().Error(sendErr)
+	}
+}
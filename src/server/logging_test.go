@@ -0,0 +1,166 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLoggingTestEcho(handler echo.HandlerFunc) (*echo.Echo, *bytes.Buffer) {
+	var logs bytes.Buffer
+	e := echo.New()
+	e.Logger.SetOutput(&logs)
+	e.Logger.SetLevel(log.INFO)
+	e.HTTPErrorHandler = (&Server{}).handleError
+	e.Use(buildRequestIDMiddleware())
+	e.GET("/test/:param", handler)
+	return e, &logs
+}
+
+func decodeLogLine(t *testing.T, logs *bytes.Buffer) map[string]interface{} {
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &line), logs.String())
+	return line
+}
+
+func TestRequestLogger(t *testing.T) {
+	var contextID string
+	e, logs := newLoggingTestEcho(func(c echo.Context) error {
+		contextID = db.RequestID(c.Request().Context())
+		c.Logger().Warnf("rendering %d instances", 3)
+		return c.NoContent(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test/value", nil))
+	requestID := rec.Header().Get(echo.HeaderXRequestID)
+	assert.Len(t, requestID, 32)
+	assert.Equal(t, requestID, contextID)
+
+	line := decodeLogLine(t, logs)
+	assert.Equal(t, "WARN", line["level"])
+	assert.Equal(t, "rendering 3 instances", line["message"])
+	assert.Equal(t, requestID, line["request_id"])
+	assert.Equal(t, map[string]interface{}{"route": "/test/:param"}, line["http"])
+	assert.NotContains(t, line, "user")
+}
+
+func TestRequestLogger_IncomingID(t *testing.T) {
+	e, _ := newLoggingTestEcho(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	for incoming, kept := range map[string]bool{
+		"f3b2c1d0-proxy.id_1":      true,
+		"has spaces":               false,
+		"injected\",\"status\":\"": false,
+		strings.Repeat("a", 65):    false,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/test/value", nil)
+		req.Header.Set(echo.HeaderXRequestID, incoming)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, kept, rec.Header().Get(echo.HeaderXRequestID) == incoming, incoming)
+		assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), req.Header.Get(echo.HeaderXRequestID))
+	}
+}
+
+func TestHandleError(t *testing.T) {
+	e, logs := newLoggingTestEcho(func(c echo.Context) error {
+		if c.Param("param") == "missing" {
+			return echo.ErrNotFound
+		}
+		return &db.TxError{RequestID: db.RequestID(c.Request().Context()), Err: errors.New("connection lost")}
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	requestID := rec.Header().Get(echo.HeaderXRequestID)
+	assert.JSONEq(t, `{"message":"Not Found","request_id":"`+requestID+`"}`, rec.Body.String())
+	assert.Empty(t, logs.String(), "client errors are not logged")
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test/failing", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	requestID = rec.Header().Get(echo.HeaderXRequestID)
+	assert.JSONEq(t, `{"message":"Internal Server Error","request_id":"`+requestID+`"}`, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "connection lost")
+
+	line := decodeLogLine(t, logs)
+	assert.Equal(t, "ERROR", line["level"])
+	assert.Equal(t, "request "+requestID+": connection lost", line["message"])
+	assert.Equal(t, requestID, line["request_id"])
+	assert.Equal(t, map[string]interface{}{"route": "/test/:param", "status": float64(500)}, line["http"])
+}
+
+func newPanicTestEcho() (*echo.Echo, *bytes.Buffer, *metrics.Registry) {
+	var logs bytes.Buffer
+	registry := metrics.NewRegistry()
+	s := &Server{statsd: registry}
+	e := echo.New()
+	e.Logger.SetOutput(&logs)
+	e.HTTPErrorHandler = s.handleError
+	e.Use(buildRequestIDMiddleware(), s.recoverPanics)
+	handler := func(c echo.Context) error {
+		var params map[string]string
+		params["boom"] = c.Param("token") // Assignment to a nil map
+		return nil
+	}
+	e.GET("/list/:token", handler)
+	e.GET("/filters/:name", handler)
+	return e, &logs, registry
+}
+
+func TestRecoverPanics(t *testing.T) {
+	e, logs, registry := newPanicTestEcho()
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/filters/buggy", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	requestID := rec.Header().Get(echo.HeaderXRequestID)
+	assert.JSONEq(t, `{"message":"Internal Server Error","request_id":"`+requestID+`"}`, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "goroutine")
+
+	line := decodeLogLine(t, logs)
+	assert.Equal(t, "ERROR", line["level"])
+	assert.Equal(t, "panic: assignment to entry in nil map", line["message"])
+	assert.Equal(t, requestID, line["request_id"])
+	assert.Contains(t, line["stack"], "goroutine")
+
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(), `letsblockit_panic_total{route="/filters/:name"} 1`)
+}
+
+func TestRecoverPanics_List(t *testing.T) {
+	e, logs, registry := newPanicTestEcho()
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list/c4f1c8b2-6d3a-4a9e-8b1f-2d6c3f9e0a11", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, panicListBody, rec.Body.String())
+	for _, line := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n") {
+		assert.True(t, strings.HasPrefix(line, "!"), "only comments are served: %s", line)
+	}
+	assert.Len(t, strings.Split(strings.TrimSpace(logs.String()), "\n"), 1, "the panic is logged once")
+
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(), `letsblockit_panic_total{route="/list/:token"} 1`)
+}
+
+func TestHashUserID(t *testing.T) {
+	assert.Equal(t, "", hashUserID(""))
+	assert.Len(t, hashUserID("user@example.com"), 16)
+	assert.NotEqual(t, hashUserID("user1"), hashUserID("user2"))
+}
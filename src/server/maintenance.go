@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const maintenancePath = "/admin/maintenance"
+
+// setMaintenance toggles the maintenance mode, during which only list downloads are served
+func (s *Server) setMaintenance(enabled bool) {
+	if s.maintenance.Swap(enabled) != enabled {
+		s.echo.Logger.Infof("maintenance mode enabled: %t", enabled)
+	}
+	var value float64
+	if enabled {
+		value = 1
+	}
+	_ = s.statsd.Gauge("letsblockit.maintenance", value, nil, 1)
+}
+
+// toggleMaintenanceOnSignal toggles the maintenance mode on every signal received, until ctx is done
+func (s *Server) toggleMaintenanceOnSignal(ctx context.Context, signals <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			s.setMaintenance(!s.maintenance.Load())
+		}
+	}
+}
+
+// pauseInMaintenance returns a 503 error during maintenance, with a friendly page for browsers.
+// The maintenance admin page is kept available, for admins to disable it.
+func (s *Server) pauseInMaintenance(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !s.maintenance.Load() || c.Path() == maintenancePath {
+			return next(c)
+		}
+		if retry := s.config().options.MaintenanceRetry; retry > 0 {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())))
+		}
+		if strings.HasPrefix(c.Path(), "/api/") {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "maintenance in progress, please retry later")
+		}
+		hc := s.buildPageContext(c, "Maintenance in progress")
+		hc.StatusCode = http.StatusServiceUnavailable
+		return s.pages.Render(c, "maintenance", hc)
+	}
+}
+
+func (s *Server) adminMaintenance(c echo.Context) error {
+	hc := s.buildPageContext(c, "Maintenance mode")
+	hc.NoBoost = true
+	hc.Add("enabled", s.maintenance.Load())
+	return s.pages.Render(c, "admin-maintenance", hc)
+}
+
+func (s *Server) adminSetMaintenance(c echo.Context) error {
+	enabled, err := strconv.ParseBool(c.FormValue("enabled"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid arguments")
+	}
+	s.setMaintenance(enabled)
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("admin-maintenance"))
+}
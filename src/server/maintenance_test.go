@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/metrics"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unavailableStore fails all transactions, as if the database was down
+type unavailableStore struct {
+	db.Store
+}
+
+func (unavailableStore) RunTx(echo.Context, db.TxFunc) error {
+	return errors.New("connection refused")
+}
+
+func TestSetMaintenance(t *testing.T) {
+	registry := metrics.NewRegistry()
+	s := &Server{echo: echo.New(), statsd: registry}
+	s.setMaintenance(true)
+	assert.True(t, s.maintenance.Load())
+
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(), "letsblockit_maintenance 1\n")
+
+	signals := make(chan os.Signal)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.toggleMaintenanceOnSignal(ctx, signals)
+		close(done)
+	}()
+	signals <- syscall.SIGUSR1
+	signals <- syscall.SIGUSR1 // Blocks until the first signal is handled
+	signals <- syscall.SIGUSR1
+	cancel()
+	<-done
+	assert.False(t, s.maintenance.Load())
+
+	out.Reset()
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(), "letsblockit_maintenance 0\n")
+}
+
+func (s *ServerTestSuite) TestMaintenance_Pages() {
+	s.server.options.MaintenanceRetry = 5 * time.Minute
+	s.server.maintenance.Store(true)
+	s.expectP.Render(gomock.Any(), "maintenance", gomock.Any()).
+		DoAndReturn(func(c echo.Context, _ string, hc *pages.Context) error {
+			s.False(hc.UserLoggedIn, "the authentication is skipped")
+			return c.HTMLBlob(hc.StatusCode, nil)
+		}).Times(2)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/filters", nil),
+		httptest.NewRequest(http.MethodPost, "/user/rotate-token", nil),
+	} {
+		s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+			assert.Equal(t, "300", rec.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func (s *ServerTestSuite) TestMaintenance_Api() {
+	s.server.maintenance.Store(true)
+	for _, path := range []string{"/api/instances", "/api/v1/filters"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		s.runApiRequest(req, apiTokenPrefix+"invalid", func(t *testing.T, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusServiceUnavailable, rec.Code, path)
+			assert.Contains(t, rec.Body.String(), "maintenance in progress", path)
+		})
+	}
+}
+
+func (s *ServerTestSuite) TestMaintenance_ListsAreServed() {
+	s.server.maintenance.Store(true)
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String(), nil)
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+	s.Contains(rec.Body.String(), "my.do.main###install-prompt-"+token.String())
+}
+
+func (s *ServerTestSuite) TestMaintenance_AdminToggle() {
+	s.setUserAdmin()
+	s.expectRender("admin-maintenance", pages.ContextData{"enabled": false})
+	s.runRequest(httptest.NewRequest(http.MethodGet, maintenancePath, nil), assertOk)
+
+	f := make(url.Values)
+	f.Add("enabled", "true")
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, maintenancePath, strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, maintenancePath)
+	s.runRequest(req, assertOk)
+	s.True(s.server.maintenance.Load())
+
+	// The admin page stays available during maintenance
+	s.expectRender("admin-maintenance", pages.ContextData{"enabled": true})
+	s.runRequest(httptest.NewRequest(http.MethodGet, maintenancePath, nil), assertOk)
+}
+
+func (s *ServerTestSuite) TestRenderList_StaleWhileError() {
+	registry := metrics.NewRegistry()
+	s.server.statsd = registry
+	s.server.listCache = newListCache(1 << 20)
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	s.addInstance(s.user, "filter2", filter2Custom)
+
+	req := httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String(), nil)
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+	fresh := rec.Body.String()
+
+	s.server.store = unavailableStore{s.store}
+	req = httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String(), nil)
+	rec = httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+	s.Equal(staleListHeader, rec.Header().Get("Warning"))
+	s.Empty(rec.Header().Get("Etag"), "stale lists are not cached by clients")
+	promptStart := "\n! Hide the list install prompt"
+	s.Equal(strings.Replace(fresh, promptStart, staleListWarning+promptStart, 1), rec.Body.String())
+
+	// The warning is not stored in the cache
+	stale, found := s.server.listCache.GetStale(token.String() + ":false")
+	require.True(s.T(), found)
+	s.NotContains(string(stale), staleListWarning)
+
+	// Lists missing from the cache fail as usual
+	req = httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+token.String()+"?test_mode", nil)
+	rec = httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(http.StatusInternalServerError, rec.Code)
+
+	var out strings.Builder
+	require.NoError(s.T(), registry.Write(&out))
+	s.Contains(out.String(), `letsblockit_list_render_cache_total{hit="stale"} 1`)
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// Users with two accounts can merge them: account A generates a merge code, that account B
+// redeems to move its filters into A's list. Merges are recorded in the audit log, with B as actor.
+const (
+	mergeCodePrefix   = "lbim_"
+	mergeCodeLength   = 16 // Random bytes in a code
+	mergeCodeLifetime = 15 * time.Minute
+	auditAccountMerge = "account_merge"
+)
+
+// Merge actions, for each filter of the merged account
+const (
+	mergeMove        = "move"        // The filter is moved to the destination list
+	mergeKeep        = "keep"        // The destination list already has this filter, it is kept as is
+	mergeConcatenate = "concatenate" // Custom rules are appended to the destination list's rules
+)
+
+// mergeStep describes what happens to a filter of the merged account
+type mergeStep struct {
+	Template string
+	Title    string
+	Action   string
+}
+
+func generateMergeCode() (string, []byte, error) {
+	secret := make([]byte, mergeCodeLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("cannot generate merge code: %w", err)
+	}
+	code := mergeCodePrefix + hex.EncodeToString(secret)
+	return code, hashApiToken(code), nil
+}
+
+// createMergeCode generates a code to merge another account into the user's, replacing any previous code
+func (s *Server) createMergeCode(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	code, hash, err := generateMergeCode()
+	if err != nil {
+		return err
+	}
+	expiresAt := s.now().Add(mergeCodeLifetime)
+	if err = s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		if err := q.DeleteMergeCodesForUser(ctx, user); err != nil {
+			return err
+		}
+		return q.CreateMergeCode(ctx, db.CreateMergeCodeParams{
+			CodeHash:  hash,
+			UserID:    user,
+			ExpiresAt: expiresAt,
+		})
+	}); err != nil {
+		return err
+	}
+	return s.renderUserAccount(c, func(hc *pages.Context) {
+		hc.Add("merge_code", code)
+		hc.Add("merge_code_expiry", expiresAt.UTC().Format(time.RFC1123))
+	})
+}
+
+// previewAccountMerge shows the filters that would be moved into the account holding the merge code
+func (s *Server) previewAccountMerge(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	code := strings.TrimSpace(c.FormValue("code"))
+	var plan []mergeStep
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		into, err := s.getMergeTarget(ctx, q, code, user)
+		if err != nil {
+			return err
+		}
+		plan, err = s.buildMergePlan(ctx, q, into, user)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	hc := s.buildPageContext(c, "Merge my account")
+	hc.NoBoost = true
+	hc.Add("merge_code", code)
+	hc.Add("merge_plan", plan)
+	return s.pages.Render(c, "account-merge", hc)
+}
+
+// mergeAccounts moves the user's filters into the account holding the merge code, then deletes the user's list
+func (s *Server) mergeAccounts(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	formParams, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+	if formParams.Get("confirm") != "on" {
+		return echo.NewHTTPError(http.StatusBadRequest, "please confirm the merge")
+	}
+	var into string
+	if err = s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		var err error
+		if into, err = s.getMergeTarget(ctx, q, strings.TrimSpace(formParams.Get("code")), user); err != nil {
+			return err
+		}
+		plan, err := s.buildMergePlan(ctx, q, into, user)
+		if err != nil {
+			return err
+		}
+		if err = applyMergePlan(ctx, q, into, user, plan); err != nil {
+			return err
+		}
+		if err = q.DeleteMergeCodesForUser(ctx, into); err != nil {
+			return err
+		}
+		return q.LogAdminAction(ctx, db.LogAdminActionParams{
+			AdminID:  user,
+			Action:   auditAccountMerge,
+			TargetID: into,
+		})
+	}); err != nil {
+		return err
+	}
+	s.hotLists.Invalidate(into)
+	s.hotLists.Invalidate(user)
+	_ = s.statsd.Incr("letsblockit.accounts_merged", nil, 1)
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("user-account"))
+}
+
+// getMergeTarget returns the account holding a valid merge code, if both accounts can be merged
+func (s *Server) getMergeTarget(ctx context.Context, q db.Querier, code, user string) (string, error) {
+	if !strings.HasPrefix(code, mergeCodePrefix) {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "invalid merge code")
+	}
+	into, err := q.GetMergeCodeUser(ctx, hashApiToken(code))
+	switch {
+	case err == db.NotFound:
+		return "", echo.NewHTTPError(http.StatusBadRequest, "invalid or expired merge code")
+	case err != nil:
+		return "", err
+	case into == user:
+		return "", echo.NewHTTPError(http.StatusBadRequest, "this merge code has been generated by this account, please log into your other account to use it")
+	case s.bans.IsBanned(into) || s.bans.IsBanned(user):
+		return "", echo.NewHTTPError(http.StatusForbidden, "banned accounts cannot be merged")
+	}
+	return into, nil
+}
+
+// buildMergePlan decides what happens to each filter of the merged account:
+// the destination account's version is kept on conflicts, except for custom rules that are concatenated.
+func (s *Server) buildMergePlan(ctx context.Context, q db.Querier, into, from string) ([]mergeStep, error) {
+	existing, err := q.GetInstancesForUser(ctx, into)
+	if err != nil {
+		return nil, err
+	}
+	existingNames := make(map[string]struct{}, len(existing))
+	for _, i := range existing {
+		existingNames[i.TemplateName] = struct{}{}
+	}
+	instances, err := q.GetInstancesForUser(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]mergeStep, 0, len(instances))
+	for _, i := range instances {
+		step := mergeStep{Template: i.TemplateName, Title: i.TemplateName, Action: mergeMove}
+		if filter, err := s.config().filters.Get(i.TemplateName); err == nil {
+			step.Title = filter.Title
+		}
+		if _, found := existingNames[i.TemplateName]; found {
+			if i.TemplateName == filters.CustomRulesFilterName {
+				step.Action = mergeConcatenate
+			} else {
+				step.Action = mergeKeep
+			}
+		}
+		plan = append(plan, step)
+	}
+	return plan, nil
+}
+
+func applyMergePlan(ctx context.Context, q db.Querier, into, from string, plan []mergeStep) error {
+	if count, err := q.CountListsForUser(ctx, into); err != nil {
+		return err
+	} else if count == 0 {
+		if _, err = q.CreateListForUser(ctx, into); err != nil {
+			return err
+		}
+	}
+	for _, step := range plan {
+		var err error
+		switch step.Action {
+		case mergeMove:
+			err = q.MoveInstance(ctx, db.MoveInstanceParams{
+				NewUserID:    into,
+				OldUserID:    from,
+				TemplateName: step.Template,
+			})
+		case mergeConcatenate:
+			err = concatenateRules(ctx, q, into, from, step.Template)
+		}
+		if err != nil {
+			return err
+		}
+		if step.Action != mergeMove {
+			if err = q.DeleteInstance(ctx, db.DeleteInstanceParams{
+				UserID:       from,
+				TemplateName: step.Template,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return q.DeleteListForUser(ctx, from)
+}
+
+// concatenateRules appends the rules of an instance to the destination account's instance
+func concatenateRules(ctx context.Context, q db.Querier, into, from, template string) error {
+	var rules [2]string
+	var testMode bool
+	var hash string
+	for i, user := range []string{into, from} {
+		stored, err := q.GetInstance(ctx, db.GetInstanceParams{UserID: user, TemplateName: template})
+		if err != nil {
+			return err
+		}
+		params := make(map[string]interface{})
+		if stored.Params.Status == pgtype.Present {
+			if err = stored.Params.AssignTo(&params); err != nil {
+				return err
+			}
+		}
+		rules[i], _ = params["rules"].(string)
+		if user == into {
+			testMode = stored.TestMode
+			hash = stored.TemplateHash
+		}
+	}
+
+	var out pgtype.JSONB
+	if err := out.Set(map[string]interface{}{"rules": joinRules(rules[0], rules[1])}); err != nil {
+		return err
+	}
+	return q.UpdateInstance(ctx, db.UpdateInstanceParams{
+		UserID:       into,
+		TemplateName: template,
+		Params:       out,
+		TestMode:     testMode,
+		TemplateHash: hash,
+	})
+}
+
+// joinRules appends custom rules to existing ones, on a new line
+func joinRules(existing, added string) string {
+	if strings.TrimSpace(existing) == "" {
+		return added
+	}
+	return strings.TrimRight(existing, "\n") + "\n" + added
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/letsblockit/letsblockit/src/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mergeTargetUser = "merge-target"
+
+// createMergeCode stores a merge code for the merge target account
+func (s *ServerTestSuite) createMergeCode() string {
+	s.T().Helper()
+	code, hash, err := generateMergeCode()
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.store.CreateMergeCode(context.Background(), db.CreateMergeCodeParams{
+		CodeHash:  hash,
+		UserID:    mergeTargetUser,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	return code
+}
+
+func (s *ServerTestSuite) addInstance(user, template string, params map[string]interface{}) {
+	s.T().Helper()
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, user, &filters.Instance{
+		Template: template,
+		Params:   params,
+	}))
+}
+
+func (s *ServerTestSuite) TestCreateMergeCode_OK() {
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/merge/code", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Render(gomock.Any(), "user-account", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			code, ok := hc.Data["merge_code"].(string)
+			require.True(s.T(), ok)
+			user, err := s.store.GetMergeCodeUser(context.Background(), hashApiToken(code))
+			require.NoError(s.T(), err)
+			assert.Equal(s.T(), s.user, user)
+			return nil
+		})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestMergeAccounts_PreviewAndMerge() {
+	s.addInstance(mergeTargetUser, "filter1", nil)
+	s.addInstance(mergeTargetUser, filters.CustomRulesFilterName, map[string]interface{}{"rules": "target-rule\n"})
+	s.addInstance(s.user, "filter1", nil)
+	s.addInstance(s.user, "filter2", map[string]interface{}{"one": "blep"})
+	s.addInstance(s.user, filters.CustomRulesFilterName, map[string]interface{}{"rules": "my-rule"})
+	code := s.createMergeCode()
+
+	f := make(url.Values)
+	f.Add("code", code)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/merge/preview", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Render(gomock.Any(), "account-merge", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.Equal(s.T(), code, hc.Data["merge_code"])
+			assert.ElementsMatch(s.T(), []mergeStep{
+				{Template: "filter1", Title: "Filter 1", Action: mergeKeep},
+				{Template: "filter2", Title: "Second filter", Action: mergeMove},
+				{Template: filters.CustomRulesFilterName, Title: "Add custom blocking rules", Action: mergeConcatenate},
+			}, hc.Data["merge_plan"])
+			return nil
+		})
+	s.runRequest(req, assertOk)
+
+	// Dry-run does not change anything
+	s.requireInstanceCount("filter2", 1)
+
+	f.Add("confirm", "on")
+	req = httptest.NewRequest(http.MethodPost, "/user/merge", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/account")
+	s.runRequest(req, assertOk)
+
+	instances, err := s.store.GetInstancesForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	s.Empty(instances)
+	_, err = s.store.GetListForUser(context.Background(), s.user)
+	s.ErrorIs(err, db.NotFound)
+
+	list, err := s.store.GetListForUser(context.Background(), mergeTargetUser)
+	require.NoError(s.T(), err)
+	s.EqualValues(3, list.InstanceCount)
+	moved, err := s.store.GetInstance(context.Background(), db.GetInstanceParams{
+		UserID:       mergeTargetUser,
+		TemplateName: "filter2",
+	})
+	require.NoError(s.T(), err)
+	s.Contains(string(moved.Params.Bytes), "blep")
+	rules, err := s.store.GetInstance(context.Background(), db.GetInstanceParams{
+		UserID:       mergeTargetUser,
+		TemplateName: filters.CustomRulesFilterName,
+	})
+	require.NoError(s.T(), err)
+	s.JSONEq(`{"rules": "target-rule\nmy-rule"}`, string(rules.Params.Bytes))
+
+	actions, err := s.store.GetAdminActions(context.Background(), auditLogPageSize)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), actions, 1)
+	s.Equal(auditAccountMerge, actions[0].Action)
+	s.Equal(s.user, actions[0].AdminID)
+	s.Equal(mergeTargetUser, actions[0].TargetID)
+
+	// The code is single-use
+	_, err = s.store.GetMergeCodeUser(context.Background(), hashApiToken(code))
+	s.ErrorIs(err, db.NotFound)
+}
+
+func (s *ServerTestSuite) TestMergeAccounts_Errors() {
+	s.addInstance(s.user, "filter2", nil)
+	code := s.createMergeCode()
+
+	for name, tc := range map[string]struct {
+		code     string
+		confirm  string
+		expected int
+		setup    func()
+	}{
+		"invalid code":  {code: "lbim_invalid", confirm: "on", expected: http.StatusBadRequest},
+		"missing code":  {code: "", confirm: "on", expected: http.StatusBadRequest},
+		"not confirmed": {code: code, confirm: "", expected: http.StatusBadRequest},
+		"banned target": {code: code, confirm: "on", expected: http.StatusForbidden, setup: func() {
+			require.NoError(s.T(), s.store.AddUserBan(context.Background(), db.AddUserBanParams{UserID: mergeTargetUser}))
+			s.server.bans, _ = users.LoadUserBans(s.store)
+		}},
+	} {
+		s.Run(name, func() {
+			if tc.setup != nil {
+				tc.setup()
+			}
+			f := make(url.Values)
+			f.Add("code", tc.code)
+			f.Add("confirm", tc.confirm)
+			f.Add(csrfLookup, s.csrf)
+			req := httptest.NewRequest(http.MethodPost, "/user/merge", strings.NewReader(f.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, tc.expected, rec.Code, rec.Body)
+			})
+		})
+	}
+	s.requireInstanceCount("filter2", 1)
+}
+
+func (s *ServerTestSuite) TestMergeAccounts_SameAccount() {
+	code, hash, err := generateMergeCode()
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.store.CreateMergeCode(context.Background(), db.CreateMergeCodeParams{
+		CodeHash:  hash,
+		UserID:    s.user,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+
+	f := make(url.Values)
+	f.Add("code", code)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/merge/preview", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
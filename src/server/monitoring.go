@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"runtime"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
@@ -17,10 +20,13 @@ import (
 	"github.com/letsblockit/letsblockit/src/users/auth"
 )
 
-func buildDogstatsMiddleware(dsd statsd.ClientInterface) echo.MiddlewareFunc {
+// buildDogstatsMiddleware reports the duration and status of requests, tagged by route pattern and method.
+// Requests not matching a registered route are collapsed in the unknownRoute bucket, to bound the cardinality.
+func buildDogstatsMiddleware(dsd statsd.ClientInterface, e *echo.Echo) echo.MiddlewareFunc {
+	routes := &routeTagger{echo: e}
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			if c.Request().URL.Path == healthPath {
+			if p := c.Request().URL.Path; isHealthPath(p) || p == metricsPath {
 				return next(c)
 			}
 
@@ -28,15 +34,92 @@ func buildDogstatsMiddleware(dsd statsd.ClientInterface) echo.MiddlewareFunc {
 			if err := next(c); err != nil {
 				c.Error(err)
 			}
-			loggedTag := fmt.Sprintf("logged:%t", auth.HasAuth(c))
+			logged := auth.HasAuth(c)
 			duration := time.Since(start)
-			_ = dsd.Distribution("letsblockit.request_duration", float64(duration.Nanoseconds()), []string{loggedTag}, 1)
-			_ = dsd.Incr("letsblockit.request_count", []string{loggedTag, fmt.Sprintf("status:%d", c.Response().Status)}, 1)
+			route := routes.lookup(c)
+			_ = dsd.Distribution("letsblockit.request_duration", float64(duration.Nanoseconds()), route.durationTags.of(logged), 1)
+			_ = dsd.Incr("letsblockit.request_count", []string{
+				loggedTag.of(logged),
+				route.route,
+				route.method,
+				"status:" + strconv.Itoa(c.Response().Status),
+			}, 1)
 			return nil
 		}
 	}
 }
 
+// unknownRoute tags the requests not matching a registered route
+const unknownRoute = "unknown"
+
+// routeTags holds the tags of a registered route, computed once
+type routeTags struct {
+	route, method string
+	durationTags  boolTagSet
+}
+
+func newRouteTags(route, method string) *routeTags {
+	t := &routeTags{route: "route:" + route, method: "method:" + method}
+	t.durationTags = newBoolTagSet([]string{t.route, t.method}, "logged")
+	return t
+}
+
+// routeTagger maps requests to the tags of their route. The routes are listed on the first
+// request, as they are registered after the middlewares.
+type routeTagger struct {
+	echo    *echo.Echo
+	once    sync.Once
+	routes  map[routeKey]*routeTags
+	unknown *routeTags
+}
+
+type routeKey struct {
+	method, path string
+}
+
+func (r *routeTagger) lookup(c echo.Context) *routeTags {
+	r.once.Do(func() {
+		r.routes = make(map[routeKey]*routeTags)
+		for _, route := range r.echo.Routes() {
+			if route.Method == echo.RouteNotFound {
+				continue
+			}
+			r.routes[routeKey{route.Method, route.Path}] = newRouteTags(route.Path, route.Method)
+		}
+		r.unknown = newRouteTags(unknownRoute, unknownRoute)
+	})
+	// Router fallbacks (404, 405) set a path but no registered route for this method
+	if tags, found := r.routes[routeKey{c.Request().Method, c.Path()}]; found {
+		return tags
+	}
+	return r.unknown
+}
+
+// routeTag returns the route pattern of the request, to tag metrics without the high-cardinality parameters
+func routeTag(c echo.Context) string {
+	if route := c.Path(); route != "" {
+		return route
+	}
+	return unknownRoute
+}
+
+// servePrometheus serves the prometheus metrics on a separate address, to keep them private
+func (s *Server) servePrometheus() {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, s.prometheus)
+	s.metricsServer = &http.Server{
+		Addr:              s.options.PrometheusAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Println("Serving prometheus metrics on", s.options.PrometheusAddress)
+	go func() {
+		if err := s.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.echo.Logger.Error("cannot serve prometheus metrics: " + err.Error())
+		}
+	}()
+}
+
 func collectBusinessStats(log echo.Logger, store db.Store, dsd statsd.ClientInterface) {
 	collect := func() {
 		stats, err := store.GetStats(context.Background())
@@ -47,6 +130,10 @@ func collectBusinessStats(log echo.Logger, store db.Store, dsd statsd.ClientInte
 		_ = dsd.Gauge("letsblockit.total_list_count", float64(stats.ListsTotal), nil, 1)
 		_ = dsd.Gauge("letsblockit.active_list_count", float64(stats.ListsActive), nil, 1)
 		_ = dsd.Gauge("letsblockit.fresh_list_count", float64(stats.ListsFresh), nil, 1)
+		_ = dsd.Gauge("letsblockit.daily_list_count", float64(stats.ListsDaily), nil, 1)
+		_ = dsd.Gauge("letsblockit.never_downloaded_list_count", float64(stats.ListsTotal-stats.ListsActive), nil, 1)
+		_ = dsd.Gauge("letsblockit.total_instance_count", float64(stats.InstancesTotal), nil, 1)
+		_ = dsd.Gauge("letsblockit.user_count", float64(stats.UsersTotal), nil, 1)
 
 		instances, err := store.GetInstanceStats(context.Background())
 		if err != nil {
@@ -109,6 +196,37 @@ func collectMemStats(dsd statsd.ClientInterface) {
 	}
 }
 
+// collectPoolStats exports the usage of the database connection pool every interval, until ctx is done
+func collectPoolStats(ctx context.Context, store db.Store, dsd statsd.ClientInterface, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	previous := store.PoolStats()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := store.PoolStats()
+			reportPoolStats(dsd, previous, current)
+			previous = current
+		}
+	}
+}
+
+// reportPoolStats sends the pool gauges, the acquire counters and mean duration cover the interval since previous
+func reportPoolStats(dsd statsd.ClientInterface, previous, current db.PoolStats) {
+	_ = dsd.Gauge("letsblockit.pg_pool_acquired_conns", float64(current.Acquired), nil, 1)
+	_ = dsd.Gauge("letsblockit.pg_pool_idle_conns", float64(current.Idle), nil, 1)
+	_ = dsd.Gauge("letsblockit.pg_pool_total_conns", float64(current.Total), nil, 1)
+	_ = dsd.Gauge("letsblockit.pg_pool_max_conns", float64(current.Max), nil, 1)
+	_ = dsd.Gauge("letsblockit.pg_pool_empty_acquires", float64(current.EmptyAcquires-previous.EmptyAcquires), nil, 1)
+	var wait time.Duration
+	if acquires := current.Acquires - previous.Acquires; acquires > 0 {
+		wait = (current.AcquireDuration - previous.AcquireDuration) / time.Duration(acquires)
+	}
+	_ = dsd.Gauge("letsblockit.pg_pool_acquire_duration", float64(wait.Nanoseconds()), nil, 1)
+}
+
 func runVector(config string) error {
 	if config == "" {
 		return nil
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportPoolStats(t *testing.T) {
+	registry := metrics.NewRegistry()
+	previous := db.PoolStats{Acquires: 10, EmptyAcquires: 1, AcquireDuration: time.Second}
+	current := db.PoolStats{
+		Acquired:        3,
+		Idle:            2,
+		Total:           5,
+		Max:             8,
+		Acquires:        14,
+		EmptyAcquires:   3,
+		AcquireDuration: time.Second + 40*time.Millisecond,
+	}
+	reportPoolStats(registry, previous, current)
+
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(), "letsblockit_pg_pool_acquired_conns 3\n")
+	assert.Contains(t, out.String(), "letsblockit_pg_pool_idle_conns 2\n")
+	assert.Contains(t, out.String(), "letsblockit_pg_pool_total_conns 5\n")
+	assert.Contains(t, out.String(), "letsblockit_pg_pool_max_conns 8\n")
+	assert.Contains(t, out.String(), "letsblockit_pg_pool_empty_acquires 2\n")
+	assert.Contains(t, out.String(), "letsblockit_pg_pool_acquire_duration 1e+07\n")
+
+	// No acquire during the interval
+	reportPoolStats(registry, current, current)
+	out.Reset()
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(), "letsblockit_pg_pool_empty_acquires 0\n")
+	assert.Contains(t, out.String(), "letsblockit_pg_pool_acquire_duration 0\n")
+}
+
+func TestDogstatsMiddleware_RouteTags(t *testing.T) {
+	registry := metrics.NewRegistry()
+	e := echo.New()
+	e.Use(buildDogstatsMiddleware(registry, e))
+	e.GET("/list/:token", func(c echo.Context) error {
+		return c.String(http.StatusOK, "list")
+	})
+	e.GET(healthPath, func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, target := range []string{"/list/" + uuid.NewString(), "/list/" + uuid.NewString(), "/not-found", healthPath} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/list/"+uuid.NewString(), nil))
+
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(),
+		`letsblockit_request_count_total{logged="false",method="GET",route="/list/:token",status="200"} 2`)
+	assert.Contains(t, out.String(),
+		`letsblockit_request_count_total{logged="false",method="unknown",route="unknown",status="404"} 1`)
+	assert.Contains(t, out.String(),
+		`letsblockit_request_count_total{logged="false",method="unknown",route="unknown",status="405"} 1`)
+	assert.Contains(t, out.String(),
+		`letsblockit_request_duration_seconds_count{logged="false",method="GET",route="/list/:token"} 2`)
+	assert.NotContains(t, out.String(), healthPath)
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.Contains(line, "route=") {
+			assert.Regexp(t, `route="(/list/:token|unknown)"`, line)
+		}
+	}
+}
@@ -19,9 +19,29 @@ func (s *Server) newsHandler(c echo.Context) error {
 	hc := s.buildPageContext(c, "Release notes")
 
 	newReleases := make(map[string]bool) // handlebars lookup only supports string keys
+	newAnnouncements := make(map[string]bool)
 	if hc.HasNews {
-		if hc.UserLoggedIn && len(releases) > 0 {
-			err := s.preferences.UpdateNewsCursor(c, hc.UserID, releases[0].CreatedAt)
+		var latest time.Time
+		if len(releases) > 0 {
+			latest = releases[0].CreatedAt
+		}
+		if len(s.announcements) > 0 && s.announcements[0].PublishedAt.After(latest) {
+			latest = s.announcements[0].PublishedAt
+		}
+		if hc.Preferences != nil {
+			updates, updatedAt, err := getProposalUpdates(c.Request().Context(), s.store, hc.UserID, hc.Preferences.NewsCursor)
+			if err != nil {
+				return err
+			}
+			if len(updates) > 0 {
+				hc.Add("proposalUpdates", updates)
+			}
+			if updatedAt.After(latest) {
+				latest = updatedAt
+			}
+		}
+		if hc.UserLoggedIn && !hc.UserIsImpersonated && !latest.IsZero() {
+			err := s.preferences.UpdateNewsCursor(c, hc.UserID, latest)
 			if err != nil {
 				c.Logger().Warnf("failed to update latest news for %s: %s", hc.UserID, err)
 			}
@@ -29,6 +49,7 @@ func (s *Server) newsHandler(c echo.Context) error {
 
 		// Shut down the menubar notification for this page
 		hc.HasNews = false
+		hc.UnreadNews = 0
 
 		// Compute new releases to highlight
 		if hc.Preferences != nil {
@@ -39,26 +60,61 @@ func (s *Server) newsHandler(c echo.Context) error {
 					break
 				}
 			}
+			for i, a := range s.announcements {
+				if a.PublishedAt.After(hc.Preferences.NewsCursor) {
+					newAnnouncements[strconv.Itoa(i)] = true
+				} else {
+					break
+				}
+			}
 		}
 	}
 
 	hc.Add("releases", releases)
 	hc.Add("newReleases", newReleases)
+	if len(s.announcements) > 0 {
+		hc.Add("announcements", s.announcements)
+		hc.Add("newAnnouncements", newAnnouncements)
+	}
 	return s.pages.Render(c, "news", hc)
 }
 
+// countUnreadNews returns the number of releases and announcements published after the cursor
+func (s *Server) countUnreadNews(cursor time.Time) int {
+	var count int
+	if latest, _ := s.releases.GetLatestAt(); latest.After(cursor) {
+		releases, _, _ := s.releases.GetReleases()
+		for _, r := range releases {
+			if !r.CreatedAt.After(cursor) {
+				break
+			}
+			count++
+		}
+	}
+	for _, a := range s.announcements {
+		if !a.PublishedAt.After(cursor) {
+			break
+		}
+		count++
+	}
+	return count
+}
+
 func (s *Server) newsAtomHandler(c echo.Context) error {
 	releases, etag, err := s.releases.GetReleases()
 	if err != nil {
 		return err
 	}
 
-	if m := getEtag(c); m != "" && m == etag {
+	if etag != "" && s.newsHash != "" {
+		etag += "-" + s.newsHash
+	}
+	if getRequestETags(c).match(etag) {
 		return c.NoContent(http.StatusNotModified)
 	}
 
 	feed := atom.Feed{
-		Title: "Release notes from letsblock.it",
+		Title: "News and release notes from letsblock.it",
 		ID:    s.absoluteReverse(c, "news-atom"),
 		Link: []atom.Link{{
 			Rel:  "self",
@@ -74,10 +130,30 @@ func (s *Server) newsAtomHandler(c echo.Context) error {
 			Name: "Let's Block It contributors",
 			URI:  "https://github.com/letsblockit/letsblockit",
 		},
-		Entry: make([]*atom.Entry, 0, len(releases)),
+		Entry: make([]*atom.Entry, 0, len(s.announcements)+len(releases)),
 	}
 
 	var latestUpdate time.Time
+	for _, a := range s.announcements {
+		feed.Entry = append(feed.Entry, &atom.Entry{
+			Title: a.Title,
+			ID:    s.absoluteReverse(c, "news") + "#" + a.Name,
+			Link: []atom.Link{{
+				Rel:  "alternate",
+				Href: s.absoluteReverse(c, "news") + "#" + a.Name,
+				Type: "text/html",
+			}},
+			Published: atom.Time(a.PublishedAt),
+			Updated:   atom.Time(a.PublishedAt),
+			Content: &atom.Text{
+				Type: "html",
+				Body: a.Description,
+			},
+		})
+		if a.PublishedAt.After(latestUpdate) {
+			latestUpdate = a.PublishedAt
+		}
+	}
 	for _, r := range releases {
 		feed.Entry = append(feed.Entry, &atom.Entry{
 			Title: fmt.Sprintf("Let's Block It: %s update", r.TagName),
@@ -7,6 +7,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/letsblockit/letsblockit/data"
+	"github.com/letsblockit/letsblockit/src/filters"
 	"github.com/letsblockit/letsblockit/src/news"
 	"github.com/letsblockit/letsblockit/src/pages"
 	"github.com/stretchr/testify/assert"
@@ -27,6 +29,28 @@ var exampleReleases = []*news.Release{{
 	PublishedAt: fixedNow,
 }}
 
+var exampleAnnouncements = []*news.Announcement{{
+	Name:        "second",
+	Title:       "Second announcement",
+	PublishedAt: fixedNow.Add(3 * time.Hour),
+}, {
+	Name:        "first",
+	Title:       "First announcement",
+	PublishedAt: fixedNow.Add(-1 * time.Hour),
+}}
+
+func TestLoadAnnouncements(t *testing.T) {
+	repo, err := filters.Load(data.Templates, data.Presets)
+	require.NoError(t, err)
+	announcements, err := news.LoadAnnouncements(data.News, false, repo)
+	require.NoError(t, err)
+	require.NotEmpty(t, announcements)
+	for _, a := range announcements {
+		assert.NotEmpty(t, a.Title, a.Name)
+		assert.NotEmpty(t, a.Description, a.Name)
+	}
+}
+
 func (s *ServerTestSuite) TestNews_Anonymous() {
 	s.user = ""
 	req := httptest.NewRequest(http.MethodGet, "/news", nil)
@@ -67,6 +91,35 @@ func (s *ServerTestSuite) TestNews_NoNews() {
 	s.runRequest(req, assertOk)
 }
 
+func (s *ServerTestSuite) TestNews_Announcements() {
+	req := httptest.NewRequest(http.MethodGet, "/news", nil)
+	s.releases = exampleReleases
+	s.server.announcements = exampleAnnouncements
+	s.expectRender("news", pages.ContextData{
+		"releases": exampleReleases,
+		"newReleases": map[string]bool{
+			"0": true,
+			"1": true,
+		},
+		"announcements":    exampleAnnouncements,
+		"newAnnouncements": map[string]bool{"0": true},
+	})
+	s.runRequest(req, assertOk)
+
+	// The cursor is set to the latest announcement
+	pref, err := s.server.preferences.Get(s.c, s.user)
+	require.NoError(s.T(), err)
+	require.True(s.T(), exampleAnnouncements[0].PublishedAt.Equal(pref.NewsCursor))
+}
+
+func (s *ServerTestSuite) TestNews_UnreadCount() {
+	s.releases = exampleReleases
+	s.server.announcements = exampleAnnouncements
+	s.Equal(3, s.server.countUnreadNews(fixedNow))
+	s.Equal(1, s.server.countUnreadNews(fixedNow.Add(time.Hour)))
+	s.Equal(0, s.server.countUnreadNews(fixedNow.Add(3*time.Hour)))
+}
+
 func (s *ServerTestSuite) TestNewsAtom() {
 	req := httptest.NewRequest(http.MethodGet, "/news.atom", nil)
 	s.releases = exampleReleases
@@ -82,6 +135,23 @@ func (s *ServerTestSuite) TestNewsAtom() {
 	})
 }
 
+func (s *ServerTestSuite) TestNewsAtom_Announcements() {
+	req := httptest.NewRequest(http.MethodGet, "/news.atom", nil)
+	s.releases = exampleReleases
+	s.server.announcements = exampleAnnouncements
+	s.server.newsHash = "announcements"
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body)
+		assert.Equal(t, newsETag+"-announcements", rec.Header().Get("ETag"))
+
+		var feed atom.Feed
+		assert.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &feed))
+		assert.Len(t, feed.Entry, 6)
+		assert.Equal(t, "Second announcement", feed.Entry[0].Title)
+		assert.Equal(t, atom.Time(fixedNow.Add(3*time.Hour)), feed.Updated)
+	})
+}
+
 func (s *ServerTestSuite) TestNewsAtom_NotModified() {
 	req := httptest.NewRequest(http.MethodGet, "/news.atom", nil)
 	req.Header.Set("If-None-Match", newsETag)
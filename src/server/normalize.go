@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgtype"
+	"github.com/letsblockit/letsblockit/src/db"
+)
+
+// NormalizeReport summarizes a run of NormalizeInstanceParams
+type NormalizeReport struct {
+	Scanned         int
+	Invalid         int // Instances with invalid or unknown parameters
+	Normalized      int
+	UnknownTemplate int // Instances of a template missing from the repository, they are left unchanged
+	Failed          int // Instances whose batch failed, they are normalized by the next run
+}
+
+// NormalizeInstanceParams drops the instance parameters left invalid or unknown by template changes, the same
+// parameters the API rejects on save, for their default value to be used instead. Pinned instances are skipped,
+// as they render an older template version. Instances are scanned by batches of batchSize, each batch is fixed
+// in its own transaction, and instances changed since they were scanned are left for the next run.
+func NormalizeInstanceParams(ctx context.Context, store db.Store, templatesDir string, dryRun bool, batchSize int32) (*NormalizeReport, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	repo, err := loadTemplates(templatesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &NormalizeReport{}
+	var lastErr error
+	var afterID int32
+	for {
+		rows, err := store.GetUnpinnedInstances(ctx, db.GetUnpinnedInstancesParams{AfterID: afterID, BatchSize: batchSize})
+		if err != nil {
+			return report, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		afterID = rows[len(rows)-1].ID
+		report.Scanned += len(rows)
+
+		var fixes []db.NormalizeInstanceParams
+		for _, row := range rows {
+			tpl, err := repo.Get(row.TemplateName)
+			if err != nil {
+				report.UnknownTemplate++
+				continue
+			}
+			var params map[string]interface{}
+			if err = row.Params.AssignTo(&params); err != nil {
+				return report, fmt.Errorf("cannot decode the params of instance %d: %w", row.ID, err)
+			}
+			normalized, changed := tpl.NormalizeParams(params)
+			if !changed {
+				continue
+			}
+			fix := db.NormalizeInstanceParams{ID: row.ID, ChangeID: row.ChangeID, Params: pgtype.JSONB{Status: pgtype.Null}}
+			if len(normalized) > 0 {
+				if err = fix.Params.Set(normalized); err != nil {
+					return report, err
+				}
+			}
+			fixes = append(fixes, fix)
+		}
+		report.Invalid += len(fixes)
+		if dryRun || len(fixes) == 0 {
+			continue
+		}
+		if err = store.RunTxContext(ctx, func(ctx context.Context, q db.Querier) error {
+			for _, fix := range fixes {
+				if err := q.NormalizeInstance(ctx, fix); err != nil {
+					return fmt.Errorf("instance %d: %w", fix.ID, err)
+				}
+			}
+			return nil
+		}); err != nil {
+			report.Failed += len(fixes)
+			lastErr = err
+			continue
+		}
+		report.Normalized += len(fixes)
+	}
+	if lastErr != nil {
+		return report, fmt.Errorf("%d of %d instances could not be normalized, last error: %w", report.Failed, report.Invalid, lastErr)
+	}
+	return report, nil
+}
@@ -0,0 +1,347 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/filters"
+)
+
+const (
+	openAPIPath         = "/api/openapi.json"
+	openAPIBearerScheme = "bearerToken"
+	openAPISessionAuth  = "sessionCookie"
+)
+
+// apiEndpoint documents a JSON API route. Every /api/ route must be listed in apiEndpoints,
+// TestOpenAPI_CoversRoutes fails otherwise.
+type apiEndpoint struct {
+	method    string
+	path      string // OpenAPI path, with {param} placeholders
+	id        string
+	summary   string
+	scope     string      // Required token scope, empty for public endpoints
+	session   bool        // Also accepts the browser session
+	v1        bool        // Returns the v1 error envelope instead of problem+json
+	request   interface{} // Zero value of the JSON request body, if any
+	status    int
+	response  interface{} // Zero value of the response body, nil for empty responses
+	mediaType string      // Media type of the response, JSON if empty
+}
+
+var apiEndpoints = []apiEndpoint{{
+	method: http.MethodGet, path: openAPIPath, id: "getOpenAPI",
+	summary: "Get this OpenAPI document",
+	status:  http.StatusOK, response: map[string]interface{}{},
+}, {
+	method: http.MethodGet, path: "/api/list/{token}", id: "getListDefinition",
+	summary: "Get a list definition, for the render tool. The token must end with .yaml",
+	status:  http.StatusOK, response: filters.List{}, mediaType: "application/yaml",
+}, {
+	method: http.MethodGet, path: "/api/instances", id: "listInstances", scope: scopeRead,
+	summary: "List the filters in your list",
+	status:  http.StatusOK, response: []*filters.Instance{},
+}, {
+	method: http.MethodGet, path: "/api/instances/{name}", id: "getInstance", scope: scopeRead,
+	summary: "Get the parameters of a filter",
+	status:  http.StatusOK, response: filters.Instance{},
+}, {
+	method: http.MethodPut, path: "/api/instances/{name}", id: "putInstance", scope: scopeWrite,
+	summary: "Add or update a filter, missing parameters use their default value",
+	request: apiInstanceRequest{}, status: http.StatusOK, response: filters.Instance{},
+}, {
+	method: http.MethodDelete, path: "/api/instances/{name}", id: "deleteInstance", scope: scopeWrite,
+	summary: "Remove a filter from your list",
+	status:  http.StatusNoContent,
+}, {
+	method: http.MethodGet, path: "/api/export", id: "exportList", scope: scopeRead,
+	summary: "Export your list as YAML, for use with the render tool",
+	status:  http.StatusOK, response: filters.List{}, mediaType: "text/yaml",
+}, {
+	method: http.MethodGet, path: "/api/template-updates", id: "listTemplateUpdates", scope: scopeRead,
+	summary: "List the filters updated since you last saved them",
+	status:  http.StatusOK, response: []templateUpdate{},
+}, {
+	method: http.MethodGet, path: "/api/sync/instances", id: "syncInstances", scope: scopeSync,
+	summary: "List the filters in your list with their change ids, and the cursor to poll changes from",
+	status:  http.StatusOK, response: syncState{},
+}, {
+	method: http.MethodGet, path: "/api/sync/changes", id: "syncChanges", scope: scopeSync,
+	summary: "List the filters changed or removed after the cursor passed in the since query parameter",
+	status:  http.StatusOK, response: syncState{},
+}, {
+	method: http.MethodPut, path: "/api/sync/instances/{name}/test-mode", id: "syncSetTestMode", scope: scopeSync,
+	summary: "Enable or disable the test mode of a filter, then list the changes like syncChanges",
+	request: syncToggleRequest{}, status: http.StatusOK, response: syncState{},
+}, {
+	method: http.MethodPut, path: "/api/sync/instances/{name}/enabled", id: "syncSetEnabled", scope: scopeSync,
+	summary: "Add a filter with its default parameters or remove it, then list the changes like syncChanges",
+	request: syncToggleRequest{}, status: http.StatusOK, response: syncState{},
+}, {
+	method: http.MethodGet, path: "/api/v1/filters", id: "v1ListFilters", scope: scopeRead, session: true, v1: true,
+	summary: "List the filters in your list",
+	status:  http.StatusOK, response: apiV1FilterList{},
+}, {
+	method: http.MethodPost, path: "/api/v1/filters", id: "v1CreateFilter", scope: scopeWrite, session: true, v1: true,
+	summary: "Add a filter, missing parameters use their default value",
+	request: apiV1CreateRequest{}, status: http.StatusCreated, response: apiV1Filter{},
+}, {
+	method: http.MethodGet, path: "/api/v1/filters/{name}", id: "v1GetFilter", scope: scopeRead, session: true, v1: true,
+	summary: "Get a filter of your list",
+	status:  http.StatusOK, response: apiV1Filter{},
+}, {
+	method: http.MethodPut, path: "/api/v1/filters/{name}", id: "v1UpdateFilter", scope: scopeWrite, session: true, v1: true,
+	summary: "Update a filter already in your list, missing parameters use their default value",
+	request: apiInstanceRequest{}, status: http.StatusOK, response: apiV1Filter{},
+}, {
+	method: http.MethodDelete, path: "/api/v1/filters/{name}", id: "v1DeleteFilter", scope: scopeWrite, session: true, v1: true,
+	summary: "Remove a filter from your list",
+	status:  http.StatusNoContent,
+}, {
+	method: http.MethodGet, path: "/api/v1/lists/{token}/subscribe", id: "v1SubscribeLinks", scope: scopeRead, session: true, v1: true,
+	summary: "Get the links to install your list in each adblocker",
+	status:  http.StatusOK, response: subscribeLinks{},
+}}
+
+var apiPathParams = map[string]string{
+	"name":  "Name of the filter template",
+	"token": "Token of the list",
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       openAPIInfo                `json:"info"`
+	Paths      map[string]openAPIPathItem `json:"paths"`
+	Components openAPIComponents          `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// openAPIPathItem maps lowercase HTTP methods to their operation
+type openAPIPathItem map[string]*openAPIOperation
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security"` // Empty for public endpoints
+}
+
+type openAPIParameter struct {
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Required    bool        `json:"required"`
+	Description string      `json:"description,omitempty"`
+	Schema      *jsonSchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                    `json:"required"`
+	Content  map[string]openAPIMedia `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                  `json:"description"`
+	Content     map[string]openAPIMedia `json:"content,omitempty"`
+}
+
+type openAPIMedia struct {
+	Schema *jsonSchema `json:"schema"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*jsonSchema            `json:"schemas"`
+	SecuritySchemes map[string]*openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	openAPIPathParam = regexp.MustCompile(`{(\w+)}`)
+	timeType         = reflect.TypeOf(time.Time{})
+	openAPISpec      = buildOpenAPIDocument(apiEndpoints)
+)
+
+// buildOpenAPIDocument generates the OpenAPI document of the endpoints, with the schemas
+// of their bodies generated from the json tags of their Go types
+func buildOpenAPIDocument(endpoints []apiEndpoint) *openAPIDocument {
+	schemas := make(schemaBuilder)
+	errorBodies := map[bool]openAPIMedia{
+		false: {Schema: schemas.schema(reflect.TypeOf(problem{}))},
+		true:  {Schema: schemas.schema(reflect.TypeOf(apiV1ErrorBody{}))},
+	}
+	doc := &openAPIDocument{
+		OpenAPI: "3.1.0",
+		Info: openAPIInfo{
+			Title:       "Let's Block It API",
+			Version:     "1",
+			Description: "Manage your filters, see https://letsblock.it/help/api",
+		},
+		Paths: make(map[string]openAPIPathItem),
+		Components: openAPIComponents{
+			Schemas: schemas,
+			SecuritySchemes: map[string]*openAPISecurityScheme{
+				openAPIBearerScheme: {
+					Type:        "http",
+					Scheme:      "bearer",
+					Description: "API token created in the account page, with the read, write or sync scope",
+				},
+				openAPISessionAuth: {
+					Type:        "apiKey",
+					In:          "cookie",
+					Name:        "ory_session",
+					Description: "Session cookie of the website, set when logging in",
+				},
+			},
+		},
+	}
+
+	for _, e := range endpoints {
+		op := &openAPIOperation{
+			OperationID: e.id,
+			Summary:     e.summary,
+			Responses:   make(map[string]*openAPIResponse),
+			Security:    []map[string][]string{},
+		}
+		for _, match := range openAPIPathParam.FindAllStringSubmatch(e.path, -1) {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name:        match[1],
+				In:          "path",
+				Required:    true,
+				Description: apiPathParams[match[1]],
+				Schema:      &jsonSchema{Type: "string"},
+			})
+		}
+		if e.request != nil {
+			op.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content: map[string]openAPIMedia{
+					echo.MIMEApplicationJSON: {Schema: schemas.schema(reflect.TypeOf(e.request))},
+				},
+			}
+		}
+
+		response := &openAPIResponse{Description: http.StatusText(e.status)}
+		if e.response != nil {
+			mediaType := e.mediaType
+			if mediaType == "" {
+				mediaType = echo.MIMEApplicationJSON
+			}
+			response.Content = map[string]openAPIMedia{
+				mediaType: {Schema: schemas.schema(reflect.TypeOf(e.response))},
+			}
+		}
+		op.Responses[strconv.Itoa(e.status)] = response
+
+		errorMediaType := problemMIME
+		if e.v1 {
+			errorMediaType = echo.MIMEApplicationJSON
+		}
+		op.Responses["default"] = &openAPIResponse{
+			Description: "Error",
+			Content:     map[string]openAPIMedia{errorMediaType: errorBodies[e.v1]},
+		}
+
+		if e.scope != "" {
+			op.Security = append(op.Security, map[string][]string{openAPIBearerScheme: {e.scope}})
+			if e.session {
+				op.Security = append(op.Security, map[string][]string{openAPISessionAuth: {}})
+			}
+		}
+
+		if doc.Paths[e.path] == nil {
+			doc.Paths[e.path] = make(openAPIPathItem)
+		}
+		doc.Paths[e.path][strings.ToLower(e.method)] = op
+	}
+	return doc
+}
+
+// schemaBuilder generates JSON schemas from Go types, registering structs as named components
+type schemaBuilder map[string]*jsonSchema
+
+func (b schemaBuilder) schema(t reflect.Type) *jsonSchema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return &jsonSchema{Type: "object", AdditionalProperties: true}
+		}
+		return &jsonSchema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &jsonSchema{Type: "string", Format: "date-time"}
+		}
+		return b.component(t)
+	}
+	return &jsonSchema{} // Any value
+}
+
+func (b schemaBuilder) component(t reflect.Type) *jsonSchema {
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	ref := &jsonSchema{Ref: "#/components/schemas/" + name}
+	if _, found := b[name]; found {
+		return ref
+	}
+	object := &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema)}
+	b[name] = object
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		key, options, _ := strings.Cut(tag, ",")
+		if key == "" {
+			key = field.Name
+		}
+		object.Properties[key] = b.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			object.Required = append(object.Required, key)
+		}
+	}
+	return ref
+}
+
+func (s *Server) openAPIHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, openAPISpec)
+}
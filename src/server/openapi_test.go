@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var echoPathParam = strings.NewReplacer(":name", "{name}", ":token", "{token}")
+
+// TestOpenAPI_CoversRoutes fails when an /api/ route is added without documenting it in apiEndpoints
+func TestOpenAPI_CoversRoutes(t *testing.T) {
+	s := &Server{
+		auth:    auth.NewProxy("X-User"),
+		echo:    echo.New(),
+		filters: filterRepo,
+		now:     time.Now,
+		options: &Options{LogLevel: "off"},
+		statsd:  &statsd.NoOpClient{},
+	}
+	s.setupRouter()
+
+	routes := make(map[string]bool)
+	for _, route := range s.echo.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/") || route.Method == echo.RouteNotFound {
+			continue
+		}
+		if route.Path == "/api/v1" || route.Path == "/api/v1/*" {
+			continue // Registered by echo to run the group middlewares on unknown routes
+		}
+		path := echoPathParam.Replace(route.Path)
+		require.NotContains(t, path, ":", "unknown path parameter in %s", route.Path)
+		routes[route.Method+" "+path] = true
+		item, found := openAPISpec.Paths[path]
+		if assert.True(t, found, "%s is missing from the OpenAPI document", path) {
+			assert.NotNil(t, item[strings.ToLower(route.Method)], "%s %s is missing from the OpenAPI document", route.Method, path)
+		}
+	}
+	for _, e := range apiEndpoints {
+		assert.True(t, routes[e.method+" "+e.path], "%s %s is documented but not registered", e.method, e.path)
+	}
+}
+
+func TestOpenAPI_Document(t *testing.T) {
+	e := echo.New()
+	e.GET(openAPIPath, (&Server{}).openAPIHandler)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, openAPIPath, nil))
+	assertOk(t, rec)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&doc))
+	assert.Equal(t, "3.1.0", doc["openapi"])
+
+	op := openAPISpec.Paths["/api/v1/filters/{name}"]["put"]
+	require.NotNil(t, op)
+	assert.Equal(t, []map[string][]string{{openAPIBearerScheme: {scopeWrite}}, {openAPISessionAuth: {}}}, op.Security)
+	assert.Equal(t, "name", op.Parameters[0].Name)
+	assert.Equal(t, "#/components/schemas/ApiInstanceRequest", op.RequestBody.Content[echo.MIMEApplicationJSON].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/ApiV1Filter", op.Responses["200"].Content[echo.MIMEApplicationJSON].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/ApiV1ErrorBody", op.Responses["default"].Content[echo.MIMEApplicationJSON].Schema.Ref)
+
+	op = openAPISpec.Paths["/api/instances/{name}"]["delete"]
+	require.NotNil(t, op)
+	assert.Nil(t, op.Responses["204"].Content)
+	assert.Equal(t, "#/components/schemas/Problem", op.Responses["default"].Content[problemMIME].Schema.Ref)
+	assert.Empty(t, openAPISpec.Paths["/api/list/{token}"]["get"].Security, "list definitions are public")
+}
+
+func TestSchemaBuilder(t *testing.T) {
+	schemas := make(schemaBuilder)
+	assert.Equal(t, &jsonSchema{Ref: "#/components/schemas/ApiV1Filter"}, schemas.schema(reflect.TypeOf(&apiV1Filter{})))
+	assert.Equal(t, &jsonSchema{
+		Type: "object",
+		Properties: map[string]*jsonSchema{
+			"template":   {Type: "string"},
+			"title":      {Type: "string"},
+			"params":     {Type: "object", AdditionalProperties: true},
+			"test_mode":  {Type: "boolean"},
+			"starred":    {Type: "boolean"},
+			"created_at": {Type: "string", Format: "date-time"},
+			"updated_at": {Type: "string", Format: "date-time"},
+		},
+		Required: []string{"template", "title", "params", "test_mode", "starred", "created_at"},
+	}, schemas["ApiV1Filter"])
+
+	assert.Equal(t, &jsonSchema{Type: "array", Items: &jsonSchema{Ref: "#/components/schemas/TemplateUpdate"}},
+		schemas.schema(reflect.TypeOf([]templateUpdate{})))
+	assert.Equal(t, &jsonSchema{Type: "object", AdditionalProperties: &jsonSchema{Type: "string"}},
+		schemas.schema(reflect.TypeOf(map[string]string{})))
+	schemas.schema(reflect.TypeOf(subscribeLinks{}))
+	assert.Equal(t, []string{"adblocker", "instructions"}, schemas["SubscribeLink"].Required,
+		"nested structs are registered too")
+}
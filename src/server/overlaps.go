@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/samber/lo"
+)
+
+// overlapSuperseded is the warning kind for a saved filter that includes the rules of another one
+const overlapSuperseded = "superseded"
+
+// overlapWarning describes an overlap between the filter being saved and another filter of the user's list
+type overlapWarning struct {
+	Template string
+	Title    string
+	Kind     string
+}
+
+// Bounds of the save-time rule clash check: larger lists are not checked, and each warning only
+// quotes the first clashing rules
+const (
+	maxClashCheckInstances = 100
+	maxClashWarningRules   = 3
+)
+
+// clashWarning describes rules of the filter being saved clashing with the rules of another filter of
+// the user's list, for its current params
+type clashWarning struct {
+	Template string
+	Title    string
+	Kind     string
+	Rules    []string // First clashing rules, as "rule" for duplicates and "rule / other rule" for exceptions
+	Count    int
+}
+
+// overlapReportRow lists the overlapping filters of a user, for the admin report
+type overlapReportRow struct {
+	UserID   string
+	Overlaps []string
+}
+
+// findOverlapWarnings returns the overlaps between a template and the other filters of the user's list
+func (s *Server) findOverlapWarnings(ctx context.Context, user, template string) ([]overlapWarning, error) {
+	repo := s.config().filters
+	if !lo.Contains(repo.OverlappingTemplates(), template) {
+		return nil, nil
+	}
+	instances, err := s.store.GetInstancesForUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(instances))
+	for _, i := range instances {
+		names = append(names, i.TemplateName)
+	}
+
+	var warnings []overlapWarning
+	for _, overlap := range repo.FindOverlaps(names) {
+		if !overlap.Involves(template) {
+			continue
+		}
+		warning := overlapWarning{Template: overlap.Template, Kind: string(overlap.Kind)}
+		if overlap.Template == template {
+			warning.Template = overlap.Other
+			if overlap.Kind == filters.OverlapSupersedes {
+				warning.Kind = overlapSuperseded
+			}
+		}
+		warning.Title = warning.Template
+		if filter, err := repo.Get(warning.Template); err == nil {
+			warning.Title = filter.Title
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings, nil
+}
+
+// adminOverlaps lists the users having overlapping filters in their list, to review new overlap annotations
+func (s *Server) adminOverlaps(c echo.Context) error {
+	repo := s.config().filters
+	var rows []overlapReportRow
+	if names := repo.OverlappingTemplates(); len(names) > 0 {
+		instances, err := s.store.GetInstancesForTemplates(c.Request().Context(), names)
+		if err != nil {
+			return err
+		}
+		// Instances are sorted by user ID
+		for start := 0; start < len(instances); {
+			end := start
+			var templates []string
+			for ; end < len(instances) && instances[end].UserID == instances[start].UserID; end++ {
+				templates = append(templates, instances[end].TemplateName)
+			}
+			if overlaps := repo.FindOverlaps(templates); len(overlaps) > 0 {
+				row := overlapReportRow{UserID: instances[start].UserID}
+				for _, o := range overlaps {
+					row.Overlaps = append(row.Overlaps, o.String())
+				}
+				rows = append(rows, row)
+			}
+			start = end
+		}
+	}
+
+	hc := s.buildPageContext(c, "Overlapping filters")
+	hc.NoBoost = true
+	hc.Add("overlapping_templates", repo.OverlappingTemplates())
+	hc.Add("overlap_users", rows)
+	return s.pages.Render(c, "admin-overlaps", hc)
+}
+
+// findClashWarnings renders the saved instance and the other instances of the user's list, to warn about
+// the rules that are duplicated or undone by exceptions. Instances failing to render are skipped.
+func (s *Server) findClashWarnings(ctx context.Context, user string, saved *filters.Instance) ([]clashWarning, error) {
+	repo := s.config().filters
+	instances, err := s.store.GetInstancesForUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) < 2 || len(instances) > maxClashCheckInstances {
+		return nil, nil
+	}
+
+	var buf strings.Builder
+	if err = repo.Render(&buf, saved); err != nil {
+		return nil, nil
+	}
+	rendered := buf.String()
+	others := make(map[string]string, len(instances)-1)
+	for _, instance := range instances {
+		if instance.TemplateName == saved.Template {
+			continue
+		}
+		other := &filters.Instance{Template: instance.TemplateName}
+		if err = instance.Params.AssignTo(&other.Params); err != nil {
+			continue
+		}
+		buf.Reset()
+		if err = repo.Render(&buf, other); err != nil {
+			continue
+		}
+		others[instance.TemplateName] = buf.String()
+	}
+
+	// One warning per clashing template and kind, in the order of their first clash
+	var warnings []clashWarning
+	positions := make(map[filters.RuleClash]int)
+	for _, clash := range filters.FindRuleClashes(rendered, others) {
+		key := filters.RuleClash{Other: clash.Other, Kind: clash.Kind}
+		pos, found := positions[key]
+		if !found {
+			warning := clashWarning{Template: clash.Other, Title: clash.Other, Kind: string(clash.Kind)}
+			if filter, err := repo.Get(clash.Other); err == nil {
+				warning.Title = filter.Title
+			}
+			pos = len(warnings)
+			positions[key] = pos
+			warnings = append(warnings, warning)
+		}
+		warning := &warnings[pos]
+		warning.Count++
+		if len(warning.Rules) < maxClashWarningRules {
+			rule := clash.Rule
+			if clash.Kind != filters.ClashDuplicate {
+				rule += " / " + clash.OtherRule
+			}
+			warning.Rules = append(warning.Rules, rule)
+		}
+	}
+	return warnings, nil
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useOverlapTemplates swaps the test templates for a copy where filter1 conflicts with filter2
+func (s *ServerTestSuite) useOverlapTemplates() {
+	repo, err := filters.LoadSources(
+		filters.Source{Name: "testdata", Templates: testTemplates, Presets: testTemplates},
+		filters.Source{Name: "overlaps", Templates: fstest.MapFS{
+			"filter1.yaml": {Data: []byte("title: Filter 1\nconflicts_with: [filter2]\ntemplate: |\n  hello from one\n---\ndescription")},
+		}, Presets: testTemplates},
+	)
+	require.NoError(s.T(), err)
+	s.server.live.Store(&liveConfig{
+		options:    s.server.options,
+		filters:    repo,
+		filterHash: s.server.filterHash,
+	})
+}
+
+func (s *ServerTestSuite) TestViewFilter_OverlapWarning() {
+	s.useOverlapTemplates()
+	s.addInstance(s.user, "filter2", filter2Custom)
+
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	f.Add("__save", "")
+	req := httptest.NewRequest(http.MethodPost, "/filters/filter1", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	s.expectP.Render(gomock.Any(), "view-filter", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.Equal(s.T(), true, hc.Data["saved_ok"])
+			assert.Equal(s.T(), []overlapWarning{{
+				Template: "filter2",
+				Title:    filter2.Title,
+				Kind:     string(filters.OverlapConflict),
+			}}, hc.Data["overlaps"])
+			return nil
+		})
+	s.runRequest(req, assertOk)
+	s.requireInstanceCount("filter1", 1)
+}
+
+func (s *ServerTestSuite) TestViewFilter_NoOverlapWarning() {
+	s.useOverlapTemplates()
+	s.addInstance(s.user, "custom-rules", nil)
+
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	f.Add("__save", "")
+	req := httptest.NewRequest(http.MethodPost, "/filters/filter1", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	s.expectP.Render(gomock.Any(), "view-filter", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.Equal(s.T(), true, hc.Data["saved_ok"])
+			assert.NotContains(s.T(), hc.Data, "overlaps")
+			return nil
+		})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestRenderList_Overlaps() {
+	s.useOverlapTemplates()
+	s.addInstance(s.user, "filter1", nil)
+	s.addInstance(s.user, "filter2", filter2Custom)
+	list, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "/list/"+list.Token.String(), nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.Contains(t, rec.Body.String(), "! Overlap: filter1 conflicts with filter2\n")
+	})
+}
+
+func (s *ServerTestSuite) TestAdminOverlaps() {
+	s.useOverlapTemplates()
+	s.addInstance(s.user, "filter1", nil)
+	s.addInstance(s.user, "filter2", filter2Custom)
+	s.addInstance("other-user", "filter1", nil)
+	s.addInstance("other-user", "custom-rules", nil)
+	s.setUserAdmin()
+
+	s.expectRender("admin-overlaps", pages.ContextData{
+		"overlapping_templates": []string{"filter1", "filter2"},
+		"overlap_users": []overlapReportRow{{
+			UserID:   s.user,
+			Overlaps: []string{"filter1 conflicts with filter2"},
+		}},
+	})
+	s.runRequest(httptest.NewRequest(http.MethodGet, "/admin/overlaps", nil), assertOk)
+}
+
+// useClashTemplates adds templates whose rules clash for some params of the hider template
+func (s *ServerTestSuite) useClashTemplates() {
+	repo, err := filters.LoadSources(
+		filters.Source{Name: "testdata", Templates: testTemplates, Presets: testTemplates},
+		filters.Source{Name: "clashes", Templates: fstest.MapFS{
+			"hider.yaml": {Data: []byte("title: Hider\nparams:\n  - name: selector\n    type: string\n    default: .ad\n" +
+				"template: |\n  example.com##{{selector}}\n  ||ads.example.com^\n---\ndescription")},
+			"unhider.yaml": {Data: []byte("title: Unhider\ntemplate: |\n  example.com#@#.banner\n---\ndescription")},
+			"blocker.yaml": {Data: []byte("title: Blocker\ntemplate: |\n  ||ads.example.com^\n---\ndescription")},
+		}, Presets: testTemplates},
+	)
+	require.NoError(s.T(), err)
+	s.server.live.Store(&liveConfig{
+		options:    s.server.options,
+		filters:    repo,
+		filterHash: s.server.filterHash,
+	})
+}
+
+func (s *ServerTestSuite) TestViewFilter_ClashWarning() {
+	s.useClashTemplates()
+	s.addInstance(s.user, "unhider", nil)
+	s.addInstance(s.user, "blocker", nil)
+
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	f.Add("__save", "")
+	f.Add("selector", ".banner")
+	req := httptest.NewRequest(http.MethodPost, "/filters/hider", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	s.expectP.Render(gomock.Any(), "view-filter", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.Equal(s.T(), true, hc.Data["saved_ok"])
+			assert.Equal(s.T(), []clashWarning{{
+				Template: "blocker",
+				Title:    "Blocker",
+				Kind:     string(filters.ClashDuplicate),
+				Rules:    []string{"||ads.example.com^"},
+				Count:    1,
+			}, {
+				Template: "unhider",
+				Title:    "Unhider",
+				Kind:     string(filters.ClashUndone),
+				Rules:    []string{"example.com##.banner / example.com#@#.banner"},
+				Count:    1,
+			}}, hc.Data["clashes"])
+			return nil
+		})
+	s.runRequest(req, assertOk)
+	s.requireInstanceCount("hider", 1)
+}
+
+func (s *ServerTestSuite) TestViewFilter_NoClashWarning() {
+	s.useClashTemplates()
+	s.addInstance(s.user, "unhider", nil)
+
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	f.Add("__save", "")
+	f.Add("selector", ".sidebar")
+	req := httptest.NewRequest(http.MethodPost, "/filters/hider", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	s.expectP.Render(gomock.Any(), "view-filter", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.Equal(s.T(), true, hc.Data["saved_ok"])
+			assert.NotContains(s.T(), hc.Data, "clashes")
+			return nil
+		})
+	s.runRequest(req, assertOk)
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+)
+
+// paramStat is the distribution of the values of a checkbox parameter or preset, across the instances of a template
+type paramStat struct {
+	Template string `json:"template"`
+	Param    string `json:"param"`
+	Default  bool   `json:"default"`
+	Enabled  int64  `json:"enabled"`
+	Disabled int64  `json:"disabled"`
+}
+
+// paramStatsReport is served to maintainers, to tune the template defaults
+type paramStatsReport struct {
+	ComputedAt *time.Time  `json:"computed_at"`
+	Params     []paramStat `json:"params"`
+}
+
+// booleanParams returns the defaults of the checkbox parameters and presets of a template, by parameter name.
+// Free-text and list parameters are left out, as their values can identify users.
+func booleanParams(tpl *filters.Template) map[string]bool {
+	params := make(map[string]bool)
+	for _, p := range tpl.Params {
+		if p.Type == filters.BooleanParam {
+			enabled, _ := p.Default.(bool)
+			params[p.Name] = enabled
+		}
+		for _, preset := range p.Presets {
+			params[p.BuildPresetParamName(preset.Name)] = preset.Default
+		}
+	}
+	return params
+}
+
+// buildParamStats keeps the counts of the checkbox parameters and presets of the known templates,
+// for the templates used by at least minInstances instances
+func buildParamStats(repo *filters.Repository, counts []db.GetBooleanParamCountsRow, totals []db.GetInstanceStatsRow,
+	minInstances int64, now time.Time) db.UpsertParamStatsParams {
+	used := make(map[string]bool, len(totals))
+	for _, t := range totals {
+		used[t.TemplateName] = t.Total >= minInstances
+	}
+	stats := db.UpsertParamStatsParams{ComputedAt: now}
+	for _, count := range counts {
+		if !used[count.TemplateName] {
+			continue
+		}
+		tpl, err := repo.Get(count.TemplateName)
+		if err != nil {
+			continue
+		}
+		if _, found := booleanParams(tpl)[count.ParamName]; !found {
+			continue
+		}
+		stats.TemplateNames = append(stats.TemplateNames, count.TemplateName)
+		stats.ParamNames = append(stats.ParamNames, count.ParamName)
+		stats.Enabled = append(stats.Enabled, count.Enabled)
+		stats.Disabled = append(stats.Disabled, count.Disabled)
+	}
+	return stats
+}
+
+// computeParamStats aggregates the parameter values of all instances, and replaces the stored statistics
+func (s *Server) computeParamStats(ctx context.Context) error {
+	counts, err := s.store.GetBooleanParamCounts(ctx)
+	if err != nil {
+		return err
+	}
+	totals, err := s.store.GetInstanceStats(ctx)
+	if err != nil {
+		return err
+	}
+	stats := buildParamStats(s.config().filters, counts, totals, int64(s.options.SuggestionMinLists), s.now())
+	if err = s.store.UpsertParamStats(ctx, stats); err != nil {
+		return err
+	}
+	return s.store.DeleteParamStatsBefore(ctx, stats.ComputedAt)
+}
+
+// refreshParamStats computes the parameter statistics now and every interval, until ctx is done
+func (s *Server) refreshParamStats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.computeParamStats(ctx); err != nil {
+			s.echo.Logger.Warnf("cannot compute the parameter statistics: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// adminParamStats returns the last computed parameter statistics as JSON, on the official instance only
+func (s *Server) adminParamStats(c echo.Context) error {
+	if !s.options.OfficialInstance {
+		return echo.ErrNotFound
+	}
+	stored, err := s.store.GetParamStats(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	repo := s.config().filters
+	report := paramStatsReport{Params: make([]paramStat, 0, len(stored))}
+	for _, row := range stored {
+		stat := paramStat{Template: row.TemplateName, Param: row.ParamName, Enabled: row.Enabled, Disabled: row.Disabled}
+		if tpl, err := repo.Get(row.TemplateName); err == nil {
+			stat.Default = booleanParams(tpl)[row.ParamName]
+		}
+		report.Params = append(report.Params, stat)
+	}
+	if len(stored) > 0 {
+		// Older rows are deleted once the new ones are stored, all rows have the same time
+		computedAt := stored[0].ComputedAt.UTC()
+		report.ComputedAt = &computedAt
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// adminComputeParamStats computes the parameter statistics now, on the official instance only
+func (s *Server) adminComputeParamStats(c echo.Context) error {
+	if !s.options.OfficialInstance {
+		return echo.ErrNotFound
+	}
+	if err := s.computeParamStats(c.Request().Context()); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("admin-param-stats"))
+}
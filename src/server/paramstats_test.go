@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildParamStats(t *testing.T) {
+	repo, err := filters.Load(testTemplates, testTemplates)
+	require.NoError(t, err)
+	counts := []db.GetBooleanParamCountsRow{
+		{TemplateName: "filter2", ParamName: "two", Enabled: 8, Disabled: 4},
+		{TemplateName: "filter2", ParamName: "three---preset---dummy", Enabled: 3, Disabled: 9},
+		// Boolean values stored for other parameter types, or removed parameters and templates
+		{TemplateName: "filter2", ParamName: "one", Enabled: 1},
+		{TemplateName: "filter2", ParamName: "three", Enabled: 1},
+		{TemplateName: "filter2", ParamName: "removed", Enabled: 1},
+		{TemplateName: "removed", ParamName: "two", Enabled: 12},
+		// Not enough instances
+		{TemplateName: "filter1", ParamName: "two", Enabled: 2},
+	}
+	totals := []db.GetInstanceStatsRow{
+		{TemplateName: "filter1", Total: 2},
+		{TemplateName: "filter2", Total: 12},
+		{TemplateName: "removed", Total: 12},
+	}
+
+	stats := buildParamStats(repo, counts, totals, 10, fixedNow)
+	assert.Equal(t, db.UpsertParamStatsParams{
+		TemplateNames: []string{"filter2", "filter2"},
+		ParamNames:    []string{"two", "three---preset---dummy"},
+		Enabled:       []int64{8, 3},
+		Disabled:      []int64{4, 9},
+		ComputedAt:    fixedNow,
+	}, stats)
+}
+
+func (s *ServerTestSuite) TestAdminParamStats() {
+	s.server.options.OfficialInstance = true
+	s.server.options.SuggestionMinLists = 2
+	s.addInstance(s.user, "filter2", filter2Custom)
+	s.addInstance("other-user", "filter2", map[string]any{
+		"one":                    "other@example.com",
+		"two":                    false,
+		"three":                  []string{"private.example.com"},
+		"three---preset---dummy": true,
+	})
+	s.addInstance("third-user", "filter1", nil)
+	s.setUserAdmin()
+
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/admin/param-stats", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/admin/param-stats")
+	s.runRequest(req, assertOk)
+
+	stored, err := s.store.GetParamStats(context.Background())
+	require.NoError(s.T(), err)
+	for _, row := range stored {
+		s.Contains([]string{"two", "three---preset---dummy"}, row.ParamName, "free-text and list parameters are excluded")
+	}
+
+	s.runRequest(httptest.NewRequest(http.MethodGet, "/admin/param-stats", nil), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.NotContains(t, rec.Body.String(), "example.com")
+		var report paramStatsReport
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, []paramStat{
+			{Template: "filter2", Param: "three---preset---dummy", Enabled: 1, Disabled: 1},
+			{Template: "filter2", Param: "two", Default: true, Enabled: 1, Disabled: 1},
+		}, report.Params)
+		if assert.NotNil(t, report.ComputedAt) {
+			assert.Equal(t, fixedNow, *report.ComputedAt)
+		}
+	})
+}
+
+func (s *ServerTestSuite) TestAdminParamStats_NotOfficial() {
+	s.setUserAdmin()
+	s.runRequest(httptest.NewRequest(http.MethodGet, "/admin/param-stats", nil), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
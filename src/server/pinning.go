@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// Instances can be pinned to the template version they were saved with, until the user updates them.
+// The definitions of the latest versions of every template are stored when the templates are loaded,
+// pinned instances whose version is no longer stored are rendered with the latest template.
+const templateVersionsKept = 5
+
+// versionCache holds the template versions parsed for pinned instances, by template name and hash.
+// Versions that are not stored anymore are cached as nil, until the next template load.
+type versionCache struct {
+	sync.Mutex
+	versions map[string]*filters.TemplateVersion
+}
+
+func (v *versionCache) get(key string) (*filters.TemplateVersion, bool) {
+	v.Lock()
+	defer v.Unlock()
+	version, found := v.versions[key]
+	return version, found
+}
+
+func (v *versionCache) set(key string, version *filters.TemplateVersion) {
+	v.Lock()
+	defer v.Unlock()
+	if v.versions == nil {
+		v.versions = make(map[string]*filters.TemplateVersion)
+	}
+	v.versions[key] = version
+}
+
+func (v *versionCache) reset() {
+	v.Lock()
+	defer v.Unlock()
+	v.versions = nil
+}
+
+// pinnedRepository renders the pinned instances of a list with their template version
+type pinnedRepository struct {
+	*filters.Repository
+	pinned map[string]*filters.TemplateVersion
+}
+
+func (r *pinnedRepository) Render(w io.Writer, instance *filters.Instance) error {
+	if version, found := r.pinned[instance.Template]; found {
+		return version.Render(w, instance)
+	}
+	return r.Repository.Render(w, instance)
+}
+
+// recordTemplateDefinitions stores the definition of the current templates, and prunes the older versions
+func recordTemplateDefinitions(ctx context.Context, q db.Querier, repo *filters.Repository) error {
+	var params db.AddTemplateDefinitionsParams
+	for _, tpl := range repo.GetAll() {
+		definition, err := repo.Definition(tpl.Name)
+		if err != nil {
+			return err
+		}
+		params.TemplateNames = append(params.TemplateNames, tpl.Name)
+		params.TemplateHashes = append(params.TemplateHashes, repo.Hash(tpl.Name))
+		params.Definitions = append(params.Definitions, definition)
+	}
+	if err := q.AddTemplateDefinitions(ctx, params); err != nil {
+		return err
+	}
+	return q.PruneTemplateDefinitions(ctx, templateVersionsKept)
+}
+
+// pinnedRepository loads the template versions of the pinned instances that are not on the latest version
+func (s *Server) pinnedRepository(ctx context.Context, logger echo.Logger, repo *filters.Repository,
+	storedInstances []db.GetInstancesForListRow) (*pinnedRepository, error) {
+	out := &pinnedRepository{Repository: repo}
+	for _, instance := range storedInstances {
+		if !instance.Pinned || instance.TemplateHash == "" || instance.TemplateHash == repo.Hash(instance.TemplateName) {
+			continue
+		}
+		key := instance.TemplateName + ":" + instance.TemplateHash
+		version, found := s.versions.get(key)
+		if !found {
+			definition, err := s.store.GetTemplateDefinition(ctx, db.GetTemplateDefinitionParams{
+				TemplateName: instance.TemplateName,
+				TemplateHash: instance.TemplateHash,
+			})
+			switch {
+			case err == db.NotFound:
+			case err != nil:
+				return nil, fmt.Errorf("failed to get template version: %w", err)
+			default:
+				if version, err = filters.ParseDefinition(instance.TemplateName, definition); err != nil {
+					logger.Warnf("cannot parse version %s, rendering the latest one: %s", key, err)
+				}
+			}
+			s.versions.set(key, version)
+		}
+		if version != nil {
+			if out.pinned == nil {
+				out.pinned = make(map[string]*filters.TemplateVersion)
+			}
+			out.pinned[instance.TemplateName] = version
+		}
+	}
+	return out, nil
+}
+
+// pinnedETag hashes the pinned versions of a list, to add them to its etag
+func pinnedETag(pinnedVersions string) string {
+	if pinnedVersions == "" {
+		return ""
+	}
+	hasher := fnv.New32a()
+	_, _ = hasher.Write([]byte(pinnedVersions))
+	return "-" + strconv.FormatUint(uint64(hasher.Sum32()), 36)
+}
+
+// pinFilter pins a filter instance to the version it was saved with, or follows the latest version again
+func (s *Server) pinFilter(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return errors.New("invalid user session")
+	}
+	repo := s.config().filters
+	filter, err := repo.Get(c.Param("name"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	pinned := c.FormValue("pinned") == "true"
+
+	if err = s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		stored, err := q.GetInstance(ctx, db.GetInstanceParams{UserID: user, TemplateName: filter.Name})
+		switch {
+		case err == db.NotFound:
+			return echo.ErrNotFound
+		case err != nil:
+			return err
+		}
+		// Instances saved before versions were recorded are pinned to the latest version
+		hash := stored.TemplateHash
+		if hash == "" {
+			hash = repo.Hash(filter.Name)
+		}
+		_, err = q.PinInstance(ctx, db.PinInstanceParams{
+			UserID:       user,
+			TemplateName: filter.Name,
+			Pinned:       pinned,
+			TemplateHash: hash,
+		})
+		return err
+	}); err != nil {
+		return err
+	}
+	s.notifyListChange(user, webhookInstanceUpdated, filter.Name)
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("view-filter", filter.Name))
+}
+
+// updateFilterVersion moves a pinned instance to the latest template version, if its parameters are valid for it
+func (s *Server) updateFilterVersion(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return errors.New("invalid user session")
+	}
+	repo := s.config().filters
+	filter, err := repo.Get(c.Param("name"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+
+	if err = s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		stored, err := q.GetInstance(ctx, db.GetInstanceParams{UserID: user, TemplateName: filter.Name})
+		switch {
+		case err == db.NotFound:
+			return echo.ErrNotFound
+		case err != nil:
+			return err
+		}
+		params := make(map[string]interface{})
+		if stored.Params.Status == pgtype.Present {
+			if err = stored.Params.AssignTo(&params); err != nil {
+				return err
+			}
+		}
+		if errs := filter.ValidateParams(params); len(errs) > 0 {
+			messages := make([]string, 0, len(errs))
+			for _, e := range errs {
+				messages = append(messages, e.Error())
+			}
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"your parameters are not valid for the latest version, update them first: "+strings.Join(messages, ", "))
+		}
+		_, err = q.PinInstance(ctx, db.PinInstanceParams{
+			UserID:       user,
+			TemplateName: filter.Name,
+			Pinned:       stored.Pinned,
+			TemplateHash: repo.Hash(filter.Name),
+		})
+		return err
+	}); err != nil {
+		return err
+	}
+	s.notifyListChange(user, webhookInstanceUpdated, filter.Name)
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("view-filter", filter.Name))
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const filter2OldDefinition = `title: Second filter
+params:
+  - name: one
+    type: string
+    default: default
+  - name: three
+    type: list
+    default: []
+template: |
+  {{#each three}}
+  bye {{.}} {{one}}
+  {{/each}}
+`
+
+func TestPinnedRepository(t *testing.T) {
+	latest, err := filters.Load(testTemplates, testTemplates)
+	require.NoError(t, err)
+	version, err := filters.ParseDefinition("filter2", filter2OldDefinition)
+	require.NoError(t, err)
+	repo := &pinnedRepository{
+		Repository: latest,
+		pinned:     map[string]*filters.TemplateVersion{"filter2": version},
+	}
+
+	var buf strings.Builder
+	require.NoError(t, repo.Render(&buf, &filters.Instance{Template: "filter2", Params: filter2Custom}))
+	assert.Equal(t, "bye one blep\nbye two blep\n", buf.String())
+	buf.Reset()
+	require.NoError(t, repo.Render(&buf, &filters.Instance{Template: "filter1"}))
+	assert.Equal(t, "hello from one\n", buf.String())
+}
+
+func TestPinnedETag(t *testing.T) {
+	assert.Equal(t, "", pinnedETag(""))
+	assert.NotEqual(t, pinnedETag("filter2:a"), pinnedETag("filter2:b"))
+	assert.True(t, strings.HasPrefix(pinnedETag("filter2:a"), "-"))
+}
+
+func (s *ServerTestSuite) pinAction(path string, values url.Values) *http.Request {
+	values.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(values.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) TestPinFilter_Lifecycle() {
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   filter2Custom,
+	}))
+
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters/filter2")
+	s.runRequest(s.pinAction("/filters/filter2/pin", url.Values{"pinned": {"true"}}), assertOk)
+	stored, err := s.store.GetInstance(context.Background(), db.GetInstanceParams{
+		UserID:       s.user,
+		TemplateName: "filter2",
+	})
+	require.NoError(s.T(), err)
+	assert.True(s.T(), stored.Pinned)
+	assert.Equal(s.T(), filterRepo.Hash("filter2"), stored.TemplateHash)
+
+	// Simulate a template update by pinning an older stored version
+	require.NoError(s.T(), s.store.AddTemplateDefinitions(context.Background(), db.AddTemplateDefinitionsParams{
+		TemplateNames:  []string{"filter2"},
+		TemplateHashes: []string{"oldhash"},
+		Definitions:    []string{filter2OldDefinition},
+	}))
+	_, err = s.store.PinInstance(context.Background(), db.PinInstanceParams{
+		UserID:       s.user,
+		TemplateName: "filter2",
+		Pinned:       true,
+		TemplateHash: "oldhash",
+	})
+	require.NoError(s.T(), err)
+
+	getList := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.server.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list/"+token.String(), nil))
+		s.Equal(http.StatusOK, rec.Code)
+		return rec
+	}
+	rec := getList()
+	s.Contains(rec.Body.String(), "bye one blep\nbye two blep\n")
+	s.NotContains(rec.Body.String(), filter2CustomOutput)
+	pinnedETag := rec.Header().Get("etag")
+
+	// Saving the parameters keeps the pinned version
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   filter2Custom,
+	}))
+	s.Contains(getList().Body.String(), "bye one blep\n")
+
+	// Updating to the latest version keeps the instance pinned
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters/filter2")
+	s.runRequest(s.pinAction("/filters/filter2/update", url.Values{}), assertOk)
+	rec = getList()
+	s.Contains(rec.Body.String(), filter2CustomOutput)
+	s.NotEqual(pinnedETag, rec.Header().Get("etag"))
+	stored, err = s.store.GetInstance(context.Background(), db.GetInstanceParams{
+		UserID:       s.user,
+		TemplateName: "filter2",
+	})
+	require.NoError(s.T(), err)
+	assert.True(s.T(), stored.Pinned)
+	assert.Equal(s.T(), filterRepo.Hash("filter2"), stored.TemplateHash)
+
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters/filter2")
+	s.runRequest(s.pinAction("/filters/filter2/pin", url.Values{"pinned": {"false"}}), assertOk)
+	list, err := s.store.GetListForToken(context.Background(), token)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), list.PinnedVersions)
+}
+
+func (s *ServerTestSuite) TestPinFilter_PrunedVersion() {
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   filter2Custom,
+	}))
+	_, err = s.store.PinInstance(context.Background(), db.PinInstanceParams{
+		UserID:       s.user,
+		TemplateName: "filter2",
+		Pinned:       true,
+		TemplateHash: "unknown",
+	})
+	require.NoError(s.T(), err)
+
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list/"+token.String(), nil))
+	s.Equal(http.StatusOK, rec.Code)
+	s.Contains(rec.Body.String(), filter2CustomOutput)
+}
+
+func (s *ServerTestSuite) TestUpdateFilterVersion_InvalidParams() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   map[string]interface{}{"one": true},
+	}))
+	s.runRequest(s.pinAction("/filters/filter2/update", url.Values{}), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "parameter one")
+	})
+}
+
+func (s *ServerTestSuite) TestRecordTemplateDefinitions() {
+	ctx := context.Background()
+	require.NoError(s.T(), recordTemplateDefinitions(ctx, s.store, filterRepo))
+	definition, err := s.store.GetTemplateDefinition(ctx, db.GetTemplateDefinitionParams{
+		TemplateName: "filter2",
+		TemplateHash: filterRepo.Hash("filter2"),
+	})
+	require.NoError(s.T(), err)
+	version, err := filters.ParseDefinition("filter2", definition)
+	require.NoError(s.T(), err)
+	var buf strings.Builder
+	require.NoError(s.T(), version.Render(&buf, &filters.Instance{Template: "filter2", Params: filter2Custom}))
+	assert.Equal(s.T(), filter2CustomOutput, buf.String())
+}
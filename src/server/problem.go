@@ -0,0 +1,78 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/filters"
+)
+
+const problemMIME = "application/problem+json"
+
+// problem is an RFC 7807 error body, returned to API clients
+type problem struct {
+	Type        string            `json:"type"`
+	Title       string            `json:"title"`
+	Status      int               `json:"status"`
+	Detail      string            `json:"detail,omitempty"`
+	RequestID   string            `json:"request_id,omitempty"`
+	FieldErrors map[string]string `json:"field_errors,omitempty"` // Extension listing the invalid fields
+}
+
+func newProblem(status int, message interface{}, requestID string) *problem {
+	p := &problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		RequestID: requestID,
+	}
+	if detail, ok := message.(string); ok && detail != p.Title {
+		p.Detail = detail
+	}
+	return p
+}
+
+// wantsProblem returns whether errors are rendered as problem+json: for API routes, and for
+// clients explicitly asking for JSON. Wildcards are ignored, as browsers send them too.
+func wantsProblem(req *http.Request) bool {
+	if strings.HasPrefix(req.URL.Path, "/api/") {
+		return true
+	}
+	for _, part := range strings.Split(req.Header.Get(echo.HeaderAccept), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && (mediaType == echo.MIMEApplicationJSON || mediaType == problemMIME) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeProblem(c echo.Context, p *problem) error {
+	c.Response().Header().Set(echo.HeaderContentType, problemMIME)
+	return c.JSON(p.Status, p)
+}
+
+// listErrorBody is served on list errors, only holding comments for adblockers to ignore it
+func listErrorBody(message interface{}, status int, requestID string) string {
+	detail, ok := message.(string)
+	if !ok {
+		detail = http.StatusText(status)
+	}
+	return fmt.Sprintf("! Error: %s\n! Request ID: %s\n", strings.ReplaceAll(detail, "\n", " "), requestID)
+}
+
+// paramFieldErrors maps template parameter validation errors to their field name
+func paramFieldErrors(errs []error) map[string]string {
+	fields := make(map[string]string, len(errs))
+	for _, e := range errs {
+		var paramErr *filters.ParamError
+		if errors.As(e, &paramErr) {
+			fields["params."+paramErr.Param] = paramErr.Message
+		}
+	}
+	return fields
+}
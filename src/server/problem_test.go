@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+)
+
+func newProblemTestEcho() *echo.Echo {
+	e := echo.New()
+	e.Logger.SetOutput(io.Discard)
+	e.HTTPErrorHandler = (&Server{}).handleError
+	e.Use(buildRequestIDMiddleware())
+	notFound := func(echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "unknown template")
+	}
+	e.GET("/api/instances/:name", notFound)
+	e.GET("/filters/:name", notFound)
+	e.Match([]string{http.MethodGet, http.MethodHead}, "/list/:token", notFound)
+	e.PUT("/api/instances/:name", func(echo.Context) error {
+		return &apiError{
+			Status:  http.StatusBadRequest,
+			Message: "invalid parameters: parameter one: expected a string value",
+			Fields:  map[string]string{"params.one": "expected a string value"},
+		}
+	})
+	e.GET("/api/export", func(echo.Context) error {
+		return errors.New("connection lost")
+	})
+	return e
+}
+
+func serveProblemTest(e *echo.Echo, method, target, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if accept != "" {
+		req.Header.Set(echo.HeaderAccept, accept)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleError_Problem(t *testing.T) {
+	e := newProblemTestEcho()
+	for name, tc := range map[string]struct {
+		method, target, accept string
+		expected               string
+	}{
+		"api route": {
+			http.MethodGet, "/api/instances/unknown", "",
+			`{"type":"about:blank","title":"Not Found","status":404,"detail":"unknown template","request_id":"%s"}`,
+		},
+		"json client": {
+			http.MethodGet, "/filters/unknown", "application/json",
+			`{"type":"about:blank","title":"Not Found","status":404,"detail":"unknown template","request_id":"%s"}`,
+		},
+		"problem client": {
+			http.MethodGet, "/filters/unknown", "text/plain, application/problem+json;q=0.9",
+			`{"type":"about:blank","title":"Not Found","status":404,"detail":"unknown template","request_id":"%s"}`,
+		},
+		"validation failure": {
+			http.MethodPut, "/api/instances/filter2", "",
+			`{"type":"about:blank","title":"Bad Request","status":400,"request_id":"%s",` +
+				`"detail":"invalid parameters: parameter one: expected a string value",` +
+				`"field_errors":{"params.one":"expected a string value"}}`,
+		},
+		"unexpected error": {
+			http.MethodGet, "/api/export", "",
+			`{"type":"about:blank","title":"Internal Server Error","status":500,"request_id":"%s"}`,
+		},
+		"unknown api route": {
+			http.MethodGet, "/api/unknown", "",
+			`{"type":"about:blank","title":"Not Found","status":404,"request_id":"%s"}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := serveProblemTest(e, tc.method, tc.target, tc.accept)
+			assert.Equal(t, problemMIME, rec.Header().Get(echo.HeaderContentType))
+			requestID := rec.Header().Get(echo.HeaderXRequestID)
+			assert.JSONEq(t, strings.Replace(tc.expected, "%s", requestID, 1), rec.Body.String())
+			assert.NotContains(t, rec.Body.String(), "connection lost")
+		})
+	}
+}
+
+func TestHandleError_Html(t *testing.T) {
+	e := newProblemTestEcho()
+	rec := serveProblemTest(e, http.MethodGet, "/filters/unknown", "text/html,application/xhtml+xml,*/*;q=0.8")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	requestID := rec.Header().Get(echo.HeaderXRequestID)
+	assert.JSONEq(t, `{"message":"unknown template","request_id":"`+requestID+`"}`, rec.Body.String())
+}
+
+func TestHandleError_List(t *testing.T) {
+	e := newProblemTestEcho()
+	rec := serveProblemTest(e, http.MethodGet, "/list/token", "application/json")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	requestID := rec.Header().Get(echo.HeaderXRequestID)
+	assert.Equal(t, "! Error: unknown template\n! Request ID: "+requestID+"\n", rec.Body.String())
+
+	rec = serveProblemTest(e, http.MethodHead, "/list/token", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestParamFieldErrors(t *testing.T) {
+	assert.Equal(t, map[string]string{"params.one": "expected a string value"}, paramFieldErrors([]error{
+		&filters.ParamError{Param: "one", Message: "expected a string value"},
+		errors.New("not a parameter error"),
+	}))
+}
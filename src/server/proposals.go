@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// Users without a GitHub account can propose new filter templates, that admins triage in the admin pages.
+// Status changes are notified to the author through the news page, as we do not store email addresses.
+const (
+	proposalsPerDay        = 3
+	proposalsPeriod        = 24 * time.Hour
+	proposalsPageSize      = 100
+	auditProposalPrefix    = "proposal_"
+	proposalMinName        = 3
+	proposalMaxName        = 64
+	proposalMinSite        = 4
+	proposalMaxSite        = 253
+	proposalMinDescription = 30
+	proposalMaxDescription = 2000
+	proposalMinRules       = 10
+	proposalMaxRules       = 5000
+)
+
+var validProposalName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// proposalTransitions lists the statuses a proposal can be moved to, adopted proposals are final
+var proposalTransitions = map[db.ProposalStatus][]db.ProposalStatus{
+	db.ProposalStatusNew:      {db.ProposalStatusTriaged, db.ProposalStatusRejected, db.ProposalStatusAdopted},
+	db.ProposalStatusTriaged:  {db.ProposalStatusRejected, db.ProposalStatusAdopted},
+	db.ProposalStatusRejected: {db.ProposalStatusTriaged},
+}
+
+// proposalInfo holds the proposal information displayed in the user and admin pages
+type proposalInfo struct {
+	ID           int32
+	UserID       string
+	TemplateName string
+	TargetSite   string
+	Description  string
+	ExampleRules string
+	Status       string
+	CreatedAt    string
+	ChangedAt    string
+	Next         []db.ProposalStatus
+}
+
+func buildProposalInfo(p db.FilterProposal) proposalInfo {
+	info := proposalInfo{
+		ID:           p.ID,
+		UserID:       p.UserID,
+		TemplateName: p.TemplateName,
+		TargetSite:   p.TargetSite,
+		Description:  p.Description,
+		ExampleRules: p.ExampleRules,
+		Status:       string(p.Status),
+		CreatedAt:    p.CreatedAt.Format(time.RFC3339),
+		Next:         proposalTransitions[p.Status],
+	}
+	if p.StatusChangedAt.Valid {
+		info.ChangedAt = p.StatusChangedAt.Time.Format(time.RFC3339)
+	}
+	return info
+}
+
+func canMoveProposal(from, to db.ProposalStatus) bool {
+	for _, allowed := range proposalTransitions[from] {
+		if to == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// checkProposalField returns a 400 error if the value length is out of bounds
+func checkProposalField(name, value string, min, max int) error {
+	if length := utf8.RuneCountInString(value); length < min || length > max {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			name+" must be between "+strconv.Itoa(min)+" and "+strconv.Itoa(max)+" characters")
+	}
+	return nil
+}
+
+// parseProposal validates the proposal form, returning a 400 error on invalid values
+func parseProposal(c echo.Context) (db.CreateProposalParams, error) {
+	var params db.CreateProposalParams
+	formParams, err := c.FormParams()
+	if err != nil {
+		return params, err
+	}
+	params.TemplateName = strings.TrimSpace(formParams.Get("template_name"))
+	params.TargetSite = strings.TrimSpace(formParams.Get("target_site"))
+	params.Description = strings.TrimSpace(formParams.Get("description"))
+	params.ExampleRules = strings.TrimSpace(formParams.Get("example_rules"))
+
+	if err = checkProposalField("the template name", params.TemplateName, proposalMinName, proposalMaxName); err != nil {
+		return params, err
+	}
+	if !validProposalName.MatchString(params.TemplateName) {
+		return params, echo.NewHTTPError(http.StatusBadRequest,
+			"the template name can only hold lowercase letters, digits and dashes")
+	}
+	if err = checkProposalField("the target site", params.TargetSite, proposalMinSite, proposalMaxSite); err != nil {
+		return params, err
+	}
+	if strings.ContainsAny(params.TargetSite, " \t\r\n") {
+		return params, echo.NewHTTPError(http.StatusBadRequest, "the target site cannot hold spaces")
+	}
+	if err = checkProposalField("the description", params.Description, proposalMinDescription, proposalMaxDescription); err != nil {
+		return params, err
+	}
+	if err = checkProposalField("the example rules", params.ExampleRules, proposalMinRules, proposalMaxRules); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+// filterProposals shows the proposal form, and the proposals the user already sent
+func (s *Server) filterProposals(c echo.Context) error {
+	hc := s.buildPageContext(c, "Propose a filter template")
+	if hc.UserLoggedIn && !hc.UserIsEphemeral {
+		stored, err := s.store.GetProposalsForUser(c.Request().Context(), hc.UserID)
+		if err != nil {
+			return err
+		}
+		if len(stored) > 0 {
+			proposals := make([]proposalInfo, 0, len(stored))
+			for _, p := range stored {
+				proposals = append(proposals, buildProposalInfo(p))
+			}
+			hc.Add("proposals", proposals)
+		}
+	}
+	return s.pages.Render(c, "filter-proposals", hc)
+}
+
+// submitProposal stores a template proposal, at most proposalsPerDay times a day per user
+func (s *Server) submitProposal(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	params, err := parseProposal(c)
+	if err != nil {
+		return err
+	}
+	params.UserID = user
+
+	recent, err := s.store.CountRecentProposals(c.Request().Context(), db.CountRecentProposalsParams{
+		UserID:    user,
+		CreatedAt: s.now().Add(-proposalsPeriod),
+	})
+	if err != nil {
+		return err
+	}
+	if recent >= proposalsPerDay {
+		return echo.NewHTTPError(http.StatusTooManyRequests,
+			"you can send up to "+strconv.Itoa(proposalsPerDay)+" proposals a day, please try again tomorrow")
+	}
+	if err = s.store.CreateProposal(c.Request().Context(), params); err != nil {
+		return err
+	}
+	_ = s.statsd.Incr("letsblockit.filter_proposal", nil, 1)
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("filter-proposals"))
+}
+
+// countProposalUpdates returns the number of the user's proposals whose status changed after the news cursor
+func (s *Server) countProposalUpdates(c echo.Context, user string, cursor time.Time) int {
+	count, err := s.store.CountProposalUpdates(c.Request().Context(), db.CountProposalUpdatesParams{
+		UserID:          user,
+		StatusChangedAt: sql.NullTime{Time: cursor, Valid: true},
+	})
+	if err != nil {
+		c.Logger().Warnf("cannot count proposal updates for %s: %s", user, err)
+		return 0
+	}
+	return int(count)
+}
+
+// getProposalUpdates returns the user's proposals whose status changed after the news cursor
+func getProposalUpdates(ctx context.Context, q db.Querier, user string, cursor time.Time) ([]proposalInfo, time.Time, error) {
+	stored, err := q.GetProposalsForUser(ctx, user)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var updates []proposalInfo
+	var latest time.Time
+	for _, p := range stored {
+		if !p.StatusChangedAt.Valid || !p.StatusChangedAt.Time.After(cursor) {
+			continue
+		}
+		updates = append(updates, buildProposalInfo(p))
+		if p.StatusChangedAt.Time.After(latest) {
+			latest = p.StatusChangedAt.Time
+		}
+	}
+	return updates, latest, nil
+}
+
+func (s *Server) adminProposals(c echo.Context) error {
+	stored, err := s.store.GetProposals(c.Request().Context(), proposalsPageSize)
+	if err != nil {
+		return err
+	}
+	proposals := make([]proposalInfo, 0, len(stored))
+	for _, p := range stored {
+		proposals = append(proposals, buildProposalInfo(p))
+	}
+
+	hc := s.buildPageContext(c, "Filter proposals")
+	hc.NoBoost = true
+	hc.Add("proposals", proposals)
+	return s.pages.Render(c, "admin-proposals", hc)
+}
+
+// adminSetProposalStatus moves a proposal to a new status, notifying its author on their next visit
+func (s *Server) adminSetProposalStatus(c echo.Context) error {
+	formParams, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+	id, err := strconv.ParseInt(formParams.Get("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid arguments")
+	}
+	status := db.ProposalStatus(formParams.Get("status"))
+
+	if err = s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		current, err := q.GetProposalStatus(ctx, int32(id))
+		switch {
+		case err == db.NotFound:
+			return echo.ErrNotFound
+		case err != nil:
+			return err
+		case !canMoveProposal(current, status):
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid status transition")
+		}
+		if err = q.UpdateProposalStatus(ctx, db.UpdateProposalStatusParams{
+			ID:              int32(id),
+			Status:          status,
+			StatusChangedAt: sql.NullTime{Time: s.now(), Valid: true},
+		}); err != nil {
+			return err
+		}
+		return q.LogAdminAction(ctx, db.LogAdminActionParams{
+			AdminID:  auth.GetRealUserId(c),
+			Action:   auditProposalPrefix + string(status),
+			TargetID: strconv.FormatInt(id, 10),
+		})
+	}); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("admin-proposals"))
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/news"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var validProposal = url.Values{
+	"template_name": {"example-cleanup"},
+	"target_site":   {"www.example.com"},
+	"description":   {"Hide the sidebar and the related articles"},
+	"example_rules": {"www.example.com##.sidebar"},
+}
+
+func proposalForm(changes map[string]string) url.Values {
+	f := make(url.Values)
+	for k, v := range validProposal {
+		f[k] = v
+	}
+	for k, v := range changes {
+		f.Set(k, v)
+	}
+	return f
+}
+
+func TestParseProposal(t *testing.T) {
+	parse := func(f url.Values) (db.CreateProposalParams, error) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		return parseProposal(echo.New().NewContext(req, httptest.NewRecorder()))
+	}
+
+	params, err := parse(proposalForm(map[string]string{"target_site": " www.example.com\n"}))
+	require.NoError(t, err)
+	assert.Equal(t, db.CreateProposalParams{
+		TemplateName: "example-cleanup",
+		TargetSite:   "www.example.com",
+		Description:  "Hide the sidebar and the related articles",
+		ExampleRules: "www.example.com##.sidebar",
+	}, params)
+
+	for name, changes := range map[string]map[string]string{
+		"short name":        {"template_name": "ab"},
+		"invalid name":      {"template_name": "Example Cleanup"},
+		"trailing dash":     {"template_name": "example-"},
+		"missing site":      {"target_site": ""},
+		"site with spaces":  {"target_site": "www.example.com and more"},
+		"short description": {"description": "Hide the sidebar"},
+		"long description":  {"description": strings.Repeat("a", proposalMaxDescription+1)},
+		"short rules":       {"example_rules": "##.ad"},
+	} {
+		_, err = parse(proposalForm(changes))
+		if assert.Error(t, err, name) {
+			assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code, name)
+		}
+	}
+}
+
+func TestCanMoveProposal(t *testing.T) {
+	assert.True(t, canMoveProposal(db.ProposalStatusNew, db.ProposalStatusTriaged))
+	assert.True(t, canMoveProposal(db.ProposalStatusRejected, db.ProposalStatusTriaged))
+	assert.False(t, canMoveProposal(db.ProposalStatusTriaged, db.ProposalStatusNew))
+	assert.False(t, canMoveProposal(db.ProposalStatusNew, db.ProposalStatusNew))
+	assert.False(t, canMoveProposal(db.ProposalStatusAdopted, db.ProposalStatusRejected), "adopted proposals are final")
+	assert.False(t, canMoveProposal(db.ProposalStatusNew, "unknown"))
+}
+
+func (s *ServerTestSuite) submitProposal(f url.Values) *http.Request {
+	f.Set(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/filters/proposals", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) TestSubmitProposal_RateLimited() {
+	for i := 0; i < proposalsPerDay; i++ {
+		s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters/proposals")
+		s.runRequest(s.submitProposal(proposalForm(nil)), assertOk)
+	}
+	s.runRequest(s.submitProposal(proposalForm(nil)), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	})
+
+	proposals, err := s.store.GetProposalsForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), proposals, proposalsPerDay)
+}
+
+func (s *ServerTestSuite) TestSubmitProposal_Anonymous() {
+	s.user = ""
+	s.runRequest(s.submitProposal(proposalForm(nil)), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestFilterProposals_List() {
+	require.NoError(s.T(), s.store.CreateProposal(context.Background(), db.CreateProposalParams{
+		UserID:       s.user,
+		TemplateName: "example-cleanup",
+		TargetSite:   "www.example.com",
+		Description:  "Hide the sidebar and the related articles",
+		ExampleRules: "www.example.com##.sidebar",
+	}))
+	stored, err := s.store.GetProposalsForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), stored, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/filters/proposals", nil)
+	s.expectRender("filter-proposals", pages.ContextData{
+		"proposals": []proposalInfo{buildProposalInfo(stored[0])},
+	})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestAdminSetProposalStatus() {
+	s.setUserAdmin()
+	require.NoError(s.T(), s.store.CreateProposal(context.Background(), db.CreateProposalParams{
+		UserID:       s.user,
+		TemplateName: "example-cleanup",
+		TargetSite:   "www.example.com",
+		Description:  "Hide the sidebar and the related articles",
+		ExampleRules: "www.example.com##.sidebar",
+	}))
+	stored, err := s.store.GetProposals(context.Background(), 10)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), stored, 1)
+	assert.Equal(s.T(), db.ProposalStatusNew, stored[0].Status)
+	id := strconv.Itoa(int(stored[0].ID))
+
+	setStatus := func(status string) *http.Request {
+		f := make(url.Values)
+		f.Add("id", id)
+		f.Add("status", status)
+		f.Add(csrfLookup, s.csrf)
+		req := httptest.NewRequest(http.MethodPost, "/admin/proposals/status", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		return req
+	}
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/admin/proposals")
+	s.runRequest(setStatus("adopted"), assertOk)
+	s.runRequest(setStatus("rejected"), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	stored, err = s.store.GetProposals(context.Background(), 10)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), db.ProposalStatusAdopted, stored[0].Status)
+	assert.True(s.T(), stored[0].StatusChangedAt.Valid && fixedNow.Equal(stored[0].StatusChangedAt.Time))
+
+	actions, err := s.store.GetAdminActions(context.Background(), 10)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), actions, 1)
+	assert.Equal(s.T(), "proposal_adopted", actions[0].Action)
+	assert.Equal(s.T(), id, actions[0].TargetID)
+}
+
+func (s *ServerTestSuite) TestAdminSetProposalStatus_NotFound() {
+	s.setUserAdmin()
+	f := make(url.Values)
+	f.Add("id", "123456")
+	f.Add("status", "triaged")
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/admin/proposals/status", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestNews_ProposalUpdates() {
+	require.NoError(s.T(), s.store.CreateProposal(context.Background(), db.CreateProposalParams{
+		UserID:       s.user,
+		TemplateName: "example-cleanup",
+		TargetSite:   "www.example.com",
+		Description:  "Hide the sidebar and the related articles",
+		ExampleRules: "www.example.com##.sidebar",
+	}))
+	stored, err := s.store.GetProposalsForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.store.UpdateProposalStatus(context.Background(), db.UpdateProposalStatusParams{
+		ID:              stored[0].ID,
+		Status:          db.ProposalStatusTriaged,
+		StatusChangedAt: sql.NullTime{Time: fixedNow.Add(time.Hour), Valid: true},
+	}))
+	stored, err = s.store.GetProposalsForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	s.Equal(1, s.server.countProposalUpdates(s.c, s.user, fixedNow))
+
+	req := httptest.NewRequest(http.MethodGet, "/news", nil)
+	s.expectRender("news", pages.ContextData{
+		"releases":        []*news.Release(nil),
+		"newReleases":     map[string]bool{},
+		"proposalUpdates": []proposalInfo{buildProposalInfo(stored[0])},
+	})
+	s.runRequest(req, assertOk)
+
+	// The cursor is set to the status change, the update is not notified anymore
+	pref, err := s.server.preferences.Get(s.c, s.user)
+	require.NoError(s.T(), err)
+	require.True(s.T(), fixedNow.Add(time.Hour).Equal(pref.NewsCursor))
+	s.Equal(0, s.server.countProposalUpdates(s.c, s.user, pref.NewsCursor))
+}
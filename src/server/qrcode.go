@@ -0,0 +1,66 @@
+package server
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	qrCodeDefaultSize = 256
+	qrCodeMinSize     = 64
+	qrCodeMaxSize     = 1024
+	qrCodeMaxAge      = 24 * time.Hour
+)
+
+// listQRCode returns a PNG QR code of the list URL, to install it on mobile browsers.
+// Like exportList, it is only served to the owner of the list.
+func (s *Server) listQRCode(c echo.Context) error {
+	size := qrCodeDefaultSize
+	if param := c.QueryParam("size"); param != "" {
+		var err error
+		size, err = strconv.Atoi(param)
+		if err != nil || size < qrCodeMinSize || size > qrCodeMaxSize {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"size must be between "+strconv.Itoa(qrCodeMinSize)+" and "+strconv.Itoa(qrCodeMaxSize)+" pixels")
+		}
+	}
+
+	token, err := uuid.Parse(c.Param("token"))
+	if err != nil {
+		return echo.ErrNotFound
+	}
+	list, err := s.store.GetListForToken(c.Request().Context(), token)
+	switch {
+	case err == db.NotFound:
+		return echo.ErrNotFound
+	case err != nil:
+		return err
+	case auth.GetUserId(c) != list.UserID:
+		return echo.ErrForbidden
+	}
+
+	// The etag changes with the token, size and list URL, as the download domain can change
+	listUrl := s.listURL(c, token)
+	hasher := fnv.New64()
+	_, _ = hasher.Write([]byte(listUrl + "@" + strconv.Itoa(size)))
+	etag := strconv.FormatUint(hasher.Sum64(), 36)
+	c.Response().Header().Set(echo.HeaderCacheControl, "private, max-age="+strconv.Itoa(int(qrCodeMaxAge.Seconds())))
+	c.Response().Header().Set("Etag", strongETag(etag))
+	if getRequestETags(c).match(etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	image, err := qrcode.Encode(listUrl, qrcode.Medium, size)
+	if err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, "image/png", image)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListQRCode_InvalidSize(t *testing.T) {
+	s := &Server{}
+	for _, size := range []string{"12", "2048", "big"} {
+		req := httptest.NewRequest(http.MethodGet, "/user/list/token/qr.png?size="+size, nil)
+		err := s.listQRCode(echo.New().NewContext(req, httptest.NewRecorder()))
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	}
+}
+
+func (s *ServerTestSuite) TestListQRCode_OK() {
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	s.Require().NoError(err)
+
+	var etag string
+	req := httptest.NewRequest(http.MethodGet, "/user/list/"+token.String()+"/qr.png?size=128", nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.Equal(t, "image/png", rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "private, max-age=86400", rec.Header().Get(echo.HeaderCacheControl))
+		assert.Equal(t, "noindex, nofollow", rec.Header().Get(robotsTag))
+		image, err := png.Decode(bytes.NewReader(rec.Body.Bytes()))
+		require.NoError(t, err)
+		assert.Equal(t, 128, image.Bounds().Dx())
+		etag = rec.Header().Get("Etag")
+	})
+	s.Require().Regexp(`^"[^"]+"$`, etag)
+
+	req = httptest.NewRequest(http.MethodGet, "/user/list/"+token.String()+"/qr.png?size=128", nil)
+	req.Header.Set("If-None-Match", etag)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+	})
+
+	req = httptest.NewRequest(http.MethodGet, "/user/list/"+token.String()+"/qr.png", nil)
+	req.Header.Set("If-None-Match", etag)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.NotEqual(t, etag, rec.Header().Get("Etag"), "the etag depends on the size")
+	})
+}
+
+func (s *ServerTestSuite) TestListQRCode_NotOwner() {
+	token, err := s.store.CreateListForUser(context.Background(), uuid.NewString())
+	s.Require().NoError(err)
+	req := httptest.NewRequest(http.MethodGet, "/user/list/"+token.String()+"/qr.png", nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	for _, token := range []string{uuid.NewString(), "invalid"} {
+		req = httptest.NewRequest(http.MethodGet, "/user/list/"+token+"/qr.png", nil)
+		s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/letsblockit/letsblockit/data"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/metrics"
+)
+
+// reloadableOptions are applied on reload, changes to the other options are logged as requiring a restart
+var reloadableOptions = map[string]bool{
+	"PublicHostname":      true,
+	"ListDownloadDomain":  true,
+	"CrawlerUserAgents":   true,
+	"ListGuessLimit":      true,
+	"ListGuessWindow":     true,
+	"GzipLevel":           true,
+	"BrotliLevel":         true,
+	"RenderTimeout":       true,
+	"SlowRenderThreshold": true,
+	"RenderSampleRate":    true,
+	"SlowTemplateCount":   true,
+	"TokenScanWebhook":    true,
+	"MaintenanceRetry":    true,
+	"LogLevel":            true,
+	"BannedListFile":      true,
+	"TemplatesDir":        true,
+	"StatsdSampleRates":   true,
+}
+
+// liveConfig is the state swapped as a whole on reload. It must not be modified once stored:
+// handlers read it once with config, and use the same snapshot until they return.
+type liveConfig struct {
+	options       *Options
+	filters       *filters.Repository
+	templatesHash string // Hash of the template sources, to only reload them when they changed
+	filterHash    string // Base of the list etags, also changed by the options altering the lists
+	bannedList    string
+	crawlers      []string
+	listGuesses   *guessLimiter
+}
+
+// config returns the live config, or builds it from the startup state for servers that are not started
+func (s *Server) config() *liveConfig {
+	if c := s.live.Load(); c != nil {
+		return c
+	}
+	c := &liveConfig{
+		options:       s.options,
+		filters:       s.filters,
+		templatesHash: s.filterHash,
+		filterHash:    s.filterHash,
+		bannedList:    s.bannedList,
+		listGuesses:   s.listGuesses,
+	}
+	if s.options != nil {
+		c.crawlers = crawlerPatterns(s.options.CrawlerUserAgents)
+	}
+	return c
+}
+
+// SetOptionsLoader sets the function re-reading the options on reload, the startup options are kept if unset
+func (s *Server) SetOptionsLoader(load func() (*Options, error)) {
+	s.loadOptions = load
+}
+
+// reload re-reads the options, the banned list file and the templates, then swaps the live config.
+// The current config is kept if any of them is invalid.
+func (s *Server) reload() error {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+	current := s.config()
+	options := current.options
+	if s.loadOptions != nil {
+		loaded, err := s.loadOptions()
+		if err != nil {
+			return fmt.Errorf("cannot load options: %w", err)
+		}
+		options = mergeReloadable(s.echo.Logger, current.options, loaded)
+	}
+	if err := checkCompressionLevels(options); err != nil {
+		return err
+	}
+	sampleRates, err := metrics.ParseSampleRates(options.StatsdSampleRates)
+	if err != nil {
+		return err
+	}
+
+	next := &liveConfig{
+		options:     options,
+		filters:     current.filters,
+		crawlers:    crawlerPatterns(options.CrawlerUserAgents),
+		listGuesses: current.listGuesses,
+	}
+	if next.bannedList, err = readBannedList(options.BannedListFile); err != nil {
+		return err
+	}
+	if next.templatesHash, err = hashTemplates(options.TemplatesDir); err != nil {
+		return err
+	}
+	if next.templatesHash != current.templatesHash {
+		if next.filters, err = loadTemplates(options.TemplatesDir); err != nil {
+			return err
+		}
+		s.echo.Logger.Infof("reload: templates changed, %d templates loaded", len(next.filters.GetAll()))
+	}
+	next.filterHash = next.templatesHash
+	if options.PublicHostname != s.options.PublicHostname {
+		// The hostname is rendered in the lists, clients must download them again
+		next.filterHash = salted(next.templatesHash, options.PublicHostname)
+	}
+	if options.ListGuessLimit != current.options.ListGuessLimit || options.ListGuessWindow != current.options.ListGuessWindow {
+		next.listGuesses = newGuessLimiter(options.ListGuessLimit, options.ListGuessWindow, func() time.Time { return s.now() })
+	}
+
+	setLogLevel(s.echo.Logger, options.LogLevel)
+	if s.sampler != nil {
+		s.sampler.SetRates(sampleRates)
+	}
+	s.live.Store(next)
+	if next.filters != current.filters {
+		s.checkTemplates()
+		if s.store != nil {
+			if err = s.loadTemplateFeed(context.Background(), next.filters); err != nil {
+				s.echo.Logger.Error(err)
+			}
+			if err = s.loadSitemap(context.Background(), next.filters); err != nil {
+				s.echo.Logger.Error(err)
+			}
+		}
+	}
+	_ = s.statsd.Incr("letsblockit.config_reload", nil, 1)
+	return nil
+}
+
+// mergeReloadable returns a copy of the current options, with the reloadable options taken from loaded
+func mergeReloadable(logger echo.Logger, current, loaded *Options) *Options {
+	merged := *current
+	target := reflect.ValueOf(&merged).Elem()
+	source := reflect.ValueOf(loaded).Elem()
+	for i := 0; i < target.NumField(); i++ {
+		name := target.Type().Field(i).Name
+		if reflect.DeepEqual(target.Field(i).Interface(), source.Field(i).Interface()) {
+			continue
+		}
+		if reloadableOptions[name] {
+			logger.Infof("reload: option %s changed", name)
+			target.Field(i).Set(source.Field(i))
+		} else {
+			logger.Warnf("reload: option %s changed, restart the server to apply it", name)
+		}
+	}
+	return &merged
+}
+
+// reloadOnSignal reloads the config on every signal received, until ctx is done
+func (s *Server) reloadOnSignal(ctx context.Context, signals <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if err := s.reload(); err != nil {
+				s.echo.Logger.Errorf("cannot reload the config: %s", err)
+				_ = s.statsd.Incr("letsblockit.config_reload_error", nil, 1)
+			}
+		}
+	}
+}
+
+func (s *Server) adminReload(c echo.Context) error {
+	if err := s.reload(); err != nil {
+		_ = s.statsd.Incr("letsblockit.config_reload_error", nil, 1)
+		return echo.NewHTTPError(http.StatusBadRequest, "cannot reload the config: "+err.Error())
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("admin-templates"))
+}
+
+// loadTemplates loads the embedded templates, then the ones in dir if set
+func loadTemplates(dir string) (*filters.Repository, error) {
+	if dir == "" {
+		return filters.Load(data.Templates, data.Presets)
+	}
+	return filters.LoadSources(
+		filters.Source{Name: "embedded", Templates: data.Templates, Presets: data.Presets},
+		filters.DirSource(dir),
+	)
+}
+
+// hashTemplates hashes the embedded templates, then the ones in dir and its sibling presets folder if set
+func hashTemplates(dir string) (string, error) {
+	sources := []fs.FS{data.Templates, data.Presets}
+	if dir != "" {
+		sources = append(sources, os.DirFS(dir))
+		presets := filepath.Join(dir, "..", "presets")
+		if info, err := os.Stat(presets); err == nil && info.IsDir() {
+			sources = append(sources, os.DirFS(presets))
+		}
+	}
+	return data.HashFiles(sources...)
+}
+
+// readBannedList returns the contents of the banned list file, or an empty string if unset
+func readBannedList(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read banned list file: %w", err)
+	}
+	return string(contents), nil
+}
+
+// crawlerPatterns lowercases the crawler user agents, skipping the empty ones
+func crawlerPatterns(userAgents []string) []string {
+	patterns := make([]string, 0, len(userAgents))
+	for _, p := range userAgents {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+func salted(hash, value string) string {
+	hasher := fnv.New32()
+	_, _ = hasher.Write([]byte(value))
+	return hash + strconv.FormatUint(uint64(hasher.Sum32()), 36)
+}
+
+func setLogLevel(logger echo.Logger, level string) {
+	switch level {
+	case "debug":
+		logger.SetLevel(log.DEBUG)
+	case "info":
+		logger.SetLevel(log.INFO)
+	case "warn":
+		logger.SetLevel(log.WARN)
+	case "error":
+		logger.SetLevel(log.ERROR)
+	case "off":
+		logger.SetLevel(log.OFF)
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/letsblockit/letsblockit/src/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReloadTestServer returns a server with its live config set, reloading the options from the returned pointer
+func newReloadTestServer(t *testing.T, options Options) (*Server, *Options, *strings.Builder) {
+	logs := &strings.Builder{}
+	s := &Server{echo: echo.New(), statsd: &statsd.NoOpClient{}, now: time.Now}
+	s.echo.Logger.SetOutput(logs)
+	s.echo.Logger.SetLevel(log.INFO)
+	s.options = &options
+	s.listGuesses = newGuessLimiter(options.ListGuessLimit, options.ListGuessWindow, time.Now)
+	var err error
+	s.filters, err = loadTemplates(options.TemplatesDir)
+	require.NoError(t, err)
+	s.filterHash, err = hashTemplates(options.TemplatesDir)
+	require.NoError(t, err)
+	s.live.Store(s.config())
+
+	loaded := options
+	s.SetOptionsLoader(func() (*Options, error) {
+		reloaded := loaded
+		return &reloaded, nil
+	})
+	return s, &loaded, logs
+}
+
+func TestReload_Options(t *testing.T) {
+	s, loaded, logs := newReloadTestServer(t, Options{
+		CrawlerUserAgents: []string{"Googlebot"},
+		DatabaseUrl:       "postgresql:///letsblockit",
+		LogLevel:          "info",
+	})
+	s.echo.GET("/list/:token", func(c echo.Context) error {
+		return c.String(http.StatusOK, s.publicHost(c))
+	}, s.rejectCrawlers)
+	download := func(userAgent string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/list/token", nil)
+		req.Header.Set("User-Agent", userAgent)
+		req.Host = "localhost"
+		rec := httptest.NewRecorder()
+		s.echo.ServeHTTP(rec, req)
+		return rec
+	}
+	assert.Equal(t, http.StatusForbidden, download("Googlebot/2.1").Code)
+	assert.Equal(t, "localhost", download("uBlock Origin").Body.String())
+	etag := s.config().filterHash
+
+	loaded.CrawlerUserAgents = []string{"bingbot"}
+	loaded.PublicHostname = "lists.example.com"
+	loaded.DatabaseUrl = "postgresql://other/letsblockit"
+	require.NoError(t, s.reload())
+
+	assert.Equal(t, http.StatusOK, download("Googlebot/2.1").Code)
+	assert.Equal(t, http.StatusForbidden, download("bingbot/2.0").Code)
+	assert.Equal(t, "lists.example.com", download("uBlock Origin").Body.String())
+	assert.NotEqual(t, etag, s.config().filterHash, "lists are rendered with the public hostname")
+	assert.Contains(t, logs.String(), "option PublicHostname changed")
+	assert.Contains(t, logs.String(), "option DatabaseUrl changed, restart the server to apply it")
+	assert.Equal(t, "postgresql:///letsblockit", s.config().options.DatabaseUrl)
+	assert.Equal(t, "postgresql:///letsblockit", s.options.DatabaseUrl, "startup options are not modified")
+
+	loaded.PublicHostname = ""
+	require.NoError(t, s.reload())
+	assert.Equal(t, etag, s.config().filterHash)
+}
+
+func TestReload_KeepsConfigOnError(t *testing.T) {
+	s, loaded, _ := newReloadTestServer(t, Options{GzipLevel: 6, ListGuessLimit: 2, ListGuessWindow: time.Minute})
+	current := s.config()
+
+	loaded.GzipLevel = 12
+	assert.ErrorContains(t, s.reload(), "invalid gzip level")
+	loaded.GzipLevel = 6
+	loaded.BannedListFile = filepath.Join(t.TempDir(), "missing.txt")
+	assert.ErrorContains(t, s.reload(), "cannot read banned list file")
+	s.SetOptionsLoader(func() (*Options, error) {
+		return nil, errors.New("invalid flag")
+	})
+	assert.ErrorContains(t, s.reload(), "invalid flag")
+	assert.Same(t, current, s.config())
+}
+
+func TestReload_SampleRates(t *testing.T) {
+	s, loaded, logs := newReloadTestServer(t, Options{StatsdSampleRates: []string{"download=0.1"}})
+	recorder := &rateRecorder{}
+	s.sampler = metrics.NewSampler(recorder, metrics.SampleRates{metrics.DownloadClass: 0.1})
+	s.statsd = s.sampler
+
+	_ = s.statsd.Incr("letsblockit.list_download", nil, 1)
+	assert.Equal(t, 0.1, recorder.rate)
+
+	loaded.StatsdSampleRates = []string{"download=0.5"}
+	require.NoError(t, s.reload())
+	assert.Contains(t, logs.String(), "option StatsdSampleRates changed")
+	_ = s.statsd.Incr("letsblockit.list_download", nil, 1)
+	assert.Equal(t, 0.5, recorder.rate)
+
+	loaded.StatsdSampleRates = []string{"download=2"}
+	assert.ErrorContains(t, s.reload(), "invalid sample rate")
+	_ = s.statsd.Incr("letsblockit.list_download", nil, 1)
+	assert.Equal(t, 0.5, recorder.rate, "rates are kept on error")
+}
+
+// rateRecorder records the rate of the last counter
+type rateRecorder struct {
+	statsd.NoOpClient
+	rate float64
+}
+
+func (r *rateRecorder) Incr(_ string, _ []string, rate float64) error {
+	r.rate = rate
+	return nil
+}
+
+func TestReload_BannedListAndGuesses(t *testing.T) {
+	s, loaded, _ := newReloadTestServer(t, Options{ListGuessLimit: 1, ListGuessWindow: time.Minute})
+	assert.Equal(t, defaultBannedListBody, s.bannedListBody())
+	assert.False(t, s.config().listGuesses.Record("1.2.3.4"))
+	assert.True(t, s.config().listGuesses.Record("1.2.3.4"))
+
+	loaded.BannedListFile = filepath.Join(t.TempDir(), "banned.txt")
+	require.NoError(t, os.WriteFile(loaded.BannedListFile, []byte("! banned\n"), 0600))
+	require.NoError(t, s.reload())
+	assert.Equal(t, "! banned\n", s.bannedListBody())
+	assert.True(t, s.config().listGuesses.Record("1.2.3.4"), "unchanged limits keep the counts")
+
+	require.NoError(t, os.WriteFile(loaded.BannedListFile, []byte("! edited\n"), 0600))
+	loaded.ListGuessLimit = 3
+	require.NoError(t, s.reload())
+	assert.Equal(t, "! edited\n", s.bannedListBody())
+	assert.False(t, s.config().listGuesses.Record("1.2.3.4"))
+
+	loaded.ListGuessLimit = 0
+	require.NoError(t, s.reload())
+	assert.Nil(t, s.config().listGuesses)
+	assert.False(t, s.config().listGuesses.Record("1.2.3.4"))
+}
+
+func TestReload_Templates(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "templates")
+	require.NoError(t, os.Mkdir(dir, 0700))
+	writeTemplate := func(contents string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "local.yaml"), []byte(contents), 0600))
+	}
+	writeTemplate("title: Local\ntemplate: first##rule\n---\nLocal template\n")
+	s, _, _ := newReloadTestServer(t, Options{TemplatesDir: dir})
+	initial := s.config()
+	_, err := initial.filters.Get("local")
+	require.NoError(t, err)
+
+	require.NoError(t, s.reload())
+	assert.Same(t, initial.filters, s.config().filters, "unchanged templates are not reloaded")
+
+	writeTemplate("title: Local\ntemplate: second##rule\n---\nLocal template\n")
+	require.NoError(t, s.reload())
+	reloaded := s.config()
+	assert.NotSame(t, initial.filters, reloaded.filters)
+	assert.NotEqual(t, initial.filterHash, reloaded.filterHash)
+	template, err := reloaded.filters.Get("local")
+	require.NoError(t, err)
+	assert.Equal(t, "second##rule", template.Template)
+	require.NotNil(t, s.templateCheck.Load())
+	assert.Empty(t, s.templateCheck.Load().Broken)
+
+	writeTemplate("title: Local\ntemplate: {{#each}\n---\nLocal template\n")
+	assert.Error(t, s.reload())
+	assert.Same(t, reloaded, s.config())
+}
+
+func TestMergeReloadable(t *testing.T) {
+	logs := &strings.Builder{}
+	logger := echo.New().Logger
+	logger.SetOutput(logs)
+	logger.SetLevel(log.INFO)
+	current := &Options{Address: "127.0.0.1:8765", RenderTimeout: time.Second, GzipLevel: 6}
+	merged := mergeReloadable(logger, current, &Options{Address: "0.0.0.0:80", RenderTimeout: time.Minute, GzipLevel: 6})
+	assert.Equal(t, &Options{Address: "127.0.0.1:8765", RenderTimeout: time.Minute, GzipLevel: 6}, merged)
+	assert.Equal(t, time.Second, current.RenderTimeout)
+	assert.Contains(t, logs.String(), "option Address changed, restart the server to apply it")
+	assert.Contains(t, logs.String(), "option RenderTimeout changed")
+	assert.NotContains(t, logs.String(), "GzipLevel")
+}
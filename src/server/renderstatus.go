@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+)
+
+// maxPendingRenderStatuses bounds the render outcomes kept between two writes, the next ones are dropped
+const maxPendingRenderStatuses = 20000
+
+// renderStatusTimeout bounds the last write of the render outcomes, when the server stops
+const renderStatusTimeout = 5 * time.Second
+
+type renderStatusKey struct {
+	listID   int32
+	template string
+}
+
+type renderStatus struct {
+	err string
+	at  time.Time
+}
+
+// renderStatusView is the outcome of the last render of an instance, shown to its user
+type renderStatusView struct {
+	RenderedAt string
+	Error      string
+}
+
+func newRenderStatusView(renderedAt time.Time, renderError sql.NullString) renderStatusView {
+	return renderStatusView{
+		RenderedAt: renderedAt.UTC().Format(time.RFC1123),
+		Error:      renderError.String,
+	}
+}
+
+// renderStatuses buffers the outcome of the last render of the instances, to write them in batches.
+// Instances rendered several times between two writes are only written once.
+type renderStatuses struct {
+	sync.Mutex
+	pending map[renderStatusKey]renderStatus
+}
+
+// record keeps the outcome of the instances of a rendered list, it returns the count of outcomes dropped
+// because too many are pending
+func (r *renderStatuses) record(listID int32, list *filters.List, at time.Time) int {
+	r.Lock()
+	defer r.Unlock()
+	if r.pending == nil {
+		r.pending = make(map[renderStatusKey]renderStatus)
+	}
+	dropped := 0
+	for _, instance := range list.Instances {
+		key := renderStatusKey{listID: listID, template: instance.Template}
+		if _, found := r.pending[key]; !found && len(r.pending) >= maxPendingRenderStatuses {
+			dropped++
+			continue
+		}
+		status := renderStatus{at: at}
+		if err := list.Failures[instance.Template]; err != nil {
+			status.err = err.Error()
+		}
+		r.pending[key] = status
+	}
+	return dropped
+}
+
+// take returns the pending outcomes as query parameters, and empties the buffer
+func (r *renderStatuses) take() db.RecordInstanceRendersParams {
+	r.Lock()
+	pending := r.pending
+	r.pending = nil
+	r.Unlock()
+
+	params := db.RecordInstanceRendersParams{
+		ListIds:       make([]int32, 0, len(pending)),
+		TemplateNames: make([]string, 0, len(pending)),
+		RenderErrors:  make([]string, 0, len(pending)),
+		RenderedAt:    make([]time.Time, 0, len(pending)),
+	}
+	for key, status := range pending {
+		params.ListIds = append(params.ListIds, key.listID)
+		params.TemplateNames = append(params.TemplateNames, key.template)
+		params.RenderErrors = append(params.RenderErrors, status.err)
+		params.RenderedAt = append(params.RenderedAt, status.at)
+	}
+	return params
+}
+
+// recordRenderStatus keeps the outcome of the instances of a rendered list, for writeRenderStatuses to store them
+func (s *Server) recordRenderStatus(listID int32, list *filters.List) {
+	if listID == 0 || s.config().options.RenderStatusFlush <= 0 {
+		return
+	}
+	if dropped := s.renderStatuses.record(listID, list, time.Now()); dropped > 0 {
+		_ = s.statsd.Count("letsblockit.render_status_dropped", int64(dropped), nil, 1)
+	}
+}
+
+// flushRenderStatuses writes the pending render outcomes. The outcomes of the instances updated since
+// their render are skipped, not to show a stale status for their new params. Outcomes are stored apart
+// from the instances, writing them must not show up as instance changes in the sync API.
+func (s *Server) flushRenderStatuses(ctx context.Context) error {
+	params := s.renderStatuses.take()
+	if len(params.ListIds) == 0 {
+		return nil
+	}
+	return s.store.RecordInstanceRenders(ctx, params)
+}
+
+// writeRenderStatuses writes the pending render outcomes every interval, and a last time when ctx is done
+func (s *Server) writeRenderStatuses(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), renderStatusTimeout)
+			defer cancel()
+			if err := s.flushRenderStatuses(flushCtx); err != nil {
+				s.echo.Logger.Warnf("cannot write the render status of the instances: %s", err)
+			}
+			return
+		case <-ticker.C:
+			if err := s.flushRenderStatuses(ctx); err != nil {
+				s.echo.Logger.Warnf("cannot write the render status of the instances: %s", err)
+			}
+		}
+	}
+}
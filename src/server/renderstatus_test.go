@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/data"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderStatuses(t *testing.T) {
+	var statuses renderStatuses
+	assert.Empty(t, statuses.take().ListIds)
+
+	list := &filters.List{
+		Instances: []*filters.Instance{{Template: "one"}, {Template: "two"}},
+		Failures:  map[string]error{"two": errors.New("template not found")},
+	}
+	assert.Zero(t, statuses.record(1, list, fixedNow.Add(-time.Minute)))
+	assert.Zero(t, statuses.record(2, list, fixedNow))
+	list.Failures = nil
+	assert.Zero(t, statuses.record(1, list, fixedNow), "the last outcome replaces the pending one")
+
+	params := statuses.take()
+	require.Len(t, params.ListIds, 4)
+	outcomes := make(map[renderStatusKey]renderStatus)
+	for i, id := range params.ListIds {
+		outcomes[renderStatusKey{listID: id, template: params.TemplateNames[i]}] = renderStatus{
+			err: params.RenderErrors[i],
+			at:  params.RenderedAt[i],
+		}
+	}
+	assert.Equal(t, map[renderStatusKey]renderStatus{
+		{listID: 1, template: "one"}: {at: fixedNow},
+		{listID: 1, template: "two"}: {at: fixedNow},
+		{listID: 2, template: "one"}: {at: fixedNow},
+		{listID: 2, template: "two"}: {at: fixedNow, err: "template not found"},
+	}, outcomes)
+	assert.Empty(t, statuses.take().ListIds, "the buffer is emptied")
+}
+
+func TestRenderStatuses_Bounded(t *testing.T) {
+	var statuses renderStatuses
+	list := &filters.List{Instances: []*filters.Instance{{Template: "one"}}}
+	for id := int32(1); id <= maxPendingRenderStatuses; id++ {
+		require.Zero(t, statuses.record(id, list, fixedNow))
+	}
+	assert.Equal(t, 1, statuses.record(maxPendingRenderStatuses+1, list, fixedNow))
+	assert.Zero(t, statuses.record(1, list, fixedNow), "pending instances are still updated")
+	assert.Len(t, statuses.take().ListIds, maxPendingRenderStatuses)
+}
+
+func TestRenderListBody_RecordsStatus(t *testing.T) {
+	repo, err := filters.Load(data.Templates, data.Presets)
+	require.NoError(t, err)
+	s := &Server{filters: repo, statsd: &statsd.NoOpClient{}, options: &Options{RenderStatusFlush: time.Minute}}
+	var params pgtype.JSONB
+	require.NoError(t, params.Set(map[string]interface{}{"rules": "example.org##.ad\n"}))
+	rows := []db.GetInstancesForListRow{
+		{TemplateName: filters.CustomRulesFilterName, Params: params},
+		{TemplateName: "removed-template"},
+	}
+	logger := echo.New().Logger
+
+	_, err = s.renderListBody(context.Background(), logger, 0, uuid.New(), filters.DefaultListTitle, rows, false)
+	require.NoError(t, err)
+	assert.Empty(t, s.renderStatuses.take().ListIds, "lists without ID are not recorded")
+
+	_, err = s.renderListBody(context.Background(), logger, 12, uuid.New(), filters.DefaultListTitle, rows, false)
+	require.NoError(t, err)
+	recorded := s.renderStatuses.take()
+	require.Len(t, recorded.TemplateNames, 2)
+	for i, template := range recorded.TemplateNames {
+		assert.EqualValues(t, 12, recorded.ListIds[i])
+		if template == filters.CustomRulesFilterName {
+			assert.Empty(t, recorded.RenderErrors[i])
+		} else {
+			assert.NotEmpty(t, recorded.RenderErrors[i])
+		}
+	}
+
+	s.options.RenderStatusFlush = 0
+	_, err = s.renderListBody(context.Background(), logger, 12, uuid.New(), filters.DefaultListTitle, rows, false)
+	require.NoError(t, err)
+	assert.Empty(t, s.renderStatuses.take().ListIds, "recording is disabled")
+}
+
+func (s *ServerTestSuite) TestRenderStatus_RecordAndClear() {
+	ctx := context.Background()
+	s.server.options.RenderStatusFlush = time.Minute
+	s.addInstance(s.user, "filter1", nil)
+	s.addInstance(s.user, "filter2", filter2Custom)
+	list, err := s.store.GetListForUser(ctx, s.user)
+	require.NoError(s.T(), err)
+	stored, err := s.store.GetListForToken(ctx, list.Token)
+	require.NoError(s.T(), err)
+	cursor, err := s.store.GetChangeCursor(ctx, s.user)
+	require.NoError(s.T(), err)
+
+	// A render started before the last update of the instances is not written
+	s.server.renderStatuses.record(stored.ID, &filters.List{Instances: []*filters.Instance{{Template: "filter1"}}},
+		time.Now().Add(-time.Hour))
+	require.NoError(s.T(), s.server.flushRenderStatuses(ctx))
+	statuses, err := s.store.GetInstanceRenderStatus(ctx, s.user)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), statuses)
+
+	s.server.renderStatuses.record(stored.ID, &filters.List{
+		Instances: []*filters.Instance{{Template: "filter1"}, {Template: "filter2"}},
+		Failures:  map[string]error{"filter2": errors.New("missing helper")},
+	}, time.Now().Add(time.Second))
+	require.NoError(s.T(), s.server.flushRenderStatuses(ctx))
+	statuses, err = s.store.GetInstanceRenderStatus(ctx, s.user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), statuses, 2)
+	newCursor, err := s.store.GetChangeCursor(ctx, s.user)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), cursor, newCursor, "render outcomes are not instance changes")
+
+	s.expectP.Render(gomock.Any(), "list-filters", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			views, ok := hc.Data["render_statuses"].(map[string]renderStatusView)
+			require.True(s.T(), ok)
+			assert.Empty(s.T(), views["filter1"].Error)
+			assert.NotEmpty(s.T(), views["filter1"].RenderedAt)
+			assert.Equal(s.T(), "missing helper", views["filter2"].Error)
+			return nil
+		})
+	s.runRequest(httptest.NewRequest(http.MethodGet, "/filters", nil), assertOk)
+
+	s.expectP.Render(gomock.Any(), "view-filter", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.Equal(s.T(), "missing helper", hc.Data["render_status"].(renderStatusView).Error)
+			return nil
+		})
+	s.runRequest(httptest.NewRequest(http.MethodGet, "/filters/filter2", nil), assertOk)
+
+	// Editing the instance clears its stale status
+	s.addInstance(s.user, "filter2", filter2Custom)
+	s.expectP.Render(gomock.Any(), "view-filter", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.NotContains(s.T(), hc.Data, "render_status")
+			return nil
+		})
+	s.runRequest(httptest.NewRequest(http.MethodGet, "/filters/filter2", nil), assertOk)
+	statuses, err = s.store.GetInstanceRenderStatus(ctx, s.user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), statuses, 1)
+	assert.Equal(s.T(), "filter1", statuses[0].TemplateName)
+
+	// Toggling the test mode through the sync API also clears it
+	updated, err := s.store.SetInstanceTestMode(ctx, db.SetInstanceTestModeParams{
+		UserID:       s.user,
+		TemplateName: "filter1",
+		TestMode:     true,
+	})
+	require.NoError(s.T(), err)
+	require.EqualValues(s.T(), 1, updated)
+	statuses, err = s.store.GetInstanceRenderStatus(ctx, s.user)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), statuses)
+}
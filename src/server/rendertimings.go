@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// renderTimingsInterval is the interval the slowest templates are exported as metrics at
+const renderTimingsInterval = time.Minute
+
+// renderTiming aggregates the sampled renders of a template in lists
+type renderTiming struct {
+	Name  string
+	Count int
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Mean returns the average render duration of the template
+func (t *renderTiming) Mean() time.Duration {
+	if t.Count == 0 {
+		return 0
+	}
+	return t.Total / time.Duration(t.Count)
+}
+
+// renderTimings aggregates the per-template durations of the sampled list renders since startup
+type renderTimings struct {
+	sync.Mutex
+	templates map[string]*renderTiming
+}
+
+// record adds a sampled render of a template
+func (r *renderTimings) record(template string, elapsed time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	if r.templates == nil {
+		r.templates = make(map[string]*renderTiming)
+	}
+	timing, found := r.templates[template]
+	if !found {
+		timing = &renderTiming{Name: template}
+		r.templates[template] = timing
+	}
+	timing.Count++
+	timing.Total += elapsed
+	if elapsed > timing.Max {
+		timing.Max = elapsed
+	}
+}
+
+// slowest returns a copy of the n templates with the highest mean render duration, slowest first
+func (r *renderTimings) slowest(n int) []renderTiming {
+	r.Lock()
+	timings := make([]renderTiming, 0, len(r.templates))
+	for _, t := range r.templates {
+		timings = append(timings, *t)
+	}
+	r.Unlock()
+
+	sort.Slice(timings, func(i, j int) bool {
+		if mi, mj := timings[i].Mean(), timings[j].Mean(); mi != mj {
+			return mi > mj
+		}
+		return timings[i].Name < timings[j].Name
+	})
+	if n >= 0 && len(timings) > n {
+		timings = timings[:n]
+	}
+	return timings
+}
+
+// exportRenderTimings exports the mean render duration of the slowest templates every interval, until ctx is done.
+// Only the slowest templates are exported, to limit the cardinality of the template tag.
+func (s *Server) exportRenderTimings(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, t := range s.renderTimings.slowest(s.config().options.SlowTemplateCount) {
+				_ = s.statsd.Gauge("letsblockit.slow_template_render_duration", float64(t.Mean().Nanoseconds()),
+					[]string{"template:" + t.Name}, 1)
+			}
+		}
+	}
+}
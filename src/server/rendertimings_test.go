@@ -0,0 +1,27 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderTimings(t *testing.T) {
+	var timings renderTimings
+	assert.Empty(t, timings.slowest(10))
+
+	timings.record("fast", time.Millisecond)
+	timings.record("slow", 10*time.Millisecond)
+	timings.record("slow", 20*time.Millisecond)
+	timings.record("tied", 15*time.Millisecond)
+	timings.record("other", 15*time.Millisecond)
+
+	assert.Equal(t, []renderTiming{
+		{Name: "other", Count: 1, Total: 15 * time.Millisecond, Max: 15 * time.Millisecond},
+		{Name: "slow", Count: 2, Total: 30 * time.Millisecond, Max: 20 * time.Millisecond},
+		{Name: "tied", Count: 1, Total: 15 * time.Millisecond, Max: 15 * time.Millisecond},
+	}, timings.slowest(3))
+	assert.Len(t, timings.slowest(10), 4)
+	assert.Empty(t, timings.slowest(0))
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// Users can start over with an empty list, after typing the confirmation phrase. The list token can be
+// rotated in the same transaction, for the old download URL to stop serving the removed filters.
+const (
+	resetListPhrase = "reset my list"
+	auditListReset  = "list_reset"
+)
+
+// resetList deletes all the filters of the user, and optionally rotates their list token
+func (s *Server) resetList(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	formParams, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+	if formParams.Get("confirm") != resetListPhrase {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid confirmation phrase")
+	}
+	rotate := formParams.Get("rotate") == "on"
+
+	var removed []string
+	if err = s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		instances, err := q.GetInstancesForUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		for _, i := range instances {
+			removed = append(removed, i.TemplateName)
+		}
+		if err = q.DeleteInstancesForUser(ctx, user); err != nil {
+			return err
+		}
+
+		list, err := q.GetListForUser(ctx, user)
+		switch {
+		case err == db.NotFound:
+			if _, err = q.CreateListForUser(ctx, user); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case rotate:
+			if err = q.RotateListToken(ctx, db.RotateListTokenParams{
+				UserID: user,
+				Token:  list.Token,
+			}); err != nil {
+				return err
+			}
+		}
+		return q.LogAdminAction(ctx, db.LogAdminActionParams{
+			AdminID:  user,
+			Action:   auditListReset,
+			TargetID: user,
+		})
+	}); err != nil {
+		return err
+	}
+
+	s.undo.forget(user, "")
+	s.hotLists.Invalidate(user)
+	for _, name := range removed {
+		s.notifyListChange(user, webhookInstanceDeleted, name)
+	}
+	_ = s.statsd.Incr("letsblockit.list_reset", nil, 1)
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("user-account"))
+}
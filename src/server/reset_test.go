@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *ServerTestSuite) resetListRequest(confirm string, rotate bool) *http.Request {
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	f.Add("confirm", confirm)
+	if rotate {
+		f.Add("rotate", "on")
+	}
+	req := httptest.NewRequest(http.MethodPost, "/user/reset-list", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) TestResetList_KeepToken() {
+	s.addInstance(s.user, "filter1", nil)
+	s.addInstance(s.user, "filter2", filter2Custom)
+	before, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/account")
+	s.runRequest(s.resetListRequest(resetListPhrase, false), assertOk)
+
+	s.requireInstanceCount("filter1", 0)
+	s.requireInstanceCount("filter2", 0)
+	after, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	s.Equal(before.Token, after.Token)
+	s.EqualValues(0, after.InstanceCount)
+
+	actions, err := s.store.GetAdminActions(context.Background(), auditLogPageSize)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), actions, 1)
+	s.Equal(auditListReset, actions[0].Action)
+	s.Equal(s.user, actions[0].TargetID)
+}
+
+func (s *ServerTestSuite) TestResetList_RotateToken() {
+	s.addInstance(s.user, "filter1", nil)
+	before, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/account")
+	s.runRequest(s.resetListRequest(resetListPhrase, true), assertOk)
+
+	s.requireInstanceCount("filter1", 0)
+	after, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	s.NotEqual(before.Token, after.Token)
+
+	req := httptest.NewRequest(http.MethodGet, "/list/"+before.Token.String(), nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestResetList_CreatesList() {
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/account")
+	s.runRequest(s.resetListRequest(resetListPhrase, true), assertOk)
+
+	list, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	s.EqualValues(0, list.InstanceCount)
+}
+
+func (s *ServerTestSuite) TestResetList_BadConfirmation() {
+	s.addInstance(s.user, "filter1", nil)
+	s.runRequest(s.resetListRequest("on", true), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	s.requireInstanceCount("filter1", 1)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const robotsTag = "X-Robots-Tag"
+
+// robotsTxt keeps crawlers away from the pages holding list tokens, and points them to the sitemap.
+// The list download domain only serves lists, crawlers are disallowed from it entirely.
+func (s *Server) robotsTxt(c echo.Context) error {
+	var rules strings.Builder
+	rules.WriteString("User-Agent: *\n")
+	if domain := s.config().options.ListDownloadDomain; domain != "" && stripPort(s.requestHost(c)) == domain {
+		rules.WriteString("Disallow: /\n")
+		return c.String(http.StatusOK, rules.String())
+	}
+	for _, prefix := range []string{"/list/", "/export/", "/api/", "/user/"} {
+		rules.WriteString("Disallow: " + prefix + "\n")
+	}
+	if s.options.AuthMethod == "kratos" {
+		rules.WriteString("Disallow: /.ory/\n")
+	}
+	sitemap := url.URL{Scheme: c.Scheme(), Host: s.publicHost(c), Path: "/sitemap.xml"}
+	rules.WriteString("\nSitemap: " + sitemap.String() + "\n")
+	return c.String(http.StatusOK, rules.String())
+}
+
+// noIndex asks search engines not to index the response, even if they found its URL
+func noIndex(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set(robotsTag, "noindex, nofollow")
+		return next(c)
+	}
+}
+
+// rejectCrawlers returns a 403 error to the user agents matching one of the CrawlerUserAgents,
+// that ignore the robots.txt rules and would leak list tokens in search results
+func (s *Server) rejectCrawlers(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userAgent := strings.ToLower(c.Request().UserAgent())
+		for _, p := range s.config().crawlers {
+			if strings.Contains(userAgent, p) {
+				_ = s.statsd.Incr("letsblockit.list_crawler_rejected", nil, 1)
+				return echo.NewHTTPError(http.StatusForbidden, "crawlers are not allowed to download lists")
+			}
+		}
+		return next(c)
+	}
+}
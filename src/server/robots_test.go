@@ -0,0 +1,79 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRobotsTxt(t *testing.T) {
+	s := &Server{options: &Options{
+		AuthMethod:         "kratos",
+		ListDownloadDomain: "get.letsblock.it",
+		PublicHostname:     "letsblock.it",
+	}}
+	serve := func(host string) string {
+		req := httptest.NewRequest(http.MethodGet, "/robots.txt", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		require.NoError(t, s.robotsTxt(echo.New().NewContext(req, rec)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.String()
+	}
+
+	assert.Equal(t, "User-Agent: *\nDisallow: /list/\nDisallow: /export/\nDisallow: /api/\nDisallow: /user/\n"+
+		"Disallow: /.ory/\n\nSitemap: http://letsblock.it/sitemap.xml\n", serve("letsblock.it"))
+	assert.Equal(t, "User-Agent: *\nDisallow: /\n", serve("get.letsblock.it:443"))
+
+	s.options.AuthMethod = "proxy"
+	assert.NotContains(t, serve("letsblock.it"), "/.ory/")
+	assert.NotContains(t, serve("letsblock.it"), "/filters", "the web UI stays indexable")
+}
+
+func TestRejectCrawlers(t *testing.T) {
+	registry := metrics.NewRegistry()
+	s := &Server{
+		options: &Options{CrawlerUserAgents: []string{"Googlebot", " bingbot", ""}},
+		statsd:  registry,
+	}
+	e := echo.New()
+	e.Logger.SetOutput(io.Discard)
+	e.HTTPErrorHandler = s.handleError
+	e.GET("/list/:token", func(c echo.Context) error {
+		return c.String(http.StatusOK, "! Title: Let's Block It")
+	}, noIndex, s.rejectCrawlers)
+
+	for userAgent, expected := range map[string]int{
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)": http.StatusForbidden,
+		"Mozilla/5.0 (compatible; BingBot/2.0; +http://www.bing.com/bingbot.htm)":  http.StatusForbidden,
+		"Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/110.0":   http.StatusOK,
+		"uBlock Origin": http.StatusOK,
+		"":              http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/list/token", nil)
+		req.Header.Set("User-Agent", userAgent)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, expected, rec.Code, userAgent)
+		assert.Equal(t, "noindex, nofollow", rec.Header().Get(robotsTag), "error responses are not indexed either")
+	}
+
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(), "letsblockit_list_crawler_rejected_total 2\n")
+}
+
+func TestRejectCrawlers_Disabled(t *testing.T) {
+	s := &Server{options: &Options{}}
+	req := httptest.NewRequest(http.MethodGet, "/list/token", nil)
+	req.Header.Set("User-Agent", "Googlebot/2.1")
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+	assert.NoError(t, s.rejectCrawlers(func(echo.Context) error { return nil })(c))
+}
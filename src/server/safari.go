@@ -0,0 +1,52 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/filters"
+)
+
+// Safari's content blocker format gets a best-effort translation of the rendered rules,
+// the report query parameter lists the rules that could not be translated.
+const safariLimitExceeded = `199 - "this list exceeds the %d rules limit of Safari content blockers"`
+
+// safariReport is returned instead of the rules when the report query parameter is set
+type safariReport struct {
+	Rules        int      `json:"rules"`
+	MaxRules     int      `json:"max_rules"`
+	Untranslated []string `json:"untranslated"`
+}
+
+// writeSafariList converts a list to Safari's content blocker format, with the install prompt rule
+func (s *Server) writeSafariList(c echo.Context, token uuid.UUID, body []byte) error {
+	if body == nil {
+		return c.JSONBlob(http.StatusOK, []byte("[]"))
+	}
+	host := mainDomain
+	if !s.options.OfficialInstance {
+		host = stripPort(s.publicHost(c))
+	}
+	body = append(body, fmt.Sprintf(installPromptFilterTemplate, host, token)...)
+	conversion := filters.ConvertToSafari(body)
+
+	c.Response().Header().Set(untranslatedHeader, strconv.Itoa(len(conversion.Untranslated)))
+	if len(conversion.Rules) > filters.SafariMaxRules {
+		c.Response().Header().Set("Warning", fmt.Sprintf(safariLimitExceeded, filters.SafariMaxRules))
+	}
+	if _, report := c.QueryParams()["report"]; report {
+		untranslated := conversion.Untranslated
+		if untranslated == nil {
+			untranslated = []string{}
+		}
+		return c.JSON(http.StatusOK, &safariReport{
+			Rules:        len(conversion.Rules),
+			MaxRules:     filters.SafariMaxRules,
+			Untranslated: untranslated,
+		})
+	}
+	return c.JSON(http.StatusOK, conversion.Rules)
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// securityEvent is the type of the security-relevant events written to the security log
+type securityEvent string
+
+const (
+	eventInvalidListToken securityEvent = "invalid_list_token" // Unknown list or share token
+	eventListGuessBlocked securityEvent = "list_guess_blocked" // IP throttled for requesting too many invalid tokens
+	eventInvalidApiToken  securityEvent = "invalid_api_token"  // Unknown API bearer token
+	eventRevokedSession   securityEvent = "revoked_session"    // Request authenticated with a revoked session
+)
+
+// securityLogFormat is the shape of the security log lines, documented in the server README for fail2ban
+// filters to match them: changing it breaks these filters.
+const securityLogFormat = "%s letsblockit-security: event=%s ip=%s route=%s\n"
+
+// securityLogger writes one line per security event, with the client IP resolved from the trusted proxies
+type securityLogger struct {
+	sync.Mutex
+	out io.Writer
+	now func() time.Time
+}
+
+// newSecurityLogger appends the events to the given file, or writes them to stderr if target is -.
+// It returns nil if target is empty.
+func newSecurityLogger(target string, now func() time.Time) (*securityLogger, error) {
+	switch target {
+	case "":
+		return nil, nil
+	case "-":
+		return &securityLogger{out: os.Stderr, now: now}, nil
+	}
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open the security log: %w", err)
+	}
+	return &securityLogger{out: file, now: now}, nil
+}
+
+// log writes an event for the request, it is a no-op on a nil logger
+func (l *securityLogger) log(c echo.Context, event securityEvent) {
+	if l == nil {
+		return
+	}
+	l.Lock()
+	defer l.Unlock()
+	_, _ = fmt.Fprintf(l.out, securityLogFormat, l.now().UTC().Format(time.RFC3339), event, clientIP(c), routeTag(c))
+}
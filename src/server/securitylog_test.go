@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityLogger(t *testing.T) {
+	var out strings.Builder
+	logger := &securityLogger{out: &out, now: func() time.Time { return fixedNow }}
+	req := httptest.NewRequest(http.MethodGet, "/list/a5c2d8e0-2ba4-4a4e-a2bc-d1a4f4cba31c", nil)
+	req.RemoteAddr = "203.0.113.7:5123"
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+	c.SetPath("/list/:token")
+
+	logger.log(c, eventInvalidListToken)
+	assert.Equal(t, "2020-06-02T17:44:22Z letsblockit-security: event=invalid_list_token ip=203.0.113.7 route=/list/:token\n", out.String())
+
+	var disabled *securityLogger
+	disabled.log(c, eventInvalidListToken)
+}
+
+func TestSecurityLogger_File(t *testing.T) {
+	logger, err := newSecurityLogger("", time.Now)
+	require.NoError(t, err)
+	assert.Nil(t, logger)
+
+	target := filepath.Join(t.TempDir(), "security.log")
+	require.NoError(t, os.WriteFile(target, []byte("previous line\n"), 0640))
+	logger, err = newSecurityLogger(target, func() time.Time { return fixedNow })
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/list", nil)
+	req.RemoteAddr = "[2001:db8::1]:443"
+	logger.log(echo.New().NewContext(req, httptest.NewRecorder()), eventInvalidApiToken)
+
+	content, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "previous line\n2020-06-02T17:44:22Z letsblockit-security: event=invalid_api_token ip=2001:db8::1 route=unknown\n", string(content))
+}
+
+func TestLimitListGuesses_SecurityLog(t *testing.T) {
+	var out strings.Builder
+	s := &Server{
+		statsd:      &statsd.NoOpClient{},
+		securityLog: &securityLogger{out: &out, now: func() time.Time { return fixedNow }},
+	}
+	s.live.Store(&liveConfig{listGuesses: newGuessLimiter(1, time.Minute, func() time.Time { return fixedNow })})
+	handler := s.limitListGuesses(func(c echo.Context) error { return echo.ErrNotFound })
+
+	for _, expected := range []int{http.StatusNotFound, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/list/unknown", nil)
+		req.RemoteAddr = "203.0.113.7:5123"
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+		c.SetPath("/list/:token")
+		assert.Equal(t, expected, handler(c).(*echo.HTTPError).Code)
+	}
+	assert.Equal(t, `2020-06-02T17:44:22Z letsblockit-security: event=invalid_list_token ip=203.0.113.7 route=/list/:token
+2020-06-02T17:44:22Z letsblockit-security: event=invalid_list_token ip=203.0.113.7 route=/list/:token
+2020-06-02T17:44:22Z letsblockit-security: event=list_guess_blocked ip=203.0.113.7 route=/list/:token
+`, out.String())
+}
@@ -1,62 +1,126 @@
 package server
 
 import (
+	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/DataDog/datadog-go/v5/statsd"
 	"github.com/coreos/go-systemd/activation"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"github.com/labstack/gommon/log"
 	"github.com/letsblockit/letsblockit/data"
 	"github.com/letsblockit/letsblockit/src/db"
 	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/metrics"
 	"github.com/letsblockit/letsblockit/src/news"
 	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/letsblockit/letsblockit/src/tracing"
 	"github.com/letsblockit/letsblockit/src/users"
 	"github.com/letsblockit/letsblockit/src/users/auth"
-	"github.com/vearutop/statigz"
 	"gopkg.in/natefinch/lumberjack.v2"
+	"zgo.at/zcache/v2"
 )
 
 var ErrDryRunFinished = errors.New("dry run finished")
 
 const (
-	loggerFormat = `{"http":{"host":"${host}","remote_ip":"${remote_ip}",` +
-		`"method":"${method}","uri":"${uri}","path":"${path}",` +
+	loggerFormat = `{"request_id":"${id}","user":"${custom}","http":{"host":"${host}","remote_ip":"${remote_ip}",` +
+		`"method":"${method}","uri":"${uri}","path":"${path}","route":"${route}",` +
 		`"user_agent":"${user_agent}","referer":"${referer}","is_htmx":"${header:HX-Request}",` +
 		`"status":"${status}","error":"${error}","latency":${latency},` +
 		`"bytes_in":${bytes_in},"bytes_out":${bytes_out}}}` + "\n"
-	mainDomain = "letsblock.it"
-	csrfLookup = "_csrf"
-	healthPath = "/_health"
+	mainDomain  = "letsblock.it"
+	csrfLookup  = "_csrf"
+	healthPath  = "/_health"
+	metricsPath = "/metrics"
 )
 
 type Options struct {
-	Address             string `group:"Networking" default:"127.0.0.1:8765" help:"address to listen to"`
-	UseSystemdSocket    bool   `group:"Networking" help:"use a systemd socket instead of opening a port"`
-	GzipResponses       bool   `group:"Networking" help:"compress most responses with gzip"`
-	DatabaseUrl         string `group:"Database" default:"postgresql:///letsblockit" help:"psql database to connect to"`
-	DatabasePoolOptions string `group:"Database" default:"" help:"pgxpool additional options"`
-	AuthMethod          string `group:"Authentication" required:"" enum:"kratos,proxy" help:"authentication method to use"`
-	AuthKratosUrl       string `group:"Authentication" default:"http://localhost:4000/.ory" help:"url of the kratos API, defaults to using local ory proxy"`
-	AuthProxyHeaderName string `group:"Authentication" placeholder:"X-Auth-Request-User" help:"name for the cookie set by the reverse proxy"`
-	LogLevel            string `group:"Development" default:"info" enum:"debug,info,warn,error,off" help:"http log level"`
-	CacheDir            string `group:"Development" placeholder:"/tmp" help:"folder to cache external resources in during local development"`
-	HotReload           bool   `group:"Development" help:"reload frontend when the backend restarts"`
-	StatsdTarget        string `group:"Monitoring" placeholder:"localhost:8125" help:"address to send statsd metrics to, disabled by default"`
-	VectorConfig        string `group:"Monitoring" help:"start the vector monitoring agent with a given yaml config"`
-	LogsFolder          string `group:"Monitoring" help:"output access logs to files instead of stdout"`
-	ListDownloadDomain  string `group:"Miscellaneous" help:"domain to use for list downloads, leave empty to use the main domain"`
-	OfficialInstance    bool   `group:"Miscellaneous" help:"turn on behaviours specific to the official letsblock.it instances"`
-	DryRun              bool   `hidden:""`
+	Address             string        `group:"Networking" default:"127.0.0.1:8765" help:"address to listen to"`
+	Listen              []string      `group:"Networking" placeholder:"tcp://127.0.0.1:8765,..." help:"listen specs replacing the address, as tcp://host:port or unix:///path.sock?mode=0660, add ?routes=public or ?routes=private to only serve the pages or the health, metrics and admin routes"`
+	UseSystemdSocket    bool          `group:"Networking" help:"use a systemd socket instead of opening a port"`
+	GzipResponses       bool          `group:"Networking" help:"compress most responses with gzip"`
+	GzipLevel           int           `group:"Networking" default:"6" help:"gzip level for lists and exports, from 1 to 9, 0 to disable gzip"`
+	BrotliLevel         int           `group:"Networking" default:"5" help:"brotli level for lists and exports, from 1 to 11, 0 to disable brotli"`
+	H2c                 bool          `group:"Networking" name:"h2c" env:"LETSBLOCKIT_H2C" help:"accept HTTP/2 without TLS (h2c) on the plain listeners, for reverse proxies speaking h2c to their upstreams"`
+	TrustedProxies      []string      `group:"Networking" placeholder:"10.0.0.0/8,..." help:"IP ranges of the reverse proxies allowed to set the client IP header, the connection IP is used if empty"`
+	ClientIPHeader      string        `group:"Networking" default:"X-Forwarded-For" enum:"X-Forwarded-For,X-Real-IP" help:"header the trusted proxies set the client IP in"`
+	PublicHostname      string        `group:"Networking" placeholder:"lists.example.com" help:"hostname the instance is reachable at, used in the rendered lists, defaults to the host forwarded by the trusted proxies"`
+	CorsOrigins         []string      `group:"Networking" placeholder:"https://dashboard.example.com,..." help:"origins allowed to call the JSON API from browsers, with the user's cookies, lists can be fetched from any origin"`
+	ListMirrors         []string      `group:"Networking" placeholder:"https://mirror.example.com,..." help:"base URLs of instances mirroring the lists of this one, advertised in the list headers for subscribers to fall back to"`
+	ListGuessLimit      int           `group:"Networking" default:"30" help:"invalid list tokens an IP can request during the window before being throttled, 0 to disable"`
+	ListGuessWindow     time.Duration `group:"Networking" default:"10m" help:"sliding window to count invalid list tokens in"`
+	CrawlerUserAgents   []string      `group:"Networking" default:"Googlebot,bingbot,Baiduspider,YandexBot,DuckDuckBot,Applebot,Slurp,AhrefsBot,SemrushBot,GPTBot" help:"user agents to reject list downloads from, matched case-insensitively, empty to allow all"`
+	ListCacheSize       int           `group:"Networking" default:"64" help:"size of the rendered lists cache, in megabytes, 0 to disable"`
+	FormBodyLimit       int           `group:"Networking" default:"256" help:"maximum size of form submissions, in kilobytes, 0 to disable"`
+	ApiBodyLimit        int           `group:"Networking" default:"1024" help:"maximum size of API request bodies, in kilobytes, 0 to disable"`
+	HotListThreshold    int           `group:"Networking" default:"0" help:"downloads per refresh interval for a list to be pre-rendered in the background, 0 to disable"`
+	HotListRefresh      time.Duration `group:"Networking" default:"1m" help:"interval to elect the hot lists and refresh their pre-rendered body"`
+	HotListCacheSize    int           `group:"Networking" default:"16" help:"size of the pre-rendered hot lists, in megabytes"`
+	RenderTimeout       time.Duration `group:"Networking" default:"10s" help:"maximum duration of list renders, 0 to disable"`
+	RuleCountWarning    int           `group:"Networking" default:"300000" help:"rules a list can hold before its header warns subscribers about its size, 0 to disable"`
+	ListSafeMode        bool          `group:"Networking" default:"true" negatable:"" help:"comment out the rendered lines with an invalid rule syntax, for lists to stay loadable"`
+	RenderStatusFlush   time.Duration `group:"Networking" default:"1m" help:"interval to write the outcome of the list renders at, shown to users next to their filters, 0 to disable"`
+	ShutdownDelay       time.Duration `group:"Networking" default:"0s" help:"keep serving requests for this duration after a stop signal, with failing health checks for load balancers to stop routing requests"`
+	ShutdownTimeout     time.Duration `group:"Networking" default:"30s" help:"time given to in-flight requests to complete when stopping"`
+	TlsDomain           string        `group:"TLS" placeholder:"example.com" help:"serve HTTPS with certificates from Let's Encrypt for this domain and the list download domain, the address must then be reachable on port 443 for the TLS-ALPN challenges, binding ports below 1024 requires root or the CAP_NET_BIND_SERVICE capability"`
+	TlsEmail            string        `group:"TLS" placeholder:"admin@example.com" help:"contact address for Let's Encrypt to send expiration notices to"`
+	TlsCacheDir         string        `group:"TLS" default:"tls-cache" placeholder:"DIR" help:"folder to store the certificates and account key in, it must persist across restarts not to hit the Let's Encrypt rate limits"`
+	TlsHttpAddress      string        `group:"TLS" default:":80" help:"plain HTTP address answering the HTTP challenges and redirecting to HTTPS, it must be reachable on port 80"`
+	DatabaseUrl         string        `group:"Database" default:"postgresql:///letsblockit" help:"psql database to connect to"`
+	DatabasePoolOptions string        `group:"Database" default:"" help:"pgxpool additional options"`
+	AutoMigrate         bool          `group:"Database" default:"true" negatable:"" help:"apply the pending schema migrations on startup, else fail if the schema is outdated"`
+	SlowQueryThreshold  time.Duration `group:"Database" default:"250ms" help:"log database queries slower than this duration, 0 to disable"`
+	PoolStatsInterval   time.Duration `group:"Database" default:"30s" help:"interval to export the connection pool statistics as metrics at, 0 to disable"`
+	AuthMethod          string        `group:"Authentication" required:"" enum:"kratos,proxy" help:"authentication method to use"`
+	AuthKratosUrl       string        `group:"Authentication" default:"http://localhost:4000/.ory" help:"url of the kratos API, defaults to using local ory proxy"`
+	AuthProxyHeaderName string        `group:"Authentication" placeholder:"X-Auth-Request-User" help:"name for the cookie set by the reverse proxy"`
+	AdminUsers          []string      `group:"Authentication" placeholder:"USER-ID,..." help:"IDs of the users allowed to access admin pages"`
+	LogLevel            string        `group:"Development" default:"info" enum:"debug,info,warn,error,off" help:"http log level"`
+	CacheDir            string        `group:"Development" placeholder:"/tmp" help:"folder to cache external resources in during local development"`
+	HotReload           bool          `group:"Development" help:"reload frontend when the backend restarts"`
+	TemplatesDir        string        `group:"Development" type:"existingdir" placeholder:"DIR" help:"load templates from a local folder, replacing the embedded templates with the same name, presets are read from its sibling presets folder"`
+	StatsdTarget        string        `group:"Monitoring" placeholder:"localhost:8125" help:"address to send statsd metrics to, disabled by default"`
+	StatsdSampleRates   []string      `group:"Monitoring" placeholder:"download=0.1,..." help:"sample rates of the statsd metric classes, as class=rate with the download, request and other classes, error metrics are never sampled"`
+	VectorConfig        string        `group:"Monitoring" help:"start the vector monitoring agent with a given yaml config"`
+	LogsFolder          string        `group:"Monitoring" help:"output access logs to files instead of stdout"`
+	SecurityLog         string        `group:"Monitoring" placeholder:"FILE" help:"append security events such as invalid list tokens to this file, for fail2ban to ban the offending IPs, - for stderr"`
+	PrometheusMetrics   bool          `group:"Monitoring" help:"expose prometheus metrics on /metrics"`
+	PrometheusAddress   string        `group:"Monitoring" placeholder:"127.0.0.1:9102" help:"serve the prometheus metrics on a separate address instead of the main one"`
+	SlowRenderThreshold time.Duration `group:"Monitoring" default:"500ms" help:"log list renders slower than this duration, 0 to disable"`
+	RenderSampleRate    float64       `group:"Monitoring" default:"0.05" help:"ratio of list renders to record per-template render durations for"`
+	TokenScanThreshold  int           `group:"Monitoring" default:"0" help:"unknown list tokens requested instance-wide during the window to log a token scan warning, 0 to disable"`
+	TokenScanWindow     time.Duration `group:"Monitoring" default:"1m" help:"sliding window to count unknown list tokens in for the token scan detection"`
+	TokenScanWebhook    string        `group:"Monitoring" placeholder:"URL" help:"URL to post a JSON alert to when a token scan is detected"`
+	SlowTemplateCount   int           `group:"Monitoring" default:"10" help:"slowest templates in the sampled renders to list in the admin page and export as metrics"`
+	TracingEndpoint     string        `group:"Monitoring" placeholder:"localhost:4318" help:"OTLP/HTTP collector to export traces to, disabled by default"`
+	TracingInsecure     bool          `group:"Monitoring" help:"export traces over plain HTTP instead of HTTPS"`
+	TracingSampleRate   float64       `group:"Monitoring" default:"0.1" help:"ratio of requests to trace, requests of the render CLI follow the sampling of their parent"`
+	ListDownloadDomain  string        `group:"Miscellaneous" help:"domain to use for list downloads, leave empty to use the main domain"`
+	OfficialInstance    bool          `group:"Miscellaneous" help:"turn on behaviours specific to the official letsblock.it instances"`
+	BannedListFile      string        `group:"Miscellaneous" type:"existingfile" help:"file holding the comment-only list served instead of the lists of banned users"`
+	EphemeralListSecret string        `group:"Miscellaneous" help:"key to sign ephemeral list and impersonation cookies with, a random key is generated if empty"`
+	WebhookAllowPrivate bool          `group:"Miscellaneous" help:"allow user webhooks to target loopback and private network addresses"`
+	Maintenance         bool          `group:"Miscellaneous" help:"start in maintenance mode, only serving list downloads, toggled at runtime with SIGUSR1"`
+	MaintenanceRetry    time.Duration `group:"Miscellaneous" default:"5m" help:"retry delay advertised to clients during maintenance"`
+	SuggestionRefresh   time.Duration `group:"Miscellaneous" default:"1h" help:"interval to compute the template suggestions from the filters of all users at, 0 to disable suggestions"`
+	ParamStatsRefresh   time.Duration `group:"Miscellaneous" default:"0" help:"interval to aggregate the checkbox values of all instances at, for maintainers to tune the template defaults, on the official instance only, 0 to disable"`
+	SuggestionMinLists  int           `group:"Miscellaneous" default:"10" help:"lists a template, or a pair of templates, must be used in to be suggested, to not disclose the filters of a few users"`
+	UsageCounts         bool          `group:"Miscellaneous" default:"true" negatable:"" help:"show the rounded number of users of the templates in the catalog, computed along the suggestions"`
+	CheckTemplates      bool          `group:"Development" help:"render all templates with their defaults and presets, then exit"`
+	DryRun              bool          `hidden:""`
 }
 
 var navigationLinks = []struct {
@@ -77,49 +141,118 @@ var navigationLinks = []struct {
 }}
 
 type Server struct {
-	assets      http.Handler
-	auth        auth.Backend
-	bans        *users.BanManager
-	echo        *echo.Echo
-	filters     *filters.Repository
-	filterHash  string
-	now         func() time.Time
-	options     *Options
-	pages       PageRenderer
-	preferences *users.PreferenceManager
-	releases    ReleaseClient
-	statsd      statsd.ClientInterface
-	store       db.Store
+	announcements  []*news.Announcement
+	assets         *assetServer
+	auth           auth.Backend
+	bannedList     string
+	bans           *users.BanManager
+	cookieKey      []byte
+	echo           *echo.Echo
+	filters        *filters.Repository
+	filterHash     string
+	hotLists       *hotLists
+	listCache      *listCache
+	listGuesses    *guessLimiter
+	live           atomic.Pointer[liveConfig] // Set on start, replaced on reload
+	loadOptions    func() (*Options, error)
+	maintenance    atomic.Bool
+	metricsServer  *http.Server
+	newsHash       string
+	now            func() time.Time
+	options        *Options
+	pages          PageRenderer
+	preferences    *users.PreferenceManager
+	prometheus     *metrics.Registry
+	proxyRanges    []*net.IPNet
+	reloadLock     sync.Mutex
+	releases       ReleaseClient
+	renderStatuses renderStatuses
+	renderTimings  renderTimings
+	securityLog    *securityLogger
+	servers        []*http.Server // One per listen spec
+	certs          certManager
+	serversLock    sync.Mutex
+	sessions       *users.SessionManager
+	shuttingDown   atomic.Bool
+	sitemap        atomic.Pointer[sitemap]
+	statsCache     *zcache.Cache[string, *instanceStats]
+	statsd         statsd.ClientInterface
+	sampler        *metrics.Sampler // Sends to the statsd target, nil if unset
+	stopTasks      context.CancelFunc
+	stopTracing    func(context.Context) error
+	store          db.Store
+	suggestions    atomic.Pointer[templateSuggestions]
+	templateCheck  atomic.Pointer[templateCheckReport]
+	templateFeed   atomic.Pointer[templateFeed]
+	tokenScans     *tokenScanDetector
+	undo           undoStash
+	versions       versionCache
+	webhooks       *webhookDispatcher
 }
 
 func NewServer(options *Options) *Server {
-	return &Server{
-		options: options,
-		echo:    echo.New(),
-		now:     time.Now,
+	s := &Server{
+		options:    options,
+		echo:       echo.New(),
+		now:        time.Now,
+		statsCache: zcache.New[string, *instanceStats](statsCacheTTL, statsCacheTTL),
 	}
+	s.listGuesses = newGuessLimiter(options.ListGuessLimit, options.ListGuessWindow, func() time.Time { return s.now() })
+	s.listCache = newListCache(options.ListCacheSize << 20)
+	s.hotLists = newHotLists(options.HotListThreshold, options.HotListCacheSize<<20)
+	s.tokenScans = newTokenScanDetector(options.TokenScanThreshold, options.TokenScanWindow,
+		func() time.Time { return s.now() }, s.alertTokenScan)
+	return s
 }
 
 func (s *Server) Start() error {
+	if s.options.CheckTemplates {
+		return s.runTemplateCheck()
+	}
+
+	var metricClients []statsd.ClientInterface
 	if s.options.StatsdTarget != "" {
 		dsd, err := statsd.New(s.options.StatsdTarget, statsd.WithoutTelemetry())
 		if err != nil {
 			return err
 		}
-		s.statsd = dsd
-		s.echo.Use(buildDogstatsMiddleware(dsd))
-	} else {
-		s.statsd = &statsd.NoOpClient{}
+		rates, err := metrics.ParseSampleRates(s.options.StatsdSampleRates)
+		if err != nil {
+			return err
+		}
+		s.sampler = metrics.NewSampler(dsd, rates)
+		metricClients = append(metricClients, s.sampler)
+	}
+	if s.options.PrometheusMetrics {
+		s.prometheus = metrics.NewRegistry()
+		metricClients = append(metricClients, s.prometheus)
+	}
+	s.statsd = metrics.New(metricClients...)
+	if len(metricClients) > 0 {
+		s.echo.Use(buildDogstatsMiddleware(s.statsd, s.echo))
+	}
+	var err error
+	if s.stopTracing, err = tracing.Setup(tracing.Options{
+		Endpoint:   s.options.TracingEndpoint,
+		Insecure:   s.options.TracingInsecure,
+		SampleRate: s.options.TracingSampleRate,
+	}); err != nil {
+		return err
 	}
 
 	concurrentRunOrPanic([]func([]error){
-		func(errs []error) { s.assets = statigz.FileServer(data.Assets) },
+		func(errs []error) { s.assets, errs[0] = newAssetServer(data.Assets) },
 		func(errs []error) { s.pages, errs[0] = pages.LoadPages() },
-		func(errs []error) { s.filters, errs[0] = filters.Load(data.Templates, data.Presets) },
+		func(errs []error) { s.filters, errs[0] = loadTemplates(s.options.TemplatesDir) },
 		func(errs []error) {
-			s.store, errs[0] = db.Connect(s.options.DatabaseUrl, s.options.DatabasePoolOptions, s.statsd)
-			if errs[0] == nil {
+			s.store, errs[0] = db.Connect(s.options.DatabaseUrl, s.options.DatabasePoolOptions, s.statsd, db.SlowQueryLog{
+				Threshold: s.options.SlowQueryThreshold,
+				Logger:    s.echo.Logger,
+			})
+			if errs[0] == nil && s.options.AutoMigrate {
 				errs[0] = db.Migrate(s.options.DatabaseUrl)
+			} else if errs[0] == nil {
+				errs[0] = db.CheckSchema(s.options.DatabaseUrl)
 			}
 			if errs[0] == nil {
 				s.bans, errs[0] = users.LoadUserBans(s.store)
@@ -127,10 +260,11 @@ func (s *Server) Start() error {
 			if errs[0] == nil {
 				s.preferences, errs[0] = users.NewPreferenceManager(s.store)
 			}
+			s.sessions = users.NewSessionManager(s.store)
 		},
 		func(errs []error) { errs[0] = runVector(s.options.VectorConfig) },
 		func(errs []error) {
-			s.filterHash, errs[0] = data.HashFiles(data.Templates, data.Presets)
+			s.filterHash, errs[0] = hashTemplates(s.options.TemplatesDir)
 		},
 	})
 
@@ -147,7 +281,32 @@ func (s *Server) Start() error {
 		})
 	}
 
+	if s.securityLog, err = newSecurityLogger(s.options.SecurityLog, func() time.Time { return s.now() }); err != nil {
+		return err
+	}
+
+	if s.bannedList, err = readBannedList(s.options.BannedListFile); err != nil {
+		return err
+	}
+
+	if s.options.EphemeralListSecret != "" {
+		s.cookieKey = []byte(s.options.EphemeralListSecret)
+	} else {
+		s.cookieKey = make([]byte, 32)
+		if _, err := rand.Read(s.cookieKey); err != nil {
+			return err
+		}
+	}
+
 	s.releases = news.NewReleaseClient(news.GithubReleasesEndpoint, s.options.CacheDir, s.options.OfficialInstance, s.filters)
+	announcements, err := news.LoadAnnouncements(data.News, s.options.OfficialInstance, s.filters)
+	if err != nil {
+		return err
+	}
+	s.announcements = announcements
+	if s.newsHash, err = data.HashFiles(data.News); err != nil {
+		return err
+	}
 
 	switch s.options.AuthMethod {
 	case "kratos":
@@ -164,16 +323,67 @@ func (s *Server) Start() error {
 		return fmt.Errorf("unsupported auth method %s", s.options.AuthMethod)
 	}
 
+	if s.proxyRanges, err = parseTrustedProxies(s.options.TrustedProxies); err != nil {
+		return err
+	}
+	if err = checkCompressionLevels(s.options); err != nil {
+		return err
+	}
+
+	s.webhooks = newWebhookDispatcher(s.store, s.statsd, s.options.WebhookAllowPrivate)
 	s.pages.RegisterHelpers(buildHelpers(s.echo))
+	s.pages.RegisterHelpers(s.assets.helpers())
 	s.pages.RegisterContextBuilder(s.buildPageContext)
 	s.setupRouter()
+	s.live.Store(s.config())
+	s.setMaintenance(s.options.Maintenance)
+	s.checkTemplates()
 	if s.options.DryRun {
 		return ErrDryRunFinished
 	}
+	if err = s.loadTemplateFeed(context.Background(), s.filters); err != nil {
+		s.echo.Logger.Error(err)
+	}
+	if err = s.loadSitemap(context.Background(), s.filters); err != nil {
+		s.echo.Logger.Error(err)
+	}
 
-	if s.options.StatsdTarget != "" {
+	var tasks context.Context
+	tasks, s.stopTasks = context.WithCancel(context.Background())
+	go s.bans.RefreshEvery(tasks, banRefreshInterval, func(err error) {
+		s.echo.Logger.Error("cannot refresh user bans: " + err.Error())
+	})
+	go purgeEphemeralLists(tasks, s.echo.Logger, s.store)
+	if s.hotLists != nil {
+		go s.refreshHotLists(tasks, s.options.HotListRefresh)
+	}
+	if s.options.SuggestionRefresh > 0 {
+		go s.refreshSuggestions(tasks, s.options.SuggestionRefresh)
+	}
+	if s.options.RenderStatusFlush > 0 {
+		go s.writeRenderStatuses(tasks, s.options.RenderStatusFlush)
+	}
+	if s.options.OfficialInstance && s.options.ParamStatsRefresh > 0 {
+		go s.refreshParamStats(tasks, s.options.ParamStatsRefresh)
+	}
+	s.webhooks.Run(s.echo.Logger)
+	if s.options.StatsdTarget != "" || s.options.PrometheusMetrics {
 		go collectBusinessStats(s.echo.Logger, s.store, s.statsd)
 		go collectMemStats(s.statsd)
+		if s.options.PoolStatsInterval > 0 {
+			go collectPoolStats(tasks, s.store, s.statsd, s.options.PoolStatsInterval)
+		}
+		go s.exportRenderTimings(tasks, renderTimingsInterval)
+	}
+	if s.prometheus != nil && s.options.PrometheusAddress != "" {
+		s.servePrometheus()
+	}
+	if s.options.TlsDomain != "" {
+		s.certs = newCertManager(s.options)
+	}
+	specs, err := s.buildListenSpecs()
+	if err != nil {
+		return err
 	}
 	if s.options.UseSystemdSocket {
 		listeners, err := activation.Listeners()
@@ -185,46 +395,104 @@ func (s *Server) Start() error {
 		} else {
 			fmt.Println("reusing systemd socket...")
 		}
-		s.echo.Listener = listeners[0]
+		specs[0].listener = listeners[0]
 	}
-	return s.echo.Start(s.options.Address)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	toggles := make(chan os.Signal, 1)
+	signal.Notify(toggles, syscall.SIGUSR1)
+	defer signal.Stop(toggles)
+	go s.toggleMaintenanceOnSignal(ctx, toggles)
+	reloads := make(chan os.Signal, 1)
+	signal.Notify(reloads, syscall.SIGHUP)
+	defer signal.Stop(reloads)
+	go s.reloadOnSignal(ctx, reloads)
+	return s.serve(ctx, func() error { return s.serveListeners(specs) })
 }
 
-func (s *Server) setupRouter() {
-	switch s.options.LogLevel {
-	case "debug":
-		s.echo.Logger.SetLevel(log.DEBUG)
-	case "info":
-		s.echo.Logger.SetLevel(log.INFO)
-	case "warn":
-		s.echo.Logger.SetLevel(log.WARN)
-	case "error":
-		s.echo.Logger.SetLevel(log.ERROR)
-	case "off":
-		s.echo.Logger.SetLevel(log.OFF)
+// serve runs start until ctx is cancelled, then shuts the server down gracefully
+func (s *Server) serve(ctx context.Context, start func() error) error {
+	errs := make(chan error, 1)
+	go func() { errs <- start() }()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return s.shutdown()
+	}
+}
+
+// shutdown stops the server in order: health checks fail first, for load balancers to stop
+// routing requests, then in-flight requests are drained and pending webhooks are delivered.
+// Metrics are flushed and the database pool is closed last, as requests still use them.
+func (s *Server) shutdown() error {
+	s.shuttingDown.Store(true)
+	fmt.Println("shutting down...")
+	time.Sleep(s.options.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.options.ShutdownTimeout)
+	defer cancel()
+	err := s.shutdownServers(ctx)
+	if err != nil {
+		err = fmt.Errorf("cannot drain requests: %w", err)
+	}
+	if s.metricsServer != nil {
+		_ = s.metricsServer.Shutdown(ctx)
 	}
+	if s.stopTasks != nil {
+		s.stopTasks()
+	}
+	if e := s.webhooks.Stop(ctx); e != nil && err == nil {
+		err = fmt.Errorf("cannot deliver pending webhooks: %w", e)
+	}
+	if s.stopTracing != nil {
+		_ = s.stopTracing(ctx) // Exports the pending spans
+	}
+	if s.statsd != nil {
+		_ = s.statsd.Close() // Flushes the buffered metrics
+	}
+	if s.store != nil {
+		s.store.Close()
+	}
+	return err
+}
+
+func (s *Server) setupRouter() {
+	setLogLevel(s.echo.Logger, s.options.LogLevel)
+	s.echo.HTTPErrorHandler = s.handleError
 	s.echo.Use(
-		middleware.Recover(),
+		buildRequestIDMiddleware(),
 		middleware.LoggerWithConfig(middleware.LoggerConfig{
-			Format: loggerFormat,
+			Format:        loggerFormat,
+			CustomTagFunc: logUserHash,
 			Skipper: func(c echo.Context) bool {
-				return c.Request().URL.Path == healthPath
+				return isHealthPath(c.Request().URL.Path)
 			},
 		}),
+		s.recoverPanics,
+		buildCORSMiddleware(s.options.CorsOrigins),
 	)
+	if s.options.TracingEndpoint != "" {
+		s.echo.Use(traceRequests)
+	}
 
 	s.echo.HideBanner = true
-	s.echo.IPExtractor = echo.ExtractIPFromXFFHeader()
+	s.echo.IPExtractor = buildIPExtractor(s.proxyRanges, s.options.ClientIPHeader)
 
+	s.echo.Pre(bindListener)
 	s.echo.Pre(middleware.RemoveTrailingSlash())
 	s.echo.Pre(middleware.Rewrite(map[string]string{
 		"/favicon.ico": "/assets/images/favicon.ico",
-		"/robots.txt":  "/assets/robots.txt",
 		"/about":       "/help/about",
 	}))
 
 	// Raw routes
-	s.echo.GET(healthPath, func(c echo.Context) error { return c.String(200, "OK") })
+	s.echo.GET(healthPath, s.healthCheck)
+	s.echo.GET(livenessPath, s.livenessCheck)
+	s.echo.GET(readinessPath, s.readinessCheck)
+	s.echo.GET("/robots.txt", s.robotsTxt)
+	s.echo.GET("/sitemap.xml", s.sitemapXml)
 	s.echo.GET("/assets/*", echo.WrapHandler(s.assets))
 	s.echo.HEAD("/assets/*", echo.WrapHandler(s.assets))
 	s.echo.GET("/filters/youtube-streams-chat", func(c echo.Context) error {
@@ -233,26 +501,66 @@ func (s *Server) setupRouter() {
 	if s.options.HotReload {
 		s.echo.GET("/should-reload", shouldReload)
 	}
+	if s.prometheus != nil && s.options.PrometheusAddress == "" {
+		s.echo.GET(metricsPath, echo.WrapHandler(s.prometheus))
+	}
 
 	var middlewares []echo.MiddlewareFunc
 	if s.options.GzipResponses {
-		middlewares = append(middlewares, middleware.GzipWithConfig(middleware.GzipConfig{Level: 6}))
+		middlewares = append(middlewares, middleware.GzipWithConfig(middleware.GzipConfig{
+			Level:   6,
+			Skipper: func(c echo.Context) bool { return encodedPaths[c.Path()] },
+		}))
 	}
+	formLimit := limitBody(int64(s.options.FormBodyLimit) << 10)
+	apiLimit := limitBody(int64(s.options.ApiBodyLimit) << 10)
 	zippedRoutes := s.echo.Group("", middlewares...)
-	zippedRoutes.POST("/filters/:name/render", s.viewFilterRender).Name = "view-filter-render"
-	zippedRoutes.GET("/list/:token", s.renderList).Name = "render-filterlist"
+	zippedRoutes.POST("/filters/:name/render", s.viewFilterRender, formLimit).Name = "view-filter-render"
+	zippedRoutes.GET("/list/:token", s.renderList, noIndex, s.rejectCrawlers, s.limitListGuesses, s.encodeResponse).Name = "render-filterlist"
+	zippedRoutes.GET("/api/list/:token", s.listDefinition, noIndex, s.limitListGuesses).Name = "list-definition"
 	zippedRoutes.GET("/news.atom", s.newsAtomHandler).Name = "news-atom"
+	zippedRoutes.GET("/filters/updates.atom", s.templateUpdatesAtom).Name = "template-updates-atom"
+
+	zippedRoutes.GET(openAPIPath, s.openAPIHandler).Name = "openapi"
+
+	// JSON API, authenticated with personal API tokens
+	zippedRoutes.GET("/api/instances", s.apiListInstances, s.pauseInMaintenance, s.bearerAuth).Name = "api-list-instances"
+	zippedRoutes.GET("/api/instances/:name", s.apiGetInstance, s.pauseInMaintenance, s.bearerAuth).Name = "api-instance"
+	zippedRoutes.PUT("/api/instances/:name", s.apiPutInstance, s.pauseInMaintenance, s.bearerAuth, apiLimit)
+	zippedRoutes.DELETE("/api/instances/:name", s.apiDeleteInstance, s.pauseInMaintenance, s.bearerAuth)
+	zippedRoutes.GET("/api/export", s.apiExportList, noIndex, s.pauseInMaintenance, s.bearerAuth, s.encodeResponse).Name = "api-export-list"
+	zippedRoutes.GET("/api/template-updates", s.apiTemplateUpdates, s.pauseInMaintenance, s.bearerAuth).Name = "api-template-updates"
+
+	// Sync API for browser extensions, authenticated with API tokens holding the sync scope
+	zippedRoutes.GET("/api/sync/instances", s.apiSyncInstances, s.pauseInMaintenance, s.syncAuth).Name = "api-sync-instances"
+	zippedRoutes.GET("/api/sync/changes", s.apiSyncChanges, s.pauseInMaintenance, s.syncAuth).Name = "api-sync-changes"
+	zippedRoutes.PUT("/api/sync/instances/:name/test-mode", s.apiSyncSetTestMode, s.pauseInMaintenance, s.syncAuth, apiLimit)
+	zippedRoutes.PUT("/api/sync/instances/:name/enabled", s.apiSyncSetEnabled, s.pauseInMaintenance, s.syncAuth, apiLimit)
+
+	// Versioned JSON API, authenticated with API tokens or browser sessions
+	apiV1Routes := zippedRoutes.Group("/api/v1", apiV1Errors, apiLimit, s.pauseInMaintenance, apiV1Negotiate, s.apiV1Auth(s.auth.BuildMiddleware()))
+	apiV1Routes.GET("/filters", s.apiV1ListFilters).Name = "api-v1-list-filters"
+	apiV1Routes.POST("/filters", s.apiV1CreateFilter)
+	apiV1Routes.GET("/filters/:name", s.apiV1GetFilter).Name = "api-v1-filter"
+	apiV1Routes.PUT("/filters/:name", s.apiV1UpdateFilter)
+	apiV1Routes.DELETE("/filters/:name", s.apiV1DeleteFilter)
+	apiV1Routes.GET("/lists/:token/subscribe", s.apiV1SubscribeLinks).Name = "api-v1-subscribe-links"
 
 	authedRoutes := zippedRoutes.Group("",
+		s.pauseInMaintenance,
+		formLimit,
 		s.auth.BuildMiddleware(),
+		s.ephemeralSession,
 		func(next echo.HandlerFunc) echo.HandlerFunc {
 			return func(c echo.Context) error {
-				if s.bans.IsBanned(auth.GetUserId(c)) {
+				// Banned users can still export their data
+				if s.bans.IsBanned(auth.GetUserId(c)) && c.Path() != dataExportPath {
 					return echo.ErrForbidden
 				}
 				return next(c)
 			}
 		},
+		auth.BuildAdminMiddleware(s.options.AdminUsers),
 		middleware.CSRFWithConfig(middleware.CSRFConfig{
 			TokenLookup:    "form:" + csrfLookup,
 			ContextKey:     csrfLookup,
@@ -261,6 +569,8 @@ func (s *Server) setupRouter() {
 			CookieSameSite: http.SameSiteStrictMode,
 			CookieHTTPOnly: true,
 		}),
+		s.checkSession,
+		s.impersonation,
 	)
 	s.auth.RegisterRoutes(authedRoutes)
 
@@ -268,17 +578,75 @@ func (s *Server) setupRouter() {
 	authedRoutes.GET("/help", s.helpPages).Name = "help-main"
 	authedRoutes.GET("/help/:page", s.helpPages).Name = "help"
 	authedRoutes.GET("/news", s.newsHandler).Name = "news"
+	authedRoutes.POST("/try", s.startEphemeralList, buildEphemeralRateLimiter()).Name = "start-ephemeral-list"
 
 	authedRoutes.GET("/filters", s.listFilters).Name = "list-filters"
 	authedRoutes.GET("/filters/tag/:tag", s.listFilters).Name = "filters-for-tag"
+	authedRoutes.GET("/filters/suggested", s.suggestedFilters).Name = "suggested-filters"
+	authedRoutes.GET("/filters/feedback", s.templateFeedback).Name = "template-feedback"
+	authedRoutes.GET("/filters/proposals", s.filterProposals).Name = "filter-proposals"
+	authedRoutes.POST("/filters/proposals", s.submitProposal, requireAccount).Name = "submit-proposal"
 
+	authedRoutes.POST("/filters/updates/dismiss", s.dismissTemplateUpdates, requireAccount).Name = "dismiss-template-updates"
 	authedRoutes.GET("/filters/:name", s.viewFilter).Name = "view-filter"
 	authedRoutes.POST("/filters/:name", s.viewFilter)
-
-	authedRoutes.GET("/export/:token", s.exportList).Name = "export-filterlist"
+	authedRoutes.POST("/filters/:name/feedback", s.sendFeedback, requireAccount).Name = "send-feedback"
+	authedRoutes.POST("/filters/:name/favorite", s.toggleFavorite, requireAccount).Name = "toggle-favorite"
+	authedRoutes.POST("/filters/:name/pin", s.pinFilter).Name = "pin-filter"
+	authedRoutes.POST("/filters/:name/update", s.updateFilterVersion).Name = "update-filter-version"
+	authedRoutes.POST("/filters/:name/undo", s.undoRemoval).Name = "undo-filter-removal"
+
+	authedRoutes.GET("/export/:token", s.exportList, noIndex, s.encodeResponse).Name = "export-filterlist"
+	authedRoutes.GET("/user/list/:token/qr.png", s.listQRCode, noIndex).Name = "list-qr-code"
+	authedRoutes.GET("/shared/:token", s.viewSharedList, noIndex, s.limitListGuesses).Name = "view-shared-list"
 	authedRoutes.GET("/user/account", s.userAccount).Name = "user-account"
-	authedRoutes.POST("/user/rotate-token", s.rotateListToken).Name = "rotate-list-token"
-	authedRoutes.POST("/user/preferences", s.updatePreferences).Name = "update-preferences"
+	authedRoutes.GET("/user/devices", s.userDevices, requireAccount).Name = "user-devices"
+	authedRoutes.POST("/user/devices", s.createDeviceToken, requireAccount).Name = "create-device-token"
+	authedRoutes.POST("/user/devices/revoke", s.revokeDeviceToken, requireAccount).Name = "revoke-device-token"
+	authedRoutes.GET("/user/snapshots", s.listSnapshots, requireAccount).Name = "list-snapshots"
+	authedRoutes.POST("/user/snapshots", s.createSnapshot, requireAccount).Name = "create-snapshot"
+	authedRoutes.POST("/user/snapshots/delete", s.deleteSnapshot, requireAccount).Name = "delete-snapshot"
+	authedRoutes.POST("/user/snapshots/restore", s.restoreSnapshot, requireAccount).Name = "restore-snapshot"
+	authedRoutes.POST("/user/rotate-token", s.rotateListToken, requireAccount).Name = "rotate-list-token"
+	authedRoutes.POST("/user/reset-list", s.resetList, requireAccount).Name = "reset-list"
+	authedRoutes.POST("/user/preferences", s.updatePreferences, requireAccount).Name = "update-preferences"
+	authedRoutes.POST("/user/delete-account", s.deleteAccount, requireAccount).Name = "delete-account"
+	authedRoutes.POST("/user/api-tokens", s.createApiToken, requireAccount).Name = "create-api-token"
+	authedRoutes.POST("/user/api-tokens/revoke", s.revokeApiToken, requireAccount).Name = "revoke-api-token"
+	authedRoutes.POST("/user/share-tokens", s.createShareToken, requireAccount).Name = "create-share-token"
+	authedRoutes.POST("/user/share-tokens/revoke", s.revokeShareToken, requireAccount).Name = "revoke-share-token"
+	authedRoutes.POST("/user/sessions/revoke", s.revokeOtherSessions, requireAccount).Name = "revoke-other-sessions"
+	authedRoutes.POST("/user/webhook", s.saveWebhook, requireAccount).Name = "save-webhook"
+	authedRoutes.POST("/user/webhook/test", s.sendTestWebhook, requireAccount).Name = "test-webhook"
+	authedRoutes.GET(dataExportPath, s.exportUserData, requireAccount).Name = "export-user-data"
+	authedRoutes.POST("/user/merge/code", s.createMergeCode, requireAccount).Name = "create-merge-code"
+	authedRoutes.POST("/user/merge/preview", s.previewAccountMerge, requireAccount).Name = "preview-account-merge"
+	authedRoutes.POST("/user/merge", s.mergeAccounts, requireAccount).Name = "merge-accounts"
+	authedRoutes.GET("/user/import", s.importRulesForm, requireAccount).Name = "import-rules-form"
+	authedRoutes.POST("/user/import/preview", s.previewRulesImport, requireAccount).Name = "preview-rules-import"
+	authedRoutes.POST("/user/import", s.importRules, requireAccount).Name = "import-rules"
+	authedRoutes.POST("/user/clone/code", s.createCloneCode, requireAccount).Name = "create-clone-code"
+	authedRoutes.POST("/user/clone", s.cloneList).Name = "clone-list"
+
+	adminRoutes := authedRoutes.Group("/admin", requireAdmin)
+	adminRoutes.GET("/bans", s.adminBans).Name = "admin-bans"
+	adminRoutes.POST("/bans", s.adminAddBan).Name = "admin-add-ban"
+	adminRoutes.POST("/bans/lift", s.adminLiftBan).Name = "admin-lift-ban"
+	adminRoutes.GET("/stats", s.adminStats).Name = "admin-stats"
+	adminRoutes.GET("/impersonate", s.adminImpersonation).Name = "admin-impersonation"
+	adminRoutes.POST("/impersonate", s.startImpersonation).Name = "start-impersonation"
+	adminRoutes.POST("/impersonate/stop", s.stopImpersonation).Name = "stop-impersonation"
+	adminRoutes.GET("/maintenance", s.adminMaintenance).Name = "admin-maintenance"
+	adminRoutes.GET("/overlaps", s.adminOverlaps).Name = "admin-overlaps"
+	adminRoutes.POST("/maintenance", s.adminSetMaintenance).Name = "set-maintenance"
+	adminRoutes.GET("/templates", s.adminTemplates).Name = "admin-templates"
+	adminRoutes.POST("/templates", s.adminCheckTemplates).Name = "check-templates"
+	adminRoutes.POST("/reload", s.adminReload).Name = "reload-config"
+	adminRoutes.POST("/feedback/strip", s.adminStripFeedback).Name = "strip-feedback"
+	adminRoutes.GET("/param-stats", s.adminParamStats).Name = "admin-param-stats"
+	adminRoutes.POST("/param-stats", s.adminComputeParamStats).Name = "compute-param-stats"
+	adminRoutes.GET("/proposals", s.adminProposals).Name = "admin-proposals"
+	adminRoutes.POST("/proposals/status", s.adminSetProposalStatus).Name = "set-proposal-status"
 }
 
 func shouldReload(c echo.Context) error {
@@ -327,14 +695,17 @@ func (s *Server) buildPageContext(c echo.Context, title string) *pages.Context {
 	}
 
 	context := &pages.Context{
-		CurrentSection:   section,
-		NavigationLinks:  navigationLinks,
-		Title:            title,
-		OfficialInstance: s.options.OfficialInstance,
-		GreyLogo:         s.options.OfficialInstance && c.Request().Host != mainDomain,
-		HotReload:        s.options.HotReload,
-		RequestInfo:      c,
-		UserHasAccount:   auth.HasAccount(c),
+		CurrentSection:     section,
+		NavigationLinks:    navigationLinks,
+		Title:              title,
+		OfficialInstance:   s.options.OfficialInstance,
+		GreyLogo:           s.options.OfficialInstance && c.Request().Host != mainDomain,
+		HotReload:          s.options.HotReload,
+		RequestInfo:        c,
+		UserHasAccount:     auth.HasAccount(c),
+		UserIsAdmin:        auth.IsAdmin(c),
+		UserIsEphemeral:    auth.IsEphemeral(c),
+		UserIsImpersonated: auth.IsImpersonating(c),
 	}
 	if t, ok := c.Get(csrfLookup).(string); ok {
 		context.CSRFToken = t
@@ -342,10 +713,13 @@ func (s *Server) buildPageContext(c echo.Context, title string) *pages.Context {
 	if u := auth.GetUserId(c); u != "" {
 		context.UserID = u
 		context.UserLoggedIn = true
+	}
+	if context.UserLoggedIn && !context.UserIsEphemeral {
 		context.Preferences, _ = s.preferences.Get(c, context.UserID)
 		if context.Preferences != nil {
-			latest, _ := s.releases.GetLatestAt()
-			context.HasNews = latest.After(context.Preferences.NewsCursor)
+			context.UnreadNews = s.countUnreadNews(context.Preferences.NewsCursor) +
+				s.countProposalUpdates(c, context.UserID, context.Preferences.NewsCursor)
+			context.HasNews = context.UnreadNews > 0
 		}
 	}
 	return context
@@ -378,7 +752,3 @@ func concurrentRunOrPanic(tasks []func([]error)) {
 	}
 	fmt.Println(output.String())
 }
-
-func getEtag(c echo.Context) string {
-	return c.Request().Header.Get("If-None-Match")
-}
@@ -2,15 +2,21 @@ package server
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/labstack/echo/v4"
 	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/metrics"
 	"github.com/letsblockit/letsblockit/src/news"
 	"github.com/letsblockit/letsblockit/src/pages"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestServerDryRun(t *testing.T) {
@@ -20,10 +26,86 @@ func TestServerDryRun(t *testing.T) {
 		DatabaseUrl:   db.GetTestDatabaseURL(),
 		StatsdTarget:  "localhost:8125",
 		DryRun:        true,
+		AutoMigrate:   true,
 		HotReload:     true,
 	}).Start())
 }
 
+// startShutdownTestServer serves a slow handler, signaling when it is called
+func startShutdownTestServer(options *Options, delay time.Duration) (*Server, string, chan struct{}, context.CancelFunc, chan error) {
+	s := &Server{echo: echo.New(), options: options, statsd: &statsd.NoOpClient{}}
+	s.echo.HideBanner, s.echo.HidePort = true, true
+	started := make(chan struct{}, 1)
+	s.echo.GET(healthPath, s.healthCheck)
+	s.echo.GET("/slow", func(c echo.Context) error {
+		started <- struct{}{}
+		time.Sleep(delay)
+		return c.String(http.StatusOK, "done")
+	})
+
+	s.echo.Listener = httptest.NewUnstartedServer(nil).Listener
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.serve(ctx, func() error { return s.echo.Start("") }) }()
+	return s, "http://" + s.echo.Listener.Addr().String(), started, cancel, served
+}
+
+func getBody(target string) (string, error) {
+	resp, err := http.Get(target)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestShutdown_DrainsRequests(t *testing.T) {
+	s, target, started, stop, served := startShutdownTestServer(&Options{ShutdownTimeout: 5 * time.Second}, 200*time.Millisecond)
+	responses := make(chan string, 1)
+	go func() {
+		body, err := getBody(target + "/slow")
+		assert.NoError(t, err)
+		responses <- body
+	}()
+
+	<-started
+	stop()
+	require.NoError(t, <-served)
+	assert.Equal(t, "done", <-responses)
+	assert.True(t, s.shuttingDown.Load())
+	_, err := getBody(target + healthPath)
+	assert.Error(t, err, "new connections are refused")
+}
+
+func TestShutdown_Timeout(t *testing.T) {
+	_, target, started, stop, served := startShutdownTestServer(&Options{ShutdownTimeout: 50 * time.Millisecond}, time.Second)
+	go func() { _, _ = getBody(target + "/slow") }()
+
+	<-started
+	stop()
+	err := <-served
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "cannot drain requests")
+}
+
+func TestShutdown_HealthCheckFailsDuringDelay(t *testing.T) {
+	_, target, _, stop, served := startShutdownTestServer(&Options{
+		ShutdownDelay:   200 * time.Millisecond,
+		ShutdownTimeout: time.Second,
+	}, 0)
+	body, err := getBody(target + healthPath)
+	require.NoError(t, err)
+	assert.Equal(t, "OK", body)
+
+	stop()
+	require.Eventually(t, func() bool {
+		body, err = getBody(target + healthPath)
+		return err == nil && body == "shutting down"
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, <-served)
+}
+
 func (s *ServerTestSuite) TestHomepage_Anonymous() {
 	s.user = ""
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -88,6 +170,7 @@ func (s *ServerTestSuite) TestAbout_HasNews() {
 		UserLoggedIn: true,
 		Preferences:  pref,
 		HasNews:      true,
+		UnreadNews:   1,
 		HotReload:    true,
 	})
 	s.runRequest(req, assertOk)
@@ -148,3 +231,68 @@ func (s *ServerTestSuite) TestShouldReload_BadHost() {
 
 	s.Equal(404, rec.Code, rec.Body)
 }
+
+func (s *ServerTestSuite) TestFormRoutes_MissingCSRF() {
+	s.setUserAdmin()
+	for _, route := range s.server.echo.Routes() {
+		if route.Method != http.MethodPost || route.Name == "view-filter-render" {
+			continue
+		}
+		if strings.HasPrefix(route.Path, "/api/") {
+			continue // JSON API, only accepts JSON bodies
+		}
+		path := strings.ReplaceAll(route.Path, ":name", "filter2")
+		s.Run(path, func() {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader("confirm=on"))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			s.runRequest(req, func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, recorder.Result().StatusCode, recorder.Body)
+			})
+		})
+	}
+}
+
+func (s *ServerTestSuite) TestFormRoutes_WrongCSRF() {
+	f := buildFilter2CustomBody()
+	f.Add("__save", "")
+	f.Set(csrfLookup, "not-the-cookie-token")
+	req := httptest.NewRequest(http.MethodPost, "/filters/filter2", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.runRequest(req, func(t *testing.T, recorder *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, recorder.Result().StatusCode)
+	})
+	s.requireInstanceCount("filter2", 0)
+}
+
+func (s *ServerTestSuite) TestPrometheusMetrics() {
+	s.server.prometheus = metrics.NewRegistry()
+	s.server.echo = echo.New()
+	s.server.echo.Use(buildDogstatsMiddleware(s.server.prometheus, s.server.echo))
+	s.server.setupRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	s.expectRender("landing", nil)
+	s.runRequest(req, assertOk)
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.Contains(t, rec.Body.String(),
+			`letsblockit_request_count_total{logged="true",method="GET",route="/",status="200"} 1`)
+		assert.Contains(t, rec.Body.String(),
+			`letsblockit_request_duration_seconds_count{logged="true",method="GET",route="/"} 1`)
+		assert.NotContains(t, rec.Body.String(), `route="/metrics"`)
+	})
+}
+
+func (s *ServerTestSuite) TestPrometheusMetrics_SeparateAddress() {
+	s.server.prometheus = metrics.NewRegistry()
+	s.server.options.PrometheusAddress = "127.0.0.1:9102"
+	s.server.echo = echo.New()
+	s.server.setupRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+const sessionDateFormat = "2006-01-02 15:04 MST"
+
+// sessionInfo holds the session information displayed in the account page
+type sessionInfo struct {
+	UserAgent  string
+	CreatedAt  string
+	LastSeenAt string
+	Current    bool
+}
+
+// checkSession records the auth sessions, and asks users to log out of sessions they revoked.
+// The logout action is allowed through, to let them end the session at the identity provider.
+func (s *Server) checkSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, session := auth.GetUserId(c), auth.GetSessionId(c)
+		if user == "" || session == "" {
+			return next(c)
+		}
+		revoked, err := s.sessions.IsRevoked(c.Request().Context(), user, session, c.Request().UserAgent())
+		if err != nil {
+			return err
+		}
+		if !revoked || (c.Path() == "/user/action/:type" && c.Param("type") == "logout") {
+			return next(c)
+		}
+		s.securityLog.log(c, eventRevokedSession)
+		hc := s.buildPageContext(c, "Session logged out")
+		hc.NoBoost = true
+		return s.pages.Render(c, "session-revoked", hc)
+	}
+}
+
+// revokeOtherSessions logs the user out of all their sessions, except the current one
+func (s *Server) revokeOtherSessions(c echo.Context) error {
+	user, session := auth.GetUserId(c), auth.GetSessionId(c)
+	if user == "" || session == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "sessions are not supported by this authentication method")
+	}
+	count, err := s.sessions.RevokeOthers(c.Request().Context(), user, session)
+	if err != nil {
+		return err
+	}
+	_ = s.statsd.Incr("letsblockit.sessions_revoked", nil, float64(count))
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("user-account"))
+}
+
+func getSessions(ctx context.Context, q db.Querier, user, current string) ([]sessionInfo, error) {
+	stored, err := q.GetSessionsForUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]sessionInfo, 0, len(stored))
+	for _, s := range stored {
+		sessions = append(sessions, sessionInfo{
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt.Format(sessionDateFormat),
+			LastSeenAt: s.LastSeenAt.Format(sessionDateFormat),
+			Current:    s.SessionID == current,
+		})
+	}
+	return sessions, nil
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+)
+
+const firefoxUserAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/111.0"
+
+func (s *ServerTestSuite) TestSessions_LogOutEverywhere() {
+	otherSession, currentSession := uuid.NewString(), uuid.NewString()
+
+	// Visit with two sessions to record them
+	for _, session := range []string{otherSession, currentSession} {
+		s.session = session
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("User-Agent", firefoxUserAgent)
+		s.expectRender("landing", nil)
+		s.runRequest(req, assertOk)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/user/account", nil)
+	s.expectP.Render(gomock.Any(), "user-account", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			sessions, _ := hc.Data["sessions"].([]sessionInfo)
+			if assert.Len(s.T(), sessions, 2) {
+				assert.Equal(s.T(), "Firefox on Linux", sessions[0].UserAgent)
+				assert.True(s.T(), sessions[0].Current)
+				assert.False(s.T(), sessions[1].Current)
+			}
+			return nil
+		})
+	s.runRequest(req, assertOk)
+
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	req = httptest.NewRequest(http.MethodPost, "/user/sessions/revoke", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/account")
+	s.runRequest(req, assertOk)
+
+	// The current session still works
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	s.expectRender("landing", nil)
+	s.runRequest(req, assertOk)
+
+	// The other session is asked to log out
+	s.session = otherSession
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	s.expectRender("session-revoked", nil)
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestSessions_NotSupported() {
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/sessions/revoke", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
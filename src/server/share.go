@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// Share tokens give a read-only access to a list: they can be used instead of the list token to subscribe
+// to it, or to view its configuration. They are revoked independently of the list token.
+const (
+	shareTokensPerList = 5
+	shareTokenMaxLabel = 64
+)
+
+// shareTokenInfo holds the share token information displayed in the user account page
+type shareTokenInfo struct {
+	ID        int32
+	Label     string
+	Token     string
+	ListURL   string
+	CreatedAt string
+}
+
+// sharedInstance holds a filter instance displayed in the read-only list view
+type sharedInstance struct {
+	Template string
+	Title    string
+	Known    bool
+	TestMode bool
+	Params   []sharedParam
+}
+
+type sharedParam struct {
+	Description string
+	Value       string
+}
+
+func (s *Server) getShareTokens(ctx context.Context, c echo.Context, q db.Querier, user string) ([]shareTokenInfo, error) {
+	stored, err := q.GetShareTokensForUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]shareTokenInfo, 0, len(stored))
+	for _, t := range stored {
+		tokens = append(tokens, shareTokenInfo{
+			ID:        t.ID,
+			Label:     t.Label,
+			Token:     t.Token.String(),
+			ListURL:   s.listURL(c, t.Token),
+			CreatedAt: t.CreatedAt.Format(apiTokenDateFormat),
+		})
+	}
+	return tokens, nil
+}
+
+// createShareToken adds a share token to the user's list, up to shareTokensPerList
+func (s *Server) createShareToken(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return errors.New("invalid user session")
+	}
+	label := strings.TrimSpace(c.FormValue("label"))
+	if label == "" || len(label) > shareTokenMaxLabel {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid arguments")
+	}
+
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		count, err := q.CountShareTokensForUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		if count >= shareTokensPerList {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"you can create up to "+strconv.Itoa(shareTokensPerList)+" share links, revoke one first")
+		}
+		_, err = q.CreateShareToken(ctx, db.CreateShareTokenParams{
+			Label:  label,
+			UserID: user,
+		})
+		if err == db.NotFound {
+			return echo.NewHTTPError(http.StatusBadRequest, "you need a filter list to share it")
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("user-account"))
+}
+
+// revokeShareToken deletes a share token, the list token and other share tokens are kept
+func (s *Server) revokeShareToken(c echo.Context) error {
+	id, err := strconv.ParseInt(c.FormValue("id"), 10, 32)
+	user := auth.GetUserId(c)
+	if user == "" || err != nil {
+		return errors.New("invalid arguments")
+	}
+	if err = s.store.DeleteShareToken(c.Request().Context(), db.DeleteShareTokenParams{
+		ID:     int32(id),
+		UserID: user,
+	}); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("user-account"))
+}
+
+// viewSharedList renders a read-only view of the list configuration, for holders of a share token
+func (s *Server) viewSharedList(c echo.Context) error {
+	token, err := uuid.Parse(c.Param("token"))
+	if err != nil {
+		return echo.ErrNotFound
+	}
+
+	var label string
+	var storedInstances []db.GetInstancesForListRow
+	if err = s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		list, e := q.GetListForShareToken(ctx, token)
+		switch {
+		case e == db.NotFound:
+			return echo.ErrNotFound
+		case e != nil:
+			return e
+		case s.bans.IsBanned(list.UserID):
+			return echo.ErrNotFound
+		}
+		label = list.Label
+		storedInstances, e = q.GetInstancesForList(ctx, list.ID)
+		return e
+	}); err != nil {
+		return err
+	}
+
+	list, err := convertFilterList(filters.DefaultListTitle, storedInstances)
+	if err != nil {
+		return fmt.Errorf("failed to convert list: %w", err)
+	}
+	instances := make([]sharedInstance, 0, len(list.Instances))
+	for _, instance := range list.Instances {
+		instances = append(instances, s.buildSharedInstance(instance))
+	}
+
+	hc := s.buildPageContext(c, "Shared filter list")
+	hc.Add("share_label", label)
+	hc.Add("instances", instances)
+	hc.Add("list_url", s.listURL(c, token))
+	return s.pages.Render(c, "view-shared-list", hc)
+}
+
+// buildSharedInstance lists the instance parameters in the template order, with their description
+func (s *Server) buildSharedInstance(instance *filters.Instance) sharedInstance {
+	shared := sharedInstance{
+		Template: instance.Template,
+		Title:    instance.Template,
+		TestMode: instance.TestMode,
+	}
+	template, err := s.config().filters.Get(instance.Template)
+	if err != nil {
+		return shared
+	}
+	shared.Title, shared.Known = template.Title, true
+	for _, p := range template.Params {
+		value, found := instance.Params[p.Name]
+		if !found {
+			continue
+		}
+		shared.Params = append(shared.Params, sharedParam{
+			Description: p.Description,
+			Value:       formatSharedParam(value),
+		})
+	}
+	return shared
+}
+
+func formatSharedParam(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "yes"
+		}
+		return "no"
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		return strings.Join(values, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatSharedParam(t *testing.T) {
+	assert.Equal(t, "yes", formatSharedParam(true))
+	assert.Equal(t, "no", formatSharedParam(false))
+	assert.Equal(t, "blep", formatSharedParam("blep"))
+	assert.Equal(t, "one, two", formatSharedParam([]interface{}{"one", "two"}))
+}
+
+func (s *ServerTestSuite) createShareToken(label string) *http.Request {
+	f := make(url.Values)
+	f.Add("label", label)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/share-tokens", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) TestShareToken_Lifecycle() {
+	listToken, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   filter2Custom,
+	}))
+
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/account")
+	s.runRequest(s.createShareToken("my friend"), assertOk)
+	tokens, err := s.store.GetShareTokensForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), tokens, 1)
+	assert.Equal(s.T(), "my friend", tokens[0].Label)
+	shareToken := tokens[0].Token.String()
+
+	// The share token can be used to download the list
+	req := httptest.NewRequest(http.MethodGet, "http://my.do.main/list/"+shareToken, nil)
+	rec := httptest.NewRecorder()
+	s.server.echo.ServeHTTP(rec, req)
+	s.Equal(200, rec.Code)
+	s.Contains(rec.Body.String(), filter2CustomOutput)
+
+	// And to view the list configuration
+	req = httptest.NewRequest(http.MethodGet, "http://my.do.main/shared/"+shareToken, nil)
+	s.expectRender("view-shared-list", pages.ContextData{
+		"share_label": "my friend",
+		"instances": []sharedInstance{{
+			Template: "filter2",
+			Title:    "Second filter",
+			Known:    true,
+			Params: []sharedParam{
+				{Value: "blep"},
+				{Value: "yes"},
+				{Value: "one, two"},
+			},
+		}},
+		"list_url": "http://my.do.main/list/" + shareToken + ".txt",
+	})
+	s.runRequest(req, assertOk)
+
+	// The list token cannot open the read-only view
+	req = httptest.NewRequest(http.MethodGet, "/shared/"+listToken.String(), nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	// Revoking the share token keeps the list token working
+	f := make(url.Values)
+	f.Add("id", strconv.Itoa(int(tokens[0].ID)))
+	f.Add(csrfLookup, s.csrf)
+	req = httptest.NewRequest(http.MethodPost, "/user/share-tokens/revoke", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/account")
+	s.runRequest(req, assertOk)
+
+	for path, code := range map[string]int{
+		"/list/" + shareToken:             http.StatusNotFound,
+		"/shared/" + shareToken:           http.StatusNotFound,
+		"/list/" + listToken.String():     http.StatusOK,
+		"/api/list/" + listToken.String(): http.StatusOK,
+	} {
+		rec = httptest.NewRecorder()
+		s.server.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		s.Equal(code, rec.Code, path)
+	}
+}
+
+func (s *ServerTestSuite) TestShareToken_Limit() {
+	_, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	for i := 0; i < shareTokensPerList; i++ {
+		_, err = s.store.CreateShareToken(context.Background(), db.CreateShareTokenParams{
+			Label:  "token " + strconv.Itoa(i),
+			UserID: s.user,
+		})
+		require.NoError(s.T(), err)
+	}
+	s.runRequest(s.createShareToken("one more"), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestShareToken_RevokeOtherUser() {
+	_, err := s.store.CreateListForUser(context.Background(), "other")
+	require.NoError(s.T(), err)
+	token, err := s.store.CreateShareToken(context.Background(), db.CreateShareTokenParams{
+		Label:  "not yours",
+		UserID: "other",
+	})
+	require.NoError(s.T(), err)
+	tokens, err := s.store.GetShareTokensForUser(context.Background(), "other")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), tokens, 1)
+
+	f := make(url.Values)
+	f.Add("id", strconv.Itoa(int(tokens[0].ID)))
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/share-tokens/revoke", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/account")
+	s.runRequest(req, assertOk)
+
+	list, err := s.store.GetListForShareToken(context.Background(), token)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "other", list.UserID)
+}
+
+func (s *ServerTestSuite) TestViewSharedList_Unknown() {
+	req := httptest.NewRequest(http.MethodGet, "/shared/"+uuid.NewString(), nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+)
+
+const sitemapMaxAge = time.Hour
+
+// sitemapEntry is a page listed in the sitemap, lastmod is zero if unknown
+type sitemapEntry struct {
+	path    string
+	lastmod time.Time
+}
+
+// sitemap lists the template, catalog and help pages, built when the templates are loaded.
+// Pages holding list tokens are never listed.
+type sitemap struct {
+	entries []sitemapEntry
+	updated time.Time
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// loadSitemap builds the sitemap of the templates, dated from their latest recorded version
+func (s *Server) loadSitemap(ctx context.Context, repo *filters.Repository) error {
+	versions, err := s.store.GetLatestTemplateVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot get the template versions: %w", err)
+	}
+	s.sitemap.Store(buildSitemap(repo, versions))
+	return nil
+}
+
+func buildSitemap(repo *filters.Repository, versions []db.GetLatestTemplateVersionsRow) *sitemap {
+	dates := make(map[string]time.Time, len(versions))
+	for _, v := range versions {
+		if repo.Hash(v.TemplateName) == v.TemplateHash {
+			dates[v.TemplateName] = v.CreatedAt
+		}
+	}
+
+	var templates []sitemapEntry
+	tags := make(map[string]time.Time)
+	var updated time.Time
+	for _, tpl := range repo.GetAll() {
+		lastmod := dates[tpl.Name]
+		templates = append(templates, sitemapEntry{path: "/filters/" + tpl.Name, lastmod: lastmod})
+		for _, tag := range tpl.Tags {
+			if lastmod.After(tags[tag]) {
+				tags[tag] = lastmod
+			}
+		}
+		if lastmod.After(updated) {
+			updated = lastmod
+		}
+	}
+
+	entries := []sitemapEntry{{path: "/"}, {path: "/filters", lastmod: updated}}
+	for _, tag := range repo.GetTags() {
+		entries = append(entries, sitemapEntry{path: "/filters/tag/" + tag, lastmod: tags[tag]})
+	}
+	entries = append(entries, templates...)
+	entries = append(entries, sitemapEntry{path: "/help"})
+	for _, section := range helpMenu {
+		for _, page := range section.Pages {
+			entries = append(entries, sitemapEntry{path: "/help/" + page.Code})
+		}
+	}
+	return &sitemap{entries: entries, updated: updated}
+}
+
+// render returns the sitemap with absolute URLs on the given scheme and host
+func (m *sitemap) render(scheme, host string) ([]byte, error) {
+	set := sitemapURLSet{URLs: make([]sitemapURL, 0, len(m.entries))}
+	for _, e := range m.entries {
+		loc := url.URL{Scheme: scheme, Host: host, Path: e.path}
+		u := sitemapURL{Loc: loc.String()}
+		if !e.lastmod.IsZero() {
+			u.LastMod = e.lastmod.UTC().Format("2006-01-02")
+		}
+		set.URLs = append(set.URLs, u)
+	}
+	body, err := xml.MarshalIndent(&set, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("cannot render the sitemap: %w", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// sitemapXml serves the sitemap of the web UI, the list download domain only serves lists
+func (s *Server) sitemapXml(c echo.Context) error {
+	m := s.sitemap.Load()
+	if m == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "the sitemap is not available")
+	}
+	if domain := s.config().options.ListDownloadDomain; domain != "" && stripPort(s.requestHost(c)) == domain {
+		return echo.ErrNotFound
+	}
+	body, err := m.render(c.Scheme(), s.publicHost(c))
+	if err != nil {
+		return err
+	}
+	hasher := fnv.New64()
+	_, _ = hasher.Write(body)
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age="+strconv.Itoa(int(sitemapMaxAge.Seconds())))
+	return serveConditional(c, strconv.FormatUint(hasher.Sum64(), 36), m.updated, "application/xml", body)
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSitemap(t *testing.T) {
+	repo, err := filters.Load(testTemplates, testTemplates)
+	require.NoError(t, err)
+	m := buildSitemap(repo, []db.GetLatestTemplateVersionsRow{{
+		TemplateName: "filter1",
+		TemplateHash: repo.Hash("filter1"),
+		CreatedAt:    fixedNow,
+	}, {
+		TemplateName: "filter2",
+		TemplateHash: "outdated",
+		CreatedAt:    fixedNow.Add(time.Hour),
+	}, {
+		TemplateName: "removed",
+		TemplateHash: "abcd",
+		CreatedAt:    fixedNow.Add(2 * time.Hour),
+	}})
+	assert.Equal(t, fixedNow, m.updated, "outdated and removed versions are ignored")
+	assert.Contains(t, m.entries, sitemapEntry{path: "/filters", lastmod: fixedNow})
+	assert.Contains(t, m.entries, sitemapEntry{path: "/filters/filter1", lastmod: fixedNow})
+	assert.Contains(t, m.entries, sitemapEntry{path: "/filters/filter2"})
+	assert.Contains(t, m.entries, sitemapEntry{path: "/filters/tag/tag2", lastmod: fixedNow})
+	assert.Contains(t, m.entries, sitemapEntry{path: "/filters/tag/tag3"})
+	assert.Contains(t, m.entries, sitemapEntry{path: "/help/api"})
+	for _, e := range m.entries {
+		assert.NotContains(t, e.path, "removed")
+	}
+
+	body, err := m.render("https", "letsblock.it")
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	assert.Contains(t, string(body), "<url>\n\t\t<loc>https://letsblock.it/filters/filter1</loc>\n\t\t<lastmod>2020-06-02</lastmod>\n\t</url>")
+	assert.Contains(t, string(body), "<url>\n\t\t<loc>https://letsblock.it/filters/filter2</loc>\n\t</url>")
+	assert.NotContains(t, string(body), "/list/")
+	assert.NotContains(t, string(body), "/export/")
+}
+
+func TestSitemapXml(t *testing.T) {
+	s := &Server{options: &Options{ListDownloadDomain: "get.letsblock.it"}}
+	e := echo.New()
+	e.GET("/sitemap.xml", s.sitemapXml)
+	get := func(host, header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil)
+		req.Host = host
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, get("letsblock.it", "", "").Code)
+
+	s.sitemap.Store(&sitemap{
+		entries: []sitemapEntry{{path: "/filters", lastmod: fixedNow}, {path: "/help"}},
+		updated: fixedNow,
+	})
+	rec := get("letsblock.it", "", "")
+	assertOk(t, rec)
+	assert.Equal(t, "application/xml", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "public, max-age=3600", rec.Header().Get(echo.HeaderCacheControl))
+	assert.Equal(t, fixedNow.Format(http.TimeFormat), rec.Header().Get(echo.HeaderLastModified))
+	assert.Contains(t, rec.Body.String(), "<loc>http://letsblock.it/help</loc>")
+	etag := rec.Header().Get("Etag")
+	assert.NotEmpty(t, etag)
+
+	assert.Equal(t, http.StatusNotModified, get("letsblock.it", "If-None-Match", etag).Code)
+	other := get("other.example.com", "If-None-Match", etag)
+	assert.Equal(t, http.StatusOK, other.Code, "the etag depends on the host")
+	assert.Contains(t, other.Body.String(), "<loc>http://other.example.com/help</loc>")
+	assert.Equal(t, http.StatusNotModified, get("letsblock.it", echo.HeaderIfModifiedSince, fixedNow.Format(http.TimeFormat)).Code)
+	assert.Equal(t, http.StatusNotFound, get("get.letsblock.it", "", "").Code, "the download domain only serves lists")
+}
+
+func (s *ServerTestSuite) TestSitemap_TemplateVersions() {
+	ctx := context.Background()
+	require.NoError(s.T(), s.server.loadTemplateFeed(ctx, filterRepo))
+	require.NoError(s.T(), s.server.loadSitemap(ctx, filterRepo))
+	m := s.server.sitemap.Load()
+	require.NotNil(s.T(), m)
+	assert.False(s.T(), m.updated.IsZero(), "templates are dated from their baseline version")
+	assert.Contains(s.T(), m.entries, sitemapEntry{path: "/filters/filter1", lastmod: m.updated})
+}
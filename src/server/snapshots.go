@@ -0,0 +1,236 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+	"gopkg.in/yaml.v3"
+)
+
+// Snapshots store a frozen copy of the list, in the export format, to roll back parameter changes.
+// Restoring a snapshot first takes an automatic snapshot, that replaces the previous automatic one.
+const (
+	snapshotsPerUser       = 10
+	snapshotMaxName        = 64
+	preRestoreSnapshotName = "pre-restore"
+)
+
+// snapshotInfo holds the snapshot information displayed in the snapshots page
+type snapshotInfo struct {
+	ID        int32
+	Name      string
+	Filters   int32
+	Automatic bool
+	CreatedAt string
+}
+
+// takeSnapshot stores the current instances of the user
+func takeSnapshot(ctx context.Context, q db.Querier, user, name string, automatic bool) error {
+	stored, err := q.GetInstancesForUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	rows := make([]db.GetInstancesForListRow, 0, len(stored))
+	for _, i := range stored {
+		rows = append(rows, db.GetInstancesForListRow(i))
+	}
+	list, err := convertFilterList(filters.DefaultListTitle, rows)
+	if err != nil {
+		return err
+	}
+	contents, err := yaml.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return q.CreateSnapshot(ctx, db.CreateSnapshotParams{
+		UserID:        user,
+		Name:          name,
+		Contents:      string(contents),
+		InstanceCount: int32(len(list.Instances)),
+		Automatic:     automatic,
+	})
+}
+
+// restoreInstances replaces the instances of the user with the snapshot contents.
+// It returns the webhook event to send for every changed template.
+func restoreInstances(ctx context.Context, q db.Querier, user string, list *filters.List) (map[string]string, error) {
+	current, err := q.GetInstancesForUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	events := make(map[string]string, len(current)+len(list.Instances))
+	for _, i := range current {
+		events[i.TemplateName] = webhookInstanceDeleted
+	}
+	if err = q.DeleteInstancesForUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	for _, instance := range list.Instances {
+		params := pgtype.JSONB{Status: pgtype.Null}
+		if len(instance.Params) > 0 {
+			if err = params.Set(&instance.Params); err != nil {
+				return nil, err
+			}
+		}
+		if err = q.CreateInstance(ctx, db.CreateInstanceParams{
+			UserID:       user,
+			TemplateName: instance.Template,
+			Params:       params,
+			TestMode:     instance.TestMode,
+		}); err != nil {
+			return nil, err
+		}
+		if _, found := events[instance.Template]; found {
+			events[instance.Template] = webhookInstanceUpdated
+		} else {
+			events[instance.Template] = webhookInstanceCreated
+		}
+	}
+	return events, nil
+}
+
+func parseSnapshotID(c echo.Context) (int32, error) {
+	id, err := strconv.ParseInt(c.FormValue("id"), 10, 32)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid arguments")
+	}
+	return int32(id), nil
+}
+
+func (s *Server) listSnapshots(c echo.Context) error {
+	hc := s.buildPageContext(c, "My snapshots")
+	hc.NoBoost = true
+	stored, err := s.store.GetSnapshotsForUser(c.Request().Context(), hc.UserID)
+	if err != nil {
+		return err
+	}
+	snapshots := make([]snapshotInfo, 0, len(stored))
+	manual := 0
+	for _, snapshot := range stored {
+		snapshots = append(snapshots, snapshotInfo{
+			ID:        snapshot.ID,
+			Name:      snapshot.Name,
+			Filters:   snapshot.InstanceCount,
+			Automatic: snapshot.Automatic,
+			CreatedAt: snapshot.CreatedAt.Format(time.RFC1123),
+		})
+		if !snapshot.Automatic {
+			manual++
+		}
+	}
+	hc.Add("snapshots", snapshots)
+	hc.Add("can_snapshot", manual < snapshotsPerUser)
+	return s.pages.Render(c, "user-snapshots", hc)
+}
+
+// createSnapshot stores a snapshot of the user's list, up to snapshotsPerUser
+func (s *Server) createSnapshot(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return errors.New("invalid user session")
+	}
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" || len(name) > snapshotMaxName {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid arguments")
+	}
+
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		count, err := q.CountSnapshotsForUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		if count >= snapshotsPerUser {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"you can keep up to "+strconv.Itoa(snapshotsPerUser)+" snapshots, delete one first")
+		}
+		return takeSnapshot(ctx, q, user, name, false)
+	}); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("list-snapshots"))
+}
+
+func (s *Server) deleteSnapshot(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return errors.New("invalid user session")
+	}
+	id, err := parseSnapshotID(c)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.store.DeleteSnapshot(c.Request().Context(), db.DeleteSnapshotParams{
+		ID:     id,
+		UserID: user,
+	})
+	switch {
+	case err != nil:
+		return err
+	case deleted == 0:
+		return echo.ErrNotFound
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("list-snapshots"))
+}
+
+// restoreSnapshot replaces the user's filters with the snapshot contents, after taking a pre-restore snapshot
+func (s *Server) restoreSnapshot(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return errors.New("invalid user session")
+	}
+	id, err := parseSnapshotID(c)
+	if err != nil {
+		return err
+	}
+
+	var events map[string]string
+	if err = s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		snapshot, err := q.GetSnapshot(ctx, db.GetSnapshotParams{ID: id, UserID: user})
+		switch {
+		case err == db.NotFound:
+			return echo.ErrNotFound
+		case err != nil:
+			return err
+		}
+		var list filters.List
+		if err = yaml.Unmarshal([]byte(snapshot.Contents), &list); err != nil {
+			return fmt.Errorf("failed to parse snapshot %d: %w", id, err)
+		}
+
+		// The snapshot is loaded before the previous automatic snapshot is replaced, as it can be the one restored
+		if err = q.DeleteAutomaticSnapshots(ctx, user); err != nil {
+			return err
+		}
+		if err = takeSnapshot(ctx, q, user, preRestoreSnapshotName, true); err != nil {
+			return err
+		}
+		if count, err := q.CountListsForUser(ctx, user); err != nil {
+			return err
+		} else if count == 0 {
+			if _, err = q.CreateListForUser(ctx, user); err != nil {
+				return err
+			}
+		}
+		events, err = restoreInstances(ctx, q, user, &list)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	for template, event := range events {
+		s.notifyListChange(user, event, template)
+	}
+	_ = s.statsd.Incr("letsblockit.snapshot_restored", nil, 1)
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("list-snapshots"))
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *ServerTestSuite) snapshotAction(path string, values map[string]string) *http.Request {
+	f := make(url.Values)
+	for k, v := range values {
+		f.Add(k, v)
+	}
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) TestSnapshots_Restore() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   filter2Custom,
+	}))
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/snapshots")
+	s.runRequest(s.snapshotAction("/user/snapshots", map[string]string{"name": "before"}), assertOk)
+
+	// Change the list after the snapshot
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   map[string]interface{}{"one": "changed"},
+	}))
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter1",
+		TestMode: true,
+	}))
+
+	snapshots, err := s.store.GetSnapshotsForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), snapshots, 1)
+	assert.Equal(s.T(), int32(1), snapshots[0].InstanceCount)
+
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/snapshots")
+	s.runRequest(s.snapshotAction("/user/snapshots/restore", map[string]string{
+		"id": strconv.Itoa(int(snapshots[0].ID)),
+	}), assertOk)
+
+	instances, err := s.store.GetInstancesForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), instances, 1)
+	assert.Equal(s.T(), "filter2", instances[0].TemplateName)
+	var params map[string]interface{}
+	require.NoError(s.T(), instances[0].Params.AssignTo(&params))
+	assert.Equal(s.T(), "blep", params["one"])
+	assert.Equal(s.T(), []interface{}{"one", "two"}, params["three"])
+
+	// The previous filters are kept in an automatic snapshot
+	snapshots, err = s.store.GetSnapshotsForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), snapshots, 2)
+	var automatic *db.GetSnapshotsForUserRow
+	for i := range snapshots {
+		if snapshots[i].Automatic {
+			automatic = &snapshots[i]
+		}
+	}
+	require.NotNil(s.T(), automatic)
+	assert.Equal(s.T(), preRestoreSnapshotName, automatic.Name)
+	assert.Equal(s.T(), int32(2), automatic.InstanceCount)
+
+	// Restoring the automatic snapshot replaces it with a new one
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/snapshots")
+	s.runRequest(s.snapshotAction("/user/snapshots/restore", map[string]string{
+		"id": strconv.Itoa(int(automatic.ID)),
+	}), assertOk)
+	instances, err = s.store.GetInstancesForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), instances, 2)
+	snapshots, err = s.store.GetSnapshotsForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), snapshots, 2)
+}
+
+func (s *ServerTestSuite) TestSnapshots_Limit() {
+	for i := 0; i < snapshotsPerUser; i++ {
+		require.NoError(s.T(), takeSnapshot(context.Background(), s.store, s.user, "snapshot "+strconv.Itoa(i), false))
+	}
+	require.NoError(s.T(), takeSnapshot(context.Background(), s.store, s.user, preRestoreSnapshotName, true))
+	s.runRequest(s.snapshotAction("/user/snapshots", map[string]string{"name": "one more"}),
+		func(t *testing.T, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+}
+
+func (s *ServerTestSuite) TestSnapshots_Page() {
+	require.NoError(s.T(), takeSnapshot(context.Background(), s.store, s.user, "empty", false))
+	snapshots, err := s.store.GetSnapshotsForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), snapshots, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/user/snapshots", nil)
+	s.expectRender("user-snapshots", pages.ContextData{
+		"snapshots": []snapshotInfo{{
+			ID:        snapshots[0].ID,
+			Name:      "empty",
+			CreatedAt: snapshots[0].CreatedAt.Format(time.RFC1123),
+		}},
+		"can_snapshot": true,
+	})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestSnapshots_OtherUser() {
+	require.NoError(s.T(), takeSnapshot(context.Background(), s.store, "other", "not yours", false))
+	snapshots, err := s.store.GetSnapshotsForUser(context.Background(), "other")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), snapshots, 1)
+	id := strconv.Itoa(int(snapshots[0].ID))
+
+	for _, path := range []string{"/user/snapshots/restore", "/user/snapshots/delete"} {
+		s.runRequest(s.snapshotAction(path, map[string]string{"id": id}), func(t *testing.T, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusNotFound, rec.Code, path)
+		})
+	}
+}
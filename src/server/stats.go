@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+)
+
+const (
+	statsCacheKey = "stats"
+	statsCacheTTL = time.Minute
+)
+
+// instanceStats holds the aggregated usage numbers of the instance
+type instanceStats struct {
+	Users                int64            `json:"users"`
+	Lists                int64            `json:"lists"`
+	Instances            int64            `json:"instances"`
+	ListsDownloadedDaily int64            `json:"lists_downloaded_24h"`
+	ListsNeverDownloaded int64            `json:"lists_never_downloaded"`
+	InstancesPerTemplate map[string]int64 `json:"instances_per_template"`
+	CollectedAt          time.Time        `json:"collected_at"`
+	MalformedTokens      int64            `json:"malformed_list_tokens"` // Since startup, not cached
+	UnknownTokens        int64            `json:"unknown_list_tokens"`   // Since startup, not cached
+}
+
+func loadInstanceStats(ctx context.Context, q db.Querier, now time.Time) (*instanceStats, error) {
+	totals, err := q.GetStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := q.GetInstanceStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := &instanceStats{
+		Users:                totals.UsersTotal,
+		Lists:                totals.ListsTotal,
+		Instances:            totals.InstancesTotal,
+		ListsDownloadedDaily: totals.ListsDaily,
+		ListsNeverDownloaded: totals.ListsTotal - totals.ListsActive,
+		InstancesPerTemplate: make(map[string]int64, len(templates)),
+		CollectedAt:          now,
+	}
+	for _, t := range templates {
+		stats.InstancesPerTemplate[t.TemplateName] = t.Total
+	}
+	return stats, nil
+}
+
+// adminStats returns the instance statistics as JSON, cached for a minute to protect the database.
+// The invalid list token counts are read from memory, and always up-to-date.
+func (s *Server) adminStats(c echo.Context) error {
+	stats, found := s.statsCache.Get(statsCacheKey)
+	if !found {
+		var err error
+		if stats, err = loadInstanceStats(c.Request().Context(), s.store, s.now()); err != nil {
+			return err
+		}
+		s.statsCache.Set(statsCacheKey, stats)
+	}
+	response := *stats
+	response.MalformedTokens, response.UnknownTokens = s.tokenScans.counts()
+	return c.JSON(http.StatusOK, &response)
+}
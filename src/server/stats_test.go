@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *ServerTestSuite) TestAdminStats_NotAdmin() {
+	req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestAdminStats_OK() {
+	s.setUserAdmin()
+	s.addInstance(s.user, "filter1", nil)
+	s.addInstance(s.user, "filter2", nil)
+	s.addInstance("other-user", "filter2", nil)
+	s.markListDownloaded()
+
+	getStats := func() instanceStats {
+		var stats instanceStats
+		req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+		s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+			assertOk(t, rec)
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+		})
+		return stats
+	}
+
+	stats := getStats()
+	s.EqualValues(1, stats.Users) // Only s.user has preferences
+	s.EqualValues(2, stats.Lists)
+	s.EqualValues(3, stats.Instances)
+	s.EqualValues(1, stats.ListsDownloadedDaily)
+	s.EqualValues(1, stats.ListsNeverDownloaded)
+	s.Equal(map[string]int64{"filter1": 1, "filter2": 2}, stats.InstancesPerTemplate)
+
+	// Results are cached
+	s.addInstance("other-user", "filter1", nil)
+	s.EqualValues(3, getStats().Instances)
+}
+
+func (s *ServerTestSuite) TestAdminStats_InvalidTokens() {
+	s.server.tokenScans = newTokenScanDetector(0, time.Minute, s.server.now, nil)
+	s.setUserAdmin()
+	for _, path := range []string{"/list/not-a-token", "/list/" + uuid.NewString(), "/list/" + uuid.NewString() + ".txt"} {
+		s.runRequest(httptest.NewRequest(http.MethodGet, path, nil), func(t *testing.T, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		var stats instanceStats
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+		assert.EqualValues(t, 1, stats.MalformedTokens)
+		assert.EqualValues(t, 2, stats.UnknownTokens)
+	})
+}
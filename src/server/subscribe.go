@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+)
+
+// subscribeTitle is the name adblockers show for the list, matching the title rendered in it
+func subscribeTitle(title string) string {
+	return "letsblock.it - " + title
+}
+
+// subscribeLink tells how to install a list in an adblocker: with a one-click link if it supports one,
+// or by copying the list URL
+type subscribeLink struct {
+	Adblocker    string `json:"adblocker"`
+	Href         string `json:"href,omitempty"`
+	Instructions string `json:"instructions"`
+}
+
+// subscribeLinks is returned by the subscribe links endpoint, for the web UI and the CLI
+type subscribeLinks struct {
+	ListURL string          `json:"list_url"`
+	Title   string          `json:"title"`
+	Links   []subscribeLink `json:"links"`
+}
+
+// subscribeHref builds a subscription link with the given scheme, like abp:subscribe?location=...&title=...
+func subscribeHref(scheme, listUrl, title string) string {
+	return scheme + "?location=" + queryEscape(listUrl) + "&title=" + queryEscape(title)
+}
+
+// queryEscape escapes spaces as %20: adblockers decode the parameters with decodeURIComponent,
+// that leaves the + signs of url.QueryEscape in the title
+func queryEscape(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+// buildSubscribeLinks lists the ways to install a list. uBlock Origin has no scheme of its own,
+// it handles the abp:subscribe links.
+func buildSubscribeLinks(listUrl, title string) *subscribeLinks {
+	title = subscribeTitle(title)
+	abp := subscribeHref("abp:subscribe", listUrl, title)
+	return &subscribeLinks{
+		ListURL: listUrl,
+		Title:   title,
+		Links: []subscribeLink{{
+			Adblocker:    "ublock-origin",
+			Href:         abp,
+			Instructions: "Click the link, then confirm the subscription in the uBlock Origin tab that opens.",
+		}, {
+			Adblocker:    "adblock-plus",
+			Href:         abp,
+			Instructions: "Click the link, then confirm the subscription in the Adblock Plus dialog.",
+		}, {
+			Adblocker:    "adguard",
+			Href:         subscribeHref("adguard:subscribe", listUrl, title),
+			Instructions: "Click the link, then confirm the subscription in the AdGuard dialog.",
+		}, {
+			Adblocker:    "other",
+			Instructions: "Copy the list URL, then add it to the custom filter lists in the settings of your adblocker.",
+		}},
+	}
+}
+
+// listURL returns the download URL of a list, on the list download domain if set, or the public host
+func (s *Server) listURL(c echo.Context, token uuid.UUID) string {
+	listUrl := url.URL{
+		Scheme: c.Scheme(),
+		Host:   s.publicHost(c),
+		Path:   c.Echo().Reverse("render-filterlist", token.String()) + renderListSuffix,
+	}
+	if domain := s.config().options.ListDownloadDomain; domain != "" {
+		listUrl.Host = domain
+	}
+	return listUrl.String()
+}
+
+// apiV1SubscribeLinks returns the subscribe links of a list, only to its owner
+func (s *Server) apiV1SubscribeLinks(c echo.Context) error {
+	token, err := uuid.Parse(c.Param("token"))
+	if err != nil {
+		return newApiError(http.StatusNotFound, "unknown list")
+	}
+	list, err := s.store.GetListForToken(c.Request().Context(), token)
+	switch {
+	case err == db.NotFound, err == nil && list.UserID != getApiUser(c):
+		// Lists of other users are not disclosed
+		return newApiError(http.StatusNotFound, "unknown list")
+	case err != nil:
+		return err
+	}
+	return c.JSON(http.StatusOK, buildSubscribeLinks(s.listURL(c, token), listTitle(list.Title)))
+}
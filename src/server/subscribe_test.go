@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSubscribeLinks(t *testing.T) {
+	links := buildSubscribeLinks("https://get.letsblock.it/list/abcd.txt", filters.DefaultListTitle)
+	assert.Equal(t, "https://get.letsblock.it/list/abcd.txt", links.ListURL)
+	assert.Equal(t, "letsblock.it - My filters", links.Title)
+	require.Len(t, links.Links, 4)
+	assert.Equal(t, "abp:subscribe?location=https%3A%2F%2Fget.letsblock.it%2Flist%2Fabcd.txt"+
+		"&title=letsblock.it%20-%20My%20filters", links.Links[0].Href)
+	assert.Equal(t, links.Links[0].Href, links.Links[1].Href)
+	assert.Equal(t, "adguard:subscribe?location=https%3A%2F%2Fget.letsblock.it%2Flist%2Fabcd.txt"+
+		"&title=letsblock.it%20-%20My%20filters", links.Links[2].Href)
+	assert.Empty(t, links.Links[3].Href, "other adblockers get copy instructions")
+}
+
+func TestBuildSubscribeLinks_Title(t *testing.T) {
+	links := buildSubscribeLinks("https://get.letsblock.it/list/abcd.txt",
+		listTitle(sql.NullString{String: "Work | ads!\n! Homepage: x", Valid: true}))
+	assert.Equal(t, "letsblock.it - Work | ads! ! Homepage: x", links.Title)
+	assert.Equal(t, "abp:subscribe?location=https%3A%2F%2Fget.letsblock.it%2Flist%2Fabcd.txt"+
+		"&title=letsblock.it%20-%20Work%20%7C%20ads%21%20%21%20Homepage%3A%20x", links.Links[0].Href)
+}
+
+func TestSubscribeHref(t *testing.T) {
+	assert.Equal(t, "abp:subscribe?location=http%3A%2F%2Fhost%2Flist%3Fa%3D1%26b%3D2&title=A%20%26%20B%2BC",
+		subscribeHref("abp:subscribe", "http://host/list?a=1&b=2", "A & B+C"))
+}
+
+func decodeSubscribeLinks(t *testing.T, rec *httptest.ResponseRecorder) *subscribeLinks {
+	var links subscribeLinks
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&links))
+	return &links
+}
+
+func (s *ServerTestSuite) TestApiV1_SubscribeLinks() {
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	s.Require().NoError(err)
+
+	req := newApiV1Request(http.MethodGet, "http://myhost/api/v1/lists/"+token.String()+"/subscribe", "")
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		links := decodeSubscribeLinks(t, rec)
+		assert.Equal(t, "http://myhost/list/"+token.String()+".txt", links.ListURL)
+		assert.Equal(t, buildSubscribeLinks(links.ListURL, filters.DefaultListTitle), links)
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_SubscribeLinksPublicHostname() {
+	token, err := s.store.CreateListForUser(context.Background(), s.user)
+	s.Require().NoError(err)
+
+	s.server.options.PublicHostname = "lists.example.com"
+	req := newApiV1Request(http.MethodGet, "https://myhost/api/v1/lists/"+token.String()+"/subscribe", "")
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.Equal(t, "https://lists.example.com/list/"+token.String()+".txt", decodeSubscribeLinks(t, rec).ListURL)
+	})
+
+	s.server.options.ListDownloadDomain = "get.letsblock.it"
+	req = newApiV1Request(http.MethodGet, "https://myhost/api/v1/lists/"+token.String()+"/subscribe", "")
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.Equal(t, "https://get.letsblock.it/list/"+token.String()+".txt", decodeSubscribeLinks(t, rec).ListURL)
+	})
+}
+
+func (s *ServerTestSuite) TestApiV1_SubscribeLinksNotOwner() {
+	token, err := s.store.CreateListForUser(context.Background(), uuid.NewString())
+	s.Require().NoError(err)
+
+	for _, target := range []string{
+		"/api/v1/lists/" + token.String() + "/subscribe",
+		"/api/v1/lists/" + uuid.NewString() + "/subscribe",
+		"/api/v1/lists/invalid/subscribe",
+	} {
+		req := newApiV1Request(http.MethodGet, target, "")
+		s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+			assert.Equal(t, "unknown list", decodeApiV1Error(t, rec).Message)
+		})
+	}
+}
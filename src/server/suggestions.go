@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+)
+
+const maxSuggestions = 10
+
+// templateUsage is the number of lists using a template, or a pair of templates
+type templateUsage struct {
+	name  string
+	lists int64
+}
+
+// templateSuggestions is computed periodically from the instances of all users. Templates and
+// pairs of templates used by less lists than the privacy threshold are left out.
+type templateSuggestions struct {
+	popular    []templateUsage            // Most used templates first
+	pairs      map[string][]templateUsage // Templates most often used with each template first
+	usage      map[string]string          // Rounded usage counts, like "500+ users"
+	computedAt time.Time
+}
+
+// suggestedFilter is a template suggested to a user, with the enabled template it is often used with
+type suggestedFilter struct {
+	Template *filters.Template
+	UsedWith *filters.Template
+}
+
+func loadTemplateSuggestions(ctx context.Context, q db.Querier, minLists int64, now time.Time) (*templateSuggestions, error) {
+	usage, err := q.GetInstanceStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pairs, err := q.GetTemplatePairs(ctx, minLists)
+	if err != nil {
+		return nil, err
+	}
+	return buildTemplateSuggestions(usage, pairs, minLists, now), nil
+}
+
+func buildTemplateSuggestions(usage []db.GetInstanceStatsRow, pairs []db.GetTemplatePairsRow, minLists int64, now time.Time) *templateSuggestions {
+	suggestions := &templateSuggestions{
+		pairs:      make(map[string][]templateUsage),
+		usage:      make(map[string]string),
+		computedAt: now,
+	}
+	for _, u := range usage {
+		if u.Total >= minLists {
+			suggestions.popular = append(suggestions.popular, templateUsage{name: u.TemplateName, lists: u.Total})
+			if bucket := usageBucket(u.Total); bucket > 0 {
+				suggestions.usage[u.TemplateName] = formatThousands(bucket) + "+ users"
+			}
+		}
+	}
+	sortUsage(suggestions.popular)
+	for _, p := range pairs {
+		if p.Total < minLists {
+			continue
+		}
+		suggestions.pairs[p.FirstTemplate] = append(suggestions.pairs[p.FirstTemplate],
+			templateUsage{name: p.SecondTemplate, lists: p.Total})
+		suggestions.pairs[p.SecondTemplate] = append(suggestions.pairs[p.SecondTemplate],
+			templateUsage{name: p.FirstTemplate, lists: p.Total})
+	}
+	for _, used := range suggestions.pairs {
+		sortUsage(used)
+	}
+	return suggestions
+}
+
+func sortUsage(usage []templateUsage) {
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].lists != usage[j].lists {
+			return usage[i].lists > usage[j].lists
+		}
+		return usage[i].name < usage[j].name
+	})
+}
+
+// usageBucket rounds a usage count down to 10, 20, 50, 100, 200..., to not disclose the exact number
+// of users of rare templates. Counts under 10 return 0.
+func usageBucket(count int64) int64 {
+	var bucket int64
+	for base := int64(10); base <= count; base *= 10 {
+		for _, step := range []int64{1, 2, 5} {
+			if base*step <= count {
+				bucket = base * step
+			}
+		}
+	}
+	return bucket
+}
+
+func formatThousands(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	var out strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out.WriteByte(',')
+		}
+		out.WriteRune(d)
+	}
+	return out.String()
+}
+
+// UsageCounts returns the rounded usage count of the templates used by enough lists
+func (t *templateSuggestions) UsageCounts() map[string]string {
+	if t == nil {
+		return nil
+	}
+	return t.usage
+}
+
+// Suggest returns the templates most often used with the enabled ones, completed with the most used
+// templates. Templates missing from the repository, like removed ones, are skipped.
+func (t *templateSuggestions) Suggest(repo *filters.Repository, enabled map[string]struct{}, limit int) []suggestedFilter {
+	if t == nil {
+		return nil
+	}
+	scores := make(map[string]int64)
+	usedWith := make(map[string]string)
+	var candidates []string
+	for name := range enabled {
+		for _, other := range t.pairs[name] {
+			if _, found := enabled[other.name]; found {
+				continue
+			}
+			if _, found := scores[other.name]; !found {
+				candidates = append(candidates, other.name)
+			}
+			scores[other.name] += other.lists
+			if previous, found := usedWith[other.name]; !found || name < previous {
+				usedWith[other.name] = name
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if scores[candidates[i]] != scores[candidates[j]] {
+			return scores[candidates[i]] > scores[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+	for _, popular := range t.popular {
+		if _, found := scores[popular.name]; !found {
+			candidates = append(candidates, popular.name)
+		}
+	}
+
+	var out []suggestedFilter
+	for _, name := range candidates {
+		if len(out) == limit {
+			break
+		}
+		if _, found := enabled[name]; found {
+			continue
+		}
+		template, err := repo.Get(name)
+		if err != nil {
+			continue
+		}
+		suggestion := suggestedFilter{Template: template}
+		if other, found := usedWith[name]; found {
+			suggestion.UsedWith, _ = repo.Get(other)
+		}
+		out = append(out, suggestion)
+	}
+	return out
+}
+
+// refreshSuggestions computes the template suggestions now and every interval, until ctx is done
+func (s *Server) refreshSuggestions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		suggestions, err := loadTemplateSuggestions(ctx, s.store, int64(s.options.SuggestionMinLists), s.now())
+		if err != nil {
+			s.echo.Logger.Warnf("cannot compute the template suggestions: %s", err)
+		} else {
+			s.suggestions.Store(suggestions)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// suggestedFilters lists the templates the user does not use yet, and other users often use with theirs
+func (s *Server) suggestedFilters(c echo.Context) error {
+	if s.options.SuggestionRefresh <= 0 {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	hc := s.buildPageContext(c, "Suggested filter templates")
+	enabled := make(map[string]struct{})
+	if hc.UserLoggedIn {
+		instances, err := s.store.GetInstancesForUser(c.Request().Context(), hc.UserID)
+		if err != nil {
+			return err
+		}
+		for _, instance := range instances {
+			enabled[instance.TemplateName] = struct{}{}
+		}
+	}
+	hc.Add("has_filters", len(enabled) > 0)
+	hc.Add("suggested_filters", s.suggestions.Load().Suggest(s.config().filters, enabled, maxSuggestions))
+	return s.pages.Render(c, "suggested-filters", hc)
+}
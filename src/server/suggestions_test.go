@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTemplateSuggestions(t *testing.T) {
+	suggestions := buildTemplateSuggestions([]db.GetInstanceStatsRow{
+		{TemplateName: "filter1", Total: 12},
+		{TemplateName: "filter2", Total: 30},
+		{TemplateName: "custom-rules", Total: 12},
+		{TemplateName: "rare", Total: 3},
+	}, []db.GetTemplatePairsRow{
+		{FirstTemplate: "filter1", SecondTemplate: "filter2", Total: 10},
+		{FirstTemplate: "custom-rules", SecondTemplate: "filter2", Total: 11},
+		{FirstTemplate: "custom-rules", SecondTemplate: "filter1", Total: 2},
+	}, 10, fixedNow)
+
+	assert.Equal(t, []templateUsage{{"filter2", 30}, {"custom-rules", 12}, {"filter1", 12}}, suggestions.popular)
+	assert.Equal(t, map[string][]templateUsage{
+		"filter1":      {{"filter2", 10}},
+		"filter2":      {{"custom-rules", 11}, {"filter1", 10}},
+		"custom-rules": {{"filter2", 11}},
+	}, suggestions.pairs, "pairs under the threshold are suppressed")
+	assert.Equal(t, map[string]string{
+		"filter1":      "10+ users",
+		"filter2":      "20+ users",
+		"custom-rules": "10+ users",
+	}, suggestions.UsageCounts())
+	assert.Equal(t, fixedNow, suggestions.computedAt)
+	assert.Nil(t, (*templateSuggestions)(nil).UsageCounts())
+}
+
+func TestUsageBucket(t *testing.T) {
+	for count, expected := range map[int64]int64{
+		0:     0,
+		9:     0,
+		10:    10,
+		19:    10,
+		20:    20,
+		49:    20,
+		50:    50,
+		99:    50,
+		100:   100,
+		512:   500,
+		4999:  2000,
+		12345: 10000,
+	} {
+		assert.Equal(t, expected, usageBucket(count), count)
+	}
+	assert.Equal(t, "500", formatThousands(500))
+	assert.Equal(t, "1,000", formatThousands(1000))
+	assert.Equal(t, "200,000", formatThousands(200000))
+	assert.Equal(t, "1,000,000", formatThousands(1000000))
+}
+
+func TestTemplateSuggestions_Suggest(t *testing.T) {
+	suggestions := &templateSuggestions{
+		popular: []templateUsage{{"removed", 50}, {"filter2", 30}, {"custom-rules", 12}, {"filter1", 12}},
+		pairs: map[string][]templateUsage{
+			"filter1":      {{"removed", 20}, {"filter2", 10}},
+			"filter2":      {{"custom-rules", 11}, {"filter1", 10}},
+			"custom-rules": {{"filter2", 11}},
+		},
+	}
+	repo, err := filters.Load(testTemplates, testTemplates)
+	require.NoError(t, err)
+	get := func(name string) *filters.Template {
+		template, err := repo.Get(name)
+		require.NoError(t, err)
+		return template
+	}
+
+	assert.Equal(t, []suggestedFilter{
+		{Template: get("filter2")},
+		{Template: get("custom-rules")},
+		{Template: get("filter1")},
+	}, suggestions.Suggest(repo, nil, 10), "popular templates are suggested to new users")
+	assert.Equal(t, []suggestedFilter{
+		{Template: get("filter2"), UsedWith: get("filter1")},
+		{Template: get("custom-rules")},
+	}, suggestions.Suggest(repo, map[string]struct{}{"filter1": {}}, 10))
+	assert.Equal(t, []suggestedFilter{
+		{Template: get("filter2"), UsedWith: get("custom-rules")},
+	}, suggestions.Suggest(repo, map[string]struct{}{"filter1": {}, "custom-rules": {}}, 10),
+		"scores are summed over the enabled templates")
+	assert.Len(t, suggestions.Suggest(repo, nil, 1), 1)
+	assert.Nil(t, (*templateSuggestions)(nil).Suggest(repo, nil, 10))
+}
+
+func (s *ServerTestSuite) TestSuggestedFilters_Disabled() {
+	req := httptest.NewRequest(http.MethodGet, "/filters/suggested", nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestSuggestedFilters_OK() {
+	s.server.options.SuggestionRefresh = time.Hour
+	for i := 0; i < 2; i++ {
+		user := uuid.NewString()
+		s.addInstance(user, "filter1", nil)
+		s.addInstance(user, "filter2", nil)
+	}
+	s.addInstance(s.user, "filter1", nil)
+
+	suggestions, err := loadTemplateSuggestions(context.Background(), s.store, 2, fixedNow)
+	s.Require().NoError(err)
+	s.server.suggestions.Store(suggestions)
+
+	req := httptest.NewRequest(http.MethodGet, "/filters/suggested", nil)
+	s.expectRender("suggested-filters", pages.ContextData{
+		"has_filters":       true,
+		"suggested_filters": []suggestedFilter{{Template: filter2, UsedWith: filter1}},
+	})
+	s.runRequest(req, assertOk)
+
+	suggestions, err = loadTemplateSuggestions(context.Background(), s.store, 3, fixedNow)
+	s.Require().NoError(err)
+	assert.Equal(s.T(), []templateUsage{{"filter1", 3}}, suggestions.popular)
+	assert.Empty(s.T(), suggestions.pairs, "pairs used by two lists are under the threshold")
+}
+
+func (s *ServerTestSuite) TestListFilters_UsageCounts() {
+	s.server.options.UsageCounts = true
+	s.server.suggestions.Store(buildTemplateSuggestions([]db.GetInstanceStatsRow{
+		{TemplateName: "filter1", Total: 512},
+		{TemplateName: "filter2", Total: 3},
+	}, nil, 2, fixedNow))
+
+	req := httptest.NewRequest(http.MethodGet, "/filters", nil)
+	s.expectRender("list-filters", pages.ContextData{
+		"filter_tags":       filterTags,
+		"available_filters": filterRepo.GetAll(),
+		"usage_counts":      map[string]string{"filter1": "500+ users"},
+	})
+	s.runRequest(req, assertOk)
+
+	s.server.options.UsageCounts = false
+	req = httptest.NewRequest(http.MethodGet, "/filters", nil)
+	s.expectRender("list-filters", pages.ContextData{
+		"filter_tags":       filterTags,
+		"available_filters": filterRepo.GetAll(),
+	})
+	s.runRequest(req, assertOk)
+}
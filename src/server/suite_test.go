@@ -25,6 +25,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"zgo.at/zcache/v2"
 )
 
 var (
@@ -72,6 +73,7 @@ type ServerTestSuite struct {
 	expectP  *mocks.MockPageRendererMockRecorder
 	expectR  *mocks.MockReleaseClientMockRecorder
 	user     string
+	session  string
 	csrf     string
 	releases []*news.Release
 	store    db.Store
@@ -84,6 +86,9 @@ func (s *ServerTestSuite) BuildMiddleware() echo.MiddlewareFunc {
 			if len(s.user) > 0 {
 				c.Set("_user", s.user)
 			}
+			if len(s.session) > 0 {
+				c.Set("_session", s.session)
+			}
 			return next(c)
 		}
 	}
@@ -103,6 +108,7 @@ func (s *ServerTestSuite) SetupTest() {
 	s.store = db.NewTestStore(s.T())
 	s.csrf = random.String(32)
 	s.user = uuid.New().String()
+	s.session = ""
 	pref, err := users.NewPreferenceManager(s.store)
 	require.NoError(s.T(), err)
 	require.NoError(s.T(), pref.UpdateNewsCursor(s.c, s.user, fixedNow))
@@ -120,8 +126,10 @@ func (s *ServerTestSuite) SetupTest() {
 		preferences: pref,
 		releases:    rm,
 		statsd:      &statsd.NoOpClient{},
+		statsCache:  zcache.New[string, *instanceStats](statsCacheTTL, statsCacheTTL),
 		store:       s.store,
 		filterHash:  "2rjz7ztfqaebl",
+		cookieKey:   []byte("test-key"),
 	}
 	s.server.setupRouter()
 
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+)
+
+// The sync API lets browser extensions show the filters of a list and toggle them. Each change to an instance
+// takes the next id of a database sequence, and removals are kept as tombstones: clients store the cursor of their
+// last response, and poll the changes after it.
+
+// syncState is returned by the sync endpoints, with the etag of the list, as sent in its ETag header,
+// for clients to tell whether their adblocker's copy is current
+type syncState struct {
+	Cursor    int64          `json:"cursor"`
+	ListETag  string         `json:"list_etag,omitempty"`
+	Instances []syncInstance `json:"instances"`
+}
+
+type syncInstance struct {
+	Template string `json:"template"`
+	TestMode bool   `json:"test_mode,omitempty"`
+	Removed  bool   `json:"removed,omitempty"`
+	Change   int64  `json:"change"`
+}
+
+// syncToggleRequest is the body accepted by the toggle endpoints
+type syncToggleRequest struct {
+	Value bool `json:"value"`
+}
+
+// syncAuth authenticates the sync API requests, with tokens holding the sync scope
+func (s *Server) syncAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return s.checkBearerToken(next, scopeSync)
+}
+
+// apiSyncInstances returns all the instances of the user, in change order
+func (s *Server) apiSyncInstances(c echo.Context) error {
+	user := getApiUser(c)
+	state := &syncState{Instances: []syncInstance{}}
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		instances, err := q.GetSyncInstances(ctx, user)
+		if err != nil {
+			return err
+		}
+		for _, i := range instances {
+			state.Instances = append(state.Instances, syncInstance{
+				Template: i.TemplateName,
+				TestMode: i.TestMode,
+				Change:   i.ChangeID,
+			})
+		}
+		return s.fillSyncState(ctx, q, user, state)
+	}); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, state)
+}
+
+// apiSyncChanges returns the instances changed or removed after the since cursor
+func (s *Server) apiSyncChanges(c echo.Context) error {
+	var since int64
+	if value := c.QueryParam("since"); value != "" {
+		var err error
+		if since, err = strconv.ParseInt(value, 10, 64); err != nil || since < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid since cursor")
+		}
+	}
+	user := getApiUser(c)
+	state := &syncState{Instances: []syncInstance{}}
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		changes, err := q.GetInstanceChanges(ctx, db.GetInstanceChangesParams{
+			UserID:   user,
+			ChangeID: since,
+		})
+		if err != nil {
+			return err
+		}
+		for _, i := range changes {
+			state.Instances = append(state.Instances, syncInstance{
+				Template: i.TemplateName,
+				TestMode: i.TestMode,
+				Removed:  i.Removed,
+				Change:   i.ChangeID,
+			})
+		}
+		return s.fillSyncState(ctx, q, user, state)
+	}); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, state)
+}
+
+// apiSyncSetTestMode toggles the test mode of an instance, then returns the changes like apiSyncChanges
+func (s *Server) apiSyncSetTestMode(c echo.Context) error {
+	filter, request, err := s.parseSyncToggle(c)
+	if err != nil {
+		return err
+	}
+	user := getApiUser(c)
+	count, err := s.store.SetInstanceTestMode(c.Request().Context(), db.SetInstanceTestModeParams{
+		UserID:       user,
+		TemplateName: filter.Name,
+		TestMode:     request.Value,
+	})
+	switch {
+	case err != nil:
+		return err
+	case count == 0:
+		return echo.NewHTTPError(http.StatusNotFound, "template not in list")
+	}
+	s.notifyListChange(user, webhookInstanceUpdated, filter.Name)
+	return s.apiSyncChanges(c)
+}
+
+// apiSyncSetEnabled adds a template with its default parameters, or removes it from the list.
+// Removals can be undone from the filter list page, like the ones made from the website.
+func (s *Server) apiSyncSetEnabled(c echo.Context) error {
+	filter, request, err := s.parseSyncToggle(c)
+	if err != nil {
+		return err
+	}
+	user := getApiUser(c)
+	if !request.Value {
+		if err = s.removeInstance(c.Request().Context(), user, filter.Name, filter.Title); err != nil {
+			return err
+		}
+		return s.apiSyncChanges(c)
+	}
+
+	count, err := s.store.CountInstances(c.Request().Context(), db.CountInstancesParams{
+		UserID:       user,
+		TemplateName: filter.Name,
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		if err = s.upsertFilterParams(c, user, &filters.Instance{
+			Template: filter.Name,
+			Params:   filter.DefaultParams(),
+		}); err != nil {
+			return err
+		}
+	}
+	return s.apiSyncChanges(c)
+}
+
+func (s *Server) parseSyncToggle(c echo.Context) (*filters.Template, *syncToggleRequest, error) {
+	filter, err := s.config().filters.Get(c.Param("name"))
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, "unknown template")
+	}
+	var request syncToggleRequest
+	if err = c.Bind(&request); err != nil {
+		return nil, nil, err
+	}
+	return filter, &request, nil
+}
+
+// fillSyncState sets the cursor of the user's latest change, and the etag of their list if they have one
+func (s *Server) fillSyncState(ctx context.Context, q db.Querier, user string, state *syncState) error {
+	var err error
+	if state.Cursor, err = q.GetChangeCursor(ctx, user); err != nil {
+		return err
+	}
+	list, err := q.GetListForUser(ctx, user)
+	switch {
+	case err == db.NotFound:
+		return nil
+	case err != nil:
+		return err
+	}
+	storedList, err := q.GetListForToken(ctx, list.Token)
+	if err != nil {
+		return err
+	}
+	state.ListETag = weakETag(s.buildListETag(storedList))
+	return nil
+}
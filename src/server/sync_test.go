@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *ServerTestSuite) runSyncRequest(method, path, body, token string) *syncState {
+	s.T().Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	state := &syncState{}
+	user, csrf := s.user, s.csrf
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), state))
+	})
+	s.user, s.csrf = user, csrf
+	return state
+}
+
+func (s *ServerTestSuite) TestSync_InstancesAndChanges() {
+	token := s.createApiToken(scopeSync)
+	s.addInstance(s.user, "filter1", nil)
+	s.addInstance(s.user, "filter2", filter2Custom)
+
+	state := s.runSyncRequest(http.MethodGet, "/api/sync/instances", "", token)
+	require.Len(s.T(), state.Instances, 2)
+	assert.Equal(s.T(), "filter1", state.Instances[0].Template)
+	assert.Equal(s.T(), "filter2", state.Instances[1].Template)
+	assert.Equal(s.T(), state.Instances[1].Change, state.Cursor)
+	assert.Regexp(s.T(), `^W/"[^"]+"$`, state.ListETag)
+	cursor := strconv.FormatInt(state.Cursor, 10)
+
+	// Nothing changed since the cursor
+	changes := s.runSyncRequest(http.MethodGet, "/api/sync/changes?since="+cursor, "", token)
+	assert.Empty(s.T(), changes.Instances)
+	assert.Equal(s.T(), state.Cursor, changes.Cursor)
+	assert.Equal(s.T(), state.ListETag, changes.ListETag)
+
+	// Updates and removals are returned in change order
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter2", TestMode: true}))
+	require.NoError(s.T(), s.server.removeInstance(s.c.Request().Context(), s.user, "filter1", "Filter 1"))
+	changes = s.runSyncRequest(http.MethodGet, "/api/sync/changes?since="+cursor, "", token)
+	require.Len(s.T(), changes.Instances, 2)
+	assert.Equal(s.T(), "filter2", changes.Instances[0].Template)
+	assert.True(s.T(), changes.Instances[0].TestMode)
+	assert.Equal(s.T(), syncInstance{
+		Template: "filter1",
+		Removed:  true,
+		Change:   changes.Cursor,
+	}, changes.Instances[1])
+	assert.Greater(s.T(), changes.Instances[1].Change, changes.Instances[0].Change)
+}
+
+func (s *ServerTestSuite) TestSync_Toggles() {
+	token := s.createApiToken(scopeSync)
+	user := s.user
+	s.addInstance(user, "filter1", nil)
+
+	state := s.runSyncRequest(http.MethodPut, "/api/sync/instances/filter1/test-mode", `{"value": true}`, token)
+	require.Len(s.T(), state.Instances, 1)
+	assert.True(s.T(), state.Instances[0].TestMode)
+	cursor := strconv.FormatInt(state.Cursor, 10)
+
+	state = s.runSyncRequest(http.MethodPut, "/api/sync/instances/filter2/enabled?since="+cursor, `{"value": true}`, token)
+	require.Len(s.T(), state.Instances, 1)
+	assert.Equal(s.T(), "filter2", state.Instances[0].Template)
+	s.requireInstanceCount("filter2", 1)
+
+	state = s.runSyncRequest(http.MethodPut, "/api/sync/instances/filter1/enabled?since="+cursor, `{"value": false}`, token)
+	require.Len(s.T(), state.Instances, 2)
+	assert.True(s.T(), state.Instances[1].Removed)
+	s.requireInstanceCount("filter1", 0)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/sync/instances/filter1/test-mode", strings.NewReader(`{"value": true}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestSync_RequiresSyncScope() {
+	token := s.createApiToken(scopeRead, scopeWrite)
+	req := httptest.NewRequest(http.MethodGet, "/api/sync/instances", nil)
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestSync_InvalidCursor() {
+	token := s.createApiToken(scopeSync)
+	req := httptest.NewRequest(http.MethodGet, "/api/sync/changes?since=abc", nil)
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
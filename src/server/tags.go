@@ -0,0 +1,69 @@
+package server
+
+// The statsd client takes the tags as a slice, that escapes to the heap if built for each metric. The tags
+// of the metrics sent on each request are computed at startup, and shared between calls: these slices
+// must not be modified.
+
+// boolTag holds the tag values for both values of a boolean, to not format them for each metric
+type boolTag struct {
+	values [2]string
+	slices [2][]string
+}
+
+func newBoolTag(name string) boolTag {
+	t := boolTag{values: [2]string{name + ":false", name + ":true"}}
+	t.slices = [2][]string{t.values[0:1:1], t.values[1:2:2]}
+	return t
+}
+
+func (t boolTag) of(value bool) string {
+	if value {
+		return t.values[1]
+	}
+	return t.values[0]
+}
+
+// tags returns the tag as a single-tag slice
+func (t boolTag) tags(value bool) []string {
+	if value {
+		return t.slices[1]
+	}
+	return t.slices[0]
+}
+
+// boolTagSet holds the tags of every combination of a few booleans, after some static tags
+type boolTagSet [][]string
+
+func newBoolTagSet(static []string, names ...string) boolTagSet {
+	set := make(boolTagSet, 1<<len(names))
+	for mask := range set {
+		tags := make([]string, 0, len(static)+len(names))
+		tags = append(tags, static...)
+		for i, name := range names {
+			tags = append(tags, newBoolTag(name).of(mask&(1<<i) != 0))
+		}
+		set[mask] = tags
+	}
+	return set
+}
+
+// of returns the tags for the given values, in the order of the names passed to newBoolTagSet
+func (s boolTagSet) of(values ...bool) []string {
+	mask := 0
+	for i, value := range values {
+		if value {
+			mask |= 1 << i
+		}
+	}
+	return s[mask]
+}
+
+var (
+	hitTag     = newBoolTag("hit")
+	storedTag  = newBoolTag("stored")
+	loggedTag  = newBoolTag("logged")
+	workingTag = newBoolTag("working")
+
+	listDownloadTags = newBoolTagSet(nil, "etag_present", "etag_match", "banned")
+	staleHitTags     = []string{"hit:stale"}
+)
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoolTag(t *testing.T) {
+	assert.Equal(t, "hit:true", hitTag.of(true))
+	assert.Equal(t, "hit:false", hitTag.of(false))
+	assert.Equal(t, []string{"hit:true"}, hitTag.tags(true))
+	assert.Equal(t, []string{"hit:false"}, hitTag.tags(false))
+}
+
+func TestBoolTagSet(t *testing.T) {
+	for _, etagPresent := range []bool{false, true} {
+		for _, etagMatch := range []bool{false, true} {
+			for _, banned := range []bool{false, true} {
+				assert.Equal(t, []string{
+					fmt.Sprintf("etag_present:%t", etagPresent),
+					fmt.Sprintf("etag_match:%t", etagMatch),
+					fmt.Sprintf("banned:%t", banned),
+				}, listDownloadTags.of(etagPresent, etagMatch, banned))
+			}
+		}
+	}
+
+	format, _ := findListFormat("token.safari.json")
+	assert.Equal(t, []string{"format:safari", "etag_match:true"}, format.downloadTags.of(true))
+	assert.Equal(t, []string{"format:safari", "etag_match:false"}, format.downloadTags.of(false))
+}
+
+func TestBoolTagSet_Shared(t *testing.T) {
+	tags := listDownloadTags.of(true, false, true)
+	assert.Equal(t, len(tags), cap(tags), "appending must not write into the shared storage")
+	assert.Equal(t, 1, cap(hitTag.tags(true)))
+}
+
+var benchmarkTags []string
+
+// BenchmarkListDownloadTags compares formatting the tags for each request, building a slice of formatted
+// tags, and the precomputed tag slices
+func BenchmarkListDownloadTags(b *testing.B) {
+	b.Run("formatted", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			benchmarkTags = []string{
+				fmt.Sprintf("etag_present:%t", i%2 == 0),
+				fmt.Sprintf("etag_match:%t", i%3 == 0),
+				fmt.Sprintf("banned:%t", i%5 == 0),
+			}
+		}
+	})
+	b.Run("slice", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			benchmarkTags = []string{hitTag.of(i%2 == 0), storedTag.of(i%3 == 0), loggedTag.of(i%5 == 0)}
+		}
+	})
+	b.Run("precomputed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			benchmarkTags = listDownloadTags.of(i%2 == 0, i%3 == 0, i%5 == 0)
+		}
+	})
+}
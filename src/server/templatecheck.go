@@ -0,0 +1,142 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+var ErrTemplateCheckFinished = errors.New("template check finished")
+
+// templateCheckReport holds the outcome of the render of all templates, see filters.Repository.Check
+type templateCheckReport struct {
+	Templates []*templateTiming
+	Broken    []string
+	Duration  time.Duration
+	RanAt     time.Time
+}
+
+// templateTiming aggregates the renders of a template with its defaults and presets
+type templateTiming struct {
+	Name     string
+	Duration time.Duration
+	Errors   []string
+}
+
+// checkTemplates renders all templates, logs their timing and stores the report for the readiness check
+func (s *Server) checkTemplates() *templateCheckReport {
+	report := &templateCheckReport{RanAt: s.now()}
+	timings := make(map[string]*templateTiming)
+	for _, result := range s.config().filters.Check() {
+		timing, found := timings[result.Template]
+		if !found {
+			timing = &templateTiming{Name: result.Template}
+			timings[result.Template] = timing
+			report.Templates = append(report.Templates, timing)
+		}
+		timing.Duration += result.Duration
+		report.Duration += result.Duration
+		if result.Err != nil {
+			variant := "defaults"
+			if result.Preset != "" {
+				variant = result.Preset + " preset"
+			}
+			timing.Errors = append(timing.Errors, fmt.Sprintf("%s: %s", variant, result.Err))
+		}
+	}
+
+	for _, timing := range report.Templates {
+		if len(timing.Errors) > 0 {
+			report.Broken = append(report.Broken, timing.Name)
+			s.echo.Logger.Errorf("template check: %s is broken: %s", timing.Name, strings.Join(timing.Errors, ", "))
+		} else {
+			s.echo.Logger.Infof("template check: %s rendered in %s", timing.Name, timing.Duration)
+		}
+	}
+	// Slowest templates first in the admin page
+	sort.SliceStable(report.Templates, func(i, j int) bool {
+		return report.Templates[i].Duration > report.Templates[j].Duration
+	})
+	s.templateCheck.Store(report)
+	return report
+}
+
+// runTemplateCheck only loads the templates and checks them, for CI pipelines
+func (s *Server) runTemplateCheck() error {
+	s.echo.Logger.SetLevel(log.INFO) // Always show the timing of the templates
+	var err error
+	if s.filters, err = loadTemplates(s.options.TemplatesDir); err != nil {
+		return err
+	}
+	report := s.checkTemplates()
+	if len(report.Broken) > 0 {
+		return fmt.Errorf("%d broken templates: %s", len(report.Broken), strings.Join(report.Broken, ", "))
+	}
+	return ErrTemplateCheckFinished
+}
+
+// checkBrokenTemplates fails the readiness check if the last template check found broken templates
+func (s *Server) checkBrokenTemplates() error {
+	if report := s.templateCheck.Load(); report != nil && len(report.Broken) > 0 {
+		return fmt.Errorf("broken templates: %s", strings.Join(report.Broken, ", "))
+	}
+	return nil
+}
+
+// templateCheckRow holds the template timing displayed in the admin page
+type templateCheckRow struct {
+	Name     string
+	Duration string
+	Errors   []string
+}
+
+// renderTimingRow holds the sampled list render durations of a template displayed in the admin page
+type renderTimingRow struct {
+	Name  string
+	Count int
+	Mean  string
+	Max   string
+}
+
+func (s *Server) adminTemplates(c echo.Context) error {
+	hc := s.buildPageContext(c, "Template check")
+	hc.NoBoost = true
+	if report := s.templateCheck.Load(); report != nil {
+		rows := make([]templateCheckRow, 0, len(report.Templates))
+		for _, t := range report.Templates {
+			rows = append(rows, templateCheckRow{
+				Name:     t.Name,
+				Duration: t.Duration.Round(time.Microsecond).String(),
+				Errors:   t.Errors,
+			})
+		}
+		hc.Add("templates", rows)
+		hc.Add("broken", len(report.Broken))
+		hc.Add("duration", report.Duration.Round(time.Microsecond).String())
+		hc.Add("ran_at", report.RanAt.Format(time.RFC3339))
+	}
+	var sampled []renderTimingRow
+	for _, t := range s.renderTimings.slowest(s.config().options.SlowTemplateCount) {
+		sampled = append(sampled, renderTimingRow{
+			Name:  t.Name,
+			Count: t.Count,
+			Mean:  t.Mean().Round(time.Microsecond).String(),
+			Max:   t.Max.Round(time.Microsecond).String(),
+		})
+	}
+	if len(sampled) > 0 {
+		hc.Add("sampled_renders", sampled)
+	}
+	return s.pages.Render(c, "admin-templates", hc)
+}
+
+func (s *Server) adminCheckTemplates(c echo.Context) error {
+	s.checkTemplates()
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("admin-templates"))
+}
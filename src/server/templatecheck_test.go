@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTemplates(t *testing.T) {
+	repo, err := filters.Load(fstest.MapFS{
+		"broken.yaml": {Data: []byte("title: Broken\ntemplate: \"{{> missing}}\"\n---\n\nBroken template")},
+		"hello.yaml":  {Data: []byte("title: Hello\ntemplate: \"Hello\"\n---\n\nWorking template")},
+	}, fstest.MapFS{})
+	require.NoError(t, err)
+	s := &Server{
+		echo:    echo.New(),
+		filters: repo,
+		now:     func() time.Time { return fixedNow },
+	}
+	assert.NoError(t, s.checkBrokenTemplates(), "templates are not checked yet")
+
+	report := s.checkTemplates()
+	assert.Equal(t, []string{"broken"}, report.Broken)
+	assert.Equal(t, fixedNow, report.RanAt)
+	require.Len(t, report.Templates, 2)
+	for _, timing := range report.Templates {
+		if timing.Name == "broken" {
+			require.Len(t, timing.Errors, 1)
+			assert.Contains(t, timing.Errors[0], "defaults: ")
+		} else {
+			assert.Empty(t, timing.Errors)
+		}
+	}
+	assert.Same(t, report, s.templateCheck.Load())
+	assert.EqualError(t, s.checkBrokenTemplates(), "broken templates: broken")
+
+	rec := runHealthCheck(t, s.readinessCheck)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"templates":"failing"`)
+	assert.NotContains(t, rec.Body.String(), "broken")
+}
+
+func TestCheckTemplates_Only(t *testing.T) {
+	s := NewServer(&Options{CheckTemplates: true})
+	assert.ErrorIs(t, s.Start(), ErrTemplateCheckFinished, "embedded templates render OK")
+	assert.Nil(t, s.store, "the database is not needed")
+}
+
+func (s *ServerTestSuite) TestAdminTemplates() {
+	s.setUserAdmin()
+	s.expectRender("admin-templates", nil)
+	s.runRequest(httptest.NewRequest(http.MethodGet, "/admin/templates", nil), assertOk)
+
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/admin/templates", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/admin/templates")
+	s.runRequest(req, assertOk)
+	if report := s.server.templateCheck.Load(); s.NotNil(report) {
+		s.Empty(report.Broken)
+		s.Len(report.Templates, len(filterRepo.GetAll()))
+	}
+}
+
+func (s *ServerTestSuite) TestAdminTemplates_SampledRenders() {
+	s.server.options.SlowTemplateCount = 1
+	s.server.renderTimings.record("filter1", 5*time.Millisecond)
+	s.server.renderTimings.record("filter2", 3*time.Millisecond)
+	s.server.renderTimings.record("filter2", time.Millisecond)
+	s.server.renderTimings.record("custom-rules", time.Microsecond)
+	s.setUserAdmin()
+	s.expectRender("admin-templates", pages.ContextData{
+		"sampled_renders": []renderTimingRow{{Name: "filter1", Count: 1, Mean: "5ms", Max: "5ms"}},
+	})
+	s.runRequest(httptest.NewRequest(http.MethodGet, "/admin/templates", nil), assertOk)
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"golang.org/x/tools/blog/atom"
+)
+
+const (
+	templateFeedSize = 50
+	templateFeedID   = "tag:letsblock.it,2023:filters/updates"
+
+	// The first load records the hash of all templates without listing them in the feed
+	templateBaseline = "baseline"
+	templateAdded    = "added"
+	templateUpdated  = "updated"
+)
+
+// templateFeed is the Atom feed of the template updates, rendered when the templates are loaded
+type templateFeed struct {
+	body    []byte
+	etag    string
+	updated time.Time
+}
+
+// loadTemplateFeed records the templates that changed since the previous load, stores their definitions
+// for the pinned instances, and renders the feed
+func (s *Server) loadTemplateFeed(ctx context.Context, repo *filters.Repository) error {
+	if err := recordTemplateVersions(ctx, s.store, repo); err != nil {
+		return fmt.Errorf("cannot record the template versions: %w", err)
+	}
+	if err := recordTemplateDefinitions(ctx, s.store, repo); err != nil {
+		return fmt.Errorf("cannot record the template definitions: %w", err)
+	}
+	s.versions.reset()
+	versions, err := s.store.GetTemplateVersions(ctx, templateFeedSize)
+	if err != nil {
+		return fmt.Errorf("cannot get the template versions: %w", err)
+	}
+	feed, err := buildTemplateFeed(versions, repo, s.now())
+	if err != nil {
+		return err
+	}
+	s.templateFeed.Store(feed)
+	return nil
+}
+
+// recordTemplateVersions stores the hash of the new and updated templates. Concurrent instances loading
+// the same templates record them once, as the hashes are unique per template.
+func recordTemplateVersions(ctx context.Context, q db.Querier, repo *filters.Repository) error {
+	latest, err := q.GetLatestTemplateVersions(ctx)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]string, len(latest))
+	for _, v := range latest {
+		known[v.TemplateName] = v.TemplateHash
+	}
+
+	var params db.AddTemplateVersionsParams
+	for _, tpl := range repo.GetAll() {
+		hash := repo.Hash(tpl.Name)
+		change := templateUpdated
+		previous, found := known[tpl.Name]
+		switch {
+		case len(known) == 0:
+			change = templateBaseline
+		case !found:
+			change = templateAdded
+		case previous == hash:
+			continue
+		}
+		params.TemplateNames = append(params.TemplateNames, tpl.Name)
+		params.TemplateHashes = append(params.TemplateHashes, hash)
+		params.Titles = append(params.Titles, tpl.Title)
+		params.Changes = append(params.Changes, change)
+	}
+	if len(params.TemplateNames) == 0 {
+		return nil
+	}
+	return q.AddTemplateVersions(ctx, params)
+}
+
+// buildTemplateFeed renders the feed of the latest versions. Links are relative to the feed URL, for the
+// body to not depend on the request host.
+func buildTemplateFeed(versions []db.TemplateVersion, repo *filters.Repository, now time.Time) (*templateFeed, error) {
+	feed := atom.Feed{
+		Title: "Filter template updates from letsblock.it",
+		ID:    templateFeedID,
+		Link: []atom.Link{{
+			Rel:  "self",
+			Href: "/filters/updates.atom",
+			Type: "application/atom+xml",
+		}, {
+			Rel:      "alternate",
+			Href:     "/filters",
+			Type:     "text/html",
+			HrefLang: "en",
+		}},
+		Author: &atom.Person{
+			Name: "Let's Block It contributors",
+			URI:  "https://github.com/letsblockit/letsblockit",
+		},
+		Entry: make([]*atom.Entry, 0, len(versions)),
+	}
+
+	var updated time.Time
+	for _, v := range versions {
+		title := v.Title + " updated"
+		content := fmt.Sprintf("<p>The %s template was updated, check the parameters of your filter.</p>",
+			html.EscapeString(v.Title))
+		if v.Change == templateAdded {
+			title = "New template: " + v.Title
+			content = fmt.Sprintf("<p>The %s template is now available.</p>", html.EscapeString(v.Title))
+		}
+		if repo.Source(v.TemplateName) == "embedded" {
+			content += fmt.Sprintf(`<p><a href="%s">See the changes on GitHub</a></p>`,
+				html.EscapeString(fmt.Sprintf(templateHistoryUrl, v.TemplateName)))
+		}
+		feed.Entry = append(feed.Entry, &atom.Entry{
+			Title: title,
+			ID:    templateFeedID + "/" + v.TemplateName + "/" + v.TemplateHash,
+			Link: []atom.Link{{
+				Rel:  "alternate",
+				Href: "/filters/" + v.TemplateName,
+				Type: "text/html",
+			}},
+			Published: atom.Time(v.CreatedAt),
+			Updated:   atom.Time(v.CreatedAt),
+			Content: &atom.Text{
+				Type: "html",
+				Body: content,
+			},
+		})
+		if v.CreatedAt.After(updated) {
+			updated = v.CreatedAt
+		}
+	}
+	if updated.IsZero() {
+		updated = now
+	}
+	feed.Updated = atom.Time(updated)
+
+	body, err := xml.MarshalIndent(&feed, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("cannot render the template updates feed: %w", err)
+	}
+	hasher := fnv.New64()
+	_, _ = hasher.Write(body)
+	return &templateFeed{
+		body:    append([]byte(xml.Header), body...),
+		etag:    strconv.FormatUint(hasher.Sum64(), 36),
+		updated: updated.Truncate(time.Second),
+	}, nil
+}
+
+func (s *Server) templateUpdatesAtom(c echo.Context) error {
+	feed := s.templateFeed.Load()
+	if feed == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "the template updates feed is not available")
+	}
+	return serveConditional(c, feed.etag, feed.updated, "application/atom+xml", feed.body)
+}
+
+// serveConditional serves a body with its etag and modification date, or a 304 response if the
+// client already has it. If-Modified-Since is ignored if the client sent an etag.
+func serveConditional(c echo.Context, etag string, updated time.Time, contentType string, body []byte) error {
+	header := c.Response().Header()
+	header.Set("Etag", strongETag(etag))
+	if !updated.IsZero() {
+		header.Set(echo.HeaderLastModified, updated.UTC().Format(http.TimeFormat))
+	}
+	if m := getRequestETags(c); m.present() {
+		if m.match(etag) {
+			return c.NoContent(http.StatusNotModified)
+		}
+	} else if since, err := http.ParseTime(c.Request().Header.Get(echo.HeaderIfModifiedSince)); err == nil &&
+		!updated.IsZero() && !updated.After(since) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.Blob(http.StatusOK, contentType, body)
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadFeedTestRepo(t *testing.T, rule string) *filters.Repository {
+	repo, err := filters.Load(fstest.MapFS{
+		"hello.yaml": {Data: []byte("title: Hello & welcome\ntemplate: \"" + rule + "\"\n---\n\nHello template")},
+		"other.yaml": {Data: []byte("title: Other\ntemplate: \"other##rule\"\n---\n\nOther template")},
+	}, fstest.MapFS{})
+	require.NoError(t, err)
+	return repo
+}
+
+func TestBuildTemplateFeed(t *testing.T) {
+	repo := loadFeedTestRepo(t, "hello##rule")
+	versions := []db.TemplateVersion{{
+		ID:           2,
+		TemplateName: "hello",
+		TemplateHash: "abcd",
+		Title:        "Hello & welcome",
+		Change:       templateUpdated,
+		CreatedAt:    fixedNow,
+	}, {
+		ID:           1,
+		TemplateName: "removed",
+		TemplateHash: "efgh",
+		Title:        "Removed",
+		Change:       templateAdded,
+		CreatedAt:    fixedNow.Add(-time.Hour),
+	}}
+	feed, err := buildTemplateFeed(versions, repo, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, fixedNow.Truncate(time.Second), feed.updated)
+
+	body := string(feed.body)
+	assert.Contains(t, body, "<id>"+templateFeedID+"/hello/abcd</id>")
+	assert.Contains(t, body, "<title>Hello &amp; welcome updated</title>")
+	assert.Contains(t, body, `<link rel="alternate" href="/filters/hello" type="text/html"></link>`)
+	assert.Contains(t, body, "The Hello &amp;amp; welcome template was updated")
+	assert.Contains(t, body, "commits/main/data/filters/hello.yaml")
+	assert.Contains(t, body, "<title>New template: Removed</title>")
+	assert.NotContains(t, body, "commits/main/data/filters/removed.yaml", "removed templates have no source")
+
+	again, err := buildTemplateFeed(versions, repo, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, feed.etag, again.etag, "the etag only depends on the versions")
+
+	empty, err := buildTemplateFeed(nil, repo, fixedNow)
+	require.NoError(t, err)
+	assert.Equal(t, fixedNow.Truncate(time.Second), empty.updated)
+	assert.NotContains(t, string(empty.body), "<entry>")
+}
+
+func TestTemplateUpdatesAtom(t *testing.T) {
+	s := &Server{}
+	e := echo.New()
+	e.GET("/filters/updates.atom", s.templateUpdatesAtom)
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/filters/updates.atom", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, get("", "").Code)
+
+	feed, err := buildTemplateFeed([]db.TemplateVersion{{
+		TemplateName: "hello",
+		TemplateHash: "abcd",
+		Title:        "Hello",
+		Change:       templateAdded,
+		CreatedAt:    fixedNow,
+	}}, loadFeedTestRepo(t, "hello##rule"), fixedNow)
+	require.NoError(t, err)
+	s.templateFeed.Store(feed)
+
+	rec := get("", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/atom+xml", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, `"`+feed.etag+`"`, rec.Header().Get("Etag"))
+	assert.Equal(t, fixedNow.UTC().Format(http.TimeFormat), rec.Header().Get(echo.HeaderLastModified))
+	assert.Equal(t, string(feed.body), rec.Body.String())
+
+	assert.Equal(t, http.StatusNotModified, get("If-None-Match", `"`+feed.etag+`"`).Code)
+	assert.Equal(t, http.StatusNotModified, get("If-None-Match", feed.etag).Code, "unquoted etags still match")
+	assert.Equal(t, http.StatusOK, get("If-None-Match", "other").Code)
+	assert.Equal(t, http.StatusNotModified, get(echo.HeaderIfModifiedSince, fixedNow.UTC().Format(http.TimeFormat)).Code)
+	assert.Equal(t, http.StatusOK, get(echo.HeaderIfModifiedSince, fixedNow.Add(-time.Minute).UTC().Format(http.TimeFormat)).Code)
+}
+
+func (s *ServerTestSuite) TestTemplateFeed_RecordsVersions() {
+	ctx := context.Background()
+	require.NoError(s.T(), s.server.loadTemplateFeed(ctx, loadFeedTestRepo(s.T(), "hello##rule")))
+	assert.NotContains(s.T(), string(s.server.templateFeed.Load().body), "<entry>", "the first load is a baseline")
+
+	// Reloading the same templates, like other instances would, records nothing
+	require.NoError(s.T(), s.server.loadTemplateFeed(ctx, loadFeedTestRepo(s.T(), "hello##rule")))
+	etag := s.server.templateFeed.Load().etag
+
+	updated := loadFeedTestRepo(s.T(), "hello##updated")
+	require.NoError(s.T(), s.server.loadTemplateFeed(ctx, updated))
+	feed := s.server.templateFeed.Load()
+	assert.NotEqual(s.T(), etag, feed.etag)
+	assert.Contains(s.T(), string(feed.body), "<id>"+templateFeedID+"/hello/"+updated.Hash("hello")+"</id>")
+	assert.NotContains(s.T(), string(feed.body), "/other/")
+
+	versions, err := s.store.GetTemplateVersions(ctx, templateFeedSize)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), versions, 1)
+	assert.Equal(s.T(), templateUpdated, versions[0].Change)
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// certManager obtains the TLS certificates and answers the ACME HTTP challenges, implemented by autocert.Manager
+type certManager interface {
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+	HTTPHandler(fallback http.Handler) http.Handler
+}
+
+// newCertManager obtains certificates from Let's Encrypt for the main and list download domains.
+// They are stored in the cache folder, to be reused across restarts instead of hitting the rate limits.
+func newCertManager(options *Options) *autocert.Manager {
+	domains := []string{options.TlsDomain}
+	if options.ListDownloadDomain != "" && options.ListDownloadDomain != options.TlsDomain {
+		domains = append(domains, options.ListDownloadDomain)
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(options.TlsCacheDir),
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Email:      options.TlsEmail,
+	}
+}
+
+// buildTLSConfig only allows TLS 1.2 and later, with the default cipher suites of the Go version.
+// The ACME protocol is advertised for the TLS-ALPN challenges, served by the certManager.
+func buildTLSConfig(certs certManager) *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1", acme.ALPNProto},
+	}
+}
+
+// buildChallengeSpec returns the plain HTTP listener answering the ACME challenges and redirecting to HTTPS
+func (s *Server) buildChallengeSpec() *listenSpec {
+	return &listenSpec{
+		spec:      "tcp://" + s.options.TlsHttpAddress,
+		network:   "tcp",
+		address:   s.options.TlsHttpAddress,
+		routes:    map[string]bool{},
+		challenge: true,
+	}
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+)
+
+// selfSignedCertificate returns a certificate for the domain, and its autocert cache entry
+func selfSignedCertificate(t *testing.T, domain string) (*tls.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	cached := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	cached = append(cached, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, cached
+}
+
+// ecdsaHello is the hello of a client supporting ECDSA certificates, that autocert caches without suffix
+var ecdsaHello = tls.ClientHelloInfo{
+	CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	SupportedCurves:  []tls.CurveID{tls.CurveP256},
+	SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+}
+
+func TestCertManager_ReusesCachedCertificate(t *testing.T) {
+	var acmeRequests atomic.Int32
+	acmeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acmeRequests.Add(1)
+		http.Error(w, "unexpected ACME request", http.StatusInternalServerError)
+	}))
+	defer acmeServer.Close()
+
+	options := &Options{TlsDomain: "example.com", ListDownloadDomain: "get.example.com", TlsCacheDir: t.TempDir()}
+	cert, cached := selfSignedCertificate(t, "example.com")
+	require.NoError(t, os.WriteFile(filepath.Join(options.TlsCacheDir, "example.com"), cached, 0o600))
+
+	// The certificate is read from the cache on startup, and kept after a restart
+	for i := 0; i < 2; i++ {
+		certs := newCertManager(options)
+		certs.Client = &acme.Client{DirectoryURL: acmeServer.URL}
+		hello := ecdsaHello
+		hello.ServerName = "example.com"
+		got, err := certs.GetCertificate(&hello)
+		require.NoError(t, err)
+		assert.Equal(t, cert.Leaf.SerialNumber, got.Leaf.SerialNumber)
+	}
+
+	// Other domains are rejected before contacting the ACME server
+	certs := newCertManager(options)
+	certs.Client = &acme.Client{DirectoryURL: acmeServer.URL}
+	hello := ecdsaHello
+	hello.ServerName = "other.example.com"
+	_, err := certs.GetCertificate(&hello)
+	assert.ErrorContains(t, err, "not configured")
+	assert.Zero(t, acmeRequests.Load())
+}
+
+// fakeCertManager serves a fixed certificate and a fixed challenge response
+type fakeCertManager struct {
+	cert  *tls.Certificate
+	calls atomic.Int32
+}
+
+func (f *fakeCertManager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	f.calls.Add(1)
+	return f.cert, nil
+}
+
+func (f *fakeCertManager) HTTPHandler(http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "challenge "+r.URL.Path)
+	})
+}
+
+func TestBuildListenSpecs_TLS(t *testing.T) {
+	s := &Server{options: &Options{Address: ":443", TlsDomain: "example.com", TlsHttpAddress: ":80"}}
+	specs, err := s.buildListenSpecs()
+	require.NoError(t, err)
+	require.Len(t, specs, 2)
+	assert.Equal(t, ":443", specs[0].address)
+	assert.False(t, specs[0].challenge)
+	assert.Equal(t, ":80", specs[1].address)
+	assert.True(t, specs[1].challenge)
+}
+
+func TestServeListeners_TLS(t *testing.T) {
+	cert, _ := selfSignedCertificate(t, "example.com")
+	certs := &fakeCertManager{cert: cert}
+	s := &Server{echo: echo.New(), options: &Options{}, statsd: &statsd.NoOpClient{}, certs: certs}
+	s.echo.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Scheme())
+	})
+
+	main, err := parseListenSpec("tcp://127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, main.listen())
+	challenge := &listenSpec{spec: "tcp://127.0.0.1:0", network: "tcp", address: "127.0.0.1:0", challenge: true}
+	require.NoError(t, challenge.listen())
+
+	served := make(chan error, 1)
+	go func() { served <- s.serveListeners([]*listenSpec{main, challenge}) }()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert.Leaf)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool, ServerName: "example.com"},
+		ForceAttemptHTTP2: true,
+	}}
+	require.Eventually(t, func() bool {
+		resp, err := client.Get("https://" + main.listener.Addr().String())
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "h2", resp.TLS.NegotiatedProtocol)
+		assert.Equal(t, "https", string(body))
+		return true
+	}, time.Second, 10*time.Millisecond)
+	assert.NotZero(t, certs.calls.Load())
+
+	_, err = (&tls.Dialer{Config: &tls.Config{
+		RootCAs:    pool,
+		ServerName: "example.com",
+		MaxVersion: tls.VersionTLS11,
+	}}).Dial("tcp", main.listener.Addr().String())
+	assert.Error(t, err, "TLS 1.1 is rejected")
+
+	resp, err := http.Get("http://" + challenge.listener.Addr().String() + "/.well-known/acme-challenge/token")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "challenge /.well-known/acme-challenge/token", string(body))
+
+	require.NoError(t, s.shutdownServers(context.Background()))
+	require.NoError(t, <-served)
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+	"github.com/samber/lo"
+)
+
+const (
+	apiTokenPrefix     = "lbit_"
+	apiTokenLength     = 32 // Random bytes in a token
+	apiTokenMaxLabel   = 64
+	apiUserContextKey  = "_api_user"
+	bearerAuthScheme   = "Bearer "
+	scopeRead          = "read"
+	scopeWrite         = "write"
+	scopeSync          = "sync"
+	apiTokenDateFormat = "2006-01-02"
+)
+
+var apiScopes = []string{scopeRead, scopeWrite, scopeSync}
+
+// apiTokenInfo holds the token information displayed in the user account page
+type apiTokenInfo struct {
+	ID         int32
+	Label      string
+	Scopes     string
+	CreatedAt  string
+	LastUsedAt string
+}
+
+// generateApiToken returns a new random token, and the hash to store
+func generateApiToken() (string, []byte, error) {
+	secret := make([]byte, apiTokenLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("cannot generate token: %w", err)
+	}
+	token := apiTokenPrefix + hex.EncodeToString(secret)
+	return token, hashApiToken(token), nil
+}
+
+// hashApiToken hashes tokens before storage and lookup. They are random enough
+// to not need a salt or a slow hash function.
+func hashApiToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+func getApiTokens(ctx context.Context, q db.Querier, user string) ([]apiTokenInfo, error) {
+	stored, err := q.GetApiTokensForUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]apiTokenInfo, 0, len(stored))
+	for _, t := range stored {
+		info := apiTokenInfo{
+			ID:        t.ID,
+			Label:     t.Label,
+			Scopes:    strings.Join(t.Scopes, ", "),
+			CreatedAt: t.CreatedAt.Format(apiTokenDateFormat),
+		}
+		if t.LastUsedAt.Valid {
+			info.LastUsedAt = t.LastUsedAt.Time.Format(apiTokenDateFormat)
+		}
+		tokens = append(tokens, info)
+	}
+	return tokens, nil
+}
+
+// createApiToken creates a new token, then renders the account page with it.
+// This is the only time the token is displayed, only its hash is stored.
+func (s *Server) createApiToken(c echo.Context) error {
+	if c.Request().Method != http.MethodPost {
+		return c.NoContent(http.StatusMethodNotAllowed)
+	}
+	user := auth.GetUserId(c)
+	if user == "" {
+		return errors.New("invalid user session")
+	}
+	formParams, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+	label := strings.TrimSpace(formParams.Get("label"))
+	var scopes []string
+	for _, scope := range apiScopes {
+		if formParams.Get("scope_"+scope) == "on" {
+			scopes = append(scopes, scope)
+		}
+	}
+	if label == "" || len(label) > apiTokenMaxLabel || len(scopes) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid arguments")
+	}
+
+	token, hash, err := generateApiToken()
+	if err != nil {
+		return err
+	}
+	if err = s.store.CreateApiToken(c.Request().Context(), db.CreateApiTokenParams{
+		UserID:    user,
+		Label:     label,
+		TokenHash: hash,
+		Scopes:    scopes,
+	}); err != nil {
+		return err
+	}
+	return s.renderUserAccount(c, func(hc *pages.Context) {
+		hc.Add("new_api_token", token)
+	})
+}
+
+func (s *Server) revokeApiToken(c echo.Context) error {
+	if c.Request().Method != http.MethodPost {
+		return c.NoContent(http.StatusMethodNotAllowed)
+	}
+	formParams, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+	id, err := strconv.ParseInt(formParams.Get("id"), 10, 32)
+	user := auth.GetUserId(c)
+	if user == "" || err != nil {
+		return errors.New("invalid arguments")
+	}
+	if err = s.store.RevokeApiToken(c.Request().Context(), db.RevokeApiTokenParams{
+		UserID: user,
+		ID:     int32(id),
+	}); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("user-account"))
+}
+
+// bearerAuth authenticates API requests with a token passed in the Authorization header.
+// Read-only requests require the read scope, all others require the write scope.
+func (s *Server) bearerAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return s.checkBearerToken(next, "")
+}
+
+// checkBearerToken authenticates API requests like bearerAuth, requiring the given scope if not empty
+func (s *Server) checkBearerToken(next echo.HandlerFunc, scope string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Request().Header.Get(echo.HeaderAuthorization)
+		if !strings.HasPrefix(header, bearerAuthScheme) {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, strings.TrimSpace(bearerAuthScheme))
+			return echo.NewHTTPError(http.StatusUnauthorized, "missing bearer token")
+		}
+		hash := hashApiToken(strings.TrimSpace(strings.TrimPrefix(header, bearerAuthScheme)))
+
+		required := scope
+		if required == "" {
+			required = scopeWrite
+			if m := c.Request().Method; m == http.MethodGet || m == http.MethodHead {
+				required = scopeRead
+			}
+		}
+
+		var user string
+		if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+			token, err := q.GetApiTokenForHash(ctx, hash)
+			switch {
+			case err == db.NotFound:
+				s.securityLog.log(c, eventInvalidApiToken)
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid bearer token")
+			case err != nil:
+				return fmt.Errorf("failed to get token: %w", err)
+			case token.Banned || s.bans.IsBanned(token.UserID):
+				return echo.ErrForbidden
+			case !lo.Contains(token.Scopes, required):
+				return echo.NewHTTPError(http.StatusForbidden, "token is missing the "+required+" scope")
+			}
+			user = token.UserID
+			return q.MarkApiTokenUsed(ctx, token.ID)
+		}); err != nil {
+			return err
+		}
+
+		c.Set(apiUserContextKey, user)
+		return next(c)
+	}
+}
+
+// getApiUser returns the user authenticated by bearerAuth
+func getApiUser(c echo.Context) string {
+	if u, ok := c.Get(apiUserContextKey).(string); ok {
+		return u
+	}
+	return ""
+}
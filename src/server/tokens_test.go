@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *ServerTestSuite) createApiToken(scopes ...string) string {
+	s.T().Helper()
+	token, hash, err := generateApiToken()
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.store.CreateApiToken(context.Background(), db.CreateApiTokenParams{
+		UserID:    s.user,
+		Label:     "test",
+		TokenHash: hash,
+		Scopes:    scopes,
+	}))
+	return token
+}
+
+func (s *ServerTestSuite) TestCreateApiToken_Ok() {
+	f := make(url.Values)
+	f.Add("label", "my script")
+	f.Add("scope_read", "on")
+	f.Add("scope_write", "on")
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/api-tokens", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	var token string
+	s.expectP.Render(gomock.Any(), "user-account", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			token, _ = hc.Data["new_api_token"].(string)
+			require.Len(s.T(), hc.Data["api_tokens"], 1)
+			return nil
+		})
+	s.runRequest(req, assertOk)
+	require.True(s.T(), strings.HasPrefix(token, apiTokenPrefix))
+
+	stored, err := s.store.GetApiTokenForHash(context.Background(), hashApiToken(token))
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), s.user, stored.UserID)
+	require.Equal(s.T(), []string{scopeRead, scopeWrite}, stored.Scopes)
+}
+
+func (s *ServerTestSuite) TestCreateApiToken_NoScope() {
+	f := make(url.Values)
+	f.Add("label", "my script")
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/api-tokens", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestUserAccount_ApiTokens() {
+	s.createApiToken(scopeRead)
+	tokens, err := s.store.GetApiTokensForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), tokens, 1)
+
+	list, err := s.store.CreateListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	req := httptest.NewRequest(http.MethodGet, "/user/account", nil)
+	s.expectRender("user-account", pages.ContextData{
+		"filter_count":    int64(0),
+		"list_token":      list.String(),
+		"list_downloaded": false,
+		"api_tokens": []apiTokenInfo{{
+			ID:        tokens[0].ID,
+			Label:     "test",
+			Scopes:    scopeRead,
+			CreatedAt: tokens[0].CreatedAt.Format(apiTokenDateFormat),
+		}},
+	})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestRevokeApiToken_Ok() {
+	token := s.createApiToken(scopeRead)
+	stored, err := s.store.GetApiTokenForHash(context.Background(), hashApiToken(token))
+	require.NoError(s.T(), err)
+
+	f := make(url.Values)
+	f.Add("id", strconv.Itoa(int(stored.ID)))
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/api-tokens/revoke", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/account")
+	s.runRequest(req, assertOk)
+
+	_, err = s.store.GetApiTokenForHash(context.Background(), hashApiToken(token))
+	require.ErrorIs(s.T(), err, db.NotFound)
+}
+
+func (s *ServerTestSuite) TestRevokeApiToken_OtherUser() {
+	token := s.createApiToken(scopeRead)
+	stored, err := s.store.GetApiTokenForHash(context.Background(), hashApiToken(token))
+	require.NoError(s.T(), err)
+
+	s.user = "other"
+	f := make(url.Values)
+	f.Add("id", strconv.Itoa(int(stored.ID)))
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/api-tokens/revoke", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/account")
+	s.runRequest(req, assertOk)
+
+	_, err = s.store.GetApiTokenForHash(context.Background(), hashApiToken(token))
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// tokenScanWebhookTimeout bounds the calls to the token scan webhook
+const tokenScanWebhookTimeout = 10 * time.Second
+
+var (
+	malformedTokenTag = []string{"reason:malformed"}
+	unknownTokenTag   = []string{"reason:unknown"}
+)
+
+// tokenScanAlert is the JSON body posted to the token scan webhook
+type tokenScanAlert struct {
+	Event         string `json:"event"`
+	UnknownTokens int    `json:"unknown_tokens"`
+	Window        string `json:"window"`
+	DetectedAt    string `json:"detected_at"`
+}
+
+// tokenScanDetector counts the invalid list tokens requested since startup, and alerts when the unknown
+// tokens requested instance-wide exceed threshold during a sliding window. It only keeps the times of the
+// last threshold+1 unknown tokens: if the oldest one is in the window, the threshold is exceeded.
+type tokenScanDetector struct {
+	sync.Mutex
+	malformed atomic.Int64
+	unknown   atomic.Int64
+	window    time.Duration
+	now       func() time.Time
+	alert     func(count int, window time.Duration)
+	times     []time.Time // Ring buffer, nil if the detection is disabled
+	next      int
+	alertedAt time.Time
+}
+
+// newTokenScanDetector returns a detector calling alert at most once per window, the detection is disabled
+// if threshold or window are zero, but the invalid tokens are still counted
+func newTokenScanDetector(threshold int, window time.Duration, now func() time.Time, alert func(int, time.Duration)) *tokenScanDetector {
+	d := &tokenScanDetector{window: window, now: now, alert: alert}
+	if threshold > 0 && window > 0 {
+		d.times = make([]time.Time, threshold+1)
+	}
+	return d
+}
+
+// recordMalformed counts a token that is not a valid UUID
+func (d *tokenScanDetector) recordMalformed() {
+	if d != nil {
+		d.malformed.Add(1)
+	}
+}
+
+// recordUnknown counts a well-formed token matching no list, and alerts if the threshold is exceeded
+func (d *tokenScanDetector) recordUnknown() {
+	if d == nil {
+		return
+	}
+	d.unknown.Add(1)
+	if d.times == nil {
+		return
+	}
+	d.Lock()
+	now := d.now()
+	d.times[d.next] = now
+	d.next = (d.next + 1) % len(d.times)
+	oldest := d.times[d.next] // Zero until the buffer is filled
+	exceeded := now.Sub(oldest) <= d.window && now.Sub(d.alertedAt) > d.window
+	if exceeded {
+		d.alertedAt = now
+	}
+	d.Unlock()
+
+	if exceeded {
+		d.alert(len(d.times), d.window)
+	}
+}
+
+// counts returns the malformed and unknown tokens requested since startup
+func (d *tokenScanDetector) counts() (malformed, unknown int64) {
+	if d == nil {
+		return 0, 0
+	}
+	return d.malformed.Load(), d.unknown.Load()
+}
+
+// countInvalidToken records an invalid list token in the metrics and the scan detector
+func (s *Server) countInvalidToken(malformed bool) {
+	if malformed {
+		_ = s.statsd.Incr("letsblockit.list_token_invalid", malformedTokenTag, 1)
+		s.tokenScans.recordMalformed()
+	} else {
+		_ = s.statsd.Incr("letsblockit.list_token_invalid", unknownTokenTag, 1)
+		s.tokenScans.recordUnknown()
+	}
+}
+
+// alertTokenScan logs a warning for a token scan, and posts it to the configured webhook if any
+func (s *Server) alertTokenScan(count int, window time.Duration) {
+	s.echo.Logger.Warnf("token scan detected: %d unknown list tokens requested in less than %s", count, window)
+	_ = s.statsd.Incr("letsblockit.token_scan_detected", nil, 1)
+	url := s.config().options.TokenScanWebhook
+	if url == "" {
+		return
+	}
+	body, err := json.Marshal(tokenScanAlert{
+		Event:         "token_scan",
+		UnknownTokens: count,
+		Window:        window.String(),
+		DetectedAt:    s.now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		s.echo.Logger.Error("cannot encode the token scan alert: " + err.Error())
+		return
+	}
+	go func() {
+		client := &http.Client{Timeout: tokenScanWebhookTimeout}
+		resp, err := client.Post(url, echo.MIMEApplicationJSON, bytes.NewReader(body))
+		if err != nil {
+			s.echo.Logger.Error("cannot call the token scan webhook: " + err.Error())
+			return
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			s.echo.Logger.Errorf("token scan webhook returned status %d", resp.StatusCode)
+		}
+	}()
+}
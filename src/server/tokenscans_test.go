@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenScanDetector(t *testing.T) {
+	now := fixedNow
+	var alerts []int
+	detector := newTokenScanDetector(2, time.Minute, func() time.Time { return now }, func(count int, window time.Duration) {
+		assert.Equal(t, time.Minute, window)
+		alerts = append(alerts, count)
+	})
+
+	detector.recordMalformed()
+	detector.recordUnknown()
+	detector.recordUnknown()
+	assert.Empty(t, alerts, "the threshold is not exceeded")
+	detector.recordUnknown()
+	assert.Equal(t, []int{3}, alerts)
+	detector.recordUnknown()
+	assert.Equal(t, []int{3}, alerts, "only one alert per window")
+
+	// Older tokens leave the window
+	now = now.Add(2 * time.Minute)
+	detector.recordUnknown()
+	detector.recordUnknown()
+	assert.Equal(t, []int{3}, alerts)
+	detector.recordUnknown()
+	assert.Equal(t, []int{3, 3}, alerts)
+
+	malformed, unknown := detector.counts()
+	assert.EqualValues(t, 1, malformed)
+	assert.EqualValues(t, 7, unknown)
+}
+
+func TestTokenScanDetector_Disabled(t *testing.T) {
+	detector := newTokenScanDetector(0, time.Minute, time.Now, func(int, time.Duration) {
+		t.Fatal("unexpected alert")
+	})
+	for i := 0; i < 10; i++ {
+		detector.recordUnknown()
+	}
+	_, unknown := detector.counts()
+	assert.EqualValues(t, 10, unknown, "tokens are still counted")
+
+	var missing *tokenScanDetector
+	missing.recordMalformed()
+	missing.recordUnknown()
+	malformed, unknown := missing.counts()
+	assert.Zero(t, malformed+unknown)
+}
+
+func TestAlertTokenScan_Webhook(t *testing.T) {
+	alerts := make(chan tokenScanAlert, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert tokenScanAlert
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		alerts <- alert
+	}))
+	defer webhook.Close()
+
+	s := &Server{echo: echo.New(), now: func() time.Time { return fixedNow }, statsd: &statsd.NoOpClient{}}
+	s.echo.Logger.SetLevel(log.OFF)
+	s.live.Store(&liveConfig{options: &Options{TokenScanWebhook: webhook.URL}})
+	s.alertTokenScan(31, time.Minute)
+
+	select {
+	case alert := <-alerts:
+		assert.Equal(t, tokenScanAlert{
+			Event:         "token_scan",
+			UnknownTokens: 31,
+			Window:        "1m0s",
+			DetectedAt:    "2020-06-02T17:44:22Z",
+		}, alert)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the webhook was not called")
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// cliUserAgent prefixes the user agent of the render CLI, set in cmd/render/remote.go.
+// The trace context of other clients is ignored, for them not to pollute our traces.
+const cliUserAgent = "letsblockit-render"
+
+var tracer = otel.Tracer("github.com/letsblockit/letsblockit/src/server")
+
+// traceRequests creates a span per request, named after its route. It is only
+// registered when tracing is enabled, health checks are not traced.
+func traceRequests(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		if isHealthPath(req.URL.Path) {
+			return next(c)
+		}
+		ctx := req.Context()
+		if strings.HasPrefix(req.UserAgent(), cliUserAgent) {
+			ctx = propagation.TraceContext{}.Extract(ctx, propagation.HeaderCarrier(req.Header))
+		}
+		ctx, span := tracer.Start(ctx, req.Method+" "+c.Path(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", req.Method),
+				attribute.String("http.route", c.Path()),
+				attribute.String("request_id", c.Response().Header().Get(echo.HeaderXRequestID)),
+			))
+		defer span.End()
+		c.SetRequest(req.WithContext(ctx))
+
+		err := next(c)
+		status := c.Response().Status
+		if err != nil {
+			// The error is rendered by the error handler after the middlewares return
+			status = http.StatusInternalServerError
+			var he *echo.HTTPError
+			var ae *apiError
+			switch {
+			case errors.As(err, &he):
+				status = he.Code
+			case errors.As(err, &ae):
+				status = ae.Status
+			}
+		}
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		return err
+	}
+}
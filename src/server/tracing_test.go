@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceRequests(t *testing.T) {
+	// Tracers obtained before the provider is set are bound to the first provider, it cannot be reset
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	e := echo.New()
+	e.Use(buildRequestIDMiddleware(), traceRequests)
+	var handlerSpan trace.SpanContext
+	e.GET("/filters/:name", func(c echo.Context) error {
+		handlerSpan = trace.SpanContextFromContext(c.Request().Context())
+		if c.Param("name") == "broken" {
+			return echo.ErrInternalServerError
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET(healthPath, func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	const parent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	serve := func(path, userAgent string) sdktrace.ReadOnlySpan {
+		count := len(recorder.Ended())
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set("traceparent", parent)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+		spans := recorder.Ended()
+		require.Len(t, spans, count+1)
+		return spans[count]
+	}
+
+	span := serve("/filters/one", cliUserAgent)
+	assert.Equal(t, "GET /filters/:name", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", span.SpanContext().TraceID().String(),
+		"the trace context of the CLI is propagated")
+	assert.Equal(t, span.SpanContext(), handlerSpan, "the span is passed to the handler")
+	assert.Contains(t, span.Attributes(), attribute.Int("http.status_code", http.StatusOK))
+	assert.Equal(t, codes.Unset, span.Status().Code)
+
+	span = serve("/filters/broken", "curl/8.0")
+	assert.NotEqual(t, "0af7651916cd43dd8448eb211c80319c", span.SpanContext().TraceID().String(),
+		"the trace context of other clients is ignored")
+	assert.Contains(t, span.Attributes(), attribute.Int("http.status_code", http.StatusInternalServerError))
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	count := len(recorder.Ended())
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, healthPath, nil))
+	assert.Len(t, recorder.Ended(), count, "health checks are not traced")
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+	"zgo.at/zcache/v2"
+)
+
+// Removed instances are deleted from the database right away, but kept in memory for a grace period,
+// to let users undo an accidental removal. Only the latest removals of each user are kept.
+const (
+	undoGracePeriod = 5 * time.Minute
+	undoMaxRemovals = 5 // Per user, older removals cannot be undone
+)
+
+// removedInstance holds a removed filter instance, to restore it as it was
+type removedInstance struct {
+	db.DeleteInstanceForUndoRow
+	Template  string
+	Title     string
+	RemovedAt time.Time
+}
+
+// undoStash holds the instances recently removed by each user, most recent first.
+// The entries of a user are dropped after the grace period following their latest removal.
+type undoStash struct {
+	sync.Mutex
+	removed *zcache.Cache[string, []*removedInstance]
+}
+
+func (u *undoStash) add(user string, removed *removedInstance) {
+	u.Lock()
+	defer u.Unlock()
+	if u.removed == nil {
+		u.removed = zcache.New[string, []*removedInstance](undoGracePeriod, undoGracePeriod)
+	}
+	previous, _ := u.removed.Get(user)
+	entries := []*removedInstance{removed}
+	for _, e := range previous {
+		if e.Template != removed.Template && len(entries) < undoMaxRemovals {
+			entries = append(entries, e)
+		}
+	}
+	u.removed.Set(user, entries)
+}
+
+// get returns the removals of a user that can still be undone
+func (u *undoStash) get(user string, now time.Time) []*removedInstance {
+	u.Lock()
+	defer u.Unlock()
+	if u.removed == nil {
+		return nil
+	}
+	entries, _ := u.removed.Get(user)
+	var out []*removedInstance
+	for _, e := range entries {
+		if now.Sub(e.RemovedAt) < undoGracePeriod {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// forget drops a removal once it is undone, or all the removals of a user if template is empty
+func (u *undoStash) forget(user, template string) {
+	u.Lock()
+	defer u.Unlock()
+	if u.removed == nil {
+		return
+	}
+	entries, _ := u.removed.Get(user)
+	var kept []*removedInstance
+	for _, e := range entries {
+		if template != "" && e.Template != template {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == 0 {
+		u.removed.Delete(user)
+	} else {
+		u.removed.Set(user, kept)
+	}
+}
+
+// removeInstance deletes an instance and keeps it in the undo stash, removing a missing instance is a no-op
+func (s *Server) removeInstance(ctx context.Context, user, template, title string) error {
+	removed, err := s.store.DeleteInstanceForUndo(ctx, db.DeleteInstanceForUndoParams{
+		UserID:       user,
+		TemplateName: template,
+	})
+	switch {
+	case err == db.NotFound:
+		return nil
+	case err != nil:
+		return err
+	}
+	s.undo.add(user, &removedInstance{
+		DeleteInstanceForUndoRow: removed,
+		Template:                 template,
+		Title:                    title,
+		RemovedAt:                s.now(),
+	})
+	s.notifyListChange(user, webhookInstanceDeleted, template)
+	return nil
+}
+
+// undoRemoval restores a recently removed instance with its parameters and dates
+func (s *Server) undoRemoval(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return errors.New("invalid user session")
+	}
+	var removed *removedInstance
+	for _, r := range s.undo.get(user, s.now()) {
+		if r.Template == c.Param("name") {
+			removed = r
+		}
+	}
+	if removed == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "this removal cannot be undone anymore")
+	}
+
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		if err := checkEphemeralLimit(ctx, q, user); err != nil {
+			return err
+		}
+		count, err := q.CountInstances(ctx, db.CountInstancesParams{UserID: user, TemplateName: removed.Template})
+		switch {
+		case err != nil:
+			return err
+		case count > 0:
+			return echo.NewHTTPError(http.StatusConflict, "this filter has been added to your list again")
+		}
+		return q.RestoreInstance(ctx, db.RestoreInstanceParams{
+			UserID:       user,
+			TemplateName: removed.Template,
+			Params:       removed.Params,
+			TestMode:     removed.TestMode,
+			Pinned:       removed.Pinned,
+			TemplateHash: removed.TemplateHash,
+			CreatedAt:    removed.CreatedAt,
+			UpdatedAt:    removed.UpdatedAt,
+		})
+	}); err != nil {
+		return err
+	}
+	s.undo.forget(user, removed.Template)
+	s.notifyListChange(user, webhookInstanceCreated, removed.Template)
+	_ = s.statsd.Incr("letsblockit.instance_restored", nil, 1)
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("view-filter", removed.Template))
+}
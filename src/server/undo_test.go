@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndoStash(t *testing.T) {
+	var stash undoStash
+	now := time.Now()
+	assert.Empty(t, stash.get("user", now))
+
+	for i := 0; i < undoMaxRemovals+2; i++ {
+		stash.add("user", &removedInstance{Template: fmt.Sprintf("filter%d", i), RemovedAt: now})
+	}
+	removed := stash.get("user", now)
+	require.Len(t, removed, undoMaxRemovals)
+	assert.Equal(t, fmt.Sprintf("filter%d", undoMaxRemovals+1), removed[0].Template)
+	assert.Empty(t, stash.get("other", now))
+
+	// Removing a template again replaces its previous entry
+	stash.add("user", &removedInstance{Template: "filter3", RemovedAt: now})
+	removed = stash.get("user", now)
+	require.Len(t, removed, undoMaxRemovals)
+	assert.Equal(t, "filter3", removed[0].Template)
+	assert.NotEqual(t, "filter3", removed[undoMaxRemovals-1].Template)
+
+	stash.forget("user", "filter3")
+	assert.Len(t, stash.get("user", now), undoMaxRemovals-1)
+	assert.Empty(t, stash.get("user", now.Add(undoGracePeriod)))
+	stash.forget("user", "")
+	assert.Empty(t, stash.get("user", now))
+}
+
+func (s *ServerTestSuite) removeFilterRequest(name string) *http.Request {
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	f.Add("__disable", "")
+	req := httptest.NewRequest(http.MethodPost, "/filters/"+name, strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) undoRemovalRequest(name string) *http.Request {
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/filters/"+name+"/undo", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) TestUndoRemoval_OK() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{
+		Template: "filter2",
+		Params:   filter2Custom,
+		TestMode: true,
+	}))
+	before, err := s.store.GetInstanceDetails(context.Background(), db.GetInstanceDetailsParams{
+		UserID:       s.user,
+		TemplateName: "filter2",
+	})
+	require.NoError(s.T(), err)
+
+	s.expectP.RedirectToPage(gomock.Any(), "list-filters")
+	s.runRequest(s.removeFilterRequest("filter2"), assertOk)
+	s.requireInstanceCount("filter2", 0)
+
+	s.expectP.Render(gomock.Any(), "list-filters", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			removed, ok := hc.Data["removed_filters"].([]*removedInstance)
+			require.True(s.T(), ok)
+			require.Len(s.T(), removed, 1)
+			assert.Equal(s.T(), "filter2", removed[0].Template)
+			assert.Equal(s.T(), filter2.Title, removed[0].Title)
+			return nil
+		})
+	s.runRequest(httptest.NewRequest(http.MethodGet, "/filters", nil), assertOk)
+
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters/filter2")
+	s.runRequest(s.undoRemovalRequest("filter2"), assertOk)
+	after, err := s.store.GetInstanceDetails(context.Background(), db.GetInstanceDetailsParams{
+		UserID:       s.user,
+		TemplateName: "filter2",
+	})
+	require.NoError(s.T(), err)
+	s.requireJSONEq(filter2Custom, after.Params)
+	s.True(after.TestMode)
+	s.True(before.CreatedAt.Equal(after.CreatedAt))
+	s.Empty(s.server.undo.get(s.user, fixedNow))
+
+	// Each removal can only be undone once
+	s.runRequest(s.undoRemovalRequest("filter2"), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestUndoRemoval_AddedAgain() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+	s.expectP.RedirectToPage(gomock.Any(), "list-filters")
+	s.runRequest(s.removeFilterRequest("filter1"), assertOk)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+
+	s.runRequest(s.undoRemovalRequest("filter1"), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+	s.requireInstanceCount("filter1", 1)
+}
+
+func (s *ServerTestSuite) TestUndoRemoval_Expired() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+	s.expectP.RedirectToPage(gomock.Any(), "list-filters")
+	s.runRequest(s.removeFilterRequest("filter1"), assertOk)
+
+	s.server.now = func() time.Time { return fixedNow.Add(undoGracePeriod) }
+	s.runRequest(s.undoRemovalRequest("filter1"), func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	s.requireInstanceCount("filter1", 0)
+}
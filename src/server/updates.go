@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+const templateHistoryUrl = "https://github.com/letsblockit/letsblockit/commits/main/data/filters/%s.yaml"
+
+// templateUpdate describes a template that changed since the user last acknowledged it
+type templateUpdate struct {
+	Name         string `json:"name"`
+	Title        string `json:"title"`
+	ChangelogUrl string `json:"changelog_url,omitempty"`
+}
+
+// getTemplateUpdates compares the templates a user has instances of with the versions they acknowledged.
+// Templates without an acknowledgement are considered up-to-date, and their current version is acknowledged.
+func (s *Server) getTemplateUpdates(ctx context.Context, q db.Querier, user string, templates []string) ([]templateUpdate, error) {
+	if strings.HasPrefix(user, ephemeralUserPrefix) {
+		return nil, nil
+	}
+	stored, err := q.GetTemplateAcksForUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	acks := make(map[string]string, len(stored))
+	for _, a := range stored {
+		acks[a.TemplateName] = a.TemplateHash
+	}
+
+	repo := s.config().filters
+	var updates []templateUpdate
+	for _, name := range templates {
+		filter, err := repo.Get(name)
+		if err != nil {
+			continue
+		}
+		hash := repo.Hash(name)
+		switch acked, found := acks[name]; {
+		case !found:
+			if err = s.ackTemplate(ctx, q, user, name); err != nil {
+				return nil, err
+			}
+		case acked != hash:
+			update := templateUpdate{Name: name, Title: filter.Title}
+			if repo.Source(name) == "embedded" {
+				update.ChangelogUrl = fmt.Sprintf(templateHistoryUrl, name)
+			}
+			updates = append(updates, update)
+		}
+	}
+	return updates, nil
+}
+
+// ackTemplate marks the current version of a template as seen by the user
+func (s *Server) ackTemplate(ctx context.Context, q db.Querier, user, name string) error {
+	if strings.HasPrefix(user, ephemeralUserPrefix) {
+		return nil
+	}
+	return q.AckTemplate(ctx, db.AckTemplateParams{
+		UserID:       user,
+		TemplateName: name,
+		TemplateHash: s.config().filters.Hash(name),
+	})
+}
+
+// dismissTemplateUpdates acknowledges the current version of all the templates used by the user
+func (s *Server) dismissTemplateUpdates(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		instances, err := q.GetInstancesForUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		for _, instance := range instances {
+			if err = s.ackTemplate(ctx, q, user, instance.TemplateName); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("list-filters"))
+}
+
+func (s *Server) apiTemplateUpdates(c echo.Context) error {
+	var updates []templateUpdate
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		instances, err := q.GetInstancesForUser(ctx, getApiUser(c))
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(instances))
+		for _, instance := range instances {
+			names = append(names, instance.TemplateName)
+		}
+		updates, err = s.getTemplateUpdates(ctx, q, getApiUser(c), names)
+		return err
+	}); err != nil {
+		return err
+	}
+	if updates == nil {
+		updates = []templateUpdate{}
+	}
+	return c.JSON(http.StatusOK, updates)
+}
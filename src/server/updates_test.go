@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/filters"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var filter2Update = templateUpdate{
+	Name:         "filter2",
+	Title:        "Second filter",
+	ChangelogUrl: "https://github.com/letsblockit/letsblockit/commits/main/data/filters/filter2.yaml",
+}
+
+// ackOutdatedTemplate simulates a template update, by acknowledging an older version
+func (s *ServerTestSuite) ackOutdatedTemplate(name string) {
+	s.T().Helper()
+	require.NoError(s.T(), s.store.AckTemplate(context.Background(), db.AckTemplateParams{
+		UserID:       s.user,
+		TemplateName: name,
+		TemplateHash: "outdated",
+	}))
+}
+
+func (s *ServerTestSuite) TestTemplateUpdates_AckedOnSave() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter2"}))
+	updates, err := s.server.getTemplateUpdates(context.Background(), s.store, s.user, []string{"filter2"})
+	require.NoError(s.T(), err)
+	s.Empty(updates)
+
+	s.ackOutdatedTemplate("filter2")
+	updates, err = s.server.getTemplateUpdates(context.Background(), s.store, s.user, []string{"filter2"})
+	require.NoError(s.T(), err)
+	s.Equal([]templateUpdate{filter2Update}, updates)
+
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter2"}))
+	updates, err = s.server.getTemplateUpdates(context.Background(), s.store, s.user, []string{"filter2"})
+	require.NoError(s.T(), err)
+	s.Empty(updates)
+}
+
+func (s *ServerTestSuite) TestTemplateUpdates_Banner() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter2"}))
+	s.ackOutdatedTemplate("filter2")
+
+	req := httptest.NewRequest(http.MethodGet, "/filters", nil)
+	s.expectP.Render(gomock.Any(), "list-filters", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.Equal(s.T(), []templateUpdate{filter2Update}, hc.Data["template_updates"])
+			return nil
+		})
+	s.runRequest(req, assertOk)
+}
+
+func (s *ServerTestSuite) TestTemplateUpdates_Dismiss() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter2"}))
+	s.ackOutdatedTemplate("filter2")
+
+	f := make(url.Values)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/filters/updates/dismiss", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/filters")
+	s.runRequest(req, assertOk)
+
+	updates, err := s.server.getTemplateUpdates(context.Background(), s.store, s.user, []string{"filter2"})
+	require.NoError(s.T(), err)
+	s.Empty(updates)
+}
+
+func (s *ServerTestSuite) TestTemplateUpdates_Api() {
+	token := s.createApiToken(scopeRead)
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter2"}))
+	s.ackOutdatedTemplate("filter2")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/template-updates", nil)
+	s.runApiRequest(req, token, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assertOk(t, rec)
+		assert.JSONEq(t, `[{"name":"filter2","title":"`+filter2Update.Title+`",`+
+			`"changelog_url":"`+filter2Update.ChangelogUrl+`"}]`, rec.Body.String())
+	})
+}
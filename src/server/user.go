@@ -4,18 +4,70 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
 	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/pages"
 	"github.com/letsblockit/letsblockit/src/users/auth"
 )
 
+const deleteAccountPhrase = "delete my account"
+
 func (s *Server) userAccount(c echo.Context) error {
+	return s.renderUserAccount(c, nil)
+}
+
+// renderUserAccount renders the account page, extraData can add information before rendering
+func (s *Server) renderUserAccount(c echo.Context, extraData func(hc *pages.Context)) error {
 	hc := s.buildPageContext(c, "My account")
 	hc.NoBoost = true
 	if hc.UserLoggedIn {
+		if expiresAt, ok := c.Get(ephemeralCookieName).(time.Time); ok {
+			hc.Add("ephemeral_expires", expiresAt.UTC().Format(time.RFC1123))
+		}
 		if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+			if !hc.UserIsEphemeral {
+				tokens, err := getApiTokens(ctx, q, hc.UserID)
+				if err != nil {
+					return err
+				}
+				if len(tokens) > 0 {
+					hc.Add("api_tokens", tokens)
+				}
+				shareTokens, err := s.getShareTokens(ctx, c, q, hc.UserID)
+				if err != nil {
+					return err
+				}
+				if len(shareTokens) > 0 {
+					hc.Add("share_tokens", shareTokens)
+				}
+				sessions, err := getSessions(ctx, q, hc.UserID, auth.GetSessionId(c))
+				if err != nil {
+					return err
+				}
+				if len(sessions) > 0 {
+					hc.Add("sessions", sessions)
+				}
+				switch webhook, err := q.GetWebhookForUser(ctx, hc.UserID); err {
+				case nil:
+					hc.Add("webhook_url", webhook.Url)
+					hc.Add("webhook_has_secret", webhook.Secret != "")
+				case db.NotFound: // ok
+				default:
+					return err
+				}
+				deliveries, err := getWebhookDeliveries(ctx, q, hc.UserID)
+				if err != nil {
+					return err
+				}
+				if len(deliveries) > 0 {
+					hc.Add("webhook_deliveries", deliveries)
+				}
+			}
+
 			info, err := q.GetListForUser(ctx, hc.UserID)
 			switch err {
 			case nil:
@@ -24,6 +76,9 @@ func (s *Server) userAccount(c echo.Context) error {
 				hc.Add("list_token", info.Token.String())
 				return nil
 			case db.NotFound:
+				if hc.UserIsEphemeral {
+					return echo.NewHTTPError(http.StatusForbidden, "your temporary list has expired")
+				}
 				token, err := q.CreateListForUser(ctx, hc.UserID)
 				hc.Add("filter_count", 0)
 				hc.Add("list_downloaded", false)
@@ -36,6 +91,9 @@ func (s *Server) userAccount(c echo.Context) error {
 			return err
 		}
 	}
+	if extraData != nil {
+		extraData(hc)
+	}
 	return s.pages.Render(c, "user-account", hc)
 }
 
@@ -88,3 +146,39 @@ func (s *Server) rotateListToken(c echo.Context) error {
 
 	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("user-account"))
 }
+
+// deleteAccount deletes all the data of the user, after they typed the confirmation phrase.
+// Bans are kept to prevent evasion. With the kratos backend, the request is then forwarded
+// to the logout action with a 307 redirect, to keep the POST method and the CSRF token.
+func (s *Server) deleteAccount(c echo.Context) error {
+	if c.Request().Method != http.MethodPost {
+		return c.NoContent(http.StatusMethodNotAllowed)
+	}
+	user := auth.GetUserId(c)
+	if user == "" {
+		return errors.New("invalid user session")
+	}
+	formParams, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+	if formParams.Get("confirm") != deleteAccountPhrase {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid confirmation phrase")
+	}
+
+	if err := s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		return db.DeleteUserData(ctx, q, user)
+	}); err != nil {
+		return err
+	}
+	s.preferences.Forget(user)
+	s.undo.forget(user, "")
+
+	c.Logger().Infoj(log.JSON{"audit": "account_deleted", "user_id": user})
+	_ = s.statsd.Incr("letsblockit.account_deleted", nil, 1)
+
+	if logout := s.echo.Reverse("user-action", "logout"); logout != "" {
+		return s.pages.Redirect(c, http.StatusTemporaryRedirect, logout)
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("landing"))
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
 	"github.com/letsblockit/letsblockit/src/filters"
 	"github.com/letsblockit/letsblockit/src/pages"
 	"github.com/stretchr/testify/assert"
@@ -94,3 +95,45 @@ func (s *ServerTestSuite) TestRotateListToken_MissingCSRF() {
 		assert.Equal(t, 400, recorder.Result().StatusCode)
 	})
 }
+
+func (s *ServerTestSuite) TestDeleteAccount_Ok() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+	list, err := s.store.GetListForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	token := s.createApiToken(scopeRead)
+
+	f := make(url.Values)
+	f.Add("confirm", deleteAccountPhrase)
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/delete-account", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/")
+	s.runRequest(req, assertOk)
+
+	s.requireInstanceCount("filter1", 0)
+	_, err = s.store.GetListForUser(context.Background(), s.user)
+	require.ErrorIs(s.T(), err, db.NotFound)
+	_, err = s.store.GetUserPreferences(context.Background(), s.user)
+	require.ErrorIs(s.T(), err, db.NotFound)
+	_, err = s.store.GetApiTokenForHash(context.Background(), hashApiToken(token))
+	require.ErrorIs(s.T(), err, db.NotFound)
+
+	req = httptest.NewRequest(http.MethodGet, "/list/"+list.Token.String(), nil)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func (s *ServerTestSuite) TestDeleteAccount_BadConfirmation() {
+	require.NoError(s.T(), s.server.upsertFilterParams(s.c, s.user, &filters.Instance{Template: "filter1"}))
+
+	f := make(url.Values)
+	f.Add("confirm", "on")
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, "/user/delete-account", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	s.requireInstanceCount("filter1", 1)
+}
@@ -0,0 +1,388 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/letsblockit/letsblockit/src/users/auth"
+)
+
+// Users can register a webhook, called when their list changes. Deliveries are sent
+// in the background: failures are logged for the user, but never fail their requests.
+const (
+	webhookQueueSize       = 256
+	webhookWorkers         = 4
+	webhookTimeout         = 10 * time.Second
+	webhookMaxURLLength    = 512
+	webhookKeptDeliveries  = 20
+	webhookSignatureHeader = "X-Letsblockit-Signature"
+	webhookEventHeader     = "X-Letsblockit-Event"
+)
+
+// Webhook event types
+const (
+	webhookInstanceCreated = "instance.created"
+	webhookInstanceUpdated = "instance.updated"
+	webhookInstanceDeleted = "instance.deleted"
+	webhookTest            = "test"
+)
+
+// webhookRetryDelays are the delays before retrying a failed delivery
+var webhookRetryDelays = []time.Duration{2 * time.Second, 10 * time.Second}
+
+type webhookEvent struct {
+	User      string
+	Event     string
+	Template  string
+	Timestamp time.Time
+}
+
+// webhookPayload is the JSON body sent to webhooks. The list token is not sent as it
+// grants access to the list, its digest allows users with several lists to tell them apart.
+type webhookPayload struct {
+	Event      string    `json:"event"`
+	Template   string    `json:"template,omitempty"`
+	ListDigest string    `json:"list_digest"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// webhookDeliveryInfo holds the delivery information displayed in the account page
+type webhookDeliveryInfo struct {
+	Event      string
+	StatusCode int32
+	Error      string
+	Attempts   int32
+	CreatedAt  string
+	Success    bool
+}
+
+type webhookDispatcher struct {
+	client       *http.Client
+	events       chan *webhookEvent
+	retryDelays  []time.Duration
+	statsd       statsd.ClientInterface
+	store        db.Store
+	allowPrivate bool
+
+	ctx     context.Context // Cancelled by Stop, to abort the deliveries that are too slow to drain
+	cancel  context.CancelFunc
+	stop    chan struct{}
+	workers sync.WaitGroup
+}
+
+func newWebhookDispatcher(store db.Store, statsd statsd.ClientInterface, allowPrivate bool) *webhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &webhookDispatcher{
+		events:       make(chan *webhookEvent, webhookQueueSize),
+		retryDelays:  webhookRetryDelays,
+		statsd:       statsd,
+		store:        store,
+		allowPrivate: allowPrivate,
+		ctx:          ctx,
+		cancel:       cancel,
+		stop:         make(chan struct{}),
+	}
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: d.checkAddress}
+	d.client = &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return d
+}
+
+// checkAddress prevents webhooks from targeting the server's internal network
+func (d *webhookDispatcher) checkAddress(_, address string, _ syscall.RawConn) error {
+	if d.allowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("address %s is not allowed", host)
+	}
+	return nil
+}
+
+// Notify queues an event for delivery, dropping it if the queue is full
+func (d *webhookDispatcher) Notify(event *webhookEvent) {
+	if d == nil {
+		return
+	}
+	select {
+	case d.events <- event:
+	default:
+		_ = d.statsd.Incr("letsblockit.webhook_dropped", nil, 1)
+	}
+}
+
+// Run starts the workers delivering the queued events, until Stop is called
+func (d *webhookDispatcher) Run(log echo.Logger) {
+	deliver := func(event *webhookEvent) {
+		if err := d.deliver(d.ctx, event); err != nil {
+			log.Error("cannot deliver webhook: " + err.Error())
+		}
+	}
+	for i := 0; i < webhookWorkers; i++ {
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			for {
+				select {
+				case <-d.stop:
+					for { // Drain the queue before exiting
+						select {
+						case event := <-d.events:
+							deliver(event)
+						default:
+							return
+						}
+					}
+				case event := <-d.events:
+					deliver(event)
+				}
+			}
+		}()
+	}
+}
+
+// Stop delivers the queued events and stops the workers. Deliveries still running
+// when ctx is done are aborted, and Stop returns the context error.
+func (d *webhookDispatcher) Stop(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+	close(d.stop)
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+	defer d.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// deliver sends an event to the user's webhook if they have one, and logs the result.
+// Only internal errors are returned, delivery errors are logged for the user to see.
+func (d *webhookDispatcher) deliver(ctx context.Context, event *webhookEvent) error {
+	webhook, err := d.store.GetWebhookForUser(ctx, event.User)
+	switch {
+	case err == db.NotFound:
+		return nil
+	case err != nil:
+		return err
+	}
+
+	payload := webhookPayload{
+		Event:     event.Event,
+		Template:  event.Template,
+		Timestamp: event.Timestamp.UTC(),
+	}
+	switch list, err := d.store.GetListForUser(ctx, event.User); err {
+	case nil:
+		digest := sha256.Sum256([]byte(list.Token.String()))
+		payload.ListDigest = hex.EncodeToString(digest[:])
+	case db.NotFound: // ok
+	default:
+		return err
+	}
+	body, err := json.Marshal(&payload)
+	if err != nil {
+		return err
+	}
+
+	var attempts int32
+	var statusCode int
+	var deliveryErr error
+	for {
+		attempts++
+		statusCode, deliveryErr = d.send(ctx, webhook, event.Event, body)
+		if deliveryErr == nil || int(attempts) > len(d.retryDelays) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d.retryDelays[attempts-1]):
+		}
+	}
+
+	_ = d.statsd.Incr("letsblockit.webhook_delivery", []string{
+		fmt.Sprintf("success:%t", deliveryErr == nil),
+	}, 1)
+	logged := db.LogWebhookDeliveryParams{
+		UserID:     event.User,
+		Event:      event.Event,
+		StatusCode: int32(statusCode),
+		Attempts:   attempts,
+	}
+	if deliveryErr != nil {
+		logged.Error = deliveryErr.Error()
+	}
+	if err = d.store.LogWebhookDelivery(ctx, logged); err != nil {
+		return err
+	}
+	return d.store.PruneWebhookDeliveries(ctx, db.PruneWebhookDeliveriesParams{
+		UserID: event.User,
+		Keep:   webhookKeptDeliveries,
+	})
+}
+
+// send posts the payload once, signing it if the webhook has a secret
+func (d *webhookDispatcher) send(ctx context.Context, webhook db.GetWebhookForUserRow, event string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("User-Agent", "letsblock.it webhooks")
+	req.Header.Set(webhookEventHeader, event)
+	if webhook.Secret != "" {
+		req.Header.Set(webhookSignatureHeader, signWebhookPayload(webhook.Secret, body))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err // Don't repeat the URL in the delivery log
+		}
+		return 0, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// signWebhookPayload returns the HMAC-SHA256 signature of the body, in the format used by GitHub
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notifyListChange queues a webhook event and invalidates the user's hot lists, to call once the change is committed
+func (s *Server) notifyListChange(user, event, template string) {
+	s.hotLists.Invalidate(user)
+	s.webhooks.Notify(&webhookEvent{
+		User:      user,
+		Event:     event,
+		Template:  template,
+		Timestamp: s.now(),
+	})
+}
+
+// saveWebhook sets the user's webhook, or removes it if the URL is empty
+func (s *Server) saveWebhook(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	formParams, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+	target := strings.TrimSpace(formParams.Get("url"))
+	if target == "" {
+		if err = s.store.DeleteWebhookForUser(c.Request().Context(), user); err != nil {
+			return err
+		}
+		return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("user-account"))
+	}
+	if parsed, err := url.Parse(target); err != nil || len(target) > webhookMaxURLLength ||
+		(parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid webhook URL")
+	}
+	if err = s.store.RunTx(c, func(ctx context.Context, q db.Querier) error {
+		// Secrets are never displayed back, an empty field keeps the current one
+		secret := strings.TrimSpace(formParams.Get("secret"))
+		if secret == "" {
+			if current, err := q.GetWebhookForUser(ctx, user); err == nil {
+				secret = current.Secret
+			} else if err != db.NotFound {
+				return err
+			}
+		}
+		return q.SetWebhookForUser(ctx, db.SetWebhookForUserParams{
+			UserID: user,
+			Url:    target,
+			Secret: secret,
+		})
+	}); err != nil {
+		return err
+	}
+	return s.pages.Redirect(c, http.StatusSeeOther, s.echo.Reverse("user-account"))
+}
+
+// sendTestWebhook queues a test event, its delivery shows up in the account page
+func (s *Server) sendTestWebhook(c echo.Context) error {
+	user := auth.GetUserId(c)
+	if user == "" {
+		return echo.ErrForbidden
+	}
+	if _, err := s.store.GetWebhookForUser(c.Request().Context(), user); err == db.NotFound {
+		return echo.NewHTTPError(http.StatusBadRequest, "please set a webhook URL first")
+	} else if err != nil {
+		return err
+	}
+	s.notifyListChange(user, webhookTest, "")
+	return s.renderUserAccount(c, func(hc *pages.Context) {
+		hc.Add("webhook_test_sent", true)
+	})
+}
+
+func getWebhookDeliveries(ctx context.Context, q db.Querier, user string) ([]webhookDeliveryInfo, error) {
+	stored, err := q.GetWebhookDeliveries(ctx, db.GetWebhookDeliveriesParams{
+		UserID: user,
+		Limit:  webhookKeptDeliveries,
+	})
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]webhookDeliveryInfo, 0, len(stored))
+	for _, d := range stored {
+		deliveries = append(deliveries, webhookDeliveryInfo{
+			Event:      d.Event,
+			StatusCode: d.StatusCode,
+			Error:      d.Error,
+			Attempts:   d.Attempts,
+			CreatedAt:  d.CreatedAt.Format(sessionDateFormat),
+			Success:    d.Error == "",
+		})
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/letsblockit/letsblockit/src/pages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *ServerTestSuite) newWebhookDispatcher() *webhookDispatcher {
+	d := newWebhookDispatcher(s.store, &statsd.NoOpClient{}, true)
+	d.retryDelays = []time.Duration{0, 0}
+	s.server.webhooks = d
+	return d
+}
+
+func (s *ServerTestSuite) setWebhook(target, secret string) {
+	s.T().Helper()
+	require.NoError(s.T(), s.store.SetWebhookForUser(context.Background(), db.SetWebhookForUserParams{
+		UserID: s.user,
+		Url:    target,
+		Secret: secret,
+	}))
+}
+
+func (s *ServerTestSuite) postWebhookForm(path string, f url.Values) *http.Request {
+	f.Add(csrfLookup, s.csrf)
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return req
+}
+
+func (s *ServerTestSuite) TestWebhook_Save() {
+	s.setWebhook("https://example.com/old", "my-secret")
+	req := s.postWebhookForm("/user/webhook", url.Values{"url": {"https://example.com/hook"}})
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/account")
+	s.runRequest(req, assertOk)
+
+	webhook, err := s.store.GetWebhookForUser(context.Background(), s.user)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "https://example.com/hook", webhook.Url)
+	assert.Equal(s.T(), "my-secret", webhook.Secret) // Kept as the field was empty
+}
+
+func (s *ServerTestSuite) TestWebhook_Remove() {
+	s.setWebhook("https://example.com/hook", "")
+	req := s.postWebhookForm("/user/webhook", url.Values{"url": {""}})
+	s.expectP.Redirect(gomock.Any(), http.StatusSeeOther, "/user/account")
+	s.runRequest(req, assertOk)
+
+	_, err := s.store.GetWebhookForUser(context.Background(), s.user)
+	assert.Equal(s.T(), db.NotFound, err)
+}
+
+func (s *ServerTestSuite) TestWebhook_InvalidURL() {
+	for _, target := range []string{"ftp://example.com", "example.com/hook", "https://"} {
+		req := s.postWebhookForm("/user/webhook", url.Values{"url": {target}})
+		s.runRequest(req, func(t *testing.T, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+		})
+	}
+}
+
+func (s *ServerTestSuite) TestWebhook_SendTestEvent() {
+	d := s.newWebhookDispatcher()
+	s.setWebhook("https://example.com/hook", "")
+	req := s.postWebhookForm("/user/webhook/test", url.Values{})
+	s.expectP.Render(gomock.Any(), "user-account", gomock.Any()).
+		DoAndReturn(func(_ echo.Context, _ string, hc *pages.Context) error {
+			assert.Equal(s.T(), true, hc.Data["webhook_test_sent"])
+			assert.Equal(s.T(), "https://example.com/hook", hc.Data["webhook_url"])
+			return nil
+		})
+	s.runRequest(req, assertOk)
+
+	if assert.Len(s.T(), d.events, 1) {
+		event := <-d.events
+		assert.Equal(s.T(), webhookTest, event.Event)
+		assert.Equal(s.T(), s.user, event.User)
+	}
+}
+
+func (s *ServerTestSuite) TestWebhook_NotifiedOnChanges() {
+	d := s.newWebhookDispatcher()
+	s.addInstance(s.user, "filter1", nil)
+	s.addInstance(s.user, "filter1", nil)
+	req := s.postWebhookForm("/filters/filter1", url.Values{"__disable": {""}})
+	s.expectP.RedirectToPage(gomock.Any(), "list-filters")
+	s.runRequest(req, assertOk)
+
+	var events []string
+	for len(d.events) > 0 {
+		event := <-d.events
+		assert.Equal(s.T(), "filter1", event.Template)
+		events = append(events, event.Event)
+	}
+	assert.Equal(s.T(), []string{webhookInstanceCreated, webhookInstanceUpdated, webhookInstanceDeleted}, events)
+}
+
+func (s *ServerTestSuite) TestWebhook_Deliver() {
+	var received *http.Request
+	var body []byte
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+
+	d := s.newWebhookDispatcher()
+	s.setWebhook(target.URL, "my-secret")
+	s.addInstance(s.user, "filter1", nil)
+	token := s.markListDownloaded()
+	require.NoError(s.T(), d.deliver(context.Background(), <-d.events))
+
+	require.NotNil(s.T(), received)
+	assert.Equal(s.T(), webhookInstanceCreated, received.Header.Get(webhookEventHeader))
+	assert.Equal(s.T(), signWebhookPayload("my-secret", body), received.Header.Get(webhookSignatureHeader))
+	var payload webhookPayload
+	require.NoError(s.T(), json.Unmarshal(body, &payload))
+	assert.Equal(s.T(), webhookInstanceCreated, payload.Event)
+	assert.Equal(s.T(), "filter1", payload.Template)
+	assert.Equal(s.T(), fixedNow, payload.Timestamp)
+	assert.Len(s.T(), payload.ListDigest, 64)
+	assert.NotContains(s.T(), string(body), token)
+
+	deliveries, err := getWebhookDeliveries(context.Background(), s.store, s.user)
+	require.NoError(s.T(), err)
+	if assert.Len(s.T(), deliveries, 1) {
+		assert.True(s.T(), deliveries[0].Success)
+		assert.EqualValues(s.T(), http.StatusNoContent, deliveries[0].StatusCode)
+		assert.EqualValues(s.T(), 1, deliveries[0].Attempts)
+	}
+}
+
+func (s *ServerTestSuite) TestWebhook_DeliverRetries() {
+	calls := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer target.Close()
+
+	d := s.newWebhookDispatcher()
+	s.setWebhook(target.URL, "")
+	require.NoError(s.T(), d.deliver(context.Background(), &webhookEvent{User: s.user, Event: webhookTest}))
+	assert.Equal(s.T(), 3, calls)
+
+	deliveries, err := getWebhookDeliveries(context.Background(), s.store, s.user)
+	require.NoError(s.T(), err)
+	if assert.Len(s.T(), deliveries, 1) {
+		assert.False(s.T(), deliveries[0].Success)
+		assert.Equal(s.T(), "unexpected status 502 Bad Gateway", deliveries[0].Error)
+		assert.EqualValues(s.T(), 3, deliveries[0].Attempts)
+	}
+}
+
+func (s *ServerTestSuite) TestWebhook_NoWebhook() {
+	d := s.newWebhookDispatcher()
+	require.NoError(s.T(), d.deliver(context.Background(), &webhookEvent{User: s.user, Event: webhookTest}))
+	deliveries, err := getWebhookDeliveries(context.Background(), s.store, s.user)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), deliveries)
+}
+
+func (s *ServerTestSuite) TestWebhook_StopDeliversQueuedEvents() {
+	calls := make(chan string, 2)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls <- r.Header.Get(webhookEventHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+
+	d := s.newWebhookDispatcher()
+	s.setWebhook(target.URL, "")
+	d.Notify(&webhookEvent{User: s.user, Event: webhookTest})
+	d.Notify(&webhookEvent{User: s.user, Event: webhookInstanceCreated})
+	d.Run(s.server.echo.Logger)
+	require.NoError(s.T(), d.Stop(context.Background()))
+	assert.Len(s.T(), calls, 2)
+	assert.Empty(s.T(), d.events)
+}
+
+func TestWebhookDispatcher_BlocksPrivateAddresses(t *testing.T) {
+	d := newWebhookDispatcher(nil, &statsd.NoOpClient{}, false)
+	for address, allowed := range map[string]bool{
+		"127.0.0.1:80":       false,
+		"10.1.2.3:443":       false,
+		"192.168.1.1:443":    false,
+		"169.254.169.254:80": false,
+		"[::1]:443":          false,
+		"1.1.1.1:443":        true,
+		"[2606:4700::1]:80":  true,
+	} {
+		assert.Equal(t, allowed, d.checkAddress("tcp", address, nil) == nil, address)
+	}
+}
+
+func TestWebhookDispatcher_NilIsNoop(t *testing.T) {
+	var d *webhookDispatcher
+	d.Notify(&webhookEvent{User: "user"})
+	assert.NoError(t, d.Stop(context.Background()))
+}
@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const serviceName = "letsblockit"
+
+// Options configure the export of traces to an OTLP collector
+type Options struct {
+	Endpoint   string  // host:port of the OTLP/HTTP collector, tracing is disabled if empty
+	Insecure   bool    // Use plain HTTP instead of HTTPS
+	SampleRate float64 // Ratio of the traces to sample, unless the parent span is sampled
+}
+
+// Setup registers a global tracer provider exporting to the collector, and returns a function
+// flushing the pending spans. If no endpoint is set, the default no-op provider is kept.
+func Setup(options Options) (func(context.Context) error, error) {
+	if options.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporterOptions := []otlptracehttp.Option{otlptracehttp.WithEndpoint(options.Endpoint)}
+	if options.Insecure {
+		exporterOptions = append(exporterOptions, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), exporterOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRate))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,29 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestSetup_Disabled(t *testing.T) {
+	defaultProvider := otel.GetTracerProvider()
+	shutdown, err := Setup(Options{})
+	require.NoError(t, err)
+	assert.Equal(t, defaultProvider, otel.GetTracerProvider(), "the no-op provider is kept")
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestSetup_Enabled(t *testing.T) {
+	defaultProvider := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(defaultProvider)
+
+	shutdown, err := Setup(Options{Endpoint: "localhost:4318", Insecure: true, SampleRate: 1})
+	require.NoError(t, err)
+	assert.IsType(t, &sdktrace.TracerProvider{}, otel.GetTracerProvider())
+	assert.NoError(t, shutdown(context.Background()), "no span to flush")
+}
@@ -0,0 +1,29 @@
+package auth
+
+import "github.com/labstack/echo/v4"
+
+const adminContextKey = "_is_admin"
+
+// BuildAdminMiddleware flags the requests of the given users as coming from an admin.
+// It must run after the backend's middleware, to get the user ID.
+func BuildAdminMiddleware(admins []string) echo.MiddlewareFunc {
+	adminSet := make(map[string]struct{}, len(admins))
+	for _, a := range admins {
+		if a != "" {
+			adminSet[a] = struct{}{}
+		}
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, found := adminSet[GetUserId(c)]; found {
+				c.Set(adminContextKey, true)
+			}
+			return next(c)
+		}
+	}
+}
+
+func IsAdmin(c echo.Context) bool {
+	isAdmin, _ := c.Get(adminContextKey).(bool)
+	return isAdmin
+}
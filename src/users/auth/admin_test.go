@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildAdminMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(NewProxy("X-User").BuildMiddleware(), BuildAdminMiddleware([]string{"admin", ""}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, strconv.FormatBool(IsAdmin(c)))
+	})
+
+	for user, expected := range map[string]string{
+		"admin":   "true",
+		"regular": "false",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expected, rec.Body.String(), user)
+	}
+}
+
+func TestIsAdmin_Anonymous(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	BuildAdminMiddleware([]string{"admin"})(func(c echo.Context) error { return nil })(c)
+	assert.False(t, IsAdmin(c))
+}
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"context"
+
 	"github.com/labstack/echo/v4"
 )
 
@@ -8,6 +10,7 @@ const (
 	hasAccountCookieName  = "has_account"
 	hasAccountCookieValue = "true"
 	userContextKey        = "_user"
+	sessionContextKey     = "_session"
 	hasAuthContextKey     = "_has_auth"
 	userActionRouteName   = "user-action"
 )
@@ -17,6 +20,11 @@ type Backend interface {
 	RegisterRoutes(group EchoRouter)
 }
 
+// HealthChecker is implemented by backends relying on an external service, to check it is reachable
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
 type EchoRouter interface {
 	GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
 	POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
@@ -33,6 +41,18 @@ func GetUserId(c echo.Context) string {
 	return ""
 }
 
+func setSessionId(c echo.Context, id string) {
+	c.Set(sessionContextKey, id)
+}
+
+// GetSessionId returns the ID of the auth session, if the backend supports sessions
+func GetSessionId(c echo.Context) string {
+	if s, ok := c.Get(sessionContextKey).(string); ok {
+		return s
+	}
+	return ""
+}
+
 func HasAccount(c echo.Context) bool {
 	_, err := c.Cookie(hasAccountCookieName)
 	return err == nil
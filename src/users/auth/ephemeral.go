@@ -0,0 +1,17 @@
+package auth
+
+import "github.com/labstack/echo/v4"
+
+const ephemeralContextKey = "_ephemeral"
+
+// SetEphemeralUser authenticates a visitor without an account, using the ID of their ephemeral list.
+// Handlers can use IsEphemeral to restrict the features available to them.
+func SetEphemeralUser(c echo.Context, id string) {
+	setUserId(c, id)
+	c.Set(ephemeralContextKey, true)
+}
+
+func IsEphemeral(c echo.Context) bool {
+	ephemeral, _ := c.Get(ephemeralContextKey).(bool)
+	return ephemeral
+}
@@ -0,0 +1,24 @@
+package auth
+
+import "github.com/labstack/echo/v4"
+
+const realUserContextKey = "_real_user"
+
+// SetImpersonatedUser lets an admin browse the site as another user. GetUserId returns
+// the impersonated user, while GetRealUserId keeps returning the admin's ID.
+func SetImpersonatedUser(c echo.Context, id string) {
+	c.Set(realUserContextKey, GetUserId(c))
+	setUserId(c, id)
+}
+
+// GetRealUserId returns the ID of the authenticated user, even during an impersonation
+func GetRealUserId(c echo.Context) string {
+	if u, ok := c.Get(realUserContextKey).(string); ok {
+		return u
+	}
+	return GetUserId(c)
+}
+
+func IsImpersonating(c echo.Context) bool {
+	return c.Get(realUserContextKey) != nil
+}
@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetImpersonatedUser(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	setUserId(c, "admin")
+	assert.False(t, IsImpersonating(c))
+	assert.Equal(t, "admin", GetRealUserId(c))
+
+	SetImpersonatedUser(c, "target")
+	assert.True(t, IsImpersonating(c))
+	assert.Equal(t, "target", GetUserId(c))
+	assert.Equal(t, "admin", GetRealUserId(c))
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -24,6 +25,7 @@ const (
 	oryReturnToPattern  = "?return_to=%s"
 	oryLogoutInfoPath   = "/self-service/logout/browser"
 	oryWhoamiPath       = "/sessions/whoami"
+	oryHealthPath       = "/health/alive"
 	returnToKey         = "return_to"
 )
 
@@ -85,8 +87,9 @@ var (
 
 // oryUser holds the parts of the kratos user we care about.
 type oryUser struct {
-	Active   bool
-	Identity struct {
+	SessionId uuid.UUID `json:"id"`
+	Active    bool
+	Identity  struct {
 		Id uuid.UUID
 	}
 }
@@ -141,7 +144,7 @@ func (o *OryBackend) RegisterRoutes(group EchoRouter) {
 // BuildMiddleware tries to resolve an Ory Cloud session from the cookies.
 // If it succeeds, a "user" value is added to the context for use by handlers.
 func (o *OryBackend) BuildMiddleware() echo.MiddlewareFunc {
-	authCache := zcache.New[string, string](15*time.Minute, 10*time.Minute)
+	authCache := zcache.New[string, *oryUser](15*time.Minute, 10*time.Minute)
 	endpoint := o.rootUrl + oryWhoamiPath
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -152,17 +155,18 @@ func (o *OryBackend) BuildMiddleware() echo.MiddlewareFunc {
 			}
 
 			if u, ok := authCache.Get(cookies); ok {
-				setUserId(c, u)
+				setUserId(c, u.Id())
+				setSessionId(c, u.SessionId.String())
 				return next(c)
 			}
 
-			var user oryUser
-			if err := o.queryKratos(c, "whoami", endpoint, &user); err != nil {
+			user := new(oryUser)
+			if err := o.queryKratos(c, "whoami", endpoint, user); err != nil {
 				c.Logger().Error("auth error: %w", err)
 			} else if user.IsActive() {
-				id := user.Id()
-				authCache.Set(cookies, id)
-				setUserId(c, id)
+				authCache.Set(cookies, user)
+				setUserId(c, user.Id())
+				setSessionId(c, user.SessionId.String())
 			}
 
 			if _, err := c.Cookie(hasAccountCookieName); err == http.ErrNoCookie {
@@ -181,6 +185,23 @@ func (o *OryBackend) BuildMiddleware() echo.MiddlewareFunc {
 	}
 }
 
+// CheckHealth queries the liveness endpoint of the proxy, without retrying
+func (o *OryBackend) CheckHealth(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.rootUrl+oryHealthPath, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := o.client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
 // getLogoutUrl retrieves the logout url for the current session by calling the proxy
 func (o *OryBackend) getLogoutUrl(c echo.Context) (string, error) {
 	var info oryLogoutInfo
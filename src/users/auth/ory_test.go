@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -79,6 +80,8 @@ func (s *OryBackendSuite) SetupTest() {
 		var err error
 		fmt.Println(r.URL.Path)
 		switch r.URL.Path {
+		case "/health/alive":
+			_, err = fmt.Fprint(w, `{"status":"ok"}`)
 		case "/self-service/logout/browser":
 			_, err = fmt.Fprint(w, `{"logout_url":"targetURL"}`)
 		case "/sessions/whoami":
@@ -136,6 +139,7 @@ func (s *OryBackendSuite) TestGet_Logged_HasCookie() { // Don't set the has_acco
 		assert.True(s.T(), HasAccount(c))
 		assert.True(s.T(), HasAuth(c))
 		assert.Equal(s.T(), s.user, GetUserId(c))
+		assert.Equal(s.T(), "af9b460f-4ca0-453d-8bc7-cf68f30d4174", GetSessionId(c))
 		return nil
 	})
 	req := httptest.NewRequest(http.MethodGet, "/check", nil)
@@ -319,6 +323,12 @@ func (s *OryBackendSuite) TestStartLoginFlow_Login() {
 	s.runRequest(req, assertOk)
 }
 
+func (s *OryBackendSuite) TestCheckHealth() {
+	s.NoError(NewOryBackend(s.kratosServer.URL, nil, &statsd.NoOpClient{}).CheckHealth(context.Background()))
+	s.ErrorContains(NewOryBackend(s.kratosServer.URL+"/invalid", nil, &statsd.NoOpClient{}).CheckHealth(context.Background()),
+		"unexpected status 404 Not Found")
+}
+
 func TestOryBackendSuite(t *testing.T) {
 	suite.Run(t, new(OryBackendSuite))
 }
@@ -1,31 +1,89 @@
 package users
 
-import "context"
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/letsblockit/letsblockit/src/db"
+)
 
 type banQuerier interface {
+	AddUserBan(ctx context.Context, arg db.AddUserBanParams) error
 	GetBannedUsers(ctx context.Context) ([]string, error)
+	LiftUserBan(ctx context.Context, arg db.LiftUserBanParams) error
 }
 
+// BanManager keeps the banned users in memory, to keep the list rendering path fast.
+// The set is reloaded after every change, and should be refreshed periodically to lift expired bans.
 type BanManager struct {
-	bans map[string]struct{}
+	sync.RWMutex
+	bans  map[string]struct{}
+	store banQuerier
 }
 
 func LoadUserBans(store banQuerier) (*BanManager, error) {
-	users, err := store.GetBannedUsers(context.Background())
-	if err != nil {
+	m := &BanManager{store: store}
+	if err := m.Refresh(context.Background()); err != nil {
 		return nil, err
 	}
+	return m, nil
+}
+
+// Refresh reloads the banned users from the database
+func (m *BanManager) Refresh(ctx context.Context) error {
+	users, err := m.store.GetBannedUsers(ctx)
+	if err != nil {
+		return err
+	}
 	bans := make(map[string]struct{}, len(users))
 	for _, u := range users {
 		bans[u] = struct{}{}
 	}
-	return &BanManager{bans: bans}, nil
+	m.Lock()
+	m.bans = bans
+	m.Unlock()
+	return nil
+}
+
+// RefreshEvery calls Refresh on every interval until ctx is cancelled, errors are passed to onError
+func (m *BanManager) RefreshEvery(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Refresh(ctx); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Ban stores a new ban and reloads the banned users
+func (m *BanManager) Ban(ctx context.Context, arg db.AddUserBanParams) error {
+	if err := m.store.AddUserBan(ctx, arg); err != nil {
+		return err
+	}
+	return m.Refresh(ctx)
+}
+
+// Lift lifts the active bans of a user and reloads the banned users
+func (m *BanManager) Lift(ctx context.Context, arg db.LiftUserBanParams) error {
+	if err := m.store.LiftUserBan(ctx, arg); err != nil {
+		return err
+	}
+	return m.Refresh(ctx)
 }
 
 func (m *BanManager) IsBanned(id string) bool {
 	if m == nil {
 		return false // For unit tests
 	}
+	m.RLock()
+	defer m.RUnlock()
 	_, found := m.bans[id]
 	return found
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/letsblockit/letsblockit/src/db"
 	"github.com/stretchr/testify/assert"
@@ -35,3 +36,40 @@ func TestLoadBannedUsers(t *testing.T) {
 	assert.False(t, bans.IsBanned("five"))  // Ban lifted
 
 }
+
+func TestBanManager_BanAndLift(t *testing.T) {
+	store := db.NewTestStore(t)
+	bans, err := LoadUserBans(store)
+	require.NoError(t, err)
+	assert.False(t, bans.IsBanned("one"))
+
+	require.NoError(t, bans.Ban(context.Background(), db.AddUserBanParams{
+		UserID: "one",
+		Reason: "testing",
+	}))
+	assert.True(t, bans.IsBanned("one"))
+
+	require.NoError(t, bans.Lift(context.Background(), db.LiftUserBanParams{
+		UserID: "one",
+	}))
+	assert.False(t, bans.IsBanned("one"))
+}
+
+func TestBanManager_Expiry(t *testing.T) {
+	store := db.NewTestStore(t)
+	bans, err := LoadUserBans(store)
+	require.NoError(t, err)
+
+	require.NoError(t, bans.Ban(context.Background(), db.AddUserBanParams{
+		UserID:    "expired",
+		Reason:    "testing",
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+	}))
+	require.NoError(t, bans.Ban(context.Background(), db.AddUserBanParams{
+		UserID:    "active",
+		Reason:    "testing",
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}))
+	assert.False(t, bans.IsBanned("expired"))
+	assert.True(t, bans.IsBanned("active"))
+}
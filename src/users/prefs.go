@@ -62,3 +62,8 @@ func (m *PreferenceManager) UpdatePreferences(c echo.Context, params db.UpdateUs
 	m.cache.Delete(params.UserID)
 	return err
 }
+
+// Forget evicts the preferences of a user from the cache, after they are deleted from the DB
+func (m *PreferenceManager) Forget(user string) {
+	m.cache.Delete(user)
+}
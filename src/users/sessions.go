@@ -0,0 +1,110 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/letsblockit/letsblockit/src/db"
+	"zgo.at/zcache/v2"
+)
+
+// SessionCheckInterval is how often sessions are checked against the database. Revocations
+// are immediate on the instance handling them, and are picked up by other instances after this delay.
+const SessionCheckInterval = 5 * time.Minute
+
+type sessionQuerier interface {
+	RevokeOtherSessions(ctx context.Context, arg db.RevokeOtherSessionsParams) ([]string, error)
+	TrackSession(ctx context.Context, arg db.TrackSessionParams) (sql.NullTime, error)
+}
+
+// SessionManager keeps a registry of the auth sessions, to allow users to log out other devices.
+// Sessions are recorded on first sight, and their revocation status is cached in memory.
+type SessionManager struct {
+	revoked *zcache.Cache[string, bool]
+	store   sessionQuerier
+}
+
+func NewSessionManager(store sessionQuerier) *SessionManager {
+	return &SessionManager{
+		revoked: zcache.New[string, bool](SessionCheckInterval, time.Minute),
+		store:   store,
+	}
+}
+
+// IsRevoked records the session if needed, and returns whether it has been revoked
+func (m *SessionManager) IsRevoked(ctx context.Context, user, session, userAgent string) (bool, error) {
+	if m == nil {
+		return false, nil // For unit tests
+	}
+	if revoked, found := m.revoked.Get(session); found {
+		return revoked, nil
+	}
+	revokedAt, err := m.store.TrackSession(ctx, db.TrackSessionParams{
+		SessionID: session,
+		UserID:    user,
+		UserAgent: DescribeUserAgent(userAgent),
+	})
+	if err != nil {
+		return false, err
+	}
+	m.revoked.Set(session, revokedAt.Valid)
+	return revokedAt.Valid, nil
+}
+
+// RevokeOthers revokes all the sessions of a user, except the current one
+func (m *SessionManager) RevokeOthers(ctx context.Context, user, current string) (int, error) {
+	revoked, err := m.store.RevokeOtherSessions(ctx, db.RevokeOtherSessionsParams{
+		UserID:    user,
+		SessionID: current,
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, session := range revoked {
+		m.revoked.Set(session, true)
+	}
+	return len(revoked), nil
+}
+
+var (
+	userAgentBrowsers = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+	userAgentSystems = []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// DescribeUserAgent returns a coarse description of a user agent, such as "Firefox on Linux".
+// Only the browser family and operating system are kept, to avoid storing fingerprinting data.
+func DescribeUserAgent(userAgent string) string {
+	browser, system := "Unknown browser", ""
+	for _, b := range userAgentBrowsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, s := range userAgentSystems {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+	if system == "" {
+		return browser
+	}
+	return browser + " on " + system
+}
@@ -0,0 +1,59 @@
+package users
+
+import (
+	"context"
+	"testing"
+
+	"github.com/letsblockit/letsblockit/src/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const firefoxLinux = "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/111.0"
+
+func TestSessionManager_RevokeOthers(t *testing.T) {
+	store := db.NewTestStore(t)
+	sessions := NewSessionManager(store)
+	for _, session := range []string{"one", "two", "three"} {
+		revoked, err := sessions.IsRevoked(context.Background(), "user", session, firefoxLinux)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	}
+	_, err := sessions.IsRevoked(context.Background(), "other", "four", firefoxLinux)
+	require.NoError(t, err)
+
+	stored, err := store.GetSessionsForUser(context.Background(), "user")
+	require.NoError(t, err)
+	require.Len(t, stored, 3)
+	assert.Equal(t, "Firefox on Linux", stored[0].UserAgent)
+
+	count, err := sessions.RevokeOthers(context.Background(), "user", "two")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	for session, expected := range map[string]bool{"one": true, "two": false, "three": true} {
+		revoked, err := sessions.IsRevoked(context.Background(), "user", session, firefoxLinux)
+		require.NoError(t, err)
+		assert.Equal(t, expected, revoked, session)
+	}
+
+	// Other instances pick up the revocation from the database
+	revoked, err := NewSessionManager(store).IsRevoked(context.Background(), "user", "one", firefoxLinux)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = NewSessionManager(store).IsRevoked(context.Background(), "other", "four", firefoxLinux)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestDescribeUserAgent(t *testing.T) {
+	for input, expected := range map[string]string{
+		firefoxLinux: "Firefox on Linux",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/111.0.0.0 Safari/537.36 Edg/111.0.1661.54":       "Edge on Windows",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 16_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.4 Mobile/15E148 Safari/604.1": "Safari on iOS",
+		"Mozilla/5.0 (Linux; Android 13) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/111.0.0.0 Mobile Safari/537.36":                            "Chrome on Android",
+		"curl/7.88.1": "Unknown browser",
+		"":            "Unknown browser",
+	} {
+		assert.Equal(t, expected, DescribeUserAgent(input), input)
+	}
+}